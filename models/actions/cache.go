@@ -0,0 +1,166 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package actions
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/modules/optional"
+	"code.gitea.io/gitea/modules/timeutil"
+	"code.gitea.io/gitea/modules/util"
+
+	"xorm.io/builder"
+)
+
+// ActionCache is an entry of the cache of the jobs of a repository, saved and restored by `actions/cache`.
+// An entry is only restored by the runs of the ref which saved it, or of a ref whose runs can read the caches of this ref.
+type ActionCache struct {
+	ID       int64
+	RepoID   int64  `xorm:"index NOT NULL"`
+	RunID    int64  // the run which saved the entry
+	CacheKey string `xorm:"VARCHAR(512) NOT NULL"`
+	Version  string `xorm:"VARCHAR(255) NOT NULL"` // the hash of the paths and the compression method of the entry
+	Ref      string `xorm:"VARCHAR(255) NOT NULL"` // the ref of the run which saved the entry
+	Size     int64
+	Complete bool `xorm:"index"` // the entry has been committed and can be restored
+
+	Created  timeutil.TimeStamp `xorm:"created"`
+	LastUsed timeutil.TimeStamp `xorm:"index"` // when the entry was saved or last restored, the least recently used entries are evicted first
+}
+
+func init() {
+	db.RegisterModel(new(ActionCache))
+}
+
+// StoragePath returns the path of the content of the entry in the storage.
+func (c *ActionCache) StoragePath() string {
+	return fmt.Sprintf("%d/%d", c.RepoID, c.ID)
+}
+
+// ChunksPath returns the directory of the chunks of the content of the entry before it is committed.
+func (c *ActionCache) ChunksPath() string {
+	return fmt.Sprintf("tmp/%d", c.ID)
+}
+
+type FindCachesOptions struct {
+	db.ListOptions
+	RepoID   int64
+	Version  string
+	Refs     []string
+	Complete optional.Option[bool]
+	UsedLT   timeutil.TimeStamp // the entries last used before this time
+}
+
+func (opts FindCachesOptions) ToConds() builder.Cond {
+	cond := builder.NewCond()
+	if opts.RepoID > 0 {
+		cond = cond.And(builder.Eq{"repo_id": opts.RepoID})
+	}
+	if opts.Version != "" {
+		cond = cond.And(builder.Eq{"version": opts.Version})
+	}
+	if len(opts.Refs) > 0 {
+		cond = cond.And(builder.In("ref", opts.Refs))
+	}
+	if opts.Complete.Has() {
+		cond = cond.And(builder.Eq{"complete": opts.Complete.Value()})
+	}
+	if opts.UsedLT > 0 {
+		cond = cond.And(builder.Lt{"last_used": opts.UsedLT})
+	}
+	return cond
+}
+
+// ToOrders returns the least recently used entries first, which are evicted first.
+func (opts FindCachesOptions) ToOrders() string {
+	return "last_used ASC, id ASC"
+}
+
+func GetCacheByID(ctx context.Context, id int64) (*ActionCache, error) {
+	var c ActionCache
+	has, err := db.GetEngine(ctx).ID(id).Get(&c)
+	if err != nil {
+		return nil, err
+	} else if !has {
+		return nil, fmt.Errorf("cache with id %d: %w", id, util.ErrNotExist)
+	}
+	return &c, nil
+}
+
+// FindCache returns the most recent complete entry matching the first possible key, looking up the refs in the given order.
+// For each key, an entry whose key is the same is preferred to an entry whose key starts with it.
+// It returns nil if no entry matches.
+func FindCache(ctx context.Context, repoID int64, keys []string, version string, refs []string) (*ActionCache, error) {
+	for _, ref := range refs {
+		caches, err := db.Find[ActionCache](ctx, FindCachesOptions{
+			RepoID:   repoID,
+			Version:  version,
+			Refs:     []string{ref},
+			Complete: optional.Some(true),
+		})
+		if err != nil {
+			return nil, err
+		}
+		// the most recently created entry matching a key is restored
+		var match *ActionCache
+		for _, key := range keys {
+			for _, c := range caches {
+				if c.CacheKey == key && (match == nil || c.Created > match.Created) {
+					match = c
+				}
+			}
+			if match != nil {
+				return match, nil
+			}
+			for _, c := range caches {
+				if strings.HasPrefix(c.CacheKey, key) && (match == nil || c.Created > match.Created) {
+					match = c
+				}
+			}
+			if match != nil {
+				return match, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// InsertCache reserves an entry, there must not be another entry with the same key and version for the same ref.
+func InsertCache(ctx context.Context, c *ActionCache) error {
+	return db.WithTx(ctx, func(ctx context.Context) error {
+		exist, err := db.GetEngine(ctx).Exist(&ActionCache{RepoID: c.RepoID, CacheKey: c.CacheKey, Version: c.Version, Ref: c.Ref})
+		if err != nil {
+			return err
+		} else if exist {
+			return fmt.Errorf("cache %q of repo %d: %w", c.CacheKey, c.RepoID, util.ErrAlreadyExist)
+		}
+		c.LastUsed = timeutil.TimeStampNow()
+		return db.Insert(ctx, c)
+	})
+}
+
+func UpdateCache(ctx context.Context, c *ActionCache, cols ...string) error {
+	_, err := db.GetEngine(ctx).ID(c.ID).Cols(cols...).Update(c)
+	return err
+}
+
+// GetCacheSize returns the total size of the complete entries of a repository.
+func GetCacheSize(ctx context.Context, repoID int64) (int64, error) {
+	return db.GetEngine(ctx).Where("repo_id=? AND complete=?", repoID, true).SumInt(new(ActionCache), "size")
+}
+
+// GetReposExceedingCacheSize returns the IDs of the repositories whose complete entries exceed the given size.
+func GetReposExceedingCacheSize(ctx context.Context, maxSize int64) ([]int64, error) {
+	var repoIDs []int64
+	err := db.GetEngine(ctx).Table("action_cache").
+		Where("complete=?", true).
+		GroupBy("repo_id").
+		Having(fmt.Sprintf("SUM(size) > %d", maxSize)).
+		Cols("repo_id").
+		Find(&repoIDs)
+	return repoIDs, err
+}
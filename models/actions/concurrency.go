@@ -0,0 +1,137 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package actions
+
+import (
+	"context"
+
+	"code.gitea.io/gitea/models/db"
+
+	"xorm.io/builder"
+)
+
+// ShouldBlockRunByConcurrency returns whether a run that hasn't started yet has to wait for
+// another run of the same concurrency group. A run is held back by the runs in progress
+// in its group and by the older runs of its group that are waiting too, so the runs of a group start in order.
+func ShouldBlockRunByConcurrency(ctx context.Context, run *ActionRun) (bool, error) {
+	if run.ConcurrencyGroup == "" {
+		return false, nil
+	}
+
+	cond := builder.Eq{
+		"repo_id":           run.RepoID,
+		"concurrency_group": run.ConcurrencyGroup,
+	}.And(builder.Neq{"id": run.ID})
+
+	inProgress := builder.In("status", StatusWaiting, StatusRunning)
+	var olderWaiting builder.Cond = builder.Eq{"status": StatusBlocked, "need_approval": false}
+	if run.ID > 0 {
+		olderWaiting = olderWaiting.And(builder.Lt{"id": run.ID})
+	}
+
+	return db.GetEngine(ctx).Where(cond.And(builder.Or(inProgress, olderWaiting))).Exist(new(ActionRun))
+}
+
+// ShouldBlockJobByConcurrency returns whether a job has to wait for another job in progress
+// in the same concurrency group.
+func ShouldBlockJobByConcurrency(ctx context.Context, job *ActionRunJob) (bool, error) {
+	if job.ConcurrencyGroup == "" {
+		return false, nil
+	}
+
+	return db.GetEngine(ctx).Where(builder.Eq{
+		"repo_id":           job.RepoID,
+		"concurrency_group": job.ConcurrencyGroup,
+	}.
+		And(builder.Neq{"id": job.ID}).
+		And(builder.In("status", StatusWaiting, StatusRunning)),
+	).Exist(new(ActionRunJob))
+}
+
+// CancelConcurrentRuns cancels the runs of the concurrency group of a run which is about to be queued.
+// The runs waiting in the group are always cancelled, the runs in progress only if the run cancels them.
+func CancelConcurrentRuns(ctx context.Context, run *ActionRun) error {
+	if run.ConcurrencyGroup == "" {
+		return nil
+	}
+
+	statuses := []Status{StatusBlocked}
+	if run.ConcurrencyCancel {
+		statuses = append(statuses, StatusWaiting, StatusRunning)
+	}
+
+	var runs []*ActionRun
+	if err := db.GetEngine(ctx).Where(builder.Eq{
+		"repo_id":           run.RepoID,
+		"concurrency_group": run.ConcurrencyGroup,
+	}.
+		And(builder.Neq{"id": run.ID}).
+		And(builder.In("status", statuses)),
+	).Find(&runs); err != nil {
+		return err
+	}
+
+	for _, r := range runs {
+		jobs, err := GetRunJobsByRunID(ctx, r.ID)
+		if err != nil {
+			return err
+		}
+		if err := CancelJobs(ctx, jobs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CancelConcurrentJobs cancels the jobs of the concurrency group of a job which is about to be queued.
+// The jobs waiting in the group are always cancelled, the jobs in progress only if the job cancels them.
+func CancelConcurrentJobs(ctx context.Context, job *ActionRunJob) error {
+	if job.ConcurrencyGroup == "" {
+		return nil
+	}
+
+	statuses := []Status{StatusBlocked}
+	if job.ConcurrencyCancel {
+		statuses = append(statuses, StatusWaiting, StatusRunning)
+	}
+
+	var jobs []*ActionRunJob
+	if err := db.GetEngine(ctx).Where(builder.Eq{
+		"repo_id":           job.RepoID,
+		"concurrency_group": job.ConcurrencyGroup,
+	}.
+		And(builder.Neq{"id": job.ID}).
+		And(builder.In("status", statuses)),
+	).Find(&jobs); err != nil {
+		return err
+	}
+
+	return CancelJobs(ctx, jobs)
+}
+
+// FindRunIDsBlockedByConcurrency returns the IDs of the runs of a repository which have been held back
+// by the given run- or job-level concurrency groups.
+func FindRunIDsBlockedByConcurrency(ctx context.Context, repoID int64, runGroup string, jobGroups []string) ([]int64, error) {
+	ids := make([]int64, 0, 10)
+	if runGroup != "" {
+		if err := db.GetEngine(ctx).Table("action_run").Cols("id").Where(builder.Eq{
+			"repo_id":           repoID,
+			"concurrency_group": runGroup,
+			"status":            StatusBlocked,
+		}).OrderBy("id").Find(&ids); err != nil {
+			return nil, err
+		}
+	}
+	if len(jobGroups) > 0 {
+		var runIDs []int64
+		if err := db.GetEngine(ctx).Table("action_run_job").Distinct("run_id").Where(builder.Eq{
+			"repo_id": repoID,
+			"status":  StatusBlocked,
+		}.And(builder.In("concurrency_group", jobGroups))).Find(&runIDs); err != nil {
+			return nil, err
+		}
+		ids = append(ids, runIDs...)
+	}
+	return ids, nil
+}
@@ -0,0 +1,83 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package actions
+
+import (
+	"testing"
+
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/unittest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldBlockRunByConcurrency(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+	ctx := db.DefaultContext
+
+	running := &ActionRun{RepoID: 1001, Index: 1, ConcurrencyGroup: "deploy", Status: StatusRunning}
+	waiting := &ActionRun{RepoID: 1001, Index: 2, ConcurrencyGroup: "deploy", Status: StatusBlocked}
+	other := &ActionRun{RepoID: 1001, Index: 3, ConcurrencyGroup: "other", Status: StatusBlocked}
+	for _, run := range []*ActionRun{running, waiting, other} {
+		require.NoError(t, db.Insert(ctx, run))
+	}
+
+	// a new run waits for the run in progress
+	blocked, err := ShouldBlockRunByConcurrency(ctx, &ActionRun{RepoID: 1001, ConcurrencyGroup: "deploy"})
+	require.NoError(t, err)
+	assert.True(t, blocked)
+
+	// the same group in another repository is unrelated
+	blocked, err = ShouldBlockRunByConcurrency(ctx, &ActionRun{RepoID: 1002, ConcurrencyGroup: "deploy"})
+	require.NoError(t, err)
+	assert.False(t, blocked)
+
+	// a run without concurrency group is never blocked
+	blocked, err = ShouldBlockRunByConcurrency(ctx, &ActionRun{RepoID: 1001})
+	require.NoError(t, err)
+	assert.False(t, blocked)
+
+	// the run waiting in the group is released once the run in progress is done
+	blocked, err = ShouldBlockRunByConcurrency(ctx, waiting)
+	require.NoError(t, err)
+	assert.True(t, blocked)
+	running.Status = StatusSuccess
+	_, err = db.GetEngine(ctx).ID(running.ID).Cols("status").Update(running)
+	require.NoError(t, err)
+	blocked, err = ShouldBlockRunByConcurrency(ctx, waiting)
+	require.NoError(t, err)
+	assert.False(t, blocked)
+
+	// the only run of its group doesn't wait
+	blocked, err = ShouldBlockRunByConcurrency(ctx, other)
+	require.NoError(t, err)
+	assert.False(t, blocked)
+
+	ids, err := FindRunIDsBlockedByConcurrency(ctx, 1001, "deploy", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{waiting.ID}, ids)
+}
+
+func TestShouldBlockJobByConcurrency(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+	ctx := db.DefaultContext
+
+	running := &ActionRunJob{RunID: 1, RepoID: 1001, JobID: "deploy", ConcurrencyGroup: "deploy", Status: StatusRunning}
+	blockedJob := &ActionRunJob{RunID: 2, RepoID: 1001, JobID: "deploy", ConcurrencyGroup: "deploy", IsConcurrencyEvaluated: true, Status: StatusBlocked}
+	require.NoError(t, db.Insert(ctx, running))
+	require.NoError(t, db.Insert(ctx, blockedJob))
+
+	blocked, err := ShouldBlockJobByConcurrency(ctx, blockedJob)
+	require.NoError(t, err)
+	assert.True(t, blocked)
+
+	blocked, err = ShouldBlockJobByConcurrency(ctx, running)
+	require.NoError(t, err)
+	assert.False(t, blocked)
+
+	ids, err := FindRunIDsBlockedByConcurrency(ctx, 1001, "", []string{"deploy"})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{2}, ids)
+}
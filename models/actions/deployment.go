@@ -0,0 +1,124 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package actions
+
+import (
+	"context"
+	"fmt"
+
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/modules/timeutil"
+	"code.gitea.io/gitea/modules/util"
+
+	"xorm.io/builder"
+)
+
+// ActionDeployment is the deployment of a job to an environment, kept in the deployment history of the repository.
+// Its status follows the status of the job once it has been approved and its wait timer has elapsed.
+type ActionDeployment struct {
+	ID            int64
+	RepoID        int64              `xorm:"index"`
+	EnvironmentID int64              `xorm:"index"`
+	Environment   *ActionEnvironment `xorm:"-"`
+	RunID         int64              `xorm:"index"`
+	Run           *ActionRun         `xorm:"-"`
+	JobID         int64              `xorm:"index"`
+	Ref           string
+	CommitSHA     string
+	URL           string `xorm:"TEXT"` // the `environment.url` of the job
+	Status        Status `xorm:"index"`
+
+	NeedApproval bool               // the deployment must be approved by a reviewer of the environment
+	ApprovedBy   int64              `xorm:"index"` // the reviewer who approved the deployment
+	RejectedBy   int64              `xorm:"index"` // the reviewer who rejected the deployment
+	Reviewed     timeutil.TimeStamp // when the deployment was approved or rejected
+	WaitUntil    timeutil.TimeStamp `xorm:"index"` // the end of the wait timer of the environment
+
+	Created timeutil.TimeStamp `xorm:"created"`
+	Updated timeutil.TimeStamp `xorm:"updated"`
+}
+
+func init() {
+	db.RegisterModel(new(ActionDeployment))
+}
+
+// IsWaitingForReview returns whether the deployment waits for a reviewer of its environment.
+func (d *ActionDeployment) IsWaitingForReview() bool {
+	return d.Status == StatusWaitingForApproval && d.NeedApproval && d.ApprovedBy == 0 && d.RejectedBy == 0
+}
+
+// CanStart returns whether the job of the deployment waiting for approval can run:
+// it has been approved if needed and the wait timer of the environment has elapsed.
+func (d *ActionDeployment) CanStart(now timeutil.TimeStamp) bool {
+	return d.Status == StatusWaitingForApproval && (!d.NeedApproval || d.ApprovedBy > 0) && d.RejectedBy == 0 && d.WaitUntil <= now
+}
+
+func (d *ActionDeployment) LoadAttributes(ctx context.Context) error {
+	if d.Environment == nil {
+		env, err := GetEnvironmentByID(ctx, d.EnvironmentID)
+		if err != nil {
+			return err
+		}
+		d.Environment = env
+	}
+	if d.Run == nil {
+		run, err := GetRunByID(ctx, d.RunID)
+		if err != nil {
+			return err
+		}
+		d.Run = run
+	}
+	return d.Run.LoadAttributes(ctx)
+}
+
+type FindDeploymentsOptions struct {
+	db.ListOptions
+	RepoID        int64
+	EnvironmentID int64
+	Statuses      []Status
+	WaitUntil     timeutil.TimeStamp // the deployments whose wait timer has elapsed at this time
+}
+
+func (opts FindDeploymentsOptions) ToConds() builder.Cond {
+	cond := builder.NewCond()
+	if opts.RepoID > 0 {
+		cond = cond.And(builder.Eq{"repo_id": opts.RepoID})
+	}
+	if opts.EnvironmentID > 0 {
+		cond = cond.And(builder.Eq{"environment_id": opts.EnvironmentID})
+	}
+	if len(opts.Statuses) > 0 {
+		cond = cond.And(builder.In("status", opts.Statuses))
+	}
+	if opts.WaitUntil > 0 {
+		cond = cond.And(builder.Lte{"wait_until": opts.WaitUntil})
+	}
+	return cond
+}
+
+func (opts FindDeploymentsOptions) ToOrders() string {
+	return "id DESC"
+}
+
+func GetDeploymentByID(ctx context.Context, id int64) (*ActionDeployment, error) {
+	var d ActionDeployment
+	has, err := db.GetEngine(ctx).ID(id).Get(&d)
+	if err != nil {
+		return nil, err
+	} else if !has {
+		return nil, fmt.Errorf("deployment with id %d: %w", id, util.ErrNotExist)
+	}
+	return &d, nil
+}
+
+func UpdateDeployment(ctx context.Context, d *ActionDeployment, cols ...string) error {
+	_, err := db.GetEngine(ctx).ID(d.ID).Cols(cols...).Update(d)
+	return err
+}
+
+// updateDeploymentStatus updates the status of the deployment of a job with the status of the job.
+func updateDeploymentStatus(ctx context.Context, job *ActionRunJob) error {
+	_, err := db.GetEngine(ctx).ID(job.DeploymentID).Where("job_id=?", job.ID).Cols("status").Update(&ActionDeployment{Status: job.Status})
+	return err
+}
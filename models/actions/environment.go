@@ -0,0 +1,163 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package actions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/timeutil"
+	"code.gitea.io/gitea/modules/util"
+
+	"github.com/gobwas/glob"
+	"xorm.io/builder"
+)
+
+// ActionEnvironment is a deployment environment of a repository, targeted by the jobs with an `environment`.
+// Its protection rules are checked before the jobs deploying to it can run,
+// and its secrets and variables are only available to these jobs.
+type ActionEnvironment struct {
+	ID        int64
+	RepoID    int64  `xorm:"UNIQUE(repo_name) NOT NULL"`
+	Name      string `xorm:"VARCHAR(255) NOT NULL"`
+	LowerName string `xorm:"VARCHAR(255) UNIQUE(repo_name) NOT NULL"` // the names of the environments are case-insensitive
+
+	ReviewerIDs    []int64  `xorm:"JSON TEXT"` // the users who can approve the deployments, no approval is required if empty
+	WaitTimer      int64    // the number of minutes the jobs wait before deploying
+	BranchPatterns []string `xorm:"JSON TEXT"` // the glob patterns of the branches and tags allowed to deploy, all refs are allowed if empty
+
+	Created timeutil.TimeStamp `xorm:"created"`
+	Updated timeutil.TimeStamp `xorm:"updated"`
+}
+
+func init() {
+	db.RegisterModel(new(ActionEnvironment))
+}
+
+// NeedApproval returns whether the deployments to the environment must be approved by a reviewer.
+func (env *ActionEnvironment) NeedApproval() bool {
+	return len(env.ReviewerIDs) > 0
+}
+
+// IsReviewer returns whether a user can approve or reject the deployments to the environment.
+func (env *ActionEnvironment) IsReviewer(userID int64) bool {
+	return slices.Contains(env.ReviewerIDs, userID)
+}
+
+// IsRefAllowed returns whether the jobs of a run for the given ref can deploy to the environment.
+// When the environment has branch patterns, only the branches and the tags matching one of them are allowed.
+func (env *ActionEnvironment) IsRefAllowed(ref string) bool {
+	if len(env.BranchPatterns) == 0 {
+		return true
+	}
+	refName := git.RefName(ref)
+	if !refName.IsBranch() && !refName.IsTag() {
+		return false
+	}
+	for _, pattern := range env.BranchPatterns {
+		g, err := glob.Compile(pattern, '/')
+		if err != nil {
+			log.Warn("Invalid branch pattern %q of environment %d: %v", pattern, env.ID, err)
+			continue
+		}
+		if g.Match(refName.ShortName()) {
+			return true
+		}
+	}
+	return false
+}
+
+type FindEnvironmentsOptions struct {
+	db.ListOptions
+	RepoID int64
+}
+
+func (opts FindEnvironmentsOptions) ToConds() builder.Cond {
+	cond := builder.NewCond()
+	if opts.RepoID > 0 {
+		cond = cond.And(builder.Eq{"repo_id": opts.RepoID})
+	}
+	return cond
+}
+
+func (opts FindEnvironmentsOptions) ToOrders() string {
+	return "lower_name ASC"
+}
+
+func GetEnvironmentByID(ctx context.Context, id int64) (*ActionEnvironment, error) {
+	var env ActionEnvironment
+	has, err := db.GetEngine(ctx).ID(id).Get(&env)
+	if err != nil {
+		return nil, err
+	} else if !has {
+		return nil, fmt.Errorf("environment with id %d: %w", id, util.ErrNotExist)
+	}
+	return &env, nil
+}
+
+// GetEnvironmentByRepoAndName returns the environment of a repository with the given name, whatever its case.
+func GetEnvironmentByRepoAndName(ctx context.Context, repoID int64, name string) (*ActionEnvironment, error) {
+	var env ActionEnvironment
+	has, err := db.GetEngine(ctx).Where("repo_id=? AND lower_name=?", repoID, strings.ToLower(name)).Get(&env)
+	if err != nil {
+		return nil, err
+	} else if !has {
+		return nil, fmt.Errorf("environment %q of repo %d: %w", name, repoID, util.ErrNotExist)
+	}
+	return &env, nil
+}
+
+// InsertEnvironment inserts an environment, its name must not be used by another environment of the repository.
+func InsertEnvironment(ctx context.Context, env *ActionEnvironment) error {
+	env.LowerName = strings.ToLower(env.Name)
+	exist, err := db.GetEngine(ctx).Exist(&ActionEnvironment{RepoID: env.RepoID, LowerName: env.LowerName})
+	if err != nil {
+		return err
+	} else if exist {
+		return fmt.Errorf("environment %q of repo %d: %w", env.Name, env.RepoID, util.ErrAlreadyExist)
+	}
+	return db.Insert(ctx, env)
+}
+
+// GetOrCreateEnvironment returns the environment of a repository with the given name,
+// it's created without protection rules if it doesn't exist yet.
+func GetOrCreateEnvironment(ctx context.Context, repoID int64, name string) (*ActionEnvironment, error) {
+	env, err := GetEnvironmentByRepoAndName(ctx, repoID, name)
+	if err == nil {
+		return env, nil
+	} else if !errors.Is(err, util.ErrNotExist) {
+		return nil, err
+	}
+	env = &ActionEnvironment{RepoID: repoID, Name: name}
+	if err := InsertEnvironment(ctx, env); err != nil {
+		return nil, err
+	}
+	return env, nil
+}
+
+func UpdateEnvironment(ctx context.Context, env *ActionEnvironment, cols ...string) error {
+	_, err := db.GetEngine(ctx).ID(env.ID).Cols(cols...).Update(env)
+	return err
+}
+
+// DeleteEnvironment deletes an environment with its variables and its deployments.
+// Its secrets are deleted by the caller.
+func DeleteEnvironment(ctx context.Context, env *ActionEnvironment) error {
+	return db.WithTx(ctx, func(ctx context.Context) error {
+		if _, err := db.GetEngine(ctx).Where("environment_id=?", env.ID).Delete(new(ActionVariable)); err != nil {
+			return err
+		}
+		if _, err := db.GetEngine(ctx).Where("environment_id=?", env.ID).Delete(new(ActionDeployment)); err != nil {
+			return err
+		}
+		_, err := db.DeleteByID[ActionEnvironment](ctx, env.ID)
+		return err
+	})
+}
@@ -14,6 +14,7 @@ func TestMain(m *testing.M) {
 		FixtureFiles: []string{
 			"action_runner.yml",
 			"action_runner_token.yml",
+			"repository.yml",
 		},
 	})
 }
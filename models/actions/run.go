@@ -28,7 +28,7 @@ import (
 type ActionRun struct {
 	ID                int64
 	Title             string
-	RepoID            int64                  `xorm:"index unique(repo_index)"`
+	RepoID            int64                  `xorm:"index unique(repo_index) index(repo_concurrency)"`
 	Repo              *repo_model.Repository `xorm:"-"`
 	OwnerID           int64                  `xorm:"index"`
 	WorkflowID        string                 `xorm:"index"`                    // the name of workflow file
@@ -45,7 +45,12 @@ type ActionRun struct {
 	EventPayload      string                       `xorm:"LONGTEXT"`
 	TriggerEvent      string                       // the trigger event defined in the `on` configuration of the triggered workflow
 	Status            Status                       `xorm:"index"`
+	RawConcurrency    string                       `xorm:"TEXT"`                    // the workflow-level `concurrency` before its expressions are evaluated
+	ConcurrencyGroup  string                       `xorm:"index(repo_concurrency)"` // the evaluated concurrency group, empty if the workflow doesn't define one
+	ConcurrencyCancel bool                         // whether the runs in progress in the same concurrency group are cancelled
 	Version           int                          `xorm:"version default 0"` // Status could be updated concomitantly, so an optimistic lock is needed
+	// whether the workflow_run event of the completion of the run has been emitted, it is reset when the run is rerun
+	IsCompletionNotified bool `xorm:"NOT NULL DEFAULT false"`
 	// Started and Stopped is used for recording last run time, if rerun happened, they will be reset to 0
 	Started timeutil.TimeStamp
 	Stopped timeutil.TimeStamp
@@ -156,6 +161,45 @@ func (run *ActionRun) GetPullRequestEventPayload() (*api.PullRequestPayload, err
 	return nil, fmt.Errorf("event %s is not a pull request event", run.Event)
 }
 
+func (run *ActionRun) GetWorkflowRunEventPayload() (*api.WorkflowRunPayload, error) {
+	if run.Event == webhook_module.HookEventWorkflowRun {
+		var payload api.WorkflowRunPayload
+		if err := json.Unmarshal([]byte(run.EventPayload), &payload); err != nil {
+			return nil, err
+		}
+		return &payload, nil
+	}
+	return nil, fmt.Errorf("event %s is not a workflow run event", run.Event)
+}
+
+// GetWorkflowName returns the name of the workflow of the run, or the name of its file if the workflow doesn't define one.
+func (run *ActionRun) GetWorkflowName(ctx context.Context) (string, error) {
+	var job ActionRunJob
+	has, err := db.GetEngine(ctx).Where("run_id=? AND parent_job_id=0", run.ID).OrderBy("id").Get(&job)
+	if err != nil {
+		return "", err
+	}
+	if has {
+		if workflows, err := jobparser.Parse(job.WorkflowPayload); err == nil && len(workflows) == 1 && workflows[0].Name != "" {
+			return workflows[0].Name, nil
+		}
+	}
+	return run.WorkflowID, nil
+}
+
+// SetRunCompletionNotified marks the completion of a run as notified,
+// it returns false if it has already been notified since the run was done.
+func SetRunCompletionNotified(ctx context.Context, run *ActionRun) (bool, error) {
+	n, err := db.GetEngine(ctx).Table("action_run").
+		Where(builder.Eq{"id": run.ID, "is_completion_notified": false}).
+		Update(map[string]any{"is_completion_notified": true})
+	if err != nil {
+		return false, err
+	}
+	run.IsCompletionNotified = true
+	return n == 1, nil
+}
+
 func updateRepoRunsNumbers(ctx context.Context, repo *repo_model.Repository) error {
 	_, err := db.GetEngine(ctx).ID(repo.ID).
 		SetExpr("num_action_runs",
@@ -189,7 +233,7 @@ func CancelPreviousJobs(ctx context.Context, repoID int64, ref, workflowID strin
 		Ref:          ref,
 		WorkflowID:   workflowID,
 		TriggerEvent: event,
-		Status:       []Status{StatusRunning, StatusWaiting, StatusBlocked},
+		Status:       []Status{StatusRunning, StatusWaiting, StatusBlocked, StatusWaitingForApproval},
 	})
 	if err != nil {
 		return err
@@ -210,47 +254,60 @@ func CancelPreviousJobs(ctx context.Context, repoID int64, ref, workflowID strin
 			return err
 		}
 
-		// Iterate over each job and attempt to cancel it.
-		for _, job := range jobs {
-			// Skip jobs that are already in a terminal state (completed, cancelled, etc.).
-			status := job.Status
-			if status.IsDone() {
-				continue
-			}
+		if err := CancelJobs(ctx, jobs); err != nil {
+			return err
+		}
+	}
 
-			// If the job has no associated task (probably an error), set its status to 'Cancelled' and stop it.
-			if job.TaskID == 0 {
-				job.Status = StatusCancelled
-				job.Stopped = timeutil.TimeStampNow()
+	// Return nil to indicate successful cancellation of all running and waiting jobs.
+	return nil
+}
 
-				// Update the job's status and stopped time in the database.
-				n, err := UpdateRunJob(ctx, job, builder.Eq{"task_id": 0}, "status", "stopped")
-				if err != nil {
-					return err
-				}
+// CancelJobs cancels the given jobs, the jobs already in a terminal state are left untouched.
+func CancelJobs(ctx context.Context, jobs []*ActionRunJob) error {
+	// Iterate over each job and attempt to cancel it.
+	for _, job := range jobs {
+		// Skip jobs that are already in a terminal state (completed, cancelled, etc.).
+		status := job.Status
+		if status.IsDone() {
+			continue
+		}
 
-				// If the update affected 0 rows, it means the job has changed in the meantime, so we need to try again.
-				if n == 0 {
-					return fmt.Errorf("job has changed, try again")
-				}
+		// If the job has no associated task (probably an error), set its status to 'Cancelled' and stop it.
+		if job.TaskID == 0 {
+			job.Status = StatusCancelled
+			job.Stopped = timeutil.TimeStampNow()
 
-				// Continue with the next job.
-				continue
+			// Update the job's status and stopped time in the database.
+			n, err := UpdateRunJob(ctx, job, builder.Eq{"task_id": 0}, "status", "stopped")
+			if err != nil {
+				return err
 			}
 
-			// If the job has an associated task, try to stop the task, effectively cancelling the job.
-			if err := StopTask(ctx, job.TaskID, StatusCancelled); err != nil {
-				return err
+			// If the update affected 0 rows, it means the job has changed in the meantime, so we need to try again.
+			if n == 0 {
+				return fmt.Errorf("job has changed, try again")
 			}
+
+			// Continue with the next job.
+			continue
 		}
-	}
 
-	// Return nil to indicate successful cancellation of all running and waiting jobs.
+		// If the job has an associated task, try to stop the task, effectively cancelling the job.
+		if err := StopTask(ctx, job.TaskID, StatusCancelled); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-// InsertRun inserts a run
-func InsertRun(ctx context.Context, run *ActionRun, jobs []*jobparser.SingleWorkflow) error {
+// InsertRun inserts a run.
+// The jobs having a job-level concurrency, given in jobsRawConcurrency by job ID, are inserted blocked:
+// their concurrency is evaluated by the job emitter when they are about to run.
+// So are the jobs calling a reusable workflow, which are expanded by the job emitter,
+// and the jobs targeting an environment, given in jobsRawEnvironment by job ID, whose deployment is created by the job emitter.
+// The jobs granted `id-token: write` are given in jobsIDTokenPermission by job ID.
+func InsertRun(ctx context.Context, run *ActionRun, jobs []*jobparser.SingleWorkflow, jobsRawConcurrency, jobsRawEnvironment map[string]string, jobsIDTokenPermission map[string]bool) error {
 	ctx, commiter, err := db.TxContext(ctx)
 	if err != nil {
 		return err
@@ -279,48 +336,102 @@ func InsertRun(ctx context.Context, run *ActionRun, jobs []*jobparser.SingleWork
 		return err
 	}
 
+	runJobs, hasWaiting, err := newRunJobs(run, nil, jobs, jobsRawConcurrency, jobsRawEnvironment, jobsIDTokenPermission)
+	if err != nil {
+		return err
+	}
+	if err := db.Insert(ctx, runJobs); err != nil {
+		return err
+	}
+
+	// if there is a job in the waiting status, increase tasks version.
+	if hasWaiting {
+		if err := IncreaseTaskVersion(ctx, run.OwnerID, run.RepoID); err != nil {
+			return err
+		}
+	}
+
+	return commiter.Commit()
+}
+
+// InsertChildJobs inserts the jobs of the reusable workflow called by a job as the children of this job.
+// The job IDs and the needs of the children are prefixed by the job ID of their parent.
+func InsertChildJobs(ctx context.Context, parent *ActionRunJob, jobs []*jobparser.SingleWorkflow, jobsRawConcurrency, jobsRawEnvironment map[string]string, jobsIDTokenPermission map[string]bool) ([]*ActionRunJob, error) {
+	if err := parent.LoadRun(ctx); err != nil {
+		return nil, err
+	}
+
+	children, hasWaiting, err := newRunJobs(parent.Run, parent, jobs, jobsRawConcurrency, jobsRawEnvironment, jobsIDTokenPermission)
+	if err != nil {
+		return nil, err
+	}
+	for _, child := range children {
+		if err := db.Insert(ctx, child); err != nil {
+			return nil, err
+		}
+	}
+
+	if hasWaiting {
+		if err := IncreaseTaskVersion(ctx, parent.OwnerID, parent.RepoID); err != nil {
+			return nil, err
+		}
+	}
+	return children, nil
+}
+
+// newRunJobs returns the jobs to insert for the single workflows of a run, or of the workflow called by the parent job.
+// The jobs with needs, with a job-level concurrency, calling a reusable workflow or targeting an environment are blocked,
+// they are released by the job emitter.
+func newRunJobs(run *ActionRun, parent *ActionRunJob, jobs []*jobparser.SingleWorkflow, jobsRawConcurrency, jobsRawEnvironment map[string]string, jobsIDTokenPermission map[string]bool) ([]*ActionRunJob, bool, error) {
 	runJobs := make([]*ActionRunJob, 0, len(jobs))
 	var hasWaiting bool
 	for _, v := range jobs {
 		id, job := v.Job()
 		needs := job.Needs()
 		if err := v.SetJob(id, job.EraseNeeds()); err != nil {
-			return err
+			return nil, false, err
 		}
 		payload, _ := v.Marshal()
 		status := StatusWaiting
-		if len(needs) > 0 || run.NeedApproval {
+		rawConcurrency := jobsRawConcurrency[id]
+		rawEnvironment := jobsRawEnvironment[id]
+		idTokenPermission := jobsIDTokenPermission[id]
+		if len(needs) > 0 || run.NeedApproval || run.Status.IsBlocked() || rawConcurrency != "" || job.Uses != "" || rawEnvironment != "" {
 			status = StatusBlocked
 		} else {
 			hasWaiting = true
 		}
-		job.Name, _ = util.SplitStringAtByteN(job.Name, 255)
+		name := job.Name
+		var parentJobID int64
+		if parent != nil {
+			name = parent.Name + " / " + name
+			id = parent.JobID + "/" + id
+			for i, need := range needs {
+				needs[i] = parent.JobID + "/" + need
+			}
+			parentJobID = parent.ID
+		}
+		name, _ = util.SplitStringAtByteN(name, 255)
 		runJobs = append(runJobs, &ActionRunJob{
 			RunID:             run.ID,
 			RepoID:            run.RepoID,
 			OwnerID:           run.OwnerID,
 			CommitSHA:         run.CommitSHA,
 			IsForkPullRequest: run.IsForkPullRequest,
-			Name:              job.Name,
+			Name:              name,
 			WorkflowPayload:   payload,
 			JobID:             id,
 			Needs:             needs,
 			RunsOn:            job.RunsOn(),
 			Status:            status,
+			RawConcurrency:    rawConcurrency,
+			RawEnvironment:    rawEnvironment,
+			ParentJobID:       parentJobID,
+			CalledWorkflow:    job.Uses,
+			IDTokenPermission: idTokenPermission,
 		})
 	}
-	if err := db.Insert(ctx, runJobs); err != nil {
-		return err
-	}
-
-	// if there is a job in the waiting status, increase tasks version.
-	if hasWaiting {
-		if err := IncreaseTaskVersion(ctx, run.OwnerID, run.RepoID); err != nil {
-			return err
-		}
-	}
-
-	return commiter.Commit()
+	return runJobs, hasWaiting, nil
 }
 
 func GetLatestRun(ctx context.Context, repoID int64) (*ActionRun, error) {
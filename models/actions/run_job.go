@@ -21,7 +21,7 @@ type ActionRunJob struct {
 	ID                int64
 	RunID             int64      `xorm:"index"`
 	Run               *ActionRun `xorm:"-"`
-	RepoID            int64      `xorm:"index"`
+	RepoID            int64      `xorm:"index index(repo_concurrency)"`
 	OwnerID           int64      `xorm:"index"`
 	CommitSHA         string     `xorm:"index"`
 	IsForkPullRequest bool
@@ -37,6 +37,26 @@ type ActionRunJob struct {
 	Stopped           timeutil.TimeStamp
 	Created           timeutil.TimeStamp `xorm:"created"`
 	Updated           timeutil.TimeStamp `xorm:"updated index"`
+
+	RawConcurrency         string `xorm:"TEXT"` // the job-level `concurrency` before its expressions are evaluated
+	IsConcurrencyEvaluated bool   // the concurrency is evaluated when the job is about to leave the blocked status
+	ConcurrencyGroup       string `xorm:"index(repo_concurrency)"`
+	ConcurrencyCancel      bool
+
+	// A job calling a reusable workflow doesn't run on a runner: when it's about to run,
+	// the jobs of the called workflow are inserted as its children.
+	ParentJobID        int64             `xorm:"index"`
+	CalledWorkflow     string            `xorm:"VARCHAR(255)"` // the `uses` of a job calling a reusable workflow
+	CallOutputs        map[string]string `xorm:"JSON TEXT"`    // the outputs of the called workflow before their expressions are evaluated
+	CallSecrets        map[string]string `xorm:"JSON TEXT"`    // the secrets passed to the called workflow before their expressions are evaluated
+	CallInheritSecrets bool              // the called workflow inherits the secrets of the calling job
+
+	// A job targeting an environment waits for the protection rules of the environment before running.
+	RawEnvironment string `xorm:"TEXT"` // the `environment` before its expressions are evaluated
+	EnvironmentID  int64  `xorm:"index"`
+	DeploymentID   int64  // the latest deployment of the job
+
+	IDTokenPermission bool `xorm:"NOT NULL DEFAULT false"` // the job is granted `id-token: write` and may request OpenID Connect ID tokens
 }
 
 func init() {
@@ -126,6 +146,18 @@ func UpdateRunJob(ctx context.Context, job *ActionRunJob, cond builder.Cond, col
 		}
 	}
 
+	if job.DeploymentID > 0 {
+		if err := updateDeploymentStatus(ctx, job); err != nil {
+			return 0, fmt.Errorf("update deployment %d: %w", job.DeploymentID, err)
+		}
+	}
+
+	if job.ParentJobID > 0 {
+		if err := updateParentJobStatus(ctx, job.ParentJobID); err != nil {
+			return 0, fmt.Errorf("update parent job %d: %w", job.ParentJobID, err)
+		}
+	}
+
 	{
 		// Other goroutines may aggregate the status of the run and update it too.
 		// So we need load the run and its jobs before updating the run.
@@ -137,7 +169,7 @@ func UpdateRunJob(ctx context.Context, job *ActionRunJob, cond builder.Cond, col
 		if err != nil {
 			return 0, err
 		}
-		run.Status = aggregateJobStatus(jobs)
+		run.Status = AggregateJobStatus(jobs)
 		if run.Started.IsZero() && run.Status.IsRunning() {
 			run.Started = timeutil.TimeStampNow()
 		}
@@ -152,9 +184,74 @@ func UpdateRunJob(ctx context.Context, job *ActionRunJob, cond builder.Cond, col
 	return affected, nil
 }
 
-func aggregateJobStatus(jobs []*ActionRunJob) Status {
+// IsCallingWorkflow returns whether the job calls a reusable workflow instead of running on a runner.
+func (job *ActionRunJob) IsCallingWorkflow() bool {
+	return job.CalledWorkflow != ""
+}
+
+// GetChildJobs returns the jobs of the reusable workflow called by a job.
+func GetChildJobs(ctx context.Context, parentJobID int64) ([]*ActionRunJob, error) {
+	var jobs []*ActionRunJob
+	if err := db.GetEngine(ctx).Where("parent_job_id=?", parentJobID).OrderBy("id").Find(&jobs); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// DeleteChildJobs deletes the jobs of the reusable workflow called by a job, and the jobs of the workflows they call.
+func DeleteChildJobs(ctx context.Context, parentJobID int64) error {
+	children, err := GetChildJobs(ctx, parentJobID)
+	if err != nil {
+		return err
+	}
+	for _, child := range children {
+		if child.IsCallingWorkflow() {
+			if err := DeleteChildJobs(ctx, child.ID); err != nil {
+				return err
+			}
+		}
+	}
+	_, err = db.GetEngine(ctx).Where("parent_job_id=?", parentJobID).Delete(new(ActionRunJob))
+	return err
+}
+
+// updateParentJobStatus aggregates the status of a job calling a reusable workflow from the status of its children.
+func updateParentJobStatus(ctx context.Context, parentJobID int64) error {
+	parent, err := GetRunJobByID(ctx, parentJobID)
+	if err != nil {
+		return err
+	}
+	if parent.Status.IsDone() {
+		// e.g. the parent job has been cancelled with its children
+		return nil
+	}
+	children, err := GetChildJobs(ctx, parentJobID)
+	if err != nil {
+		return err
+	}
+
+	status := AggregateJobStatus(children)
+	if !status.IsDone() {
+		// the parent job is running as long as its children are not done
+		status = StatusRunning
+	}
+	if status == parent.Status {
+		return nil
+	}
+	parent.Status = status
+	if parent.Stopped.IsZero() && status.IsDone() {
+		parent.Stopped = timeutil.TimeStampNow()
+	}
+	_, err = UpdateRunJob(ctx, parent, builder.Eq{"status": StatusRunning}, "status", "stopped")
+	return err
+}
+
+func AggregateJobStatus(jobs []*ActionRunJob) Status {
 	allDone := true
 	allWaiting := true
+	allBlocked := len(jobs) > 0
+	hasWaitingForApproval := false
+	allHeld := true // all the jobs are done or can't run until a deployment is approved
 	hasFailure := false
 	for _, job := range jobs {
 		if !job.Status.IsDone() {
@@ -163,6 +260,14 @@ func aggregateJobStatus(jobs []*ActionRunJob) Status {
 		if job.Status != StatusWaiting && !job.Status.IsDone() {
 			allWaiting = false
 		}
+		if job.Status != StatusBlocked {
+			allBlocked = false
+		}
+		if job.Status == StatusWaitingForApproval {
+			hasWaitingForApproval = true
+		} else if job.Status != StatusBlocked && !job.Status.IsDone() {
+			allHeld = false
+		}
 		if job.Status == StatusFailure || job.Status == StatusCancelled {
 			hasFailure = true
 		}
@@ -176,5 +281,13 @@ func aggregateJobStatus(jobs []*ActionRunJob) Status {
 	if allWaiting {
 		return StatusWaiting
 	}
+	if hasWaitingForApproval && allHeld {
+		// the run can't go on until a deployment is approved
+		return StatusWaitingForApproval
+	}
+	if allBlocked {
+		// e.g. the run waits for an approval or for another run of its concurrency group
+		return StatusBlocked
+	}
 	return StatusRunning
 }
@@ -0,0 +1,72 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package actions
+
+import (
+	"testing"
+
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/unittest"
+
+	"github.com/nektos/act/pkg/jobparser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInsertChildJobs(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+	ctx := db.DefaultContext
+
+	run := &ActionRun{RepoID: 1, OwnerID: 2, Index: 1001, Status: StatusRunning}
+	require.NoError(t, db.Insert(ctx, run))
+	parent := &ActionRunJob{RunID: run.ID, RepoID: 1, OwnerID: 2, JobID: "call", Name: "call", CalledWorkflow: "./.forgejo/workflows/build.yml", Status: StatusRunning}
+	require.NoError(t, db.Insert(ctx, parent))
+
+	workflows, err := jobparser.Parse([]byte(`
+on: workflow_call
+jobs:
+  build:
+    runs-on: docker
+    steps:
+      - run: make
+  test:
+    needs: build
+    runs-on: docker
+    steps:
+      - run: make test
+`))
+	require.NoError(t, err)
+	children, err := InsertChildJobs(ctx, parent, workflows, nil, nil, nil)
+	require.NoError(t, err)
+	require.Len(t, children, 2)
+
+	build, test := children[0], children[1]
+	assert.Equal(t, parent.ID, build.ParentJobID)
+	assert.Equal(t, "call/build", build.JobID)
+	assert.Equal(t, "call / build", build.Name)
+	assert.Equal(t, StatusWaiting, build.Status)
+	assert.Equal(t, []string{"call/build"}, test.Needs)
+	assert.Equal(t, StatusBlocked, test.Status)
+
+	// the parent job is done once all its children are done
+	build.Status = StatusSuccess
+	_, err = UpdateRunJob(ctx, build, nil, "status")
+	require.NoError(t, err)
+	parent, err = GetRunJobByID(ctx, parent.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusRunning, parent.Status)
+
+	test.Status = StatusFailure
+	_, err = UpdateRunJob(ctx, test, nil, "status")
+	require.NoError(t, err)
+	parent, err = GetRunJobByID(ctx, parent.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusFailure, parent.Status)
+	assert.False(t, parent.Stopped.IsZero())
+
+	require.NoError(t, DeleteChildJobs(ctx, parent.ID))
+	children, err = GetChildJobs(ctx, parent.ID)
+	require.NoError(t, err)
+	assert.Empty(t, children)
+}
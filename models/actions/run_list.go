@@ -70,6 +70,7 @@ type FindRunOptions struct {
 	TriggerUserID int64
 	TriggerEvent  webhook_module.HookEventType
 	Approved      bool // not util.OptionalBool, it works only when it's true
+	NeedApproval  bool // not util.OptionalBool, it works only when it's true
 	Status        []Status
 }
 
@@ -90,6 +91,9 @@ func (opts FindRunOptions) ToConds() builder.Cond {
 	if opts.Approved {
 		cond = cond.And(builder.Gt{"approved_by": 0})
 	}
+	if opts.NeedApproval {
+		cond = cond.And(builder.Eq{"need_approval": true})
+	}
 	if len(opts.Status) > 0 {
 		cond = cond.And(builder.In("status", opts.Status))
 	}
@@ -113,7 +117,7 @@ type StatusInfo struct {
 
 // GetStatusInfoList returns a slice of StatusInfo
 func GetStatusInfoList(ctx context.Context) []StatusInfo {
-	// same as those in aggregateJobStatus
+	// same as those in AggregateJobStatus
 	allStatus := []Status{StatusSuccess, StatusFailure, StatusWaiting, StatusRunning}
 	statusInfoList := make([]StatusInfo, 0, 4)
 	for _, s := range allStatus {
@@ -36,6 +36,8 @@ type ActionRunner struct {
 	Description string                 `xorm:"TEXT"`
 	Base        int                    // 0 native 1 docker 2 virtual machine
 	RepoRange   string                 // glob match which repositories could use this runner
+	GroupID     int64                  `xorm:"index NOT NULL DEFAULT 0"` // the group restricting the jobs of the runner, 0 if it doesn't belong to a group
+	Group       *ActionRunnerGroup     `xorm:"-"`
 
 	Token     string `xorm:"-"`
 	TokenHash string `xorm:"UNIQUE"` // sha256 of token
@@ -143,6 +145,16 @@ func (r *ActionRunner) LoadAttributes(ctx context.Context) error {
 			r.Repo = &repo
 		}
 	}
+	if r.GroupID > 0 {
+		var group ActionRunnerGroup
+		has, err := db.GetEngine(ctx).ID(r.GroupID).Get(&group)
+		if err != nil {
+			return err
+		}
+		if has {
+			r.Group = &group
+		}
+	}
 	return nil
 }
 
@@ -163,6 +175,7 @@ type FindRunnerOptions struct {
 	Filter        string
 	IsOnline      optional.Option[bool]
 	WithAvailable bool // not only runners belong to, but also runners can be used
+	GroupID       int64
 }
 
 func (opts FindRunnerOptions) ToConds() builder.Cond {
@@ -184,6 +197,10 @@ func (opts FindRunnerOptions) ToConds() builder.Cond {
 		cond = cond.And(c)
 	}
 
+	if opts.GroupID > 0 {
+		cond = cond.And(builder.Eq{"group_id": opts.GroupID})
+	}
+
 	if opts.Filter != "" {
 		cond = cond.And(builder.Like{"name", opts.Filter})
 	}
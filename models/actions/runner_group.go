@@ -0,0 +1,149 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package actions
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"code.gitea.io/gitea/models/db"
+	repo_model "code.gitea.io/gitea/models/repo"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/timeutil"
+	"code.gitea.io/gitea/modules/util"
+
+	"github.com/gobwas/glob"
+	"xorm.io/builder"
+)
+
+// ActionRunnerGroup is a named group of the instance runners or of the runners of an owner.
+// The runners of a group only run the jobs of the repositories and the workflows allowed by the group.
+type ActionRunnerGroup struct {
+	ID          int64
+	OwnerID     int64  `xorm:"UNIQUE(owner_name) NOT NULL DEFAULT 0"` // 0 for the groups of the instance runners
+	Name        string `xorm:"VARCHAR(255) NOT NULL"`
+	LowerName   string `xorm:"VARCHAR(255) UNIQUE(owner_name) NOT NULL"` // the names of the groups are case-insensitive
+	Description string `xorm:"TEXT"`
+
+	AllowedOwnerIDs  []int64  `xorm:"JSON TEXT"` // the owners whose repositories can use the runners, only for the groups of the instance runners, all owners are allowed if empty
+	AllowedRepoIDs   []int64  `xorm:"JSON TEXT"` // the repositories which can use the runners, all repositories are allowed if empty
+	AllowedWorkflows []string `xorm:"JSON TEXT"` // the glob patterns of the workflow files which can use the runners, all workflows are allowed if empty
+
+	Created timeutil.TimeStamp `xorm:"created"`
+	Updated timeutil.TimeStamp `xorm:"updated"`
+}
+
+func init() {
+	db.RegisterModel(new(ActionRunnerGroup))
+}
+
+// IsRepoAllowed returns whether the jobs of a repository can run on the runners of the group.
+func (g *ActionRunnerGroup) IsRepoAllowed(ownerID, repoID int64) bool {
+	if len(g.AllowedOwnerIDs) > 0 && !slices.Contains(g.AllowedOwnerIDs, ownerID) {
+		return false
+	}
+	return len(g.AllowedRepoIDs) == 0 || slices.Contains(g.AllowedRepoIDs, repoID)
+}
+
+// IsWorkflowAllowed returns whether the jobs of a workflow, identified by the name of its file, can run on the runners of the group.
+func (g *ActionRunnerGroup) IsWorkflowAllowed(workflowID string) bool {
+	if len(g.AllowedWorkflows) == 0 {
+		return true
+	}
+	for _, pattern := range g.AllowedWorkflows {
+		gl, err := glob.Compile(pattern)
+		if err != nil {
+			log.Warn("Invalid workflow pattern %q of runner group %d: %v", pattern, g.ID, err)
+			continue
+		}
+		if gl.Match(workflowID) {
+			return true
+		}
+	}
+	return false
+}
+
+// runCond returns the condition on the runs whose jobs can run on the runners of the group,
+// the workflows are checked by IsWorkflowAllowed since they are matched by glob patterns.
+func (g *ActionRunnerGroup) runCond() builder.Cond {
+	cond := builder.NewCond()
+	if len(g.AllowedOwnerIDs) > 0 {
+		cond = cond.And(builder.In("owner_id", g.AllowedOwnerIDs))
+	}
+	if len(g.AllowedRepoIDs) > 0 {
+		cond = cond.And(builder.In("repo_id", g.AllowedRepoIDs))
+	}
+	return cond
+}
+
+// GetAllowedRepoNames returns the full names of the repositories allowed by the group.
+func (g *ActionRunnerGroup) GetAllowedRepoNames(ctx context.Context) ([]string, error) {
+	repos, err := repo_model.GetRepositoriesMapByIDs(ctx, g.AllowedRepoIDs)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(g.AllowedRepoIDs))
+	for _, id := range g.AllowedRepoIDs {
+		if repo, ok := repos[id]; ok {
+			names = append(names, repo.FullName())
+		}
+	}
+	return names, nil
+}
+
+type FindRunnerGroupsOptions struct {
+	db.ListOptions
+	OwnerID int64 // the groups of the instance runners if 0
+}
+
+func (opts FindRunnerGroupsOptions) ToConds() builder.Cond {
+	return builder.Eq{"owner_id": opts.OwnerID}
+}
+
+func (opts FindRunnerGroupsOptions) ToOrders() string {
+	return "lower_name ASC"
+}
+
+func GetRunnerGroupByID(ctx context.Context, id int64) (*ActionRunnerGroup, error) {
+	var g ActionRunnerGroup
+	has, err := db.GetEngine(ctx).ID(id).Get(&g)
+	if err != nil {
+		return nil, err
+	} else if !has {
+		return nil, fmt.Errorf("runner group with id %d: %w", id, util.ErrNotExist)
+	}
+	return &g, nil
+}
+
+// InsertRunnerGroup inserts a runner group, its name must not be used by another group of the owner.
+func InsertRunnerGroup(ctx context.Context, g *ActionRunnerGroup) error {
+	return db.WithTx(ctx, func(ctx context.Context) error {
+		g.LowerName = strings.ToLower(g.Name)
+		exist, err := db.GetEngine(ctx).Where("owner_id=? AND lower_name=?", g.OwnerID, g.LowerName).Exist(new(ActionRunnerGroup))
+		if err != nil {
+			return err
+		} else if exist {
+			return fmt.Errorf("runner group %q of owner %d: %w", g.Name, g.OwnerID, util.ErrAlreadyExist)
+		}
+		return db.Insert(ctx, g)
+	})
+}
+
+func UpdateRunnerGroup(ctx context.Context, g *ActionRunnerGroup, cols ...string) error {
+	_, err := db.GetEngine(ctx).ID(g.ID).Cols(cols...).Update(g)
+	return err
+}
+
+// DeleteRunnerGroup deletes a runner group, its runners don't belong to a group anymore.
+func DeleteRunnerGroup(ctx context.Context, g *ActionRunnerGroup) error {
+	return db.WithTx(ctx, func(ctx context.Context) error {
+		if _, err := db.GetEngine(ctx).Table("action_runner").Where("group_id=?", g.ID).Update(map[string]any{"group_id": 0}); err != nil {
+			return err
+		}
+		_, err := db.DeleteByID[ActionRunnerGroup](ctx, g.ID)
+		return err
+	})
+}
@@ -6,6 +6,7 @@ package actions
 import (
 	"context"
 	"crypto/subtle"
+	"errors"
 	"fmt"
 	"time"
 
@@ -232,6 +233,17 @@ func CreateTaskForRunner(ctx context.Context, runner *ActionRunner) (*ActionTask
 			Join("INNER", "repo_unit", "`repository`.id = `repo_unit`.repo_id").
 			Where(builder.Eq{"`repository`.owner_id": runner.OwnerID, "`repo_unit`.type": unit.TypeActions}))
 	}
+	var group *ActionRunnerGroup
+	if runner.GroupID > 0 {
+		group, err = GetRunnerGroupByID(ctx, runner.GroupID)
+		if err != nil && !errors.Is(err, util.ErrNotExist) {
+			return nil, false, err
+		}
+		if group != nil {
+			// the runner only runs the jobs of the repositories allowed by its group
+			jobCond = jobCond.And(group.runCond())
+		}
+	}
 	if jobCond.IsValid() {
 		jobCond = builder.In("run_id", builder.Select("id").From("action_run").Where(jobCond))
 	}
@@ -245,10 +257,25 @@ func CreateTaskForRunner(ctx context.Context, runner *ActionRunner) (*ActionTask
 	var job *ActionRunJob
 	log.Trace("runner labels: %v", runner.AgentLabels)
 	for _, v := range jobs {
-		if isSubset(runner.AgentLabels, v.RunsOn) {
-			job = v
-			break
+		if v.IsCallingWorkflow() {
+			// a job calling a reusable workflow is expanded by the job emitter, it never runs on a runner
+			continue
+		}
+		if !isSubset(runner.AgentLabels, v.RunsOn) {
+			continue
+		}
+		if err := v.LoadRun(ctx); err != nil {
+			return nil, false, err
+		}
+		if v.Run.NeedApproval {
+			// the jobs of a run from an untrusted contributor never run before a maintainer approves it
+			continue
+		}
+		if group != nil && !group.IsWorkflowAllowed(v.Run.WorkflowID) {
+			continue
 		}
+		job = v
+		break
 	}
 	if job == nil {
 		return nil, false, nil
@@ -16,13 +16,14 @@ import (
 )
 
 type ActionVariable struct {
-	ID          int64              `xorm:"pk autoincr"`
-	OwnerID     int64              `xorm:"UNIQUE(owner_repo_name)"`
-	RepoID      int64              `xorm:"INDEX UNIQUE(owner_repo_name)"`
-	Name        string             `xorm:"UNIQUE(owner_repo_name) NOT NULL"`
-	Data        string             `xorm:"LONGTEXT NOT NULL"`
-	CreatedUnix timeutil.TimeStamp `xorm:"created NOT NULL"`
-	UpdatedUnix timeutil.TimeStamp `xorm:"updated"`
+	ID            int64              `xorm:"pk autoincr"`
+	OwnerID       int64              `xorm:"UNIQUE(owner_repo_name)"`
+	RepoID        int64              `xorm:"INDEX UNIQUE(owner_repo_name)"`
+	EnvironmentID int64              `xorm:"INDEX UNIQUE(owner_repo_name) NOT NULL DEFAULT 0"` // a variable of an environment of the repository
+	Name          string             `xorm:"UNIQUE(owner_repo_name) NOT NULL"`
+	Data          string             `xorm:"LONGTEXT NOT NULL"`
+	CreatedUnix   timeutil.TimeStamp `xorm:"created NOT NULL"`
+	UpdatedUnix   timeutil.TimeStamp `xorm:"updated"`
 }
 
 func init() {
@@ -33,15 +34,19 @@ func (v *ActionVariable) Validate() error {
 	if v.OwnerID != 0 && v.RepoID != 0 {
 		return errors.New("a variable should not be bound to an owner and a repository at the same time")
 	}
+	if v.EnvironmentID != 0 && v.RepoID == 0 {
+		return errors.New("a variable of an environment should be bound to a repository")
+	}
 	return nil
 }
 
-func InsertVariable(ctx context.Context, ownerID, repoID int64, name, data string) (*ActionVariable, error) {
+func InsertVariable(ctx context.Context, ownerID, repoID, environmentID int64, name, data string) (*ActionVariable, error) {
 	variable := &ActionVariable{
-		OwnerID: ownerID,
-		RepoID:  repoID,
-		Name:    strings.ToUpper(name),
-		Data:    data,
+		OwnerID:       ownerID,
+		RepoID:        repoID,
+		EnvironmentID: environmentID,
+		Name:          strings.ToUpper(name),
+		Data:          data,
 	}
 	if err := variable.Validate(); err != nil {
 		return variable, err
@@ -51,9 +56,10 @@ func InsertVariable(ctx context.Context, ownerID, repoID int64, name, data strin
 
 type FindVariablesOpts struct {
 	db.ListOptions
-	OwnerID int64
-	RepoID  int64
-	Name    string
+	OwnerID       int64
+	RepoID        int64
+	EnvironmentID int64
+	Name          string
 }
 
 func (opts FindVariablesOpts) ToConds() builder.Cond {
@@ -62,6 +68,7 @@ func (opts FindVariablesOpts) ToConds() builder.Cond {
 	// there is no need to check for null values for `owner_id` and `repo_id`
 	cond = cond.And(builder.Eq{"owner_id": opts.OwnerID})
 	cond = cond.And(builder.Eq{"repo_id": opts.RepoID})
+	cond = cond.And(builder.Eq{"environment_id": opts.EnvironmentID})
 
 	if opts.Name != "" {
 		cond = cond.And(builder.Eq{"name": strings.ToUpper(opts.Name)})
@@ -125,3 +132,28 @@ func GetVariablesOfRun(ctx context.Context, run *ActionRun) (map[string]string,
 
 	return variables, nil
 }
+
+// GetVariablesOfJob returns the variables of the run of a job,
+// and the variables of the environment targeted by the job which take precedence.
+func GetVariablesOfJob(ctx context.Context, job *ActionRunJob) (map[string]string, error) {
+	if err := job.LoadRun(ctx); err != nil {
+		return nil, err
+	}
+	variables, err := GetVariablesOfRun(ctx, job.Run)
+	if err != nil {
+		return nil, err
+	}
+	if job.EnvironmentID == 0 {
+		return variables, nil
+	}
+
+	environmentVariables, err := db.Find[ActionVariable](ctx, FindVariablesOpts{RepoID: job.RepoID, EnvironmentID: job.EnvironmentID})
+	if err != nil {
+		log.Error("find variables of environment: %d, error: %v", job.EnvironmentID, err)
+		return nil, err
+	}
+	for _, v := range environmentVariables {
+		variables[v.Name] = v.Data
+	}
+	return variables, nil
+}
@@ -74,6 +74,34 @@ var migrations = []*Migration{
 	NewMigration("Add `normalized_federated_uri` column to `user` table", AddNormalizedFederatedURIToUser),
 	// v18 -> v19
 	NewMigration("Create the `following_repo` table", CreateFollowingRepoTable),
+	// v19 -> v20
+	NewMigration("Add concurrency columns to the `action_run` and `action_run_job` tables", AddConcurrencyToActionRunAndJob),
+	// v20 -> v21
+	NewMigration("Add reusable workflow columns to the `action_run_job` table", AddReusableWorkflowToActionRunJob),
+	// v21 -> v22
+	NewMigration("Add the Actions environments and deployments", AddActionsEnvironments),
+	// v22 -> v23
+	NewMigration("Add the Actions cache", AddActionsCache),
+	// v23 -> v24
+	NewMigration("Add `is_completion_notified` to the `action_run` table", AddIsCompletionNotifiedToActionRun),
+	// v24 -> v25
+	NewMigration("Add the Actions runner groups", AddActionsRunnerGroups),
+	// v25 -> v26
+	NewMigration("Add the `package_proxy` table", AddPackageProxy),
+	// v26 -> v27
+	NewMigration("Add the `package_virtual_registry` table", AddPackageVirtualRegistry),
+	// v27 -> v28
+	NewMigration("Add the Terraform state tables", AddTerraformState),
+	// v28 -> v29
+	NewMigration("Add the quota tables", AddQuotaTables),
+	// v29 -> v30
+	NewMigration("Add the package download statistics tables", AddPackageDownloadStatistics),
+	// v30 -> v31
+	NewMigration("Add `attempt` to the `hook_task` table and `failing_since` to the `webhook` table", AddWebhookRetries),
+	// v31 -> v32
+	NewMigration("Add `id_token_permission` to the `action_run_job` table", AddIDTokenPermissionToActionRunJob),
+	// v32 -> v33
+	NewMigration("Add `delivery_uuid` to the `hook_task` table", AddDeliveryUUIDToHookTask),
 }
 
 // GetCurrentDBVersion returns the current Forgejo database version.
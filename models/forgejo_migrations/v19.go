@@ -0,0 +1,29 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package forgejo_migrations //nolint:revive
+
+import "xorm.io/xorm"
+
+func AddConcurrencyToActionRunAndJob(x *xorm.Engine) error {
+	type ActionRun struct {
+		ID                int64
+		RepoID            int64  `xorm:"index unique(repo_index) index(repo_concurrency)"`
+		RawConcurrency    string `xorm:"TEXT"`
+		ConcurrencyGroup  string `xorm:"index(repo_concurrency)"`
+		ConcurrencyCancel bool
+	}
+	if err := x.Sync(new(ActionRun)); err != nil {
+		return err
+	}
+
+	type ActionRunJob struct {
+		ID                     int64
+		RepoID                 int64  `xorm:"index index(repo_concurrency)"`
+		RawConcurrency         string `xorm:"TEXT"`
+		IsConcurrencyEvaluated bool
+		ConcurrencyGroup       string `xorm:"index(repo_concurrency)"`
+		ConcurrencyCancel      bool
+	}
+	return x.Sync(new(ActionRunJob))
+}
@@ -0,0 +1,18 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package forgejo_migrations //nolint:revive
+
+import "xorm.io/xorm"
+
+func AddReusableWorkflowToActionRunJob(x *xorm.Engine) error {
+	type ActionRunJob struct {
+		ID                 int64
+		ParentJobID        int64             `xorm:"index"`
+		CalledWorkflow     string            `xorm:"VARCHAR(255)"`
+		CallOutputs        map[string]string `xorm:"JSON TEXT"`
+		CallSecrets        map[string]string `xorm:"JSON TEXT"`
+		CallInheritSecrets bool
+	}
+	return x.Sync(new(ActionRunJob))
+}
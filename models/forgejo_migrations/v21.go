@@ -0,0 +1,69 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package forgejo_migrations //nolint:revive
+
+import (
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+func AddActionsEnvironments(x *xorm.Engine) error {
+	type ActionEnvironment struct {
+		ID             int64
+		RepoID         int64   `xorm:"UNIQUE(repo_name) NOT NULL"`
+		Name           string  `xorm:"VARCHAR(255) NOT NULL"`
+		LowerName      string  `xorm:"VARCHAR(255) UNIQUE(repo_name) NOT NULL"`
+		ReviewerIDs    []int64 `xorm:"JSON TEXT"`
+		WaitTimer      int64
+		BranchPatterns []string           `xorm:"JSON TEXT"`
+		Created        timeutil.TimeStamp `xorm:"created"`
+		Updated        timeutil.TimeStamp `xorm:"updated"`
+	}
+
+	type ActionDeployment struct {
+		ID            int64
+		RepoID        int64 `xorm:"index"`
+		EnvironmentID int64 `xorm:"index"`
+		RunID         int64 `xorm:"index"`
+		JobID         int64 `xorm:"index"`
+		Ref           string
+		CommitSHA     string
+		URL           string `xorm:"TEXT"`
+		Status        int    `xorm:"index"`
+		NeedApproval  bool
+		ApprovedBy    int64 `xorm:"index"`
+		RejectedBy    int64 `xorm:"index"`
+		Reviewed      timeutil.TimeStamp
+		WaitUntil     timeutil.TimeStamp `xorm:"index"`
+		Created       timeutil.TimeStamp `xorm:"created"`
+		Updated       timeutil.TimeStamp `xorm:"updated"`
+	}
+
+	type ActionRunJob struct {
+		ID             int64
+		RawEnvironment string `xorm:"TEXT"`
+		EnvironmentID  int64  `xorm:"index"`
+		DeploymentID   int64
+	}
+
+	// the environment is part of the unique index of the secrets and the variables
+	type Secret struct {
+		ID            int64
+		OwnerID       int64  `xorm:"INDEX UNIQUE(owner_repo_name) NOT NULL"`
+		RepoID        int64  `xorm:"INDEX UNIQUE(owner_repo_name) NOT NULL DEFAULT 0"`
+		EnvironmentID int64  `xorm:"INDEX UNIQUE(owner_repo_name) NOT NULL DEFAULT 0"`
+		Name          string `xorm:"UNIQUE(owner_repo_name) NOT NULL"`
+	}
+
+	type ActionVariable struct {
+		ID            int64  `xorm:"pk autoincr"`
+		OwnerID       int64  `xorm:"UNIQUE(owner_repo_name)"`
+		RepoID        int64  `xorm:"INDEX UNIQUE(owner_repo_name)"`
+		EnvironmentID int64  `xorm:"INDEX UNIQUE(owner_repo_name) NOT NULL DEFAULT 0"`
+		Name          string `xorm:"UNIQUE(owner_repo_name) NOT NULL"`
+	}
+
+	return x.Sync(new(ActionEnvironment), new(ActionDeployment), new(ActionRunJob), new(Secret), new(ActionVariable))
+}
@@ -0,0 +1,27 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package forgejo_migrations //nolint:revive
+
+import (
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+func AddActionsCache(x *xorm.Engine) error {
+	type ActionCache struct {
+		ID       int64
+		RepoID   int64 `xorm:"index NOT NULL"`
+		RunID    int64
+		CacheKey string `xorm:"VARCHAR(512) NOT NULL"`
+		Version  string `xorm:"VARCHAR(255) NOT NULL"`
+		Ref      string `xorm:"VARCHAR(255) NOT NULL"`
+		Size     int64
+		Complete bool               `xorm:"index"`
+		Created  timeutil.TimeStamp `xorm:"created"`
+		LastUsed timeutil.TimeStamp `xorm:"index"`
+	}
+
+	return x.Sync(new(ActionCache))
+}
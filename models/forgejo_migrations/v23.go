@@ -0,0 +1,24 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package forgejo_migrations //nolint:revive
+
+import (
+	"xorm.io/builder"
+	"xorm.io/xorm"
+)
+
+func AddIsCompletionNotifiedToActionRun(x *xorm.Engine) error {
+	type ActionRun struct {
+		IsCompletionNotified bool `xorm:"NOT NULL DEFAULT false"`
+	}
+	if err := x.Sync(new(ActionRun)); err != nil {
+		return err
+	}
+
+	// the runs which were already done don't emit the workflow_run event of their completion
+	_, err := x.Table("action_run").
+		Where(builder.In("status", 1, 2, 3, 4)). // success, failure, cancelled and skipped
+		Update(map[string]any{"is_completion_notified": true})
+	return err
+}
@@ -0,0 +1,31 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package forgejo_migrations //nolint:revive
+
+import (
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+func AddActionsRunnerGroups(x *xorm.Engine) error {
+	type ActionRunnerGroup struct {
+		ID          int64
+		OwnerID     int64  `xorm:"UNIQUE(owner_name) NOT NULL DEFAULT 0"`
+		Name        string `xorm:"VARCHAR(255) NOT NULL"`
+		LowerName   string `xorm:"VARCHAR(255) UNIQUE(owner_name) NOT NULL"`
+		Description string `xorm:"TEXT"`
+
+		AllowedOwnerIDs  []int64  `xorm:"JSON TEXT"`
+		AllowedRepoIDs   []int64  `xorm:"JSON TEXT"`
+		AllowedWorkflows []string `xorm:"JSON TEXT"`
+
+		Created timeutil.TimeStamp `xorm:"created"`
+		Updated timeutil.TimeStamp `xorm:"updated"`
+	}
+	type ActionRunner struct {
+		GroupID int64 `xorm:"index NOT NULL DEFAULT 0"`
+	}
+	return x.Sync(new(ActionRunnerGroup), new(ActionRunner))
+}
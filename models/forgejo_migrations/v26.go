@@ -0,0 +1,24 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package forgejo_migrations //nolint:revive
+
+import (
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+func AddPackageVirtualRegistry(x *xorm.Engine) error {
+	type PackageVirtualRegistry struct {
+		ID          int64              `xorm:"pk autoincr"`
+		Enabled     bool               `xorm:"INDEX NOT NULL DEFAULT false"`
+		OwnerID     int64              `xorm:"UNIQUE(s) INDEX NOT NULL DEFAULT 0"`
+		Type        string             `xorm:"UNIQUE(s) INDEX NOT NULL"`
+		MemberIDs   []int64            `xorm:"JSON TEXT"`
+		UseProxies  bool               `xorm:"NOT NULL DEFAULT false"`
+		CreatedUnix timeutil.TimeStamp `xorm:"created NOT NULL DEFAULT 0"`
+		UpdatedUnix timeutil.TimeStamp `xorm:"updated NOT NULL DEFAULT 0"`
+	}
+	return x.Sync(new(PackageVirtualRegistry))
+}
@@ -0,0 +1,39 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package forgejo_migrations //nolint:revive
+
+import (
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+func AddTerraformState(x *xorm.Engine) error {
+	type TerraformState struct {
+		ID          int64              `xorm:"pk autoincr"`
+		RepoID      int64              `xorm:"UNIQUE(s) INDEX NOT NULL"`
+		Name        string             `xorm:"NOT NULL"`
+		LowerName   string             `xorm:"UNIQUE(s) NOT NULL"`
+		LockID      string             `xorm:"NOT NULL DEFAULT ''"`
+		LockInfo    string             `xorm:"TEXT"`
+		LockerID    int64              `xorm:"NOT NULL DEFAULT 0"`
+		LockedUnix  timeutil.TimeStamp `xorm:"NOT NULL DEFAULT 0"`
+		CreatedUnix timeutil.TimeStamp `xorm:"created NOT NULL"`
+		UpdatedUnix timeutil.TimeStamp `xorm:"updated NOT NULL"`
+	}
+
+	type TerraformStateVersion struct {
+		ID               int64              `xorm:"pk autoincr"`
+		RepoID           int64              `xorm:"INDEX NOT NULL"`
+		StateID          int64              `xorm:"INDEX NOT NULL"`
+		Serial           int64              `xorm:"NOT NULL DEFAULT 0"`
+		Lineage          string             `xorm:"NOT NULL DEFAULT ''"`
+		TerraformVersion string             `xorm:"NOT NULL DEFAULT ''"`
+		Size             int64              `xorm:"NOT NULL DEFAULT 0"`
+		CreatorID        int64              `xorm:"NOT NULL DEFAULT 0"`
+		CreatedUnix      timeutil.TimeStamp `xorm:"created INDEX NOT NULL"`
+	}
+
+	return x.Sync(new(TerraformState), new(TerraformStateVersion))
+}
@@ -0,0 +1,54 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package forgejo_migrations //nolint:revive
+
+import (
+	"xorm.io/xorm"
+)
+
+type QuotaRule struct {
+	ID        int64  `xorm:"pk autoincr"`
+	Name      string `xorm:"NOT NULL"`
+	LowerName string `xorm:"UNIQUE NOT NULL"`
+	Limit     int64  `xorm:"'limit_size' NOT NULL DEFAULT -1"`
+	Subjects  []int  `xorm:"JSON TEXT"`
+}
+
+func (*QuotaRule) TableName() string {
+	return "quota_rule"
+}
+
+type QuotaGroup struct {
+	ID        int64  `xorm:"pk autoincr"`
+	Name      string `xorm:"NOT NULL"`
+	LowerName string `xorm:"UNIQUE NOT NULL"`
+}
+
+func (*QuotaGroup) TableName() string {
+	return "quota_group"
+}
+
+type QuotaGroupRuleMapping struct {
+	ID      int64 `xorm:"pk autoincr"`
+	GroupID int64 `xorm:"UNIQUE(s) INDEX NOT NULL"`
+	RuleID  int64 `xorm:"UNIQUE(s) INDEX NOT NULL"`
+}
+
+func (*QuotaGroupRuleMapping) TableName() string {
+	return "quota_group_rule_mapping"
+}
+
+type QuotaGroupMapping struct {
+	ID      int64 `xorm:"pk autoincr"`
+	GroupID int64 `xorm:"UNIQUE(s) INDEX NOT NULL"`
+	UserID  int64 `xorm:"UNIQUE(s) INDEX NOT NULL"`
+}
+
+func (*QuotaGroupMapping) TableName() string {
+	return "quota_group_mapping"
+}
+
+func AddQuotaTables(x *xorm.Engine) error {
+	return x.Sync(new(QuotaRule), new(QuotaGroup), new(QuotaGroupRuleMapping), new(QuotaGroupMapping))
+}
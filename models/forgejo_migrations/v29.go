@@ -0,0 +1,29 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package forgejo_migrations //nolint:revive
+
+import (
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+func AddPackageDownloadStatistics(x *xorm.Engine) error {
+	type PackageDownload struct {
+		ID          int64              `xorm:"pk autoincr"`
+		VersionID   int64              `xorm:"INDEX NOT NULL"`
+		UserID      int64              `xorm:"NOT NULL DEFAULT 0"`
+		CreatedUnix timeutil.TimeStamp `xorm:"created NOT NULL"`
+	}
+
+	type PackageDownloadStat struct {
+		ID        int64              `xorm:"pk autoincr"`
+		VersionID int64              `xorm:"UNIQUE(s) INDEX NOT NULL"`
+		UserID    int64              `xorm:"UNIQUE(s) NOT NULL DEFAULT 0"`
+		DayUnix   timeutil.TimeStamp `xorm:"UNIQUE(s) INDEX NOT NULL"`
+		Downloads int64              `xorm:"NOT NULL DEFAULT 0"`
+	}
+
+	return x.Sync(new(PackageDownload), new(PackageDownloadStat))
+}
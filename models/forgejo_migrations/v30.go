@@ -0,0 +1,20 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package forgejo_migrations //nolint:revive
+
+import (
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+func AddWebhookRetries(x *xorm.Engine) error {
+	type HookTask struct {
+		Attempt int `xorm:"NOT NULL DEFAULT 1"`
+	}
+	type Webhook struct {
+		FailingSince timeutil.TimeStamp `xorm:"NOT NULL DEFAULT 0"`
+	}
+	return x.Sync(new(HookTask), new(Webhook))
+}
@@ -0,0 +1,13 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package forgejo_migrations //nolint:revive
+
+import "xorm.io/xorm"
+
+func AddDeliveryUUIDToHookTask(x *xorm.Engine) error {
+	type HookTask struct {
+		DeliveryUUID string `xorm:"index"`
+	}
+	return x.Sync(new(HookTask))
+}
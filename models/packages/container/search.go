@@ -26,6 +26,7 @@ type BlobSearchOptions struct {
 	Tag        string
 	IsManifest bool
 	Repository string
+	Subject    string
 }
 
 func (opts *BlobSearchOptions) toConds() builder.Cond {
@@ -63,6 +64,15 @@ func (opts *BlobSearchOptions) toConds() builder.Cond {
 
 		cond = cond.And(builder.In("package.id", builder.Select("package_property.ref_id").Where(propsCond).From("package_property")))
 	}
+	if opts.Subject != "" {
+		var propsCond builder.Cond = builder.Eq{
+			"package_property.ref_type": packages.PropertyTypeVersion,
+			"package_property.name":     container_module.PropertyManifestSubject,
+			"package_property.value":    opts.Subject,
+		}
+
+		cond = cond.And(builder.In("package_version.id", builder.Select("package_property.ref_id").Where(propsCond).From("package_property")))
+	}
 
 	return cond
 }
@@ -113,6 +123,7 @@ func GetManifestVersions(ctx context.Context, opts *BlobSearchOptions) ([]*packa
 		Join("INNER", "package", "package.id = package_version.package_id").
 		Join("INNER", "package_file", "package_file.version_id = package_version.id").
 		Where(cond).
+		Asc("package_version.id").
 		Find(&pvs)
 }
 
@@ -31,6 +31,7 @@ type Type string
 // List of supported packages
 const (
 	TypeAlpine    Type = "alpine"
+	TypeArch      Type = "arch"
 	TypeCargo     Type = "cargo"
 	TypeChef      Type = "chef"
 	TypeComposer  Type = "composer"
@@ -43,6 +44,7 @@ const (
 	TypeGo        Type = "go"
 	TypeHelm      Type = "helm"
 	TypeMaven     Type = "maven"
+	TypeNix       Type = "nix"
 	TypeNpm       Type = "npm"
 	TypeNuGet     Type = "nuget"
 	TypePub       Type = "pub"
@@ -50,11 +52,13 @@ const (
 	TypeRpm       Type = "rpm"
 	TypeRubyGems  Type = "rubygems"
 	TypeSwift     Type = "swift"
+	TypeTerraform Type = "terraform"
 	TypeVagrant   Type = "vagrant"
 )
 
 var TypeList = []Type{
 	TypeAlpine,
+	TypeArch,
 	TypeCargo,
 	TypeChef,
 	TypeComposer,
@@ -67,6 +71,7 @@ var TypeList = []Type{
 	TypeGo,
 	TypeHelm,
 	TypeMaven,
+	TypeNix,
 	TypeNpm,
 	TypeNuGet,
 	TypePub,
@@ -74,6 +79,7 @@ var TypeList = []Type{
 	TypeRpm,
 	TypeRubyGems,
 	TypeSwift,
+	TypeTerraform,
 	TypeVagrant,
 }
 
@@ -82,6 +88,8 @@ func (pt Type) Name() string {
 	switch pt {
 	case TypeAlpine:
 		return "Alpine"
+	case TypeArch:
+		return "Arch"
 	case TypeCargo:
 		return "Cargo"
 	case TypeChef:
@@ -106,6 +114,8 @@ func (pt Type) Name() string {
 		return "Helm"
 	case TypeMaven:
 		return "Maven"
+	case TypeNix:
+		return "Nix"
 	case TypeNpm:
 		return "npm"
 	case TypeNuGet:
@@ -120,6 +130,8 @@ func (pt Type) Name() string {
 		return "RubyGems"
 	case TypeSwift:
 		return "Swift"
+	case TypeTerraform:
+		return "Terraform"
 	case TypeVagrant:
 		return "Vagrant"
 	}
@@ -131,6 +143,8 @@ func (pt Type) SVGName() string {
 	switch pt {
 	case TypeAlpine:
 		return "gitea-alpine"
+	case TypeArch:
+		return "gitea-arch"
 	case TypeCargo:
 		return "gitea-cargo"
 	case TypeChef:
@@ -155,6 +169,8 @@ func (pt Type) SVGName() string {
 		return "gitea-helm"
 	case TypeMaven:
 		return "gitea-maven"
+	case TypeNix:
+		return "gitea-nix"
 	case TypeNpm:
 		return "gitea-npm"
 	case TypeNuGet:
@@ -169,6 +185,8 @@ func (pt Type) SVGName() string {
 		return "gitea-rubygems"
 	case TypeSwift:
 		return "gitea-swift"
+	case TypeTerraform:
+		return "gitea-terraform"
 	case TypeVagrant:
 		return "gitea-vagrant"
 	}
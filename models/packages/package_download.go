@@ -0,0 +1,132 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package packages
+
+import (
+	"context"
+	"time"
+
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"xorm.io/builder"
+)
+
+func init() {
+	db.RegisterModel(new(PackageDownload))
+	db.RegisterModel(new(PackageDownloadStat))
+}
+
+// PackageDownload represents a single download of a package version which is not aggregated yet
+type PackageDownload struct {
+	ID          int64              `xorm:"pk autoincr"`
+	VersionID   int64              `xorm:"INDEX NOT NULL"`
+	UserID      int64              `xorm:"NOT NULL DEFAULT 0"`
+	CreatedUnix timeutil.TimeStamp `xorm:"created NOT NULL"`
+}
+
+// PackageDownloadStat represents the number of downloads of a package version by a user on a day
+type PackageDownloadStat struct {
+	ID        int64              `xorm:"pk autoincr"`
+	VersionID int64              `xorm:"UNIQUE(s) INDEX NOT NULL"`
+	UserID    int64              `xorm:"UNIQUE(s) NOT NULL DEFAULT 0"`
+	DayUnix   timeutil.TimeStamp `xorm:"UNIQUE(s) INDEX NOT NULL"`
+	Downloads int64              `xorm:"NOT NULL DEFAULT 0"`
+}
+
+// DayOf returns the start of the UTC day of the timestamp
+func DayOf(ts timeutil.TimeStamp) timeutil.TimeStamp {
+	t := ts.AsTime().UTC()
+	return timeutil.TimeStamp(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Unix())
+}
+
+// InsertDownload records a download of a package version. The user id is 0 for anonymous downloads.
+func InsertDownload(ctx context.Context, versionID, userID int64) error {
+	return db.Insert(ctx, &PackageDownload{
+		VersionID: versionID,
+		UserID:    userID,
+	})
+}
+
+// GetOldestDownloads gets the oldest downloads which are not aggregated yet
+func GetOldestDownloads(ctx context.Context, limit int) ([]*PackageDownload, error) {
+	pds := make([]*PackageDownload, 0, limit)
+	return pds, db.GetEngine(ctx).OrderBy("id ASC").Limit(limit).Find(&pds)
+}
+
+// DeleteDownloadsUpToID deletes the downloads which are aggregated
+func DeleteDownloadsUpToID(ctx context.Context, id int64) error {
+	_, err := db.GetEngine(ctx).Where("id <= ?", id).Delete(&PackageDownload{})
+	return err
+}
+
+// AddDownloadStat adds the number of downloads of the version by the user on the day
+func AddDownloadStat(ctx context.Context, versionID, userID int64, day timeutil.TimeStamp, downloads int64) error {
+	e := db.GetEngine(ctx)
+
+	n, err := e.Where(builder.Eq{"version_id": versionID, "user_id": userID, "day_unix": day}).
+		Incr("downloads", downloads).
+		NoAutoTime().
+		Update(&PackageDownloadStat{})
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+
+	_, err = e.Insert(&PackageDownloadStat{
+		VersionID: versionID,
+		UserID:    userID,
+		DayUnix:   day,
+		Downloads: downloads,
+	})
+	return err
+}
+
+// DownloadsPerDay represents the number of downloads on a day
+type DownloadsPerDay struct {
+	DayUnix   timeutil.TimeStamp
+	Downloads int64
+}
+
+// GetDownloadsPerDay gets the number of downloads of the version per day since the given day, days without downloads are omitted
+func GetDownloadsPerDay(ctx context.Context, versionID int64, since timeutil.TimeStamp) ([]*DownloadsPerDay, error) {
+	result := make([]*DownloadsPerDay, 0, 30)
+	return result, db.GetEngine(ctx).
+		Table("package_download_stat").
+		Select("day_unix, SUM(downloads) AS downloads").
+		Where(builder.Eq{"version_id": versionID}.And(builder.Gte{"day_unix": since})).
+		GroupBy("day_unix").
+		OrderBy("day_unix ASC").
+		Find(&result)
+}
+
+// DownloadsPerUser represents the number of downloads by a user
+type DownloadsPerUser struct {
+	UserID    int64
+	Downloads int64
+}
+
+// GetDownloadsPerUser gets the number of downloads of the version per user since the given day, the most active users first
+func GetDownloadsPerUser(ctx context.Context, versionID int64, since timeutil.TimeStamp, limit int) ([]*DownloadsPerUser, error) {
+	result := make([]*DownloadsPerUser, 0, limit)
+	return result, db.GetEngine(ctx).
+		Table("package_download_stat").
+		Select("user_id, SUM(downloads) AS downloads").
+		Where(builder.Eq{"version_id": versionID}.And(builder.Gte{"day_unix": since})).
+		GroupBy("user_id").
+		OrderBy("downloads DESC, user_id ASC").
+		Limit(limit).
+		Find(&result)
+}
+
+// DeleteDownloadsByVersionID deletes the downloads and the download statistics of the version
+func DeleteDownloadsByVersionID(ctx context.Context, versionID int64) error {
+	if _, err := db.GetEngine(ctx).Where("version_id = ?", versionID).Delete(&PackageDownload{}); err != nil {
+		return err
+	}
+	_, err := db.GetEngine(ctx).Where("version_id = ?", versionID).Delete(&PackageDownloadStat{})
+	return err
+}
@@ -26,6 +26,13 @@ const (
 	PropertyTypePackage // 2
 )
 
+const (
+	// PropertyVersionDeprecated marks a version as deprecated, the value is the deprecation message
+	PropertyVersionDeprecated = "package.deprecated"
+	// PropertyVersionRetentionLocked marks a version as locked, locked versions can't be deleted and are kept by cleanup rules
+	PropertyVersionRetentionLocked = "package.retention_locked"
+)
+
 // PackageProperty represents a property of a package, version or file
 type PackageProperty struct {
 	ID      int64        `xorm:"pk autoincr"`
@@ -60,6 +67,11 @@ func GetPropertiesByName(ctx context.Context, refType PropertyType, refID int64,
 	return pps, db.GetEngine(ctx).Where("ref_type = ? AND ref_id = ? AND name = ?", refType, refID, name).Find(&pps)
 }
 
+// ExistProperty checks if a property with the specific name exists
+func ExistProperty(ctx context.Context, refType PropertyType, refID int64, name string) (bool, error) {
+	return db.GetEngine(ctx).Where("ref_type = ? AND ref_id = ? AND name = ?", refType, refID, name).Exist(&PackageProperty{})
+}
+
 // UpdateProperty updates a property
 func UpdateProperty(ctx context.Context, pp *PackageProperty) error {
 	_, err := db.GetEngine(ctx).ID(pp.ID).Update(pp)
@@ -0,0 +1,125 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package packages
+
+import (
+	"context"
+
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/modules/secret"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/timeutil"
+	"code.gitea.io/gitea/modules/util"
+)
+
+var ErrPackageProxyNotExist = util.NewNotExistErrorf("package proxy does not exist")
+
+// ProxyTypeList are the package types which can be proxied
+var ProxyTypeList = []Type{
+	TypeContainer,
+	TypeMaven,
+	TypeNpm,
+	TypePyPI,
+}
+
+func init() {
+	db.RegisterModel(new(PackageProxy))
+}
+
+// PackageProxy represents an upstream registry whose packages are cached by an owner when they are requested
+type PackageProxy struct {
+	ID      int64  `xorm:"pk autoincr"`
+	Enabled bool   `xorm:"INDEX NOT NULL DEFAULT false"`
+	OwnerID int64  `xorm:"UNIQUE(s) INDEX NOT NULL DEFAULT 0"`
+	Type    Type   `xorm:"UNIQUE(s) INDEX NOT NULL"`
+	URL     string `xorm:"TEXT NOT NULL"`
+	// the credentials sent to the upstream registry, PasswordEncrypted should be accessed using Password() and SetPassword()
+	Username          string             `xorm:"NOT NULL DEFAULT ''"`
+	PasswordEncrypted string             `xorm:"TEXT"`
+	CreatedUnix       timeutil.TimeStamp `xorm:"created NOT NULL DEFAULT 0"`
+	UpdatedUnix       timeutil.TimeStamp `xorm:"updated NOT NULL DEFAULT 0"`
+}
+
+// IsProxyType returns if the packages of a type can be proxied
+func IsProxyType(pt Type) bool {
+	for _, t := range ProxyTypeList {
+		if t == pt {
+			return true
+		}
+	}
+	return false
+}
+
+// Password returns the decrypted password of the upstream registry
+func (pp *PackageProxy) Password() (string, error) {
+	if pp.PasswordEncrypted == "" {
+		return "", nil
+	}
+	return secret.DecryptSecret(setting.SecretKey, pp.PasswordEncrypted)
+}
+
+// SetPassword encrypts and sets the password of the upstream registry
+func (pp *PackageProxy) SetPassword(cleartext string) error {
+	if cleartext == "" {
+		pp.PasswordEncrypted = ""
+		return nil
+	}
+	ciphertext, err := secret.EncryptSecret(setting.SecretKey, cleartext)
+	if err != nil {
+		return err
+	}
+	pp.PasswordEncrypted = ciphertext
+	return nil
+}
+
+func InsertProxy(ctx context.Context, pp *PackageProxy) (*PackageProxy, error) {
+	return pp, db.Insert(ctx, pp)
+}
+
+func GetProxyByID(ctx context.Context, id int64) (*PackageProxy, error) {
+	pp := &PackageProxy{}
+
+	has, err := db.GetEngine(ctx).ID(id).Get(pp)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, ErrPackageProxyNotExist
+	}
+	return pp, nil
+}
+
+// GetEnabledProxy returns the enabled proxy of an owner for a package type, or nil if there is none
+func GetEnabledProxy(ctx context.Context, ownerID int64, packageType Type) (*PackageProxy, error) {
+	pp := &PackageProxy{}
+
+	has, err := db.GetEngine(ctx).
+		Where("owner_id = ? AND type = ? AND enabled = ?", ownerID, packageType, true).
+		Get(pp)
+	if err != nil || !has {
+		return nil, err
+	}
+	return pp, nil
+}
+
+func UpdateProxy(ctx context.Context, pp *PackageProxy) error {
+	_, err := db.GetEngine(ctx).ID(pp.ID).AllCols().Update(pp)
+	return err
+}
+
+func GetProxiesByOwner(ctx context.Context, ownerID int64) ([]*PackageProxy, error) {
+	pps := make([]*PackageProxy, 0, len(ProxyTypeList))
+	return pps, db.GetEngine(ctx).Where("owner_id = ?", ownerID).Find(&pps)
+}
+
+func DeleteProxyByID(ctx context.Context, proxyID int64) error {
+	_, err := db.GetEngine(ctx).ID(proxyID).Delete(&PackageProxy{})
+	return err
+}
+
+func HasOwnerProxyForPackageType(ctx context.Context, ownerID int64, packageType Type) (bool, error) {
+	return db.GetEngine(ctx).
+		Where("owner_id = ? AND type = ?", ownerID, packageType).
+		Exist(&PackageProxy{})
+}
@@ -10,6 +10,7 @@ import (
 	packages_model "code.gitea.io/gitea/models/packages"
 	"code.gitea.io/gitea/models/unittest"
 	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/timeutil"
 
 	_ "code.gitea.io/gitea/models"
 	_ "code.gitea.io/gitea/models/actions"
@@ -65,3 +66,57 @@ func TestHasOwnerPackages(t *testing.T) {
 	assert.True(t, has)
 	assert.NoError(t, err)
 }
+
+func TestDownloadStats(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	day := packages_model.DayOf(timeutil.TimeStampNow())
+	assert.Equal(t, day, packages_model.DayOf(day.Add(86399)))
+	assert.Equal(t, day.Add(86400), packages_model.DayOf(day.Add(86400)))
+
+	assert.NoError(t, packages_model.InsertDownload(db.DefaultContext, 1, 0))
+	assert.NoError(t, packages_model.InsertDownload(db.DefaultContext, 1, 2))
+
+	pds, err := packages_model.GetOldestDownloads(db.DefaultContext, 1)
+	assert.NoError(t, err)
+	assert.Len(t, pds, 1)
+	assert.EqualValues(t, 0, pds[0].UserID)
+
+	assert.NoError(t, packages_model.DeleteDownloadsUpToID(db.DefaultContext, pds[0].ID))
+
+	pds, err = packages_model.GetOldestDownloads(db.DefaultContext, 10)
+	assert.NoError(t, err)
+	assert.Len(t, pds, 1)
+	assert.EqualValues(t, 2, pds[0].UserID)
+
+	assert.NoError(t, packages_model.AddDownloadStat(db.DefaultContext, 1, 2, day, 3))
+	assert.NoError(t, packages_model.AddDownloadStat(db.DefaultContext, 1, 2, day, 3))
+	assert.NoError(t, packages_model.AddDownloadStat(db.DefaultContext, 1, 0, day, 1))
+	assert.NoError(t, packages_model.AddDownloadStat(db.DefaultContext, 1, 0, day.Add(-86400), 4))
+	assert.NoError(t, packages_model.AddDownloadStat(db.DefaultContext, 2, 0, day, 7))
+
+	perDay, err := packages_model.GetDownloadsPerDay(db.DefaultContext, 1, day.Add(-86400))
+	assert.NoError(t, err)
+	assert.Len(t, perDay, 2)
+	assert.Equal(t, day.Add(-86400), perDay[0].DayUnix)
+	assert.EqualValues(t, 4, perDay[0].Downloads)
+	assert.Equal(t, day, perDay[1].DayUnix)
+	assert.EqualValues(t, 7, perDay[1].Downloads)
+
+	perDay, err = packages_model.GetDownloadsPerDay(db.DefaultContext, 1, day)
+	assert.NoError(t, err)
+	assert.Len(t, perDay, 1)
+
+	perUser, err := packages_model.GetDownloadsPerUser(db.DefaultContext, 1, day.Add(-86400), 10)
+	assert.NoError(t, err)
+	assert.Len(t, perUser, 2)
+	assert.EqualValues(t, 2, perUser[0].UserID)
+	assert.EqualValues(t, 6, perUser[0].Downloads)
+	assert.EqualValues(t, 0, perUser[1].UserID)
+	assert.EqualValues(t, 5, perUser[1].Downloads)
+
+	assert.NoError(t, packages_model.DeleteDownloadsByVersionID(db.DefaultContext, 1))
+
+	unittest.AssertCount(t, &packages_model.PackageDownload{}, 0)
+	unittest.AssertCount(t, &packages_model.PackageDownloadStat{}, 1)
+}
@@ -0,0 +1,102 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package packages
+
+import (
+	"context"
+
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/modules/timeutil"
+	"code.gitea.io/gitea/modules/util"
+)
+
+var ErrPackageVirtualRegistryNotExist = util.NewNotExistErrorf("package virtual registry does not exist")
+
+// VirtualRegistryTypeList are the package types whose registries can be virtual
+var VirtualRegistryTypeList = []Type{
+	TypeMaven,
+	TypeNpm,
+	TypePyPI,
+}
+
+func init() {
+	db.RegisterModel(new(PackageVirtualRegistry))
+}
+
+// PackageVirtualRegistry represents a registry of an owner which also serves the packages of other owners.
+// The packages are resolved in the registry of the owner, then in the registries of the members in their order,
+// and then in the upstream registries proxied by these owners.
+type PackageVirtualRegistry struct {
+	ID      int64 `xorm:"pk autoincr"`
+	Enabled bool  `xorm:"INDEX NOT NULL DEFAULT false"`
+	OwnerID int64 `xorm:"UNIQUE(s) INDEX NOT NULL DEFAULT 0"`
+	Type    Type  `xorm:"UNIQUE(s) INDEX NOT NULL"`
+	// the owners whose packages are served, by order of priority
+	MemberIDs []int64 `xorm:"JSON TEXT"`
+	// if the upstream registries proxied by the members are used, the proxy of the owner is always used
+	UseProxies  bool               `xorm:"NOT NULL DEFAULT false"`
+	CreatedUnix timeutil.TimeStamp `xorm:"created NOT NULL DEFAULT 0"`
+	UpdatedUnix timeutil.TimeStamp `xorm:"updated NOT NULL DEFAULT 0"`
+}
+
+// IsVirtualRegistryType returns if the registries of a package type can be virtual
+func IsVirtualRegistryType(pt Type) bool {
+	for _, t := range VirtualRegistryTypeList {
+		if t == pt {
+			return true
+		}
+	}
+	return false
+}
+
+func InsertVirtualRegistry(ctx context.Context, pvr *PackageVirtualRegistry) (*PackageVirtualRegistry, error) {
+	return pvr, db.Insert(ctx, pvr)
+}
+
+func GetVirtualRegistryByID(ctx context.Context, id int64) (*PackageVirtualRegistry, error) {
+	pvr := &PackageVirtualRegistry{}
+
+	has, err := db.GetEngine(ctx).ID(id).Get(pvr)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, ErrPackageVirtualRegistryNotExist
+	}
+	return pvr, nil
+}
+
+// GetEnabledVirtualRegistry returns the enabled virtual registry of an owner for a package type, or nil if there is none
+func GetEnabledVirtualRegistry(ctx context.Context, ownerID int64, packageType Type) (*PackageVirtualRegistry, error) {
+	pvr := &PackageVirtualRegistry{}
+
+	has, err := db.GetEngine(ctx).
+		Where("owner_id = ? AND type = ? AND enabled = ?", ownerID, packageType, true).
+		Get(pvr)
+	if err != nil || !has {
+		return nil, err
+	}
+	return pvr, nil
+}
+
+func UpdateVirtualRegistry(ctx context.Context, pvr *PackageVirtualRegistry) error {
+	_, err := db.GetEngine(ctx).ID(pvr.ID).AllCols().Update(pvr)
+	return err
+}
+
+func GetVirtualRegistriesByOwner(ctx context.Context, ownerID int64) ([]*PackageVirtualRegistry, error) {
+	pvrs := make([]*PackageVirtualRegistry, 0, len(VirtualRegistryTypeList))
+	return pvrs, db.GetEngine(ctx).Where("owner_id = ?", ownerID).Find(&pvrs)
+}
+
+func DeleteVirtualRegistryByID(ctx context.Context, registryID int64) error {
+	_, err := db.GetEngine(ctx).ID(registryID).Delete(&PackageVirtualRegistry{})
+	return err
+}
+
+func HasOwnerVirtualRegistryForPackageType(ctx context.Context, ownerID int64, packageType Type) (bool, error) {
+	return db.GetEngine(ctx).
+		Where("owner_id = ? AND type = ?", ownerID, packageType).
+		Exist(&PackageVirtualRegistry{})
+}
@@ -0,0 +1,267 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package quota
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/util"
+
+	"xorm.io/builder"
+)
+
+// ErrGroupNotExist represents a "GroupNotExist" kind of error.
+type ErrGroupNotExist struct {
+	Name string
+}
+
+// IsErrGroupNotExist checks if an error is a ErrGroupNotExist.
+func IsErrGroupNotExist(err error) bool {
+	_, ok := err.(ErrGroupNotExist)
+	return ok
+}
+
+func (err ErrGroupNotExist) Error() string {
+	return fmt.Sprintf("quota group does not exist [name: %s]", err.Name)
+}
+
+func (err ErrGroupNotExist) Unwrap() error {
+	return util.ErrNotExist
+}
+
+// ErrGroupAlreadyExist represents a "GroupAlreadyExist" kind of error.
+type ErrGroupAlreadyExist struct {
+	Name string
+}
+
+// IsErrGroupAlreadyExist checks if an error is a ErrGroupAlreadyExist.
+func IsErrGroupAlreadyExist(err error) bool {
+	_, ok := err.(ErrGroupAlreadyExist)
+	return ok
+}
+
+func (err ErrGroupAlreadyExist) Error() string {
+	return fmt.Sprintf("quota group already exists [name: %s]", err.Name)
+}
+
+func (err ErrGroupAlreadyExist) Unwrap() error {
+	return util.ErrAlreadyExist
+}
+
+// Group is a set of rules which is assigned to users and organizations
+type Group struct {
+	ID        int64   `xorm:"pk autoincr"`
+	Name      string  `xorm:"NOT NULL"`
+	LowerName string  `xorm:"UNIQUE NOT NULL"`
+	Rules     []*Rule `xorm:"-"`
+}
+
+// GroupRuleMapping assigns a rule to a group
+type GroupRuleMapping struct {
+	ID      int64 `xorm:"pk autoincr"`
+	GroupID int64 `xorm:"UNIQUE(s) INDEX NOT NULL"`
+	RuleID  int64 `xorm:"UNIQUE(s) INDEX NOT NULL"`
+}
+
+// GroupMapping assigns a group to a user or an organization
+type GroupMapping struct {
+	ID      int64 `xorm:"pk autoincr"`
+	GroupID int64 `xorm:"UNIQUE(s) INDEX NOT NULL"`
+	UserID  int64 `xorm:"UNIQUE(s) INDEX NOT NULL"`
+}
+
+func init() {
+	db.RegisterModel(new(Group))
+	db.RegisterModel(new(GroupRuleMapping))
+	db.RegisterModel(new(GroupMapping))
+}
+
+// TableName sets the table name of the quota groups
+func (*Group) TableName() string {
+	return "quota_group"
+}
+
+// TableName sets the table name of the rule assignments
+func (*GroupRuleMapping) TableName() string {
+	return "quota_group_rule_mapping"
+}
+
+// TableName sets the table name of the group assignments
+func (*GroupMapping) TableName() string {
+	return "quota_group_mapping"
+}
+
+// LoadRules loads the rules of the group
+func (g *Group) LoadRules(ctx context.Context) error {
+	rules := make([]*Rule, 0, 10)
+	if err := db.GetEngine(ctx).
+		Where(builder.In("id", builder.Select("rule_id").From("quota_group_rule_mapping").Where(builder.Eq{"group_id": g.ID}))).
+		OrderBy("lower_name").
+		Find(&rules); err != nil {
+		return err
+	}
+	g.Rules = rules
+	return nil
+}
+
+// Evaluate tests if the group applies to the subject and if all its rules which apply allow to use more storage
+func (g *Group) Evaluate(used *Used, subject LimitSubject) (acceptable, applies bool) {
+	acceptable = true
+	for _, r := range g.Rules {
+		ok, has := r.Evaluate(used, subject)
+		if !has {
+			continue
+		}
+		applies = true
+		if !ok {
+			acceptable = false
+		}
+	}
+	return acceptable && applies, applies
+}
+
+// GroupList is a list of groups
+type GroupList []*Group
+
+// Evaluate tests if more storage of the subject may be used.
+// The most permissive group wins, it's allowed if no group applies to the subject.
+func (l GroupList) Evaluate(used *Used, subject LimitSubject) bool {
+	applies := false
+	for _, g := range l {
+		ok, has := g.Evaluate(used, subject)
+		if ok {
+			return true
+		}
+		applies = applies || has
+	}
+	return !applies
+}
+
+func (l GroupList) loadRules(ctx context.Context) error {
+	for _, g := range l {
+		if err := g.LoadRules(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetGroupByName gets a group by its name, the rules of the group are loaded
+func GetGroupByName(ctx context.Context, name string) (*Group, error) {
+	g := &Group{}
+	has, err := db.GetEngine(ctx).Where("lower_name=?", strings.ToLower(name)).Get(g)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, ErrGroupNotExist{Name: name}
+	}
+	return g, g.LoadRules(ctx)
+}
+
+// GetGroups returns all groups ordered by name, the rules of the groups are loaded
+func GetGroups(ctx context.Context) (GroupList, error) {
+	groups := make(GroupList, 0, 10)
+	if err := db.GetEngine(ctx).OrderBy("lower_name").Find(&groups); err != nil {
+		return nil, err
+	}
+	return groups, groups.loadRules(ctx)
+}
+
+// GetGroupsForUser returns the groups which are assigned to the user or organization.
+// The default groups are returned if no group is assigned.
+func GetGroupsForUser(ctx context.Context, userID int64) (GroupList, error) {
+	groups := make(GroupList, 0, 5)
+	if err := db.GetEngine(ctx).
+		Where(builder.In("id", builder.Select("group_id").From("quota_group_mapping").Where(builder.Eq{"user_id": userID}))).
+		OrderBy("lower_name").
+		Find(&groups); err != nil {
+		return nil, err
+	}
+
+	if len(groups) == 0 && len(setting.Quota.DefaultGroups) > 0 {
+		names := make([]string, 0, len(setting.Quota.DefaultGroups))
+		for _, name := range setting.Quota.DefaultGroups {
+			names = append(names, strings.ToLower(name))
+		}
+		if err := db.GetEngine(ctx).In("lower_name", names).OrderBy("lower_name").Find(&groups); err != nil {
+			return nil, err
+		}
+	}
+
+	return groups, groups.loadRules(ctx)
+}
+
+// GetUserIDsOfGroup returns the ids of the users and organizations which are assigned to the group
+func GetUserIDsOfGroup(ctx context.Context, g *Group) ([]int64, error) {
+	ids := make([]int64, 0, 10)
+	return ids, db.GetEngine(ctx).Table("quota_group_mapping").Where("group_id=?", g.ID).Cols("user_id").OrderBy("user_id").Find(&ids)
+}
+
+// CreateGroup creates a group
+func CreateGroup(ctx context.Context, name string) (*Group, error) {
+	if _, err := GetGroupByName(ctx, name); err == nil {
+		return nil, ErrGroupAlreadyExist{Name: name}
+	} else if !IsErrGroupNotExist(err) {
+		return nil, err
+	}
+
+	g := &Group{
+		Name:      name,
+		LowerName: strings.ToLower(name),
+		Rules:     []*Rule{},
+	}
+	_, err := db.GetEngine(ctx).Insert(g)
+	return g, err
+}
+
+// DeleteGroup deletes a group and its assignments
+func DeleteGroup(ctx context.Context, g *Group) error {
+	return db.WithTx(ctx, func(ctx context.Context) error {
+		if _, err := db.GetEngine(ctx).Where("group_id=?", g.ID).Delete(&GroupRuleMapping{}); err != nil {
+			return err
+		}
+		if _, err := db.GetEngine(ctx).Where("group_id=?", g.ID).Delete(&GroupMapping{}); err != nil {
+			return err
+		}
+		_, err := db.GetEngine(ctx).ID(g.ID).Delete(&Group{})
+		return err
+	})
+}
+
+// AddRuleToGroup adds a rule to a group, it's a no-op if the rule is already part of the group
+func AddRuleToGroup(ctx context.Context, g *Group, r *Rule) error {
+	has, err := db.GetEngine(ctx).Exist(&GroupRuleMapping{GroupID: g.ID, RuleID: r.ID})
+	if err != nil || has {
+		return err
+	}
+	_, err = db.GetEngine(ctx).Insert(&GroupRuleMapping{GroupID: g.ID, RuleID: r.ID})
+	return err
+}
+
+// RemoveRuleFromGroup removes a rule from a group
+func RemoveRuleFromGroup(ctx context.Context, g *Group, r *Rule) error {
+	_, err := db.GetEngine(ctx).Delete(&GroupRuleMapping{GroupID: g.ID, RuleID: r.ID})
+	return err
+}
+
+// AddUserToGroup assigns a group to a user or organization, it's a no-op if the group is already assigned
+func AddUserToGroup(ctx context.Context, g *Group, userID int64) error {
+	has, err := db.GetEngine(ctx).Exist(&GroupMapping{GroupID: g.ID, UserID: userID})
+	if err != nil || has {
+		return err
+	}
+	_, err = db.GetEngine(ctx).Insert(&GroupMapping{GroupID: g.ID, UserID: userID})
+	return err
+}
+
+// RemoveUserFromGroup removes the assignment of a group to a user or organization
+func RemoveUserFromGroup(ctx context.Context, g *Group, userID int64) error {
+	_, err := db.GetEngine(ctx).Delete(&GroupMapping{GroupID: g.ID, UserID: userID})
+	return err
+}
@@ -0,0 +1,138 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package quota
+
+import (
+	"fmt"
+	"slices"
+)
+
+// LimitSubject is a kind of storage which is limited by a quota rule.
+// The subjects form a hierarchy, a subject covers itself and all its children.
+type LimitSubject int
+
+const (
+	LimitSubjectNone LimitSubject = iota
+	LimitSubjectSizeAll
+	LimitSubjectSizeReposAll
+	LimitSubjectSizeReposGit
+	LimitSubjectSizeReposLFS
+	LimitSubjectSizeAssetsAll
+	LimitSubjectSizeAssetsAttachments
+	LimitSubjectSizeAssetsArtifacts
+	LimitSubjectSizeAssetsPackages
+)
+
+var limitSubjectNames = map[LimitSubject]string{
+	LimitSubjectNone:                  "none",
+	LimitSubjectSizeAll:               "size:all",
+	LimitSubjectSizeReposAll:          "size:repos:all",
+	LimitSubjectSizeReposGit:          "size:repos:git",
+	LimitSubjectSizeReposLFS:          "size:repos:lfs",
+	LimitSubjectSizeAssetsAll:         "size:assets:all",
+	LimitSubjectSizeAssetsAttachments: "size:assets:attachments",
+	LimitSubjectSizeAssetsArtifacts:   "size:assets:artifacts",
+	LimitSubjectSizeAssetsPackages:    "size:assets:packages",
+}
+
+var limitSubjectChildren = map[LimitSubject][]LimitSubject{
+	LimitSubjectSizeAll:       {LimitSubjectSizeReposAll, LimitSubjectSizeAssetsAll},
+	LimitSubjectSizeReposAll:  {LimitSubjectSizeReposGit, LimitSubjectSizeReposLFS},
+	LimitSubjectSizeAssetsAll: {LimitSubjectSizeAssetsAttachments, LimitSubjectSizeAssetsArtifacts, LimitSubjectSizeAssetsPackages},
+}
+
+// ParseLimitSubject parses the name of a subject
+func ParseLimitSubject(name string) (LimitSubject, error) {
+	for subject, n := range limitSubjectNames {
+		if n == name {
+			return subject, nil
+		}
+	}
+	return LimitSubjectNone, ErrInvalidLimitSubject{Name: name}
+}
+
+// ParseLimitSubjects parses a list of subject names
+func ParseLimitSubjects(names []string) (LimitSubjects, error) {
+	subjects := make(LimitSubjects, 0, len(names))
+	for _, name := range names {
+		subject, err := ParseLimitSubject(name)
+		if err != nil {
+			return nil, err
+		}
+		subjects = append(subjects, subject)
+	}
+	return subjects, nil
+}
+
+// String returns the name of the subject
+func (s LimitSubject) String() string {
+	if name, ok := limitSubjectNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown(%d)", int(s))
+}
+
+// Covers tests if the subject is the other subject or one of its ancestors
+func (s LimitSubject) Covers(other LimitSubject) bool {
+	if s == other {
+		return true
+	}
+	for _, child := range limitSubjectChildren[s] {
+		if child.Covers(other) {
+			return true
+		}
+	}
+	return false
+}
+
+// Leaves returns the subjects without children which are covered by the subject
+func (s LimitSubject) Leaves() []LimitSubject {
+	children, ok := limitSubjectChildren[s]
+	if !ok {
+		if s == LimitSubjectNone {
+			return nil
+		}
+		return []LimitSubject{s}
+	}
+	leaves := make([]LimitSubject, 0, 5)
+	for _, child := range children {
+		leaves = append(leaves, child.Leaves()...)
+	}
+	return leaves
+}
+
+// LimitSubjects is a list of subjects
+type LimitSubjects []LimitSubject
+
+// Covers tests if one of the subjects covers the other subject
+func (l LimitSubjects) Covers(other LimitSubject) bool {
+	for _, s := range l {
+		if s.Covers(other) {
+			return true
+		}
+	}
+	return false
+}
+
+// Leaves returns the distinct subjects without children which are covered by the subjects
+func (l LimitSubjects) Leaves() []LimitSubject {
+	leaves := make([]LimitSubject, 0, 5)
+	for _, s := range l {
+		for _, leaf := range s.Leaves() {
+			if !slices.Contains(leaves, leaf) {
+				leaves = append(leaves, leaf)
+			}
+		}
+	}
+	return leaves
+}
+
+// Names returns the names of the subjects
+func (l LimitSubjects) Names() []string {
+	names := make([]string, 0, len(l))
+	for _, s := range l {
+		names = append(names, s.String())
+	}
+	return names
+}
@@ -0,0 +1,86 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package quota
+
+import (
+	"context"
+	"fmt"
+
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/util"
+)
+
+// ErrQuotaExceeded represents a "QuotaExceeded" kind of error.
+type ErrQuotaExceeded struct {
+	UserID  int64
+	Subject LimitSubject
+}
+
+// IsErrQuotaExceeded checks if an error is a ErrQuotaExceeded.
+func IsErrQuotaExceeded(err error) bool {
+	_, ok := err.(ErrQuotaExceeded)
+	return ok
+}
+
+func (err ErrQuotaExceeded) Error() string {
+	return fmt.Sprintf("quota exceeded [user_id: %d, subject: %s]", err.UserID, err.Subject)
+}
+
+func (err ErrQuotaExceeded) Unwrap() error {
+	return util.ErrPermissionDenied
+}
+
+// ErrInvalidLimitSubject represents an "InvalidLimitSubject" kind of error.
+type ErrInvalidLimitSubject struct {
+	Name string
+}
+
+// IsErrInvalidLimitSubject checks if an error is a ErrInvalidLimitSubject.
+func IsErrInvalidLimitSubject(err error) bool {
+	_, ok := err.(ErrInvalidLimitSubject)
+	return ok
+}
+
+func (err ErrInvalidLimitSubject) Error() string {
+	return fmt.Sprintf("invalid quota limit subject [name: %s]", err.Name)
+}
+
+func (err ErrInvalidLimitSubject) Unwrap() error {
+	return util.ErrInvalidArgument
+}
+
+// EvaluateForUser tests if the user or organization may use more storage of the subject.
+// It's always allowed if the quotas are disabled.
+func EvaluateForUser(ctx context.Context, userID int64, subject LimitSubject) (bool, error) {
+	if !setting.Quota.Enabled {
+		return true, nil
+	}
+
+	groups, err := GetGroupsForUser(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	if len(groups) == 0 {
+		return true, nil
+	}
+
+	used, err := GetUsedForUser(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return groups.Evaluate(used, subject), nil
+}
+
+// CheckForUser returns an ErrQuotaExceeded error if the user or organization may not use more storage of the subject
+func CheckForUser(ctx context.Context, userID int64, subject LimitSubject) error {
+	ok, err := EvaluateForUser(ctx, userID, subject)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrQuotaExceeded{UserID: userID, Subject: subject}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package quota
+
+import (
+	"testing"
+
+	"code.gitea.io/gitea/models/db"
+	repo_model "code.gitea.io/gitea/models/repo"
+	"code.gitea.io/gitea/models/unittest"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/test"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimitSubject(t *testing.T) {
+	s, err := ParseLimitSubject("size:repos:all")
+	assert.NoError(t, err)
+	assert.Equal(t, LimitSubjectSizeReposAll, s)
+	assert.Equal(t, "size:repos:all", s.String())
+
+	_, err = ParseLimitSubject("size:invalid")
+	assert.True(t, IsErrInvalidLimitSubject(err))
+
+	assert.True(t, LimitSubjectSizeAll.Covers(LimitSubjectSizeAssetsPackages))
+	assert.True(t, LimitSubjectSizeReposAll.Covers(LimitSubjectSizeReposLFS))
+	assert.True(t, LimitSubjectSizeReposGit.Covers(LimitSubjectSizeReposGit))
+	assert.False(t, LimitSubjectSizeReposGit.Covers(LimitSubjectSizeReposAll))
+	assert.False(t, LimitSubjectSizeAssetsAll.Covers(LimitSubjectSizeReposGit))
+
+	assert.Equal(t, []LimitSubject{LimitSubjectSizeReposGit, LimitSubjectSizeReposLFS}, LimitSubjectSizeReposAll.Leaves())
+	assert.Len(t, LimitSubjectSizeAll.Leaves(), 5)
+	assert.Empty(t, LimitSubjectNone.Leaves())
+
+	subjects := LimitSubjects{LimitSubjectSizeReposAll, LimitSubjectSizeReposLFS, LimitSubjectSizeAssetsPackages}
+	assert.Equal(t, []LimitSubject{LimitSubjectSizeReposGit, LimitSubjectSizeReposLFS, LimitSubjectSizeAssetsPackages}, subjects.Leaves())
+	assert.Equal(t, []string{"size:repos:all", "size:repos:lfs", "size:assets:packages"}, subjects.Names())
+}
+
+func TestEvaluate(t *testing.T) {
+	used := &Used{
+		Git:         100,
+		LFS:         50,
+		Attachments: 10,
+		Packages:    20,
+	}
+
+	assert.EqualValues(t, 150, used.Get(LimitSubjectSizeReposAll))
+	assert.EqualValues(t, 30, used.Get(LimitSubjectSizeAssetsAll))
+	assert.EqualValues(t, 180, used.Get(LimitSubjectSizeAll))
+
+	repos := &Rule{Name: "repos", Limit: 200, Subjects: LimitSubjects{LimitSubjectSizeReposAll}}
+	git := &Rule{Name: "git", Limit: 100, Subjects: LimitSubjects{LimitSubjectSizeReposGit}}
+	unlimited := &Rule{Name: "unlimited", Limit: -1, Subjects: LimitSubjects{LimitSubjectSizeAll}}
+
+	ok, applies := repos.Evaluate(used, LimitSubjectSizeReposLFS)
+	assert.True(t, ok)
+	assert.True(t, applies)
+	_, applies = repos.Evaluate(used, LimitSubjectSizeAssetsPackages)
+	assert.False(t, applies)
+	ok, applies = git.Evaluate(used, LimitSubjectSizeReposGit)
+	assert.False(t, ok)
+	assert.True(t, applies)
+
+	limited := &Group{Name: "limited", Rules: []*Rule{repos, git}}
+	assert.True(t, GroupList{limited}.Evaluate(used, LimitSubjectSizeReposLFS))
+	assert.False(t, GroupList{limited}.Evaluate(used, LimitSubjectSizeReposGit))
+	assert.True(t, GroupList{limited}.Evaluate(used, LimitSubjectSizeAssetsPackages))
+
+	// the most permissive group wins
+	assert.True(t, GroupList{limited, {Name: "unlimited", Rules: []*Rule{unlimited}}}.Evaluate(used, LimitSubjectSizeReposGit))
+	assert.True(t, GroupList{}.Evaluate(used, LimitSubjectSizeReposGit))
+}
+
+func TestCheckForUser(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	defer test.MockVariableValue(&setting.Quota.Enabled, true)()
+
+	used, err := GetUsedForUser(db.DefaultContext, 2)
+	assert.NoError(t, err)
+
+	assert.NoError(t, CheckForUser(db.DefaultContext, 2, LimitSubjectSizeAssetsAttachments))
+
+	r, err := CreateRule(db.DefaultContext, "Attachments", used.Attachments+100, LimitSubjects{LimitSubjectSizeAssetsAttachments})
+	assert.NoError(t, err)
+	_, err = CreateRule(db.DefaultContext, "attachments", -1, LimitSubjects{LimitSubjectSizeAll})
+	assert.True(t, IsErrRuleAlreadyExist(err))
+
+	g, err := CreateGroup(db.DefaultContext, "limited")
+	assert.NoError(t, err)
+	assert.NoError(t, AddRuleToGroup(db.DefaultContext, g, r))
+	assert.NoError(t, AddRuleToGroup(db.DefaultContext, g, r))
+
+	// the default groups apply to users without groups
+	defer test.MockVariableValue(&setting.Quota.DefaultGroups, []string{"Limited"})()
+
+	groups, err := GetGroupsForUser(db.DefaultContext, 2)
+	assert.NoError(t, err)
+	assert.Len(t, groups, 1)
+	assert.Len(t, groups[0].Rules, 1)
+
+	assert.NoError(t, CheckForUser(db.DefaultContext, 2, LimitSubjectSizeAssetsAttachments))
+
+	assert.NoError(t, db.Insert(db.DefaultContext, &repo_model.Attachment{RepoID: 1, Name: "quota.bin", Size: 100}))
+
+	used, err = GetUsedForUser(db.DefaultContext, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, r.Limit, used.Attachments)
+
+	err = CheckForUser(db.DefaultContext, 2, LimitSubjectSizeAssetsAttachments)
+	assert.True(t, IsErrQuotaExceeded(err))
+	assert.NoError(t, CheckForUser(db.DefaultContext, 2, LimitSubjectSizeReposGit))
+
+	// an assigned group replaces the default groups
+	unlimited, err := CreateGroup(db.DefaultContext, "unlimited")
+	assert.NoError(t, err)
+	assert.NoError(t, AddUserToGroup(db.DefaultContext, unlimited, 2))
+	assert.NoError(t, CheckForUser(db.DefaultContext, 2, LimitSubjectSizeAssetsAttachments))
+
+	userIDs, err := GetUserIDsOfGroup(db.DefaultContext, unlimited)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{2}, userIDs)
+
+	assert.NoError(t, DeleteGroup(db.DefaultContext, unlimited))
+	assert.True(t, IsErrQuotaExceeded(CheckForUser(db.DefaultContext, 2, LimitSubjectSizeAssetsAttachments)))
+
+	r.Limit = -1
+	assert.NoError(t, UpdateRule(db.DefaultContext, r))
+	assert.NoError(t, CheckForUser(db.DefaultContext, 2, LimitSubjectSizeAssetsAttachments))
+
+	assert.NoError(t, DeleteRule(db.DefaultContext, r))
+	g, err = GetGroupByName(db.DefaultContext, "limited")
+	assert.NoError(t, err)
+	assert.Empty(t, g.Rules)
+}
@@ -0,0 +1,143 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package quota
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/modules/util"
+)
+
+// ErrRuleNotExist represents a "RuleNotExist" kind of error.
+type ErrRuleNotExist struct {
+	Name string
+}
+
+// IsErrRuleNotExist checks if an error is a ErrRuleNotExist.
+func IsErrRuleNotExist(err error) bool {
+	_, ok := err.(ErrRuleNotExist)
+	return ok
+}
+
+func (err ErrRuleNotExist) Error() string {
+	return fmt.Sprintf("quota rule does not exist [name: %s]", err.Name)
+}
+
+func (err ErrRuleNotExist) Unwrap() error {
+	return util.ErrNotExist
+}
+
+// ErrRuleAlreadyExist represents a "RuleAlreadyExist" kind of error.
+type ErrRuleAlreadyExist struct {
+	Name string
+}
+
+// IsErrRuleAlreadyExist checks if an error is a ErrRuleAlreadyExist.
+func IsErrRuleAlreadyExist(err error) bool {
+	_, ok := err.(ErrRuleAlreadyExist)
+	return ok
+}
+
+func (err ErrRuleAlreadyExist) Error() string {
+	return fmt.Sprintf("quota rule already exists [name: %s]", err.Name)
+}
+
+func (err ErrRuleAlreadyExist) Unwrap() error {
+	return util.ErrAlreadyExist
+}
+
+// Rule limits the storage of the subjects, a limit of -1 means unlimited storage
+type Rule struct {
+	ID        int64         `xorm:"pk autoincr"`
+	Name      string        `xorm:"NOT NULL"`
+	LowerName string        `xorm:"UNIQUE NOT NULL"`
+	Limit     int64         `xorm:"'limit_size' NOT NULL DEFAULT -1"`
+	Subjects  LimitSubjects `xorm:"JSON TEXT"`
+}
+
+func init() {
+	db.RegisterModel(new(Rule))
+}
+
+// TableName sets the table name of the quota rules
+func (*Rule) TableName() string {
+	return "quota_rule"
+}
+
+// Sum returns the storage used by the subjects of the rule
+func (r *Rule) Sum(used *Used) int64 {
+	var sum int64
+	for _, subject := range r.Subjects.Leaves() {
+		sum += used.Get(subject)
+	}
+	return sum
+}
+
+// Evaluate tests if the rule applies to the subject and if it allows to use more storage
+func (r *Rule) Evaluate(used *Used, subject LimitSubject) (acceptable, applies bool) {
+	if !r.Subjects.Covers(subject) {
+		return false, false
+	}
+	if r.Limit < 0 {
+		return true, true
+	}
+	return r.Sum(used) < r.Limit, true
+}
+
+// GetRuleByName gets a rule by its name
+func GetRuleByName(ctx context.Context, name string) (*Rule, error) {
+	r := &Rule{}
+	has, err := db.GetEngine(ctx).Where("lower_name=?", strings.ToLower(name)).Get(r)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, ErrRuleNotExist{Name: name}
+	}
+	return r, nil
+}
+
+// GetRules returns all rules ordered by name
+func GetRules(ctx context.Context) ([]*Rule, error) {
+	rules := make([]*Rule, 0, 10)
+	return rules, db.GetEngine(ctx).OrderBy("lower_name").Find(&rules)
+}
+
+// CreateRule creates a rule
+func CreateRule(ctx context.Context, name string, limit int64, subjects LimitSubjects) (*Rule, error) {
+	if _, err := GetRuleByName(ctx, name); err == nil {
+		return nil, ErrRuleAlreadyExist{Name: name}
+	} else if !IsErrRuleNotExist(err) {
+		return nil, err
+	}
+
+	r := &Rule{
+		Name:      name,
+		LowerName: strings.ToLower(name),
+		Limit:     limit,
+		Subjects:  subjects,
+	}
+	_, err := db.GetEngine(ctx).Insert(r)
+	return r, err
+}
+
+// UpdateRule updates the limit and the subjects of a rule
+func UpdateRule(ctx context.Context, r *Rule) error {
+	_, err := db.GetEngine(ctx).ID(r.ID).Cols("limit_size", "subjects").Update(r)
+	return err
+}
+
+// DeleteRule deletes a rule and removes it from all groups
+func DeleteRule(ctx context.Context, r *Rule) error {
+	return db.WithTx(ctx, func(ctx context.Context) error {
+		if _, err := db.GetEngine(ctx).Where("rule_id=?", r.ID).Delete(&GroupRuleMapping{}); err != nil {
+			return err
+		}
+		_, err := db.GetEngine(ctx).ID(r.ID).Delete(&Rule{})
+		return err
+	})
+}
@@ -0,0 +1,85 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package quota
+
+import (
+	"context"
+
+	actions_model "code.gitea.io/gitea/models/actions"
+	"code.gitea.io/gitea/models/db"
+	git_model "code.gitea.io/gitea/models/git"
+	packages_model "code.gitea.io/gitea/models/packages"
+	repo_model "code.gitea.io/gitea/models/repo"
+
+	"xorm.io/builder"
+)
+
+// Used is the storage used by a user or an organization
+type Used struct {
+	Git         int64
+	LFS         int64
+	Attachments int64
+	Artifacts   int64
+	Packages    int64
+}
+
+// Get returns the storage used by the subject
+func (u *Used) Get(subject LimitSubject) int64 {
+	switch subject {
+	case LimitSubjectSizeReposGit:
+		return u.Git
+	case LimitSubjectSizeReposLFS:
+		return u.LFS
+	case LimitSubjectSizeAssetsAttachments:
+		return u.Attachments
+	case LimitSubjectSizeAssetsArtifacts:
+		return u.Artifacts
+	case LimitSubjectSizeAssetsPackages:
+		return u.Packages
+	}
+
+	var sum int64
+	for _, leaf := range subject.Leaves() {
+		sum += u.Get(leaf)
+	}
+	return sum
+}
+
+// GetUsedForUser calculates the storage used by the repositories, assets and packages of a user or an organization
+func GetUsedForUser(ctx context.Context, userID int64) (*Used, error) {
+	used := &Used{}
+
+	var err error
+	if used.Git, err = db.GetEngine(ctx).Where("owner_id=?", userID).SumInt(new(repo_model.Repository), "git_size"); err != nil {
+		return nil, err
+	}
+
+	ownedRepos := builder.Select("id").From("repository").Where(builder.Eq{"owner_id": userID})
+
+	if used.LFS, err = db.GetEngine(ctx).Where(builder.In("repository_id", ownedRepos)).SumInt(new(git_model.LFSMetaObject), "size"); err != nil {
+		return nil, err
+	}
+
+	if used.Attachments, err = db.GetEngine(ctx).Where(builder.In("repo_id", ownedRepos)).SumInt(new(repo_model.Attachment), "size"); err != nil {
+		return nil, err
+	}
+
+	if used.Artifacts, err = db.GetEngine(ctx).Where(builder.Eq{"owner_id": userID}.And(builder.In("status", actions_model.ArtifactStatusUploadPending, actions_model.ArtifactStatusUploadConfirmed))).
+		SumInt(new(actions_model.ActionArtifact), "file_compressed_size"); err != nil {
+		return nil, err
+	}
+
+	// blobs are shared by the files of an owner, every blob is counted only once
+	ownedBlobs := builder.Select("package_file.blob_id").
+		From("package_file").
+		InnerJoin("package_version", "package_version.id = package_file.version_id").
+		InnerJoin("package", "package.id = package_version.package_id").
+		Where(builder.Eq{"package.owner_id": userID})
+
+	if used.Packages, err = db.GetEngine(ctx).Where(builder.In("id", ownedBlobs)).SumInt(new(packages_model.PackageBlob), "size"); err != nil {
+		return nil, err
+	}
+
+	return used, nil
+}
@@ -197,8 +197,27 @@ func (cfg *PullRequestsConfig) GetDefaultMergeStyle() MergeStyle {
 	return MergeStyleMerge
 }
 
+// ActionsApprovalPolicy defines which runs triggered by the pull requests from forks wait for the approval of a maintainer
+type ActionsApprovalPolicy string
+
+const (
+	// ActionsApprovalFirstTimeContributors requires an approval for the contributors who have neither a merged pull request nor an approved run
+	ActionsApprovalFirstTimeContributors ActionsApprovalPolicy = "first_time_contributors"
+	// ActionsApprovalAllForks requires an approval for all the pull requests from forks
+	ActionsApprovalAllForks ActionsApprovalPolicy = "all_forks"
+)
+
 type ActionsConfig struct {
 	DisabledWorkflows []string
+	ApprovalPolicy    ActionsApprovalPolicy
+}
+
+// GetApprovalPolicy returns the approval policy of the runs, ActionsApprovalFirstTimeContributors if it isn't set
+func (cfg *ActionsConfig) GetApprovalPolicy() ActionsApprovalPolicy {
+	if cfg.ApprovalPolicy == ActionsApprovalAllForks {
+		return ActionsApprovalAllForks
+	}
+	return ActionsApprovalFirstTimeContributors
 }
 
 func (cfg *ActionsConfig) EnableWorkflow(file string) {
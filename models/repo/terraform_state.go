@@ -0,0 +1,220 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package repo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/modules/timeutil"
+	"code.gitea.io/gitea/modules/util"
+)
+
+var (
+	ErrTerraformStateNotExist        = util.NewNotExistErrorf("terraform state does not exist")
+	ErrTerraformStateVersionNotExist = util.NewNotExistErrorf("terraform state version does not exist")
+)
+
+// TerraformState represents a Terraform state stored by the HTTP state backend of a repository.
+// The content of the state is stored in its versions, a state without versions is only locked.
+type TerraformState struct {
+	ID          int64              `xorm:"pk autoincr"`
+	RepoID      int64              `xorm:"UNIQUE(s) INDEX NOT NULL"`
+	Name        string             `xorm:"NOT NULL"`
+	LowerName   string             `xorm:"UNIQUE(s) NOT NULL"`
+	LockID      string             `xorm:"NOT NULL DEFAULT ''"`
+	LockInfo    string             `xorm:"TEXT"`
+	LockerID    int64              `xorm:"NOT NULL DEFAULT 0"`
+	LockedUnix  timeutil.TimeStamp `xorm:"NOT NULL DEFAULT 0"`
+	CreatedUnix timeutil.TimeStamp `xorm:"created NOT NULL"`
+	UpdatedUnix timeutil.TimeStamp `xorm:"updated NOT NULL"`
+}
+
+// TerraformStateVersion represents a version of the content of a Terraform state
+type TerraformStateVersion struct {
+	ID               int64              `xorm:"pk autoincr"`
+	RepoID           int64              `xorm:"INDEX NOT NULL"`
+	StateID          int64              `xorm:"INDEX NOT NULL"`
+	Serial           int64              `xorm:"NOT NULL DEFAULT 0"`
+	Lineage          string             `xorm:"NOT NULL DEFAULT ''"`
+	TerraformVersion string             `xorm:"NOT NULL DEFAULT ''"`
+	Size             int64              `xorm:"NOT NULL DEFAULT 0"`
+	CreatorID        int64              `xorm:"NOT NULL DEFAULT 0"`
+	CreatedUnix      timeutil.TimeStamp `xorm:"created INDEX NOT NULL"`
+}
+
+func init() {
+	db.RegisterModel(new(TerraformState))
+	db.RegisterModel(new(TerraformStateVersion))
+}
+
+// IsLocked tests if the state is locked
+func (s *TerraformState) IsLocked() bool {
+	return s.LockID != ""
+}
+
+// RelativePath returns the path of the content of the version relative to the Terraform state storage root
+func (v *TerraformStateVersion) RelativePath() string {
+	return fmt.Sprintf("%d/%d/%d.tfstate", v.RepoID, v.StateID, v.ID)
+}
+
+// GetTerraformState gets a state of a repository by its name
+func GetTerraformState(ctx context.Context, repoID int64, name string) (*TerraformState, error) {
+	s := &TerraformState{}
+	has, err := db.GetEngine(ctx).Where("repo_id=? AND lower_name=?", repoID, strings.ToLower(name)).Get(s)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, ErrTerraformStateNotExist
+	}
+	return s, nil
+}
+
+// GetTerraformStateByID gets a state of a repository by its id
+func GetTerraformStateByID(ctx context.Context, repoID, id int64) (*TerraformState, error) {
+	s := &TerraformState{}
+	has, err := db.GetEngine(ctx).Where("repo_id=? AND id=?", repoID, id).Get(s)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, ErrTerraformStateNotExist
+	}
+	return s, nil
+}
+
+// GetOrInsertTerraformState gets a state of a repository by its name, it's created if it doesn't exist
+func GetOrInsertTerraformState(ctx context.Context, repoID int64, name string) (*TerraformState, error) {
+	s, err := GetTerraformState(ctx, repoID, name)
+	if err != ErrTerraformStateNotExist {
+		return s, err
+	}
+
+	s = &TerraformState{
+		RepoID:    repoID,
+		Name:      name,
+		LowerName: strings.ToLower(name),
+	}
+	if _, err := db.GetEngine(ctx).Insert(s); err != nil {
+		// the state may have been created by a concurrent request
+		if s, getErr := GetTerraformState(ctx, repoID, name); getErr == nil {
+			return s, nil
+		}
+		return nil, err
+	}
+	return s, nil
+}
+
+// GetTerraformStates returns the states of a repository ordered by name
+func GetTerraformStates(ctx context.Context, repoID int64) ([]*TerraformState, error) {
+	states := make([]*TerraformState, 0, 10)
+	return states, db.GetEngine(ctx).Where("repo_id=?", repoID).OrderBy("lower_name").Find(&states)
+}
+
+// LockTerraformState locks a state if it isn't locked yet and reports if the lock has been acquired
+func LockTerraformState(ctx context.Context, s *TerraformState, lockID, lockInfo string, lockerID int64) (bool, error) {
+	lock := &TerraformState{
+		LockID:     lockID,
+		LockInfo:   lockInfo,
+		LockerID:   lockerID,
+		LockedUnix: timeutil.TimeStampNow(),
+	}
+	n, err := db.GetEngine(ctx).Where("id=? AND lock_id=''", s.ID).Cols("lock_id", "lock_info", "locker_id", "locked_unix").Update(lock)
+	if err != nil || n != 1 {
+		return false, err
+	}
+	s.LockID = lock.LockID
+	s.LockInfo = lock.LockInfo
+	s.LockerID = lock.LockerID
+	s.LockedUnix = lock.LockedUnix
+	return true, nil
+}
+
+// UnlockTerraformState unlocks a state. If the lock id isn't empty, the state is only unlocked if it's locked with this id.
+func UnlockTerraformState(ctx context.Context, s *TerraformState, lockID string) (bool, error) {
+	sess := db.GetEngine(ctx).Where("id=?", s.ID)
+	if lockID != "" {
+		sess = sess.And("lock_id=?", lockID)
+	}
+	n, err := sess.Cols("lock_id", "lock_info", "locker_id", "locked_unix").Update(&TerraformState{})
+	if err != nil || n != 1 {
+		return false, err
+	}
+	s.LockID = ""
+	s.LockInfo = ""
+	s.LockerID = 0
+	s.LockedUnix = 0
+	return true, nil
+}
+
+// DeleteTerraformState deletes a state and its versions, it returns the versions to remove their content
+func DeleteTerraformState(ctx context.Context, s *TerraformState) ([]*TerraformStateVersion, error) {
+	versions, err := GetTerraformStateVersions(ctx, s.ID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.GetEngine(ctx).Where("state_id=?", s.ID).Delete(&TerraformStateVersion{}); err != nil {
+		return nil, err
+	}
+	if _, err := db.GetEngine(ctx).ID(s.ID).Delete(&TerraformState{}); err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+// InsertTerraformStateVersion inserts a version of a state
+func InsertTerraformStateVersion(ctx context.Context, v *TerraformStateVersion) error {
+	_, err := db.GetEngine(ctx).Insert(v)
+	return err
+}
+
+// GetTerraformStateVersions returns the versions of a state, the latest first
+func GetTerraformStateVersions(ctx context.Context, stateID int64) ([]*TerraformStateVersion, error) {
+	versions := make([]*TerraformStateVersion, 0, 10)
+	return versions, db.GetEngine(ctx).Where("state_id=?", stateID).OrderBy("id DESC").Find(&versions)
+}
+
+// GetTerraformStateVersionByID gets a version of a state by its id
+func GetTerraformStateVersionByID(ctx context.Context, stateID, id int64) (*TerraformStateVersion, error) {
+	v := &TerraformStateVersion{}
+	has, err := db.GetEngine(ctx).Where("state_id=? AND id=?", stateID, id).Get(v)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, ErrTerraformStateVersionNotExist
+	}
+	return v, nil
+}
+
+// GetLatestTerraformStateVersion gets the current version of a state
+func GetLatestTerraformStateVersion(ctx context.Context, stateID int64) (*TerraformStateVersion, error) {
+	v := &TerraformStateVersion{}
+	has, err := db.GetEngine(ctx).Where("state_id=?", stateID).OrderBy("id DESC").Get(v)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, ErrTerraformStateVersionNotExist
+	}
+	return v, nil
+}
+
+// DeleteOldTerraformStateVersions deletes the versions of a state beyond the given count of the latest versions,
+// it returns the deleted versions to remove their content
+func DeleteOldTerraformStateVersions(ctx context.Context, stateID int64, keep int) ([]*TerraformStateVersion, error) {
+	versions := make([]*TerraformStateVersion, 0, 10)
+	if err := db.GetEngine(ctx).Where("state_id=?", stateID).OrderBy("id DESC").Limit(1000, keep).Find(&versions); err != nil {
+		return nil, err
+	}
+	for _, v := range versions {
+		if _, err := db.GetEngine(ctx).ID(v.ID).Delete(&TerraformStateVersion{}); err != nil {
+			return nil, err
+		}
+	}
+	return versions, nil
+}
@@ -0,0 +1,83 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package repo_test
+
+import (
+	"testing"
+
+	"code.gitea.io/gitea/models/db"
+	repo_model "code.gitea.io/gitea/models/repo"
+	"code.gitea.io/gitea/models/unittest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTerraformStateLock(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+
+	s, err := repo_model.GetOrInsertTerraformState(db.DefaultContext, 1, "Default")
+	require.NoError(t, err)
+
+	// the name of a state is case insensitive
+	s2, err := repo_model.GetOrInsertTerraformState(db.DefaultContext, 1, "default")
+	require.NoError(t, err)
+	assert.Equal(t, s.ID, s2.ID)
+
+	locked, err := repo_model.LockTerraformState(db.DefaultContext, s, "lock-1", "{}", 2)
+	require.NoError(t, err)
+	assert.True(t, locked)
+
+	locked, err = repo_model.LockTerraformState(db.DefaultContext, s2, "lock-2", "{}", 2)
+	require.NoError(t, err)
+	assert.False(t, locked)
+
+	unlocked, err := repo_model.UnlockTerraformState(db.DefaultContext, s, "lock-2")
+	require.NoError(t, err)
+	assert.False(t, unlocked)
+
+	unlocked, err = repo_model.UnlockTerraformState(db.DefaultContext, s, "lock-1")
+	require.NoError(t, err)
+	assert.True(t, unlocked)
+
+	s, err = repo_model.GetTerraformStateByID(db.DefaultContext, 1, s.ID)
+	require.NoError(t, err)
+	assert.False(t, s.IsLocked())
+}
+
+func TestDeleteOldTerraformStateVersions(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+
+	s, err := repo_model.GetOrInsertTerraformState(db.DefaultContext, 1, "default")
+	require.NoError(t, err)
+
+	for serial := int64(1); serial <= 5; serial++ {
+		require.NoError(t, repo_model.InsertTerraformStateVersion(db.DefaultContext, &repo_model.TerraformStateVersion{
+			RepoID:  1,
+			StateID: s.ID,
+			Serial:  serial,
+		}))
+	}
+
+	deleted, err := repo_model.DeleteOldTerraformStateVersions(db.DefaultContext, s.ID, 3)
+	require.NoError(t, err)
+	assert.Len(t, deleted, 2)
+
+	versions, err := repo_model.GetTerraformStateVersions(db.DefaultContext, s.ID)
+	require.NoError(t, err)
+	assert.Len(t, versions, 3)
+	assert.EqualValues(t, 5, versions[0].Serial)
+	assert.EqualValues(t, 3, versions[2].Serial)
+
+	latest, err := repo_model.GetLatestTerraformStateVersion(db.DefaultContext, s.ID)
+	require.NoError(t, err)
+	assert.Equal(t, versions[0].ID, latest.ID)
+
+	deleted, err = repo_model.DeleteTerraformState(db.DefaultContext, s)
+	require.NoError(t, err)
+	assert.Len(t, deleted, 3)
+
+	_, err = repo_model.GetTerraformState(db.DefaultContext, 1, "default")
+	assert.ErrorIs(t, err, repo_model.ErrTerraformStateNotExist)
+}
@@ -23,12 +23,13 @@ import (
 
 // Secret represents a secret
 type Secret struct {
-	ID          int64
-	OwnerID     int64              `xorm:"INDEX UNIQUE(owner_repo_name) NOT NULL"`
-	RepoID      int64              `xorm:"INDEX UNIQUE(owner_repo_name) NOT NULL DEFAULT 0"`
-	Name        string             `xorm:"UNIQUE(owner_repo_name) NOT NULL"`
-	Data        string             `xorm:"LONGTEXT"` // encrypted data
-	CreatedUnix timeutil.TimeStamp `xorm:"created NOT NULL"`
+	ID            int64
+	OwnerID       int64              `xorm:"INDEX UNIQUE(owner_repo_name) NOT NULL"`
+	RepoID        int64              `xorm:"INDEX UNIQUE(owner_repo_name) NOT NULL DEFAULT 0"`
+	EnvironmentID int64              `xorm:"INDEX UNIQUE(owner_repo_name) NOT NULL DEFAULT 0"` // a secret of an environment of the repository
+	Name          string             `xorm:"UNIQUE(owner_repo_name) NOT NULL"`
+	Data          string             `xorm:"LONGTEXT"` // encrypted data
+	CreatedUnix   timeutil.TimeStamp `xorm:"created NOT NULL"`
 }
 
 // ErrSecretNotFound represents a "secret not found" error.
@@ -45,16 +46,17 @@ func (err ErrSecretNotFound) Unwrap() error {
 }
 
 // InsertEncryptedSecret Creates, encrypts, and validates a new secret with yet unencrypted data and insert into database
-func InsertEncryptedSecret(ctx context.Context, ownerID, repoID int64, name, data string) (*Secret, error) {
+func InsertEncryptedSecret(ctx context.Context, ownerID, repoID, environmentID int64, name, data string) (*Secret, error) {
 	encrypted, err := secret_module.EncryptSecret(setting.SecretKey, data)
 	if err != nil {
 		return nil, err
 	}
 	secret := &Secret{
-		OwnerID: ownerID,
-		RepoID:  repoID,
-		Name:    strings.ToUpper(name),
-		Data:    encrypted,
+		OwnerID:       ownerID,
+		RepoID:        repoID,
+		EnvironmentID: environmentID,
+		Name:          strings.ToUpper(name),
+		Data:          encrypted,
 	}
 	if err := secret.Validate(); err != nil {
 		return secret, err
@@ -70,15 +72,19 @@ func (s *Secret) Validate() error {
 	if s.OwnerID == 0 && s.RepoID == 0 {
 		return errors.New("the secret is not bound to any scope")
 	}
+	if s.EnvironmentID != 0 && s.RepoID == 0 {
+		return errors.New("the secret of an environment is not bound to a repository")
+	}
 	return nil
 }
 
 type FindSecretsOptions struct {
 	db.ListOptions
-	OwnerID  int64
-	RepoID   int64
-	SecretID int64
-	Name     string
+	OwnerID       int64
+	RepoID        int64
+	EnvironmentID int64 // the secrets of an environment are only found with its ID
+	SecretID      int64
+	Name          string
 }
 
 func (opts FindSecretsOptions) ToConds() builder.Cond {
@@ -89,6 +95,7 @@ func (opts FindSecretsOptions) ToConds() builder.Cond {
 	if opts.RepoID > 0 {
 		cond = cond.And(builder.Eq{"repo_id": opts.RepoID})
 	}
+	cond = cond.And(builder.Eq{"environment_id": opts.EnvironmentID})
 	if opts.SecretID != 0 {
 		cond = cond.And(builder.Eq{"id": opts.SecretID})
 	}
@@ -140,7 +147,17 @@ func GetSecretsOfTask(ctx context.Context, task *actions_model.ActionTask) (map[
 		return nil, err
 	}
 
-	for _, secret := range append(ownerSecrets, repoSecrets...) {
+	var environmentSecrets []*Secret
+	if task.Job.EnvironmentID > 0 {
+		environmentSecrets, err = db.Find[Secret](ctx, FindSecretsOptions{RepoID: task.Job.Run.RepoID, EnvironmentID: task.Job.EnvironmentID})
+		if err != nil {
+			log.Error("find secrets of environment %v: %v", task.Job.EnvironmentID, err)
+			return nil, err
+		}
+	}
+
+	// the secrets of the environment of the job take precedence over the secrets of the repository
+	for _, secret := range append(append(ownerSecrets, repoSecrets...), environmentSecrets...) {
 		v, err := secret_module.DecryptSecret(setting.SecretKey, secret.Data)
 		if err != nil {
 			log.Error("decrypt secret %v %q: %v", secret.ID, secret.Name, err)
@@ -149,5 +166,32 @@ func GetSecretsOfTask(ctx context.Context, task *actions_model.ActionTask) (map[
 		secrets[secret.Name] = v
 	}
 
+	if task.Job.ParentJobID > 0 {
+		return getSecretsOfCalledWorkflow(ctx, task, secrets)
+	}
+	return secrets, nil
+}
+
+// getSecretsOfCalledWorkflow returns the secrets passed down to the job of a called workflow
+// by the jobs calling the reusable workflows, from the outermost one.
+func getSecretsOfCalledWorkflow(ctx context.Context, task *actions_model.ActionTask, secrets map[string]string) (map[string]string, error) {
+	var callers []*actions_model.ActionRunJob
+	for id := task.Job.ParentJobID; id > 0; {
+		caller, err := actions_model.GetRunJobByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		callers = append(callers, caller)
+		id = caller.ParentJobID
+	}
+
+	for i := len(callers) - 1; i >= 0; i-- {
+		if callers[i].CallInheritSecrets {
+			continue
+		}
+		secrets = actions_module.EvaluateWorkflowCallSecrets(callers[i].CallSecrets, secrets)
+		secrets["GITHUB_TOKEN"] = task.Token
+		secrets["GITEA_TOKEN"] = task.Token
+	}
 	return secrets, nil
 }
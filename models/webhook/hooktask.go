@@ -46,6 +46,7 @@ type HookTask struct {
 	ID             int64  `xorm:"pk autoincr"`
 	HookID         int64  `xorm:"index"`
 	UUID           string `xorm:"unique"`
+	DeliveryUUID   string `xorm:"index"` // sent to the receivers, it is the UUID of the first attempt and is kept by the automatic retries
 	PayloadContent string `xorm:"LONGTEXT"`
 	// PayloadVersion number to allow for smooth version upgrades:
 	//  - PayloadVersion 1: PayloadContent contains the JSON as sent to the URL
@@ -54,7 +55,10 @@ type HookTask struct {
 
 	EventType   webhook_module.HookEventType
 	IsDelivered bool
-	Delivered   timeutil.TimeStampNano
+	// Delivered is the time of the delivery, or the planned time for retries which are not delivered yet
+	Delivered timeutil.TimeStampNano
+	// Attempt is 1 for the first delivery and is incremented for each automatic retry
+	Attempt int `xorm:"NOT NULL DEFAULT 1"`
 
 	// History info.
 	IsSucceed       bool
@@ -116,16 +120,31 @@ func HookTasks(ctx context.Context, hookID int64, page int) ([]*HookTask, error)
 		Find(&tasks)
 }
 
+// GetDeliveryUUID returns the UUID identifying the delivery to the receivers, the tasks created before
+// the retries kept it only have their own UUID.
+func (t *HookTask) GetDeliveryUUID() string {
+	if t.DeliveryUUID == "" {
+		return t.UUID
+	}
+	return t.DeliveryUUID
+}
+
 // CreateHookTask creates a new hook task,
 // it handles conversion from Payload to PayloadContent.
 func CreateHookTask(ctx context.Context, t *HookTask) (*HookTask, error) {
 	t.UUID = gouuid.New().String()
+	if t.DeliveryUUID == "" {
+		t.DeliveryUUID = t.UUID
+	}
 	if t.Delivered == 0 {
 		t.Delivered = timeutil.TimeStampNanoNow()
 	}
 	if t.PayloadVersion == 0 {
 		return nil, errors.New("missing HookTask.PayloadVersion")
 	}
+	if t.Attempt == 0 {
+		t.Attempt = 1
+	}
 	return t, db.Insert(ctx, t)
 }
 
@@ -170,6 +189,20 @@ func ReplayHookTask(ctx context.Context, hookID int64, uuid string) (*HookTask,
 	})
 }
 
+// CreateRetryHookTask copies a failed hook task to get re-delivered at the given time,
+// the copy keeps the delivery UUID so that the receivers can recognize the retries.
+func CreateRetryHookTask(ctx context.Context, t *HookTask, at time.Time) (*HookTask, error) {
+	return CreateHookTask(ctx, &HookTask{
+		HookID:         t.HookID,
+		DeliveryUUID:   t.GetDeliveryUUID(),
+		PayloadContent: t.PayloadContent,
+		EventType:      t.EventType,
+		PayloadVersion: t.PayloadVersion,
+		Attempt:        t.Attempt + 1,
+		Delivered:      timeutil.TimeStampNano(at.UnixNano()),
+	})
+}
+
 // FindUndeliveredHookTaskIDs will find the next 100 undelivered hook tasks with ID greater than the provided lowerID
 func FindUndeliveredHookTaskIDs(ctx context.Context, lowerID int64) ([]int64, error) {
 	const batchSize = 100
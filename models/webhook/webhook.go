@@ -70,11 +70,17 @@ const (
 	ContentTypeJSON HookContentType = iota + 1
 	// ContentTypeForm is an url-encoded form payload for web hook
 	ContentTypeForm
+	// ContentTypeCloudEventsBinary is a JSON payload with the CloudEvents attributes in the ce-* headers
+	ContentTypeCloudEventsBinary
+	// ContentTypeCloudEventsStructured is a CloudEvents JSON envelope holding the attributes and the payload
+	ContentTypeCloudEventsStructured
 )
 
 var hookContentTypes = map[string]HookContentType{
-	"json": ContentTypeJSON,
-	"form": ContentTypeForm,
+	"json":                   ContentTypeJSON,
+	"form":                   ContentTypeForm,
+	"cloudevents_binary":     ContentTypeCloudEventsBinary,
+	"cloudevents_structured": ContentTypeCloudEventsStructured,
 }
 
 // ToHookContentType returns HookContentType by given name.
@@ -109,10 +115,19 @@ func (t HookContentType) Name() string {
 		return "json"
 	case ContentTypeForm:
 		return "form"
+	case ContentTypeCloudEventsBinary:
+		return "cloudevents_binary"
+	case ContentTypeCloudEventsStructured:
+		return "cloudevents_structured"
 	}
 	return ""
 }
 
+// IsCloudEvents returns true if the payloads are sent as CloudEvents
+func (t HookContentType) IsCloudEvents() bool {
+	return t == ContentTypeCloudEventsBinary || t == ContentTypeCloudEventsStructured
+}
+
 // IsValidHookContentType returns true if given name is a valid hook content type.
 func IsValidHookContentType(name string) bool {
 	_, ok := hookContentTypes[name]
@@ -135,6 +150,7 @@ type Webhook struct {
 	Type                      webhook_module.HookType   `xorm:"VARCHAR(16) 'type'"`
 	Meta                      string                    `xorm:"TEXT"` // store hook-specific attributes
 	LastStatus                webhook_module.HookStatus // Last delivery status
+	FailingSince              timeutil.TimeStamp        `xorm:"NOT NULL DEFAULT 0"` // Time of the first failed delivery since the last successful one
 
 	// HeaderAuthorizationEncrypted should be accessed using HeaderAuthorization() and SetHeaderAuthorization()
 	HeaderAuthorizationEncrypted string `xorm:"TEXT"`
@@ -475,7 +491,25 @@ func UpdateWebhook(ctx context.Context, w *Webhook) error {
 
 // UpdateWebhookLastStatus updates last status of webhook.
 func UpdateWebhookLastStatus(ctx context.Context, w *Webhook) error {
-	_, err := db.GetEngine(ctx).ID(w.ID).Cols("last_status").Update(w)
+	_, err := db.GetEngine(ctx).ID(w.ID).Cols("last_status", "failing_since").Update(w)
+	return err
+}
+
+// SetLastStatus sets the last delivery status and keeps track of how long the deliveries are failing
+func (w *Webhook) SetLastStatus(status webhook_module.HookStatus) {
+	w.LastStatus = status
+	if status == webhook_module.HookStatusSucceed {
+		w.FailingSince = 0
+	} else if w.FailingSince == 0 {
+		w.FailingSince = timeutil.TimeStampNow()
+	}
+}
+
+// DisableWebhook deactivates the webhook. The failure tracking is reset in the database
+// so a re-activated webhook starts over, but kept on w to allow reporting it.
+func DisableWebhook(ctx context.Context, w *Webhook) error {
+	w.IsActive = false
+	_, err := db.GetEngine(ctx).ID(w.ID).Cols("is_active", "failing_since").Update(&Webhook{})
 	return err
 }
 
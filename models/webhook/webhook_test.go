@@ -21,11 +21,14 @@ import (
 func TestHookContentType_Name(t *testing.T) {
 	assert.Equal(t, "json", ContentTypeJSON.Name())
 	assert.Equal(t, "form", ContentTypeForm.Name())
+	assert.Equal(t, "cloudevents_binary", ContentTypeCloudEventsBinary.Name())
+	assert.Equal(t, "cloudevents_structured", ContentTypeCloudEventsStructured.Name())
 }
 
 func TestIsValidHookContentType(t *testing.T) {
 	assert.True(t, IsValidHookContentType("json"))
 	assert.True(t, IsValidHookContentType("form"))
+	assert.True(t, IsValidHookContentType("cloudevents_structured"))
 	assert.False(t, IsValidHookContentType("invalid"))
 }
 
@@ -237,6 +240,57 @@ func TestCreateHookTask(t *testing.T) {
 	unittest.AssertExistsAndLoadBean(t, hookTask)
 }
 
+func TestCreateRetryHookTask(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	hookTask, err := CreateHookTask(db.DefaultContext, &HookTask{
+		HookID:         3,
+		PayloadVersion: 2,
+		PayloadContent: `{"data": 42}`,
+		EventType:      webhook_module.HookEventPush,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, hookTask.Attempt)
+
+	at := time.Now().Add(time.Minute)
+	retryTask, err := CreateRetryHookTask(db.DefaultContext, hookTask, at)
+	assert.NoError(t, err)
+
+	retryTask = unittest.AssertExistsAndLoadBean(t, &HookTask{ID: retryTask.ID})
+	assert.Equal(t, 2, retryTask.Attempt)
+	assert.Equal(t, hookTask.HookID, retryTask.HookID)
+	assert.Equal(t, hookTask.PayloadContent, retryTask.PayloadContent)
+	assert.Equal(t, hookTask.EventType, retryTask.EventType)
+	assert.NotEqual(t, hookTask.UUID, retryTask.UUID)
+	assert.Equal(t, hookTask.UUID, hookTask.GetDeliveryUUID())
+	assert.Equal(t, hookTask.GetDeliveryUUID(), retryTask.GetDeliveryUUID())
+
+	// the next retries keep the delivery UUID of the first attempt too
+	nextRetryTask, err := CreateRetryHookTask(db.DefaultContext, retryTask, at)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, nextRetryTask.Attempt)
+	assert.Equal(t, hookTask.GetDeliveryUUID(), nextRetryTask.GetDeliveryUUID())
+	assert.False(t, retryTask.IsDelivered)
+	assert.EqualValues(t, at.UnixNano(), retryTask.Delivered)
+}
+
+func TestWebhook_SetLastStatus(t *testing.T) {
+	w := &Webhook{}
+
+	w.SetLastStatus(webhook_module.HookStatusFail)
+	assert.Equal(t, webhook_module.HookStatusFail, w.LastStatus)
+	failingSince := w.FailingSince
+	assert.NotZero(t, failingSince)
+
+	w.FailingSince = failingSince - 100
+	w.SetLastStatus(webhook_module.HookStatusFail)
+	assert.Equal(t, failingSince-100, w.FailingSince)
+
+	w.SetLastStatus(webhook_module.HookStatusSucceed)
+	assert.Equal(t, webhook_module.HookStatusSucceed, w.LastStatus)
+	assert.Zero(t, w.FailingSince)
+}
+
 func TestUpdateHookTask(t *testing.T) {
 	assert.NoError(t, unittest.PrepareTestDatabase())
 
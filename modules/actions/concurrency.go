@@ -0,0 +1,135 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package actions
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/nektos/act/pkg/jobparser"
+	"github.com/nektos/act/pkg/model"
+	"gopkg.in/yaml.v3"
+)
+
+// RawConcurrency is the `concurrency` of a workflow or a job before its expressions are evaluated.
+// See https://docs.github.com/en/actions/writing-workflows/workflow-syntax-for-github-actions#concurrency
+type RawConcurrency struct {
+	Group            string `yaml:"group,omitempty"`
+	CancelInProgress string `yaml:"cancel-in-progress,omitempty"`
+}
+
+// UnmarshalYAML supports both the short form `concurrency: <group>` and the mapping form.
+func (rc *RawConcurrency) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		rc.Group = node.Value
+		return nil
+	}
+	type plain RawConcurrency
+	return node.Decode((*plain)(rc))
+}
+
+// Marshal returns the YAML representation stored in the database.
+func (rc *RawConcurrency) Marshal() string {
+	if rc == nil || rc.Group == "" {
+		return ""
+	}
+	out, _ := yaml.Marshal(rc)
+	return string(out)
+}
+
+// UnmarshalRawConcurrency parses a RawConcurrency previously returned by Marshal.
+// It returns nil if the content is empty.
+func UnmarshalRawConcurrency(content string) (*RawConcurrency, error) {
+	if content == "" {
+		return nil, nil
+	}
+	rc := &RawConcurrency{}
+	if err := yaml.Unmarshal([]byte(content), rc); err != nil {
+		return nil, err
+	}
+	return rc, nil
+}
+
+// ReadWorkflowRawConcurrency returns the workflow-level concurrency and the job-level concurrency of each job
+// defined in the workflow content. The jobparser drops these keys, so they are read from the raw content.
+func ReadWorkflowRawConcurrency(content []byte) (*RawConcurrency, map[string]*RawConcurrency, error) {
+	var workflow struct {
+		Concurrency *RawConcurrency `yaml:"concurrency"`
+		Jobs        map[string]struct {
+			Concurrency *RawConcurrency `yaml:"concurrency"`
+		} `yaml:"jobs"`
+	}
+	if err := yaml.Unmarshal(content, &workflow); err != nil {
+		return nil, nil, err
+	}
+
+	jobs := make(map[string]*RawConcurrency, len(workflow.Jobs))
+	for id, job := range workflow.Jobs {
+		if job.Concurrency != nil && job.Concurrency.Group != "" {
+			jobs[id] = job.Concurrency
+		}
+	}
+	if workflow.Concurrency != nil && workflow.Concurrency.Group == "" {
+		workflow.Concurrency = nil
+	}
+	return workflow.Concurrency, jobs, nil
+}
+
+// EvaluateConcurrency evaluates the expressions of a raw concurrency and returns the concurrency group
+// and whether the runs or jobs in progress in this group should be cancelled.
+// The jobID and job are empty for a workflow-level concurrency.
+func EvaluateConcurrency(rc *RawConcurrency, jobID string, job *jobparser.Job, gitCtx *model.GithubContext, results map[string]*jobparser.JobResult, vars map[string]string) (string, bool, error) {
+	matrix, err := singleJobMatrix(job)
+	if err != nil {
+		return "", false, err
+	}
+
+	if results == nil {
+		results = map[string]*jobparser.JobResult{}
+	}
+	if _, ok := results[jobID]; !ok {
+		var needs []string
+		if job != nil {
+			needs = job.Needs()
+		}
+		results[jobID] = &jobparser.JobResult{Needs: needs}
+	}
+
+	evaluator := jobparser.NewExpressionEvaluator(jobparser.NewInterpeter(jobID, &model.Job{}, matrix, gitCtx, results, vars))
+
+	group := strings.TrimSpace(evaluator.Interpolate(rc.Group))
+	if group == "" {
+		return "", false, fmt.Errorf("concurrency group %q is evaluated to an empty string", rc.Group)
+	}
+
+	cancelInProgress := false
+	if rc.CancelInProgress != "" {
+		cancelInProgress, err = strconv.ParseBool(strings.TrimSpace(evaluator.Interpolate(rc.CancelInProgress)))
+		if err != nil {
+			return "", false, fmt.Errorf("cancel-in-progress %q is not a boolean: %w", rc.CancelInProgress, err)
+		}
+	}
+
+	return group, cancelInProgress, nil
+}
+
+// singleJobMatrix returns the matrix of a single job, which has been flattened by the jobparser
+// into lists of one element.
+func singleJobMatrix(job *jobparser.Job) (map[string]any, error) {
+	if job == nil || job.Strategy.RawMatrix.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	var flattened map[string][]any
+	if err := job.Strategy.RawMatrix.Decode(&flattened); err != nil {
+		return nil, fmt.Errorf("decode matrix: %w", err)
+	}
+	matrix := make(map[string]any, len(flattened))
+	for k, v := range flattened {
+		if len(v) > 0 {
+			matrix[k] = v[0]
+		}
+	}
+	return matrix, nil
+}
@@ -0,0 +1,99 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package actions
+
+import (
+	"testing"
+
+	"github.com/nektos/act/pkg/jobparser"
+	"github.com/nektos/act/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadWorkflowRawConcurrency(t *testing.T) {
+	content := []byte(`
+on: push
+concurrency:
+  group: deploy-${{ github.ref }}
+  cancel-in-progress: true
+jobs:
+  build:
+    runs-on: docker
+    concurrency: build
+    steps:
+      - run: make
+  test:
+    runs-on: docker
+    steps:
+      - run: make test
+`)
+	wf, jobs, err := ReadWorkflowRawConcurrency(content)
+	require.NoError(t, err)
+	assert.Equal(t, &RawConcurrency{Group: "deploy-${{ github.ref }}", CancelInProgress: "true"}, wf)
+	assert.Equal(t, map[string]*RawConcurrency{"build": {Group: "build"}}, jobs)
+
+	wf, jobs, err = ReadWorkflowRawConcurrency([]byte("on: push\njobs:\n  build:\n    runs-on: docker\n"))
+	require.NoError(t, err)
+	assert.Nil(t, wf)
+	assert.Empty(t, jobs)
+}
+
+func TestRawConcurrencyMarshal(t *testing.T) {
+	rc := &RawConcurrency{Group: "deploy-${{ github.ref }}", CancelInProgress: "${{ github.ref != 'refs/heads/main' }}"}
+	parsed, err := UnmarshalRawConcurrency(rc.Marshal())
+	require.NoError(t, err)
+	assert.Equal(t, rc, parsed)
+
+	parsed, err = UnmarshalRawConcurrency((&RawConcurrency{}).Marshal())
+	require.NoError(t, err)
+	assert.Nil(t, parsed)
+}
+
+func TestEvaluateConcurrency(t *testing.T) {
+	gitCtx := &model.GithubContext{
+		Ref:        "refs/heads/main",
+		Repository: "user2/repo1",
+	}
+
+	group, cancel, err := EvaluateConcurrency(&RawConcurrency{
+		Group:            "deploy-${{ github.ref }}",
+		CancelInProgress: "${{ github.ref != 'refs/heads/main' }}",
+	}, "", nil, gitCtx, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "deploy-refs/heads/main", group)
+	assert.False(t, cancel)
+
+	group, cancel, err = EvaluateConcurrency(&RawConcurrency{
+		Group:            "${{ github.repository }}-${{ vars.ENVIRONMENT }}",
+		CancelInProgress: "true",
+	}, "", nil, gitCtx, nil, map[string]string{"ENVIRONMENT": "production"})
+	require.NoError(t, err)
+	assert.Equal(t, "user2/repo1-production", group)
+	assert.True(t, cancel)
+
+	workflows, err := jobparser.Parse([]byte(`
+on: push
+jobs:
+  test:
+    runs-on: docker
+    strategy:
+      matrix:
+        os: [linux, windows]
+    steps:
+      - run: make test
+`))
+	require.NoError(t, err)
+	require.Len(t, workflows, 2)
+	id, job := workflows[0].Job()
+	group, _, err = EvaluateConcurrency(&RawConcurrency{Group: "test-${{ matrix.os }}"}, id, job, gitCtx, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "test-linux", group)
+
+	_, _, err = EvaluateConcurrency(&RawConcurrency{Group: "${{ vars.MISSING }}"}, "", nil, gitCtx, nil, nil)
+	require.Error(t, err)
+
+	_, _, err = EvaluateConcurrency(&RawConcurrency{Group: "deploy", CancelInProgress: "sometimes"}, "", nil, gitCtx, nil, nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,91 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package actions
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nektos/act/pkg/jobparser"
+	"github.com/nektos/act/pkg/model"
+	"gopkg.in/yaml.v3"
+)
+
+// RawEnvironment is the `environment` of a job before its expressions are evaluated.
+// See https://docs.github.com/en/actions/writing-workflows/workflow-syntax-for-github-actions#jobsjob_idenvironment
+type RawEnvironment struct {
+	Name string `yaml:"name,omitempty"`
+	URL  string `yaml:"url,omitempty"`
+}
+
+// UnmarshalYAML supports both the short form `environment: <name>` and the mapping form.
+func (re *RawEnvironment) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		re.Name = node.Value
+		return nil
+	}
+	type plain RawEnvironment
+	return node.Decode((*plain)(re))
+}
+
+// Marshal returns the YAML representation stored in the database.
+func (re *RawEnvironment) Marshal() string {
+	if re == nil || re.Name == "" {
+		return ""
+	}
+	out, _ := yaml.Marshal(re)
+	return string(out)
+}
+
+// UnmarshalRawEnvironment parses a RawEnvironment previously returned by Marshal.
+// It returns nil if the content is empty.
+func UnmarshalRawEnvironment(content string) (*RawEnvironment, error) {
+	if content == "" {
+		return nil, nil
+	}
+	re := &RawEnvironment{}
+	if err := yaml.Unmarshal([]byte(content), re); err != nil {
+		return nil, err
+	}
+	return re, nil
+}
+
+// ReadWorkflowRawEnvironments returns the environment of each job defined in the workflow content which targets one.
+// The jobparser drops this key, so it is read from the raw content.
+func ReadWorkflowRawEnvironments(content []byte) (map[string]*RawEnvironment, error) {
+	var workflow struct {
+		Jobs map[string]struct {
+			Environment *RawEnvironment `yaml:"environment"`
+		} `yaml:"jobs"`
+	}
+	if err := yaml.Unmarshal(content, &workflow); err != nil {
+		return nil, err
+	}
+
+	jobs := make(map[string]*RawEnvironment, len(workflow.Jobs))
+	for id, job := range workflow.Jobs {
+		if job.Environment != nil && job.Environment.Name != "" {
+			jobs[id] = job.Environment
+		}
+	}
+	return jobs, nil
+}
+
+// EvaluateEnvironment evaluates the expressions of the environment of a job which is about to run
+// and returns the name of the environment and its URL.
+// The URL is evaluated before the job runs, so it can't refer to the outputs of the steps of the job.
+func EvaluateEnvironment(re *RawEnvironment, jobID string, job *jobparser.Job, gitCtx *model.GithubContext, results map[string]*jobparser.JobResult, vars map[string]string, inputs map[string]any) (string, string, error) {
+	interpreter, err := newJobInterpreter(jobID, job, gitCtx, results, vars, inputs)
+	if err != nil {
+		return "", "", err
+	}
+	evaluator := jobparser.NewExpressionEvaluator(interpreter)
+
+	name := strings.TrimSpace(evaluator.Interpolate(re.Name))
+	if name == "" {
+		return "", "", fmt.Errorf("environment %q is evaluated to an empty string", re.Name)
+	}
+	url := strings.TrimSpace(evaluator.Interpolate(re.URL))
+	return name, url, nil
+}
@@ -0,0 +1,82 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package actions
+
+import (
+	"testing"
+
+	"github.com/nektos/act/pkg/jobparser"
+	"github.com/nektos/act/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadWorkflowRawEnvironments(t *testing.T) {
+	jobs, err := ReadWorkflowRawEnvironments([]byte(`
+on: push
+jobs:
+  build:
+    runs-on: docker
+    steps:
+      - run: make
+  staging:
+    runs-on: docker
+    environment: staging
+    steps:
+      - run: ./deploy.sh
+  production:
+    runs-on: docker
+    environment:
+      name: production
+      url: https://${{ vars.HOST }}
+    steps:
+      - run: ./deploy.sh
+`))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]*RawEnvironment{
+		"staging":    {Name: "staging"},
+		"production": {Name: "production", URL: "https://${{ vars.HOST }}"},
+	}, jobs)
+}
+
+func TestRawEnvironmentMarshal(t *testing.T) {
+	re := &RawEnvironment{Name: "review-${{ github.ref_name }}", URL: "https://example.com"}
+	parsed, err := UnmarshalRawEnvironment(re.Marshal())
+	require.NoError(t, err)
+	assert.Equal(t, re, parsed)
+
+	parsed, err = UnmarshalRawEnvironment((&RawEnvironment{}).Marshal())
+	require.NoError(t, err)
+	assert.Nil(t, parsed)
+}
+
+func TestEvaluateEnvironment(t *testing.T) {
+	gitCtx := &model.GithubContext{Ref: "refs/heads/main", RefName: "main"}
+
+	workflows, err := jobparser.Parse([]byte(`
+on: push
+jobs:
+  deploy:
+    runs-on: docker
+    strategy:
+      matrix:
+        region: [eu]
+    steps:
+      - run: ./deploy.sh
+`))
+	require.NoError(t, err)
+	require.Len(t, workflows, 1)
+	id, job := workflows[0].Job()
+
+	name, url, err := EvaluateEnvironment(&RawEnvironment{
+		Name: "production-${{ matrix.region }}",
+		URL:  "https://${{ matrix.region }}.${{ vars.DOMAIN }}/${{ github.ref_name }}",
+	}, id, job, gitCtx, nil, map[string]string{"DOMAIN": "example.com"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "production-eu", name)
+	assert.Equal(t, "https://eu.example.com/main", url)
+
+	_, _, err = EvaluateEnvironment(&RawEnvironment{Name: "${{ vars.MISSING }}"}, id, job, gitCtx, nil, nil, nil)
+	require.ErrorContains(t, err, "evaluated to an empty string")
+}
@@ -24,6 +24,8 @@ const (
 	GithubEventGollum                   = "gollum"
 	GithubEventSchedule                 = "schedule"
 	GithubEventWorkflowDispatch         = "workflow_dispatch"
+	GithubEventWorkflowCall             = "workflow_call"
+	GithubEventWorkflowRun              = "workflow_run"
 )
 
 // IsDefaultBranchWorkflow returns true if the event only triggers workflows on the default branch
@@ -57,6 +59,10 @@ func IsDefaultBranchWorkflow(triggedEvent webhook_module.HookEventType) bool {
 		// GitHub "workflow_dispatch" event
 		// https://docs.github.com/en/actions/using-workflows/events-that-trigger-workflows#workflow_dispatch
 		return true
+	case webhook_module.HookEventWorkflowRun:
+		// GitHub "workflow_run" event
+		// https://docs.github.com/en/actions/using-workflows/events-that-trigger-workflows#workflow_run
+		return true
 	case webhook_module.HookEventIssues,
 		webhook_module.HookEventIssueAssign,
 		webhook_module.HookEventIssueLabel,
@@ -82,6 +88,9 @@ func canGithubEventMatch(eventName string, triggedEvent webhook_module.HookEvent
 	case GithubEventWorkflowDispatch:
 		return triggedEvent == webhook_module.HookEventWorkflowDispatch
 
+	case GithubEventWorkflowRun:
+		return triggedEvent == webhook_module.HookEventWorkflowRun
+
 	case GithubEventIssues:
 		switch triggedEvent {
 		case webhook_module.HookEventIssues,
@@ -0,0 +1,57 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package actions
+
+import (
+	"gopkg.in/yaml.v3"
+)
+
+// Permissions is the `permissions` of a workflow or a job.
+// See https://docs.github.com/en/actions/writing-workflows/workflow-syntax-for-github-actions#permissions
+type Permissions struct {
+	All    string            // `read-all` or `write-all` for the short form, empty otherwise
+	Scopes map[string]string // the access (`read`, `write` or `none`) by scope for the mapping form
+}
+
+// UnmarshalYAML supports both the short form `permissions: read-all|write-all` and the mapping form.
+func (p *Permissions) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		p.All = node.Value
+		return nil
+	}
+	return node.Decode(&p.Scopes)
+}
+
+// CanWrite returns whether the permissions grant the write access to a scope.
+func (p *Permissions) CanWrite(scope string) bool {
+	if p == nil {
+		return false
+	}
+	if p.All != "" {
+		return p.All == "write-all"
+	}
+	return p.Scopes[scope] == "write"
+}
+
+// ReadWorkflowPermissions returns the workflow-level permissions and the job-level permissions of each job
+// defined in the workflow content, nil when they are not set. The jobparser drops these keys, so they are read from the raw content.
+func ReadWorkflowPermissions(content []byte) (*Permissions, map[string]*Permissions, error) {
+	var workflow struct {
+		Permissions *Permissions `yaml:"permissions"`
+		Jobs        map[string]struct {
+			Permissions *Permissions `yaml:"permissions"`
+		} `yaml:"jobs"`
+	}
+	if err := yaml.Unmarshal(content, &workflow); err != nil {
+		return nil, nil, err
+	}
+
+	jobs := make(map[string]*Permissions, len(workflow.Jobs))
+	for id, job := range workflow.Jobs {
+		if job.Permissions != nil {
+			jobs[id] = job.Permissions
+		}
+	}
+	return workflow.Permissions, jobs, nil
+}
@@ -0,0 +1,65 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package actions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadWorkflowPermissions(t *testing.T) {
+	wf, jobs, err := ReadWorkflowPermissions([]byte(`
+on: push
+permissions:
+  contents: read
+  id-token: write
+jobs:
+  build:
+    runs-on: docker
+    steps:
+      - run: make
+  deploy:
+    runs-on: docker
+    permissions: write-all
+    steps:
+      - run: ./deploy.sh
+  lint:
+    runs-on: docker
+    permissions:
+      id-token: none
+    steps:
+      - run: make lint
+  test:
+    runs-on: docker
+    permissions: {}
+    steps:
+      - run: make test
+`))
+	require.NoError(t, err)
+	assert.Equal(t, &Permissions{Scopes: map[string]string{"contents": "read", "id-token": "write"}}, wf)
+	assert.True(t, wf.CanWrite("id-token"))
+	assert.False(t, wf.CanWrite("contents"))
+
+	require.Len(t, jobs, 3)
+	assert.True(t, jobs["deploy"].CanWrite("id-token"))
+	assert.False(t, jobs["lint"].CanWrite("id-token"))
+	assert.False(t, jobs["test"].CanWrite("id-token"))
+	assert.NotContains(t, jobs, "build")
+
+	wf, jobs, err = ReadWorkflowPermissions([]byte(`
+on: push
+jobs:
+  build:
+    runs-on: docker
+    steps:
+      - run: make
+`))
+	require.NoError(t, err)
+	assert.Nil(t, wf)
+	assert.Empty(t, jobs)
+	assert.False(t, wf.CanWrite("id-token"))
+	assert.False(t, (&Permissions{All: "read-all"}).CanWrite("id-token"))
+}
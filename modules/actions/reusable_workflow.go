@@ -0,0 +1,240 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package actions
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/nektos/act/pkg/exprparser"
+	"github.com/nektos/act/pkg/jobparser"
+	"github.com/nektos/act/pkg/model"
+	"gopkg.in/yaml.v3"
+)
+
+// ReusableWorkflow is the workflow called by a job with `uses`.
+// See https://docs.github.com/en/actions/sharing-automations/reusing-workflows
+type ReusableWorkflow struct {
+	// OwnerName and RepoName are empty for a workflow of the calling repository
+	OwnerName string
+	RepoName  string
+	Path      string
+	// Ref is empty for a workflow of the calling repository, which is read at the commit of the run
+	Ref string
+}
+
+// IsLocal returns whether the workflow is in the calling repository.
+func (w *ReusableWorkflow) IsLocal() bool {
+	return w.OwnerName == ""
+}
+
+// ParseReusableWorkflowUses parses the `uses` of a job calling a reusable workflow, which is either
+// `./.forgejo/workflows/<file>` for a workflow of the same repository or
+// `<owner>/<repo>/.forgejo/workflows/<file>@<ref>` for a workflow of another repository of the instance.
+func ParseReusableWorkflowUses(uses string) (*ReusableWorkflow, error) {
+	if p, ok := strings.CutPrefix(uses, "./"); ok {
+		if !isReusableWorkflowPath(p) {
+			return nil, fmt.Errorf("%q is not a workflow file", uses)
+		}
+		return &ReusableWorkflow{Path: p}, nil
+	}
+
+	fullPath, ref, ok := strings.Cut(uses, "@")
+	if !ok || ref == "" {
+		return nil, fmt.Errorf("the reusable workflow %q has no ref", uses)
+	}
+	parts := strings.SplitN(fullPath, "/", 3)
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || !isReusableWorkflowPath(parts[2]) {
+		return nil, fmt.Errorf("%q is not a workflow file", uses)
+	}
+	return &ReusableWorkflow{
+		OwnerName: parts[0],
+		RepoName:  parts[1],
+		Path:      parts[2],
+		Ref:       ref,
+	}, nil
+}
+
+func isReusableWorkflowPath(p string) bool {
+	return path.Clean(p) == p && IsWorkflow(p)
+}
+
+// WorkflowCallInputsEnv returns the environment variables passing the inputs to the jobs of a called workflow.
+// The runner reads the `inputs` context from the `INPUT_*` variables, whatever the event of the run.
+func WorkflowCallInputsEnv(inputs map[string]string) map[string]string {
+	env := make(map[string]string, len(inputs))
+	for k, v := range inputs {
+		env["INPUT_"+strings.ToUpper(k)] = v
+	}
+	return env
+}
+
+// ReadWorkflowCallInputs returns the inputs passed by WorkflowCallInputsEnv to the jobs of a called workflow.
+func ReadWorkflowCallInputs(env map[string]string) map[string]any {
+	inputs := map[string]any{}
+	for k, v := range env {
+		if name, ok := strings.CutPrefix(k, "INPUT_"); ok {
+			inputs[strings.ToLower(name)] = v
+		}
+	}
+	return inputs
+}
+
+// newJobInterpreter returns an interpreter for the expressions of a job evaluated by the server.
+// Unlike jobparser.NewInterpeter, it provides the status of the job to the status check functions
+// and the inputs of the workflow when the job belongs to a called workflow.
+func newJobInterpreter(jobID string, job *jobparser.Job, gitCtx *model.GithubContext, results map[string]*jobparser.JobResult, vars map[string]string, inputs map[string]any) (exprparser.Interpreter, error) {
+	matrix, err := singleJobMatrix(job)
+	if err != nil {
+		return nil, err
+	}
+
+	run := &model.Run{
+		Workflow: &model.Workflow{Jobs: map[string]*model.Job{}},
+		JobID:    jobID,
+	}
+	for id, result := range results {
+		need := yaml.Node{}
+		_ = need.Encode(result.Needs)
+		run.Workflow.Jobs[id] = &model.Job{
+			RawNeeds: need,
+			Result:   result.Result,
+			Outputs:  result.Outputs,
+		}
+	}
+	if _, ok := run.Workflow.Jobs[jobID]; !ok {
+		need := yaml.Node{}
+		_ = need.Encode(job.Needs())
+		run.Workflow.Jobs[jobID] = &model.Job{RawNeeds: need}
+	}
+
+	needs := map[string]exprparser.Needs{}
+	for _, need := range run.Job().Needs() {
+		if v, ok := run.Workflow.Jobs[need]; ok {
+			needs[need] = exprparser.Needs{
+				Outputs: v.Outputs,
+				Result:  v.Result,
+			}
+		}
+	}
+
+	return exprparser.NewInterpeter(&exprparser.EvaluationEnvironment{
+		Github:   gitCtx,
+		Job:      &model.JobContext{Status: "success"},
+		Strategy: map[string]any{},
+		Matrix:   matrix,
+		Needs:    needs,
+		Inputs:   inputs,
+		Vars:     vars,
+	}, exprparser.Config{
+		Run:     run,
+		Context: "job",
+	}), nil
+}
+
+// EvaluateJobIf evaluates the `if` of a job which doesn't run on a runner, e.g. a job calling a reusable workflow.
+// The needs of the job must be done.
+func EvaluateJobIf(jobID string, job *jobparser.Job, gitCtx *model.GithubContext, results map[string]*jobparser.JobResult, vars map[string]string, inputs map[string]any) (bool, error) {
+	interpreter, err := newJobInterpreter(jobID, job, gitCtx, results, vars, inputs)
+	if err != nil {
+		return false, err
+	}
+
+	expr := strings.TrimSpace(job.If.Value)
+	if strings.HasPrefix(expr, "${{") && strings.HasSuffix(expr, "}}") {
+		expr = strings.TrimSpace(expr[3 : len(expr)-2])
+	}
+	result, err := interpreter.Evaluate(expr, exprparser.DefaultStatusCheckSuccess)
+	if err != nil {
+		return false, fmt.Errorf("evaluate if %q: %w", job.If.Value, err)
+	}
+	return exprparser.IsTruthy(result), nil
+}
+
+// EvaluateWorkflowCallInputs evaluates the `with` of a job calling a reusable workflow and checks them against
+// the inputs declared by the called workflow, whose defaults are used for the inputs which are not given.
+func EvaluateWorkflowCallInputs(config *model.WorkflowCall, jobID string, job *jobparser.Job, gitCtx *model.GithubContext, results map[string]*jobparser.JobResult, vars map[string]string, inputs map[string]any) (map[string]string, error) {
+	interpreter, err := newJobInterpreter(jobID, job, gitCtx, results, vars, inputs)
+	if err != nil {
+		return nil, err
+	}
+	evaluator := jobparser.NewExpressionEvaluator(interpreter)
+
+	for name := range job.With {
+		if _, ok := config.Inputs[name]; !ok {
+			return nil, fmt.Errorf("input %q is not defined by the called workflow", name)
+		}
+	}
+
+	ret := make(map[string]string, len(config.Inputs))
+	for name, input := range config.Inputs {
+		var value string
+		if v, ok := job.With[name]; ok && v != nil {
+			if s, ok := v.(string); ok {
+				value = evaluator.Interpolate(s)
+			} else {
+				value = fmt.Sprint(v)
+			}
+		} else if input.Default != "" {
+			value = evaluator.Interpolate(input.Default)
+		} else if input.Required {
+			return nil, fmt.Errorf("input %q is required by the called workflow", name)
+		} else {
+			continue
+		}
+
+		switch input.Type {
+		case "boolean":
+			if _, err := strconv.ParseBool(value); err != nil {
+				return nil, fmt.Errorf("input %q is not a boolean: %q", name, value)
+			}
+		case "number":
+			if _, err := strconv.ParseFloat(value, 64); err != nil {
+				return nil, fmt.Errorf("input %q is not a number: %q", name, value)
+			}
+		}
+		ret[name] = value
+	}
+	return ret, nil
+}
+
+// EvaluateWorkflowCallOutputs evaluates the `on.workflow_call.outputs.*.value` of a called workflow
+// with the outputs of its jobs, given by job ID.
+func EvaluateWorkflowCallOutputs(outputs map[string]string, jobsOutputs map[string]map[string]string) map[string]string {
+	jobs := make(map[string]*model.WorkflowCallResult, len(jobsOutputs))
+	for id, o := range jobsOutputs {
+		jobs[id] = &model.WorkflowCallResult{Outputs: o}
+	}
+	evaluator := jobparser.NewExpressionEvaluator(exprparser.NewInterpeter(&exprparser.EvaluationEnvironment{
+		Jobs: &jobs,
+	}, exprparser.Config{
+		Run:     &model.Run{Workflow: &model.Workflow{Jobs: map[string]*model.Job{}}},
+		Context: "job",
+	}))
+
+	ret := make(map[string]string, len(outputs))
+	for name, value := range outputs {
+		ret[name] = evaluator.Interpolate(value)
+	}
+	return ret
+}
+
+// EvaluateWorkflowCallSecrets evaluates the `secrets` of a job calling a reusable workflow
+// with the secrets available to the calling job.
+func EvaluateWorkflowCallSecrets(rawSecrets, secrets map[string]string) map[string]string {
+	evaluator := jobparser.NewExpressionEvaluator(exprparser.NewInterpeter(&exprparser.EvaluationEnvironment{
+		Secrets: secrets,
+	}, exprparser.Config{
+		Run:     &model.Run{Workflow: &model.Workflow{Jobs: map[string]*model.Job{}}},
+		Context: "job",
+	}))
+
+	ret := make(map[string]string, len(rawSecrets))
+	for name, value := range rawSecrets {
+		ret[name] = evaluator.Interpolate(value)
+	}
+	return ret
+}
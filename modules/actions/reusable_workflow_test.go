@@ -0,0 +1,247 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package actions
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/nektos/act/pkg/jobparser"
+	"github.com/nektos/act/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseReusableWorkflowUses(t *testing.T) {
+	for _, tc := range []struct {
+		uses     string
+		expected *ReusableWorkflow
+	}{
+		{
+			uses:     "./.forgejo/workflows/build.yml",
+			expected: &ReusableWorkflow{Path: ".forgejo/workflows/build.yml"},
+		},
+		{
+			uses:     "user2/ci/.forgejo/workflows/build.yaml@v1",
+			expected: &ReusableWorkflow{OwnerName: "user2", RepoName: "ci", Path: ".forgejo/workflows/build.yaml", Ref: "v1"},
+		},
+		{
+			uses:     "user2/ci/.github/workflows/build.yml@refs/heads/main",
+			expected: &ReusableWorkflow{OwnerName: "user2", RepoName: "ci", Path: ".github/workflows/build.yml", Ref: "refs/heads/main"},
+		},
+		{uses: "./build.yml"},
+		{uses: "./.forgejo/workflows/../../build.yml"},
+		{uses: "user2/ci/.forgejo/workflows/build.yml"},
+		{uses: "user2/.forgejo/workflows/build.yml@v1"},
+		{uses: "actions/checkout@v4"},
+	} {
+		t.Run(tc.uses, func(t *testing.T) {
+			got, err := ParseReusableWorkflowUses(tc.uses)
+			if tc.expected == nil {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, got)
+			assert.Equal(t, tc.expected.OwnerName == "", got.IsLocal())
+		})
+	}
+}
+
+func TestWorkflowCallInputsEnv(t *testing.T) {
+	env := WorkflowCallInputsEnv(map[string]string{"version": "1.2", "dry-run": "true"})
+	assert.Equal(t, map[string]string{"INPUT_VERSION": "1.2", "INPUT_DRY-RUN": "true"}, env)
+	env["PATH"] = "/bin"
+	assert.Equal(t, map[string]any{"version": "1.2", "dry-run": "true"}, ReadWorkflowCallInputs(env))
+}
+
+func parseCallingJob(t *testing.T, content string) (string, *jobparser.Job) {
+	workflows, err := jobparser.Parse([]byte(content))
+	require.NoError(t, err)
+	for _, w := range workflows {
+		if id, job := w.Job(); id == "call" {
+			return id, job
+		}
+	}
+	require.FailNow(t, "no calling job")
+	return "", nil
+}
+
+func TestEvaluateWorkflowCallInputs(t *testing.T) {
+	called, err := model.ReadWorkflow(bytes.NewReader([]byte(`
+on:
+  workflow_call:
+    inputs:
+      version:
+        type: string
+        required: true
+      dry-run:
+        type: boolean
+        default: false
+      retries:
+        type: number
+        default: 3
+jobs:
+  build:
+    runs-on: docker
+    steps:
+      - run: make
+`)))
+	require.NoError(t, err)
+	config := called.WorkflowCallConfig()
+	gitCtx := &model.GithubContext{Ref: "refs/heads/main"}
+
+	id, job := parseCallingJob(t, `
+on: push
+jobs:
+  call:
+    needs: [prepare]
+    uses: ./.forgejo/workflows/build.yml
+    with:
+      version: ${{ needs.prepare.outputs.version }}-${{ vars.SUFFIX }}
+      dry-run: ${{ github.ref != 'refs/heads/main' }}
+  prepare:
+    runs-on: docker
+    steps:
+      - run: make
+`)
+	results := map[string]*jobparser.JobResult{
+		"call":    {Needs: []string{"prepare"}},
+		"prepare": {Result: "success", Outputs: map[string]string{"version": "1.2"}},
+	}
+	inputs, err := EvaluateWorkflowCallInputs(config, id, job, gitCtx, results, map[string]string{"SUFFIX": "rc1"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"version": "1.2-rc1", "dry-run": "false", "retries": "3"}, inputs)
+
+	// the inputs of a called workflow can be passed to the workflow it calls
+	id, job = parseCallingJob(t, `
+on: workflow_call
+jobs:
+  call:
+    uses: ./.forgejo/workflows/build.yml
+    with:
+      version: ${{ inputs.version }}
+      retries: 5
+`)
+	inputs, err = EvaluateWorkflowCallInputs(config, id, job, gitCtx, nil, nil, map[string]any{"version": "2.0"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"version": "2.0", "dry-run": "false", "retries": "5"}, inputs)
+
+	id, job = parseCallingJob(t, `
+on: push
+jobs:
+  call:
+    uses: ./.forgejo/workflows/build.yml
+    with:
+      dry-run: true
+`)
+	_, err = EvaluateWorkflowCallInputs(config, id, job, gitCtx, nil, nil, nil)
+	require.ErrorContains(t, err, `input "version" is required`)
+
+	id, job = parseCallingJob(t, `
+on: push
+jobs:
+  call:
+    uses: ./.forgejo/workflows/build.yml
+    with:
+      version: 1.2
+      unknown: value
+`)
+	_, err = EvaluateWorkflowCallInputs(config, id, job, gitCtx, nil, nil, nil)
+	require.ErrorContains(t, err, `input "unknown" is not defined`)
+
+	id, job = parseCallingJob(t, `
+on: push
+jobs:
+  call:
+    uses: ./.forgejo/workflows/build.yml
+    with:
+      version: 1.2
+      retries: many
+`)
+	_, err = EvaluateWorkflowCallInputs(config, id, job, gitCtx, nil, nil, nil)
+	require.ErrorContains(t, err, `input "retries" is not a number`)
+}
+
+func TestEvaluateJobIf(t *testing.T) {
+	gitCtx := &model.GithubContext{Ref: "refs/heads/main"}
+
+	id, job := parseCallingJob(t, `
+on: push
+jobs:
+  call:
+    if: github.ref == 'refs/heads/main'
+    uses: ./.forgejo/workflows/build.yml
+`)
+	ok, err := EvaluateJobIf(id, job, gitCtx, nil, nil, nil)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	id, job = parseCallingJob(t, `
+on: push
+jobs:
+  call:
+    if: ${{ github.ref != 'refs/heads/main' }}
+    uses: ./.forgejo/workflows/build.yml
+`)
+	ok, err = EvaluateJobIf(id, job, gitCtx, nil, nil, nil)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	id, job = parseCallingJob(t, `
+on: push
+jobs:
+  call:
+    needs: [test]
+    if: failure()
+    uses: ./.forgejo/workflows/notify.yml
+  test:
+    runs-on: docker
+    steps:
+      - run: make test
+`)
+	results := map[string]*jobparser.JobResult{
+		"call": {Needs: []string{"test"}},
+		"test": {Result: "failure"},
+	}
+	ok, err = EvaluateJobIf(id, job, gitCtx, results, nil, nil)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	results["test"].Result = "success"
+	ok, err = EvaluateJobIf(id, job, gitCtx, results, nil, nil)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestEvaluateWorkflowCallOutputs(t *testing.T) {
+	outputs := EvaluateWorkflowCallOutputs(map[string]string{
+		"version":  "${{ jobs.build.outputs.version }}",
+		"artifact": "build-${{ jobs.build.outputs.version }}.tar.gz",
+		"missing":  "${{ jobs.test.outputs.report }}",
+	}, map[string]map[string]string{
+		"build": {"version": "1.2"},
+	})
+	assert.Equal(t, map[string]string{
+		"version":  "1.2",
+		"artifact": "build-1.2.tar.gz",
+		"missing":  "",
+	}, outputs)
+}
+
+func TestEvaluateWorkflowCallSecrets(t *testing.T) {
+	secrets := EvaluateWorkflowCallSecrets(map[string]string{
+		"token":  "${{ secrets.DEPLOY_TOKEN }}",
+		"plain":  "value",
+		"absent": "${{ secrets.ABSENT }}",
+	}, map[string]string{
+		"DEPLOY_TOKEN": "s3cr3t",
+		"OTHER":        "not passed",
+	})
+	assert.Equal(t, map[string]string{
+		"token":  "s3cr3t",
+		"plain":  "value",
+		"absent": "",
+	}, secrets)
+}
@@ -247,6 +247,10 @@ func detectMatched(gitRepo *git.Repository, commit *git.Commit, triggedEvent web
 		webhook_module.HookEventPackage:
 		return matchPackageEvent(payload.(*api.PackagePayload), evt)
 
+	case // workflow_run
+		webhook_module.HookEventWorkflowRun:
+		return matchWorkflowRunEvent(payload.(*api.WorkflowRunPayload), evt)
+
 	default:
 		log.Warn("unsupported event %q", triggedEvent)
 		return false
@@ -702,3 +706,61 @@ func matchPackageEvent(payload *api.PackagePayload, evt *jobparser.Event) bool {
 	}
 	return matchTimes == len(evt.Acts())
 }
+
+func matchWorkflowRunEvent(payload *api.WorkflowRunPayload, evt *jobparser.Event) bool {
+	// the workflows whose runs trigger the event are required
+	// See https://docs.github.com/en/actions/using-workflows/events-that-trigger-workflows#workflow_run
+	if _, ok := evt.Acts()["workflows"]; !ok {
+		log.Warn("workflow run event without workflows condition")
+		return false
+	}
+
+	matchTimes := 0
+	// all acts conditions should be satisfied
+	for cond, vals := range evt.Acts() {
+		switch cond {
+		case "types":
+			// Activity types with the same name:
+			// requested, completed
+			// Unsupported activity types:
+			// in_progress
+			for _, val := range vals {
+				if glob.MustCompile(val, '/').Match(string(payload.Action)) {
+					matchTimes++
+					break
+				}
+			}
+		case "workflows":
+			// the workflows are matched by their name, or by the name of their file if they don't have one
+			for _, val := range vals {
+				if val == payload.WorkflowRun.Name || val == payload.WorkflowRun.WorkflowID {
+					matchTimes++
+					break
+				}
+				if g, err := glob.Compile(val, '/'); err == nil && g.Match(payload.WorkflowRun.Name) {
+					matchTimes++
+					break
+				}
+			}
+		case "branches":
+			patterns, err := workflowpattern.CompilePatterns(vals...)
+			if err != nil {
+				break
+			}
+			if !workflowpattern.Skip(patterns, []string{payload.WorkflowRun.HeadBranch}, &workflowpattern.EmptyTraceWriter{}) {
+				matchTimes++
+			}
+		case "branches-ignore":
+			patterns, err := workflowpattern.CompilePatterns(vals...)
+			if err != nil {
+				break
+			}
+			if !workflowpattern.Filter(patterns, []string{payload.WorkflowRun.HeadBranch}, &workflowpattern.EmptyTraceWriter{}) {
+				matchTimes++
+			}
+		default:
+			log.Warn("workflow run event unsupported condition %q", cond)
+		}
+	}
+	return matchTimes == len(evt.Acts())
+}
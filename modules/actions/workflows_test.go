@@ -132,6 +132,48 @@ func TestDetectMatched(t *testing.T) {
 			yamlOn:       "on: workflow_dispatch",
 			expected:     true,
 		},
+		{
+			desc:         "HookEventWorkflowRun(workflow_run) `completed` action matches GithubEventWorkflowRun(workflow_run) with `completed` activity type",
+			triggedEvent: webhook_module.HookEventWorkflowRun,
+			payload:      &api.WorkflowRunPayload{Action: api.HookWorkflowRunCompleted, WorkflowRun: &api.ActionWorkflowRun{Name: "CI", WorkflowID: "ci.yml", HeadBranch: "main"}},
+			yamlOn:       "on:\n  workflow_run:\n    workflows: [CI]\n    types: [completed]",
+			expected:     true,
+		},
+		{
+			desc:         "HookEventWorkflowRun(workflow_run) `requested` action doesn't match GithubEventWorkflowRun(workflow_run) with `completed` activity type",
+			triggedEvent: webhook_module.HookEventWorkflowRun,
+			payload:      &api.WorkflowRunPayload{Action: api.HookWorkflowRunRequested, WorkflowRun: &api.ActionWorkflowRun{Name: "CI", WorkflowID: "ci.yml", HeadBranch: "main"}},
+			yamlOn:       "on:\n  workflow_run:\n    workflows: [CI]\n    types: [completed]",
+			expected:     false,
+		},
+		{
+			desc:         "HookEventWorkflowRun(workflow_run) matches GithubEventWorkflowRun(workflow_run) with the file name of the workflow",
+			triggedEvent: webhook_module.HookEventWorkflowRun,
+			payload:      &api.WorkflowRunPayload{Action: api.HookWorkflowRunRequested, WorkflowRun: &api.ActionWorkflowRun{Name: "CI", WorkflowID: "ci.yml", HeadBranch: "main"}},
+			yamlOn:       "on:\n  workflow_run:\n    workflows: [ci.yml]",
+			expected:     true,
+		},
+		{
+			desc:         "HookEventWorkflowRun(workflow_run) doesn't match GithubEventWorkflowRun(workflow_run) with other workflows",
+			triggedEvent: webhook_module.HookEventWorkflowRun,
+			payload:      &api.WorkflowRunPayload{Action: api.HookWorkflowRunCompleted, WorkflowRun: &api.ActionWorkflowRun{Name: "CI", WorkflowID: "ci.yml", HeadBranch: "main"}},
+			yamlOn:       "on:\n  workflow_run:\n    workflows: [Release]",
+			expected:     false,
+		},
+		{
+			desc:         "HookEventWorkflowRun(workflow_run) doesn't match GithubEventWorkflowRun(workflow_run) without workflows",
+			triggedEvent: webhook_module.HookEventWorkflowRun,
+			payload:      &api.WorkflowRunPayload{Action: api.HookWorkflowRunCompleted, WorkflowRun: &api.ActionWorkflowRun{Name: "CI", WorkflowID: "ci.yml", HeadBranch: "main"}},
+			yamlOn:       "on: workflow_run",
+			expected:     false,
+		},
+		{
+			desc:         "HookEventWorkflowRun(workflow_run) doesn't match GithubEventWorkflowRun(workflow_run) with other branches",
+			triggedEvent: webhook_module.HookEventWorkflowRun,
+			payload:      &api.WorkflowRunPayload{Action: api.HookWorkflowRunCompleted, WorkflowRun: &api.ActionWorkflowRun{Name: "CI", WorkflowID: "ci.yml", HeadBranch: "feature"}},
+			yamlOn:       "on:\n  workflow_run:\n    workflows: [CI]\n    branches: [main, 'release/**']",
+			expected:     false,
+		},
 	}
 
 	for _, tc := range testCases {
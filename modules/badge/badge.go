@@ -0,0 +1,112 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+// Package badge renders the badges of the repositories as SVG images, in the flat style of shields.io.
+package badge
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"regexp"
+	"strings"
+)
+
+const (
+	height      = 20
+	padding     = 5
+	labelColor  = "#555"
+	fontFamily  = "Verdana,Geneva,DejaVu Sans,sans-serif"
+	fontSize    = 11
+	defaultChar = 7.0
+)
+
+// the named colors of shields.io which aren't SVG color keywords
+var namedColors = map[string]string{
+	"brightgreen": "#4c1",
+	"green":       "#97ca00",
+	"yellowgreen": "#a4a61d",
+	"yellow":      "#dfb317",
+	"orange":      "#fe7d37",
+	"red":         "#e05d44",
+	"blue":        "#007ec6",
+	"grey":        "#555",
+	"gray":        "#555",
+	"lightgrey":   "#9f9f9f",
+	"lightgray":   "#9f9f9f",
+}
+
+var validColor = regexp.MustCompile(`^(#[0-9a-fA-F]{3}|#[0-9a-fA-F]{6}|[a-zA-Z]+)$`)
+
+// the widths of the printable ASCII characters in Verdana 11px, from the space to the tilde
+var charWidths = [...]float64{
+	3.9, 4.7, 5.5, 9.0, 7.0, 11.9, 8.0, 3.0, 4.9, 4.9, 7.0, 9.0, 4.0, 5.0, 4.0, 4.9, // space to /
+	7.0, 7.0, 7.0, 7.0, 7.0, 7.0, 7.0, 7.0, 7.0, 7.0, // 0 to 9
+	4.9, 4.9, 9.0, 9.0, 9.0, 6.0, 11.0, // : to @
+	7.5, 7.6, 7.7, 8.5, 7.0, 6.3, 8.5, 8.3, 4.6, 5.0, 7.6, 6.1, 9.3, // A to M
+	8.2, 8.7, 6.6, 8.7, 7.7, 7.5, 6.8, 8.1, 7.5, 10.9, 7.5, 6.8, 7.5, // N to Z
+	4.9, 4.9, 4.9, 9.0, 7.0, 7.0, // [ to `
+	6.6, 6.8, 5.8, 6.8, 6.6, 3.8, 6.8, 7.0, 3.0, 3.8, 6.5, 3.0, 10.7, // a to m
+	7.0, 6.7, 6.8, 6.8, 4.7, 5.7, 4.3, 7.0, 6.5, 9.0, 6.5, 6.5, 5.8, // n to z
+	7.0, 4.9, 7.0, 9.0, // { to ~
+}
+
+// Badge is a badge with a label on the left and a text on the right.
+type Badge struct {
+	Label string
+	Text  string
+	Color string
+}
+
+// textWidth estimates the width of a text in pixels.
+func textWidth(s string) int {
+	var width float64
+	for _, c := range s {
+		if c >= ' ' && int(c-' ') < len(charWidths) {
+			width += charWidths[c-' ']
+		} else {
+			width += defaultChar
+		}
+	}
+	return int(math.Ceil(width))
+}
+
+// color returns the fill color of the text of the badge, the invalid colors are rendered grey.
+func (b Badge) color() string {
+	if c, ok := namedColors[strings.ToLower(b.Color)]; ok {
+		return c
+	}
+	if validColor.MatchString(b.Color) {
+		return b.Color
+	}
+	return namedColors["lightgrey"]
+}
+
+// SVG renders the badge.
+func (b Badge) SVG() string {
+	labelWidth := textWidth(b.Label) + 2*padding
+	textWidth := textWidth(b.Text) + 2*padding
+	width := labelWidth + textWidth
+	label := html.EscapeString(b.Label)
+	text := html.EscapeString(b.Text)
+
+	sb := &strings.Builder{}
+	fmt.Fprintf(sb, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" role="img" aria-label="%s: %s">`, width, height, label, text)
+	fmt.Fprintf(sb, `<title>%s: %s</title>`, label, text)
+	sb.WriteString(`<linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>`)
+	fmt.Fprintf(sb, `<clipPath id="r"><rect width="%d" height="%d" rx="3" fill="#fff"/></clipPath>`, width, height)
+	fmt.Fprintf(sb, `<g clip-path="url(#r)"><rect width="%d" height="%d" fill="%s"/><rect x="%d" width="%d" height="%d" fill="%s"/><rect width="%d" height="%d" fill="url(#s)"/></g>`,
+		labelWidth, height, labelColor, labelWidth, textWidth, height, b.color(), width, height)
+	fmt.Fprintf(sb, `<g fill="#fff" text-anchor="middle" font-family="%s" font-size="%d">`, fontFamily, fontSize)
+	for _, part := range []struct {
+		x    float64
+		text string
+	}{
+		{float64(labelWidth) / 2, label},
+		{float64(labelWidth) + float64(textWidth)/2, text},
+	} {
+		fmt.Fprintf(sb, `<text x="%g" y="15" fill="#010101" fill-opacity=".3">%s</text><text x="%g" y="14">%s</text>`, part.x, part.text, part.x, part.text)
+	}
+	sb.WriteString(`</g></svg>`)
+	return sb.String()
+}
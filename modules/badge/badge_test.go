@@ -0,0 +1,30 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package badge
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBadgeColor(t *testing.T) {
+	assert.Equal(t, "#4c1", Badge{Color: "brightgreen"}.color())
+	assert.Equal(t, "crimson", Badge{Color: "crimson"}.color())
+	assert.Equal(t, "#abcdef", Badge{Color: "#abcdef"}.color())
+	assert.Equal(t, "#9f9f9f", Badge{Color: `red"/><script>`}.color())
+}
+
+func TestBadgeSVG(t *testing.T) {
+	assert.Equal(t, 42, textWidth("passing"))
+	assert.Less(t, textWidth("iiii"), textWidth("MMMM"))
+
+	svg := Badge{Label: "ci.yml", Text: "success", Color: "brightgreen"}.SVG()
+	assert.Contains(t, svg, `<title>ci.yml: success</title>`)
+	assert.Contains(t, svg, `fill="#4c1"`)
+
+	svg = Badge{Label: "<b>", Text: "a & b", Color: "blue"}.SVG()
+	assert.Contains(t, svg, `<title>&lt;b&gt;: a &amp; b</title>`)
+	assert.NotContains(t, svg, "<b>")
+}
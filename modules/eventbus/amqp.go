@@ -0,0 +1,90 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"code.gitea.io/gitea/modules/setting"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// PublishAMQP publishes the message as persistent message to the exchange of an AMQP 0-9-1
+// broker like RabbitMQ, using msg.Key as routing key, and waits for the broker to confirm it.
+// The URL has the form amqp[s]://[user:password@]host[:port][/vhost].
+func PublishAMQP(ctx context.Context, d *Dialer, rawURL, exchange string, msg *Message) (string, error) {
+	if _, err := amqp.ParseURI(rawURL); err != nil {
+		return "", err
+	}
+	if len(exchange) > 255 || len(msg.Key) > 255 {
+		return "", errors.New("exchange and routing key must not be longer than 255 bytes")
+	}
+
+	// the library starts the TLS session itself for the amqps scheme
+	conns := &connections{ctx: ctx, d: d}
+	defer conns.closeAll()
+
+	conn, err := amqp.DialConfig(rawURL, amqp.Config{
+		Properties: amqp.Table{
+			"product": "Forgejo",
+			"version": setting.AppVer,
+		},
+		// the config is always set, otherwise the library would load the certificates named in the URL
+		TLSClientConfig: d.tlsConfig(""),
+		Dial: func(_, addr string) (net.Conn, error) {
+			return conns.dial(addr)
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	ch, err := conn.Channel()
+	if err != nil {
+		return "", err
+	}
+	closed := ch.NotifyClose(make(chan *amqp.Error, 1))
+	if err := ch.Confirm(false); err != nil {
+		return "", err
+	}
+
+	headers := make(amqp.Table, len(msg.Headers))
+	for k, v := range msg.Headers {
+		headers[k] = v
+	}
+	confirmation, err := ch.PublishWithDeferredConfirmWithContext(ctx, exchange, msg.Key, false, false, amqp.Publishing{
+		Headers:      headers,
+		ContentType:  msg.ContentType,
+		DeliveryMode: amqp.Persistent,
+		Body:         msg.Body,
+	})
+	if err != nil {
+		return "", err
+	}
+	ack, err := confirmation.WaitContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	if !ack {
+		// the confirmations are not acknowledged when the broker closes the channel, e.g. for an unknown exchange
+		select {
+		case closeErr := <-closed:
+			if closeErr != nil {
+				return "", closeErr
+			}
+		default:
+		}
+		return "", errors.New("the message was rejected by the broker")
+	}
+
+	if exchange == "" {
+		return fmt.Sprintf("Published to the default exchange with routing key %s", msg.Key), nil
+	}
+	return fmt.Sprintf("Published to exchange %s with routing key %s", exchange, msg.Key), nil
+}
@@ -0,0 +1,447 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package eventbus
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	amqpFrameMethod    = 1
+	amqpFrameHeader    = 2
+	amqpFrameHeartbeat = 8
+	amqpFrameEnd       = 0xCE
+)
+
+type amqpMethod struct {
+	class, method uint16
+}
+
+var (
+	amqpConnectionStart   = amqpMethod{10, 10}
+	amqpConnectionStartOk = amqpMethod{10, 11}
+	amqpConnectionTune    = amqpMethod{10, 30}
+	amqpConnectionTuneOk  = amqpMethod{10, 31}
+	amqpConnectionOpen    = amqpMethod{10, 40}
+	amqpConnectionOpenOk  = amqpMethod{10, 41}
+	amqpConnectionClose   = amqpMethod{10, 50}
+	amqpConnectionCloseOk = amqpMethod{10, 51}
+	amqpChannelOpen       = amqpMethod{20, 10}
+	amqpChannelOpenOk     = amqpMethod{20, 11}
+	amqpChannelClose      = amqpMethod{20, 40}
+	amqpBasicPublish      = amqpMethod{60, 40}
+	amqpBasicAck          = amqpMethod{60, 80}
+	amqpBasicNack         = amqpMethod{60, 120}
+	amqpConfirmSelect     = amqpMethod{85, 10}
+	amqpConfirmSelectOk   = amqpMethod{85, 11}
+	amqpProtocolHeader    = []byte("AMQP\x00\x00\x09\x01")
+)
+
+// fakeAMQPConn is the broker side of an AMQP connection
+type fakeAMQPConn struct {
+	w io.Writer
+	r *bufio.Reader
+}
+
+func (c *fakeAMQPConn) writeFrame(typ byte, channel uint16, payload []byte) error {
+	frame := make([]byte, 0, len(payload)+8)
+	frame = append(frame, typ)
+	frame = binary.BigEndian.AppendUint16(frame, channel)
+	frame = binary.BigEndian.AppendUint32(frame, uint32(len(payload)))
+	frame = append(frame, payload...)
+	frame = append(frame, amqpFrameEnd)
+	_, err := c.w.Write(frame)
+	return err
+}
+
+func (c *fakeAMQPConn) writeMethod(channel uint16, m amqpMethod, args []byte) error {
+	payload := make([]byte, 0, len(args)+4)
+	payload = binary.BigEndian.AppendUint16(payload, m.class)
+	payload = binary.BigEndian.AppendUint16(payload, m.method)
+	return c.writeFrame(amqpFrameMethod, channel, append(payload, args...))
+}
+
+// readFrame reads the next frame which is not a heartbeat
+func (c *fakeAMQPConn) readFrame() (byte, uint16, []byte, error) {
+	for {
+		var header [7]byte
+		if _, err := io.ReadFull(c.r, header[:]); err != nil {
+			return 0, 0, nil, err
+		}
+		size := binary.BigEndian.Uint32(header[3:])
+		payload := make([]byte, size+1)
+		if _, err := io.ReadFull(c.r, payload); err != nil {
+			return 0, 0, nil, err
+		}
+		if payload[size] != amqpFrameEnd {
+			return 0, 0, nil, errors.New("invalid frame end")
+		}
+		if header[0] != amqpFrameHeartbeat {
+			return header[0], binary.BigEndian.Uint16(header[1:]), payload[:size], nil
+		}
+	}
+}
+
+func (c *fakeAMQPConn) expect(channel uint16, m amqpMethod) (*amqpReader, error) {
+	typ, ch, payload, err := c.readFrame()
+	if err != nil {
+		return nil, err
+	}
+	if typ != amqpFrameMethod || len(payload) < 4 {
+		return nil, fmt.Errorf("unexpected frame of type %d", typ)
+	}
+	got := amqpMethod{binary.BigEndian.Uint16(payload), binary.BigEndian.Uint16(payload[2:])}
+	if ch != channel || got != m {
+		return nil, fmt.Errorf("expected method %d.%d on channel %d, got %d.%d on channel %d", m.class, m.method, channel, got.class, got.method, ch)
+	}
+	return &amqpReader{b: payload[4:]}, nil
+}
+
+// waitClose answers the close of the connection, the other methods are ignored
+func (c *fakeAMQPConn) waitClose() error {
+	for {
+		typ, _, payload, err := c.readFrame()
+		if err != nil {
+			return err
+		}
+		if typ == amqpFrameMethod && len(payload) >= 4 && (amqpMethod{binary.BigEndian.Uint16(payload), binary.BigEndian.Uint16(payload[2:])}) == amqpConnectionClose {
+			return c.writeMethod(0, amqpConnectionCloseOk, nil)
+		}
+	}
+}
+
+// amqpBuffer encodes the AMQP data types
+type amqpBuffer struct {
+	bytes.Buffer
+}
+
+func (b *amqpBuffer) octet(v byte) {
+	b.WriteByte(v)
+}
+
+func (b *amqpBuffer) short(v uint16) {
+	_ = binary.Write(b, binary.BigEndian, v)
+}
+
+func (b *amqpBuffer) long(v uint32) {
+	_ = binary.Write(b, binary.BigEndian, v)
+}
+
+func (b *amqpBuffer) longlong(v uint64) {
+	_ = binary.Write(b, binary.BigEndian, v)
+}
+
+func (b *amqpBuffer) shortstr(s string) {
+	b.WriteByte(byte(len(s)))
+	b.WriteString(s)
+}
+
+func (b *amqpBuffer) longstr(s []byte) {
+	b.long(uint32(len(s)))
+	b.Write(s)
+}
+
+// table encodes a field table of strings
+func (b *amqpBuffer) table(t map[string]string) {
+	var fields amqpBuffer
+	for k, v := range t {
+		fields.shortstr(k)
+		fields.octet('S')
+		fields.longstr([]byte(v))
+	}
+	b.longstr(fields.Bytes())
+}
+
+// amqpReader decodes the AMQP data types, the first error is kept and makes all further reads return zero values
+type amqpReader struct {
+	b   []byte
+	err error
+}
+
+func (r *amqpReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n > len(r.b) {
+		r.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	v := r.b[:n]
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *amqpReader) octet() byte {
+	if v := r.next(1); v != nil {
+		return v[0]
+	}
+	return 0
+}
+
+func (r *amqpReader) short() uint16 {
+	if v := r.next(2); v != nil {
+		return binary.BigEndian.Uint16(v)
+	}
+	return 0
+}
+
+func (r *amqpReader) long() uint32 {
+	if v := r.next(4); v != nil {
+		return binary.BigEndian.Uint32(v)
+	}
+	return 0
+}
+
+func (r *amqpReader) longlong() uint64 {
+	if v := r.next(8); v != nil {
+		return binary.BigEndian.Uint64(v)
+	}
+	return 0
+}
+
+func (r *amqpReader) shortstr() string {
+	return string(r.next(int(r.octet())))
+}
+
+func (r *amqpReader) longstr() []byte {
+	return r.next(int(r.long()))
+}
+
+// table skips a field table
+func (r *amqpReader) table() {
+	r.longstr()
+}
+
+// fields decodes a field table containing only long strings
+func (r *amqpReader) fields() map[string]string {
+	t := &amqpReader{b: r.longstr()}
+	fields := map[string]string{}
+	for len(t.b) > 0 && t.err == nil {
+		k := t.shortstr()
+		if t.octet() != 'S' {
+			r.err = errors.New("unexpected field type")
+			return nil
+		}
+		fields[k] = string(t.longstr())
+	}
+	return fields
+}
+
+type amqpRecord struct {
+	response   string
+	vhost      string
+	exchange   string
+	routingKey string
+	flags      uint16
+	properties []byte
+	body       []byte
+	err        error
+}
+
+// fakeAMQP answers the handshake and the publish of a single message with an ack,
+// a nack or a close of the channel depending on reply
+func fakeAMQP(t *testing.T, frameMax uint32, reply string) (string, chan amqpRecord) {
+	records := make(chan amqpRecord, 1)
+	addr := serve(t, func(conn net.Conn) {
+		var rec amqpRecord
+		defer func() { records <- rec }()
+		rec.err = func() error {
+			c := &fakeAMQPConn{w: conn, r: bufio.NewReader(conn)}
+			header := make([]byte, len(amqpProtocolHeader))
+			if _, err := io.ReadFull(c.r, header); err != nil {
+				return err
+			}
+			if !bytes.Equal(header, amqpProtocolHeader) {
+				return fmt.Errorf("invalid protocol header %q", header)
+			}
+
+			var b amqpBuffer
+			b.octet(0)
+			b.octet(9)
+			b.table(map[string]string{"product": "fake"})
+			b.longstr([]byte("AMQPLAIN PLAIN"))
+			b.longstr([]byte("en_US"))
+			if err := c.writeMethod(0, amqpConnectionStart, b.Bytes()); err != nil {
+				return err
+			}
+			args, err := c.expect(0, amqpConnectionStartOk)
+			if err != nil {
+				return err
+			}
+			args.table()
+			if mechanism := args.shortstr(); mechanism != "PLAIN" {
+				return fmt.Errorf("unexpected mechanism %q", mechanism)
+			}
+			rec.response = string(args.longstr())
+
+			b.Reset()
+			b.short(2047)
+			b.long(frameMax)
+			b.short(60)
+			if err := c.writeMethod(0, amqpConnectionTune, b.Bytes()); err != nil {
+				return err
+			}
+			args, err = c.expect(0, amqpConnectionTuneOk)
+			if err != nil {
+				return err
+			}
+			args.short()
+			if negotiated := args.long(); negotiated != frameMax {
+				return fmt.Errorf("unexpected frame max %d", negotiated)
+			}
+
+			args, err = c.expect(0, amqpConnectionOpen)
+			if err != nil {
+				return err
+			}
+			rec.vhost = args.shortstr()
+			b.Reset()
+			b.shortstr("")
+			if err := c.writeMethod(0, amqpConnectionOpenOk, b.Bytes()); err != nil {
+				return err
+			}
+
+			if _, err := c.expect(1, amqpChannelOpen); err != nil {
+				return err
+			}
+			b.Reset()
+			b.longstr(nil)
+			if err := c.writeMethod(1, amqpChannelOpenOk, b.Bytes()); err != nil {
+				return err
+			}
+			if _, err := c.expect(1, amqpConfirmSelect); err != nil {
+				return err
+			}
+			if err := c.writeMethod(1, amqpConfirmSelectOk, nil); err != nil {
+				return err
+			}
+
+			args, err = c.expect(1, amqpBasicPublish)
+			if err != nil {
+				return err
+			}
+			args.short()
+			rec.exchange = args.shortstr()
+			rec.routingKey = args.shortstr()
+
+			typ, _, payload, err := c.readFrame()
+			if err != nil {
+				return err
+			}
+			if typ != amqpFrameHeader {
+				return fmt.Errorf("expected a header frame, got %d", typ)
+			}
+			args = &amqpReader{b: payload}
+			args.short() // class
+			args.short() // weight
+			size := args.longlong()
+			rec.flags = args.short()
+			rec.properties = args.b
+			for uint64(len(rec.body)) < size {
+				_, _, payload, err := c.readFrame()
+				if err != nil {
+					return err
+				}
+				if frameMax != 0 && len(payload) > int(frameMax)-8 {
+					return fmt.Errorf("body frame of %d bytes is too large", len(payload))
+				}
+				rec.body = append(rec.body, payload...)
+			}
+
+			b.Reset()
+			switch reply {
+			case "ack":
+				b.longlong(1)
+				b.octet(0)
+				err = c.writeMethod(1, amqpBasicAck, b.Bytes())
+			case "nack":
+				b.longlong(1)
+				b.octet(0)
+				err = c.writeMethod(1, amqpBasicNack, b.Bytes())
+			case "close":
+				b.short(404)
+				b.shortstr("NOT_FOUND - no exchange 'forge' in vhost '/'")
+				b.short(amqpBasicPublish.class)
+				b.short(amqpBasicPublish.method)
+				err = c.writeMethod(1, amqpChannelClose, b.Bytes())
+			}
+			if err != nil {
+				return err
+			}
+			return c.waitClose()
+		}()
+	})
+	return addr, records
+}
+
+func TestPublishAMQP(t *testing.T) {
+	msg := &Message{
+		Key:         "forgejo.push.owner.repo",
+		Headers:     map[string]string{"X-Forgejo-Event": "push"},
+		ContentType: "application/json",
+		Body:        bytes.Repeat([]byte("x"), 10000),
+	}
+
+	t.Run("Confirmed", func(t *testing.T) {
+		addr, records := fakeAMQP(t, 4096, "ack")
+		ack, err := PublishAMQP(testContext(t), nil, "amqp://user:secret@"+addr+"/events", "forge", msg)
+		require.NoError(t, err)
+		assert.Equal(t, "Published to exchange forge with routing key forgejo.push.owner.repo", ack)
+
+		rec := <-records
+		require.NoError(t, rec.err)
+		assert.Equal(t, "\x00user\x00secret", rec.response)
+		assert.Equal(t, "events", rec.vhost)
+		assert.Equal(t, "forge", rec.exchange)
+		assert.Equal(t, "forgejo.push.owner.repo", rec.routingKey)
+		assert.Equal(t, msg.Body, rec.body)
+
+		// content-type, headers and delivery-mode
+		assert.Equal(t, uint16(1<<15|1<<13|1<<12), rec.flags)
+		props := &amqpReader{b: rec.properties}
+		assert.Equal(t, "application/json", props.shortstr())
+		assert.Equal(t, map[string]string{"X-Forgejo-Event": "push"}, props.fields())
+		assert.Equal(t, byte(2), props.octet()) // persistent
+		assert.NoError(t, props.err)
+	})
+
+	t.Run("DefaultVhost", func(t *testing.T) {
+		addr, records := fakeAMQP(t, 131072, "ack")
+		ack, err := PublishAMQP(testContext(t), nil, "amqp://"+addr, "", msg)
+		require.NoError(t, err)
+		assert.Equal(t, "Published to the default exchange with routing key forgejo.push.owner.repo", ack)
+
+		rec := <-records
+		require.NoError(t, rec.err)
+		assert.Equal(t, "\x00guest\x00guest", rec.response)
+		assert.Equal(t, "/", rec.vhost)
+	})
+
+	t.Run("Rejected", func(t *testing.T) {
+		addr, _ := fakeAMQP(t, 131072, "nack")
+		_, err := PublishAMQP(testContext(t), nil, "amqp://"+addr+"/%2f", "forge", msg)
+		assert.EqualError(t, err, "the message was rejected by the broker")
+	})
+
+	t.Run("UnknownExchange", func(t *testing.T) {
+		addr, _ := fakeAMQP(t, 131072, "close")
+		_, err := PublishAMQP(testContext(t), nil, "amqp://"+addr, "forge", msg)
+		assert.ErrorContains(t, err, "NOT_FOUND - no exchange 'forge'")
+	})
+
+	t.Run("UnsupportedScheme", func(t *testing.T) {
+		_, err := PublishAMQP(testContext(t), nil, "http://127.0.0.1:1", "forge", msg)
+		assert.Error(t, err)
+	})
+}
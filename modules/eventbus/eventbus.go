@@ -0,0 +1,146 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+// Package eventbus publishes messages to AMQP 0-9-1, NATS and Kafka brokers with their
+// client libraries. A client is created for every message and closed as soon as the broker
+// acknowledged it, which suits the low volume of webhook deliveries. All the connections of
+// the clients are opened by the Dialer, so they are subject to its host restrictions.
+package eventbus
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+)
+
+// Message is published to a message broker
+type Message struct {
+	// Key is the routing key (AMQP), the subject (NATS) or the record key (Kafka)
+	Key         string
+	Headers     map[string]string
+	ContentType string
+	Body        []byte
+}
+
+// Dialer opens the connections to the brokers
+type Dialer struct {
+	// DialContext opens the TCP connection, net.Dialer is used if it is nil
+	DialContext func(ctx context.Context, network, addr string) (net.Conn, error)
+	// TLSConfig is used for the connections using TLS
+	TLSConfig *tls.Config
+}
+
+// dial connects to addr and starts a TLS session if useTLS is set. The connection
+// is closed when the context is done, the caller must call the returned stop function
+// once it has finished to use the connection.
+func (d *Dialer) dial(ctx context.Context, addr string, useTLS bool) (net.Conn, func(), error) {
+	var dialContext func(ctx context.Context, network, addr string) (net.Conn, error)
+	if d != nil && d.DialContext != nil {
+		dialContext = d.DialContext
+	} else {
+		dialContext = (&net.Dialer{}).DialContext
+	}
+
+	conn, err := dialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if useTLS {
+		conn, err = d.startTLS(ctx, conn, addr)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.Close()
+	})
+	return conn, func() {
+		stop()
+		_ = conn.Close()
+	}, nil
+}
+
+// tlsConfig returns a copy of the TLS config, the server name is set to the host of addr if it is not empty
+func (d *Dialer) tlsConfig(addr string) *tls.Config {
+	var cfg *tls.Config
+	if d != nil && d.TLSConfig != nil {
+		cfg = d.TLSConfig.Clone()
+	} else {
+		cfg = &tls.Config{}
+	}
+	if cfg.ServerName == "" && addr != "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			host = addr
+		}
+		cfg.ServerName = host
+	}
+	return cfg
+}
+
+// startTLS starts a TLS session on the connection
+func (d *Dialer) startTLS(ctx context.Context, conn net.Conn, addr string) (net.Conn, error) {
+	tlsConn := tls.Client(conn, d.tlsConfig(addr))
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("tls handshake: %w", err)
+	}
+	return tlsConn, nil
+}
+
+// connections opens the connections of a client library for a single publish,
+// they are closed when the context is done or by closeAll.
+type connections struct {
+	ctx    context.Context
+	d      *Dialer
+	useTLS bool
+
+	mu    sync.Mutex
+	stops []func()
+}
+
+func (c *connections) dial(addr string) (net.Conn, error) {
+	conn, stop, err := c.d.dial(c.ctx, addr, c.useTLS)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.stops = append(c.stops, stop)
+	c.mu.Unlock()
+	return conn, nil
+}
+
+func (c *connections) closeAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, stop := range c.stops {
+		stop()
+	}
+	c.stops = nil
+}
+
+// hostPort returns the address of the URL, using the default port if it has none
+func hostPort(u *url.URL, defaultPort string) string {
+	port := u.Port()
+	if port == "" {
+		port = defaultPort
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+// credentials returns the user name and password of the URL
+func credentials(u *url.URL) (string, string) {
+	if u.User == nil {
+		return "", ""
+	}
+	password, _ := u.User.Password()
+	return u.User.Username(), password
+}
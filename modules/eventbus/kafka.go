@@ -0,0 +1,107 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package eventbus
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+)
+
+// ValidKafkaTopic checks if the string is a legal Kafka topic name
+func ValidKafkaTopic(topic string) bool {
+	if topic == "" || topic == "." || topic == ".." || len(topic) > 249 {
+		return false
+	}
+	for _, c := range topic {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '.' || c == '_' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// PublishKafka produces the message to the topic of a Kafka cluster and waits until all
+// in-sync replicas acknowledged it. The partition is chosen by the hash of msg.Key like
+// the Java client does, so the messages with the same key keep their order. The URL has the
+// form kafka://[user:password@]host[:port] of one of the brokers, the scheme kafka+tls:// uses TLS.
+// The credentials are used for SASL PLAIN authentication.
+func PublishKafka(ctx context.Context, d *Dialer, rawURL, topic string, msg *Message) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	var useTLS bool
+	switch u.Scheme {
+	case "kafka":
+	case "kafka+tls":
+		useTLS = true
+	default:
+		return "", fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	if !ValidKafkaTopic(topic) {
+		return "", fmt.Errorf("invalid topic %q", topic)
+	}
+
+	// the client dials the brokers it discovers with the dialer, which starts the TLS sessions
+	conns := &connections{ctx: ctx, d: d, useTLS: useTLS}
+	defer conns.closeAll()
+
+	opts := []kgo.Opt{
+		kgo.SeedBrokers(hostPort(u, "9092")),
+		kgo.ClientID("forgejo"),
+		kgo.Dialer(func(_ context.Context, _, addr string) (net.Conn, error) {
+			return conns.dial(addr)
+		}),
+		kgo.RequiredAcks(kgo.AllISRAcks()),
+		// the idempotent producer needs the IDEMPOTENT_WRITE permission for a single record
+		kgo.DisableIdempotentWrite(),
+	}
+	if u.User != nil {
+		user, password := credentials(u)
+		opts = append(opts, kgo.SASL(plain.Auth{User: user, Pass: password}.AsMechanism()))
+	}
+	client, err := kgo.NewClient(opts...)
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+
+	// the producer retries until the context is done when the brokers cannot be reached
+	// or refuse the credentials, the ping reports these errors immediately
+	if err := client.Ping(ctx); err != nil {
+		return "", fmt.Errorf("connect: %w", err)
+	}
+
+	record := &kgo.Record{Topic: topic, Value: msg.Body}
+	if msg.Key != "" {
+		record.Key = []byte(msg.Key)
+	}
+	headers := make(map[string]string, len(msg.Headers)+1)
+	for k, v := range msg.Headers {
+		headers[k] = v
+	}
+	if msg.ContentType != "" {
+		headers["Content-Type"] = msg.ContentType
+	}
+	keys := make([]string, 0, len(headers))
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		record.Headers = append(record.Headers, kgo.RecordHeader{Key: k, Value: []byte(headers[k])})
+	}
+
+	produced, err := client.ProduceSync(ctx, record).First()
+	if err != nil {
+		return "", fmt.Errorf("produce: %w", err)
+	}
+	return fmt.Sprintf("Produced to partition %d of topic %s at offset %d", produced.Partition, topic, produced.Offset), nil
+}
@@ -0,0 +1,126 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package eventbus
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/kmsg"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+)
+
+// fakeKafka is a single broker cluster with a topic of two partitions
+func fakeKafka(t *testing.T, opts ...kfake.Opt) (*kfake.Cluster, string) {
+	cluster, err := kfake.NewCluster(append([]kfake.Opt{kfake.NumBrokers(1), kfake.SeedTopics(2, "events")}, opts...)...)
+	require.NoError(t, err)
+	t.Cleanup(cluster.Close)
+	return cluster, cluster.ListenAddrs()[0]
+}
+
+// consumeKafka returns the first n records of the topic events
+func consumeKafka(t *testing.T, addr string, n int, opts ...kgo.Opt) []*kgo.Record {
+	client, err := kgo.NewClient(append([]kgo.Opt{
+		kgo.SeedBrokers(addr),
+		kgo.ConsumeTopics("events"),
+		kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()),
+	}, opts...)...)
+	require.NoError(t, err)
+	defer client.Close()
+
+	ctx := testContext(t)
+	var records []*kgo.Record
+	for len(records) < n {
+		fetches := client.PollRecords(ctx, n-len(records))
+		require.NoError(t, fetches.Err())
+		records = append(records, fetches.Records()...)
+	}
+	return records
+}
+
+func TestPublishKafka(t *testing.T) {
+	msg := &Message{
+		Key:         "owner/repo",
+		Headers:     map[string]string{"X-Forgejo-Event": "push"},
+		ContentType: "application/json",
+		Body:        []byte(`{"ref":"refs/heads/main"}`),
+	}
+
+	t.Run("Produced", func(t *testing.T) {
+		_, addr := fakeKafka(t, kfake.EnableSASL(), kfake.Superuser("PLAIN", "user", "secret"))
+		ack, err := PublishKafka(testContext(t), nil, "kafka://user:secret@"+addr, "events", msg)
+		require.NoError(t, err)
+
+		records := consumeKafka(t, addr, 1, kgo.SASL(plain.Auth{User: "user", Pass: "secret"}.AsMechanism()))
+		rec := records[0]
+		assert.Equal(t, fmt.Sprintf("Produced to partition %d of topic events at offset 0", rec.Partition), ack)
+		assert.Equal(t, "events", rec.Topic)
+		assert.Equal(t, "owner/repo", string(rec.Key))
+		assert.Equal(t, string(msg.Body), string(rec.Value))
+		assert.Equal(t, []kgo.RecordHeader{
+			{Key: "Content-Type", Value: []byte("application/json")},
+			{Key: "X-Forgejo-Event", Value: []byte("push")},
+		}, rec.Headers)
+	})
+
+	t.Run("BadCredentials", func(t *testing.T) {
+		_, addr := fakeKafka(t, kfake.EnableSASL(), kfake.Superuser("PLAIN", "user", "secret"))
+		_, err := PublishKafka(testContext(t), nil, "kafka://user:wrong@"+addr, "events", msg)
+		assert.Error(t, err)
+	})
+
+	t.Run("StablePartition", func(t *testing.T) {
+		_, addr := fakeKafka(t)
+		for range 3 {
+			_, err := PublishKafka(testContext(t), nil, "kafka://"+addr, "events", msg)
+			require.NoError(t, err)
+		}
+
+		records := consumeKafka(t, addr, 3)
+		for _, rec := range records {
+			assert.Equal(t, records[0].Partition, rec.Partition)
+		}
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		cluster, addr := fakeKafka(t)
+		cluster.ControlKey(int16(kmsg.Produce), func(kreq kmsg.Request) (kmsg.Response, error, bool) {
+			req := kreq.(*kmsg.ProduceRequest)
+			resp := req.ResponseKind().(*kmsg.ProduceResponse)
+			for _, rt := range req.Topics {
+				st := kmsg.NewProduceResponseTopic()
+				st.Topic = rt.Topic
+				for _, rp := range rt.Partitions {
+					sp := kmsg.NewProduceResponseTopicPartition()
+					sp.Partition = rp.Partition
+					sp.ErrorCode = kerr.TopicAuthorizationFailed.Code
+					st.Partitions = append(st.Partitions, sp)
+				}
+				resp.Topics = append(resp.Topics, st)
+			}
+			return resp, nil, true
+		})
+
+		_, err := PublishKafka(testContext(t), nil, "kafka://"+addr, "events", msg)
+		assert.ErrorIs(t, err, kerr.TopicAuthorizationFailed)
+	})
+
+	t.Run("UnknownTopic", func(t *testing.T) {
+		_, addr := fakeKafka(t)
+		_, err := PublishKafka(testContext(t), nil, "kafka://"+addr, "other", msg)
+		assert.ErrorIs(t, err, kerr.UnknownTopicOrPartition)
+	})
+}
+
+func TestValidKafkaTopic(t *testing.T) {
+	assert.True(t, ValidKafkaTopic("forgejo.events_v1-test"))
+	assert.False(t, ValidKafkaTopic(""))
+	assert.False(t, ValidKafkaTopic(".."))
+	assert.False(t, ValidKafkaTopic("forgejo/events"))
+}
@@ -0,0 +1,94 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package eventbus
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+)
+
+// ValidNATSSubject checks if the string can be used as NATS subject to publish to
+func ValidNATSSubject(subject string) bool {
+	if subject == "" || strings.ContainsAny(subject, " \t\r\n*>") {
+		return false
+	}
+	for _, token := range strings.Split(subject, ".") {
+		if token == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// natsDialer opens the connections of the NATS client, the client starts the TLS session itself
+type natsDialer struct {
+	conns *connections
+}
+
+func (d natsDialer) Dial(_, addr string) (net.Conn, error) {
+	return d.conns.dial(addr)
+}
+
+// PublishNATS publishes the message to the subject msg.Key of a NATS server and waits
+// until the server processed it. The URL has the form nats://[user:password@|token@]host[:port],
+// the scheme tls:// requires a TLS connection.
+func PublishNATS(ctx context.Context, d *Dialer, rawURL string, msg *Message) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "nats" && u.Scheme != "tls" {
+		return "", fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	if !ValidNATSSubject(msg.Key) {
+		return "", fmt.Errorf("invalid subject %q", msg.Key)
+	}
+
+	conns := &connections{ctx: ctx, d: d}
+	defer conns.closeAll()
+
+	nc, err := nats.Connect(rawURL,
+		nats.Name("forgejo"),
+		nats.NoReconnect(),
+		nats.SetCustomDialer(natsDialer{conns: conns}),
+		func(o *nats.Options) error {
+			// used when the URL or the server requires TLS
+			o.TLSConfig = d.tlsConfig("")
+			return nil
+		},
+	)
+	if err != nil {
+		return "", err
+	}
+	defer nc.Close()
+
+	m := &nats.Msg{Subject: msg.Key, Data: msg.Body}
+	if nc.HeadersSupported() && (len(msg.Headers) > 0 || msg.ContentType != "") {
+		m.Header = nats.Header{}
+		if msg.ContentType != "" {
+			m.Header.Set("Content-Type", msg.ContentType)
+		}
+		for k, v := range msg.Headers {
+			m.Header.Set(k, v)
+		}
+	}
+	if err := nc.PublishMsg(m); err != nil {
+		return "", err
+	}
+
+	// the server answers the PING of the flush after it processed the message,
+	// an error like a permissions violation is received before the PONG
+	if err := nc.FlushWithContext(ctx); err != nil {
+		return "", err
+	}
+	if err := nc.LastError(); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Published to subject %s", msg.Key), nil
+}
@@ -0,0 +1,164 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package eventbus
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"code.gitea.io/gitea/modules/json"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serve accepts a single connection and passes it to the handler
+func serve(t *testing.T, handle func(conn net.Conn)) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_ = conn.SetDeadline(time.Now().Add(10 * time.Second))
+		handle(conn)
+	}()
+	return l.Addr().String()
+}
+
+func testContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+// natsConnect is the CONNECT of a NATS client
+type natsConnect struct {
+	Name      string `json:"name"`
+	Headers   bool   `json:"headers"`
+	User      string `json:"user"`
+	Pass      string `json:"pass"`
+	AuthToken string `json:"auth_token"`
+}
+
+type natsRecord struct {
+	connect natsConnect
+	command string
+	payload string
+}
+
+// fakeNATS answers the handshake of a client and the PING sent after a single message with reply
+func fakeNATS(t *testing.T, headers bool, reply string) (string, chan natsRecord) {
+	records := make(chan natsRecord, 1)
+	addr := serve(t, func(conn net.Conn) {
+		fmt.Fprintf(conn, "INFO {\"server_id\":\"test\",\"proto\":1,\"headers\":%t,\"max_payload\":1048576}\r\n", headers)
+
+		var rec natsRecord
+		defer func() { records <- rec }()
+
+		r := bufio.NewReader(conn)
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		connect, _ := strings.CutPrefix(strings.TrimSpace(line), "CONNECT ")
+		_ = json.Unmarshal([]byte(connect), &rec.connect)
+
+		// the client waits for the PONG of its first PING to complete the connection
+		if line, err = r.ReadString('\n'); err != nil || strings.TrimSpace(line) != "PING" {
+			return
+		}
+		fmt.Fprint(conn, "PONG\r\n")
+
+		line, err = r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		rec.command = strings.TrimSpace(line)
+		fields := strings.Fields(rec.command)
+		size, _ := strconv.Atoi(fields[len(fields)-1])
+		payload := make([]byte, size+2)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return
+		}
+		rec.payload = string(payload[:size])
+
+		line, _ = r.ReadString('\n')
+		if strings.TrimSpace(line) == "PING" {
+			fmt.Fprint(conn, reply)
+		}
+		// wait for the client to close the connection
+		_, _ = io.Copy(io.Discard, r)
+	})
+	return addr, records
+}
+
+func TestPublishNATS(t *testing.T) {
+	msg := &Message{
+		Key:         "forgejo.push.owner.repo",
+		Headers:     map[string]string{"X-Forgejo-Event": "push"},
+		ContentType: "application/json",
+		Body:        []byte(`{"ref":"refs/heads/main"}`),
+	}
+
+	t.Run("Headers", func(t *testing.T) {
+		addr, records := fakeNATS(t, true, "PONG\r\n")
+		ack, err := PublishNATS(testContext(t), nil, "nats://user:secret@"+addr, msg)
+		require.NoError(t, err)
+		assert.Equal(t, "Published to subject forgejo.push.owner.repo", ack)
+
+		rec := <-records
+		assert.Equal(t, "user", rec.connect.User)
+		assert.Equal(t, "secret", rec.connect.Pass)
+		assert.Equal(t, "forgejo", rec.connect.Name)
+		assert.True(t, rec.connect.Headers)
+		headers := "NATS/1.0\r\nContent-Type: application/json\r\nX-Forgejo-Event: push\r\n\r\n"
+		assert.Equal(t, fmt.Sprintf("HPUB forgejo.push.owner.repo %d %d", len(headers), len(headers)+len(msg.Body)), rec.command)
+		assert.Equal(t, headers+string(msg.Body), rec.payload)
+	})
+
+	t.Run("NoHeaders", func(t *testing.T) {
+		addr, records := fakeNATS(t, false, "PONG\r\n")
+		_, err := PublishNATS(testContext(t), nil, "nats://token@"+addr, msg)
+		require.NoError(t, err)
+
+		rec := <-records
+		assert.Equal(t, "token", rec.connect.AuthToken)
+		assert.Equal(t, fmt.Sprintf("PUB forgejo.push.owner.repo %d", len(msg.Body)), rec.command)
+		assert.Equal(t, string(msg.Body), rec.payload)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		addr, _ := fakeNATS(t, true, "-ERR 'Permissions Violation for Publish to forgejo.push.owner.repo'\r\nPONG\r\n")
+		_, err := PublishNATS(testContext(t), nil, "nats://"+addr, msg)
+		assert.EqualError(t, err, "nats: Permissions Violation for Publish to forgejo.push.owner.repo")
+	})
+
+	t.Run("InvalidSubject", func(t *testing.T) {
+		_, err := PublishNATS(testContext(t), nil, "nats://127.0.0.1:1", &Message{Key: "forgejo.*"})
+		assert.Error(t, err)
+	})
+}
+
+func TestValidNATSSubject(t *testing.T) {
+	assert.True(t, ValidNATSSubject("forgejo.push.owner.repo"))
+	assert.False(t, ValidNATSSubject(""))
+	assert.False(t, ValidNATSSubject("forgejo..push"))
+	assert.False(t, ValidNATSSubject("forgejo.push."))
+	assert.False(t, ValidNATSSubject("forgejo.>"))
+	assert.False(t, ValidNATSSubject("forgejo push"))
+}
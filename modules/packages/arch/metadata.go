@@ -0,0 +1,272 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package arch
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"code.gitea.io/gitea/modules/util"
+	"code.gitea.io/gitea/modules/validation"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+var (
+	ErrMissingPKGINFOFile  = util.NewInvalidArgumentErrorf(".PKGINFO file is missing")
+	ErrUnsupportedFormat   = util.NewInvalidArgumentErrorf("package format is not supported")
+	ErrInvalidName         = util.NewInvalidArgumentErrorf("package name is invalid")
+	ErrInvalidVersion      = util.NewInvalidArgumentErrorf("package version is invalid")
+	ErrInvalidArchitecture = util.NewInvalidArgumentErrorf("package architecture is invalid")
+
+	// https://man.archlinux.org/man/PKGBUILD.5
+	namePattern = regexp.MustCompile(`\A[a-z0-9@_+][a-z0-9@._+-]*\z`)
+	// (epoch:)pkgver-pkgrel
+	versionPattern = regexp.MustCompile(`\A(?:\d+:)?[\w.+~]+-\d+(?:\.\d+)?\z`)
+)
+
+const (
+	PropertyDistribution = "arch.distribution"
+	PropertyArchitecture = "arch.architecture"
+	PropertyMetadata     = "arch.metadata"
+	PropertySignature    = "arch.signature"
+
+	SettingKeyPrivate = "arch.key.private"
+	SettingKeyPublic  = "arch.key.public"
+
+	RepositoryPackage = "_arch"
+	RepositoryVersion = "_repository"
+
+	// AnyArchitecture marks packages which are installable on every architecture
+	AnyArchitecture = "any"
+
+	ExtensionZstd = ".pkg.tar.zst"
+	ExtensionXz   = ".pkg.tar.xz"
+)
+
+var (
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	xzMagic   = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+)
+
+// https://man.archlinux.org/man/PKGBUILD.5
+// https://gitlab.archlinux.org/pacman/pacman/-/blob/master/lib/libalpm/be_package.c
+
+// Package represents an Arch Linux package
+type Package struct {
+	Name            string
+	Version         string
+	FileExtension   string
+	VersionMetadata VersionMetadata
+	FileMetadata    FileMetadata
+}
+
+// VersionMetadata of an Arch Linux package
+type VersionMetadata struct {
+	Base        string   `json:"base,omitempty"`
+	Description string   `json:"description,omitempty"`
+	ProjectURL  string   `json:"project_url,omitempty"`
+	Licenses    []string `json:"licenses,omitempty"`
+	Groups      []string `json:"groups,omitempty"`
+}
+
+// FileMetadata of an Arch Linux package file
+type FileMetadata struct {
+	Architecture  string   `json:"architecture"`
+	InstalledSize int64    `json:"installed_size,omitempty"`
+	BuildDate     int64    `json:"build_date,omitempty"`
+	Packager      string   `json:"packager,omitempty"`
+	Provides      []string `json:"provides,omitempty"`
+	Depends       []string `json:"depends,omitempty"`
+	OptDepends    []string `json:"opt_depends,omitempty"`
+	MakeDepends   []string `json:"make_depends,omitempty"`
+	CheckDepends  []string `json:"check_depends,omitempty"`
+	Conflicts     []string `json:"conflicts,omitempty"`
+	Replaces      []string `json:"replaces,omitempty"`
+	Backup        []string `json:"backup,omitempty"`
+	Files         []string `json:"files,omitempty"`
+}
+
+// ParsePackage parses the Arch Linux package file (.pkg.tar.zst or .pkg.tar.xz)
+func ParsePackage(r io.Reader) (*Package, error) {
+	br := bufio.NewReader(r)
+
+	magic, err := br.Peek(len(xzMagic))
+	if err != nil {
+		return nil, ErrUnsupportedFormat
+	}
+
+	var tr *tar.Reader
+	var extension string
+	switch {
+	case bytes.HasPrefix(magic, zstdMagic):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		tr = tar.NewReader(zr)
+		extension = ExtensionZstd
+	case bytes.HasPrefix(magic, xzMagic):
+		xzr, err := xz.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		tr = tar.NewReader(xzr)
+		extension = ExtensionXz
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+
+	var p *Package
+	files := make([]string, 0, 10)
+	for {
+		hd, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		name := strings.TrimPrefix(hd.Name, "./")
+		if name == ".PKGINFO" {
+			p, err = ParsePackageInfo(tr)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		// the other metadata files (.BUILDINFO, .MTREE, .INSTALL, ...) are not part of the installed files
+		if name == "" || strings.HasPrefix(name, ".") {
+			continue
+		}
+		if hd.Typeflag == tar.TypeDir && !strings.HasSuffix(name, "/") {
+			name += "/"
+		}
+		files = append(files, name)
+	}
+
+	if p == nil {
+		return nil, ErrMissingPKGINFOFile
+	}
+
+	p.FileExtension = extension
+	p.FileMetadata.Files = files
+
+	return p, nil
+}
+
+// ParsePackageInfo parses a .PKGINFO file to retrieve the metadata of an Arch Linux package
+func ParsePackageInfo(r io.Reader) (*Package, error) {
+	p := &Package{}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "pkgname":
+			p.Name = value
+		case "pkgbase":
+			p.VersionMetadata.Base = value
+		case "pkgver":
+			p.Version = value
+		case "pkgdesc":
+			p.VersionMetadata.Description = value
+		case "url":
+			p.VersionMetadata.ProjectURL = value
+		case "license":
+			p.VersionMetadata.Licenses = appendValue(p.VersionMetadata.Licenses, value)
+		case "group":
+			p.VersionMetadata.Groups = appendValue(p.VersionMetadata.Groups, value)
+		case "builddate":
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err == nil {
+				p.FileMetadata.BuildDate = n
+			}
+		case "size":
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err == nil {
+				p.FileMetadata.InstalledSize = n
+			}
+		case "packager":
+			p.FileMetadata.Packager = value
+		case "arch":
+			p.FileMetadata.Architecture = value
+		case "provides":
+			p.FileMetadata.Provides = appendValue(p.FileMetadata.Provides, value)
+		case "depend":
+			p.FileMetadata.Depends = appendValue(p.FileMetadata.Depends, value)
+		case "optdepend":
+			p.FileMetadata.OptDepends = appendValue(p.FileMetadata.OptDepends, value)
+		case "makedepend":
+			p.FileMetadata.MakeDepends = appendValue(p.FileMetadata.MakeDepends, value)
+		case "checkdepend":
+			p.FileMetadata.CheckDepends = appendValue(p.FileMetadata.CheckDepends, value)
+		case "conflict":
+			p.FileMetadata.Conflicts = appendValue(p.FileMetadata.Conflicts, value)
+		case "replaces":
+			p.FileMetadata.Replaces = appendValue(p.FileMetadata.Replaces, value)
+		case "backup":
+			p.FileMetadata.Backup = appendValue(p.FileMetadata.Backup, value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if !namePattern.MatchString(p.Name) {
+		return nil, ErrInvalidName
+	}
+
+	if !versionPattern.MatchString(p.Version) {
+		return nil, ErrInvalidVersion
+	}
+
+	if !namePattern.MatchString(p.FileMetadata.Architecture) {
+		return nil, ErrInvalidArchitecture
+	}
+
+	if p.VersionMetadata.Base == "" {
+		p.VersionMetadata.Base = p.Name
+	}
+
+	if !validation.IsValidURL(p.VersionMetadata.ProjectURL) {
+		p.VersionMetadata.ProjectURL = ""
+	}
+
+	return p, nil
+}
+
+func appendValue(values []string, value string) []string {
+	if value == "" {
+		return values
+	}
+	return append(values, value)
+}
+
+// Filename returns the name of the package file, the extension matches the compression of the package
+func (p *Package) Filename() string {
+	return p.Name + "-" + p.Version + "-" + p.FileMetadata.Architecture + p.FileExtension
+}
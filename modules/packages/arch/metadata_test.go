@@ -0,0 +1,175 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package arch
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ulikunitz/xz"
+)
+
+const (
+	packageName        = "gitea"
+	packageVersion     = "1:1.0.1-2"
+	packageDescription = "Package Description"
+	packageProjectURL  = "https://gitea.io"
+)
+
+func createPKGINFOContent(name, version, architecture string) []byte {
+	return []byte(`# Generated by makepkg
+pkgname = ` + name + `
+pkgbase = ` + name + `-base
+pkgver = ` + version + `
+pkgdesc = ` + packageDescription + `
+url = ` + packageProjectURL + `
+builddate = 1678834800
+packager = Gitea <pack@ag.er>
+size = 123456
+arch = ` + architecture + `
+license = MIT
+license = Apache-2.0
+group = tools
+depend = glibc
+depend = git
+optdepend = sqlite: database support
+makedepend = go
+provides = forge
+conflict = gitea-bin
+backup = etc/gitea/app.ini`)
+}
+
+func createPackage(compression string, files map[string][]byte) io.Reader {
+	var buf bytes.Buffer
+
+	var w io.WriteCloser
+	switch compression {
+	case "zst":
+		w, _ = zstd.NewWriter(&buf)
+	case "xz":
+		w, _ = xz.NewWriter(&buf)
+	}
+
+	tw := tar.NewWriter(w)
+	for _, name := range []string{".PKGINFO", ".MTREE", "usr/", "usr/bin/", "usr/bin/gitea"} {
+		content, ok := files[name]
+		if !ok {
+			continue
+		}
+		hdr := &tar.Header{
+			Name: name,
+			Mode: 0o600,
+			Size: int64(len(content)),
+		}
+		if strings.HasSuffix(name, "/") {
+			hdr.Typeflag = tar.TypeDir
+			hdr.Name = strings.TrimSuffix(name, "/")
+		}
+		tw.WriteHeader(hdr)
+		tw.Write(content)
+	}
+	tw.Close()
+	w.Close()
+
+	return &buf
+}
+
+func TestParsePackage(t *testing.T) {
+	t.Run("UnsupportedFormat", func(t *testing.T) {
+		pp, err := ParsePackage(strings.NewReader("dummy content"))
+		assert.Nil(t, pp)
+		assert.ErrorIs(t, err, ErrUnsupportedFormat)
+	})
+
+	t.Run("MissingPKGINFOFile", func(t *testing.T) {
+		data := createPackage("zst", map[string][]byte{"usr/bin/gitea": {}})
+
+		pp, err := ParsePackage(data)
+		assert.Nil(t, pp)
+		assert.ErrorIs(t, err, ErrMissingPKGINFOFile)
+	})
+
+	t.Run("InvalidPKGINFOFile", func(t *testing.T) {
+		data := createPackage("zst", map[string][]byte{".PKGINFO": createPKGINFOContent(packageName, "1.0", "x86_64")})
+
+		pp, err := ParsePackage(data)
+		assert.Nil(t, pp)
+		assert.ErrorIs(t, err, ErrInvalidVersion)
+	})
+
+	for _, compression := range []string{"zst", "xz"} {
+		t.Run("Valid"+compression, func(t *testing.T) {
+			data := createPackage(compression, map[string][]byte{
+				".PKGINFO":      createPKGINFOContent(packageName, packageVersion, "x86_64"),
+				".MTREE":        {},
+				"usr/":          {},
+				"usr/bin/":      {},
+				"usr/bin/gitea": []byte("binary"),
+			})
+
+			pp, err := ParsePackage(data)
+			require.NoError(t, err)
+			assert.NotNil(t, pp)
+			assert.Equal(t, packageName, pp.Name)
+			assert.Equal(t, packageVersion, pp.Version)
+			assert.Equal(t, ".pkg.tar."+compression, pp.FileExtension)
+			assert.Equal(t, "gitea-1:1.0.1-2-x86_64.pkg.tar."+compression, pp.Filename())
+			assert.Equal(t, []string{"usr/", "usr/bin/", "usr/bin/gitea"}, pp.FileMetadata.Files)
+		})
+	}
+}
+
+func TestParsePackageInfo(t *testing.T) {
+	t.Run("InvalidName", func(t *testing.T) {
+		for _, name := range []string{"", "-gitea", ".gitea", "Gitea", "git ea"} {
+			pp, err := ParsePackageInfo(bytes.NewReader(createPKGINFOContent(name, packageVersion, "x86_64")))
+			assert.Nil(t, pp)
+			assert.ErrorIs(t, err, ErrInvalidName)
+		}
+	})
+
+	t.Run("InvalidVersion", func(t *testing.T) {
+		for _, version := range []string{"", "1.0", "1.0-a", "1.0-1-1", "a:1.0-1"} {
+			pp, err := ParsePackageInfo(bytes.NewReader(createPKGINFOContent(packageName, version, "x86_64")))
+			assert.Nil(t, pp)
+			assert.ErrorIs(t, err, ErrInvalidVersion)
+		}
+	})
+
+	t.Run("InvalidArchitecture", func(t *testing.T) {
+		pp, err := ParsePackageInfo(bytes.NewReader(createPKGINFOContent(packageName, packageVersion, "")))
+		assert.Nil(t, pp)
+		assert.ErrorIs(t, err, ErrInvalidArchitecture)
+	})
+
+	t.Run("Valid", func(t *testing.T) {
+		pp, err := ParsePackageInfo(bytes.NewReader(createPKGINFOContent(packageName, packageVersion, AnyArchitecture)))
+		require.NoError(t, err)
+		assert.NotNil(t, pp)
+
+		assert.Equal(t, packageName, pp.Name)
+		assert.Equal(t, packageVersion, pp.Version)
+		assert.Equal(t, packageName+"-base", pp.VersionMetadata.Base)
+		assert.Equal(t, packageDescription, pp.VersionMetadata.Description)
+		assert.Equal(t, packageProjectURL, pp.VersionMetadata.ProjectURL)
+		assert.Equal(t, []string{"MIT", "Apache-2.0"}, pp.VersionMetadata.Licenses)
+		assert.Equal(t, []string{"tools"}, pp.VersionMetadata.Groups)
+		assert.Equal(t, AnyArchitecture, pp.FileMetadata.Architecture)
+		assert.EqualValues(t, 123456, pp.FileMetadata.InstalledSize)
+		assert.EqualValues(t, 1678834800, pp.FileMetadata.BuildDate)
+		assert.Equal(t, "Gitea <pack@ag.er>", pp.FileMetadata.Packager)
+		assert.Equal(t, []string{"glibc", "git"}, pp.FileMetadata.Depends)
+		assert.Equal(t, []string{"sqlite: database support"}, pp.FileMetadata.OptDepends)
+		assert.Equal(t, []string{"go"}, pp.FileMetadata.MakeDepends)
+		assert.Equal(t, []string{"forge"}, pp.FileMetadata.Provides)
+		assert.Equal(t, []string{"gitea-bin"}, pp.FileMetadata.Conflicts)
+		assert.Equal(t, []string{"etc/gitea/app.ini"}, pp.FileMetadata.Backup)
+	})
+}
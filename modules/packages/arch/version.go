@@ -0,0 +1,155 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package arch
+
+import (
+	"strings"
+)
+
+// CompareVersions compares two package versions like pacman does.
+// It returns -1 if a is older than b, 1 if a is newer than b and 0 if both versions are equal.
+// https://gitlab.archlinux.org/pacman/pacman/-/blob/master/lib/libalpm/version.c
+func CompareVersions(a, b string) int {
+	if a == b {
+		return 0
+	}
+
+	epoch1, version1, release1 := parseEVR(a)
+	epoch2, version2, release2 := parseEVR(b)
+
+	if c := compareSegments(epoch1, epoch2); c != 0 {
+		return c
+	}
+	if c := compareSegments(version1, version2); c != 0 {
+		return c
+	}
+	if release1 != "" && release2 != "" {
+		return compareSegments(release1, release2)
+	}
+	return 0
+}
+
+// parseEVR splits a version into epoch, version and release
+func parseEVR(evr string) (string, string, string) {
+	epoch := "0"
+	version := evr
+
+	i := 0
+	for i < len(evr) && isDigit(evr[i]) {
+		i++
+	}
+	if i < len(evr) && evr[i] == ':' {
+		if i > 0 {
+			epoch = evr[:i]
+		}
+		version = evr[i+1:]
+	}
+
+	release := ""
+	if j := strings.LastIndexByte(version, '-'); j != -1 {
+		release = version[j+1:]
+		version = version[:j]
+	}
+
+	return epoch, version, release
+}
+
+// compareSegments is the rpmvercmp algorithm used by pacman
+func compareSegments(a, b string) int {
+	if a == b {
+		return 0
+	}
+
+	one, two := 0, 0
+	ptr1, ptr2 := 0, 0
+
+	for one < len(a) && two < len(b) {
+		for one < len(a) && !isAlnum(a[one]) {
+			one++
+		}
+		for two < len(b) && !isAlnum(b[two]) {
+			two++
+		}
+
+		if one >= len(a) || two >= len(b) {
+			break
+		}
+
+		// the version with the longer separator is newer
+		if one-ptr1 != two-ptr2 {
+			if one-ptr1 < two-ptr2 {
+				return -1
+			}
+			return 1
+		}
+
+		ptr1, ptr2 = one, two
+
+		isNum := isDigit(a[ptr1])
+		if isNum {
+			for ptr1 < len(a) && isDigit(a[ptr1]) {
+				ptr1++
+			}
+			for ptr2 < len(b) && isDigit(b[ptr2]) {
+				ptr2++
+			}
+		} else {
+			for ptr1 < len(a) && isAlpha(a[ptr1]) {
+				ptr1++
+			}
+			for ptr2 < len(b) && isAlpha(b[ptr2]) {
+				ptr2++
+			}
+		}
+
+		seg1, seg2 := a[one:ptr1], b[two:ptr2]
+
+		// the segments are of different types, numeric segments are newer
+		if seg2 == "" {
+			if isNum {
+				return 1
+			}
+			return -1
+		}
+
+		if isNum {
+			seg1 = strings.TrimLeft(seg1, "0")
+			seg2 = strings.TrimLeft(seg2, "0")
+			if len(seg1) > len(seg2) {
+				return 1
+			}
+			if len(seg2) > len(seg1) {
+				return -1
+			}
+		}
+
+		if c := strings.Compare(seg1, seg2); c != 0 {
+			return c
+		}
+
+		one, two = ptr1, ptr2
+	}
+
+	if one >= len(a) && two >= len(b) {
+		return 0
+	}
+
+	// "1.0" is newer than "1.0a" but older than "1.0.1"
+	if (one >= len(a) && !isAlpha(b[two])) || (one < len(a) && isAlpha(a[one])) {
+		return -1
+	}
+	return 1
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isAlpha(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+func isAlnum(c byte) bool {
+	return isDigit(c) || isAlpha(c)
+}
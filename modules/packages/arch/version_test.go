@@ -0,0 +1,41 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package arch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareVersions(t *testing.T) {
+	cases := []struct {
+		A        string
+		B        string
+		Expected int
+	}{
+		{"1.0-1", "1.0-1", 0},
+		{"1.0-1", "1.0-2", -1},
+		{"1.0-2", "1.0-1", 1},
+		{"1.0-1", "1.1-1", -1},
+		{"1.10-1", "1.9-1", 1},
+		{"1.0a-1", "1.0-1", -1},
+		{"1.0-1", "1.0.1-1", -1},
+		{"1.0alpha-1", "1.0beta-1", -1},
+		{"1.001-1", "1.1-1", 0},
+		{"1:1.0-1", "2.0-1", 1},
+		{"1:1.0-1", "1:1.0-1", 0},
+		{"0:1.0-1", "1.0-1", 0},
+		{"2:1.0-1", "1:9.0-1", 1},
+		{"1.0", "1.0-1", 0},
+		{"1.0-1.1", "1.0-1", 1},
+		{"1.0_1-1", "1.0.1-1", 0},
+		{"1.0..1-1", "1.0.1-1", 1},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.Expected, CompareVersions(c.A, c.B), "%s <=> %s", c.A, c.B)
+		assert.Equal(t, -c.Expected, CompareVersions(c.B, c.A), "%s <=> %s", c.B, c.A)
+	}
+}
@@ -0,0 +1,31 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package nix
+
+// Nix uses its own base32 alphabet which omits the letters e, o, u and t
+const base32Alphabet = "0123456789abcdfghijklmnpqrsvwxyz"
+
+// EncodeBase32 encodes the data with the base32 encoding of Nix.
+// The encoding processes the bits in reverse order and has no padding.
+// https://github.com/NixOS/nix/blob/master/src/libutil/hash.cc
+func EncodeBase32(data []byte) string {
+	if len(data) == 0 {
+		return ""
+	}
+
+	length := (len(data)*8-1)/5 + 1
+
+	s := make([]byte, 0, length)
+	for n := length - 1; n >= 0; n-- {
+		b := n * 5
+		i := b / 8
+		j := b % 8
+		c := data[i] >> j
+		if i+1 < len(data) {
+			c |= data[i+1] << (8 - j)
+		}
+		s = append(s, base32Alphabet[c&0x1f])
+	}
+	return string(s)
+}
@@ -0,0 +1,274 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package nix
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"code.gitea.io/gitea/modules/util"
+)
+
+var (
+	ErrInvalidNarInfo    = util.NewInvalidArgumentErrorf("narinfo is invalid")
+	ErrInvalidStorePath  = util.NewInvalidArgumentErrorf("store path is invalid")
+	ErrInvalidURL        = util.NewInvalidArgumentErrorf("nar url is invalid")
+	ErrInvalidPublicKey  = util.NewInvalidArgumentErrorf("public key is invalid")
+	ErrInvalidReferences = util.NewInvalidArgumentErrorf("references are invalid")
+
+	// <hash>-<name>, the hash is the 160 bit store path hash in the Nix base32 encoding
+	storePathBasePattern = regexp.MustCompile(`\A([` + base32Alphabet + `]{32})-([A-Za-z0-9+\-._?=]+)\z`)
+	// <file hash>.nar(.<compression>)
+	narFilenamePattern = regexp.MustCompile(`\A[` + base32Alphabet + `]+\.nar(?:\.[a-z0-9]+)?\z`)
+)
+
+const (
+	SettingKeyTrustedKeys = "nix.trusted_keys"
+
+	// NARs are uploaded before their narinfo and kept in this internal package until they are referenced
+	UploadPackage = "_nar"
+	UploadVersion = "_uploads"
+
+	StoreDir = "/nix/store"
+
+	NarInfoExtension = ".narinfo"
+)
+
+// NarInfo represents the metadata of a store path in a binary cache
+// https://github.com/NixOS/nix/blob/master/src/libstore/nar-info.cc
+type NarInfo struct {
+	StorePath   string
+	URL         string
+	Compression string
+	FileHash    string
+	FileSize    int64
+	NarHash     string
+	NarSize     int64
+	References  []string
+	Deriver     string
+	System      string
+	Signatures  []string
+	CA          string
+}
+
+// VersionMetadata of a store path
+type VersionMetadata struct {
+	StorePath  string   `json:"store_path"`
+	NarHash    string   `json:"nar_hash"`
+	NarSize    int64    `json:"nar_size"`
+	References []string `json:"references,omitempty"`
+	Deriver    string   `json:"deriver,omitempty"`
+	System     string   `json:"system,omitempty"`
+	CA         string   `json:"ca,omitempty"`
+}
+
+// ParseNarInfo parses a narinfo file
+func ParseNarInfo(r io.Reader) (*NarInfo, error) {
+	ni := &NarInfo{}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ": ")
+		if !ok {
+			return nil, ErrInvalidNarInfo
+		}
+
+		switch key {
+		case "StorePath":
+			ni.StorePath = value
+		case "URL":
+			ni.URL = value
+		case "Compression":
+			ni.Compression = value
+		case "FileHash":
+			ni.FileHash = value
+		case "FileSize":
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, ErrInvalidNarInfo
+			}
+			ni.FileSize = n
+		case "NarHash":
+			ni.NarHash = value
+		case "NarSize":
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, ErrInvalidNarInfo
+			}
+			ni.NarSize = n
+		case "References":
+			ni.References = strings.Fields(value)
+		case "Deriver":
+			if value != "unknown-deriver" {
+				ni.Deriver = value
+			}
+		case "System":
+			ni.System = value
+		case "Sig":
+			ni.Signatures = append(ni.Signatures, value)
+		case "CA":
+			ni.CA = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if ni.Hash() == "" {
+		return nil, ErrInvalidStorePath
+	}
+	if !strings.HasPrefix(ni.URL, "nar/") || !narFilenamePattern.MatchString(strings.TrimPrefix(ni.URL, "nar/")) {
+		return nil, ErrInvalidURL
+	}
+	if ni.NarHash == "" || ni.NarSize <= 0 {
+		return nil, ErrInvalidNarInfo
+	}
+	for _, reference := range ni.References {
+		if !storePathBasePattern.MatchString(reference) {
+			return nil, ErrInvalidReferences
+		}
+	}
+	if ni.Compression == "" {
+		ni.Compression = "bzip2"
+	}
+
+	return ni, nil
+}
+
+func (ni *NarInfo) storePathParts() []string {
+	base, ok := strings.CutPrefix(ni.StorePath, StoreDir+"/")
+	if !ok {
+		return nil
+	}
+	return storePathBasePattern.FindStringSubmatch(base)
+}
+
+// Hash returns the hash part of the store path, it's empty if the store path is invalid
+func (ni *NarInfo) Hash() string {
+	if m := ni.storePathParts(); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// Name returns the name part of the store path, it's empty if the store path is invalid
+func (ni *NarInfo) Name() string {
+	if m := ni.storePathParts(); m != nil {
+		return m[2]
+	}
+	return ""
+}
+
+// NarFilename returns the name of the NAR file referenced by the narinfo
+func (ni *NarInfo) NarFilename() string {
+	return strings.TrimPrefix(ni.URL, "nar/")
+}
+
+// VersionMetadata returns the metadata which is stored with the package version
+func (ni *NarInfo) VersionMetadata() *VersionMetadata {
+	return &VersionMetadata{
+		StorePath:  ni.StorePath,
+		NarHash:    ni.NarHash,
+		NarSize:    ni.NarSize,
+		References: ni.References,
+		Deriver:    ni.Deriver,
+		System:     ni.System,
+		CA:         ni.CA,
+	}
+}
+
+// Fingerprint returns the data which is signed by the signatures of the narinfo
+func (ni *NarInfo) Fingerprint() string {
+	references := make([]string, 0, len(ni.References))
+	for _, reference := range ni.References {
+		references = append(references, StoreDir+"/"+reference)
+	}
+	return "1;" + ni.StorePath + ";" + ni.NarHash + ";" + strconv.FormatInt(ni.NarSize, 10) + ";" + strings.Join(references, ",")
+}
+
+// IsSignedBy tests if the narinfo has a valid signature of one of the keys
+func (ni *NarInfo) IsSignedBy(keys []*PublicKey) bool {
+	fingerprint := []byte(ni.Fingerprint())
+	for _, signature := range ni.Signatures {
+		name, value, ok := strings.Cut(signature, ":")
+		if !ok {
+			continue
+		}
+		sig, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			continue
+		}
+		for _, key := range keys {
+			if key.Name == name && ed25519.Verify(key.Key, fingerprint, sig) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// MatchesFileHash tests if the file hash of the narinfo matches the SHA256 hash of the NAR file.
+// Nix writes the hash in its base32 encoding but the hexadecimal form is accepted too.
+func (ni *NarInfo) MatchesFileHash(hashSHA256 string) bool {
+	if ni.FileHash == "" {
+		return true
+	}
+
+	value, ok := strings.CutPrefix(ni.FileHash, "sha256:")
+	if !ok {
+		return false
+	}
+
+	sum, err := hex.DecodeString(hashSHA256)
+	if err != nil {
+		return false
+	}
+
+	return value == EncodeBase32(sum) || strings.EqualFold(value, hashSHA256)
+}
+
+// PublicKey is a named ed25519 key used to sign narinfo files
+type PublicKey struct {
+	Name string
+	Key  ed25519.PublicKey
+}
+
+// ParsePublicKey parses a public key in the format of nix-store --generate-binary-cache-key (<name>:<base64 key>)
+func ParsePublicKey(s string) (*PublicKey, error) {
+	name, value, ok := strings.Cut(strings.TrimSpace(s), ":")
+	if !ok || name == "" {
+		return nil, ErrInvalidPublicKey
+	}
+
+	key, err := base64.StdEncoding.DecodeString(value)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, ErrInvalidPublicKey
+	}
+
+	return &PublicKey{
+		Name: name,
+		Key:  ed25519.PublicKey(key),
+	}, nil
+}
+
+// String returns the key in the format of nix-store --generate-binary-cache-key
+func (k *PublicKey) String() string {
+	return k.Name + ":" + base64.StdEncoding.EncodeToString(k.Key)
+}
+
+// IsValidNarFilename tests if the name is a valid name of a NAR file
+func IsValidNarFilename(name string) bool {
+	return narFilenamePattern.MatchString(name)
+}
@@ -0,0 +1,148 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package nix
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	storePathHash = "3m4jfbx6pgzkz4vbp2ik0fd2zlhbqqyy"
+	storePathName = "hello-2.12.1"
+	storePath     = StoreDir + "/" + storePathHash + "-" + storePathName
+	narHash       = "sha256:0mdqa9w1p6cmli6976v4wi0sw9r4p5prkj7lzfd1877wk11c9c73"
+	narURL        = "nar/0mdqa9w1p6cmli6976v4wi0sw9r4p5prkj7lzfd1877wk11c9c73.nar.xz"
+	reference     = "9ipzgm7ls9bzdxd3blbqp2qbns5nq8r9-glibc-2.38-44"
+)
+
+func createNarInfoContent(signatures ...string) string {
+	content := `StorePath: ` + storePath + `
+URL: ` + narURL + `
+Compression: xz
+FileHash: ` + narHash + `
+FileSize: 1234
+NarHash: ` + narHash + `
+NarSize: 5678
+References: ` + storePathHash + "-" + storePathName + " " + reference + `
+Deriver: 5bkb8b1vqr6pd2kjw1v7lr3s8vc3iq0m-hello-2.12.1.drv
+System: x86_64-linux
+`
+	for _, signature := range signatures {
+		content += "Sig: " + signature + "\n"
+	}
+	return content
+}
+
+func TestEncodeBase32(t *testing.T) {
+	assert.Empty(t, EncodeBase32(nil))
+
+	sum := sha256.Sum256(nil)
+	assert.Equal(t, "0mdqa9w1p6cmli6976v4wi0sw9r4p5prkj7lzfd1877wk11c9c73", EncodeBase32(sum[:]))
+}
+
+func TestParseNarInfo(t *testing.T) {
+	t.Run("Valid", func(t *testing.T) {
+		ni, err := ParseNarInfo(strings.NewReader(createNarInfoContent("cache-1:c2ln")))
+		require.NoError(t, err)
+
+		assert.Equal(t, storePath, ni.StorePath)
+		assert.Equal(t, storePathHash, ni.Hash())
+		assert.Equal(t, storePathName, ni.Name())
+		assert.Equal(t, narURL, ni.URL)
+		assert.Equal(t, "0mdqa9w1p6cmli6976v4wi0sw9r4p5prkj7lzfd1877wk11c9c73.nar.xz", ni.NarFilename())
+		assert.Equal(t, "xz", ni.Compression)
+		assert.EqualValues(t, 1234, ni.FileSize)
+		assert.Equal(t, narHash, ni.NarHash)
+		assert.EqualValues(t, 5678, ni.NarSize)
+		assert.Equal(t, []string{storePathHash + "-" + storePathName, reference}, ni.References)
+		assert.Equal(t, "5bkb8b1vqr6pd2kjw1v7lr3s8vc3iq0m-hello-2.12.1.drv", ni.Deriver)
+		assert.Equal(t, "x86_64-linux", ni.System)
+		assert.Equal(t, []string{"cache-1:c2ln"}, ni.Signatures)
+	})
+
+	t.Run("InvalidStorePath", func(t *testing.T) {
+		for _, path := range []string{"", "/nix/store/hello", "/usr/" + storePathHash + "-hello", StoreDir + "/" + storePathHash + "-hello/bin"} {
+			_, err := ParseNarInfo(strings.NewReader(strings.Replace(createNarInfoContent(), storePath, path, 1)))
+			assert.ErrorIs(t, err, ErrInvalidStorePath)
+		}
+	})
+
+	t.Run("InvalidURL", func(t *testing.T) {
+		for _, url := range []string{"", "0mdqa9w1p6.nar", "nar/../secret.nar", "https://example.com/nar/0mdqa9w1p6.nar"} {
+			_, err := ParseNarInfo(strings.NewReader(strings.Replace(createNarInfoContent(), narURL, url, 1)))
+			assert.ErrorIs(t, err, ErrInvalidURL)
+		}
+	})
+
+	t.Run("InvalidReferences", func(t *testing.T) {
+		_, err := ParseNarInfo(strings.NewReader(strings.Replace(createNarInfoContent(), reference, "invalid", 1)))
+		assert.ErrorIs(t, err, ErrInvalidReferences)
+	})
+
+	t.Run("InvalidLine", func(t *testing.T) {
+		_, err := ParseNarInfo(strings.NewReader(createNarInfoContent() + "invalid\n"))
+		assert.ErrorIs(t, err, ErrInvalidNarInfo)
+	})
+}
+
+func TestSignatures(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	key, err := ParsePublicKey("cache-1:" + base64.StdEncoding.EncodeToString(pub))
+	require.NoError(t, err)
+	assert.Equal(t, "cache-1", key.Name)
+	assert.Equal(t, "cache-1:"+base64.StdEncoding.EncodeToString(pub), key.String())
+
+	ni, err := ParseNarInfo(strings.NewReader(createNarInfoContent()))
+	require.NoError(t, err)
+
+	assert.Equal(t, "1;"+storePath+";"+narHash+";5678;"+storePath+","+StoreDir+"/"+reference, ni.Fingerprint())
+
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(priv, []byte(ni.Fingerprint())))
+
+	assert.False(t, ni.IsSignedBy([]*PublicKey{key}))
+
+	ni.Signatures = []string{"other-1:" + signature}
+	assert.False(t, ni.IsSignedBy([]*PublicKey{key}))
+
+	ni.Signatures = []string{"cache-1:invalid", "cache-1:" + signature}
+	assert.True(t, ni.IsSignedBy([]*PublicKey{key}))
+
+	ni.NarSize++
+	assert.False(t, ni.IsSignedBy([]*PublicKey{key}))
+
+	for _, s := range []string{"", "cache-1", ":" + base64.StdEncoding.EncodeToString(pub), "cache-1:c2ln"} {
+		_, err := ParsePublicKey(s)
+		assert.ErrorIs(t, err, ErrInvalidPublicKey)
+	}
+}
+
+func TestMatchesFileHash(t *testing.T) {
+	sum := sha256.Sum256(nil)
+	hashSHA256 := hex.EncodeToString(sum[:])
+
+	ni := &NarInfo{}
+	assert.True(t, ni.MatchesFileHash(hashSHA256))
+
+	ni.FileHash = narHash
+	assert.True(t, ni.MatchesFileHash(hashSHA256))
+
+	ni.FileHash = "sha256:" + hashSHA256
+	assert.True(t, ni.MatchesFileHash(hashSHA256))
+
+	ni.FileHash = "sha512:" + hashSHA256
+	assert.False(t, ni.MatchesFileHash(hashSHA256))
+
+	ni.FileHash = "sha256:1mdqa9w1p6cmli6976v4wi0sw9r4p5prkj7lzfd1877wk11c9c73"
+	assert.False(t, ni.MatchesFileHash(hashSHA256))
+}
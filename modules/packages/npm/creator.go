@@ -84,6 +84,7 @@ type PackageMetadataVersion struct {
 	Bin                  map[string]string   `json:"bin,omitempty"`
 	OptionalDependencies map[string]string   `json:"optionalDependencies,omitempty"`
 	Readme               string              `json:"readme,omitempty"`
+	Deprecated           string              `json:"deprecated,omitempty"`
 	Dist                 PackageDistribution `json:"dist"`
 	Maintainers          []User              `json:"maintainers,omitempty"`
 }
@@ -185,56 +186,15 @@ func ParsePackage(r io.Reader) (*Package, error) {
 	}
 
 	for _, meta := range upload.Versions {
-		if !validateName(meta.Name) {
-			return nil, ErrInvalidPackageName
-		}
-
-		v, err := version.NewSemver(meta.Version)
+		p, err := newPackage(meta)
 		if err != nil {
-			return nil, ErrInvalidPackageVersion
-		}
-
-		scope := ""
-		name := meta.Name
-		nameParts := strings.SplitN(meta.Name, "/", 2)
-		if len(nameParts) == 2 {
-			scope = nameParts[0]
-			name = nameParts[1]
-		}
-
-		if !validation.IsValidURL(meta.Homepage) {
-			meta.Homepage = ""
-		}
-
-		p := &Package{
-			Name:     meta.Name,
-			Version:  v.String(),
-			DistTags: make([]string, 0, 1),
-			Metadata: Metadata{
-				Scope:                   scope,
-				Name:                    name,
-				Description:             meta.Description,
-				Author:                  meta.Author.Name,
-				License:                 meta.License,
-				ProjectURL:              meta.Homepage,
-				Keywords:                meta.Keywords,
-				Dependencies:            meta.Dependencies,
-				BundleDependencies:      meta.BundleDependencies,
-				DevelopmentDependencies: meta.DevDependencies,
-				PeerDependencies:        meta.PeerDependencies,
-				OptionalDependencies:    meta.OptionalDependencies,
-				Bin:                     meta.Bin,
-				Readme:                  meta.Readme,
-				Repository:              meta.Repository,
-			},
+			return nil, err
 		}
 
 		for tag := range upload.DistTags {
 			p.DistTags = append(p.DistTags, tag)
 		}
 
-		p.Filename = strings.ToLower(fmt.Sprintf("%s-%s.tgz", name, p.Version))
-
 		attachment := func() *PackageAttachment {
 			for _, a := range upload.Attachments {
 				return a
@@ -251,25 +211,8 @@ func ParsePackage(r io.Reader) (*Package, error) {
 		}
 		p.Data = data
 
-		integrity := strings.SplitN(meta.Dist.Integrity, "-", 2)
-		if len(integrity) != 2 {
-			return nil, ErrInvalidIntegrity
-		}
-		integrityHash, err := base64.StdEncoding.DecodeString(integrity[1])
-		if err != nil {
-			return nil, ErrInvalidIntegrity
-		}
-		var hash []byte
-		switch integrity[0] {
-		case "sha1":
-			tmp := sha1.Sum(data)
-			hash = tmp[:]
-		case "sha512":
-			tmp := sha512.Sum512(data)
-			hash = tmp[:]
-		}
-		if !bytes.Equal(integrityHash, hash) {
-			return nil, ErrInvalidIntegrity
+		if err := validateIntegrity(meta.Dist.Integrity, data); err != nil {
+			return nil, err
 		}
 
 		return p, nil
@@ -278,6 +221,96 @@ func ParsePackage(r io.Reader) (*Package, error) {
 	return nil, ErrInvalidPackage
 }
 
+// ParseUpstreamPackage creates a npm package from a version of the metadata of an upstream registry
+// and the hashes of its tarball, the data of the package is not set.
+func ParseUpstreamPackage(meta *PackageMetadataVersion, hashSHA1, hashSHA512 []byte) (*Package, error) {
+	p, err := newPackage(meta)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateIntegrityHash(meta.Dist.Integrity, hashSHA1, hashSHA512); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func newPackage(meta *PackageMetadataVersion) (*Package, error) {
+	if !validateName(meta.Name) {
+		return nil, ErrInvalidPackageName
+	}
+
+	v, err := version.NewSemver(meta.Version)
+	if err != nil {
+		return nil, ErrInvalidPackageVersion
+	}
+
+	scope := ""
+	name := meta.Name
+	nameParts := strings.SplitN(meta.Name, "/", 2)
+	if len(nameParts) == 2 {
+		scope = nameParts[0]
+		name = nameParts[1]
+	}
+
+	if !validation.IsValidURL(meta.Homepage) {
+		meta.Homepage = ""
+	}
+
+	return &Package{
+		Name:     meta.Name,
+		Version:  v.String(),
+		DistTags: make([]string, 0, 1),
+		Metadata: Metadata{
+			Scope:                   scope,
+			Name:                    name,
+			Description:             meta.Description,
+			Author:                  meta.Author.Name,
+			License:                 meta.License,
+			ProjectURL:              meta.Homepage,
+			Keywords:                meta.Keywords,
+			Dependencies:            meta.Dependencies,
+			BundleDependencies:      meta.BundleDependencies,
+			DevelopmentDependencies: meta.DevDependencies,
+			PeerDependencies:        meta.PeerDependencies,
+			OptionalDependencies:    meta.OptionalDependencies,
+			Bin:                     meta.Bin,
+			Readme:                  meta.Readme,
+			Repository:              meta.Repository,
+		},
+		Filename: strings.ToLower(fmt.Sprintf("%s-%s.tgz", name, v.String())),
+	}, nil
+}
+
+func validateIntegrity(integrity string, data []byte) error {
+	hashSHA1 := sha1.Sum(data)
+	hashSHA512 := sha512.Sum512(data)
+	return validateIntegrityHash(integrity, hashSHA1[:], hashSHA512[:])
+}
+
+func validateIntegrityHash(integrity string, hashSHA1, hashSHA512 []byte) error {
+	parts := strings.SplitN(integrity, "-", 2)
+	if len(parts) != 2 {
+		return ErrInvalidIntegrity
+	}
+	integrityHash, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return ErrInvalidIntegrity
+	}
+	var hash []byte
+	switch parts[0] {
+	case "sha1":
+		hash = hashSHA1
+	case "sha512":
+		hash = hashSHA512
+	}
+	if !bytes.Equal(integrityHash, hash) {
+		return ErrInvalidIntegrity
+	}
+	return nil
+}
+
 func validateName(name string) bool {
 	if strings.TrimSpace(name) != name {
 		return false
@@ -0,0 +1,169 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package terraform
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+
+	"code.gitea.io/gitea/modules/json"
+	"code.gitea.io/gitea/modules/util"
+
+	"github.com/hashicorp/go-version"
+)
+
+const (
+	PropertyOS   = "terraform.os"
+	PropertyArch = "terraform.arch"
+
+	SettingKeyPrivate = "terraform.key.private"
+	SettingKeyPublic  = "terraform.key.public"
+
+	ManifestSuffix = "_manifest.json"
+	ShasumsSuffix  = "_SHA256SUMS"
+
+	maxReadmeSize = 1 * 1024 * 1024
+)
+
+var (
+	ErrMissingConfigurationFile = util.NewInvalidArgumentErrorf("module archive has no configuration file")
+	ErrInvalidProviderFilename  = util.NewInvalidArgumentErrorf("provider filename is invalid")
+	ErrInvalidManifest          = util.NewInvalidArgumentErrorf("provider manifest is invalid")
+)
+
+// Kind is the kind of a Terraform package
+type Kind string
+
+const (
+	KindModule   Kind = "module"
+	KindProvider Kind = "provider"
+)
+
+// DefaultProtocols are the plugin protocols of a provider without manifest
+var DefaultProtocols = []string{"5.0"}
+
+// Metadata represents the metadata of a Terraform module or provider
+type Metadata struct {
+	Kind      Kind     `json:"kind"`
+	Readme    string   `json:"readme,omitempty"`
+	Protocols []string `json:"protocols,omitempty"`
+}
+
+// ParseModuleArchive parses the metadata of a module archive (.tar.gz).
+// The archive must contain at least one configuration file.
+func ParseModuleArchive(r io.Reader) (*Metadata, error) {
+	gzr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, util.NewInvalidArgumentErrorf("module archive is not a gzip file: %v", err)
+	}
+	defer gzr.Close()
+
+	m := &Metadata{
+		Kind: KindModule,
+	}
+
+	hasConfiguration := false
+
+	tr := tar.NewReader(gzr)
+	for {
+		hd, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, util.NewInvalidArgumentErrorf("module archive is not a tar file: %v", err)
+		}
+
+		if hd.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		name := path.Clean(strings.TrimPrefix(hd.Name, "./"))
+		switch ext := path.Ext(name); {
+		case ext == ".tf" || ext == ".tofu" || strings.HasSuffix(name, ".tf.json") || strings.HasSuffix(name, ".tofu.json"):
+			hasConfiguration = true
+		case strings.EqualFold(name, "README.md"):
+			data, err := io.ReadAll(io.LimitReader(tr, maxReadmeSize))
+			if err != nil {
+				return nil, err
+			}
+			m.Readme = string(data)
+		}
+	}
+
+	if !hasConfiguration {
+		return nil, ErrMissingConfigurationFile
+	}
+
+	return m, nil
+}
+
+// ProviderFile is a file of a provider release
+type ProviderFile struct {
+	Name    string
+	Version string
+	// OS and Arch are empty for the manifest
+	OS   string
+	Arch string
+}
+
+// IsManifest tests if the file is the manifest of the release
+func (f *ProviderFile) IsManifest() bool {
+	return f.OS == ""
+}
+
+var (
+	providerArchivePattern  = regexp.MustCompile(`\Aterraform-provider-([0-9a-z][0-9a-z-]*)_([^_]+)_([0-9a-z]+)_([0-9a-z]+)\.zip\z`)
+	providerManifestPattern = regexp.MustCompile(`\Aterraform-provider-([0-9a-z][0-9a-z-]*)_([^_]+)` + regexp.QuoteMeta(ManifestSuffix) + `\z`)
+)
+
+// ParseProviderFilename parses the filename of a provider release file.
+// The valid names are `terraform-provider-{name}_{version}_{os}_{arch}.zip` and `terraform-provider-{name}_{version}_manifest.json`.
+func ParseProviderFilename(filename string) (*ProviderFile, error) {
+	f := &ProviderFile{}
+	if m := providerArchivePattern.FindStringSubmatch(filename); m != nil {
+		f.Name, f.Version, f.OS, f.Arch = m[1], m[2], m[3], m[4]
+	} else if m := providerManifestPattern.FindStringSubmatch(filename); m != nil {
+		f.Name, f.Version = m[1], m[2]
+	} else {
+		return nil, ErrInvalidProviderFilename
+	}
+
+	if _, err := version.NewSemver(f.Version); err != nil {
+		return nil, ErrInvalidProviderFilename
+	}
+
+	return f, nil
+}
+
+// ParseProviderManifest parses the plugin protocols of a provider manifest
+func ParseProviderManifest(r io.Reader) ([]string, error) {
+	var manifest struct {
+		Version  int `json:"version"`
+		Metadata struct {
+			ProtocolVersions []string `json:"protocol_versions"`
+		} `json:"metadata"`
+	}
+	if err := json.NewDecoder(r).Decode(&manifest); err != nil {
+		return nil, ErrInvalidManifest
+	}
+	if manifest.Version != 1 {
+		return nil, ErrInvalidManifest
+	}
+
+	protocols := manifest.Metadata.ProtocolVersions
+	if len(protocols) == 0 {
+		return DefaultProtocols, nil
+	}
+	for _, p := range protocols {
+		if _, err := version.NewVersion(p); err != nil {
+			return nil, ErrInvalidManifest
+		}
+	}
+	return protocols, nil
+}
@@ -0,0 +1,128 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package terraform
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+
+	"code.gitea.io/gitea/modules/util"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const readme = "# Test Module"
+
+func TestParseModuleArchive(t *testing.T) {
+	createArchive := func(files map[string][]byte) io.Reader {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		tw := tar.NewWriter(zw)
+		for filename, content := range files {
+			hdr := &tar.Header{
+				Name: filename,
+				Mode: 0o600,
+				Size: int64(len(content)),
+			}
+			tw.WriteHeader(hdr)
+			tw.Write(content)
+		}
+		tw.Close()
+		zw.Close()
+		return &buf
+	}
+
+	t.Run("InvalidArchive", func(t *testing.T) {
+		metadata, err := ParseModuleArchive(strings.NewReader("dummy"))
+		assert.Nil(t, metadata)
+		assert.ErrorIs(t, err, util.ErrInvalidArgument)
+	})
+
+	t.Run("MissingConfigurationFile", func(t *testing.T) {
+		data := createArchive(map[string][]byte{"README.md": []byte(readme)})
+
+		metadata, err := ParseModuleArchive(data)
+		assert.Nil(t, metadata)
+		assert.ErrorIs(t, err, ErrMissingConfigurationFile)
+	})
+
+	t.Run("Valid", func(t *testing.T) {
+		data := createArchive(map[string][]byte{
+			"./main.tf":              {},
+			"./README.md":            []byte(readme),
+			"modules/nested/main.tf": {},
+		})
+
+		metadata, err := ParseModuleArchive(data)
+		assert.NoError(t, err)
+		assert.NotNil(t, metadata)
+		assert.Equal(t, KindModule, metadata.Kind)
+		assert.Equal(t, readme, metadata.Readme)
+	})
+}
+
+func TestParseProviderFilename(t *testing.T) {
+	t.Run("Archive", func(t *testing.T) {
+		f, err := ParseProviderFilename("terraform-provider-test-cloud_1.2.3-rc1_linux_amd64.zip")
+		assert.NoError(t, err)
+		assert.Equal(t, "test-cloud", f.Name)
+		assert.Equal(t, "1.2.3-rc1", f.Version)
+		assert.Equal(t, "linux", f.OS)
+		assert.Equal(t, "amd64", f.Arch)
+		assert.False(t, f.IsManifest())
+	})
+
+	t.Run("Manifest", func(t *testing.T) {
+		f, err := ParseProviderFilename("terraform-provider-test_1.2.3_manifest.json")
+		assert.NoError(t, err)
+		assert.Equal(t, "test", f.Name)
+		assert.Equal(t, "1.2.3", f.Version)
+		assert.True(t, f.IsManifest())
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, filename := range []string{
+			"",
+			"test_1.2.3_linux_amd64.zip",
+			"terraform-provider-test_1.2.3_linux_amd64.tar.gz",
+			"terraform-provider-Test_1.2.3_linux_amd64.zip",
+			"terraform-provider-test_invalid_linux_amd64.zip",
+			"terraform-provider-test_1.2.3_SHA256SUMS",
+		} {
+			f, err := ParseProviderFilename(filename)
+			assert.Nil(t, f, filename)
+			assert.ErrorIs(t, err, ErrInvalidProviderFilename, filename)
+		}
+	})
+}
+
+func TestParseProviderManifest(t *testing.T) {
+	t.Run("Invalid", func(t *testing.T) {
+		for _, content := range []string{
+			"",
+			`{"version":2}`,
+			`{"version":1,"metadata":{"protocol_versions":["invalid"]}}`,
+		} {
+			protocols, err := ParseProviderManifest(strings.NewReader(content))
+			assert.Nil(t, protocols, content)
+			assert.ErrorIs(t, err, ErrInvalidManifest, content)
+		}
+	})
+
+	t.Run("Default", func(t *testing.T) {
+		protocols, err := ParseProviderManifest(strings.NewReader(`{"version":1}`))
+		assert.NoError(t, err)
+		assert.Equal(t, DefaultProtocols, protocols)
+	})
+
+	t.Run("Valid", func(t *testing.T) {
+		protocols, err := ParseProviderManifest(strings.NewReader(`{"version":1,"metadata":{"protocol_versions":["6.0"]}}`))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"6.0"}, protocols)
+	})
+}
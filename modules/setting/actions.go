@@ -15,6 +15,10 @@ var (
 		LogStorage            *Storage // how the created logs should be stored
 		ArtifactStorage       *Storage // how the created artifacts should be stored
 		ArtifactRetentionDays int64    `ini:"ARTIFACT_RETENTION_DAYS"`
+		CacheStorage          *Storage // how the caches of the jobs should be stored
+		CacheEnabled          bool     `ini:"CACHE_ENABLED"`
+		CacheMaxSize          int64    `ini:"-"`
+		CacheRetentionDays    int64    `ini:"CACHE_RETENTION_DAYS"`
 		Enabled               bool
 		DefaultActionsURL     defaultActionsURL `ini:"DEFAULT_ACTIONS_URL"`
 		ZombieTaskTimeout     time.Duration     `ini:"ZOMBIE_TASK_TIMEOUT"`
@@ -24,6 +28,7 @@ var (
 		LimitDispatchInputs   int64             `ini:"LIMIT_DISPATCH_INPUTS"`
 	}{
 		Enabled:             true,
+		CacheEnabled:        true,
 		DefaultActionsURL:   defaultActionsURLForgejo,
 		SkipWorkflowStrings: []string{"[skip ci]", "[ci skip]", "[no ci]", "[skip actions]", "[actions skip]"},
 		LimitDispatchInputs: 10,
@@ -71,6 +76,23 @@ func loadActionsFrom(rootCfg ConfigProvider) error {
 		Actions.ArtifactRetentionDays = 90
 	}
 
+	cacheSec, _ := rootCfg.GetSection("actions.cache")
+
+	Actions.CacheStorage, err = getStorage(rootCfg, "actions_cache", "", cacheSec)
+	if err != nil {
+		return err
+	}
+
+	// the caches which haven't been used for 7 days are evicted in Github Actions
+	if Actions.CacheRetentionDays <= 0 {
+		Actions.CacheRetentionDays = 7
+	}
+	// the caches of a repository are limited to 10 GB in Github Actions
+	Actions.CacheMaxSize = mustBytes(sec, "CACHE_MAX_SIZE")
+	if Actions.CacheMaxSize < 0 {
+		Actions.CacheMaxSize = 10 * 1024 * 1024 * 1024
+	}
+
 	Actions.ZombieTaskTimeout = sec.Key("ZOMBIE_TASK_TIMEOUT").MustDuration(10 * time.Minute)
 	Actions.EndlessTaskTimeout = sec.Key("ENDLESS_TASK_TIMEOUT").MustDuration(3 * time.Hour)
 	Actions.AbandonedJobTimeout = sec.Key("ABANDONED_JOB_TIMEOUT").MustDuration(24 * time.Hour)
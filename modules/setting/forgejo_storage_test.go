@@ -40,6 +40,8 @@ var testSectionToBasePath = map[string]string{
 	"packages":            "packages",
 	"storage.actions_log": "actions_log",
 	"actions.artifacts":   "actions_artifacts",
+	"actions.cache":       "actions_cache",
+	"terraform_state":     "terraform_state",
 }
 
 type testSectionToPathFun func(StorageType, string) string
@@ -170,6 +172,8 @@ func testStorageTypesSpecificStorages(t *testing.T, iniStr string, defaultStorag
 		"packages":            &Packages.Storage,
 		"storage.actions_log": &Actions.LogStorage,
 		"actions.artifacts":   &Actions.ArtifactStorage,
+		"actions.cache":       &Actions.CacheStorage,
+		"terraform_state":     &TerraformState.Storage,
 	}
 
 	for sectionName, storage := range testSectionsMap {
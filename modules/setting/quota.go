@@ -0,0 +1,17 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package setting
+
+// Quota represents the configuration of the storage quotas of the owners
+var Quota = struct {
+	Enabled       bool
+	DefaultGroups []string
+}{
+	Enabled:       false,
+	DefaultGroups: []string{},
+}
+
+func loadQuotaFrom(rootCfg ConfigProvider) {
+	mustMapSetting(rootCfg, "quota", &Quota)
+}
@@ -145,6 +145,10 @@ func loadCommonSettingsFrom(cfg ConfigProvider) error {
 	if err := loadActionsFrom(cfg); err != nil {
 		return err
 	}
+	if err := loadTerraformStateFrom(cfg); err != nil {
+		return err
+	}
+	loadQuotaFrom(cfg)
 	loadUIFrom(cfg)
 	loadAdminFrom(cfg)
 	loadAPIFrom(cfg)
@@ -0,0 +1,39 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package setting
+
+import (
+	"fmt"
+)
+
+// TerraformState represents the configuration of the Terraform state backend of the repositories
+var TerraformState = struct {
+	Enabled     bool
+	MaxVersions int   `ini:"MAX_VERSIONS"`
+	MaxSize     int64 `ini:"-"`
+
+	Storage *Storage
+}{
+	Enabled:     true,
+	MaxVersions: 100,
+	MaxSize:     64 * 1024 * 1024,
+}
+
+func loadTerraformStateFrom(rootCfg ConfigProvider) (err error) {
+	sec, _ := rootCfg.GetSection("terraform_state")
+	if sec == nil {
+		TerraformState.Storage, err = getStorage(rootCfg, "terraform_state", "", nil)
+		return err
+	}
+
+	if err = sec.MapTo(&TerraformState); err != nil {
+		return fmt.Errorf("failed to map Terraform state settings: %v", err)
+	}
+	if sec.HasKey("MAX_SIZE") {
+		TerraformState.MaxSize = mustBytes(sec, "MAX_SIZE")
+	}
+
+	TerraformState.Storage, err = getStorage(rootCfg, "terraform_state", "", sec)
+	return err
+}
@@ -5,6 +5,7 @@ package setting
 
 import (
 	"net/url"
+	"time"
 
 	"code.gitea.io/gitea/modules/log"
 )
@@ -19,6 +20,11 @@ var Webhook = struct {
 	ProxyURL        string
 	ProxyURLFixed   *url.URL
 	ProxyHosts      []string
+
+	MaxRetries       int
+	RetryBackoff     time.Duration
+	RetryMaxBackoff  time.Duration
+	AutoDisableAfter time.Duration
 }{
 	QueueLength:    1000,
 	DeliverTimeout: 5,
@@ -26,6 +32,11 @@ var Webhook = struct {
 	PagingNum:      10,
 	ProxyURL:       "",
 	ProxyHosts:     []string{},
+
+	MaxRetries:       5,
+	RetryBackoff:     30 * time.Second,
+	RetryMaxBackoff:  time.Hour,
+	AutoDisableAfter: 7 * 24 * time.Hour,
 }
 
 func loadWebhookFrom(rootCfg ConfigProvider) {
@@ -45,4 +56,8 @@ func loadWebhookFrom(rootCfg ConfigProvider) {
 		}
 	}
 	Webhook.ProxyHosts = sec.Key("PROXY_HOSTS").Strings(",")
+	Webhook.MaxRetries = sec.Key("MAX_RETRIES").MustInt(5)
+	Webhook.RetryBackoff = sec.Key("RETRY_BACKOFF").MustDuration(30 * time.Second)
+	Webhook.RetryMaxBackoff = sec.Key("RETRY_MAX_BACKOFF").MustDuration(time.Hour)
+	Webhook.AutoDisableAfter = sec.Key("AUTO_DISABLE_AFTER").MustDuration(7 * 24 * time.Hour)
 }
@@ -129,6 +129,11 @@ var (
 	Actions ObjectStorage = UninitializedStorage
 	// Actions Artifacts represents actions artifacts storage
 	ActionsArtifacts ObjectStorage = UninitializedStorage
+	// ActionsCache represents the storage of the caches of the actions jobs
+	ActionsCache ObjectStorage = UninitializedStorage
+
+	// TerraformState represents the storage of the Terraform states of the repositories
+	TerraformState ObjectStorage = UninitializedStorage
 )
 
 // Init init the stoarge
@@ -141,6 +146,7 @@ func Init() error {
 		initRepoArchives,
 		initPackages,
 		initActions,
+		initTerraformState,
 	} {
 		if err := f(); err != nil {
 			return err
@@ -214,6 +220,7 @@ func initActions() (err error) {
 	if !setting.Actions.Enabled {
 		Actions = DiscardStorage("Actions isn't enabled")
 		ActionsArtifacts = DiscardStorage("ActionsArtifacts isn't enabled")
+		ActionsCache = DiscardStorage("ActionsCache isn't enabled")
 		return nil
 	}
 	log.Info("Initialising Actions storage with type: %s", setting.Actions.LogStorage.Type)
@@ -221,6 +228,24 @@ func initActions() (err error) {
 		return err
 	}
 	log.Info("Initialising ActionsArtifacts storage with type: %s", setting.Actions.ArtifactStorage.Type)
-	ActionsArtifacts, err = NewStorage(setting.Actions.ArtifactStorage.Type, setting.Actions.ArtifactStorage)
+	if ActionsArtifacts, err = NewStorage(setting.Actions.ArtifactStorage.Type, setting.Actions.ArtifactStorage); err != nil {
+		return err
+	}
+	if !setting.Actions.CacheEnabled {
+		ActionsCache = DiscardStorage("ActionsCache isn't enabled")
+		return nil
+	}
+	log.Info("Initialising ActionsCache storage with type: %s", setting.Actions.CacheStorage.Type)
+	ActionsCache, err = NewStorage(setting.Actions.CacheStorage.Type, setting.Actions.CacheStorage)
+	return err
+}
+
+func initTerraformState() (err error) {
+	if !setting.TerraformState.Enabled {
+		TerraformState = DiscardStorage("Terraform state isn't enabled")
+		return nil
+	}
+	log.Info("Initialising Terraform state storage with type: %s", setting.TerraformState.Storage.Type)
+	TerraformState, err = NewStorage(setting.TerraformState.Storage.Type, setting.TerraformState.Storage)
 	return err
 }
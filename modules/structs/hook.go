@@ -45,7 +45,7 @@ type CreateHookOptionConfig map[string]string
 // CreateHookOption options when create a hook
 type CreateHookOption struct {
 	// required: true
-	// enum: forgejo,dingtalk,discord,gitea,gogs,msteams,slack,telegram,feishu,wechatwork,packagist
+	// enum: forgejo,dingtalk,discord,gitea,gogs,msteams,slack,telegram,feishu,wechatwork,packagist,ntfy,gotify,zulip,amqp,nats,kafka,custom
 	Type string `json:"type" binding:"Required"`
 	// required: true
 	Config              CreateHookOptionConfig `json:"config" binding:"Required"`
@@ -424,6 +424,29 @@ type WorkflowDispatchPayload struct {
 	Workflow   string            `json:"workflow"`
 }
 
+// HookWorkflowRunAction represents the action of a workflow run event
+type HookWorkflowRunAction string
+
+const (
+	// HookWorkflowRunRequested requested
+	HookWorkflowRunRequested HookWorkflowRunAction = "requested"
+	// HookWorkflowRunCompleted completed
+	HookWorkflowRunCompleted HookWorkflowRunAction = "completed"
+)
+
+// WorkflowRunPayload represents a payload information of workflow run event
+type WorkflowRunPayload struct {
+	Action      HookWorkflowRunAction `json:"action"`
+	WorkflowRun *ActionWorkflowRun    `json:"workflow_run"`
+	Repository  *Repository           `json:"repository"`
+	Sender      *User                 `json:"sender"`
+}
+
+// JSONPayload implements Payload
+func (p *WorkflowRunPayload) JSONPayload() ([]byte, error) {
+	return json.MarshalIndent(p, "", "  ")
+}
+
 // ReviewPayload FIXME
 type ReviewPayload struct {
 	Type    string `json:"type"`
@@ -19,6 +19,10 @@ type Package struct {
 	HTMLURL    string      `json:"html_url"`
 	// swagger:strfmt date-time
 	CreatedAt time.Time `json:"created_at"`
+	// the deprecation message, empty if the version is not deprecated
+	DeprecationMessage string `json:"deprecation_message"`
+	// locked versions can't be deleted and are kept by cleanup rules
+	RetentionLocked bool `json:"retention_locked"`
 }
 
 // PackageFile represents a package file
@@ -31,3 +35,33 @@ type PackageFile struct {
 	HashSHA256 string `json:"sha256"`
 	HashSHA512 string `json:"sha512"`
 }
+
+// DeprecatePackageOption options when deprecating a package version
+type DeprecatePackageOption struct {
+	// required: true
+	Message string `json:"message" binding:"Required"`
+}
+
+// PackageDownloadStats represents the download statistics of a package version
+type PackageDownloadStats struct {
+	// the downloads since the package version was created
+	TotalDownloads int64 `json:"total_downloads"`
+	// the downloads per day, oldest day first
+	Days []*PackageDownloadsPerDay `json:"days"`
+	// the users with the most downloads, only visible to users with write access to the package
+	Users []*PackageDownloadsPerUser `json:"users,omitempty"`
+}
+
+// PackageDownloadsPerDay represents the downloads of a package version on a day
+type PackageDownloadsPerDay struct {
+	// the day in UTC, formatted as YYYY-MM-DD
+	Date      string `json:"date"`
+	Downloads int64  `json:"downloads"`
+}
+
+// PackageDownloadsPerUser represents the downloads of a package version by a user
+type PackageDownloadsPerUser struct {
+	// the user, null for anonymous downloads
+	User      *User `json:"user"`
+	Downloads int64 `json:"downloads"`
+}
@@ -0,0 +1,69 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package structs
+
+// QuotaRuleInfo represents a quota rule which limits the storage of its subjects
+// swagger:model
+type QuotaRuleInfo struct {
+	Name string `json:"name"`
+	// the storage limit in bytes, -1 means unlimited storage
+	Limit int64 `json:"limit"`
+	// the subjects whose storage is limited, e.g. size:all, size:repos:lfs or size:assets:packages
+	Subjects []string `json:"subjects"`
+}
+
+// CreateQuotaRuleOption options when creating a quota rule
+// swagger:model
+type CreateQuotaRuleOption struct {
+	// required: true
+	Name string `json:"name" binding:"Required;MaxSize(255)"`
+	// the storage limit in bytes, -1 means unlimited storage
+	// required: true
+	Limit int64 `json:"limit"`
+	// the subjects whose storage is limited
+	// required: true
+	Subjects []string `json:"subjects"`
+}
+
+// EditQuotaRuleOption options when editing a quota rule, the omitted ones are unchanged
+// swagger:model
+type EditQuotaRuleOption struct {
+	// the storage limit in bytes, -1 means unlimited storage
+	Limit *int64 `json:"limit"`
+	// the subjects whose storage is limited
+	Subjects *[]string `json:"subjects"`
+}
+
+// QuotaGroup represents a set of quota rules which is assigned to users and organizations
+// swagger:model
+type QuotaGroup struct {
+	Name  string          `json:"name"`
+	Rules []QuotaRuleInfo `json:"rules"`
+}
+
+// CreateQuotaGroupOption options when creating a quota group
+// swagger:model
+type CreateQuotaGroupOption struct {
+	// required: true
+	Name string `json:"name" binding:"Required;MaxSize(255)"`
+	// the names of the rules of the group
+	Rules []string `json:"rules"`
+}
+
+// QuotaUsed represents the storage used by a user or an organization in bytes
+// swagger:model
+type QuotaUsed struct {
+	ReposGit          int64 `json:"repos_git"`
+	ReposLFS          int64 `json:"repos_lfs"`
+	AssetsAttachments int64 `json:"assets_attachments"`
+	AssetsArtifacts   int64 `json:"assets_artifacts"`
+	AssetsPackages    int64 `json:"assets_packages"`
+}
+
+// QuotaInfo represents the storage used by a user or an organization and the quota groups which apply to it
+// swagger:model
+type QuotaInfo struct {
+	Used   QuotaUsed    `json:"used"`
+	Groups []QuotaGroup `json:"groups"`
+}
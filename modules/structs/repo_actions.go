@@ -19,6 +19,8 @@ type ActionTask struct {
 	Status       string `json:"status"`
 	WorkflowID   string `json:"workflow_id"`
 	URL          string `json:"url"`
+	// the concurrency group of the job, or of its run if the job doesn't define one
+	ConcurrencyGroup string `json:"concurrency_group,omitempty"`
 	// swagger:strfmt date-time
 	CreatedAt time.Time `json:"created_at"`
 	// swagger:strfmt date-time
@@ -32,3 +34,36 @@ type ActionTaskResponse struct {
 	Entries    []*ActionTask `json:"workflow_runs"`
 	TotalCount int64         `json:"total_count"`
 }
+
+// ActionWorkflowRun represents a run of a workflow
+type ActionWorkflowRun struct {
+	ID int64 `json:"id"`
+	// the name of the workflow, or the name of its file if it doesn't define one
+	Name         string `json:"name"`
+	DisplayTitle string `json:"display_title"`
+	HeadBranch   string `json:"head_branch"`
+	HeadSHA      string `json:"head_sha"`
+	RunNumber    int64  `json:"run_number"`
+	Event        string `json:"event"`
+	// queued, in_progress or completed
+	Status string `json:"status"`
+	// the status of a completed run: success, failure, cancelled or skipped
+	Conclusion string `json:"conclusion,omitempty"`
+	// the name of the file of the workflow
+	WorkflowID string `json:"workflow_id"`
+	HTMLURL    string `json:"html_url"`
+	// swagger:strfmt date-time
+	CreatedAt time.Time `json:"created_at"`
+	// swagger:strfmt date-time
+	UpdatedAt time.Time `json:"updated_at"`
+	// swagger:strfmt date-time
+	RunStartedAt time.Time `json:"run_started_at"`
+	// the run is from an untrusted contributor and waits for the approval of a maintainer
+	NeedApproval bool `json:"need_approval"`
+}
+
+// ActionWorkflowRunsResponse returns ActionWorkflowRuns
+type ActionWorkflowRunsResponse struct {
+	Entries    []*ActionWorkflowRun `json:"workflow_runs"`
+	TotalCount int64                `json:"total_count"`
+}
@@ -0,0 +1,50 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package structs
+
+import "time"
+
+// ActionRunnerGroup represents a group of runners with its access policies
+// swagger:model
+type ActionRunnerGroup struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// the users and organizations whose repositories can use the runners, all the owners are allowed if empty
+	AllowedOwners []string `json:"allowed_owners"`
+	// the full names of the repositories which can use the runners, all the repositories are allowed if empty
+	AllowedRepos []string `json:"allowed_repos"`
+	// the glob patterns of the workflow files which can use the runners, all the workflows are allowed if empty
+	AllowedWorkflows []string `json:"allowed_workflows"`
+	// the IDs of the runners of the group
+	RunnerIDs []int64 `json:"runner_ids"`
+	// swagger:strfmt date-time
+	Created time.Time `json:"created_at"`
+}
+
+// CreateActionRunnerGroupOption options when creating a runner group
+// swagger:model
+type CreateActionRunnerGroupOption struct {
+	// required: true
+	Name        string `json:"name" binding:"Required;MaxSize(255)"`
+	Description string `json:"description"`
+	// the users and organizations whose repositories can use the runners, only for the groups of the instance runners
+	AllowedOwners []string `json:"allowed_owners"`
+	// the names of the repositories which can use the runners
+	AllowedRepos []string `json:"allowed_repos"`
+	// the glob patterns of the workflow files which can use the runners
+	AllowedWorkflows []string `json:"allowed_workflows"`
+}
+
+// EditActionRunnerGroupOption options when editing the access policies of a runner group, the omitted ones are unchanged
+// swagger:model
+type EditActionRunnerGroupOption struct {
+	Description *string `json:"description"`
+	// the users and organizations whose repositories can use the runners, only for the groups of the instance runners
+	AllowedOwners *[]string `json:"allowed_owners"`
+	// the names of the repositories which can use the runners
+	AllowedRepos *[]string `json:"allowed_repos"`
+	// the glob patterns of the workflow files which can use the runners
+	AllowedWorkflows *[]string `json:"allowed_workflows"`
+}
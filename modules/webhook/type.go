@@ -33,6 +33,7 @@ const (
 	HookEventPackage                   HookEventType = "package"
 	HookEventSchedule                  HookEventType = "schedule"
 	HookEventWorkflowDispatch          HookEventType = "workflow_dispatch"
+	HookEventWorkflowRun               HookEventType = "workflow_run"
 )
 
 // Event returns the HookEventType as an event string
@@ -87,6 +88,13 @@ const (
 	WECHATWORK       HookType = "wechatwork"
 	PACKAGIST        HookType = "packagist"
 	SOURCEHUT_BUILDS HookType = "sourcehut_builds" //nolint:revive
+	AMQP             HookType = "amqp"
+	NATS             HookType = "nats"
+	KAFKA            HookType = "kafka"
+	CUSTOM           HookType = "custom"
+	NTFY             HookType = "ntfy"
+	GOTIFY           HookType = "gotify"
+	ZULIP            HookType = "zulip"
 )
 
 // HookStatus is the status of a web hook
@@ -20,5 +20,8 @@ func Routes(prefix string) *web.Route {
 	path, handler = runner.NewRunnerServiceHandler()
 	m.Post(path+"*", http.StripPrefix(prefix, handler).ServeHTTP)
 
+	m.Get("/.well-known/openid-configuration", IDTokenWellKnown)
+	m.Get("/idtoken", ArtifactContexter(), IDToken)
+
 	return m
 }
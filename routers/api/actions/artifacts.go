@@ -239,6 +239,9 @@ func (ar artifactRoutes) uploadArtifact(ctx *ArtifactContext) {
 	if !ok {
 		return
 	}
+	if !validateArtifactQuota(ctx, task) {
+		return
+	}
 
 	// get upload file size
 	fileRealTotalSize, contentLength := getUploadFileSize(ctx)
@@ -11,6 +11,7 @@ import (
 	"strings"
 
 	"code.gitea.io/gitea/models/actions"
+	quota_model "code.gitea.io/gitea/models/quota"
 	"code.gitea.io/gitea/modules/log"
 	"code.gitea.io/gitea/modules/util"
 )
@@ -54,6 +55,20 @@ func validateRunIDV4(ctx *ArtifactContext, rawRunID string) (*actions.ActionTask
 	return task, runID, true
 }
 
+func validateArtifactQuota(ctx *ArtifactContext, task *actions.ActionTask) bool {
+	if err := quota_model.CheckForUser(ctx, task.OwnerID, quota_model.LimitSubjectSizeAssetsArtifacts); err != nil {
+		if quota_model.IsErrQuotaExceeded(err) {
+			log.Warn("Artifact storage quota of owner %d exceeded", task.OwnerID)
+			ctx.Error(http.StatusRequestEntityTooLarge, "Storage quota exceeded")
+		} else {
+			log.Error("Error checking artifact storage quota: %v", err)
+			ctx.Error(http.StatusInternalServerError, "Error checking artifact storage quota")
+		}
+		return false
+	}
+	return true
+}
+
 func validateArtifactHash(ctx *ArtifactContext, artifactName string) bool {
 	paramHash := ctx.Params("artifact_hash")
 	// use artifact name to create upload url
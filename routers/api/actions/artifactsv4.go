@@ -252,10 +252,13 @@ func (r *artifactV4Routes) createArtifact(ctx *ArtifactContext) {
 	if ok := r.parseProtbufBody(ctx, &req); !ok {
 		return
 	}
-	_, _, ok := validateRunIDV4(ctx, req.WorkflowRunBackendId)
+	task, _, ok := validateRunIDV4(ctx, req.WorkflowRunBackendId)
 	if !ok {
 		return
 	}
+	if !validateArtifactQuota(ctx, task) {
+		return
+	}
 
 	artifactName := req.Name
 
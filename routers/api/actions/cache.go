@@ -0,0 +1,286 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package actions
+
+// GitHub Actions Cache API Simple Description
+//
+// It is the API used by `actions/cache`, which reads the URL of the server from ACTIONS_CACHE_URL.
+// The runners use this server when it is set as their external cache server, e.g.
+// `cache.external_server: https://forgejo.example.com/api/actions_cache/` in the configuration of act_runner.
+//
+// 1. Restore a cache
+// 1.1. Look up an entry
+// GET: /api/actions_cache/_apis/artifactcache/cache?keys=key,restore-key-prefix&version=hash
+// Response: 204 if no entry matches, otherwise
+// {
+//   "result": "hit",
+//   "archiveLocation": "/api/actions_cache/_apis/artifactcache/artifacts/{cache_id}?expires=...&sig=...",
+//   "cacheKey": "key"
+// }
+// 1.2. Download the content of the entry from the signed archiveLocation
+//
+// 2. Save a cache
+// 2.1. Reserve an entry
+// POST: /api/actions_cache/_apis/artifactcache/caches
+// Request:
+// {
+//   "key": "key",
+//   "version": "hash",
+//   "cacheSize": 1024
+// }
+// Response:
+// {
+//   "cacheId": 1
+// }
+// 2.2. Upload the content by chunks
+// PATCH: /api/actions_cache/_apis/artifactcache/caches/{cache_id}
+// with the header content-range: bytes 0-1023/*
+// 2.3. Commit the entry
+// POST: /api/actions_cache/_apis/artifactcache/caches/{cache_id}
+// Request:
+// {
+//   "size": 1024
+// }
+//
+// The entries are scoped to the repository of the job, and the least recently used ones are evicted
+// when the caches of the repository exceed [actions].CACHE_MAX_SIZE.
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	actions_model "code.gitea.io/gitea/models/actions"
+	"code.gitea.io/gitea/modules/json"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/storage"
+	"code.gitea.io/gitea/modules/util"
+	"code.gitea.io/gitea/modules/web"
+	"code.gitea.io/gitea/routers/common"
+	actions_service "code.gitea.io/gitea/services/actions"
+)
+
+const cacheRouteBase = "/_apis/artifactcache"
+
+type cacheRoutes struct {
+	prefix string
+}
+
+func CacheRoutes(prefix string) *web.Route {
+	m := web.NewRoute()
+
+	r := cacheRoutes{prefix: prefix}
+
+	m.Group(cacheRouteBase, func() {
+		m.Group("", func() {
+			m.Get("/cache", r.lookupCache)
+			m.Post("/caches", r.reserveCache)
+			m.Patch("/caches/{cache_id}", r.uploadCache)
+			m.Post("/caches/{cache_id}", r.commitCache)
+		}, ArtifactContexter())
+		// the content is downloaded without the token of the job, the link is signed instead
+		m.Get("/artifacts/{cache_id}", ArtifactV4Contexter(), r.downloadCache)
+	})
+
+	return m
+}
+
+func (r cacheRoutes) buildSignature(cacheID int64, expires string) []byte {
+	mac := hmac.New(sha256.New, setting.GetGeneralTokenSigningSecret())
+	mac.Write([]byte("cache"))
+	mac.Write([]byte(strconv.FormatInt(cacheID, 10)))
+	mac.Write([]byte(expires))
+	return mac.Sum(nil)
+}
+
+func (r cacheRoutes) buildDownloadURL(cacheID int64) string {
+	expires := strconv.FormatInt(time.Now().Add(60*time.Minute).Unix(), 10)
+	return fmt.Sprintf("%s%s%s/artifacts/%d?expires=%s&sig=%s",
+		strings.TrimSuffix(setting.AppURL, "/"), strings.TrimSuffix(r.prefix, "/"), cacheRouteBase,
+		cacheID, expires, base64.URLEncoding.EncodeToString(r.buildSignature(cacheID, expires)))
+}
+
+// getCache returns the entry of the URL, which must belong to the repository of the job.
+func (r cacheRoutes) getCache(ctx *ArtifactContext) (*actions_model.ActionCache, bool) {
+	c, err := actions_model.GetCacheByID(ctx, ctx.ParamsInt64("cache_id"))
+	if err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			ctx.Error(http.StatusNotFound, "Error cache not found")
+			return nil, false
+		}
+		log.Error("Error getting cache: %v", err)
+		ctx.Error(http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+	if c.RepoID != ctx.ActionTask.RepoID {
+		ctx.Error(http.StatusNotFound, "Error cache not found")
+		return nil, false
+	}
+	return c, true
+}
+
+type lookupCacheResponse struct {
+	Result          string `json:"result"`
+	ArchiveLocation string `json:"archiveLocation"`
+	CacheKey        string `json:"cacheKey"`
+}
+
+func (r cacheRoutes) lookupCache(ctx *ArtifactContext) {
+	var keys []string
+	for _, key := range strings.Split(ctx.Req.URL.Query().Get("keys"), ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	version := ctx.Req.URL.Query().Get("version")
+	if len(keys) == 0 || version == "" {
+		ctx.Error(http.StatusBadRequest, "Error keys and version are required")
+		return
+	}
+
+	c, err := actions_service.LookupCache(ctx, ctx.ActionTask, keys, version)
+	if err != nil {
+		log.Error("Error looking up cache: %v", err)
+		ctx.Error(http.StatusInternalServerError, err.Error())
+		return
+	}
+	if c == nil {
+		ctx.Status(http.StatusNoContent)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, lookupCacheResponse{
+		Result:          "hit",
+		ArchiveLocation: r.buildDownloadURL(c.ID),
+		CacheKey:        c.CacheKey,
+	})
+}
+
+type reserveCacheRequest struct {
+	Key       string `json:"key"`
+	Version   string `json:"version"`
+	CacheSize int64  `json:"cacheSize"`
+}
+
+type reserveCacheResponse struct {
+	CacheID int64 `json:"cacheId"`
+}
+
+func (r cacheRoutes) reserveCache(ctx *ArtifactContext) {
+	var req reserveCacheRequest
+	if err := json.NewDecoder(ctx.Req.Body).Decode(&req); err != nil {
+		log.Error("Error decode request body: %v", err)
+		ctx.Error(http.StatusBadRequest, "Error decode request body")
+		return
+	}
+
+	c, err := actions_service.ReserveCache(ctx, ctx.ActionTask, req.Key, req.Version, req.CacheSize)
+	if err != nil {
+		switch {
+		case errors.Is(err, util.ErrAlreadyExist):
+			ctx.Error(http.StatusConflict, "Error cache already exists")
+		case errors.Is(err, util.ErrInvalidArgument):
+			ctx.Error(http.StatusBadRequest, err.Error())
+		default:
+			log.Error("Error reserving cache: %v", err)
+			ctx.Error(http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, reserveCacheResponse{CacheID: c.ID})
+}
+
+func (r cacheRoutes) uploadCache(ctx *ArtifactContext) {
+	c, ok := r.getCache(ctx)
+	if !ok {
+		return
+	}
+
+	// parse content-range header, format: bytes 0-1023/*
+	var start, end int64
+	contentRange := ctx.Req.Header.Get("Content-Range")
+	if _, err := fmt.Sscanf(contentRange, "bytes %d-%d/", &start, &end); err != nil {
+		log.Warn("parse content range error: %v, content-range: %s", err, contentRange)
+		ctx.Error(http.StatusBadRequest, "Error parse content range")
+		return
+	}
+
+	if err := actions_service.SaveCacheChunk(c, start, end, ctx.Req.Body); err != nil {
+		if errors.Is(err, util.ErrInvalidArgument) {
+			ctx.Error(http.StatusBadRequest, err.Error())
+			return
+		}
+		log.Error("Error uploading cache chunk: %v", err)
+		ctx.Error(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+type commitCacheRequest struct {
+	Size int64 `json:"size"`
+}
+
+func (r cacheRoutes) commitCache(ctx *ArtifactContext) {
+	c, ok := r.getCache(ctx)
+	if !ok {
+		return
+	}
+	var req commitCacheRequest
+	if err := json.NewDecoder(ctx.Req.Body).Decode(&req); err != nil {
+		log.Error("Error decode request body: %v", err)
+		ctx.Error(http.StatusBadRequest, "Error decode request body")
+		return
+	}
+
+	if err := actions_service.CommitCache(ctx, c, req.Size); err != nil {
+		if errors.Is(err, util.ErrInvalidArgument) {
+			ctx.Error(http.StatusBadRequest, err.Error())
+			return
+		}
+		log.Error("Error committing cache: %v", err)
+		ctx.Error(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (r cacheRoutes) downloadCache(ctx *ArtifactContext) {
+	cacheID := ctx.ParamsInt64("cache_id")
+	expires := ctx.Req.URL.Query().Get("expires")
+	sig, _ := base64.URLEncoding.DecodeString(ctx.Req.URL.Query().Get("sig"))
+	if !hmac.Equal(sig, r.buildSignature(cacheID, expires)) {
+		ctx.Error(http.StatusUnauthorized, "Error unauthorized")
+		return
+	}
+	if unix, err := strconv.ParseInt(expires, 10, 64); err != nil || time.Unix(unix, 0).Before(time.Now()) {
+		ctx.Error(http.StatusUnauthorized, "Error link expired")
+		return
+	}
+
+	c, err := actions_model.GetCacheByID(ctx, cacheID)
+	if err != nil || !c.Complete {
+		ctx.Error(http.StatusNotFound, "Error cache not found")
+		return
+	}
+	f, err := storage.ActionsCache.Open(c.StoragePath())
+	if err != nil {
+		log.Error("Error opening cache %d: %v", c.ID, err)
+		ctx.Error(http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer f.Close()
+
+	common.ServeContentByReadSeeker(ctx.Base, fmt.Sprintf("cache-%d.tzst", c.ID), util.ToPointer(c.Created.AsTime()), f)
+}
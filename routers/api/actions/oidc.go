@@ -0,0 +1,75 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package actions
+
+import (
+	"errors"
+	"net/http"
+
+	"code.gitea.io/gitea/modules/json"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/setting"
+	actions_service "code.gitea.io/gitea/services/actions"
+	"code.gitea.io/gitea/services/auth/source/oauth2"
+)
+
+// The jobs request OpenID Connect ID tokens to prove their identity to third-party services.
+// The tokens are issued at /api/actions/idtoken to the jobs authenticated with ACTIONS_ID_TOKEN_REQUEST_TOKEN,
+// the issuer is described by /api/actions/.well-known/openid-configuration.
+
+type openIDConfiguration struct {
+	Issuer                           string   `json:"issuer"`
+	JWKSURI                          string   `json:"jwks_uri"`
+	SubjectTypesSupported            []string `json:"subject_types_supported"`
+	ResponseTypesSupported           []string `json:"response_types_supported"`
+	ClaimsSupported                  []string `json:"claims_supported"`
+	IDTokenSigningAlgValuesSupported []string `json:"id_token_signing_alg_values_supported"`
+	ScopesSupported                  []string `json:"scopes_supported"`
+}
+
+// IDTokenWellKnown serves the OpenID configuration of the issuer of the ID tokens of the jobs
+func IDTokenWellKnown(resp http.ResponseWriter, req *http.Request) {
+	if oauth2.DefaultSigningKey == nil || oauth2.DefaultSigningKey.IsSymmetric() {
+		http.NotFound(resp, req)
+		return
+	}
+
+	resp.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(resp).Encode(&openIDConfiguration{
+		Issuer: actions_service.IDTokenIssuer(),
+		// the ID tokens are signed with the key of the OAuth2 provider
+		JWKSURI:                setting.AppURL + "login/oauth/keys",
+		SubjectTypesSupported:  []string{"public"},
+		ResponseTypesSupported: []string{"id_token"},
+		ClaimsSupported: []string{
+			"sub", "aud", "exp", "iat", "iss", "jti", "nbf",
+			"ref", "ref_type", "sha", "repository", "repository_id", "repository_owner", "repository_owner_id",
+			"repository_visibility", "actor", "actor_id", "workflow", "workflow_ref", "job_workflow_ref",
+			"event_name", "environment", "head_ref", "base_ref", "run_id", "run_number", "run_attempt", "runner_environment",
+		},
+		IDTokenSigningAlgValuesSupported: []string{oauth2.DefaultSigningKey.SigningMethod().Alg()},
+		ScopesSupported:                  []string{"openid"},
+	}); err != nil {
+		log.Error("Failed to encode the OpenID configuration: %v", err)
+	}
+}
+
+type idTokenResponse struct {
+	Value string `json:"value"`
+}
+
+// IDToken issues an ID token to the job of the task requesting it
+func IDToken(ctx *ArtifactContext) {
+	token, err := actions_service.CreateIDToken(ctx, ctx.ActionTask, ctx.Req.URL.Query().Get("audience"))
+	if err != nil {
+		if errors.Is(err, actions_service.ErrIDTokenUnavailable) {
+			ctx.Error(http.StatusForbidden, "ID tokens are not available to this job")
+			return
+		}
+		log.Error("Error creating the ID token of task %d: %v", ctx.ActionTask.ID, err)
+		ctx.Error(http.StatusInternalServerError, "Error creating the ID token")
+		return
+	}
+	ctx.JSON(http.StatusOK, &idTokenResponse{Value: token})
+}
@@ -219,6 +219,8 @@ func (s *Service) UpdateTask(
 		actions_service.CreateCommitStatus(ctx, task.Job)
 	}
 
+	actions_service.StreamTaskLogs(ctx, task, 0, nil)
+
 	if req.Msg.State.Result != runnerv1.Result_RESULT_UNSPECIFIED {
 		if err := actions_service.EmitJobsIfReady(task.Job.RunID); err != nil {
 			log.Error("Emit ready jobs of run %d: %v", task.Job.RunID, err)
@@ -284,6 +286,7 @@ func (s *Service) UpdateLog(
 	if remove != nil {
 		remove()
 	}
+	actions_service.StreamTaskLogs(ctx, task, ack, rows)
 
 	return res, nil
 }
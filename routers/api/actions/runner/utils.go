@@ -6,6 +6,7 @@ package runner
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	actions_model "code.gitea.io/gitea/models/actions"
 	"code.gitea.io/gitea/models/db"
@@ -36,16 +37,26 @@ func pickTask(ctx context.Context, runner *actions_model.ActionRunner) (*runnerv
 		return nil, false, fmt.Errorf("GetSecretsOfTask: %w", err)
 	}
 
-	vars, err := actions_model.GetVariablesOfRun(ctx, t.Job.Run)
+	vars, err := actions_model.GetVariablesOfJob(ctx, t.Job)
 	if err != nil {
-		return nil, false, fmt.Errorf("GetVariablesOfRun: %w", err)
+		return nil, false, fmt.Errorf("GetVariablesOfJob: %w", err)
 	}
 
 	actions.CreateCommitStatus(ctx, t.Job)
 
+	payload := t.Job.WorkflowPayload
+	if actions.IsIDTokenAvailable(t) {
+		if p, err := actions.AddIDTokenRequestEnv(t, payload); err != nil {
+			// Go on without ID token, the job will fail when requesting one.
+			log.Error("Cannot add the ID token request environment to task %v: %v", t.ID, err)
+		} else {
+			payload = p
+		}
+	}
+
 	task := &runnerv1.Task{
 		Id:              t.ID,
-		WorkflowPayload: t.Job.WorkflowPayload,
+		WorkflowPayload: payload,
 		Context:         generateTaskContext(t),
 		Secrets:         secrets,
 		Vars:            vars,
@@ -162,24 +173,23 @@ func findTaskNeeds(ctx context.Context, task *actions_model.ActionTask) (map[str
 		return nil, fmt.Errorf("FindRunJobs: %w", err)
 	}
 
+	// the jobs of a called workflow refer to each other without the prefix of their parent job
+	prefix := actions.JobIDPrefix(task.Job)
+
 	ret := make(map[string]*runnerv1.TaskNeed, len(needs))
 	for _, job := range jobs {
 		if !needs.Contains(job.JobID) {
 			continue
 		}
-		if job.TaskID == 0 || !job.Status.IsDone() {
+		if (job.TaskID == 0 && !job.IsCallingWorkflow()) || !job.Status.IsDone() {
 			// it shouldn't happen, or the job has been rerun
 			continue
 		}
-		outputs := make(map[string]string)
-		got, err := actions_model.FindTaskOutputByTaskID(ctx, job.TaskID)
+		outputs, err := actions.FindJobOutputs(ctx, job, jobs)
 		if err != nil {
-			return nil, fmt.Errorf("FindTaskOutputByTaskID: %w", err)
-		}
-		for _, v := range got {
-			outputs[v.OutputKey] = v.OutputValue
+			return nil, err
 		}
-		ret[job.JobID] = &runnerv1.TaskNeed{
+		ret[strings.TrimPrefix(job.JobID, prefix)] = &runnerv1.TaskNeed{
 			Outputs: outputs,
 			Result:  runnerv1.Result(job.Status),
 		}
@@ -14,6 +14,7 @@ import (
 	"code.gitea.io/gitea/modules/setting"
 	"code.gitea.io/gitea/modules/web"
 	"code.gitea.io/gitea/routers/api/packages/alpine"
+	"code.gitea.io/gitea/routers/api/packages/arch"
 	"code.gitea.io/gitea/routers/api/packages/cargo"
 	"code.gitea.io/gitea/routers/api/packages/chef"
 	"code.gitea.io/gitea/routers/api/packages/composer"
@@ -26,6 +27,7 @@ import (
 	"code.gitea.io/gitea/routers/api/packages/goproxy"
 	"code.gitea.io/gitea/routers/api/packages/helm"
 	"code.gitea.io/gitea/routers/api/packages/maven"
+	"code.gitea.io/gitea/routers/api/packages/nix"
 	"code.gitea.io/gitea/routers/api/packages/npm"
 	"code.gitea.io/gitea/routers/api/packages/nuget"
 	"code.gitea.io/gitea/routers/api/packages/pub"
@@ -33,6 +35,7 @@ import (
 	"code.gitea.io/gitea/routers/api/packages/rpm"
 	"code.gitea.io/gitea/routers/api/packages/rubygems"
 	"code.gitea.io/gitea/routers/api/packages/swift"
+	"code.gitea.io/gitea/routers/api/packages/terraform"
 	"code.gitea.io/gitea/routers/api/packages/vagrant"
 	"code.gitea.io/gitea/services/auth"
 	"code.gitea.io/gitea/services/context"
@@ -107,6 +110,19 @@ func CommonRoutes() *web.Route {
 		&chef.Auth{},
 	})
 
+	// The Terraform registry protocols address the packages by a namespace, which is the owner.
+	// `-` is never a valid username.
+	r.Group("/-/terraform", func() {
+		r.Group("/modules/v1/{username}/{name}/{system}", func() {
+			r.Get("/versions", terraform.EnumerateModuleVersions)
+			r.Get("/{version}/download", terraform.DownloadModule)
+		}, context.UserAssignmentWeb(), context.PackageAssignment(), reqPackageAccess(perm.AccessModeRead))
+		r.Group("/providers/v1/{username}/{name}", func() {
+			r.Get("/versions", terraform.EnumerateProviderVersions)
+			r.Get("/{version}/download/{os}/{arch}", terraform.DownloadProvider)
+		}, context.UserAssignmentWeb(), context.PackageAssignment(), reqPackageAccess(perm.AccessModeRead))
+	})
+
 	r.Group("/{username}", func() {
 		r.Group("/alpine", func() {
 			r.Get("/key", alpine.GetRepositoryKey)
@@ -121,6 +137,14 @@ func CommonRoutes() *web.Route {
 				})
 			})
 		}, reqPackageAccess(perm.AccessModeRead))
+		r.Group("/arch", func() {
+			r.Get("/repository.key", arch.GetRepositoryKey)
+			r.Group("/{distribution}", func() {
+				r.Put("", reqPackageAccess(perm.AccessModeWrite), arch.UploadPackageFile)
+				r.Get("/{architecture}/{filename}", arch.GetFile)
+				r.Delete("/{name}/{version}/{architecture}", reqPackageAccess(perm.AccessModeWrite), arch.DeletePackageFile)
+			})
+		}, reqPackageAccess(perm.AccessModeRead))
 		r.Group("/cargo", func() {
 			r.Group("/api/v1/crates", func() {
 				r.Get("", cargo.SearchPackages)
@@ -447,6 +471,18 @@ func CommonRoutes() *web.Route {
 				})
 			}, reqPackageAccess(perm.AccessModeRead))
 		})
+		r.Group("/nix", func() {
+			r.Methods("GET,HEAD", "/nix-cache-info", nix.CacheInfo)
+			r.Group("/nar/{filename}", func() {
+				r.Methods("GET,HEAD", "", nix.DownloadNar)
+				r.Put("", reqPackageAccess(perm.AccessModeWrite), nix.UploadNar)
+			})
+			r.Group("/{hash}.narinfo", func() {
+				r.Methods("GET,HEAD", "", nix.DownloadNarInfo)
+				r.Put("", reqPackageAccess(perm.AccessModeWrite), nix.UploadNarInfo)
+				r.Delete("", reqPackageAccess(perm.AccessModeWrite), nix.DeleteNarInfo)
+			})
+		}, reqPackageAccess(perm.AccessModeRead))
 		r.Group("/npm", func() {
 			r.Group("/@{scope}/{id}", func() {
 				r.Get("", npm.PackageMetadata)
@@ -630,6 +666,20 @@ func CommonRoutes() *web.Route {
 			})
 			r.Get("/identifiers", swift.CheckAcceptMediaType(swift.AcceptJSON), swift.LookupPackageIdentifiers)
 		}, reqPackageAccess(perm.AccessModeRead))
+		r.Group("/terraform", func() {
+			r.Group("/modules/{name}/{system}/{version}", func() {
+				r.Put("", reqPackageAccess(perm.AccessModeWrite), terraform.UploadModule)
+				r.Delete("", reqPackageAccess(perm.AccessModeWrite), terraform.DeleteModule)
+				r.Get("/{filename}", terraform.VerifyDownloadSignature, reqPackageAccess(perm.AccessModeRead), terraform.DownloadModuleArchive)
+			})
+			r.Group("/providers/{name}/{version}", func() {
+				r.Delete("", reqPackageAccess(perm.AccessModeWrite), terraform.DeleteProvider)
+				r.Group("/{filename}", func() {
+					r.Get("", terraform.VerifyDownloadSignature, reqPackageAccess(perm.AccessModeRead), terraform.DownloadProviderFile)
+					r.Put("", reqPackageAccess(perm.AccessModeWrite), terraform.UploadProviderFile)
+				})
+			})
+		})
 		r.Group("/vagrant", func() {
 			r.Group("/authenticate", func() {
 				r.Get("", vagrant.CheckAuthenticate)
@@ -690,12 +740,14 @@ func ContainerRoutes() *web.Route {
 				r.Delete("", reqPackageAccess(perm.AccessModeWrite), container.DeleteManifest)
 			})
 			r.Get("/tags/list", container.GetTagList)
+			r.Get("/referrers/{digest}", container.GetReferrers)
 		}, container.VerifyImageName)
 
 		var (
 			blobsUploadsPattern = regexp.MustCompile(`\A(.+)/blobs/uploads/([a-zA-Z0-9-_.=]+)\z`)
 			blobsPattern        = regexp.MustCompile(`\A(.+)/blobs/([^/]+)\z`)
 			manifestsPattern    = regexp.MustCompile(`\A(.+)/manifests/([^/]+)\z`)
+			referrersPattern    = regexp.MustCompile(`\A(.+)/referrers/([^/]+)\z`)
 		)
 
 		// Manual mapping of routes because {image} can contain slashes which chi does not support
@@ -810,6 +862,19 @@ func ContainerRoutes() *web.Route {
 				}
 				return
 			}
+			m = referrersPattern.FindStringSubmatch(path)
+			if len(m) == 3 && isGet {
+				ctx.SetParams("image", m[1])
+				container.VerifyImageName(ctx)
+				if ctx.Written() {
+					return
+				}
+
+				ctx.SetParams("digest", m[2])
+
+				container.GetReferrers(ctx)
+				return
+			}
 
 			ctx.Status(http.StatusNotFound)
 		})
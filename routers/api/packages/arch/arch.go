@@ -0,0 +1,351 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package arch
+
+import (
+	"bytes"
+	stdctx "context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"code.gitea.io/gitea/models/db"
+	packages_model "code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/modules/json"
+	packages_module "code.gitea.io/gitea/modules/packages"
+	arch_module "code.gitea.io/gitea/modules/packages/arch"
+	"code.gitea.io/gitea/modules/util"
+	"code.gitea.io/gitea/routers/api/packages/helper"
+	"code.gitea.io/gitea/services/context"
+	notify_service "code.gitea.io/gitea/services/notify"
+	packages_service "code.gitea.io/gitea/services/packages"
+	arch_service "code.gitea.io/gitea/services/packages/arch"
+)
+
+var (
+	// pacman requests the databases with the name of the repository section, e.g. core.db or core.files.tar.gz
+	databasePattern = regexp.MustCompile(`\A[^/]+\.(db|files)(?:\.tar\.gz)?(\.sig)?\z`)
+	// name-pkgver-pkgrel-arch.pkg.tar.zst, only the name can contain dashes
+	packagePattern = regexp.MustCompile(`\A(.+)-([^-]+-[^-]+)-([^-]+)(\.pkg\.tar\.(?:zst|xz))(\.sig)?\z`)
+)
+
+func apiError(ctx *context.Context, status int, obj any) {
+	helper.LogAndProcessError(ctx, status, obj, func(message string) {
+		ctx.PlainText(status, message)
+	})
+}
+
+// GetRepositoryKey gets or creates the PGP public key used to sign packages and repository databases
+func GetRepositoryKey(ctx *context.Context) {
+	_, pub, err := arch_service.GetOrCreateKeyPair(ctx, ctx.Package.Owner.ID)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.ServeContent(strings.NewReader(pub), &context.ServeHeaderOptions{
+		ContentType: "application/pgp-keys",
+		Filename:    "repository.key",
+	})
+}
+
+// GetFile serves the repository databases, the package files and their signatures
+func GetFile(ctx *context.Context) {
+	filename := ctx.Params("filename")
+
+	if m := databasePattern.FindStringSubmatch(filename); m != nil {
+		getRepositoryFile(ctx, m[1] == "files", m[2] != "")
+		return
+	}
+	if m := packagePattern.FindStringSubmatch(filename); m != nil {
+		getPackageFile(ctx, m[1], m[2], m[1]+"-"+m[2]+"-"+m[3]+m[4], m[5] != "")
+		return
+	}
+
+	apiError(ctx, http.StatusNotFound, nil)
+}
+
+func getRepositoryFile(ctx *context.Context, isFilesDatabase, isSignature bool) {
+	pv, err := arch_service.GetOrCreateRepositoryVersion(ctx, ctx.Package.Owner.ID)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	filename := arch_service.DatabaseFilename
+	if isFilesDatabase {
+		filename = arch_service.FilesDatabaseFilename
+	}
+	if isSignature {
+		filename += arch_service.SignatureExtension
+	}
+
+	distribution := ctx.Params("distribution")
+
+	// Fall back to the database of the "any" packages if there are no packages for the requested architecture
+	var pf *packages_model.PackageFile
+	for _, architecture := range []string{ctx.Params("architecture"), arch_module.AnyArchitecture} {
+		pf, err = packages_model.GetFileForVersionByName(ctx, pv.ID, filename, distribution+"|"+architecture)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, util.ErrNotExist) {
+			apiError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+	}
+	if pf == nil {
+		apiError(ctx, http.StatusNotFound, err)
+		return
+	}
+
+	s, u, pf, err := packages_service.GetPackageFileStream(ctx, ctx.Doer, pf)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	helper.ServePackageFile(ctx, s, u, pf)
+}
+
+func getPackageFile(ctx *context.Context, name, version, filename string, isSignature bool) {
+	pv, err := packages_model.GetVersionByNameAndVersion(ctx, ctx.Package.Owner.ID, packages_model.TypeArch, name, version)
+	if err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			apiError(ctx, http.StatusNotFound, err)
+		} else {
+			apiError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	pf, err := packages_model.GetFileForVersionByName(ctx, pv.ID, filename, ctx.Params("distribution"))
+	if err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			apiError(ctx, http.StatusNotFound, err)
+		} else {
+			apiError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	if isSignature {
+		signature, err := arch_service.GetPackageSignature(ctx, pf)
+		if err != nil {
+			if errors.Is(err, util.ErrNotExist) {
+				apiError(ctx, http.StatusNotFound, err)
+			} else {
+				apiError(ctx, http.StatusInternalServerError, err)
+			}
+			return
+		}
+
+		ctx.ServeContent(bytes.NewReader(signature), &context.ServeHeaderOptions{
+			ContentType:  "application/pgp-signature",
+			Filename:     filename + arch_service.SignatureExtension,
+			LastModified: pf.CreatedUnix.AsLocalTime(),
+		})
+		return
+	}
+
+	s, u, pf, err := packages_service.GetPackageFileStream(ctx, ctx.Doer, pf)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	helper.ServePackageFile(ctx, s, u, pf)
+}
+
+// UploadPackageFile adds a package file to the distribution and rebuilds its databases
+func UploadPackageFile(ctx *context.Context) {
+	distribution := strings.TrimSpace(ctx.Params("distribution"))
+	if distribution == "" {
+		apiError(ctx, http.StatusBadRequest, "invalid distribution")
+		return
+	}
+
+	upload, needToClose, err := ctx.UploadStream()
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if needToClose {
+		defer upload.Close()
+	}
+
+	buf, err := packages_module.CreateHashedBufferFromReader(upload)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	defer buf.Close()
+
+	pck, err := arch_module.ParsePackage(buf)
+	if err != nil {
+		if errors.Is(err, util.ErrInvalidArgument) || err == io.EOF {
+			apiError(ctx, http.StatusBadRequest, err)
+		} else {
+			apiError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	if _, err := buf.Seek(0, io.SeekStart); err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	signature, err := arch_service.SignData(ctx, ctx.Package.Owner.ID, buf)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	if _, err := buf.Seek(0, io.SeekStart); err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	fileMetadataRaw, err := json.Marshal(pck.FileMetadata)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	_, _, err = packages_service.CreatePackageOrAddFileToExisting(
+		ctx,
+		&packages_service.PackageCreationInfo{
+			PackageInfo: packages_service.PackageInfo{
+				Owner:       ctx.Package.Owner,
+				PackageType: packages_model.TypeArch,
+				Name:        pck.Name,
+				Version:     pck.Version,
+			},
+			Creator:  ctx.Doer,
+			Metadata: pck.VersionMetadata,
+		},
+		&packages_service.PackageFileCreationInfo{
+			PackageFileInfo: packages_service.PackageFileInfo{
+				Filename:     pck.Filename(),
+				CompositeKey: distribution,
+			},
+			Creator: ctx.Doer,
+			Data:    buf,
+			IsLead:  true,
+			Properties: map[string]string{
+				arch_module.PropertyDistribution: distribution,
+				arch_module.PropertyArchitecture: pck.FileMetadata.Architecture,
+				arch_module.PropertyMetadata:     string(fileMetadataRaw),
+				arch_module.PropertySignature:    base64.StdEncoding.EncodeToString(signature),
+			},
+		},
+	)
+	if err != nil {
+		switch err {
+		case packages_model.ErrDuplicatePackageVersion, packages_model.ErrDuplicatePackageFile:
+			apiError(ctx, http.StatusConflict, err)
+		case packages_service.ErrQuotaTotalCount, packages_service.ErrQuotaTypeSize, packages_service.ErrQuotaTotalSize:
+			apiError(ctx, http.StatusForbidden, err)
+		default:
+			apiError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	if err := arch_service.BuildSpecificRepositoryFiles(ctx, ctx.Package.Owner.ID, distribution); err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.Status(http.StatusCreated)
+}
+
+// DeletePackageFile removes a package file from the distribution and rebuilds its databases
+func DeletePackageFile(webctx *context.Context) {
+	distribution := webctx.Params("distribution")
+	name := webctx.Params("name")
+	version := webctx.Params("version")
+	architecture := webctx.Params("architecture")
+
+	var pd *packages_model.PackageDescriptor
+
+	err := db.WithTx(webctx, func(ctx stdctx.Context) error {
+		pv, err := packages_model.GetVersionByNameAndVersion(ctx,
+			webctx.Package.Owner.ID,
+			packages_model.TypeArch,
+			name,
+			version,
+		)
+		if err != nil {
+			return err
+		}
+
+		pfs, err := packages_model.GetFilesByVersionID(ctx, pv.ID)
+		if err != nil {
+			return err
+		}
+
+		// the file extension depends on the compression of the uploaded package
+		var pf *packages_model.PackageFile
+		for _, f := range pfs {
+			if f.CompositeKey == distribution && (f.Name == name+"-"+version+"-"+architecture+arch_module.ExtensionZstd || f.Name == name+"-"+version+"-"+architecture+arch_module.ExtensionXz) {
+				pf = f
+				break
+			}
+		}
+		if pf == nil {
+			return packages_model.ErrPackageFileNotExist
+		}
+
+		if err := packages_service.CheckVersionRemovable(ctx, pv); err != nil {
+			return err
+		}
+
+		if err := packages_service.DeletePackageFile(ctx, pf); err != nil {
+			return err
+		}
+
+		has, err := packages_model.HasVersionFileReferences(ctx, pv.ID)
+		if err != nil {
+			return err
+		}
+		if !has {
+			pd, err = packages_model.GetPackageDescriptor(ctx, pv)
+			if err != nil {
+				return err
+			}
+
+			if err := packages_service.DeletePackageVersionAndReferences(ctx, pv); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			apiError(webctx, http.StatusNotFound, err)
+		} else if errors.Is(err, util.ErrPermissionDenied) {
+			apiError(webctx, http.StatusForbidden, err)
+		} else {
+			apiError(webctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	if pd != nil {
+		notify_service.PackageDelete(webctx, webctx.Doer, pd)
+	}
+
+	if err := arch_service.BuildSpecificRepositoryFiles(webctx, webctx.Package.Owner.ID, distribution); err != nil {
+		apiError(webctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	webctx.Status(http.StatusNoContent)
+}
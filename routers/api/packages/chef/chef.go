@@ -343,7 +343,7 @@ func DownloadPackage(ctx *context.Context) {
 
 	pf := pd.Files[0].File
 
-	s, u, _, err := packages_service.GetPackageFileStream(ctx, pf)
+	s, u, _, err := packages_service.GetPackageFileStream(ctx, ctx.Doer, pf)
 	if err != nil {
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
@@ -370,6 +370,8 @@ func DeletePackageVersion(ctx *context.Context) {
 	if err != nil {
 		if err == packages_model.ErrPackageNotExist {
 			apiError(ctx, http.StatusNotFound, err)
+		} else if errors.Is(err, util.ErrPermissionDenied) {
+			apiError(ctx, http.StatusForbidden, err)
 		} else {
 			apiError(ctx, http.StatusInternalServerError, err)
 		}
@@ -394,6 +396,10 @@ func DeletePackage(ctx *context.Context) {
 
 	for _, pv := range pvs {
 		if err := packages_service.RemovePackageVersion(ctx, ctx.Doer, pv); err != nil {
+			if errors.Is(err, util.ErrPermissionDenied) {
+				apiError(ctx, http.StatusForbidden, err)
+				return
+			}
 			apiError(ctx, http.StatusInternalServerError, err)
 			return
 		}
@@ -165,6 +165,7 @@ func PackageMetadata(ctx *context.Context) {
 func DownloadPackageFile(ctx *context.Context) {
 	s, u, pf, err := packages_service.GetFileStreamByPackageNameAndVersion(
 		ctx,
+		ctx.Doer,
 		&packages_service.PackageInfo{
 			Owner:       ctx.Package.Owner,
 			PackageType: packages_model.TypeComposer,
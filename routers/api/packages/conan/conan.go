@@ -5,6 +5,7 @@ package conan
 
 import (
 	std_ctx "context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -20,6 +21,7 @@ import (
 	packages_module "code.gitea.io/gitea/modules/packages"
 	conan_module "code.gitea.io/gitea/modules/packages/conan"
 	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/util"
 	"code.gitea.io/gitea/routers/api/packages/helper"
 	"code.gitea.io/gitea/services/context"
 	notify_service "code.gitea.io/gitea/services/notify"
@@ -359,6 +361,9 @@ func uploadFile(ctx *context.Context, fileFilter container.Set[string], fileKey
 		pfci.Properties[conan_module.PropertyPackageRevision] = pref.RevisionOrDefault()
 	}
 
+	// the metadata of an existing version is only updated once the conanfile is accepted
+	var existingVersion *packages_model.PackageVersion
+	var existingMetadata *conan_module.Metadata
 	if isConanfileFile || isConaninfoFile {
 		if isConanfileFile {
 			metadata, err := conan_module.ParseConanfile(buf)
@@ -373,16 +378,7 @@ func uploadFile(ctx *context.Context, fileFilter container.Set[string], fileKey
 				return
 			}
 			if pv != nil {
-				raw, err := json.Marshal(metadata)
-				if err != nil {
-					apiError(ctx, http.StatusInternalServerError, err)
-					return
-				}
-				pv.MetadataJSON = string(raw)
-				if err := packages_model.UpdateVersion(ctx, pv); err != nil {
-					apiError(ctx, http.StatusInternalServerError, err)
-					return
-				}
+				existingVersion, existingMetadata = pv, metadata
 			} else {
 				pci.Metadata = metadata
 			}
@@ -413,10 +409,12 @@ func uploadFile(ctx *context.Context, fileFilter container.Set[string], fileKey
 		pfci,
 	)
 	if err != nil {
-		switch err {
-		case packages_model.ErrDuplicatePackageFile:
+		switch {
+		case err == packages_model.ErrDuplicatePackageFile:
 			apiError(ctx, http.StatusConflict, err)
-		case packages_service.ErrQuotaTotalCount, packages_service.ErrQuotaTypeSize, packages_service.ErrQuotaTotalSize:
+		case err == packages_service.ErrQuotaTotalCount, err == packages_service.ErrQuotaTypeSize, err == packages_service.ErrQuotaTotalSize:
+			apiError(ctx, http.StatusForbidden, err)
+		case errors.Is(err, util.ErrPermissionDenied):
 			apiError(ctx, http.StatusForbidden, err)
 		default:
 			apiError(ctx, http.StatusInternalServerError, err)
@@ -424,6 +422,19 @@ func uploadFile(ctx *context.Context, fileFilter container.Set[string], fileKey
 		return
 	}
 
+	if existingVersion != nil {
+		raw, err := json.Marshal(existingMetadata)
+		if err != nil {
+			apiError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+		existingVersion.MetadataJSON = string(raw)
+		if err := packages_model.UpdateVersion(ctx, existingVersion); err != nil {
+			apiError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
 	ctx.Status(http.StatusCreated)
 }
 
@@ -452,6 +463,7 @@ func downloadFile(ctx *context.Context, fileFilter container.Set[string], fileKe
 
 	s, u, pf, err := packages_service.GetFileStreamByPackageNameAndVersion(
 		ctx,
+		ctx.Doer,
 		&packages_service.PackageInfo{
 			Owner:       ctx.Package.Owner,
 			PackageType: packages_model.TypeConan,
@@ -482,6 +494,8 @@ func DeleteRecipeV1(ctx *context.Context) {
 	if err := deleteRecipeOrPackage(ctx, rref, true, nil, false); err != nil {
 		if err == packages_model.ErrPackageNotExist || err == conan_model.ErrPackageReferenceNotExist {
 			apiError(ctx, http.StatusNotFound, err)
+		} else if errors.Is(err, util.ErrPermissionDenied) {
+			apiError(ctx, http.StatusForbidden, err)
 		} else {
 			apiError(ctx, http.StatusInternalServerError, err)
 		}
@@ -497,6 +511,8 @@ func DeleteRecipeV2(ctx *context.Context) {
 	if err := deleteRecipeOrPackage(ctx, rref, rref.Revision == "", nil, false); err != nil {
 		if err == packages_model.ErrPackageNotExist || err == conan_model.ErrPackageReferenceNotExist {
 			apiError(ctx, http.StatusNotFound, err)
+		} else if errors.Is(err, util.ErrPermissionDenied) {
+			apiError(ctx, http.StatusForbidden, err)
 		} else {
 			apiError(ctx, http.StatusInternalServerError, err)
 		}
@@ -544,6 +560,8 @@ func DeletePackageV1(ctx *context.Context) {
 			if err := deleteRecipeOrPackage(ctx, currentRref, true, pref, true); err != nil {
 				if err == packages_model.ErrPackageNotExist || err == conan_model.ErrPackageReferenceNotExist {
 					apiError(ctx, http.StatusNotFound, err)
+				} else if errors.Is(err, util.ErrPermissionDenied) {
+					apiError(ctx, http.StatusForbidden, err)
 				} else {
 					apiError(ctx, http.StatusInternalServerError, err)
 				}
@@ -563,6 +581,8 @@ func DeletePackageV2(ctx *context.Context) {
 		if err := deleteRecipeOrPackage(ctx, rref, false, pref, pref.Revision == ""); err != nil {
 			if err == packages_model.ErrPackageNotExist || err == conan_model.ErrPackageReferenceNotExist {
 				apiError(ctx, http.StatusNotFound, err)
+			} else if errors.Is(err, util.ErrPermissionDenied) {
+				apiError(ctx, http.StatusForbidden, err)
 			} else {
 				apiError(ctx, http.StatusInternalServerError, err)
 			}
@@ -588,6 +608,8 @@ func DeletePackageV2(ctx *context.Context) {
 		if err := deleteRecipeOrPackage(ctx, rref, false, pref, true); err != nil {
 			if err == packages_model.ErrPackageNotExist || err == conan_model.ErrPackageReferenceNotExist {
 				apiError(ctx, http.StatusNotFound, err)
+			} else if errors.Is(err, util.ErrPermissionDenied) {
+				apiError(ctx, http.StatusForbidden, err)
 			} else {
 				apiError(ctx, http.StatusInternalServerError, err)
 			}
@@ -608,6 +630,10 @@ func deleteRecipeOrPackage(apictx *context.Context, rref *conan_module.RecipeRef
 			return err
 		}
 
+		if err := packages_service.CheckVersionRemovable(ctx, pv); err != nil {
+			return err
+		}
+
 		pd, err = packages_model.GetPackageDescriptor(ctx, pv)
 		if err != nil {
 			return err
@@ -293,7 +293,7 @@ func DownloadPackageFile(ctx *context.Context) {
 
 	pf := pfs[0]
 
-	s, u, _, err := packages_service.GetPackageFileStream(ctx, pf)
+	s, u, _, err := packages_service.GetPackageFileStream(ctx, ctx.Doer, pf)
 	if err != nil {
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
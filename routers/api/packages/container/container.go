@@ -29,6 +29,8 @@ import (
 	container_service "code.gitea.io/gitea/services/packages/container"
 
 	digest "github.com/opencontainers/go-digest"
+	specs "github.com/opencontainers/image-spec/specs-go"
+	oci "github.com/opencontainers/image-spec/specs-go/v1"
 )
 
 // maximum size of a container manifest
@@ -48,6 +50,8 @@ type containerHeaders struct {
 	Location      string
 	ContentType   string
 	ContentLength int64
+	Subject       string
+	Filters       string
 }
 
 // https://github.com/opencontainers/distribution-spec/blob/main/spec.md#legacy-docker-support-http-headers
@@ -71,6 +75,12 @@ func setResponseHeaders(resp http.ResponseWriter, h *containerHeaders) {
 		resp.Header().Set("Docker-Content-Digest", h.ContentDigest)
 		resp.Header().Set("ETag", fmt.Sprintf(`"%s"`, h.ContentDigest))
 	}
+	if h.Subject != "" {
+		resp.Header().Set("OCI-Subject", h.Subject)
+	}
+	if h.Filters != "" {
+		resp.Header().Set("OCI-Filters-Applied", h.Filters)
+	}
 	resp.Header().Set("Docker-Distribution-Api-Version", "registry/2.0")
 	resp.WriteHeader(h.Status)
 }
@@ -472,22 +482,29 @@ func getBlobFromContext(ctx *context.Context) (*packages_model.PackageFileDescri
 		return nil, container_model.ErrContainerBlobNotExist
 	}
 
-	return workaroundGetContainerBlob(ctx, &container_model.BlobSearchOptions{
+	opts := &container_model.BlobSearchOptions{
 		OwnerID: ctx.Package.Owner.ID,
 		Image:   ctx.Params("image"),
 		Digest:  d,
-	})
+	}
+
+	blob, err := workaroundGetContainerBlob(ctx, opts)
+	if err == container_model.ErrContainerBlobNotExist {
+		if cached, cacheErr := cacheUpstreamBlob(ctx, ctx.Package.Owner, opts.Image, d); cached {
+			if cacheErr != nil {
+				return nil, cacheErr
+			}
+			blob, err = workaroundGetContainerBlob(ctx, opts)
+		}
+	}
+	return blob, err
 }
 
 // https://github.com/opencontainers/distribution-spec/blob/main/spec.md#checking-if-content-exists-in-the-registry
 func HeadBlob(ctx *context.Context) {
 	blob, err := getBlobFromContext(ctx)
 	if err != nil {
-		if err == container_model.ErrContainerBlobNotExist {
-			apiErrorDefined(ctx, errBlobUnknown)
-		} else {
-			apiError(ctx, http.StatusInternalServerError, err)
-		}
+		blobError(ctx, err, errBlobUnknown)
 		return
 	}
 
@@ -502,11 +519,7 @@ func HeadBlob(ctx *context.Context) {
 func GetBlob(ctx *context.Context) {
 	blob, err := getBlobFromContext(ctx)
 	if err != nil {
-		if err == container_model.ErrContainerBlobNotExist {
-			apiErrorDefined(ctx, errBlobUnknown)
-		} else {
-			apiError(ctx, http.StatusInternalServerError, err)
-		}
+		blobError(ctx, err, errBlobUnknown)
 		return
 	}
 
@@ -563,13 +576,15 @@ func UploadManifest(ctx *context.Context) {
 		return
 	}
 
-	digest, err := processManifest(ctx, mci, buf)
+	digest, subject, err := processManifest(ctx, mci, buf)
 	if err != nil {
 		var namedError *namedError
 		if errors.As(err, &namedError) {
 			apiErrorDefined(ctx, namedError)
 		} else if errors.Is(err, container_model.ErrContainerBlobNotExist) {
 			apiErrorDefined(ctx, errBlobUnknown)
+		} else if errors.Is(err, util.ErrPermissionDenied) {
+			apiErrorDefined(ctx, errDenied.WithMessage(err.Error()))
 		} else {
 			switch err {
 			case packages_service.ErrQuotaTotalCount, packages_service.ErrQuotaTypeSize, packages_service.ErrQuotaTotalSize:
@@ -584,6 +599,7 @@ func UploadManifest(ctx *context.Context) {
 	setResponseHeaders(ctx.Resp, &containerHeaders{
 		Location:      fmt.Sprintf("/v2/%s/%s/manifests/%s", ctx.Package.Owner.LowerName, mci.Image, reference),
 		ContentDigest: digest,
+		Subject:       subject,
 		Status:        http.StatusCreated,
 	})
 }
@@ -614,18 +630,43 @@ func getManifestFromContext(ctx *context.Context) (*packages_model.PackageFileDe
 		return nil, err
 	}
 
-	return workaroundGetContainerBlob(ctx, opts)
+	manifest, err := workaroundGetContainerBlob(ctx, opts)
+	if err != nil && err != container_model.ErrContainerBlobNotExist {
+		return nil, err
+	}
+	if err == nil && opts.Tag == "" {
+		// manifests referenced by their digest can't change
+		return manifest, nil
+	}
+
+	if cached, cacheErr := cacheUpstreamManifest(ctx, ctx.Package.Owner, opts.Image, ctx.Params("reference"), manifest); cached {
+		if cacheErr != nil {
+			return nil, cacheErr
+		}
+		return workaroundGetContainerBlob(ctx, opts)
+	}
+	return manifest, err
+}
+
+// blobError responds with the error of a blob or a manifest which couldn't be found or fetched from the upstream registry
+func blobError(ctx *context.Context, err error, errUnknown *namedError) {
+	switch helper.UpstreamErrorStatus(err) {
+	case http.StatusNotFound:
+		apiErrorDefined(ctx, errUnknown)
+	case http.StatusForbidden:
+		apiErrorDefined(ctx, errDenied.WithMessage(err.Error()))
+	case http.StatusBadGateway:
+		apiError(ctx, http.StatusBadGateway, err)
+	default:
+		apiError(ctx, http.StatusInternalServerError, err)
+	}
 }
 
 // https://github.com/opencontainers/distribution-spec/blob/main/spec.md#checking-if-content-exists-in-the-registry
 func HeadManifest(ctx *context.Context) {
 	manifest, err := getManifestFromContext(ctx)
 	if err != nil {
-		if err == container_model.ErrContainerBlobNotExist {
-			apiErrorDefined(ctx, errManifestUnknown)
-		} else {
-			apiError(ctx, http.StatusInternalServerError, err)
-		}
+		blobError(ctx, err, errManifestUnknown)
 		return
 	}
 
@@ -641,11 +682,7 @@ func HeadManifest(ctx *context.Context) {
 func GetManifest(ctx *context.Context) {
 	manifest, err := getManifestFromContext(ctx)
 	if err != nil {
-		if err == container_model.ErrContainerBlobNotExist {
-			apiErrorDefined(ctx, errManifestUnknown)
-		} else {
-			apiError(ctx, http.StatusInternalServerError, err)
-		}
+		blobError(ctx, err, errManifestUnknown)
 		return
 	}
 
@@ -674,6 +711,10 @@ func DeleteManifest(ctx *context.Context) {
 
 	for _, pv := range pvs {
 		if err := packages_service.RemovePackageVersion(ctx, ctx.Doer, pv); err != nil {
+			if errors.Is(err, util.ErrPermissionDenied) {
+				apiErrorDefined(ctx, errDenied.WithMessage(err.Error()))
+				return
+			}
 			apiError(ctx, http.StatusInternalServerError, err)
 			return
 		}
@@ -685,7 +726,7 @@ func DeleteManifest(ctx *context.Context) {
 }
 
 func serveBlob(ctx *context.Context, pfd *packages_model.PackageFileDescriptor) {
-	s, u, _, err := packages_service.GetPackageBlobStream(ctx, pfd.File, pfd.Blob)
+	s, u, _, err := packages_service.GetPackageBlobStream(ctx, ctx.Doer, pfd.File, pfd.Blob)
 	if err != nil {
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
@@ -714,6 +755,60 @@ func serveBlob(ctx *context.Context, pfd *packages_model.PackageFileDescriptor)
 	}
 }
 
+// https://github.com/opencontainers/distribution-spec/blob/main/spec.md#listing-referrers
+func GetReferrers(ctx *context.Context) {
+	subject := ctx.Params("digest")
+	if digest.Digest(subject).Validate() != nil {
+		apiErrorDefined(ctx, errDigestInvalid)
+		return
+	}
+
+	artifactType := ctx.FormTrim("artifactType")
+
+	pds, err := container_service.GetReferrers(ctx, ctx.Package.Owner.ID, ctx.Params("image"), subject, artifactType)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	index := oci.Index{
+		Versioned: specs.Versioned{
+			SchemaVersion: 2,
+		},
+		MediaType: oci.MediaTypeImageIndex,
+		Manifests: make([]oci.Descriptor, 0, len(pds)),
+	}
+	for _, pd := range pds {
+		pfd := container_service.GetManifestFile(pd)
+		if pfd == nil {
+			continue
+		}
+
+		metadata := pd.Metadata.(*container_module.Metadata)
+
+		index.Manifests = append(index.Manifests, oci.Descriptor{
+			MediaType:    pfd.Properties.GetByName(container_module.PropertyMediaType),
+			Digest:       digest.Digest(pfd.Properties.GetByName(container_module.PropertyDigest)),
+			Size:         pfd.Blob.Size,
+			ArtifactType: metadata.ArtifactType,
+			Annotations:  metadata.Annotations,
+		})
+	}
+
+	headers := &containerHeaders{
+		ContentType: oci.MediaTypeImageIndex,
+		Status:      http.StatusOK,
+	}
+	if artifactType != "" {
+		headers.Filters = "artifactType"
+	}
+
+	setResponseHeaders(ctx.Resp, headers)
+	if err := json.NewEncoder(ctx.Resp).Encode(index); err != nil {
+		log.Error("JSON encode: %v", err)
+	}
+}
+
 // https://github.com/opencontainers/distribution-spec/blob/main/spec.md#content-discovery
 func GetTagList(ctx *context.Context) {
 	image := ctx.Params("image")
@@ -50,33 +50,62 @@ type manifestCreationInfo struct {
 	Properties map[string]string
 }
 
-func processManifest(ctx context.Context, mci *manifestCreationInfo, buf *packages_module.HashedBuffer) (string, error) {
+// processManifest creates a manifest and returns its digest and the digest of its subject if it refers to another manifest
+func processManifest(ctx context.Context, mci *manifestCreationInfo, buf *packages_module.HashedBuffer) (string, string, error) {
 	var index oci.Index
 	if err := json.NewDecoder(buf).Decode(&index); err != nil {
-		return "", err
+		return "", "", err
 	}
 
 	if index.SchemaVersion != 2 {
-		return "", errUnsupported.WithMessage("Schema version is not supported")
+		return "", "", errUnsupported.WithMessage("Schema version is not supported")
 	}
 
 	if _, err := buf.Seek(0, io.SeekStart); err != nil {
-		return "", err
+		return "", "", err
 	}
 
 	if !isValidMediaType(mci.MediaType) {
 		mci.MediaType = index.MediaType
 		if !isValidMediaType(mci.MediaType) {
-			return "", errManifestInvalid.WithMessage("MediaType not recognized")
+			return "", "", errManifestInvalid.WithMessage("MediaType not recognized")
 		}
 	}
 
+	subject := ""
+	if index.Subject != nil {
+		subject = string(index.Subject.Digest)
+	}
+
+	var manifestDigest string
+	var err error
 	if isImageManifestMediaType(mci.MediaType) {
-		return processImageManifest(ctx, mci, buf)
+		manifestDigest, err = processImageManifest(ctx, mci, buf)
 	} else if isImageIndexMediaType(mci.MediaType) {
-		return processImageManifestIndex(ctx, mci, buf)
+		manifestDigest, err = processImageManifestIndex(ctx, mci, buf)
+	} else {
+		return "", "", errManifestInvalid
+	}
+	if err != nil {
+		return "", "", err
+	}
+	return manifestDigest, subject, nil
+}
+
+// setSubjectMetadata adds the information about the subject of a manifest which is needed to list it as referrer
+// https://github.com/opencontainers/distribution-spec/blob/main/spec.md#listing-referrers
+func setSubjectMetadata(metadata *container_module.Metadata, subject *oci.Descriptor, artifactType string, annotations map[string]string) error {
+	if subject == nil {
+		return nil
+	}
+	if subject.Digest.Validate() != nil {
+		return errManifestInvalid.WithMessage("Subject digest is invalid")
 	}
-	return "", errManifestInvalid
+
+	metadata.Subject = string(subject.Digest)
+	metadata.ArtifactType = artifactType
+	metadata.Annotations = annotations
+	return nil
 }
 
 func processImageManifest(ctx context.Context, mci *manifestCreationInfo, buf *packages_module.HashedBuffer) (string, error) {
@@ -115,6 +144,20 @@ func processImageManifest(ctx context.Context, mci *manifestCreationInfo, buf *p
 
 		metadata, err := container_module.ParseImageConfig(manifest.Config.MediaType, configReader)
 		if err != nil {
+			// the config of an artifact doesn't need to be an image config
+			if manifest.Subject == nil && manifest.ArtifactType == "" {
+				return err
+			}
+			metadata = &container_module.Metadata{
+				Type: container_module.TypeOCI,
+			}
+		}
+
+		artifactType := manifest.ArtifactType
+		if artifactType == "" {
+			artifactType = manifest.Config.MediaType
+		}
+		if err := setSubjectMetadata(metadata, manifest.Subject, artifactType, manifest.Annotations); err != nil {
 			return err
 		}
 
@@ -145,7 +188,7 @@ func processImageManifest(ctx context.Context, mci *manifestCreationInfo, buf *p
 			})
 		}
 
-		pv, err := createPackageAndVersion(ctx, mci, metadata)
+		pv, err := createPackageAndVersion(ctx, mci, metadata, digestFromHashSummer(buf))
 		if err != nil {
 			return err
 		}
@@ -260,7 +303,11 @@ func processImageManifestIndex(ctx context.Context, mci *manifestCreationInfo, b
 			})
 		}
 
-		pv, err := createPackageAndVersion(ctx, mci, metadata)
+		if err := setSubjectMetadata(metadata, index.Subject, index.ArtifactType, index.Annotations); err != nil {
+			return err
+		}
+
+		pv, err := createPackageAndVersion(ctx, mci, metadata, digestFromHashSummer(buf))
 		if err != nil {
 			return err
 		}
@@ -311,7 +358,7 @@ func notifyPackageCreate(ctx context.Context, doer *user_model.User, pv *package
 	return nil
 }
 
-func createPackageAndVersion(ctx context.Context, mci *manifestCreationInfo, metadata *container_module.Metadata) (*packages_model.PackageVersion, error) {
+func createPackageAndVersion(ctx context.Context, mci *manifestCreationInfo, metadata *container_module.Metadata, manifestDigest string) (*packages_model.PackageVersion, error) {
 	created := true
 	p := &packages_model.Package{
 		OwnerID:   mci.Owner.ID,
@@ -353,6 +400,10 @@ func createPackageAndVersion(ctx context.Context, mci *manifestCreationInfo, met
 	var pv *packages_model.PackageVersion
 	if pv, err = packages_model.GetOrInsertVersion(ctx, _pv); err != nil {
 		if err == packages_model.ErrDuplicatePackageVersion {
+			if err := checkManifestReplaceable(ctx, pv, manifestDigest); err != nil {
+				return nil, err
+			}
+
 			if err := packages_service.DeletePackageVersionAndReferences(ctx, pv); err != nil {
 				return nil, err
 			}
@@ -386,10 +437,35 @@ func createPackageAndVersion(ctx context.Context, mci *manifestCreationInfo, met
 			return nil, err
 		}
 	}
+	if metadata.Subject != "" {
+		if _, err := packages_model.InsertProperty(ctx, packages_model.PropertyTypeVersion, pv.ID, container_module.PropertyManifestSubject, metadata.Subject); err != nil {
+			log.Error("Error setting package version property: %v", err)
+			return nil, err
+		}
+	}
 
 	return pv, nil
 }
 
+// checkManifestReplaceable checks if the manifest of an existing version may be replaced by the manifest with the given digest.
+// Pushing the same manifest again is always allowed, a tag of a locked or immutable version can't point to another manifest.
+func checkManifestReplaceable(ctx context.Context, pv *packages_model.PackageVersion, manifestDigest string) error {
+	pf, err := packages_model.GetFileForVersionByName(ctx, pv.ID, container_model.ManifestFilename, packages_model.EmptyFileKey)
+	if err != nil && err != packages_model.ErrPackageFileNotExist {
+		return err
+	}
+	if pf != nil {
+		pb, err := packages_model.GetBlobByID(ctx, pf.BlobID)
+		if err != nil {
+			return err
+		}
+		if "sha256:"+pb.HashSHA256 == manifestDigest {
+			return nil
+		}
+	}
+	return packages_service.CheckVersionRemovable(ctx, pv)
+}
+
 type blobReference struct {
 	Digest       digest.Digest
 	MediaType    string
@@ -0,0 +1,319 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package container
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	packages_model "code.gitea.io/gitea/models/packages"
+	container_model "code.gitea.io/gitea/models/packages/container"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/json"
+	"code.gitea.io/gitea/modules/log"
+	packages_module "code.gitea.io/gitea/modules/packages"
+	container_module "code.gitea.io/gitea/modules/packages/container"
+	packages_service "code.gitea.io/gitea/services/packages"
+	packages_proxy "code.gitea.io/gitea/services/packages/proxy"
+
+	digest "github.com/opencontainers/go-digest"
+	oci "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// the manifests of an upstream registry are only cached if they are supported by the registry of the owner
+var upstreamManifestMediaTypes = strings.Join([]string{
+	oci.MediaTypeImageManifest,
+	oci.MediaTypeImageIndex,
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+}, ", ")
+
+var challengeParamPattern = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// upstreamRegistry requests the images of an upstream registry, it obtains a token if the registry requires one
+// https://distribution.github.io/distribution/spec/auth/token/
+type upstreamRegistry struct {
+	client *packages_proxy.Client
+	owner  *user_model.User
+	image  string
+	token  string
+}
+
+func getUpstreamRegistry(ctx context.Context, owner *user_model.User, image string) (*upstreamRegistry, error) {
+	client, err := packages_proxy.GetClient(ctx, owner.ID, packages_model.TypeContainer)
+	if err != nil || client == nil {
+		return nil, err
+	}
+	return &upstreamRegistry{
+		client: client,
+		owner:  owner,
+		image:  image,
+	}, nil
+}
+
+func (r *upstreamRegistry) request(ctx context.Context, method, escapedPath, accept string) (*http.Response, error) {
+	newRequest := func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, method, r.client.URL(escapedPath), nil)
+		if err != nil {
+			return nil, err
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		if r.token != "" {
+			req.Header.Set("Authorization", "Bearer "+r.token)
+		}
+		return req, nil
+	}
+
+	req, err := newRequest()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized && r.token == "" {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		if err := r.authenticate(ctx, challenge); err != nil {
+			return nil, err
+		}
+
+		if req, err = newRequest(); err != nil {
+			return nil, err
+		}
+		if resp, err = r.client.Do(req); err != nil {
+			return nil, err
+		}
+	}
+	return resp, packages_proxy.CheckResponse(resp)
+}
+
+// authenticate obtains a token to pull the image from the realm of a challenge
+func (r *upstreamRegistry) authenticate(ctx context.Context, challenge string) error {
+	if !strings.HasPrefix(strings.ToLower(challenge), "bearer ") {
+		return &packages_proxy.UpstreamError{Err: fmt.Errorf("unsupported authentication challenge %q", challenge)}
+	}
+	params := map[string]string{}
+	for _, match := range challengeParamPattern.FindAllStringSubmatch(challenge, -1) {
+		params[strings.ToLower(match[1])] = match[2]
+	}
+	realm, err := url.Parse(params["realm"])
+	if err != nil || (realm.Scheme != "http" && realm.Scheme != "https") {
+		return &packages_proxy.UpstreamError{Err: fmt.Errorf("invalid authentication realm %q", params["realm"])}
+	}
+
+	query := realm.Query()
+	if params["service"] != "" {
+		query.Set("service", params["service"])
+	}
+	query.Set("scope", "repository:"+r.image+":pull")
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return err
+	}
+	// the credentials of the proxy are exchanged for a token by the realm of the registry
+	r.client.SetCredentials(req)
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	if err := packages_proxy.CheckResponse(resp); err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return &packages_proxy.UpstreamError{Err: err}
+	}
+	r.token = token.Token
+	if r.token == "" {
+		r.token = token.AccessToken
+	}
+	if r.token == "" {
+		return &packages_proxy.UpstreamError{Err: errors.New("no token has been issued")}
+	}
+	return nil
+}
+
+// manifestDigest returns the digest of a manifest in the upstream registry
+func (r *upstreamRegistry) manifestDigest(ctx context.Context, reference string) (string, error) {
+	resp, err := r.request(ctx, http.MethodHead, "v2/"+r.image+"/manifests/"+url.PathEscape(reference), upstreamManifestMediaTypes)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	return resp.Header.Get("Docker-Content-Digest"), nil
+}
+
+// cacheManifest stores a manifest of the upstream registry with the manifests and the blobs it references
+func (r *upstreamRegistry) cacheManifest(ctx context.Context, reference string) error {
+	resp, err := r.request(ctx, http.MethodGet, "v2/"+r.image+"/manifests/"+url.PathEscape(reference), upstreamManifestMediaTypes)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	maxSize := maxManifestSize + 1
+	buf, err := packages_module.CreateHashedBufferFromReaderWithSize(&io.LimitedReader{R: resp.Body, N: int64(maxSize)}, maxSize)
+	if err != nil {
+		return &packages_proxy.UpstreamError{Err: err}
+	}
+	defer buf.Close()
+
+	if buf.Size() > maxManifestSize {
+		return &packages_proxy.UpstreamError{Err: errors.New("manifest exceeds maximum size")}
+	}
+	isTagged := digest.Digest(reference).Validate() != nil
+	if !isTagged && digestFromHashSummer(buf) != reference {
+		return &packages_proxy.UpstreamError{Err: fmt.Errorf("digest of manifest %s mismatch", reference)}
+	}
+
+	var manifest struct {
+		MediaType string           `json:"mediaType"`
+		Config    oci.Descriptor   `json:"config"`
+		Layers    []oci.Descriptor `json:"layers"`
+		Manifests []oci.Descriptor `json:"manifests"`
+	}
+	if err := json.NewDecoder(buf).Decode(&manifest); err != nil {
+		return &packages_proxy.UpstreamError{Err: err}
+	}
+	if _, err := buf.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	mediaType := resp.Header.Get("Content-Type")
+	if !isValidMediaType(mediaType) {
+		mediaType = manifest.MediaType
+	}
+	if isImageIndexMediaType(mediaType) {
+		// the manifests of all the platforms of the index are cached, they must be stored with it
+		for _, m := range manifest.Manifests {
+			if _, err := container_model.GetContainerBlob(ctx, &container_model.BlobSearchOptions{
+				OwnerID:    r.owner.ID,
+				Image:      r.image,
+				Digest:     string(m.Digest),
+				IsManifest: true,
+			}); err == nil {
+				continue
+			} else if err != container_model.ErrContainerBlobNotExist {
+				return err
+			}
+			if err := r.cacheManifest(ctx, string(m.Digest)); err != nil {
+				return err
+			}
+		}
+	} else if isImageManifestMediaType(mediaType) {
+		for _, d := range append([]oci.Descriptor{manifest.Config}, manifest.Layers...) {
+			if err := r.cacheBlob(ctx, string(d.Digest)); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, _, err = processManifest(ctx, &manifestCreationInfo{
+		MediaType: mediaType,
+		Owner:     r.owner,
+		Creator:   r.owner,
+		Image:     r.image,
+		Reference: reference,
+		IsTagged:  isTagged,
+	}, buf)
+	var namedError *namedError
+	if errors.As(err, &namedError) {
+		// the manifest of the upstream registry isn't supported
+		return &packages_proxy.UpstreamError{Err: err}
+	}
+	return err
+}
+
+// cacheBlob stores a blob of the upstream registry if it isn't stored yet
+func (r *upstreamRegistry) cacheBlob(ctx context.Context, blobDigest string) error {
+	if digest.Digest(blobDigest).Validate() != nil {
+		return &packages_proxy.UpstreamError{Err: fmt.Errorf("invalid digest %q", blobDigest)}
+	}
+	if _, err := container_model.GetContainerBlob(ctx, &container_model.BlobSearchOptions{
+		OwnerID: r.owner.ID,
+		Image:   r.image,
+		Digest:  blobDigest,
+	}); err == nil {
+		return nil
+	} else if err != container_model.ErrContainerBlobNotExist {
+		return err
+	}
+
+	resp, err := r.request(ctx, http.MethodGet, "v2/"+r.image+"/blobs/"+blobDigest, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	buf, err := packages_proxy.ReadBody(resp, packages_service.TypeSizeLimit(packages_model.TypeContainer))
+	if err != nil {
+		return err
+	}
+	defer buf.Close()
+
+	if digestFromHashSummer(buf) != blobDigest {
+		return &packages_proxy.UpstreamError{Err: fmt.Errorf("digest of blob %s mismatch", blobDigest)}
+	}
+
+	_, err = saveAsPackageBlob(ctx, buf, &packages_service.PackageCreationInfo{
+		PackageInfo: packages_service.PackageInfo{
+			Owner: r.owner,
+			Name:  r.image,
+		},
+		Creator: r.owner,
+	})
+	return err
+}
+
+// cacheUpstreamManifest stores a manifest of the upstream registry of the owner if it isn't stored yet, or if its tag has been moved.
+// It returns false if the images of the owner aren't proxied, or if the tag is stored and the upstream registry is unavailable.
+func cacheUpstreamManifest(ctx context.Context, owner *user_model.User, image, reference string, stored *packages_model.PackageFileDescriptor) (bool, error) {
+	r, err := getUpstreamRegistry(ctx, owner, image)
+	if err != nil || r == nil {
+		return false, err
+	}
+
+	if stored != nil {
+		// the tags of the upstream registry can be moved to other manifests
+		upstreamDigest, err := r.manifestDigest(ctx, reference)
+		if err != nil {
+			if !errors.Is(err, packages_proxy.ErrUpstreamNotExist) {
+				log.Warn("Unable to check the tag %s of the image %s in the upstream registry: %v", reference, image, err)
+			}
+			return false, nil
+		}
+		if upstreamDigest == "" || upstreamDigest == stored.Properties.GetByName(container_module.PropertyDigest) {
+			return false, nil
+		}
+	}
+
+	return true, r.cacheManifest(ctx, reference)
+}
+
+// cacheUpstreamBlob stores a blob of the upstream registry of the owner.
+// It returns false if the images of the owner aren't proxied.
+func cacheUpstreamBlob(ctx context.Context, owner *user_model.User, image, blobDigest string) (bool, error) {
+	r, err := getUpstreamRegistry(ctx, owner, image)
+	if err != nil || r == nil {
+		return false, err
+	}
+	return true, r.cacheBlob(ctx, blobDigest)
+}
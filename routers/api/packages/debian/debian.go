@@ -61,6 +61,7 @@ func GetRepositoryFile(ctx *context.Context) {
 
 	s, u, pf, err := packages_service.GetFileStreamByPackageVersion(
 		ctx,
+		ctx.Doer,
 		pv,
 		&packages_service.PackageFileInfo{
 			Filename:     ctx.Params("filename"),
@@ -106,7 +107,7 @@ func GetRepositoryFileByHash(ctx *context.Context) {
 		return
 	}
 
-	s, u, pf, err := packages_service.GetPackageFileStream(ctx, pfs[0])
+	s, u, pf, err := packages_service.GetPackageFileStream(ctx, ctx.Doer, pfs[0])
 	if err != nil {
 		if errors.Is(err, util.ErrNotExist) {
 			apiError(ctx, http.StatusNotFound, err)
@@ -212,6 +213,7 @@ func DownloadPackageFile(ctx *context.Context) {
 
 	s, u, pf, err := packages_service.GetFileStreamByPackageNameAndVersion(
 		ctx,
+		ctx.Doer,
 		&packages_service.PackageInfo{
 			Owner:       ctx.Package.Owner,
 			PackageType: packages_model.TypeDebian,
@@ -256,6 +258,10 @@ func DeletePackageFile(ctx *context.Context) {
 			return err
 		}
 
+		if err := packages_service.CheckVersionRemovable(ctx, pv); err != nil {
+			return err
+		}
+
 		pf, err := packages_model.GetFileForVersionByName(
 			ctx,
 			pv.ID,
@@ -290,6 +296,8 @@ func DeletePackageFile(ctx *context.Context) {
 	if err != nil {
 		if errors.Is(err, util.ErrNotExist) {
 			apiError(ctx, http.StatusNotFound, err)
+		} else if errors.Is(err, util.ErrPermissionDenied) {
+			apiError(ctx, http.StatusForbidden, err)
 		} else {
 			apiError(ctx, http.StatusInternalServerError, err)
 		}
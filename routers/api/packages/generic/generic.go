@@ -13,6 +13,7 @@ import (
 	packages_model "code.gitea.io/gitea/models/packages"
 	"code.gitea.io/gitea/modules/log"
 	packages_module "code.gitea.io/gitea/modules/packages"
+	"code.gitea.io/gitea/modules/util"
 	"code.gitea.io/gitea/routers/api/packages/helper"
 	"code.gitea.io/gitea/services/context"
 	packages_service "code.gitea.io/gitea/services/packages"
@@ -33,6 +34,7 @@ func apiError(ctx *context.Context, status int, obj any) {
 func DownloadPackageFile(ctx *context.Context) {
 	s, u, pf, err := packages_service.GetFileStreamByPackageNameAndVersion(
 		ctx,
+		ctx.Doer,
 		&packages_service.PackageInfo{
 			Owner:       ctx.Package.Owner,
 			PackageType: packages_model.TypeGeneric,
@@ -159,6 +161,10 @@ func DeletePackage(ctx *context.Context) {
 			apiError(ctx, http.StatusNotFound, err)
 			return
 		}
+		if errors.Is(err, util.ErrPermissionDenied) {
+			apiError(ctx, http.StatusForbidden, err)
+			return
+		}
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
 	}
@@ -168,18 +174,13 @@ func DeletePackage(ctx *context.Context) {
 
 // DeletePackageFile deletes the specific file of a generic package.
 func DeletePackageFile(ctx *context.Context) {
-	pv, pf, err := func() (*packages_model.PackageVersion, *packages_model.PackageFile, error) {
+	pf, err := func() (*packages_model.PackageFile, error) {
 		pv, err := packages_model.GetVersionByNameAndVersion(ctx, ctx.Package.Owner.ID, packages_model.TypeGeneric, ctx.Params("packagename"), ctx.Params("packageversion"))
 		if err != nil {
-			return nil, nil, err
-		}
-
-		pf, err := packages_model.GetFileForVersionByName(ctx, pv.ID, ctx.Params("filename"), packages_model.EmptyFileKey)
-		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
 
-		return pv, pf, nil
+		return packages_model.GetFileForVersionByName(ctx, pv.ID, ctx.Params("filename"), packages_model.EmptyFileKey)
 	}()
 	if err != nil {
 		if err == packages_model.ErrPackageNotExist || err == packages_model.ErrPackageFileNotExist {
@@ -190,22 +191,13 @@ func DeletePackageFile(ctx *context.Context) {
 		return
 	}
 
-	pfs, err := packages_model.GetFilesByVersionID(ctx, pv.ID)
-	if err != nil {
-		apiError(ctx, http.StatusInternalServerError, err)
-		return
-	}
-
-	if len(pfs) == 1 {
-		if err := packages_service.RemovePackageVersion(ctx, ctx.Doer, pv); err != nil {
-			apiError(ctx, http.StatusInternalServerError, err)
-			return
-		}
-	} else {
-		if err := packages_service.DeletePackageFile(ctx, pf); err != nil {
-			apiError(ctx, http.StatusInternalServerError, err)
+	if err := packages_service.RemovePackageFileAndVersionIfUnreferenced(ctx, ctx.Doer, pf); err != nil {
+		if errors.Is(err, util.ErrPermissionDenied) {
+			apiError(ctx, http.StatusForbidden, err)
 			return
 		}
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
 	}
 
 	ctx.Status(http.StatusNoContent)
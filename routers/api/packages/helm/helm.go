@@ -124,6 +124,7 @@ func DownloadPackageFile(ctx *context.Context) {
 
 	s, u, pf, err := packages_service.GetFileStreamByPackageVersion(
 		ctx,
+		ctx.Doer,
 		pvs[0],
 		&packages_service.PackageFileInfo{
 			Filename: filename,
@@ -198,10 +199,12 @@ func UploadPackage(ctx *context.Context) {
 		},
 	)
 	if err != nil {
-		switch err {
-		case packages_model.ErrDuplicatePackageVersion:
+		switch {
+		case err == packages_model.ErrDuplicatePackageVersion:
 			apiError(ctx, http.StatusConflict, err)
-		case packages_service.ErrQuotaTotalCount, packages_service.ErrQuotaTypeSize, packages_service.ErrQuotaTotalSize:
+		case err == packages_service.ErrQuotaTotalCount, err == packages_service.ErrQuotaTypeSize, err == packages_service.ErrQuotaTotalSize:
+			apiError(ctx, http.StatusForbidden, err)
+		case errors.Is(err, util.ErrPermissionDenied):
 			apiError(ctx, http.StatusForbidden, err)
 		default:
 			apiError(ctx, http.StatusInternalServerError, err)
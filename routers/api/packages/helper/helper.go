@@ -4,6 +4,7 @@
 package helper
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -12,7 +13,10 @@ import (
 	packages_model "code.gitea.io/gitea/models/packages"
 	"code.gitea.io/gitea/modules/log"
 	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/util"
 	"code.gitea.io/gitea/services/context"
+	packages_service "code.gitea.io/gitea/services/packages"
+	packages_proxy "code.gitea.io/gitea/services/packages/proxy"
 )
 
 // LogAndProcessError logs an error and calls a custom callback with the processed error message.
@@ -61,3 +65,19 @@ func ServePackageFile(ctx *context.Context, s io.ReadSeekCloser, u *url.URL, pf
 
 	ctx.ServeContent(s, opts)
 }
+
+// UpstreamErrorStatus returns the status of the response to a request of a package which couldn't be fetched from an upstream registry
+func UpstreamErrorStatus(err error) int {
+	var upstreamErr *packages_proxy.UpstreamError
+	switch {
+	case errors.Is(err, util.ErrNotExist):
+		return http.StatusNotFound
+	case err == packages_service.ErrQuotaTotalCount, err == packages_service.ErrQuotaTypeSize, err == packages_service.ErrQuotaTotalSize:
+		return http.StatusForbidden
+	case errors.As(err, &upstreamErr), errors.Is(err, util.ErrInvalidArgument):
+		log.Warn("Unable to fetch a package from an upstream registry: %v", err)
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package helper
+
+import (
+	packages_model "code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/models/perm"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/services/context"
+)
+
+// RegistrySources are the owners whose packages are served by the registry of the owner of a request, by order of priority
+type RegistrySources struct {
+	// the owners whose packages are served from Forgejo
+	Hosted []*user_model.User
+	// the owners whose upstream registries are proxied, they are only requested if no hosted package matches
+	Proxied []*user_model.User
+}
+
+// GetRegistrySources returns the sources of the registry of the owner of a request.
+// If the owner has a virtual registry for the package type, the members which the user can read are added after the owner.
+func GetRegistrySources(ctx *context.Context, packageType packages_model.Type) (*RegistrySources, error) {
+	sources := &RegistrySources{
+		Hosted:  []*user_model.User{ctx.Package.Owner},
+		Proxied: []*user_model.User{ctx.Package.Owner},
+	}
+
+	pvr, err := packages_model.GetEnabledVirtualRegistry(ctx, ctx.Package.Owner.ID, packageType)
+	if err != nil || pvr == nil {
+		return sources, err
+	}
+
+	members, err := user_model.GetUsersByIDs(ctx, pvr.MemberIDs)
+	if err != nil {
+		return nil, err
+	}
+	membersByID := make(map[int64]*user_model.User, len(members))
+	for _, member := range members {
+		membersByID[member.ID] = member
+	}
+
+	for _, id := range pvr.MemberIDs {
+		member, ok := membersByID[id]
+		if !ok || member.ID == ctx.Package.Owner.ID {
+			continue
+		}
+		accessMode, err := context.PackageOwnerAccessMode(ctx.Base, member, ctx.Doer)
+		if err != nil {
+			return nil, err
+		}
+		if accessMode < perm.AccessModeRead {
+			continue
+		}
+
+		sources.Hosted = append(sources.Hosted, member)
+		if pvr.UseProxies {
+			sources.Proxied = append(sources.Proxied, member)
+		}
+	}
+	return sources, nil
+}
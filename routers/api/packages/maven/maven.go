@@ -4,6 +4,7 @@
 package maven
 
 import (
+	std_ctx "context"
 	"crypto/md5"
 	"crypto/sha1"
 	"crypto/sha256"
@@ -11,6 +12,7 @@ import (
 	"encoding/hex"
 	"encoding/xml"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"path/filepath"
@@ -20,13 +22,16 @@ import (
 	"strings"
 
 	packages_model "code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/modules/container"
 	"code.gitea.io/gitea/modules/json"
 	"code.gitea.io/gitea/modules/log"
 	packages_module "code.gitea.io/gitea/modules/packages"
 	maven_module "code.gitea.io/gitea/modules/packages/maven"
+	"code.gitea.io/gitea/modules/util"
 	"code.gitea.io/gitea/routers/api/packages/helper"
 	"code.gitea.io/gitea/services/context"
 	packages_service "code.gitea.io/gitea/services/packages"
+	packages_proxy "code.gitea.io/gitea/services/packages/proxy"
 )
 
 const (
@@ -88,37 +93,68 @@ func serveMavenMetadata(ctx *context.Context, params parameters) {
 	// /com/foo/project/maven-metadata.xml[.md5/.sha1/.sha256/.sha512]
 
 	packageName := params.GroupID + "-" + params.ArtifactID
-	pvs, err := packages_model.GetVersionsByPackageName(ctx, ctx.Package.Owner.ID, packages_model.TypeMaven, packageName)
+
+	sources, err := helper.GetRegistrySources(ctx, packages_model.TypeMaven)
 	if err != nil {
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
 	}
-	if len(pvs) == 0 {
-		apiError(ctx, http.StatusNotFound, packages_model.ErrPackageNotExist)
-		return
+
+	var pds []*packages_model.PackageDescriptor
+	versions := make(container.Set[string])
+	for _, owner := range sources.Hosted {
+		pvs, err := packages_model.GetVersionsByPackageName(ctx, owner.ID, packages_model.TypeMaven, packageName)
+		if err != nil {
+			apiError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+
+		ownerPds, err := packages_model.GetPackageDescriptors(ctx, pvs)
+		if err != nil {
+			apiError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+
+		// a version is served by the first owner which has it
+		for _, pd := range ownerPds {
+			if versions.Add(pd.Version.Version) {
+				pds = append(pds, pd)
+			}
+		}
 	}
 
-	pds, err := packages_model.GetPackageDescriptors(ctx, pvs)
-	if err != nil {
-		apiError(ctx, http.StatusInternalServerError, err)
-		return
+	var metadata *MetadataResponse
+	if len(pds) > 0 {
+		sort.Slice(pds, func(i, j int) bool {
+			// Maven and Gradle order packages by their creation timestamp and not by their version string
+			return pds[i].Version.CreatedUnix < pds[j].Version.CreatedUnix
+		})
+
+		metadata = createMetadataResponse(pds)
+
+		latest := pds[len(pds)-1]
+		ctx.Resp.Header().Set("Last-Modified", latest.Version.CreatedUnix.Format(http.TimeFormat))
 	}
 
-	sort.Slice(pds, func(i, j int) bool {
-		// Maven and Gradle order packages by their creation timestamp and not by their version string
-		return pds[i].Version.CreatedUnix < pds[j].Version.CreatedUnix
-	})
+	// the versions of the upstream registries are listed if the packages are proxied, even if they haven't been cached yet
+	for _, owner := range sources.Proxied {
+		if upstream := getUpstreamMetadataResponse(ctx, owner); upstream != nil {
+			metadata = mergeMetadataResponse(upstream, metadata)
+			ctx.Resp.Header().Del("Last-Modified")
+		}
+	}
+	if metadata == nil {
+		apiError(ctx, http.StatusNotFound, packages_model.ErrPackageNotExist)
+		return
+	}
 
-	xmlMetadata, err := xml.Marshal(createMetadataResponse(pds))
+	xmlMetadata, err := xml.Marshal(metadata)
 	if err != nil {
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
 	}
 	xmlMetadataWithHeader := append([]byte(xml.Header), xmlMetadata...)
 
-	latest := pds[len(pds)-1]
-	ctx.Resp.Header().Set("Last-Modified", latest.Version.CreatedUnix.Format(http.TimeFormat))
-
 	ext := strings.ToLower(filepath.Ext(params.Filename))
 	if isChecksumExtension(ext) {
 		var hash []byte
@@ -149,16 +185,6 @@ func serveMavenMetadata(ctx *context.Context, params parameters) {
 func servePackageFile(ctx *context.Context, params parameters, serveContent bool) {
 	packageName := params.GroupID + "-" + params.ArtifactID
 
-	pv, err := packages_model.GetVersionByNameAndVersion(ctx, ctx.Package.Owner.ID, packages_model.TypeMaven, packageName, params.Version)
-	if err != nil {
-		if err == packages_model.ErrPackageNotExist {
-			apiError(ctx, http.StatusNotFound, err)
-		} else {
-			apiError(ctx, http.StatusInternalServerError, err)
-		}
-		return
-	}
-
 	filename := params.Filename
 
 	ext := strings.ToLower(filepath.Ext(filename))
@@ -166,19 +192,40 @@ func servePackageFile(ctx *context.Context, params parameters, serveContent bool
 		filename = filename[:len(filename)-len(ext)]
 	}
 
-	pf, err := packages_model.GetFileForVersionByName(ctx, pv.ID, filename, packages_model.EmptyFileKey)
+	sources, err := helper.GetRegistrySources(ctx, packages_model.TypeMaven)
 	if err != nil {
-		if err == packages_model.ErrPackageFileNotExist {
-			apiError(ctx, http.StatusNotFound, err)
-		} else {
-			apiError(ctx, http.StatusInternalServerError, err)
-		}
+		apiError(ctx, http.StatusInternalServerError, err)
 		return
 	}
 
-	pb, err := packages_model.GetBlobByID(ctx, pf.BlobID)
+	var pf *packages_model.PackageFile
+	var pb *packages_model.PackageBlob
+	for _, owner := range sources.Hosted {
+		pf, pb, err = getPackageFile(ctx, owner.ID, packageName, params.Version, filename)
+		if !errors.Is(err, util.ErrNotExist) {
+			break
+		}
+	}
+	if errors.Is(err, util.ErrNotExist) && !params.IsMeta {
+		for _, owner := range sources.Proxied {
+			cached, cacheErr := cacheUpstreamPackageFile(ctx, owner, params, filename)
+			if !cached || errors.Is(cacheErr, packages_proxy.ErrUpstreamNotExist) {
+				continue
+			}
+			if cacheErr != nil {
+				upstreamError(ctx, cacheErr)
+				return
+			}
+			pf, pb, err = getPackageFile(ctx, owner.ID, packageName, params.Version, filename)
+			break
+		}
+	}
 	if err != nil {
-		apiError(ctx, http.StatusInternalServerError, err)
+		if err == packages_model.ErrPackageNotExist || err == packages_model.ErrPackageFileNotExist {
+			apiError(ctx, http.StatusNotFound, err)
+		} else {
+			apiError(ctx, http.StatusInternalServerError, err)
+		}
 		return
 	}
 
@@ -215,7 +262,7 @@ func servePackageFile(ctx *context.Context, params parameters, serveContent bool
 		return
 	}
 
-	s, u, _, err := packages_service.GetPackageBlobStream(ctx, pf, pb)
+	s, u, _, err := packages_service.GetPackageBlobStream(ctx, ctx.Doer, pf, pb)
 	if err != nil {
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
@@ -226,6 +273,24 @@ func servePackageFile(ctx *context.Context, params parameters, serveContent bool
 	helper.ServePackageFile(ctx, s, u, pf, opts)
 }
 
+func getPackageFile(ctx std_ctx.Context, ownerID int64, packageName, packageVersion, filename string) (*packages_model.PackageFile, *packages_model.PackageBlob, error) {
+	pv, err := packages_model.GetVersionByNameAndVersion(ctx, ownerID, packages_model.TypeMaven, packageName, packageVersion)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pf, err := packages_model.GetFileForVersionByName(ctx, pv.ID, filename, packages_model.EmptyFileKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pb, err := packages_model.GetBlobByID(ctx, pf.BlobID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pf, pb, nil
+}
+
 // UploadPackageFile adds a file to the package. If the package does not exist, it gets created.
 func UploadPackageFile(ctx *context.Context) {
 	params, err := extractPathParameters(ctx)
@@ -308,71 +373,75 @@ func UploadPackageFile(ctx *context.Context) {
 		return
 	}
 
+	if err := addPackageFile(ctx, pvci, params, buf); err != nil {
+		switch {
+		case err == packages_model.ErrDuplicatePackageFile:
+			apiError(ctx, http.StatusConflict, err)
+		case err == packages_service.ErrQuotaTotalCount, err == packages_service.ErrQuotaTypeSize, err == packages_service.ErrQuotaTotalSize:
+			apiError(ctx, http.StatusForbidden, err)
+		case errors.Is(err, util.ErrPermissionDenied):
+			apiError(ctx, http.StatusForbidden, err)
+		case errors.Is(err, util.ErrInvalidArgument):
+			apiError(ctx, http.StatusBadRequest, err)
+		default:
+			apiError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	ctx.Status(http.StatusCreated)
+}
+
+// addPackageFile adds a file to a package version, the metadata of the version is read from its pom file
+func addPackageFile(ctx std_ctx.Context, pvci *packages_service.PackageCreationInfo, params parameters, buf *packages_module.HashedBuffer) error {
 	pfci := &packages_service.PackageFileCreationInfo{
 		PackageFileInfo: packages_service.PackageFileInfo{
 			Filename: params.Filename,
 		},
-		Creator:           ctx.Doer,
+		Creator:           pvci.Creator,
 		Data:              buf,
 		IsLead:            false,
 		OverwriteExisting: params.IsMeta,
 	}
 
 	// If it's the package pom file extract the metadata
-	if ext == extensionPom {
+	if filepath.Ext(params.Filename) == extensionPom {
 		pfci.IsLead = true
 
 		var err error
 		pvci.Metadata, err = maven_module.ParsePackageMetaData(buf)
 		if err != nil {
-			apiError(ctx, http.StatusBadRequest, err)
-			return
+			return fmt.Errorf("%w: %v", util.ErrInvalidArgument, err)
 		}
 
 		if pvci.Metadata != nil {
 			pv, err := packages_model.GetVersionByNameAndVersion(ctx, pvci.Owner.ID, pvci.PackageType, pvci.Name, pvci.Version)
 			if err != nil && err != packages_model.ErrPackageNotExist {
-				apiError(ctx, http.StatusInternalServerError, err)
-				return
+				return err
 			}
 			if pv != nil {
 				raw, err := json.Marshal(pvci.Metadata)
 				if err != nil {
-					apiError(ctx, http.StatusInternalServerError, err)
-					return
+					return err
 				}
 				pv.MetadataJSON = string(raw)
 				if err := packages_model.UpdateVersion(ctx, pv); err != nil {
-					apiError(ctx, http.StatusInternalServerError, err)
-					return
+					return err
 				}
 			}
 		}
 
 		if _, err := buf.Seek(0, io.SeekStart); err != nil {
-			apiError(ctx, http.StatusInternalServerError, err)
-			return
+			return err
 		}
 	}
 
-	_, _, err = packages_service.CreatePackageOrAddFileToExisting(
+	_, _, err := packages_service.CreatePackageOrAddFileToExisting(
 		ctx,
 		pvci,
 		pfci,
 	)
-	if err != nil {
-		switch err {
-		case packages_model.ErrDuplicatePackageFile:
-			apiError(ctx, http.StatusConflict, err)
-		case packages_service.ErrQuotaTotalCount, packages_service.ErrQuotaTypeSize, packages_service.ErrQuotaTotalSize:
-			apiError(ctx, http.StatusForbidden, err)
-		default:
-			apiError(ctx, http.StatusInternalServerError, err)
-		}
-		return
-	}
-
-	ctx.Status(http.StatusCreated)
+	return err
 }
 
 func isChecksumExtension(ext string) bool {
@@ -0,0 +1,129 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package maven
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/url"
+	"strings"
+
+	packages_model "code.gitea.io/gitea/models/packages"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/routers/api/packages/helper"
+	"code.gitea.io/gitea/services/context"
+	packages_service "code.gitea.io/gitea/services/packages"
+	packages_proxy "code.gitea.io/gitea/services/packages/proxy"
+)
+
+// upstreamPath returns the escaped path of a file in the upstream registry
+func upstreamPath(parts []string) string {
+	escaped := make([]string, 0, len(parts))
+	for _, part := range parts {
+		escaped = append(escaped, url.PathEscape(part))
+	}
+	return strings.Join(escaped, "/")
+}
+
+// getUpstreamMetadataResponse returns the metadata of a package in the upstream registry of an owner.
+// It returns nil if the packages of the owner aren't proxied or if the upstream registry is unavailable.
+func getUpstreamMetadataResponse(ctx *context.Context, owner *user_model.User) *MetadataResponse {
+	client, err := packages_proxy.GetClient(ctx, owner.ID, packages_model.TypeMaven)
+	if err != nil {
+		log.Error("GetClient: %v", err)
+		return nil
+	}
+	if client == nil {
+		return nil
+	}
+
+	// the checksums are computed from the merged metadata
+	parts := strings.Split(ctx.Params("*"), "/")
+	parts[len(parts)-1] = mavenMetadataFile
+
+	u := client.URL(upstreamPath(parts))
+	resp, err := client.Get(ctx, u, nil)
+	if err != nil {
+		if !errors.Is(err, packages_proxy.ErrUpstreamNotExist) {
+			log.Warn("Unable to get the Maven metadata %s from the upstream registry: %v", u, err)
+		}
+		return nil
+	}
+	defer resp.Body.Close()
+
+	metadata := &MetadataResponse{}
+	if err := xml.NewDecoder(resp.Body).Decode(metadata); err != nil {
+		log.Warn("Unable to parse the Maven metadata %s of the upstream registry: %v", u, err)
+		return nil
+	}
+	return metadata
+}
+
+// mergeMetadataResponse adds the versions of a source with a higher priority to the upstream versions, they are considered to be the latest
+func mergeMetadataResponse(upstream, hosted *MetadataResponse) *MetadataResponse {
+	if hosted == nil {
+		return upstream
+	}
+
+	versions := make(map[string]bool, len(upstream.Version))
+	for _, v := range upstream.Version {
+		versions[v] = true
+	}
+	for _, v := range hosted.Version {
+		if !versions[v] {
+			upstream.Version = append(upstream.Version, v)
+		}
+	}
+	upstream.Latest = hosted.Latest
+	if hosted.Release != "" {
+		upstream.Release = hosted.Release
+	}
+	return upstream
+}
+
+// cacheUpstreamPackageFile stores a file of the upstream registry of an owner.
+// It returns false if the packages of the owner aren't proxied.
+func cacheUpstreamPackageFile(ctx *context.Context, owner *user_model.User, params parameters, filename string) (bool, error) {
+	client, err := packages_proxy.GetClient(ctx, owner.ID, packages_model.TypeMaven)
+	if err != nil || client == nil {
+		return false, err
+	}
+
+	// the checksum files aren't cached, they are computed from the file
+	parts := strings.Split(ctx.Params("*"), "/")
+	parts[len(parts)-1] = filename
+
+	buf, err := client.Download(ctx, client.URL(upstreamPath(parts)), packages_service.TypeSizeLimit(packages_model.TypeMaven))
+	if err != nil {
+		return true, err
+	}
+	defer buf.Close()
+
+	params.Filename = filename
+	err = addPackageFile(
+		ctx,
+		&packages_service.PackageCreationInfo{
+			PackageInfo: packages_service.PackageInfo{
+				Owner:       owner,
+				PackageType: packages_model.TypeMaven,
+				Name:        params.GroupID + "-" + params.ArtifactID,
+				Version:     params.Version,
+			},
+			SemverCompatible: false,
+			Creator:          owner,
+		},
+		params,
+		buf,
+	)
+	if err == packages_model.ErrDuplicatePackageFile {
+		// the file has been cached by a concurrent request
+		err = nil
+	}
+	return true, err
+}
+
+func upstreamError(ctx *context.Context, err error) {
+	apiError(ctx, helper.UpstreamErrorStatus(err), err)
+}
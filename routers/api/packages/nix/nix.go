@@ -0,0 +1,211 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package nix
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	packages_model "code.gitea.io/gitea/models/packages"
+	packages_module "code.gitea.io/gitea/modules/packages"
+	nix_module "code.gitea.io/gitea/modules/packages/nix"
+	"code.gitea.io/gitea/modules/util"
+	"code.gitea.io/gitea/routers/api/packages/helper"
+	"code.gitea.io/gitea/services/context"
+	packages_service "code.gitea.io/gitea/services/packages"
+	nix_service "code.gitea.io/gitea/services/packages/nix"
+)
+
+func apiError(ctx *context.Context, status int, obj any) {
+	helper.LogAndProcessError(ctx, status, obj, func(message string) {
+		ctx.PlainText(status, message)
+	})
+}
+
+// CacheInfo describes the binary cache
+// https://nixos.org/manual/nix/stable/store/types/http-binary-cache-store
+func CacheInfo(ctx *context.Context) {
+	ctx.PlainText(http.StatusOK, "StoreDir: "+nix_module.StoreDir+"\nWantMassQuery: 1\nPriority: 50\n")
+}
+
+// DownloadNarInfo serves the narinfo of a store path
+func DownloadNarInfo(ctx *context.Context) {
+	hash := ctx.Params("hash")
+
+	pv, err := nix_service.GetStorePathVersion(ctx, ctx.Package.Owner.ID, hash)
+	if err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			apiError(ctx, http.StatusNotFound, err)
+		} else {
+			apiError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	s, u, pf, err := packages_service.GetFileStreamByPackageVersion(
+		ctx,
+		ctx.Doer,
+		pv,
+		&packages_service.PackageFileInfo{
+			Filename: hash + nix_module.NarInfoExtension,
+		},
+	)
+	if err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			apiError(ctx, http.StatusNotFound, err)
+		} else {
+			apiError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	helper.ServePackageFile(ctx, s, u, pf, &context.ServeHeaderOptions{
+		ContentType:  "text/x-nix-narinfo",
+		Filename:     pf.Name,
+		LastModified: pf.CreatedUnix.AsLocalTime(),
+	})
+}
+
+// UploadNarInfo creates a store path from the narinfo and its previously uploaded NAR file
+func UploadNarInfo(ctx *context.Context) {
+	upload, needToClose, err := ctx.UploadStream()
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if needToClose {
+		defer upload.Close()
+	}
+
+	buf, err := packages_module.CreateHashedBufferFromReader(upload)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	defer buf.Close()
+
+	ni, err := nix_module.ParseNarInfo(buf)
+	if err != nil {
+		if errors.Is(err, util.ErrInvalidArgument) {
+			apiError(ctx, http.StatusBadRequest, err)
+		} else {
+			apiError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	if _, err := buf.Seek(0, io.SeekStart); err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	if _, err := nix_service.UploadNarInfo(ctx, ctx.Doer, ctx.Package.Owner, ctx.Params("hash"), ni, buf); err != nil {
+		switch {
+		case errors.Is(err, packages_model.ErrDuplicatePackageVersion):
+			apiError(ctx, http.StatusConflict, err)
+		case errors.Is(err, packages_service.ErrQuotaTotalCount), errors.Is(err, packages_service.ErrQuotaTypeSize), errors.Is(err, packages_service.ErrQuotaTotalSize):
+			apiError(ctx, http.StatusForbidden, err)
+		case errors.Is(err, util.ErrInvalidArgument):
+			apiError(ctx, http.StatusBadRequest, err)
+		default:
+			apiError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	ctx.Status(http.StatusCreated)
+}
+
+// DeleteNarInfo deletes a store path with its NAR file
+func DeleteNarInfo(ctx *context.Context) {
+	pv, err := nix_service.GetStorePathVersion(ctx, ctx.Package.Owner.ID, ctx.Params("hash"))
+	if err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			apiError(ctx, http.StatusNotFound, err)
+		} else {
+			apiError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	if err := packages_service.RemovePackageVersion(ctx, ctx.Doer, pv); err != nil {
+		if errors.Is(err, util.ErrPermissionDenied) {
+			apiError(ctx, http.StatusForbidden, err)
+			return
+		}
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// DownloadNar serves a NAR file
+func DownloadNar(ctx *context.Context) {
+	filename := ctx.Params("filename")
+	if !nix_module.IsValidNarFilename(filename) {
+		apiError(ctx, http.StatusNotFound, nil)
+		return
+	}
+
+	pf, err := nix_service.GetNarFile(ctx, ctx.Package.Owner.ID, filename)
+	if err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			apiError(ctx, http.StatusNotFound, err)
+		} else {
+			apiError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	s, u, pf, err := packages_service.GetPackageFileStream(ctx, ctx.Doer, pf)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	helper.ServePackageFile(ctx, s, u, pf, &context.ServeHeaderOptions{
+		ContentType:  "application/x-nix-nar",
+		Filename:     pf.Name,
+		LastModified: pf.CreatedUnix.AsLocalTime(),
+	})
+}
+
+// UploadNar stores a NAR file, it's referenced by the narinfo uploaded afterwards
+func UploadNar(ctx *context.Context) {
+	filename := ctx.Params("filename")
+	if !nix_module.IsValidNarFilename(filename) {
+		apiError(ctx, http.StatusBadRequest, "invalid NAR filename")
+		return
+	}
+
+	upload, needToClose, err := ctx.UploadStream()
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if needToClose {
+		defer upload.Close()
+	}
+
+	buf, err := packages_module.CreateHashedBufferFromReader(upload)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	defer buf.Close()
+
+	if err := nix_service.UploadNar(ctx, ctx.Doer, ctx.Package.Owner, filename, buf); err != nil {
+		switch err {
+		case packages_service.ErrQuotaTotalCount, packages_service.ErrQuotaTypeSize, packages_service.ErrQuotaTotalSize:
+			apiError(ctx, http.StatusForbidden, err)
+		default:
+			apiError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	ctx.Status(http.StatusCreated)
+}
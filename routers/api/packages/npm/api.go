@@ -70,6 +70,7 @@ func createPackageMetadataVersion(registryURL string, pd *packages_model.Package
 		OptionalDependencies: metadata.OptionalDependencies,
 		Readme:               metadata.Readme,
 		Bin:                  metadata.Bin,
+		Deprecated:           pd.DeprecationMessage(),
 		Dist: npm_module.PackageDistribution{
 			Shasum:    pd.Files[0].Blob.HashSHA1,
 			Integrity: "sha512-" + base64.StdEncoding.EncodeToString(hashBytes),
@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
 
 	"code.gitea.io/gitea/models/db"
@@ -17,6 +18,7 @@ import (
 	access_model "code.gitea.io/gitea/models/perm/access"
 	repo_model "code.gitea.io/gitea/models/repo"
 	"code.gitea.io/gitea/models/unit"
+	user_model "code.gitea.io/gitea/models/user"
 	"code.gitea.io/gitea/modules/optional"
 	packages_module "code.gitea.io/gitea/modules/packages"
 	npm_module "code.gitea.io/gitea/modules/packages/npm"
@@ -25,6 +27,7 @@ import (
 	"code.gitea.io/gitea/routers/api/packages/helper"
 	"code.gitea.io/gitea/services/context"
 	packages_service "code.gitea.io/gitea/services/packages"
+	packages_proxy "code.gitea.io/gitea/services/packages/proxy"
 
 	"github.com/hashicorp/go-version"
 )
@@ -54,28 +57,43 @@ func packageNameFromParams(ctx *context.Context) string {
 // PackageMetadata returns the metadata for a single package
 func PackageMetadata(ctx *context.Context) {
 	packageName := packageNameFromParams(ctx)
+	registryURL := setting.AppURL + "api/packages/" + ctx.Package.Owner.Name + "/npm"
 
-	pvs, err := packages_model.GetVersionsByPackageName(ctx, ctx.Package.Owner.ID, packages_model.TypeNpm, packageName)
+	sources, err := helper.GetRegistrySources(ctx, packages_model.TypeNpm)
 	if err != nil {
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
 	}
-	if len(pvs) == 0 {
-		apiError(ctx, http.StatusNotFound, err)
-		return
+
+	var resp *npm_module.PackageMetadata
+	for _, owner := range sources.Hosted {
+		pvs, err := packages_model.GetVersionsByPackageName(ctx, owner.ID, packages_model.TypeNpm, packageName)
+		if err != nil {
+			apiError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+		if len(pvs) == 0 {
+			continue
+		}
+
+		pds, err := packages_model.GetPackageDescriptors(ctx, pvs)
+		if err != nil {
+			apiError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+
+		resp = mergePackageMetadata(resp, createPackageMetadataResponse(registryURL, pds))
 	}
 
-	pds, err := packages_model.GetPackageDescriptors(ctx, pvs)
-	if err != nil {
-		apiError(ctx, http.StatusInternalServerError, err)
+	// the versions of the upstream registries are listed if the packages are proxied, even if they haven't been cached yet
+	for _, owner := range sources.Proxied {
+		resp = mergePackageMetadata(resp, getUpstreamPackageMetadata(ctx, owner, registryURL, packageName))
+	}
+	if resp == nil {
+		apiError(ctx, http.StatusNotFound, packages_model.ErrPackageNotExist)
 		return
 	}
 
-	resp := createPackageMetadataResponse(
-		setting.AppURL+"api/packages/"+ctx.Package.Owner.Name+"/npm",
-		pds,
-	)
-
 	ctx.JSON(http.StatusOK, resp)
 }
 
@@ -85,18 +103,51 @@ func DownloadPackageFile(ctx *context.Context) {
 	packageVersion := ctx.Params("version")
 	filename := ctx.Params("filename")
 
-	s, u, pf, err := packages_service.GetFileStreamByPackageNameAndVersion(
-		ctx,
-		&packages_service.PackageInfo{
-			Owner:       ctx.Package.Owner,
-			PackageType: packages_model.TypeNpm,
-			Name:        packageName,
-			Version:     packageVersion,
-		},
-		&packages_service.PackageFileInfo{
-			Filename: filename,
-		},
-	)
+	sources, err := helper.GetRegistrySources(ctx, packages_model.TypeNpm)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	getFileStream := func(owner *user_model.User) (io.ReadSeekCloser, *url.URL, *packages_model.PackageFile, error) {
+		return packages_service.GetFileStreamByPackageNameAndVersion(
+			ctx,
+			ctx.Doer,
+			&packages_service.PackageInfo{
+				Owner:       owner,
+				PackageType: packages_model.TypeNpm,
+				Name:        packageName,
+				Version:     packageVersion,
+			},
+			&packages_service.PackageFileInfo{
+				Filename: filename,
+			},
+		)
+	}
+
+	var s io.ReadSeekCloser
+	var u *url.URL
+	var pf *packages_model.PackageFile
+	for _, owner := range sources.Hosted {
+		s, u, pf, err = getFileStream(owner)
+		if err != packages_model.ErrPackageNotExist {
+			break
+		}
+	}
+	if err == packages_model.ErrPackageNotExist {
+		for _, owner := range sources.Proxied {
+			cached, cacheErr := cacheUpstreamPackage(ctx, owner, packageName, packageVersion)
+			if !cached || errors.Is(cacheErr, packages_proxy.ErrUpstreamNotExist) {
+				continue
+			}
+			if cacheErr != nil {
+				upstreamError(ctx, cacheErr)
+				return
+			}
+			s, u, pf, err = getFileStream(owner)
+			break
+		}
+	}
 	if err != nil {
 		if err == packages_model.ErrPackageNotExist || err == packages_model.ErrPackageFileNotExist {
 			apiError(ctx, http.StatusNotFound, err)
@@ -113,20 +164,32 @@ func DownloadPackageFile(ctx *context.Context) {
 func DownloadPackageFileByName(ctx *context.Context) {
 	filename := ctx.Params("filename")
 
-	pvs, _, err := packages_model.SearchVersions(ctx, &packages_model.PackageSearchOptions{
-		OwnerID: ctx.Package.Owner.ID,
-		Type:    packages_model.TypeNpm,
-		Name: packages_model.SearchValue{
-			ExactMatch: true,
-			Value:      packageNameFromParams(ctx),
-		},
-		HasFileWithName: filename,
-		IsInternal:      optional.Some(false),
-	})
+	sources, err := helper.GetRegistrySources(ctx, packages_model.TypeNpm)
 	if err != nil {
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
 	}
+
+	var pvs []*packages_model.PackageVersion
+	for _, owner := range sources.Hosted {
+		pvs, _, err = packages_model.SearchVersions(ctx, &packages_model.PackageSearchOptions{
+			OwnerID: owner.ID,
+			Type:    packages_model.TypeNpm,
+			Name: packages_model.SearchValue{
+				ExactMatch: true,
+				Value:      packageNameFromParams(ctx),
+			},
+			HasFileWithName: filename,
+			IsInternal:      optional.Some(false),
+		})
+		if err != nil {
+			apiError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+		if len(pvs) != 0 {
+			break
+		}
+	}
 	if len(pvs) != 1 {
 		apiError(ctx, http.StatusNotFound, nil)
 		return
@@ -134,6 +197,7 @@ func DownloadPackageFileByName(ctx *context.Context) {
 
 	s, u, pf, err := packages_service.GetFileStreamByPackageVersion(
 		ctx,
+		ctx.Doer,
 		pvs[0],
 		&packages_service.PackageFileInfo{
 			Filename: filename,
@@ -271,6 +335,10 @@ func DeletePackageVersion(ctx *context.Context) {
 			apiError(ctx, http.StatusNotFound, err)
 			return
 		}
+		if errors.Is(err, util.ErrPermissionDenied) {
+			apiError(ctx, http.StatusForbidden, err)
+			return
+		}
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
 	}
@@ -295,6 +363,10 @@ func DeletePackage(ctx *context.Context) {
 
 	for _, pv := range pvs {
 		if err := packages_service.RemovePackageVersion(ctx, ctx.Doer, pv); err != nil {
+			if errors.Is(err, util.ErrPermissionDenied) {
+				apiError(ctx, http.StatusForbidden, err)
+				return
+			}
 			apiError(ctx, http.StatusInternalServerError, err)
 			return
 		}
@@ -0,0 +1,166 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package npm
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	packages_model "code.gitea.io/gitea/models/packages"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/log"
+	npm_module "code.gitea.io/gitea/modules/packages/npm"
+	"code.gitea.io/gitea/routers/api/packages/helper"
+	"code.gitea.io/gitea/services/context"
+	packages_service "code.gitea.io/gitea/services/packages"
+	packages_proxy "code.gitea.io/gitea/services/packages/proxy"
+
+	"github.com/hashicorp/go-version"
+)
+
+// getUpstreamPackageMetadata returns the metadata of a package in the upstream registry of an owner, with the tarballs served by the registry.
+// It returns nil if the packages of the owner aren't proxied or if the upstream registry is unavailable.
+func getUpstreamPackageMetadata(ctx *context.Context, owner *user_model.User, registryURL, packageName string) *npm_module.PackageMetadata {
+	client, err := packages_proxy.GetClient(ctx, owner.ID, packages_model.TypeNpm)
+	if err != nil {
+		log.Error("GetClient: %v", err)
+		return nil
+	}
+	if client == nil {
+		return nil
+	}
+
+	metadata := &npm_module.PackageMetadata{}
+	if err := client.GetJSON(ctx, client.URL(url.PathEscape(packageName)), metadata); err != nil {
+		if !errors.Is(err, packages_proxy.ErrUpstreamNotExist) {
+			log.Warn("Unable to get the metadata of the npm package %s from the upstream registry: %v", packageName, err)
+		}
+		return nil
+	}
+
+	versions := make(map[string]*npm_module.PackageMetadataVersion, len(metadata.Versions))
+	for _, meta := range metadata.Versions {
+		v, err := version.NewSemver(meta.Version)
+		if err != nil {
+			continue
+		}
+		meta.Dist.Tarball = fmt.Sprintf("%s/%s/-/%s/%s", registryURL, url.QueryEscape(packageName), url.PathEscape(v.String()), url.PathEscape(tarballFilename(packageName, v)))
+		versions[v.String()] = meta
+	}
+	metadata.Versions = versions
+
+	return metadata
+}
+
+// mergePackageMetadata adds the versions and the tags which are missing in the metadata of a source with a higher priority
+func mergePackageMetadata(higher, lower *npm_module.PackageMetadata) *npm_module.PackageMetadata {
+	if higher == nil {
+		return lower
+	}
+	if lower == nil {
+		return higher
+	}
+	if higher.DistTags == nil {
+		higher.DistTags = make(map[string]string, len(lower.DistTags))
+	}
+	for tag, v := range lower.DistTags {
+		if _, ok := higher.DistTags[tag]; !ok {
+			higher.DistTags[tag] = v
+		}
+	}
+	for v, meta := range lower.Versions {
+		if _, ok := higher.Versions[v]; !ok {
+			higher.Versions[v] = meta
+		}
+	}
+	for v, t := range lower.Time {
+		if _, ok := higher.Time[v]; !ok && higher.Time != nil {
+			higher.Time[v] = t
+		}
+	}
+	return higher
+}
+
+func tarballFilename(packageName string, v *version.Version) string {
+	name := packageName
+	if parts := strings.SplitN(packageName, "/", 2); len(parts) == 2 {
+		name = parts[1]
+	}
+	return strings.ToLower(fmt.Sprintf("%s-%s.tgz", name, v.String()))
+}
+
+// cacheUpstreamPackage stores a version of a package of the upstream registry of an owner.
+// It returns false if the packages of the owner aren't proxied.
+func cacheUpstreamPackage(ctx *context.Context, owner *user_model.User, packageName, packageVersion string) (bool, error) {
+	client, err := packages_proxy.GetClient(ctx, owner.ID, packages_model.TypeNpm)
+	if err != nil || client == nil {
+		return false, err
+	}
+
+	v, err := version.NewSemver(packageVersion)
+	if err != nil {
+		return true, packages_proxy.ErrUpstreamNotExist
+	}
+
+	var metadata npm_module.PackageMetadata
+	if err := client.GetJSON(ctx, client.URL(url.PathEscape(packageName)), &metadata); err != nil {
+		return true, err
+	}
+	var meta *npm_module.PackageMetadataVersion
+	for _, m := range metadata.Versions {
+		if mv, err := version.NewSemver(m.Version); err == nil && mv.Equal(v) && m.Name == packageName {
+			meta = m
+			break
+		}
+	}
+	if meta == nil {
+		return true, packages_proxy.ErrUpstreamNotExist
+	}
+
+	buf, err := client.Download(ctx, meta.Dist.Tarball, packages_service.TypeSizeLimit(packages_model.TypeNpm))
+	if err != nil {
+		return true, err
+	}
+	defer buf.Close()
+
+	_, hashSHA1, _, hashSHA512 := buf.Sums()
+	npmPackage, err := npm_module.ParseUpstreamPackage(meta, hashSHA1, hashSHA512)
+	if err != nil {
+		return true, err
+	}
+
+	_, _, err = packages_service.CreatePackageAndAddFile(
+		ctx,
+		&packages_service.PackageCreationInfo{
+			PackageInfo: packages_service.PackageInfo{
+				Owner:       owner,
+				PackageType: packages_model.TypeNpm,
+				Name:        npmPackage.Name,
+				Version:     npmPackage.Version,
+			},
+			SemverCompatible: true,
+			Creator:          owner,
+			Metadata:         npmPackage.Metadata,
+		},
+		&packages_service.PackageFileCreationInfo{
+			PackageFileInfo: packages_service.PackageFileInfo{
+				Filename: npmPackage.Filename,
+			},
+			Creator: owner,
+			Data:    buf,
+			IsLead:  true,
+		},
+	)
+	if err == packages_model.ErrDuplicatePackageVersion {
+		// the version has been cached by a concurrent request
+		err = nil
+	}
+	return true, err
+}
+
+func upstreamError(ctx *context.Context, err error) {
+	apiError(ctx, helper.UpstreamErrorStatus(err), err)
+}
@@ -0,0 +1,209 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package pypi
+
+import (
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+
+	packages_model "code.gitea.io/gitea/models/packages"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/log"
+	pypi_module "code.gitea.io/gitea/modules/packages/pypi"
+	"code.gitea.io/gitea/modules/validation"
+	"code.gitea.io/gitea/routers/api/packages/helper"
+	"code.gitea.io/gitea/services/context"
+	packages_service "code.gitea.io/gitea/services/packages"
+	packages_proxy "code.gitea.io/gitea/services/packages/proxy"
+)
+
+// upstreamFile is a file of a release in the JSON API of an upstream registry
+// https://docs.pypi.org/api/json/
+type upstreamFile struct {
+	Filename       string `json:"filename"`
+	URL            string `json:"url"`
+	RequiresPython string `json:"requires_python"`
+	Digests        struct {
+		SHA256 string `json:"sha256"`
+	} `json:"digests"`
+}
+
+type upstreamInfo struct {
+	Author         string `json:"author"`
+	Description    string `json:"description"`
+	Summary        string `json:"summary"`
+	HomePage       string `json:"home_page"`
+	License        string `json:"license"`
+	RequiresPython string `json:"requires_python"`
+}
+
+// getUpstreamPackageDescriptors returns the files of the releases of a package in the upstream registry of an owner,
+// as package descriptors which are only used to render the simple index.
+// It returns nil if the packages of the owner aren't proxied or if the upstream registry is unavailable.
+func getUpstreamPackageDescriptors(ctx *context.Context, owner *user_model.User, packageName string) []*packages_model.PackageDescriptor {
+	client, err := packages_proxy.GetClient(ctx, owner.ID, packages_model.TypePyPI)
+	if err != nil {
+		log.Error("GetClient: %v", err)
+		return nil
+	}
+	if client == nil {
+		return nil
+	}
+
+	var project struct {
+		Releases map[string][]*upstreamFile `json:"releases"`
+	}
+	if err := client.GetJSON(ctx, client.URL("pypi/"+url.PathEscape(packageName)+"/json"), &project); err != nil {
+		if !errors.Is(err, packages_proxy.ErrUpstreamNotExist) {
+			log.Warn("Unable to get the releases of the PyPI package %s from the upstream registry: %v", packageName, err)
+		}
+		return nil
+	}
+
+	pds := make([]*packages_model.PackageDescriptor, 0, len(project.Releases))
+	for v, files := range project.Releases {
+		if len(files) == 0 || !isValidNameAndVersion(packageName, v) {
+			continue
+		}
+		pd := &packages_model.PackageDescriptor{
+			Package:  &packages_model.Package{Name: packageName, LowerName: strings.ToLower(packageName)},
+			Version:  &packages_model.PackageVersion{Version: v},
+			Metadata: &pypi_module.Metadata{RequiresPython: files[0].RequiresPython},
+			Files:    make([]*packages_model.PackageFileDescriptor, 0, len(files)),
+		}
+		for _, f := range files {
+			pd.Files = append(pd.Files, &packages_model.PackageFileDescriptor{
+				File: &packages_model.PackageFile{Name: f.Filename},
+				Blob: &packages_model.PackageBlob{HashSHA256: f.Digests.SHA256},
+			})
+		}
+		pds = append(pds, pd)
+	}
+	return pds
+}
+
+// mergePackageDescriptors adds the releases and the files which are missing in the releases of a source with a higher priority
+func mergePackageDescriptors(higher, lower []*packages_model.PackageDescriptor) []*packages_model.PackageDescriptor {
+	if len(higher) == 0 {
+		return lower
+	}
+
+	versions := make(map[string]*packages_model.PackageDescriptor, len(higher))
+	for _, pd := range higher {
+		versions[pd.Version.Version] = pd
+	}
+	for _, pd := range lower {
+		higherPd, ok := versions[pd.Version.Version]
+		if !ok {
+			higher = append(higher, pd)
+			versions[pd.Version.Version] = pd
+			continue
+		}
+		for _, pfd := range pd.Files {
+			found := false
+			for _, higherPfd := range higherPd.Files {
+				if higherPfd.File.Name == pfd.File.Name {
+					found = true
+					break
+				}
+			}
+			if !found {
+				higherPd.Files = append(higherPd.Files, pfd)
+			}
+		}
+	}
+	return higher
+}
+
+// cacheUpstreamPackageFile stores a file of a release of the upstream registry of an owner.
+// It returns false if the packages of the owner aren't proxied.
+func cacheUpstreamPackageFile(ctx *context.Context, owner *user_model.User, packageName, packageVersion, filename string) (bool, error) {
+	client, err := packages_proxy.GetClient(ctx, owner.ID, packages_model.TypePyPI)
+	if err != nil || client == nil {
+		return false, err
+	}
+
+	if !isValidNameAndVersion(packageName, packageVersion) {
+		return true, packages_proxy.ErrUpstreamNotExist
+	}
+
+	var release struct {
+		Info upstreamInfo    `json:"info"`
+		URLs []*upstreamFile `json:"urls"`
+	}
+	if err := client.GetJSON(ctx, client.URL("pypi/"+url.PathEscape(packageName)+"/"+url.PathEscape(packageVersion)+"/json"), &release); err != nil {
+		return true, err
+	}
+	var file *upstreamFile
+	for _, f := range release.URLs {
+		if f.Filename == filename {
+			file = f
+			break
+		}
+	}
+	if file == nil {
+		return true, packages_proxy.ErrUpstreamNotExist
+	}
+
+	buf, err := client.Download(ctx, file.URL, packages_service.TypeSizeLimit(packages_model.TypePyPI))
+	if err != nil {
+		return true, err
+	}
+	defer buf.Close()
+
+	_, _, hashSHA256, _ := buf.Sums()
+	if !strings.EqualFold(file.Digests.SHA256, hex.EncodeToString(hashSHA256)) {
+		return true, &packages_proxy.UpstreamError{Err: errors.New("hash mismatch")}
+	}
+	if _, err := buf.Seek(0, io.SeekStart); err != nil {
+		return true, err
+	}
+
+	projectURL := release.Info.HomePage
+	if !validation.IsValidURL(projectURL) {
+		projectURL = ""
+	}
+
+	_, _, err = packages_service.CreatePackageOrAddFileToExisting(
+		ctx,
+		&packages_service.PackageCreationInfo{
+			PackageInfo: packages_service.PackageInfo{
+				Owner:       owner,
+				PackageType: packages_model.TypePyPI,
+				Name:        packageName,
+				Version:     packageVersion,
+			},
+			SemverCompatible: false,
+			Creator:          owner,
+			Metadata: &pypi_module.Metadata{
+				Author:         release.Info.Author,
+				Description:    release.Info.Description,
+				Summary:        release.Info.Summary,
+				ProjectURL:     projectURL,
+				License:        release.Info.License,
+				RequiresPython: release.Info.RequiresPython,
+			},
+		},
+		&packages_service.PackageFileCreationInfo{
+			PackageFileInfo: packages_service.PackageFileInfo{
+				Filename: filename,
+			},
+			Creator: owner,
+			Data:    buf,
+			IsLead:  true,
+		},
+	)
+	if err == packages_model.ErrDuplicatePackageFile {
+		// the file has been cached by a concurrent request
+		err = nil
+	}
+	return true, err
+}
+
+func upstreamError(ctx *context.Context, err error) {
+	apiError(ctx, helper.UpstreamErrorStatus(err), err)
+}
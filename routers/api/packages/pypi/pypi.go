@@ -5,13 +5,16 @@ package pypi
 
 import (
 	"encoding/hex"
+	"errors"
 	"io"
 	"net/http"
+	"net/url"
 	"regexp"
 	"sort"
 	"strings"
 
 	packages_model "code.gitea.io/gitea/models/packages"
+	user_model "code.gitea.io/gitea/models/user"
 	packages_module "code.gitea.io/gitea/modules/packages"
 	pypi_module "code.gitea.io/gitea/modules/packages/pypi"
 	"code.gitea.io/gitea/modules/setting"
@@ -19,6 +22,7 @@ import (
 	"code.gitea.io/gitea/routers/api/packages/helper"
 	"code.gitea.io/gitea/services/context"
 	packages_service "code.gitea.io/gitea/services/packages"
+	packages_proxy "code.gitea.io/gitea/services/packages/proxy"
 )
 
 // https://peps.python.org/pep-0426/#name
@@ -47,19 +51,35 @@ func apiError(ctx *context.Context, status int, obj any) {
 func PackageMetadata(ctx *context.Context) {
 	packageName := normalizer.Replace(ctx.Params("id"))
 
-	pvs, err := packages_model.GetVersionsByPackageName(ctx, ctx.Package.Owner.ID, packages_model.TypePyPI, packageName)
+	sources, err := helper.GetRegistrySources(ctx, packages_model.TypePyPI)
 	if err != nil {
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
 	}
-	if len(pvs) == 0 {
-		apiError(ctx, http.StatusNotFound, err)
-		return
+
+	var pds []*packages_model.PackageDescriptor
+	for _, owner := range sources.Hosted {
+		pvs, err := packages_model.GetVersionsByPackageName(ctx, owner.ID, packages_model.TypePyPI, packageName)
+		if err != nil {
+			apiError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+
+		ownerPds, err := packages_model.GetPackageDescriptors(ctx, pvs)
+		if err != nil {
+			apiError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+
+		pds = mergePackageDescriptors(pds, ownerPds)
 	}
 
-	pds, err := packages_model.GetPackageDescriptors(ctx, pvs)
-	if err != nil {
-		apiError(ctx, http.StatusInternalServerError, err)
+	// the releases of the upstream registries are listed if the packages are proxied, even if they haven't been cached yet
+	for _, owner := range sources.Proxied {
+		pds = mergePackageDescriptors(pds, getUpstreamPackageDescriptors(ctx, owner, packageName))
+	}
+	if len(pds) == 0 {
+		apiError(ctx, http.StatusNotFound, packages_model.ErrPackageNotExist)
 		return
 	}
 
@@ -80,18 +100,51 @@ func DownloadPackageFile(ctx *context.Context) {
 	packageVersion := ctx.Params("version")
 	filename := ctx.Params("filename")
 
-	s, u, pf, err := packages_service.GetFileStreamByPackageNameAndVersion(
-		ctx,
-		&packages_service.PackageInfo{
-			Owner:       ctx.Package.Owner,
-			PackageType: packages_model.TypePyPI,
-			Name:        packageName,
-			Version:     packageVersion,
-		},
-		&packages_service.PackageFileInfo{
-			Filename: filename,
-		},
-	)
+	sources, err := helper.GetRegistrySources(ctx, packages_model.TypePyPI)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	getFileStream := func(owner *user_model.User) (io.ReadSeekCloser, *url.URL, *packages_model.PackageFile, error) {
+		return packages_service.GetFileStreamByPackageNameAndVersion(
+			ctx,
+			ctx.Doer,
+			&packages_service.PackageInfo{
+				Owner:       owner,
+				PackageType: packages_model.TypePyPI,
+				Name:        packageName,
+				Version:     packageVersion,
+			},
+			&packages_service.PackageFileInfo{
+				Filename: filename,
+			},
+		)
+	}
+
+	var s io.ReadSeekCloser
+	var u *url.URL
+	var pf *packages_model.PackageFile
+	for _, owner := range sources.Hosted {
+		s, u, pf, err = getFileStream(owner)
+		if err != packages_model.ErrPackageNotExist && err != packages_model.ErrPackageFileNotExist {
+			break
+		}
+	}
+	if err == packages_model.ErrPackageNotExist || err == packages_model.ErrPackageFileNotExist {
+		for _, owner := range sources.Proxied {
+			cached, cacheErr := cacheUpstreamPackageFile(ctx, owner, packageName, packageVersion, filename)
+			if !cached || errors.Is(cacheErr, packages_proxy.ErrUpstreamNotExist) {
+				continue
+			}
+			if cacheErr != nil {
+				upstreamError(ctx, cacheErr)
+				return
+			}
+			s, u, pf, err = getFileStream(owner)
+			break
+		}
+	}
 	if err != nil {
 		if err == packages_model.ErrPackageNotExist || err == packages_model.ErrPackageFileNotExist {
 			apiError(ctx, http.StatusNotFound, err)
@@ -98,6 +98,7 @@ func GetRepositoryFile(ctx *context.Context) {
 
 	s, u, pf, err := packages_service.GetFileStreamByPackageVersion(
 		ctx,
+		ctx.Doer,
 		pv,
 		&packages_service.PackageFileInfo{
 			Filename:     ctx.Params("filename"),
@@ -207,6 +208,7 @@ func DownloadPackageFile(ctx *context.Context) {
 
 	s, u, pf, err := packages_service.GetFileStreamByPackageNameAndVersion(
 		ctx,
+		ctx.Doer,
 		&packages_service.PackageInfo{
 			Owner:       ctx.Package.Owner,
 			PackageType: packages_model.TypeRpm,
@@ -259,6 +261,10 @@ func DeletePackageFile(webctx *context.Context) {
 			return err
 		}
 
+		if err := packages_service.CheckVersionRemovable(ctx, pv); err != nil {
+			return err
+		}
+
 		if err := packages_service.DeletePackageFile(ctx, pf); err != nil {
 			return err
 		}
@@ -283,6 +289,8 @@ func DeletePackageFile(webctx *context.Context) {
 	if err != nil {
 		if errors.Is(err, util.ErrNotExist) {
 			apiError(webctx, http.StatusNotFound, err)
+		} else if errors.Is(err, util.ErrPermissionDenied) {
+			apiError(webctx, http.StatusForbidden, err)
 		} else {
 			apiError(webctx, http.StatusInternalServerError, err)
 		}
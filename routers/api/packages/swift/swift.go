@@ -399,7 +399,7 @@ func DownloadPackageFile(ctx *context.Context) {
 
 	pf := pd.Files[0].File
 
-	s, u, _, err := packages_service.GetPackageFileStream(ctx, pf)
+	s, u, _, err := packages_service.GetPackageFileStream(ctx, ctx.Doer, pf)
 	if err != nil {
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
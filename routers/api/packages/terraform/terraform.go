@@ -0,0 +1,691 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package terraform
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	packages_model "code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/models/perm"
+	"code.gitea.io/gitea/modules/json"
+	packages_module "code.gitea.io/gitea/modules/packages"
+	terraform_module "code.gitea.io/gitea/modules/packages/terraform"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/util"
+	"code.gitea.io/gitea/routers/api/packages/helper"
+	"code.gitea.io/gitea/services/context"
+	packages_service "code.gitea.io/gitea/services/packages"
+	terraform_service "code.gitea.io/gitea/services/packages/terraform"
+
+	"github.com/hashicorp/go-version"
+)
+
+// https://developer.hashicorp.com/terraform/internals/module-registry-protocol
+// https://developer.hashicorp.com/terraform/internals/provider-registry-protocol
+
+var (
+	moduleNamePattern   = regexp.MustCompile(`\A[0-9A-Za-z](?:[0-9A-Za-z-_]{0,62}[0-9A-Za-z])?\z`)
+	providerNamePattern = regexp.MustCompile(`\A[0-9a-z][0-9a-z-]*\z`)
+)
+
+// downloadLinkLifetime is the duration in which the signed download links returned by the protocol endpoints are valid.
+// Terraform doesn't send credentials when it downloads the archives of modules and providers.
+const downloadLinkLifetime = time.Hour
+
+func apiError(ctx *context.Context, status int, obj any) {
+	helper.LogAndProcessError(ctx, status, obj, func(message string) {
+		ctx.JSON(status, struct {
+			Errors []string `json:"errors"`
+		}{
+			Errors: []string{
+				message,
+			},
+		})
+	})
+}
+
+func modulePackageName(name, system string) string {
+	return name + "/" + system
+}
+
+func moduleArchiveFilename(name, system, version string) string {
+	return strings.ToLower(fmt.Sprintf("%s-%s-%s.tar.gz", name, system, version))
+}
+
+func shasumsFilename(name, version string) string {
+	return fmt.Sprintf("terraform-provider-%s_%s%s", name, version, terraform_module.ShasumsSuffix)
+}
+
+// downloadResource returns the escaped path of a downloaded file relative to the registry of its owner
+func downloadResource(ctx *context.Context) string {
+	if system := ctx.Params("system"); system != "" {
+		return fmt.Sprintf("modules/%s/%s/%s/%s", url.PathEscape(ctx.Params("name")), url.PathEscape(system), url.PathEscape(ctx.Params("version")), url.PathEscape(ctx.Params("filename")))
+	}
+	return fmt.Sprintf("providers/%s/%s/%s", url.PathEscape(ctx.Params("name")), url.PathEscape(ctx.Params("version")), url.PathEscape(ctx.Params("filename")))
+}
+
+func downloadSignature(ownerID int64, resource string, expires int64) string {
+	mac := hmac.New(sha256.New, setting.GetGeneralTokenSigningSecret())
+	mac.Write([]byte("terraform\n"))
+	mac.Write([]byte(strconv.FormatInt(ownerID, 10) + "\n"))
+	mac.Write([]byte(resource + "\n"))
+	mac.Write([]byte(strconv.FormatInt(expires, 10)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func signedDownloadURL(ctx *context.Context, resource string) string {
+	expires := time.Now().Add(downloadLinkLifetime).Unix()
+	return fmt.Sprintf(
+		"%sapi/packages/%s/terraform/%s?expires=%d&sig=%s",
+		setting.AppURL,
+		url.PathEscape(ctx.Package.Owner.Name),
+		resource,
+		expires,
+		downloadSignature(ctx.Package.Owner.ID, resource, expires),
+	)
+}
+
+// VerifyDownloadSignature grants read access to a file of a package for a valid signed download link
+func VerifyDownloadSignature(ctx *context.Context) {
+	sig := ctx.FormString("sig")
+	if sig == "" || ctx.Package.AccessMode >= perm.AccessModeRead {
+		return
+	}
+
+	expires := ctx.FormInt64("expires")
+	if expires < time.Now().Unix() {
+		return
+	}
+
+	if hmac.Equal([]byte(sig), []byte(downloadSignature(ctx.Package.Owner.ID, downloadResource(ctx), expires))) {
+		ctx.Package.AccessMode = perm.AccessModeRead
+	}
+}
+
+func getPackageDescriptors(ctx *context.Context, packageName string, kind terraform_module.Kind) ([]*packages_model.PackageDescriptor, error) {
+	pvs, err := packages_model.GetVersionsByPackageName(ctx, ctx.Package.Owner.ID, packages_model.TypeTerraform, packageName)
+	if err != nil {
+		return nil, err
+	}
+
+	pds, err := packages_model.GetPackageDescriptors(ctx, pvs)
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := make([]*packages_model.PackageDescriptor, 0, len(pds))
+	for _, pd := range pds {
+		if pd.Metadata.(*terraform_module.Metadata).Kind == kind {
+			filtered = append(filtered, pd)
+		}
+	}
+	if len(filtered) == 0 {
+		return nil, packages_model.ErrPackageNotExist
+	}
+
+	sort.Slice(filtered, func(i, j int) bool {
+		return filtered[i].SemVer.LessThan(filtered[j].SemVer)
+	})
+
+	return filtered, nil
+}
+
+func getPackageDescriptor(ctx *context.Context, packageName, packageVersion string, kind terraform_module.Kind) (*packages_model.PackageDescriptor, error) {
+	pv, err := packages_model.GetVersionByNameAndVersion(ctx, ctx.Package.Owner.ID, packages_model.TypeTerraform, packageName, packageVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	pd, err := packages_model.GetPackageDescriptor(ctx, pv)
+	if err != nil {
+		return nil, err
+	}
+
+	if pd.Metadata.(*terraform_module.Metadata).Kind != kind {
+		return nil, packages_model.ErrPackageNotExist
+	}
+
+	return pd, nil
+}
+
+type moduleVersion struct {
+	Version string `json:"version"`
+}
+
+type moduleVersionList struct {
+	Versions []*moduleVersion `json:"versions"`
+}
+
+type moduleVersions struct {
+	Modules []*moduleVersionList `json:"modules"`
+}
+
+// EnumerateModuleVersions lists the available versions of a module
+func EnumerateModuleVersions(ctx *context.Context) {
+	pds, err := getPackageDescriptors(ctx, modulePackageName(ctx.Params("name"), ctx.Params("system")), terraform_module.KindModule)
+	if err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			apiError(ctx, http.StatusNotFound, err)
+			return
+		}
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	versions := make([]*moduleVersion, 0, len(pds))
+	for _, pd := range pds {
+		versions = append(versions, &moduleVersion{
+			Version: pd.Version.Version,
+		})
+	}
+
+	ctx.JSON(http.StatusOK, &moduleVersions{
+		Modules: []*moduleVersionList{
+			{
+				Versions: versions,
+			},
+		},
+	})
+}
+
+// DownloadModule returns the location of the archive of a module version
+func DownloadModule(ctx *context.Context) {
+	name := ctx.Params("name")
+	system := ctx.Params("system")
+	version := ctx.Params("version")
+
+	if _, err := getPackageDescriptor(ctx, modulePackageName(name, system), version, terraform_module.KindModule); err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			apiError(ctx, http.StatusNotFound, err)
+			return
+		}
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.Resp.Header().Set("X-Terraform-Get", signedDownloadURL(ctx, fmt.Sprintf(
+		"modules/%s/%s/%s/%s",
+		url.PathEscape(name),
+		url.PathEscape(system),
+		url.PathEscape(version),
+		url.PathEscape(moduleArchiveFilename(name, system, version)),
+	)))
+	ctx.Status(http.StatusNoContent)
+}
+
+// UploadModule creates a module version from its archive
+func UploadModule(ctx *context.Context) {
+	name := ctx.Params("name")
+	system := ctx.Params("system")
+	packageVersion := ctx.Params("version")
+	if !moduleNamePattern.MatchString(name) || !moduleNamePattern.MatchString(system) {
+		apiError(ctx, http.StatusBadRequest, "invalid module name or system")
+		return
+	}
+	if _, err := version.NewSemver(packageVersion); err != nil {
+		apiError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	upload, needsClose, err := ctx.UploadStream()
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if needsClose {
+		defer upload.Close()
+	}
+
+	buf, err := packages_module.CreateHashedBufferFromReader(upload)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	defer buf.Close()
+
+	metadata, err := terraform_module.ParseModuleArchive(buf)
+	if err != nil {
+		if errors.Is(err, util.ErrInvalidArgument) {
+			apiError(ctx, http.StatusBadRequest, err)
+		} else {
+			apiError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	if _, err := buf.Seek(0, io.SeekStart); err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	_, _, err = packages_service.CreatePackageAndAddFile(
+		ctx,
+		&packages_service.PackageCreationInfo{
+			PackageInfo: packages_service.PackageInfo{
+				Owner:       ctx.Package.Owner,
+				PackageType: packages_model.TypeTerraform,
+				Name:        modulePackageName(name, system),
+				Version:     packageVersion,
+			},
+			SemverCompatible: true,
+			Creator:          ctx.Doer,
+			Metadata:         metadata,
+		},
+		&packages_service.PackageFileCreationInfo{
+			PackageFileInfo: packages_service.PackageFileInfo{
+				Filename: moduleArchiveFilename(name, system, packageVersion),
+			},
+			Creator: ctx.Doer,
+			Data:    buf,
+			IsLead:  true,
+		},
+	)
+	if err != nil {
+		switch err {
+		case packages_model.ErrDuplicatePackageVersion:
+			apiError(ctx, http.StatusConflict, err)
+		case packages_service.ErrQuotaTotalCount, packages_service.ErrQuotaTypeSize, packages_service.ErrQuotaTotalSize:
+			apiError(ctx, http.StatusForbidden, err)
+		default:
+			apiError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	ctx.Status(http.StatusCreated)
+}
+
+// DeleteModule deletes a module version
+func DeleteModule(ctx *context.Context) {
+	deletePackageVersion(ctx, modulePackageName(ctx.Params("name"), ctx.Params("system")), terraform_module.KindModule)
+}
+
+// DownloadModuleArchive serves the archive of a module version
+func DownloadModuleArchive(ctx *context.Context) {
+	name := ctx.Params("name")
+	system := ctx.Params("system")
+	version := ctx.Params("version")
+
+	if ctx.Params("filename") != moduleArchiveFilename(name, system, version) {
+		apiError(ctx, http.StatusNotFound, packages_model.ErrPackageFileNotExist)
+		return
+	}
+
+	serveFile(ctx, modulePackageName(name, system), version, ctx.Params("filename"))
+}
+
+type providerPlatform struct {
+	OS   string `json:"os"`
+	Arch string `json:"arch"`
+}
+
+type providerVersion struct {
+	Version   string              `json:"version"`
+	Protocols []string            `json:"protocols"`
+	Platforms []*providerPlatform `json:"platforms"`
+}
+
+type providerVersions struct {
+	Versions []*providerVersion `json:"versions"`
+}
+
+// EnumerateProviderVersions lists the available versions of a provider and their platforms
+func EnumerateProviderVersions(ctx *context.Context) {
+	pds, err := getPackageDescriptors(ctx, ctx.Params("name"), terraform_module.KindProvider)
+	if err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			apiError(ctx, http.StatusNotFound, err)
+			return
+		}
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	versions := make([]*providerVersion, 0, len(pds))
+	for _, pd := range pds {
+		platforms := make([]*providerPlatform, 0, len(pd.Files))
+		for _, pfd := range pd.Files {
+			if os := pfd.Properties.GetByName(terraform_module.PropertyOS); os != "" {
+				platforms = append(platforms, &providerPlatform{
+					OS:   os,
+					Arch: pfd.Properties.GetByName(terraform_module.PropertyArch),
+				})
+			}
+		}
+		if len(platforms) == 0 {
+			continue
+		}
+
+		versions = append(versions, &providerVersion{
+			Version:   pd.Version.Version,
+			Protocols: pd.Metadata.(*terraform_module.Metadata).Protocols,
+			Platforms: platforms,
+		})
+	}
+
+	ctx.JSON(http.StatusOK, &providerVersions{
+		Versions: versions,
+	})
+}
+
+type gpgPublicKey struct {
+	KeyID      string `json:"key_id"`
+	ASCIIArmor string `json:"ascii_armor"`
+}
+
+type providerPackage struct {
+	Protocols           []string `json:"protocols"`
+	OS                  string   `json:"os"`
+	Arch                string   `json:"arch"`
+	Filename            string   `json:"filename"`
+	DownloadURL         string   `json:"download_url"`
+	ShasumsURL          string   `json:"shasums_url"`
+	ShasumsSignatureURL string   `json:"shasums_signature_url"`
+	Shasum              string   `json:"shasum"`
+	SigningKeys         struct {
+		GPGPublicKeys []*gpgPublicKey `json:"gpg_public_keys"`
+	} `json:"signing_keys"`
+}
+
+// DownloadProvider returns the location and the signed checksums of the archive of a provider version for a platform
+func DownloadProvider(ctx *context.Context) {
+	name := ctx.Params("name")
+	version := ctx.Params("version")
+
+	pd, err := getPackageDescriptor(ctx, name, version, terraform_module.KindProvider)
+	if err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			apiError(ctx, http.StatusNotFound, err)
+			return
+		}
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	var archive *packages_model.PackageFileDescriptor
+	for _, pfd := range pd.Files {
+		if pfd.Properties.GetByName(terraform_module.PropertyOS) == ctx.Params("os") && pfd.Properties.GetByName(terraform_module.PropertyArch) == ctx.Params("arch") {
+			archive = pfd
+			break
+		}
+	}
+	if archive == nil {
+		apiError(ctx, http.StatusNotFound, packages_model.ErrPackageFileNotExist)
+		return
+	}
+
+	_, pub, err := terraform_service.GetOrCreateKeyPair(ctx, ctx.Package.Owner.ID)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	keyID, err := terraform_service.GetPublicKeyID(pub)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	resourceBase := fmt.Sprintf("providers/%s/%s/", url.PathEscape(name), url.PathEscape(version))
+	shasums := shasumsFilename(name, version)
+
+	resp := &providerPackage{
+		Protocols:           pd.Metadata.(*terraform_module.Metadata).Protocols,
+		OS:                  ctx.Params("os"),
+		Arch:                ctx.Params("arch"),
+		Filename:            archive.File.Name,
+		DownloadURL:         signedDownloadURL(ctx, resourceBase+url.PathEscape(archive.File.Name)),
+		ShasumsURL:          signedDownloadURL(ctx, resourceBase+url.PathEscape(shasums)),
+		ShasumsSignatureURL: signedDownloadURL(ctx, resourceBase+url.PathEscape(shasums+".sig")),
+		Shasum:              archive.Blob.HashSHA256,
+	}
+	resp.SigningKeys.GPGPublicKeys = []*gpgPublicKey{
+		{
+			KeyID:      keyID,
+			ASCIIArmor: pub,
+		},
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// UploadProviderFile adds an archive or the manifest to a provider version. If the version does not exist, it gets created.
+func UploadProviderFile(ctx *context.Context) {
+	name := ctx.Params("name")
+	providerVersion := ctx.Params("version")
+	filename := ctx.Params("filename")
+	if !providerNamePattern.MatchString(name) {
+		apiError(ctx, http.StatusBadRequest, "invalid provider name")
+		return
+	}
+
+	pf, err := terraform_module.ParseProviderFilename(filename)
+	if err != nil {
+		apiError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	if pf.Name != name || pf.Version != providerVersion {
+		apiError(ctx, http.StatusBadRequest, "filename doesn't match the provider name or version")
+		return
+	}
+
+	upload, needsClose, err := ctx.UploadStream()
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if needsClose {
+		defer upload.Close()
+	}
+
+	buf, err := packages_module.CreateHashedBufferFromReader(upload)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	defer buf.Close()
+
+	metadata := &terraform_module.Metadata{
+		Kind:      terraform_module.KindProvider,
+		Protocols: terraform_module.DefaultProtocols,
+	}
+	pfci := &packages_service.PackageFileCreationInfo{
+		PackageFileInfo: packages_service.PackageFileInfo{
+			Filename: filename,
+		},
+		Creator: ctx.Doer,
+		Data:    buf,
+	}
+
+	if pf.IsManifest() {
+		metadata.Protocols, err = terraform_module.ParseProviderManifest(buf)
+		if err != nil {
+			apiError(ctx, http.StatusBadRequest, err)
+			return
+		}
+		pfci.OverwriteExisting = true
+	} else {
+		if _, err := zip.NewReader(buf, buf.Size()); err != nil {
+			apiError(ctx, http.StatusBadRequest, err)
+			return
+		}
+		pfci.IsLead = true
+		pfci.Properties = map[string]string{
+			terraform_module.PropertyOS:   pf.OS,
+			terraform_module.PropertyArch: pf.Arch,
+		}
+	}
+
+	if _, err := buf.Seek(0, io.SeekStart); err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	_, _, err = packages_service.CreatePackageOrAddFileToExisting(
+		ctx,
+		&packages_service.PackageCreationInfo{
+			PackageInfo: packages_service.PackageInfo{
+				Owner:       ctx.Package.Owner,
+				PackageType: packages_model.TypeTerraform,
+				Name:        name,
+				Version:     providerVersion,
+			},
+			SemverCompatible: true,
+			Creator:          ctx.Doer,
+			Metadata:         metadata,
+		},
+		pfci,
+	)
+	if err != nil {
+		switch {
+		case err == packages_model.ErrDuplicatePackageFile:
+			apiError(ctx, http.StatusConflict, err)
+		case err == packages_service.ErrQuotaTotalCount, err == packages_service.ErrQuotaTypeSize, err == packages_service.ErrQuotaTotalSize:
+			apiError(ctx, http.StatusForbidden, err)
+		case errors.Is(err, util.ErrPermissionDenied):
+			apiError(ctx, http.StatusForbidden, err)
+		default:
+			apiError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	// the manifest may be uploaded after the first archives, it is only stored once the file is accepted
+	if pf.IsManifest() {
+		if err := updateProviderProtocols(ctx, name, providerVersion, metadata.Protocols); err != nil {
+			apiError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	ctx.Status(http.StatusCreated)
+}
+
+func updateProviderProtocols(ctx *context.Context, name, version string, protocols []string) error {
+	pv, err := packages_model.GetVersionByNameAndVersion(ctx, ctx.Package.Owner.ID, packages_model.TypeTerraform, name, version)
+	if err != nil {
+		if err == packages_model.ErrPackageNotExist {
+			return nil
+		}
+		return err
+	}
+
+	metadata := &terraform_module.Metadata{}
+	if err := json.Unmarshal([]byte(pv.MetadataJSON), metadata); err != nil {
+		return err
+	}
+	metadata.Protocols = protocols
+
+	raw, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+	pv.MetadataJSON = string(raw)
+	return packages_model.UpdateVersion(ctx, pv)
+}
+
+// DownloadProviderFile serves an archive of a provider version, or its signed checksums
+func DownloadProviderFile(ctx *context.Context) {
+	name := ctx.Params("name")
+	version := ctx.Params("version")
+	filename := ctx.Params("filename")
+
+	shasums := shasumsFilename(name, version)
+	if filename != shasums && filename != shasums+".sig" {
+		serveFile(ctx, name, version, filename)
+		return
+	}
+
+	pd, err := getPackageDescriptor(ctx, name, version, terraform_module.KindProvider)
+	if err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			apiError(ctx, http.StatusNotFound, err)
+			return
+		}
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	content := terraform_service.BuildShasums(pd)
+	if filename != shasums {
+		content, err = terraform_service.SignShasums(ctx, ctx.Package.Owner.ID, content)
+		if err != nil {
+			apiError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	ctx.ServeContent(bytes.NewReader(content), &context.ServeHeaderOptions{
+		Filename:     filename,
+		LastModified: pd.Version.CreatedUnix.AsLocalTime(),
+	})
+}
+
+// DeleteProvider deletes a provider version
+func DeleteProvider(ctx *context.Context) {
+	deletePackageVersion(ctx, ctx.Params("name"), terraform_module.KindProvider)
+}
+
+func serveFile(ctx *context.Context, packageName, packageVersion, filename string) {
+	s, u, pf, err := packages_service.GetFileStreamByPackageNameAndVersion(
+		ctx,
+		ctx.Doer,
+		&packages_service.PackageInfo{
+			Owner:       ctx.Package.Owner,
+			PackageType: packages_model.TypeTerraform,
+			Name:        packageName,
+			Version:     packageVersion,
+		},
+		&packages_service.PackageFileInfo{
+			Filename: filename,
+		},
+	)
+	if err != nil {
+		if err == packages_model.ErrPackageNotExist || err == packages_model.ErrPackageFileNotExist {
+			apiError(ctx, http.StatusNotFound, err)
+			return
+		}
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	helper.ServePackageFile(ctx, s, u, pf)
+}
+
+func deletePackageVersion(ctx *context.Context, packageName string, kind terraform_module.Kind) {
+	pd, err := getPackageDescriptor(ctx, packageName, ctx.Params("version"), kind)
+	if err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			apiError(ctx, http.StatusNotFound, err)
+			return
+		}
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	if err := packages_service.RemovePackageVersion(ctx, ctx.Doer, pd.Version); err != nil {
+		if errors.Is(err, util.ErrPermissionDenied) {
+			apiError(ctx, http.StatusForbidden, err)
+			return
+		}
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
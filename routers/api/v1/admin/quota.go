@@ -0,0 +1,648 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package admin
+
+import (
+	std_context "context"
+	"net/http"
+
+	"code.gitea.io/gitea/models/db"
+	quota_model "code.gitea.io/gitea/models/quota"
+	user_model "code.gitea.io/gitea/models/user"
+	api "code.gitea.io/gitea/modules/structs"
+	"code.gitea.io/gitea/modules/web"
+	"code.gitea.io/gitea/routers/api/v1/shared"
+	"code.gitea.io/gitea/services/context"
+	"code.gitea.io/gitea/services/convert"
+)
+
+// getQuotaRule returns the quota rule from the URL, it writes a 404 if it doesn't exist
+func getQuotaRule(ctx *context.APIContext) *quota_model.Rule {
+	r, err := quota_model.GetRuleByName(ctx, ctx.Params("quotarule"))
+	if err != nil {
+		if quota_model.IsErrRuleNotExist(err) {
+			ctx.NotFound()
+		} else {
+			ctx.InternalServerError(err)
+		}
+		return nil
+	}
+	return r
+}
+
+// getQuotaGroup returns the quota group from the URL, it writes a 404 if it doesn't exist
+func getQuotaGroup(ctx *context.APIContext) *quota_model.Group {
+	g, err := quota_model.GetGroupByName(ctx, ctx.Params("quotagroup"))
+	if err != nil {
+		if quota_model.IsErrGroupNotExist(err) {
+			ctx.NotFound()
+		} else {
+			ctx.InternalServerError(err)
+		}
+		return nil
+	}
+	return g
+}
+
+// parseQuotaRuleOptions validates the limit and the subjects of a rule, it writes a 422 if they are invalid
+func parseQuotaRuleOptions(ctx *context.APIContext, limit int64, names []string) (quota_model.LimitSubjects, bool) {
+	if limit < -1 {
+		ctx.Error(http.StatusUnprocessableEntity, "", "the limit must be -1 or greater")
+		return nil, false
+	}
+	if len(names) == 0 {
+		ctx.Error(http.StatusUnprocessableEntity, "", "at least one subject is required")
+		return nil, false
+	}
+	subjects, err := quota_model.ParseLimitSubjects(names)
+	if err != nil {
+		ctx.Error(http.StatusUnprocessableEntity, "", err)
+		return nil, false
+	}
+	return subjects, true
+}
+
+// ListQuotaRules lists the quota rules
+func ListQuotaRules(ctx *context.APIContext) {
+	// swagger:operation GET /admin/quota/rules admin adminListQuotaRules
+	// ---
+	// summary: List the quota rules
+	// produces:
+	// - application/json
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/QuotaRuleInfoList"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+
+	rules, err := quota_model.GetRules(ctx)
+	if err != nil {
+		ctx.InternalServerError(err)
+		return
+	}
+
+	result := make([]api.QuotaRuleInfo, 0, len(rules))
+	for _, r := range rules {
+		result = append(result, convert.ToQuotaRuleInfo(r))
+	}
+	ctx.JSON(http.StatusOK, result)
+}
+
+// CreateQuotaRule creates a quota rule
+func CreateQuotaRule(ctx *context.APIContext) {
+	// swagger:operation POST /admin/quota/rules admin adminCreateQuotaRule
+	// ---
+	// summary: Create a quota rule
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: body
+	//   in: body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/CreateQuotaRuleOption"
+	// responses:
+	//   "201":
+	//     "$ref": "#/responses/QuotaRuleInfo"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "409":
+	//     "$ref": "#/responses/error"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
+
+	form := web.GetForm(ctx).(*api.CreateQuotaRuleOption)
+
+	subjects, ok := parseQuotaRuleOptions(ctx, form.Limit, form.Subjects)
+	if !ok {
+		return
+	}
+
+	r, err := quota_model.CreateRule(ctx, form.Name, form.Limit, subjects)
+	if err != nil {
+		if quota_model.IsErrRuleAlreadyExist(err) {
+			ctx.Error(http.StatusConflict, "", err)
+		} else {
+			ctx.InternalServerError(err)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, convert.ToQuotaRuleInfo(r))
+}
+
+// GetQuotaRule returns a quota rule
+func GetQuotaRule(ctx *context.APIContext) {
+	// swagger:operation GET /admin/quota/rules/{quotarule} admin adminGetQuotaRule
+	// ---
+	// summary: Get a quota rule
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: quotarule
+	//   in: path
+	//   description: name of the quota rule
+	//   type: string
+	//   required: true
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/QuotaRuleInfo"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	r := getQuotaRule(ctx)
+	if r == nil {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, convert.ToQuotaRuleInfo(r))
+}
+
+// EditQuotaRule updates the limit and the subjects of a quota rule
+func EditQuotaRule(ctx *context.APIContext) {
+	// swagger:operation PATCH /admin/quota/rules/{quotarule} admin adminEditQuotaRule
+	// ---
+	// summary: Edit the limit and the subjects of a quota rule
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: quotarule
+	//   in: path
+	//   description: name of the quota rule
+	//   type: string
+	//   required: true
+	// - name: body
+	//   in: body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/EditQuotaRuleOption"
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/QuotaRuleInfo"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
+
+	r := getQuotaRule(ctx)
+	if r == nil {
+		return
+	}
+
+	form := web.GetForm(ctx).(*api.EditQuotaRuleOption)
+
+	limit := r.Limit
+	if form.Limit != nil {
+		limit = *form.Limit
+	}
+	names := r.Subjects.Names()
+	if form.Subjects != nil {
+		names = *form.Subjects
+	}
+
+	subjects, ok := parseQuotaRuleOptions(ctx, limit, names)
+	if !ok {
+		return
+	}
+
+	r.Limit = limit
+	r.Subjects = subjects
+	if err := quota_model.UpdateRule(ctx, r); err != nil {
+		ctx.InternalServerError(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, convert.ToQuotaRuleInfo(r))
+}
+
+// DeleteQuotaRule deletes a quota rule
+func DeleteQuotaRule(ctx *context.APIContext) {
+	// swagger:operation DELETE /admin/quota/rules/{quotarule} admin adminDeleteQuotaRule
+	// ---
+	// summary: Delete a quota rule, it's removed from all groups
+	// parameters:
+	// - name: quotarule
+	//   in: path
+	//   description: name of the quota rule
+	//   type: string
+	//   required: true
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/empty"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	r := getQuotaRule(ctx)
+	if r == nil {
+		return
+	}
+
+	if err := quota_model.DeleteRule(ctx, r); err != nil {
+		ctx.InternalServerError(err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// ListQuotaGroups lists the quota groups
+func ListQuotaGroups(ctx *context.APIContext) {
+	// swagger:operation GET /admin/quota/groups admin adminListQuotaGroups
+	// ---
+	// summary: List the quota groups
+	// produces:
+	// - application/json
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/QuotaGroupList"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+
+	groups, err := quota_model.GetGroups(ctx)
+	if err != nil {
+		ctx.InternalServerError(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, convert.ToQuotaGroupList(groups))
+}
+
+// CreateQuotaGroup creates a quota group
+func CreateQuotaGroup(ctx *context.APIContext) {
+	// swagger:operation POST /admin/quota/groups admin adminCreateQuotaGroup
+	// ---
+	// summary: Create a quota group
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: body
+	//   in: body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/CreateQuotaGroupOption"
+	// responses:
+	//   "201":
+	//     "$ref": "#/responses/QuotaGroup"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "409":
+	//     "$ref": "#/responses/error"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
+
+	form := web.GetForm(ctx).(*api.CreateQuotaGroupOption)
+
+	rules := make([]*quota_model.Rule, 0, len(form.Rules))
+	for _, name := range form.Rules {
+		r, err := quota_model.GetRuleByName(ctx, name)
+		if err != nil {
+			if quota_model.IsErrRuleNotExist(err) {
+				ctx.Error(http.StatusUnprocessableEntity, "", err)
+			} else {
+				ctx.InternalServerError(err)
+			}
+			return
+		}
+		rules = append(rules, r)
+	}
+
+	var g *quota_model.Group
+	err := db.WithTx(ctx, func(ctx std_context.Context) error {
+		var err error
+		if g, err = quota_model.CreateGroup(ctx, form.Name); err != nil {
+			return err
+		}
+		for _, r := range rules {
+			if err := quota_model.AddRuleToGroup(ctx, g, r); err != nil {
+				return err
+			}
+		}
+		return g.LoadRules(ctx)
+	})
+	if err != nil {
+		if quota_model.IsErrGroupAlreadyExist(err) {
+			ctx.Error(http.StatusConflict, "", err)
+		} else {
+			ctx.InternalServerError(err)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, convert.ToQuotaGroup(g))
+}
+
+// GetQuotaGroup returns a quota group
+func GetQuotaGroup(ctx *context.APIContext) {
+	// swagger:operation GET /admin/quota/groups/{quotagroup} admin adminGetQuotaGroup
+	// ---
+	// summary: Get a quota group
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: quotagroup
+	//   in: path
+	//   description: name of the quota group
+	//   type: string
+	//   required: true
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/QuotaGroup"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	g := getQuotaGroup(ctx)
+	if g == nil {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, convert.ToQuotaGroup(g))
+}
+
+// DeleteQuotaGroup deletes a quota group
+func DeleteQuotaGroup(ctx *context.APIContext) {
+	// swagger:operation DELETE /admin/quota/groups/{quotagroup} admin adminDeleteQuotaGroup
+	// ---
+	// summary: Delete a quota group, its rules are kept
+	// parameters:
+	// - name: quotagroup
+	//   in: path
+	//   description: name of the quota group
+	//   type: string
+	//   required: true
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/empty"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	g := getQuotaGroup(ctx)
+	if g == nil {
+		return
+	}
+
+	if err := quota_model.DeleteGroup(ctx, g); err != nil {
+		ctx.InternalServerError(err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// AddRuleToQuotaGroup adds a quota rule to a quota group
+func AddRuleToQuotaGroup(ctx *context.APIContext) {
+	// swagger:operation PUT /admin/quota/groups/{quotagroup}/rules/{quotarule} admin adminAddRuleToQuotaGroup
+	// ---
+	// summary: Add a quota rule to a quota group
+	// parameters:
+	// - name: quotagroup
+	//   in: path
+	//   description: name of the quota group
+	//   type: string
+	//   required: true
+	// - name: quotarule
+	//   in: path
+	//   description: name of the quota rule
+	//   type: string
+	//   required: true
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/empty"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	g := getQuotaGroup(ctx)
+	if g == nil {
+		return
+	}
+	r := getQuotaRule(ctx)
+	if r == nil {
+		return
+	}
+
+	if err := quota_model.AddRuleToGroup(ctx, g, r); err != nil {
+		ctx.InternalServerError(err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// RemoveRuleFromQuotaGroup removes a quota rule from a quota group
+func RemoveRuleFromQuotaGroup(ctx *context.APIContext) {
+	// swagger:operation DELETE /admin/quota/groups/{quotagroup}/rules/{quotarule} admin adminRemoveRuleFromQuotaGroup
+	// ---
+	// summary: Remove a quota rule from a quota group
+	// parameters:
+	// - name: quotagroup
+	//   in: path
+	//   description: name of the quota group
+	//   type: string
+	//   required: true
+	// - name: quotarule
+	//   in: path
+	//   description: name of the quota rule
+	//   type: string
+	//   required: true
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/empty"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	g := getQuotaGroup(ctx)
+	if g == nil {
+		return
+	}
+	r := getQuotaRule(ctx)
+	if r == nil {
+		return
+	}
+
+	if err := quota_model.RemoveRuleFromGroup(ctx, g, r); err != nil {
+		ctx.InternalServerError(err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// ListUsersInQuotaGroup lists the users and organizations which are assigned to a quota group
+func ListUsersInQuotaGroup(ctx *context.APIContext) {
+	// swagger:operation GET /admin/quota/groups/{quotagroup}/users admin adminListUsersInQuotaGroup
+	// ---
+	// summary: List the users and organizations which are assigned to a quota group
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: quotagroup
+	//   in: path
+	//   description: name of the quota group
+	//   type: string
+	//   required: true
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/UserList"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	g := getQuotaGroup(ctx)
+	if g == nil {
+		return
+	}
+
+	ids, err := quota_model.GetUserIDsOfGroup(ctx, g)
+	if err != nil {
+		ctx.InternalServerError(err)
+		return
+	}
+	users, err := user_model.GetUsersByIDs(ctx, ids)
+	if err != nil {
+		ctx.InternalServerError(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, convert.ToUsers(ctx, ctx.Doer, users))
+}
+
+// getQuotaGroupUser returns the user or organization from the URL, it writes a 404 if it doesn't exist
+func getQuotaGroupUser(ctx *context.APIContext) *user_model.User {
+	u, err := user_model.GetUserByName(ctx, ctx.Params("username"))
+	if err != nil {
+		if user_model.IsErrUserNotExist(err) {
+			ctx.NotFound()
+		} else {
+			ctx.InternalServerError(err)
+		}
+		return nil
+	}
+	return u
+}
+
+// AddUserToQuotaGroup assigns a quota group to a user or an organization
+func AddUserToQuotaGroup(ctx *context.APIContext) {
+	// swagger:operation PUT /admin/quota/groups/{quotagroup}/users/{username} admin adminAddUserToQuotaGroup
+	// ---
+	// summary: Assign a quota group to a user or an organization
+	// parameters:
+	// - name: quotagroup
+	//   in: path
+	//   description: name of the quota group
+	//   type: string
+	//   required: true
+	// - name: username
+	//   in: path
+	//   description: name of the user or organization
+	//   type: string
+	//   required: true
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/empty"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	g := getQuotaGroup(ctx)
+	if g == nil {
+		return
+	}
+	u := getQuotaGroupUser(ctx)
+	if u == nil {
+		return
+	}
+
+	if err := quota_model.AddUserToGroup(ctx, g, u.ID); err != nil {
+		ctx.InternalServerError(err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// RemoveUserFromQuotaGroup removes a quota group from a user or an organization
+func RemoveUserFromQuotaGroup(ctx *context.APIContext) {
+	// swagger:operation DELETE /admin/quota/groups/{quotagroup}/users/{username} admin adminRemoveUserFromQuotaGroup
+	// ---
+	// summary: Remove a quota group from a user or an organization
+	// parameters:
+	// - name: quotagroup
+	//   in: path
+	//   description: name of the quota group
+	//   type: string
+	//   required: true
+	// - name: username
+	//   in: path
+	//   description: name of the user or organization
+	//   type: string
+	//   required: true
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/empty"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	g := getQuotaGroup(ctx)
+	if g == nil {
+		return
+	}
+	u := getQuotaGroupUser(ctx)
+	if u == nil {
+		return
+	}
+
+	if err := quota_model.RemoveUserFromGroup(ctx, g, u.ID); err != nil {
+		ctx.InternalServerError(err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// GetUserQuota returns the used storage and the quota groups of a user or an organization
+func GetUserQuota(ctx *context.APIContext) {
+	// swagger:operation GET /admin/users/{username}/quota admin adminGetUserQuota
+	// ---
+	// summary: Get the used storage and the quota groups of a user or an organization
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: username
+	//   in: path
+	//   description: name of the user or organization
+	//   type: string
+	//   required: true
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/QuotaInfo"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	shared.GetQuota(ctx, ctx.ContextUser.ID)
+}
@@ -0,0 +1,194 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package admin
+
+import (
+	"code.gitea.io/gitea/routers/api/v1/shared"
+	"code.gitea.io/gitea/services/context"
+)
+
+// ListRunnerGroups lists the runner groups of the instance
+func ListRunnerGroups(ctx *context.APIContext) {
+	// swagger:operation GET /admin/runners/groups admin adminListRunnerGroups
+	// ---
+	// summary: List the runner groups of the instance
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: page
+	//   in: query
+	//   description: page number of results to return (1-based)
+	//   type: integer
+	// - name: limit
+	//   in: query
+	//   description: page size of results
+	//   type: integer
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/ActionRunnerGroupList"
+
+	shared.ListRunnerGroups(ctx, 0)
+}
+
+// CreateRunnerGroup creates a runner group for the instance
+func CreateRunnerGroup(ctx *context.APIContext) {
+	// swagger:operation POST /admin/runners/groups admin adminCreateRunnerGroup
+	// ---
+	// summary: Create a runner group for the instance
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: body
+	//   in: body
+	//   schema:
+	//     "$ref": "#/definitions/CreateActionRunnerGroupOption"
+	// responses:
+	//   "201":
+	//     "$ref": "#/responses/ActionRunnerGroup"
+	//   "409":
+	//     "$ref": "#/responses/error"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
+
+	shared.CreateRunnerGroup(ctx, 0)
+}
+
+// GetRunnerGroup returns a runner group of the instance
+func GetRunnerGroup(ctx *context.APIContext) {
+	// swagger:operation GET /admin/runners/groups/{group_id} admin adminGetRunnerGroup
+	// ---
+	// summary: Get a runner group of the instance
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: group_id
+	//   in: path
+	//   description: id of the runner group
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/ActionRunnerGroup"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	shared.GetRunnerGroup(ctx, 0)
+}
+
+// EditRunnerGroup updates the access policies of a runner group of the instance
+func EditRunnerGroup(ctx *context.APIContext) {
+	// swagger:operation PATCH /admin/runners/groups/{group_id} admin adminEditRunnerGroup
+	// ---
+	// summary: Edit the access policies of a runner group of the instance
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: group_id
+	//   in: path
+	//   description: id of the runner group
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// - name: body
+	//   in: body
+	//   schema:
+	//     "$ref": "#/definitions/EditActionRunnerGroupOption"
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/ActionRunnerGroup"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
+
+	shared.EditRunnerGroup(ctx, 0)
+}
+
+// DeleteRunnerGroup deletes a runner group of the instance
+func DeleteRunnerGroup(ctx *context.APIContext) {
+	// swagger:operation DELETE /admin/runners/groups/{group_id} admin adminDeleteRunnerGroup
+	// ---
+	// summary: Delete a runner group of the instance
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: group_id
+	//   in: path
+	//   description: id of the runner group
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/empty"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	shared.DeleteRunnerGroup(ctx, 0)
+}
+
+// AddRunnerToGroup adds a runner of the instance to a runner group
+func AddRunnerToGroup(ctx *context.APIContext) {
+	// swagger:operation PUT /admin/runners/groups/{group_id}/runners/{runner_id} admin adminAddRunnerToGroup
+	// ---
+	// summary: Add a runner of the instance to a runner group
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: group_id
+	//   in: path
+	//   description: id of the runner group
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// - name: runner_id
+	//   in: path
+	//   description: id of the runner
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/empty"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
+
+	shared.SetRunnerGroup(ctx, 0, true)
+}
+
+// RemoveRunnerFromGroup removes a runner of the instance from a runner group
+func RemoveRunnerFromGroup(ctx *context.APIContext) {
+	// swagger:operation DELETE /admin/runners/groups/{group_id}/runners/{runner_id} admin adminRemoveRunnerFromGroup
+	// ---
+	// summary: Remove a runner of the instance from a runner group
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: group_id
+	//   in: path
+	//   description: id of the runner group
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// - name: runner_id
+	//   in: path
+	//   description: id of the runner
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/empty"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	shared.SetRunnerGroup(ctx, 0, false)
+}
@@ -896,6 +896,7 @@ func Routes() *web.Route {
 				m.Get("", user.GetUserSettings)
 				m.Patch("", bind(api.UserSettingsOptions{}), user.UpdateUserSettings)
 			}, reqToken())
+			m.Get("/quota", reqToken(), user.GetQuota)
 			m.Combo("/emails").
 				Get(user.ListEmails).
 				Post(bind(api.CreateEmailOption{}), user.AddEmail).
@@ -1123,6 +1124,10 @@ func Routes() *web.Route {
 				}, reqToken(), reqAdmin())
 				m.Group("/actions", func() {
 					m.Get("/tasks", repo.ListActionTasks)
+					m.Group("/runs", func() {
+						m.Get("", repo.ListActionRuns)
+						m.Post("/{run_id}/approve", reqToken(), reqRepoWriter(unit.TypeActions), mustNotBeArchived, repo.ApproveActionRun)
+					})
 
 					m.Group("/workflows", func() {
 						m.Group("/{workflowname}", func() {
@@ -1423,6 +1428,13 @@ func Routes() *web.Route {
 				m.Get("", reqToken(), packages.GetPackage)
 				m.Delete("", reqToken(), reqPackageAccess(perm.AccessModeWrite), packages.DeletePackage)
 				m.Get("/files", reqToken(), packages.ListPackageFiles)
+				m.Get("/stats", reqToken(), packages.GetPackageDownloadStats)
+				m.Combo("/deprecation", reqToken(), reqPackageAccess(perm.AccessModeWrite)).
+					Put(bind(api.DeprecatePackageOption{}), packages.DeprecatePackage).
+					Delete(packages.UndeprecatePackage)
+				m.Combo("/lock", reqToken()).
+					Put(reqPackageAccess(perm.AccessModeWrite), packages.LockPackage).
+					Delete(reqPackageAccess(perm.AccessModeAdmin), packages.UnlockPackage)
 			})
 			m.Get("/", reqToken(), packages.ListPackages)
 		}, tokenRequiresScopes(auth_model.AccessTokenScopeCategoryPackage), context.UserAssignmentAPI(), context.PackageAssignmentAPI(), reqPackageAccess(perm.AccessModeRead))
@@ -1441,6 +1453,7 @@ func Routes() *web.Route {
 				Delete(reqToken(), reqOrgOwnership(), org.Delete)
 			m.Combo("/repos").Get(user.ListOrgRepos).
 				Post(reqToken(), bind(api.CreateRepoOption{}), repo.CreateOrgRepo)
+			m.Get("/quota", reqToken(), reqOrgOwnership(), org.GetQuota)
 			m.Group("/members", func() {
 				m.Get("", reqToken(), org.ListMembers)
 				m.Combo("/{username}").Get(reqToken(), org.IsMember).
@@ -1451,6 +1464,15 @@ func Routes() *web.Route {
 				reqOrgOwnership(),
 				org.NewAction(),
 			)
+			m.Group("/actions/runners/groups", func() {
+				m.Combo("").Get(org.ListRunnerGroups).
+					Post(bind(api.CreateActionRunnerGroupOption{}), org.CreateRunnerGroup)
+				m.Combo("/{group_id}").Get(org.GetRunnerGroup).
+					Patch(bind(api.EditActionRunnerGroupOption{}), org.EditRunnerGroup).
+					Delete(org.DeleteRunnerGroup)
+				m.Combo("/{group_id}/runners/{runner_id}").Put(org.AddRunnerToGroup).
+					Delete(org.RemoveRunnerFromGroup)
+			}, reqToken(), reqOrgOwnership())
 			m.Group("/public_members", func() {
 				m.Get("", org.ListPublicMembers)
 				m.Combo("/{username}").Get(org.IsPublicMember).
@@ -1531,6 +1553,7 @@ func Routes() *web.Route {
 					m.Post("/orgs", bind(api.CreateOrgOption{}), admin.CreateOrg)
 					m.Post("/repos", bind(api.CreateRepoOption{}), admin.CreateRepo)
 					m.Post("/rename", bind(api.RenameUserOption{}), admin.RenameUser)
+					m.Get("/quota", admin.GetUserQuota)
 				}, context.UserAssignmentAPI())
 			})
 			m.Group("/emails", func() {
@@ -1551,6 +1574,37 @@ func Routes() *web.Route {
 			})
 			m.Group("/runners", func() {
 				m.Get("/registration-token", admin.GetRegistrationToken)
+				m.Group("/groups", func() {
+					m.Combo("").Get(admin.ListRunnerGroups).
+						Post(bind(api.CreateActionRunnerGroupOption{}), admin.CreateRunnerGroup)
+					m.Combo("/{group_id}").Get(admin.GetRunnerGroup).
+						Patch(bind(api.EditActionRunnerGroupOption{}), admin.EditRunnerGroup).
+						Delete(admin.DeleteRunnerGroup)
+					m.Combo("/{group_id}/runners/{runner_id}").Put(admin.AddRunnerToGroup).
+						Delete(admin.RemoveRunnerFromGroup)
+				})
+			})
+			m.Group("/quota", func() {
+				m.Group("/rules", func() {
+					m.Combo("").Get(admin.ListQuotaRules).
+						Post(bind(api.CreateQuotaRuleOption{}), admin.CreateQuotaRule)
+					m.Combo("/{quotarule}").Get(admin.GetQuotaRule).
+						Patch(bind(api.EditQuotaRuleOption{}), admin.EditQuotaRule).
+						Delete(admin.DeleteQuotaRule)
+				})
+				m.Group("/groups", func() {
+					m.Combo("").Get(admin.ListQuotaGroups).
+						Post(bind(api.CreateQuotaGroupOption{}), admin.CreateQuotaGroup)
+					m.Group("/{quotagroup}", func() {
+						m.Combo("").Get(admin.GetQuotaGroup).
+							Delete(admin.DeleteQuotaGroup)
+						m.Combo("/rules/{quotarule}").Put(admin.AddRuleToQuotaGroup).
+							Delete(admin.RemoveRuleFromQuotaGroup)
+						m.Get("/users", admin.ListUsersInQuotaGroup)
+						m.Combo("/users/{username}").Put(admin.AddUserToQuotaGroup).
+							Delete(admin.RemoveUserFromQuotaGroup)
+					})
+				})
 			})
 		}, tokenRequiresScopes(auth_model.AccessTokenScopeCategoryAdmin), reqToken(), reqSiteAdmin())
 
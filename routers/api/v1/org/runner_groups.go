@@ -0,0 +1,229 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package org
+
+import (
+	"code.gitea.io/gitea/routers/api/v1/shared"
+	"code.gitea.io/gitea/services/context"
+)
+
+// ListRunnerGroups lists the runner groups of the runners of an organization
+func ListRunnerGroups(ctx *context.APIContext) {
+	// swagger:operation GET /orgs/{org}/actions/runners/groups organization orgListRunnerGroups
+	// ---
+	// summary: List the runner groups of an organization's runners
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: org
+	//   in: path
+	//   description: name of the organization
+	//   type: string
+	//   required: true
+	// - name: page
+	//   in: query
+	//   description: page number of results to return (1-based)
+	//   type: integer
+	// - name: limit
+	//   in: query
+	//   description: page size of results
+	//   type: integer
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/ActionRunnerGroupList"
+
+	shared.ListRunnerGroups(ctx, ctx.Org.Organization.ID)
+}
+
+// CreateRunnerGroup creates a runner group for the runners of an organization
+func CreateRunnerGroup(ctx *context.APIContext) {
+	// swagger:operation POST /orgs/{org}/actions/runners/groups organization orgCreateRunnerGroup
+	// ---
+	// summary: Create a runner group for an organization's runners
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: org
+	//   in: path
+	//   description: name of the organization
+	//   type: string
+	//   required: true
+	// - name: body
+	//   in: body
+	//   schema:
+	//     "$ref": "#/definitions/CreateActionRunnerGroupOption"
+	// responses:
+	//   "201":
+	//     "$ref": "#/responses/ActionRunnerGroup"
+	//   "409":
+	//     "$ref": "#/responses/error"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
+
+	shared.CreateRunnerGroup(ctx, ctx.Org.Organization.ID)
+}
+
+// GetRunnerGroup returns a runner group of the runners of an organization
+func GetRunnerGroup(ctx *context.APIContext) {
+	// swagger:operation GET /orgs/{org}/actions/runners/groups/{group_id} organization orgGetRunnerGroup
+	// ---
+	// summary: Get a runner group of an organization's runners
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: org
+	//   in: path
+	//   description: name of the organization
+	//   type: string
+	//   required: true
+	// - name: group_id
+	//   in: path
+	//   description: id of the runner group
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/ActionRunnerGroup"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	shared.GetRunnerGroup(ctx, ctx.Org.Organization.ID)
+}
+
+// EditRunnerGroup updates the access policies of a runner group of the runners of an organization
+func EditRunnerGroup(ctx *context.APIContext) {
+	// swagger:operation PATCH /orgs/{org}/actions/runners/groups/{group_id} organization orgEditRunnerGroup
+	// ---
+	// summary: Edit the access policies of a runner group of an organization's runners
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: org
+	//   in: path
+	//   description: name of the organization
+	//   type: string
+	//   required: true
+	// - name: group_id
+	//   in: path
+	//   description: id of the runner group
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// - name: body
+	//   in: body
+	//   schema:
+	//     "$ref": "#/definitions/EditActionRunnerGroupOption"
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/ActionRunnerGroup"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
+
+	shared.EditRunnerGroup(ctx, ctx.Org.Organization.ID)
+}
+
+// DeleteRunnerGroup deletes a runner group of the runners of an organization
+func DeleteRunnerGroup(ctx *context.APIContext) {
+	// swagger:operation DELETE /orgs/{org}/actions/runners/groups/{group_id} organization orgDeleteRunnerGroup
+	// ---
+	// summary: Delete a runner group of an organization's runners
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: org
+	//   in: path
+	//   description: name of the organization
+	//   type: string
+	//   required: true
+	// - name: group_id
+	//   in: path
+	//   description: id of the runner group
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/empty"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	shared.DeleteRunnerGroup(ctx, ctx.Org.Organization.ID)
+}
+
+// AddRunnerToGroup adds a runner of the runners of an organization to a runner group
+func AddRunnerToGroup(ctx *context.APIContext) {
+	// swagger:operation PUT /orgs/{org}/actions/runners/groups/{group_id}/runners/{runner_id} organization orgAddRunnerToGroup
+	// ---
+	// summary: Add a runner of an organization's runners to a runner group
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: org
+	//   in: path
+	//   description: name of the organization
+	//   type: string
+	//   required: true
+	// - name: group_id
+	//   in: path
+	//   description: id of the runner group
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// - name: runner_id
+	//   in: path
+	//   description: id of the runner
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/empty"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
+
+	shared.SetRunnerGroup(ctx, ctx.Org.Organization.ID, true)
+}
+
+// RemoveRunnerFromGroup removes a runner of the runners of an organization from a runner group
+func RemoveRunnerFromGroup(ctx *context.APIContext) {
+	// swagger:operation DELETE /orgs/{org}/actions/runners/groups/{group_id}/runners/{runner_id} organization orgRemoveRunnerFromGroup
+	// ---
+	// summary: Remove a runner of an organization's runners from a runner group
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: org
+	//   in: path
+	//   description: name of the organization
+	//   type: string
+	//   required: true
+	// - name: group_id
+	//   in: path
+	//   description: id of the runner group
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// - name: runner_id
+	//   in: path
+	//   description: id of the runner
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/empty"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	shared.SetRunnerGroup(ctx, ctx.Org.Organization.ID, false)
+}
@@ -4,15 +4,22 @@
 package packages
 
 import (
+	"errors"
 	"net/http"
+	"strings"
+	"time"
 
 	"code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/models/perm"
 	"code.gitea.io/gitea/modules/optional"
 	api "code.gitea.io/gitea/modules/structs"
+	"code.gitea.io/gitea/modules/util"
+	"code.gitea.io/gitea/modules/web"
 	"code.gitea.io/gitea/routers/api/v1/utils"
 	"code.gitea.io/gitea/services/context"
 	"code.gitea.io/gitea/services/convert"
 	packages_service "code.gitea.io/gitea/services/packages"
+	cargo_service "code.gitea.io/gitea/services/packages/cargo"
 )
 
 // ListPackages gets all packages of an owner
@@ -40,7 +47,7 @@ func ListPackages(ctx *context.APIContext) {
 	//   in: query
 	//   description: package type filter
 	//   type: string
-	//   enum: [alpine, cargo, chef, composer, conan, conda, container, cran, debian, generic, go, helm, maven, npm, nuget, pub, pypi, rpm, rubygems, swift, vagrant]
+	//   enum: [alpine, arch, cargo, chef, composer, conan, conda, container, cran, debian, generic, go, helm, maven, nix, npm, nuget, pub, pypi, rpm, rubygems, swift, terraform, vagrant]
 	// - name: q
 	//   in: query
 	//   description: name filter
@@ -161,11 +168,17 @@ func DeletePackage(ctx *context.APIContext) {
 	// responses:
 	//   "204":
 	//     "$ref": "#/responses/empty"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
 	//   "404":
 	//     "$ref": "#/responses/notFound"
 
 	err := packages_service.RemovePackageVersion(ctx, ctx.Doer, ctx.Package.Descriptor.Version)
 	if err != nil {
+		if errors.Is(err, util.ErrPermissionDenied) {
+			ctx.Error(http.StatusForbidden, "RemovePackageVersion", err)
+			return
+		}
 		ctx.Error(http.StatusInternalServerError, "RemovePackageVersion", err)
 		return
 	}
@@ -213,3 +226,263 @@ func ListPackageFiles(ctx *context.APIContext) {
 
 	ctx.JSON(http.StatusOK, apiPackageFiles)
 }
+
+// GetPackageDownloadStats gets the download statistics of a package version
+func GetPackageDownloadStats(ctx *context.APIContext) {
+	// swagger:operation GET /packages/{owner}/{type}/{name}/{version}/stats package getPackageDownloadStats
+	// ---
+	// summary: Gets the download statistics of a package version
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the package
+	//   type: string
+	//   required: true
+	// - name: type
+	//   in: path
+	//   description: type of the package
+	//   type: string
+	//   required: true
+	// - name: name
+	//   in: path
+	//   description: name of the package
+	//   type: string
+	//   required: true
+	// - name: version
+	//   in: path
+	//   description: version of the package
+	//   type: string
+	//   required: true
+	// - name: days
+	//   in: query
+	//   description: number of days to return, including today (default 30, maximum 365)
+	//   type: integer
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/PackageDownloadStats"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
+
+	days := ctx.FormInt("days")
+	if days == 0 {
+		days = 30
+	}
+	if days < 1 || days > 365 {
+		ctx.Error(http.StatusUnprocessableEntity, "", "days must be between 1 and 365")
+		return
+	}
+
+	withUsers := ctx.Package.AccessMode >= perm.AccessModeWrite
+
+	stats, err := packages_service.GetDownloadStats(ctx, ctx.Package.Descriptor.Version, days, withUsers)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "GetDownloadStats", err)
+		return
+	}
+
+	apiStats := &api.PackageDownloadStats{
+		TotalDownloads: ctx.Package.Descriptor.Version.DownloadCount,
+		Days:           make([]*api.PackageDownloadsPerDay, 0, len(stats.Days)),
+	}
+	for _, d := range stats.Days {
+		apiStats.Days = append(apiStats.Days, &api.PackageDownloadsPerDay{
+			Date:      d.DayUnix.AsTimeInLocation(time.UTC).Format(time.DateOnly),
+			Downloads: d.Downloads,
+		})
+	}
+	if withUsers {
+		apiStats.Users = make([]*api.PackageDownloadsPerUser, 0, len(stats.Users))
+		for _, d := range stats.Users {
+			apiStats.Users = append(apiStats.Users, &api.PackageDownloadsPerUser{
+				User:      convert.ToUser(ctx, d.User, ctx.Doer),
+				Downloads: d.Downloads,
+			})
+		}
+	}
+
+	ctx.JSON(http.StatusOK, apiStats)
+}
+
+// DeprecatePackage marks a package version as deprecated
+func DeprecatePackage(ctx *context.APIContext) {
+	// swagger:operation PUT /packages/{owner}/{type}/{name}/{version}/deprecation package deprecatePackage
+	// ---
+	// summary: Deprecate a package version
+	// consumes:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the package
+	//   type: string
+	//   required: true
+	// - name: type
+	//   in: path
+	//   description: type of the package
+	//   type: string
+	//   required: true
+	// - name: name
+	//   in: path
+	//   description: name of the package
+	//   type: string
+	//   required: true
+	// - name: version
+	//   in: path
+	//   description: version of the package
+	//   type: string
+	//   required: true
+	// - name: body
+	//   in: body
+	//   schema:
+	//     "$ref": "#/definitions/DeprecatePackageOption"
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/empty"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
+
+	form := web.GetForm(ctx).(*api.DeprecatePackageOption)
+
+	setPackageDeprecation(ctx, strings.TrimSpace(form.Message))
+}
+
+// UndeprecatePackage removes the deprecation of a package version
+func UndeprecatePackage(ctx *context.APIContext) {
+	// swagger:operation DELETE /packages/{owner}/{type}/{name}/{version}/deprecation package undeprecatePackage
+	// ---
+	// summary: Remove the deprecation of a package version
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the package
+	//   type: string
+	//   required: true
+	// - name: type
+	//   in: path
+	//   description: type of the package
+	//   type: string
+	//   required: true
+	// - name: name
+	//   in: path
+	//   description: name of the package
+	//   type: string
+	//   required: true
+	// - name: version
+	//   in: path
+	//   description: version of the package
+	//   type: string
+	//   required: true
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/empty"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	setPackageDeprecation(ctx, "")
+}
+
+func setPackageDeprecation(ctx *context.APIContext, message string) {
+	pd := ctx.Package.Descriptor
+
+	if err := packages_service.SetVersionDeprecation(ctx, pd.Version, message); err != nil {
+		ctx.Error(http.StatusInternalServerError, "SetVersionDeprecation", err)
+		return
+	}
+
+	// the yanked state is part of the Cargo index
+	if pd.Package.Type == packages.TypeCargo {
+		if err := cargo_service.UpdatePackageIndexIfExists(ctx, ctx.Doer, pd.Owner, pd.Package.ID); err != nil {
+			ctx.Error(http.StatusInternalServerError, "UpdatePackageIndexIfExists", err)
+			return
+		}
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// LockPackage protects a package version from deletion and cleanup rules
+func LockPackage(ctx *context.APIContext) {
+	// swagger:operation PUT /packages/{owner}/{type}/{name}/{version}/lock package lockPackage
+	// ---
+	// summary: Lock a package version, locked versions can't be deleted and are kept by cleanup rules
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the package
+	//   type: string
+	//   required: true
+	// - name: type
+	//   in: path
+	//   description: type of the package
+	//   type: string
+	//   required: true
+	// - name: name
+	//   in: path
+	//   description: name of the package
+	//   type: string
+	//   required: true
+	// - name: version
+	//   in: path
+	//   description: version of the package
+	//   type: string
+	//   required: true
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/empty"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	setPackageRetentionLock(ctx, true)
+}
+
+// UnlockPackage removes the retention lock of a package version
+func UnlockPackage(ctx *context.APIContext) {
+	// swagger:operation DELETE /packages/{owner}/{type}/{name}/{version}/lock package unlockPackage
+	// ---
+	// summary: Unlock a package version, only the owner and the administrators of the organization can unlock it
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the package
+	//   type: string
+	//   required: true
+	// - name: type
+	//   in: path
+	//   description: type of the package
+	//   type: string
+	//   required: true
+	// - name: name
+	//   in: path
+	//   description: name of the package
+	//   type: string
+	//   required: true
+	// - name: version
+	//   in: path
+	//   description: version of the package
+	//   type: string
+	//   required: true
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/empty"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	setPackageRetentionLock(ctx, false)
+}
+
+func setPackageRetentionLock(ctx *context.APIContext, locked bool) {
+	if err := packages_service.SetVersionRetentionLock(ctx, ctx.Package.Descriptor.Version, locked); err != nil {
+		ctx.Error(http.StatusInternalServerError, "SetVersionRetentionLock", err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	issues_model "code.gitea.io/gitea/models/issues"
+	quota_model "code.gitea.io/gitea/models/quota"
 	repo_model "code.gitea.io/gitea/models/repo"
 	"code.gitea.io/gitea/modules/log"
 	"code.gitea.io/gitea/modules/setting"
@@ -160,6 +161,8 @@ func CreateIssueAttachment(ctx *context.APIContext) {
 	//     "$ref": "#/responses/error"
 	//   "404":
 	//     "$ref": "#/responses/error"
+	//   "413":
+	//     "$ref": "#/responses/quotaExceeded"
 	//   "422":
 	//     "$ref": "#/responses/validationError"
 	//   "423":
@@ -212,6 +215,8 @@ func CreateIssueAttachment(ctx *context.APIContext) {
 	if err != nil {
 		if upload.IsErrFileTypeForbidden(err) {
 			ctx.Error(http.StatusUnprocessableEntity, "", err)
+		} else if quota_model.IsErrQuotaExceeded(err) {
+			ctx.Error(http.StatusRequestEntityTooLarge, "", err)
 		} else {
 			ctx.Error(http.StatusInternalServerError, "UploadAttachment", err)
 		}
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"strings"
 
+	quota_model "code.gitea.io/gitea/models/quota"
 	repo_model "code.gitea.io/gitea/models/repo"
 	"code.gitea.io/gitea/modules/log"
 	"code.gitea.io/gitea/modules/setting"
@@ -191,6 +192,8 @@ func CreateReleaseAttachment(ctx *context.APIContext) {
 	//     "$ref": "#/responses/error"
 	//   "404":
 	//     "$ref": "#/responses/notFound"
+	//   "413":
+	//     "$ref": "#/responses/quotaExceeded"
 
 	// Check if attachments are enabled
 	if !setting.Attachment.Enabled {
@@ -245,6 +248,10 @@ func CreateReleaseAttachment(ctx *context.APIContext) {
 			ctx.Error(http.StatusBadRequest, "DetectContentType", err)
 			return
 		}
+		if quota_model.IsErrQuotaExceeded(err) {
+			ctx.Error(http.StatusRequestEntityTooLarge, "", err)
+			return
+		}
 		ctx.Error(http.StatusInternalServerError, "NewAttachment", err)
 		return
 	}
@@ -0,0 +1,225 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package shared
+
+import (
+	"errors"
+	"net/http"
+
+	actions_model "code.gitea.io/gitea/models/actions"
+	"code.gitea.io/gitea/models/db"
+	api "code.gitea.io/gitea/modules/structs"
+	"code.gitea.io/gitea/modules/util"
+	"code.gitea.io/gitea/modules/web"
+	"code.gitea.io/gitea/routers/api/v1/utils"
+	actions_service "code.gitea.io/gitea/services/actions"
+	"code.gitea.io/gitea/services/context"
+	"code.gitea.io/gitea/services/convert"
+)
+
+// ActionRunnerGroup
+// swagger:response ActionRunnerGroup
+type swaggerResponseActionRunnerGroup struct {
+	// in:body
+	Body api.ActionRunnerGroup `json:"body"`
+}
+
+// ActionRunnerGroupList
+// swagger:response ActionRunnerGroupList
+type swaggerResponseActionRunnerGroupList struct {
+	// in:body
+	Body []api.ActionRunnerGroup `json:"body"`
+}
+
+// getRunnerGroup returns the runner group of the owner from the URL, it writes a 404 if it doesn't exist.
+func getRunnerGroup(ctx *context.APIContext, ownerID int64) *actions_model.ActionRunnerGroup {
+	g, err := actions_model.GetRunnerGroupByID(ctx, ctx.ParamsInt64("group_id"))
+	if err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			ctx.NotFound()
+		} else {
+			ctx.InternalServerError(err)
+		}
+		return nil
+	}
+	if g.OwnerID != ownerID {
+		ctx.NotFound()
+		return nil
+	}
+	return g
+}
+
+// respondRunnerGroupError writes the error of the validation of the access policies of a runner group
+func respondRunnerGroupError(ctx *context.APIContext, err error) {
+	switch {
+	case errors.Is(err, util.ErrNotExist), errors.Is(err, util.ErrInvalidArgument):
+		ctx.Error(http.StatusUnprocessableEntity, "", err)
+	case errors.Is(err, util.ErrAlreadyExist):
+		ctx.Error(http.StatusConflict, "", err)
+	default:
+		ctx.InternalServerError(err)
+	}
+}
+
+func writeRunnerGroup(ctx *context.APIContext, status int, g *actions_model.ActionRunnerGroup) {
+	res, err := convert.ToActionRunnerGroup(ctx, g)
+	if err != nil {
+		ctx.InternalServerError(err)
+		return
+	}
+	ctx.JSON(status, res)
+}
+
+// ListRunnerGroups lists the runner groups of an owner, or of the instance runners if ownerID is 0
+func ListRunnerGroups(ctx *context.APIContext, ownerID int64) {
+	groups, count, err := db.FindAndCount[actions_model.ActionRunnerGroup](ctx, actions_model.FindRunnerGroupsOptions{
+		ListOptions: utils.GetListOptions(ctx),
+		OwnerID:     ownerID,
+	})
+	if err != nil {
+		ctx.InternalServerError(err)
+		return
+	}
+
+	res := make([]*api.ActionRunnerGroup, len(groups))
+	for i, g := range groups {
+		res[i], err = convert.ToActionRunnerGroup(ctx, g)
+		if err != nil {
+			ctx.InternalServerError(err)
+			return
+		}
+	}
+
+	ctx.SetTotalCountHeader(count)
+	ctx.JSON(http.StatusOK, res)
+}
+
+// CreateRunnerGroup creates a runner group of an owner, or of the instance runners if ownerID is 0
+func CreateRunnerGroup(ctx *context.APIContext, ownerID int64) {
+	form := web.GetForm(ctx).(*api.CreateActionRunnerGroupOption)
+
+	opts := &actions_service.RunnerGroupOptions{
+		Description:      form.Description,
+		AllowedWorkflows: form.AllowedWorkflows,
+	}
+	var err error
+	if opts.AllowedOwnerIDs, err = actions_service.ResolveRunnerGroupOwners(ctx, form.AllowedOwners); err != nil {
+		respondRunnerGroupError(ctx, err)
+		return
+	}
+	if opts.AllowedRepoIDs, err = actions_service.ResolveRunnerGroupRepos(ctx, ownerID, form.AllowedRepos); err != nil {
+		respondRunnerGroupError(ctx, err)
+		return
+	}
+
+	g, err := actions_service.CreateRunnerGroup(ctx, ownerID, form.Name, opts)
+	if err != nil {
+		respondRunnerGroupError(ctx, err)
+		return
+	}
+
+	writeRunnerGroup(ctx, http.StatusCreated, g)
+}
+
+// GetRunnerGroup returns a runner group of an owner, or of the instance runners if ownerID is 0
+func GetRunnerGroup(ctx *context.APIContext, ownerID int64) {
+	g := getRunnerGroup(ctx, ownerID)
+	if ctx.Written() {
+		return
+	}
+
+	writeRunnerGroup(ctx, http.StatusOK, g)
+}
+
+// EditRunnerGroup updates the access policies of a runner group of an owner, or of the instance runners if ownerID is 0
+func EditRunnerGroup(ctx *context.APIContext, ownerID int64) {
+	g := getRunnerGroup(ctx, ownerID)
+	if ctx.Written() {
+		return
+	}
+	form := web.GetForm(ctx).(*api.EditActionRunnerGroupOption)
+
+	opts := &actions_service.RunnerGroupOptions{
+		Description:      g.Description,
+		AllowedOwnerIDs:  g.AllowedOwnerIDs,
+		AllowedRepoIDs:   g.AllowedRepoIDs,
+		AllowedWorkflows: g.AllowedWorkflows,
+	}
+	if form.Description != nil {
+		opts.Description = *form.Description
+	}
+	var err error
+	if form.AllowedOwners != nil {
+		if opts.AllowedOwnerIDs, err = actions_service.ResolveRunnerGroupOwners(ctx, *form.AllowedOwners); err != nil {
+			respondRunnerGroupError(ctx, err)
+			return
+		}
+	}
+	if form.AllowedRepos != nil {
+		if opts.AllowedRepoIDs, err = actions_service.ResolveRunnerGroupRepos(ctx, ownerID, *form.AllowedRepos); err != nil {
+			respondRunnerGroupError(ctx, err)
+			return
+		}
+	}
+	if form.AllowedWorkflows != nil {
+		opts.AllowedWorkflows = *form.AllowedWorkflows
+	}
+
+	if err := actions_service.UpdateRunnerGroup(ctx, g, opts); err != nil {
+		respondRunnerGroupError(ctx, err)
+		return
+	}
+
+	writeRunnerGroup(ctx, http.StatusOK, g)
+}
+
+// DeleteRunnerGroup deletes a runner group of an owner, or of the instance runners if ownerID is 0
+func DeleteRunnerGroup(ctx *context.APIContext, ownerID int64) {
+	g := getRunnerGroup(ctx, ownerID)
+	if ctx.Written() {
+		return
+	}
+
+	if err := actions_model.DeleteRunnerGroup(ctx, g); err != nil {
+		ctx.InternalServerError(err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// SetRunnerGroup adds a runner of an owner to one of its runner groups if add is true, or removes it from the group otherwise
+func SetRunnerGroup(ctx *context.APIContext, ownerID int64, add bool) {
+	g := getRunnerGroup(ctx, ownerID)
+	if ctx.Written() {
+		return
+	}
+	runner, err := actions_model.GetRunnerByID(ctx, ctx.ParamsInt64("runner_id"))
+	if err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			ctx.NotFound()
+		} else {
+			ctx.InternalServerError(err)
+		}
+		return
+	}
+	if runner.OwnerID != ownerID || runner.RepoID != 0 {
+		ctx.NotFound()
+		return
+	}
+
+	groupID := int64(0)
+	if add {
+		groupID = g.ID
+	} else if runner.GroupID != g.ID {
+		ctx.NotFound()
+		return
+	}
+	if err := actions_service.SetRunnerGroup(ctx, runner, groupID); err != nil {
+		respondRunnerGroupError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
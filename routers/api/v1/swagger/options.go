@@ -219,4 +219,22 @@ type swaggerParameterBodies struct {
 
 	// in:body
 	DispatchWorkflowOption api.DispatchWorkflowOption
+
+	// in:body
+	CreateActionRunnerGroupOption api.CreateActionRunnerGroupOption
+
+	// in:body
+	EditActionRunnerGroupOption api.EditActionRunnerGroupOption
+
+	// in:body
+	CreateQuotaRuleOption api.CreateQuotaRuleOption
+
+	// in:body
+	EditQuotaRuleOption api.EditQuotaRuleOption
+
+	// in:body
+	CreateQuotaGroupOption api.CreateQuotaGroupOption
+
+	// in:body
+	DeprecatePackageOption api.DeprecatePackageOption
 }
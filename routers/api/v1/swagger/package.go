@@ -27,3 +27,10 @@ type swaggerResponsePackageFileList struct {
 	// in:body
 	Body []api.PackageFile `json:"body"`
 }
+
+// PackageDownloadStats
+// swagger:response PackageDownloadStats
+type swaggerResponsePackageDownloadStats struct {
+	// in:body
+	Body api.PackageDownloadStats `json:"body"`
+}
@@ -241,6 +241,21 @@ func addHook(ctx *context.APIContext, form *api.CreateHookOption, ownerID, repoI
 		}
 		w.Meta = string(meta)
 	}
+	if webhook_service.IsBrokerHookType(w.Type) {
+		if err := webhook_service.UpdateBrokerMeta(w, form.Config); err != nil {
+			ctx.Error(http.StatusUnprocessableEntity, "", err.Error())
+			return nil, false
+		}
+	}
+	if w.Type == webhook_module.CUSTOM {
+		if err := webhook_service.UpdateCustomMeta(w, form.Config); err != nil {
+			ctx.Error(http.StatusUnprocessableEntity, "", err.Error())
+			return nil, false
+		}
+	}
+	if !updateNotificationHookMeta(ctx, w, form.Config) {
+		return nil, false
+	}
 
 	if err := w.UpdateEvent(); err != nil {
 		ctx.Error(http.StatusInternalServerError, "UpdateEvent", err)
@@ -252,6 +267,25 @@ func addHook(ctx *context.APIContext, form *api.CreateHookOption, ownerID, repoI
 	return w, true
 }
 
+// updateNotificationHookMeta updates the metadata of the webhooks of the notification services from the config options.
+// If an error occurs, write to `ctx` accordingly. Return whether successful
+func updateNotificationHookMeta(ctx *context.APIContext, w *webhook.Webhook, config map[string]string) bool {
+	var err error
+	switch w.Type {
+	case webhook_module.NTFY:
+		err = webhook_service.UpdateNtfyMeta(w, config)
+	case webhook_module.GOTIFY:
+		err = webhook_service.UpdateGotifyMeta(w, config)
+	case webhook_module.ZULIP:
+		err = webhook_service.UpdateZulipMeta(w, config)
+	}
+	if err != nil {
+		ctx.Error(http.StatusUnprocessableEntity, "", err.Error())
+		return false
+	}
+	return true
+}
+
 // EditSystemHook edit system webhook `w` according to `form`. Writes to `ctx` accordingly
 func EditSystemHook(ctx *context.APIContext, form *api.EditHookOption, hookID int64) {
 	hook, err := webhook.GetSystemOrDefaultWebhook(ctx, hookID)
@@ -347,6 +381,21 @@ func editHook(ctx *context.APIContext, form *api.EditHookOption, w *webhook.Webh
 				w.Meta = string(meta)
 			}
 		}
+		if webhook_service.IsBrokerHookType(w.Type) {
+			if err := webhook_service.UpdateBrokerMeta(w, form.Config); err != nil {
+				ctx.Error(http.StatusUnprocessableEntity, "", err.Error())
+				return false
+			}
+		}
+		if w.Type == webhook_module.CUSTOM {
+			if err := webhook_service.UpdateCustomMeta(w, form.Config); err != nil {
+				ctx.Error(http.StatusUnprocessableEntity, "", err.Error())
+				return false
+			}
+		}
+		if !updateNotificationHookMeta(ctx, w, form.Config) {
+			return false
+		}
 	}
 
 	// Update events
@@ -202,6 +202,11 @@ func NormalRoutes() *web.Route {
 		r.Mount(prefix, actions_router.ArtifactsRoutes(prefix))
 		prefix = actions_router.ArtifactV4RouteBase
 		r.Mount(prefix, actions_router.ArtifactsV4Routes(prefix))
+
+		if setting.Actions.CacheEnabled {
+			prefix = "/api/actions_cache"
+			r.Mount(prefix, actions_router.CacheRoutes(prefix))
+		}
 	}
 
 	return r
@@ -15,6 +15,7 @@ import (
 	issues_model "code.gitea.io/gitea/models/issues"
 	perm_model "code.gitea.io/gitea/models/perm"
 	access_model "code.gitea.io/gitea/models/perm/access"
+	quota_model "code.gitea.io/gitea/models/quota"
 	"code.gitea.io/gitea/models/unit"
 	user_model "code.gitea.io/gitea/models/user"
 	"code.gitea.io/gitea/modules/git"
@@ -140,6 +141,38 @@ func (ctx *preReceiveContext) assertPushOptions() bool {
 	return true
 }
 
+// assertQuota rejects pushes which add content to the repository if the quota of its owner is exceeded,
+// pushes which only delete references are always allowed
+func (ctx *preReceiveContext) assertQuota() bool {
+	emptyObjectID := ctx.Repo.GetObjectFormat().EmptyObjectID().String()
+
+	addsContent := false
+	for _, newCommitID := range ctx.opts.NewCommitIDs {
+		if newCommitID != emptyObjectID {
+			addsContent = true
+			break
+		}
+	}
+	if !addsContent {
+		return true
+	}
+
+	if err := quota_model.CheckForUser(ctx, ctx.Repo.Repository.OwnerID, quota_model.LimitSubjectSizeReposGit); err != nil {
+		if quota_model.IsErrQuotaExceeded(err) {
+			ctx.JSON(http.StatusRequestEntityTooLarge, private.Response{
+				UserMsg: "the storage quota of the repository owner has been exceeded",
+			})
+			return false
+		}
+		log.Error("Unable to check the quota of %-v: %v", ctx.Repo.Repository, err)
+		ctx.JSON(http.StatusInternalServerError, private.Response{
+			Err: err.Error(),
+		})
+		return false
+	}
+	return true
+}
+
 // HookPreReceive checks whether a individual commit is acceptable
 func HookPreReceive(ctx *gitea_context.PrivateContext) {
 	opts := web.GetForm(ctx).(*private.HookOptions)
@@ -177,6 +210,11 @@ func HookPreReceive(ctx *gitea_context.PrivateContext) {
 		}
 	}
 
+	if !ourCtx.assertQuota() {
+		log.Trace("Git push quota check failed")
+		return
+	}
+
 	ctx.PlainText(http.StatusOK, "ok")
 }
 
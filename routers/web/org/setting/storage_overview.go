@@ -0,0 +1,33 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package setting
+
+import (
+	"net/http"
+
+	"code.gitea.io/gitea/modules/base"
+	shared_quota "code.gitea.io/gitea/routers/web/shared/quota"
+	shared_user "code.gitea.io/gitea/routers/web/shared/user"
+	"code.gitea.io/gitea/services/context"
+)
+
+const tplSettingsStorageOverview base.TplName = "org/settings/storage_overview"
+
+// StorageOverview renders the storage used by the organization and the quotas which apply to it
+func StorageOverview(ctx *context.Context) {
+	ctx.Data["Title"] = ctx.Tr("settings.storage_overview")
+	ctx.Data["PageIsStorageOverview"] = true
+
+	if err := shared_user.LoadHeaderCount(ctx); err != nil {
+		ctx.ServerError("LoadHeaderCount", err)
+		return
+	}
+
+	shared_quota.PrepareStorageOverview(ctx, ctx.Org.Organization.ID)
+	if ctx.Written() {
+		return
+	}
+
+	ctx.HTML(http.StatusOK, tplSettingsStorageOverview)
+}
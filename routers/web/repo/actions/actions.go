@@ -7,11 +7,13 @@ import (
 	"bytes"
 	"fmt"
 	"net/http"
+	"net/url"
 	"slices"
 	"strings"
 
 	actions_model "code.gitea.io/gitea/models/actions"
 	"code.gitea.io/gitea/models/db"
+	repo_model "code.gitea.io/gitea/models/repo"
 	"code.gitea.io/gitea/models/unit"
 	"code.gitea.io/gitea/modules/actions"
 	"code.gitea.io/gitea/modules/base"
@@ -21,6 +23,7 @@ import (
 	"code.gitea.io/gitea/modules/setting"
 	"code.gitea.io/gitea/modules/util"
 	"code.gitea.io/gitea/routers/web/repo"
+	"code.gitea.io/gitea/routers/web/repo/badges"
 	"code.gitea.io/gitea/services/context"
 	"code.gitea.io/gitea/services/convert"
 
@@ -57,6 +60,45 @@ func MustEnableActions(ctx *context.Context) {
 	}
 }
 
+// StatusBadge is a badge of a workflow, which can be copied as Markdown.
+type StatusBadge struct {
+	Name     string
+	Markdown string
+}
+
+// workflowBadges returns the badges of a workflow and of its jobs, followed by the badge of the combined status of the default branch.
+func workflowBadges(ctx *context.Context, repo *repo_model.Repository, workflowFile string, wf *model.Workflow) ([]StatusBadge, error) {
+	badgePath := "badges/workflows/" + url.PathEscape(workflowFile)
+	link, err := badges.Link(ctx, repo, badgePath+"/badge.svg")
+	if err != nil {
+		return nil, err
+	}
+	result := []StatusBadge{{
+		Name:     workflowFile,
+		Markdown: fmt.Sprintf("![%s](%s)", workflowFile, link),
+	}}
+	jobIDs := util.KeysOfMap(wf.Jobs)
+	slices.Sort(jobIDs)
+	for _, jobID := range jobIDs {
+		link, err := badges.Link(ctx, repo, badgePath+"/jobs/"+url.PathEscape(jobID)+"/badge.svg")
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, StatusBadge{
+			Name:     workflowFile + " / " + jobID,
+			Markdown: fmt.Sprintf("![%s](%s)", jobID, link),
+		})
+	}
+	link, err = badges.Link(ctx, repo, "badges/status.svg")
+	if err != nil {
+		return nil, err
+	}
+	return append(result, StatusBadge{
+		Name:     repo.DefaultBranch,
+		Markdown: fmt.Sprintf("![status](%s)", link),
+	}), nil
+}
+
 func List(ctx *context.Context) {
 	ctx.Data["Title"] = ctx.Tr("actions.actions")
 	ctx.Data["PageIsActions"] = true
@@ -148,6 +190,15 @@ func List(ctx *context.Context) {
 			}
 			workflows = append(workflows, workflow)
 
+			if setting.Badges.Enabled && workflow.Entry.Name() == curWorkflow {
+				statusBadges, err := workflowBadges(ctx, ctx.Repo.Repository, curWorkflow, wf)
+				if err != nil {
+					ctx.ServerError("workflowBadges", err)
+					return
+				}
+				ctx.Data["CurWorkflowBadges"] = statusBadges
+			}
+
 			if canRun && workflow.Entry.Name() == curWorkflow {
 				config := wf.WorkflowDispatchConfig()
 				if config != nil {
@@ -13,6 +13,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -23,6 +24,9 @@ import (
 	"code.gitea.io/gitea/models/unit"
 	"code.gitea.io/gitea/modules/actions"
 	"code.gitea.io/gitea/modules/base"
+	"code.gitea.io/gitea/modules/eventsource"
+	"code.gitea.io/gitea/modules/graceful"
+	"code.gitea.io/gitea/modules/log"
 	"code.gitea.io/gitea/modules/setting"
 	"code.gitea.io/gitea/modules/storage"
 	"code.gitea.io/gitea/modules/timeutil"
@@ -116,13 +120,15 @@ type ViewResponse struct {
 			CanRerun          bool       `json:"canRerun"`
 			CanDeleteArtifact bool       `json:"canDeleteArtifact"`
 			Done              bool       `json:"done"`
+			ConcurrencyGroup  string     `json:"concurrencyGroup"`
 			Jobs              []*ViewJob `json:"jobs"`
 			Commit            ViewCommit `json:"commit"`
 		} `json:"run"`
 		CurrentJob struct {
-			Title  string         `json:"title"`
-			Detail string         `json:"detail"`
-			Steps  []*ViewJobStep `json:"steps"`
+			Title      string          `json:"title"`
+			Detail     string          `json:"detail"`
+			Deployment *ViewDeployment `json:"deployment"`
+			Steps      []*ViewJobStep  `json:"steps"`
 		} `json:"currentJob"`
 	} `json:"state"`
 	Logs struct {
@@ -131,11 +137,18 @@ type ViewResponse struct {
 }
 
 type ViewJob struct {
-	ID       int64  `json:"id"`
-	Name     string `json:"name"`
-	Status   string `json:"status"`
-	CanRerun bool   `json:"canRerun"`
-	Duration string `json:"duration"`
+	ID               int64  `json:"id"`
+	Name             string `json:"name"`
+	Status           string `json:"status"`
+	CanRerun         bool   `json:"canRerun"`
+	Duration         string `json:"duration"`
+	ConcurrencyGroup string `json:"concurrencyGroup"`
+}
+
+type ViewDeployment struct {
+	Environment string `json:"environment"`
+	URL         string `json:"url"`
+	CanReview   bool   `json:"canReview"` // the deployment waits for a review and the doer is a reviewer of the environment
 }
 
 type ViewCommit struct {
@@ -201,15 +214,17 @@ func ViewPost(ctx *context_module.Context) {
 	resp.State.Run.CanRerun = run.Status.IsDone() && ctx.Repo.CanWrite(unit.TypeActions)
 	resp.State.Run.CanDeleteArtifact = run.Status.IsDone() && ctx.Repo.CanWrite(unit.TypeActions)
 	resp.State.Run.Done = run.Status.IsDone()
+	resp.State.Run.ConcurrencyGroup = run.ConcurrencyGroup
 	resp.State.Run.Jobs = make([]*ViewJob, 0, len(jobs)) // marshal to '[]' instead of 'null' in json
 	resp.State.Run.Status = run.Status.String()
 	for _, v := range jobs {
 		resp.State.Run.Jobs = append(resp.State.Run.Jobs, &ViewJob{
-			ID:       v.ID,
-			Name:     v.Name,
-			Status:   v.Status.String(),
-			CanRerun: v.Status.IsDone() && ctx.Repo.CanWrite(unit.TypeActions),
-			Duration: v.Duration().String(),
+			ID:               v.ID,
+			Name:             v.Name,
+			Status:           v.Status.String(),
+			CanRerun:         v.Status.IsDone() && ctx.Repo.CanWrite(unit.TypeActions),
+			Duration:         v.Duration().String(),
+			ConcurrencyGroup: v.ConcurrencyGroup,
 		})
 	}
 
@@ -250,6 +265,36 @@ func ViewPost(ctx *context_module.Context) {
 	resp.State.CurrentJob.Detail = current.Status.LocaleString(ctx.Locale)
 	if run.NeedApproval {
 		resp.State.CurrentJob.Detail = ctx.Locale.TrString("actions.need_approval_desc")
+	} else if current.Status.IsBlocked() && current.ConcurrencyGroup != "" {
+		resp.State.CurrentJob.Detail = ctx.Locale.TrString("actions.runs.waiting_for_concurrency_group", current.ConcurrencyGroup)
+	} else if run.Status.IsBlocked() && run.ConcurrencyGroup != "" {
+		resp.State.CurrentJob.Detail = ctx.Locale.TrString("actions.runs.waiting_for_concurrency_group", run.ConcurrencyGroup)
+	}
+	if current.DeploymentID > 0 {
+		deployment, err := actions_model.GetDeploymentByID(ctx, current.DeploymentID)
+		if err != nil {
+			ctx.Error(http.StatusInternalServerError, err.Error())
+			return
+		}
+		deployment.Run = run
+		if err := deployment.LoadAttributes(ctx); err != nil {
+			ctx.Error(http.StatusInternalServerError, err.Error())
+			return
+		}
+		resp.State.CurrentJob.Deployment = &ViewDeployment{
+			Environment: deployment.Environment.Name,
+			URL:         deployment.URL,
+			CanReview:   deployment.IsWaitingForReview() && ctx.Doer != nil && deployment.Environment.IsReviewer(ctx.Doer.ID),
+		}
+		if current.Status.IsWaitingForApproval() {
+			if deployment.IsWaitingForReview() {
+				resp.State.CurrentJob.Detail = ctx.Locale.TrString("actions.runs.waiting_for_deployment_review", deployment.Environment.Name)
+			} else {
+				resp.State.CurrentJob.Detail = ctx.Locale.TrString("actions.runs.waiting_for_deployment_timer", deployment.Environment.Name, deployment.WaitUntil.AsLocalTime().Format(time.RFC3339))
+			}
+		} else if deployment.RejectedBy > 0 {
+			resp.State.CurrentJob.Detail = ctx.Locale.TrString("actions.runs.deployment_rejected", deployment.Environment.Name)
+		}
 	}
 	resp.State.CurrentJob.Steps = make([]*ViewJobStep, 0) // marshal to '[]' instead of 'null' in json
 	resp.Logs.StepsLog = make([]*ViewStepLog, 0)          // marshal to '[]' instead of 'null' in json
@@ -339,11 +384,14 @@ func Rerun(ctx *context_module.Context) {
 	}
 
 	// reset run's start and stop time when it is done
-	if run.Status.IsDone() {
+	// and emit the workflow_run event of its completion again
+	isNewAttempt := run.Status.IsDone()
+	if isNewAttempt {
 		run.PreviousDuration = run.Duration()
 		run.Started = 0
 		run.Stopped = 0
-		if err := actions_model.UpdateRun(ctx, run, "started", "stopped", "previous_duration"); err != nil {
+		run.IsCompletionNotified = false
+		if err := actions_model.UpdateRun(ctx, run, "started", "stopped", "previous_duration", "is_completion_notified"); err != nil {
 			ctx.Error(http.StatusInternalServerError, err.Error())
 			return
 		}
@@ -354,15 +402,33 @@ func Rerun(ctx *context_module.Context) {
 		return
 	}
 
+	// the jobs of a run with a concurrency group wait for the job emitter to check the group
+	hasConcurrency := run.ConcurrencyGroup != ""
+
+	// the jobs of the called workflows are inserted again when their parent job is rerun
+	jobs = slices.DeleteFunc(jobs, func(j *actions_model.ActionRunJob) bool {
+		return j.ParentJobID > 0
+	})
+	for job.ParentJobID > 0 {
+		if job, err = actions_model.GetRunJobByID(ctx, job.ParentJobID); err != nil {
+			ctx.Error(http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
 	if jobIndexStr == "" { // rerun all jobs
 		for _, j := range jobs {
 			// if the job has needs, it should be set to "blocked" status to wait for other jobs
-			shouldBlock := len(j.Needs) > 0
+			shouldBlock := len(j.Needs) > 0 || hasConcurrency || j.RawConcurrency != "" || j.IsCallingWorkflow() || j.RawEnvironment != ""
 			if err := rerunJob(ctx, j, shouldBlock); err != nil {
 				ctx.Error(http.StatusInternalServerError, err.Error())
 				return
 			}
 		}
+		emitRerunJobs(run, jobs)
+		if isNewAttempt {
+			notifyRerunRequested(ctx, run.ID)
+		}
 		ctx.JSON(http.StatusOK, struct{}{})
 		return
 	}
@@ -371,12 +437,16 @@ func Rerun(ctx *context_module.Context) {
 
 	for _, j := range rerunJobs {
 		// jobs other than the specified one should be set to "blocked" status
-		shouldBlock := j.JobID != job.JobID
+		shouldBlock := j.JobID != job.JobID || hasConcurrency || j.RawConcurrency != "" || j.IsCallingWorkflow() || j.RawEnvironment != ""
 		if err := rerunJob(ctx, j, shouldBlock); err != nil {
 			ctx.Error(http.StatusInternalServerError, err.Error())
 			return
 		}
 	}
+	emitRerunJobs(run, rerunJobs)
+	if isNewAttempt {
+		notifyRerunRequested(ctx, run.ID)
+	}
 
 	ctx.JSON(http.StatusOK, struct{}{})
 }
@@ -394,9 +464,19 @@ func rerunJob(ctx *context_module.Context, job *actions_model.ActionRunJob, shou
 	}
 	job.Started = 0
 	job.Stopped = 0
+	// the job-level concurrency is evaluated again for the new attempt
+	job.IsConcurrencyEvaluated = false
+	job.ConcurrencyGroup = ""
+	job.ConcurrencyCancel = false
 
 	if err := db.WithTx(ctx, func(ctx context.Context) error {
-		_, err := actions_model.UpdateRunJob(ctx, job, builder.Eq{"status": status}, "task_id", "status", "started", "stopped")
+		if job.IsCallingWorkflow() {
+			// the called workflow is expanded again by the job emitter
+			if err := actions_model.DeleteChildJobs(ctx, job.ID); err != nil {
+				return err
+			}
+		}
+		_, err := actions_model.UpdateRunJob(ctx, job, builder.Eq{"status": status}, "task_id", "status", "started", "stopped", "is_concurrency_evaluated", "concurrency_group", "concurrency_cancel")
 		return err
 	}); err != nil {
 		return err
@@ -406,6 +486,95 @@ func rerunJob(ctx *context_module.Context, job *actions_model.ActionRunJob, shou
 	return nil
 }
 
+// emitRerunJobs lets the job emitter check the concurrency of the jobs which have been rerun,
+// expand the reusable workflows they call and create their deployments.
+func emitRerunJobs(run *actions_model.ActionRun, jobs []*actions_model.ActionRunJob) {
+	needsEmit := run.ConcurrencyGroup != ""
+	for _, j := range jobs {
+		needsEmit = needsEmit || j.RawConcurrency != "" || j.IsCallingWorkflow() || j.RawEnvironment != ""
+	}
+	if !needsEmit {
+		return
+	}
+	if err := actions_service.EmitJobsIfReady(run.ID); err != nil {
+		log.Error("EmitJobsIfReady: %v", err)
+	}
+}
+
+// notifyRerunRequested emits the workflow_run event of the new attempt of a run which was done.
+func notifyRerunRequested(ctx *context_module.Context, runID int64) {
+	// the status of the run has been updated with the status of the jobs which have been rerun
+	run, err := actions_model.GetRunByID(ctx, runID)
+	if err != nil {
+		log.Error("GetRunByID: %v", err)
+		return
+	}
+	actions_service.NotifyWorkflowRunRequested(ctx, run)
+}
+
+// LogsStream pushes the new lines of the logs of a job to its viewer as Server-Sent Events,
+// the viewer polls the logs while it isn't connected.
+func LogsStream(ctx *context_module.Context) {
+	job, _ := getRunJobs(ctx, ctx.ParamsInt64("run"), ctx.ParamsInt64("job"))
+	if ctx.Written() {
+		return
+	}
+
+	ctx.Resp.Header().Set("Content-Type", "text/event-stream")
+	ctx.Resp.Header().Set("Cache-Control", "no-cache")
+	ctx.Resp.Header().Set("Connection", "keep-alive")
+	ctx.Resp.Header().Set("X-Accel-Buffering", "no")
+	ctx.Resp.WriteHeader(http.StatusOK)
+
+	messageChan := actions_service.SubscribeJobLogs(job.ID)
+	unregister := func() {
+		actions_service.UnsubscribeJobLogs(job.ID, messageChan)
+		// ensure the messageChan is closed
+		for {
+			_, ok := <-messageChan
+			if !ok {
+				break
+			}
+		}
+	}
+
+	if _, err := ctx.Resp.Write([]byte("\n")); err != nil {
+		log.Error("Unable to write to the logs stream of job %d: %v", job.ID, err)
+		unregister()
+		return
+	}
+	ctx.Resp.Flush()
+
+	shutdownCtx := graceful.GetManager().ShutdownContext()
+	timer := time.NewTicker(30 * time.Second)
+	defer timer.Stop()
+
+	for {
+		var event *eventsource.Event
+		select {
+		case <-timer.C:
+			event = &eventsource.Event{Name: "ping"}
+		case <-ctx.Done():
+			go unregister()
+			return
+		case <-shutdownCtx.Done():
+			go unregister()
+			return
+		case e, ok := <-messageChan:
+			if !ok {
+				return
+			}
+			event = e
+		}
+		if _, err := event.WriteTo(ctx.Resp); err != nil {
+			log.Error("Unable to write to the logs stream of job %d: %v", job.ID, err)
+			go unregister()
+			return
+		}
+		ctx.Resp.Flush()
+	}
+}
+
 func Logs(ctx *context_module.Context) {
 	runIndex := ctx.ParamsInt64("run")
 	jobIndex := ctx.ParamsInt64("job")
@@ -493,42 +662,65 @@ func Cancel(ctx *context_module.Context) {
 
 	actions_service.CreateCommitStatus(ctx, jobs...)
 
+	// release the runs waiting in the concurrency groups of the cancelled run
+	if err := actions_service.EmitJobsIfReady(jobs[0].RunID); err != nil {
+		log.Error("EmitJobsIfReady: %v", err)
+	}
+
 	ctx.JSON(http.StatusOK, struct{}{})
 }
 
 func Approve(ctx *context_module.Context) {
 	runIndex := ctx.ParamsInt64("run")
 
-	current, jobs := getRunJobs(ctx, runIndex, -1)
+	current, _ := getRunJobs(ctx, runIndex, -1)
 	if ctx.Written() {
 		return
 	}
-	run := current.Run
-	doer := ctx.Doer
+	if err := actions_service.ApproveRun(ctx, current.Run, ctx.Doer); err != nil {
+		if errors.Is(err, util.ErrInvalidArgument) {
+			ctx.JSONError(ctx.Locale.Tr("actions.runs.not_waiting_for_approval"))
+			return
+		}
+		ctx.Error(http.StatusInternalServerError, err.Error())
+		return
+	}
 
-	if err := db.WithTx(ctx, func(ctx context.Context) error {
-		run.NeedApproval = false
-		run.ApprovedBy = doer.ID
-		if err := actions_model.UpdateRun(ctx, run, "need_approval", "approved_by"); err != nil {
-			return err
+	ctx.JSON(http.StatusOK, struct{}{})
+}
+
+// ApproveDeployment approves the deployment of a job waiting for approval
+func ApproveDeployment(ctx *context_module.Context) {
+	reviewDeployment(ctx, true)
+}
+
+// RejectDeployment rejects the deployment of a job waiting for approval, the job fails
+func RejectDeployment(ctx *context_module.Context) {
+	reviewDeployment(ctx, false)
+}
+
+func reviewDeployment(ctx *context_module.Context, approve bool) {
+	runIndex := ctx.ParamsInt64("run")
+	jobIndex := ctx.ParamsInt64("job")
+
+	job, _ := getRunJobs(ctx, runIndex, jobIndex)
+	if ctx.Written() {
+		return
+	}
+
+	if err := actions_service.ReviewDeployment(ctx, job, ctx.Doer, approve); err != nil {
+		if errors.Is(err, util.ErrPermissionDenied) {
+			ctx.JSONError(ctx.Locale.Tr("actions.runs.deployment_review_denied"))
+			return
 		}
-		for _, job := range jobs {
-			if len(job.Needs) == 0 && job.Status.IsBlocked() {
-				job.Status = actions_model.StatusWaiting
-				_, err := actions_model.UpdateRunJob(ctx, job, nil, "status")
-				if err != nil {
-					return err
-				}
-			}
+		if errors.Is(err, util.ErrInvalidArgument) {
+			ctx.JSONError(ctx.Locale.Tr("actions.runs.deployment_not_waiting_for_review"))
+			return
 		}
-		return nil
-	}); err != nil {
 		ctx.Error(http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	actions_service.CreateCommitStatus(ctx, jobs...)
-
 	ctx.JSON(http.StatusOK, struct{}{})
 }
 
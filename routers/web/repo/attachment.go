@@ -8,6 +8,7 @@ import (
 	"net/http"
 
 	access_model "code.gitea.io/gitea/models/perm/access"
+	quota_model "code.gitea.io/gitea/models/quota"
 	repo_model "code.gitea.io/gitea/models/repo"
 	"code.gitea.io/gitea/modules/httpcache"
 	"code.gitea.io/gitea/modules/log"
@@ -55,6 +56,10 @@ func uploadAttachment(ctx *context.Context, repoID int64, allowedTypes string) {
 			ctx.Error(http.StatusBadRequest, err.Error())
 			return
 		}
+		if quota_model.IsErrQuotaExceeded(err) {
+			ctx.Error(http.StatusRequestEntityTooLarge, ctx.Locale.TrString("error.quota_exceeded"))
+			return
+		}
 		ctx.Error(http.StatusInternalServerError, fmt.Sprintf("NewAttachment: %v", err))
 		return
 	}
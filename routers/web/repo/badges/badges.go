@@ -5,13 +5,17 @@ package badges
 
 import (
 	"fmt"
+	"net/http"
 	"net/url"
 	"strings"
 
 	actions_model "code.gitea.io/gitea/models/actions"
+	git_model "code.gitea.io/gitea/models/git"
 	repo_model "code.gitea.io/gitea/models/repo"
 	"code.gitea.io/gitea/models/unit"
+	"code.gitea.io/gitea/modules/badge"
 	"code.gitea.io/gitea/modules/setting"
+	api "code.gitea.io/gitea/modules/structs"
 	context_module "code.gitea.io/gitea/services/context"
 )
 
@@ -25,10 +29,11 @@ func getBadgeURL(ctx *context_module.Context, label, text, color string) string
 
 	badgeURL := sb.String()
 	q := ctx.Req.URL.Query()
-	// Remove any `branch` or `event` query parameters. They're used by the
-	// workflow badge route, and do not need forwarding to the badge generator.
+	// Remove any `branch`, `event` or `sig` query parameters. They're used by the
+	// badge routes, and do not need forwarding to the badge generator.
 	delete(q, "branch")
 	delete(q, "event")
+	delete(q, "sig")
 	if len(q) > 0 {
 		return fmt.Sprintf("%s?%s", badgeURL, q.Encode())
 	}
@@ -36,29 +41,116 @@ func getBadgeURL(ctx *context_module.Context, label, text, color string) string
 }
 
 func redirectToBadge(ctx *context_module.Context, label, text, color string) {
+	if setting.Badges.GeneratorURLTemplate == "" {
+		// without a badge generator, the badges are rendered by the instance itself
+		ctx.Resp.Header().Set("Content-Type", "image/svg+xml")
+		ctx.Resp.Header().Set("Cache-Control", "no-cache")
+		ctx.Resp.WriteHeader(http.StatusOK)
+		_, _ = ctx.Resp.Write([]byte(badge.Badge{Label: label, Text: text, Color: color}.SVG()))
+		return
+	}
 	ctx.Redirect(getBadgeURL(ctx, label, text, color))
 }
 
 func errorBadge(ctx *context_module.Context, label, text string) { //nolint:unparam
-	ctx.Redirect(getBadgeURL(ctx, label, text, "crimson"))
+	redirectToBadge(ctx, label, text, "crimson")
 }
 
-func GetWorkflowBadge(ctx *context_module.Context) {
+// getLatestWorkflowRun returns the latest run of the workflow of the URL, for the branch and the event of the query if they are given.
+func getLatestWorkflowRun(ctx *context_module.Context) (*actions_model.ActionRun, error) {
 	branch := ctx.Req.URL.Query().Get("branch")
 	if branch != "" {
 		branch = fmt.Sprintf("refs/heads/%s", branch)
 	}
 	event := ctx.Req.URL.Query().Get("event")
 
+	return actions_model.GetLatestRunForBranchAndWorkflow(ctx, ctx.Repo.Repository.ID, branch, ctx.Params("workflow_name"), event)
+}
+
+func GetWorkflowBadge(ctx *context_module.Context) {
 	workflowFile := ctx.Params("workflow_name")
-	run, err := actions_model.GetLatestRunForBranchAndWorkflow(ctx, ctx.Repo.Repository.ID, branch, workflowFile, event)
+	run, err := getLatestWorkflowRun(ctx)
 	if err != nil {
 		errorBadge(ctx, workflowFile, "Not found")
 		return
 	}
 
+	redirectToBadge(ctx, workflowFile, run.Status.String(), statusColor(run.Status))
+}
+
+// GetWorkflowJobBadge returns the badge of a job of the latest run of a workflow,
+// the status of the jobs of a matrix is aggregated.
+func GetWorkflowJobBadge(ctx *context_module.Context) {
+	jobID := ctx.Params("job_id")
+	run, err := getLatestWorkflowRun(ctx)
+	if err != nil {
+		errorBadge(ctx, jobID, "Not found")
+		return
+	}
+	runJobs, err := actions_model.GetRunJobsByRunID(ctx, run.ID)
+	if err != nil {
+		ctx.ServerError("GetRunJobsByRunID", err)
+		return
+	}
+	var jobs []*actions_model.ActionRunJob
+	for _, job := range runJobs {
+		if job.JobID == jobID && job.ParentJobID == 0 {
+			jobs = append(jobs, job)
+		}
+	}
+	if len(jobs) == 0 {
+		errorBadge(ctx, jobID, "Not found")
+		return
+	}
+
+	status := actions_model.AggregateJobStatus(jobs)
+	redirectToBadge(ctx, jobID, status.String(), statusColor(status))
+}
+
+// GetCommitStatusBadge returns the badge of the combined commit status of the head of a branch, the default branch if none is given.
+func GetCommitStatusBadge(ctx *context_module.Context) {
+	if !ctx.Repo.CanRead(unit.TypeCode) {
+		errorBadge(ctx, "status", "Not found")
+		return
+	}
+	branchName := ctx.Req.URL.Query().Get("branch")
+	if branchName == "" {
+		branchName = ctx.Repo.Repository.DefaultBranch
+	}
+	branch, err := git_model.GetBranch(ctx, ctx.Repo.Repository.ID, branchName)
+	if err != nil || branch.IsDeleted {
+		errorBadge(ctx, "status", "Not found")
+		return
+	}
+	statuses, err := git_model.GetLatestCommitStatusForRepoAndSHAs(ctx, []git_model.RepoSHA{{RepoID: ctx.Repo.Repository.ID, SHA: branch.CommitID}})
+	if err != nil {
+		ctx.ServerError("GetLatestCommitStatusForRepoAndSHAs", err)
+		return
+	}
+	if len(statuses) == 0 {
+		redirectToBadge(ctx, "status", "none", "lightgrey")
+		return
+	}
+
 	var color string
-	switch run.Status {
+	switch statuses[0].State {
+	case api.CommitStatusSuccess:
+		color = "brightgreen"
+	case api.CommitStatusPending:
+		color = "gold"
+	case api.CommitStatusWarning:
+		color = "orange"
+	case api.CommitStatusError, api.CommitStatusFailure:
+		color = "crimson"
+	default:
+		color = "lightgrey"
+	}
+	redirectToBadge(ctx, "status", string(statuses[0].State), color)
+}
+
+func statusColor(status actions_model.Status) string {
+	var color string
+	switch status {
 	case actions_model.StatusUnknown:
 		color = "lightgrey"
 	case actions_model.StatusWaiting:
@@ -73,13 +165,12 @@ func GetWorkflowBadge(ctx *context_module.Context) {
 		color = "orange"
 	case actions_model.StatusSkipped:
 		color = "blue"
-	case actions_model.StatusBlocked:
+	case actions_model.StatusBlocked, actions_model.StatusWaitingForApproval:
 		color = "yellow"
 	default:
 		color = "lightgrey"
 	}
-
-	redirectToBadge(ctx, workflowFile, run.Status.String(), color)
+	return color
 }
 
 func getIssueOrPullBadge(ctx *context_module.Context, label, variant string, num int) {
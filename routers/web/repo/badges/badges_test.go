@@ -0,0 +1,102 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package badges
+
+import (
+	"net/http"
+	"testing"
+
+	"code.gitea.io/gitea/models/db"
+	git_model "code.gitea.io/gitea/models/git"
+	repo_model "code.gitea.io/gitea/models/repo"
+	"code.gitea.io/gitea/models/unittest"
+	"code.gitea.io/gitea/modules/setting"
+	api "code.gitea.io/gitea/modules/structs"
+	"code.gitea.io/gitea/modules/test"
+	"code.gitea.io/gitea/services/contexttest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommitStatusBadge(t *testing.T) {
+	unittest.PrepareTestEnv(t)
+	defer test.MockVariableValue(&setting.Badges.GeneratorURLTemplate, "")()
+
+	require.NoError(t, db.Insert(db.DefaultContext, &git_model.CommitStatusSummary{
+		RepoID: 1,
+		SHA:    "65f1bf27bc3bf70f64657658635e66094edbcb4d",
+		State:  api.CommitStatusFailure,
+	}))
+
+	ctx, resp := contexttest.MockContext(t, "user2/repo1/badges/status.svg")
+	contexttest.LoadRepo(t, ctx, 1)
+	GetCommitStatusBadge(ctx)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "image/svg+xml", resp.Header().Get("Content-Type"))
+	assert.Contains(t, resp.Body.String(), "<title>status: failure</title>")
+
+	ctx, resp = contexttest.MockContext(t, "user2/repo1/badges/status.svg?branch=branch2")
+	contexttest.LoadRepo(t, ctx, 1)
+	GetCommitStatusBadge(ctx)
+	assert.Contains(t, resp.Body.String(), "<title>status: none</title>")
+
+	ctx, resp = contexttest.MockContext(t, "user2/repo1/badges/status.svg?branch=no-such-branch")
+	contexttest.LoadRepo(t, ctx, 1)
+	GetCommitStatusBadge(ctx)
+	assert.Contains(t, resp.Body.String(), "<title>status: Not found</title>")
+}
+
+func TestSignedBadge(t *testing.T) {
+	unittest.PrepareTestEnv(t)
+	repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 2})
+	require.True(t, repo.IsPrivate)
+
+	link, err := Link(db.DefaultContext, repo, "badges/stars.svg")
+	require.NoError(t, err)
+	assert.Contains(t, link, "/user2/repo2/badges/stars.svg?sig=")
+
+	assign := func(path string) (int, *repo_model.Repository) {
+		ctx, resp := contexttest.MockContext(t, path)
+		ctx.SetParams(":username", "user2")
+		ctx.SetParams(":reponame", "repo2")
+		RepoAssignment(ctx)
+		return resp.Code, ctx.Repo.Repository
+	}
+
+	code, _ := assign("/user2/repo2/badges/stars.svg")
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = assign("/user2/repo2/badges/release.svg?sig=" + signature(repo.ID, "badges/stars.svg"))
+	assert.Equal(t, http.StatusNotFound, code)
+	code, assigned := assign("/user2/repo2/badges/stars.svg?sig=" + signature(repo.ID, "badges/stars.svg"))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, repo.ID, assigned.ID)
+}
+
+func TestSignedBadgeOfPrivateOwner(t *testing.T) {
+	unittest.PrepareTestEnv(t)
+	repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 40})
+	require.False(t, repo.IsPrivate)
+
+	// the public repositories of a private organization can't be read anonymously either
+	link, err := Link(db.DefaultContext, repo, "badges/stars.svg")
+	require.NoError(t, err)
+	assert.Contains(t, link, "/privated_org/public_repo_on_private_org/badges/stars.svg?sig=")
+
+	assign := func(path string) int {
+		ctx, resp := contexttest.MockContext(t, path)
+		ctx.SetParams(":username", "privated_org")
+		ctx.SetParams(":reponame", "public_repo_on_private_org")
+		RepoAssignment(ctx)
+		return resp.Code
+	}
+
+	assert.Equal(t, http.StatusNotFound, assign("/privated_org/public_repo_on_private_org/badges/stars.svg"))
+	assert.Equal(t, http.StatusOK, assign("/privated_org/public_repo_on_private_org/badges/stars.svg?sig="+signature(repo.ID, "badges/stars.svg")))
+
+	repo = unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 1})
+	link, err = Link(db.DefaultContext, repo, "badges/stars.svg")
+	require.NoError(t, err)
+	assert.NotContains(t, link, "?sig=")
+}
@@ -0,0 +1,106 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package badges
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"strings"
+
+	perm_model "code.gitea.io/gitea/models/perm"
+	access_model "code.gitea.io/gitea/models/perm/access"
+	repo_model "code.gitea.io/gitea/models/repo"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/setting"
+	context_module "code.gitea.io/gitea/services/context"
+)
+
+// signature signs the escaped path of a badge relative to the link of its repository, e.g. `badges/status.svg`.
+// The query isn't signed, a signed badge can be displayed for any branch or event.
+func signature(repoID int64, badgePath string) string {
+	mac := hmac.New(sha256.New, setting.GetGeneralTokenSigningSecret())
+	mac.Write([]byte("badge"))
+	mac.Write([]byte(strconv.FormatInt(repoID, 10)))
+	mac.Write([]byte(badgePath))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Link returns the absolute URL of a badge of a repository from its escaped path relative to the link of the repository.
+// The badges of a repository which can't be read anonymously, because it is private or its owner isn't public, are signed,
+// so they can be displayed to the readers of a README who can't read the repository, e.g. on a mirror or in an external documentation.
+func Link(ctx context.Context, repo *repo_model.Repository, badgePath string) (string, error) {
+	if err := repo.LoadOwner(ctx); err != nil {
+		return "", err
+	}
+	link := repo.HTMLURL() + "/" + badgePath
+	if repo.IsPrivate || !repo.Owner.Visibility.IsPublic() {
+		link += "?sig=" + signature(repo.ID, badgePath)
+	}
+	return link, nil
+}
+
+// RepoAssignment assigns the repository of a badge. The badges of a repository which can't be read by the doer
+// are only returned for a signed URL.
+func RepoAssignment(ctx *context_module.Context) {
+	owner, err := user_model.GetUserByName(ctx, ctx.Params("username"))
+	if err != nil {
+		if user_model.IsErrUserNotExist(err) {
+			if redirectUserID, err := user_model.LookupUserRedirect(ctx, ctx.Params("username")); err == nil {
+				context_module.RedirectToUser(ctx.Base, ctx.Params("username"), redirectUserID)
+			} else if user_model.IsErrUserRedirectNotExist(err) {
+				ctx.NotFound("GetUserByName", nil)
+			} else {
+				ctx.ServerError("LookupUserRedirect", err)
+			}
+		} else {
+			ctx.ServerError("GetUserByName", err)
+		}
+		return
+	}
+	repo, err := repo_model.GetRepositoryByName(ctx, owner.ID, ctx.Params("reponame"))
+	if err != nil {
+		if repo_model.IsErrRepoNotExist(err) {
+			if redirectRepoID, err := repo_model.LookupRedirect(ctx, owner.ID, ctx.Params("reponame")); err == nil {
+				context_module.RedirectToRepo(ctx.Base, redirectRepoID)
+			} else if repo_model.IsErrRedirectNotExist(err) {
+				ctx.NotFound("GetRepositoryByName", nil)
+			} else {
+				ctx.ServerError("LookupRepoRedirect", err)
+			}
+		} else {
+			ctx.ServerError("GetRepositoryByName", err)
+		}
+		return
+	}
+	repo.Owner = owner
+
+	permission, err := access_model.GetUserRepoPermission(ctx, repo, ctx.Doer)
+	if err != nil {
+		ctx.ServerError("GetUserRepoPermission", err)
+		return
+	}
+	if !permission.HasAccess() {
+		// the path is `/{username}/{reponame}/badges/...`, without the sub-path of the instance
+		parts := strings.SplitN(strings.TrimPrefix(ctx.Req.URL.EscapedPath(), "/"), "/", 3)
+		sig := ctx.Req.URL.Query().Get("sig")
+		if len(parts) != 3 || sig == "" || !hmac.Equal([]byte(sig), []byte(signature(repo.ID, parts[2]))) {
+			ctx.NotFound("no access right", nil)
+			return
+		}
+		// a signed badge can be displayed by anyone, as if the repository was public
+		if err := repo.LoadUnits(ctx); err != nil {
+			ctx.ServerError("LoadUnits", err)
+			return
+		}
+		permission = access_model.Permission{AccessMode: perm_model.AccessModeRead, Units: repo.Units}
+	}
+
+	ctx.Repo.Owner = owner
+	ctx.Repo.Repository = repo
+	ctx.Repo.Permission = permission
+	ctx.Repo.RepoLink = repo.Link()
+}
@@ -1506,6 +1506,9 @@ func ViewIssue(ctx *context.Context) {
 		} else {
 			PrepareViewPullInfo(ctx, issue)
 			ctx.Data["DisableStatusChange"] = ctx.Data["IsPullRequestBroken"] == true && issue.IsClosed
+			if !ctx.Written() && !issue.IsClosed {
+				prepareViewPullActionsApproval(ctx, issue)
+			}
 		}
 		if ctx.Written() {
 			return
@@ -39,6 +39,7 @@ import (
 	"code.gitea.io/gitea/modules/util"
 	"code.gitea.io/gitea/modules/web"
 	"code.gitea.io/gitea/routers/utils"
+	actions_service "code.gitea.io/gitea/services/actions"
 	asymkey_service "code.gitea.io/gitea/services/asymkey"
 	"code.gitea.io/gitea/services/automerge"
 	"code.gitea.io/gitea/services/context"
@@ -1420,6 +1421,35 @@ func CancelAutoMergePullRequest(ctx *context.Context) {
 	ctx.Redirect(fmt.Sprintf("%s/pulls/%d", ctx.Repo.RepoLink, issue.Index))
 }
 
+// ApprovePullRequestActionsRuns approves the runs of a pull request from an untrusted contributor
+func ApprovePullRequestActionsRuns(ctx *context.Context) {
+	issue, ok := getPullInfo(ctx)
+	if !ok {
+		return
+	}
+
+	if err := actions_service.ApprovePullRequestRuns(ctx, issue.PullRequest, ctx.Doer); err != nil {
+		ctx.ServerError("ApprovePullRequestRuns", err)
+		return
+	}
+	ctx.Flash.Success(ctx.Tr("repo.pulls.actions_runs_approved"))
+	ctx.Redirect(issue.Link())
+}
+
+// prepareViewPullActionsApproval shows the runs of the pull request waiting for the approval of a maintainer
+func prepareViewPullActionsApproval(ctx *context.Context, issue *issues_model.Issue) {
+	if !ctx.Repo.CanRead(unit.TypeActions) {
+		return
+	}
+	runs, err := actions_service.FindPullRequestRunsNeedingApproval(ctx, issue.PullRequest)
+	if err != nil {
+		ctx.ServerError("FindPullRequestRunsNeedingApproval", err)
+		return
+	}
+	ctx.Data["ActionsRunsNeedingApproval"] = len(runs)
+	ctx.Data["CanApproveActionsRuns"] = ctx.Repo.CanWrite(unit.TypeActions) && !ctx.Repo.Repository.IsArchived
+}
+
 func stopTimerIfAvailable(ctx *context.Context, user *user_model.User, issue *issues_model.Issue) error {
 	if issues_model.StopwatchExists(ctx, user.ID, issue.ID) {
 		if err := issues_model.CreateOrStopIssueStopwatch(ctx, user, issue); err != nil {
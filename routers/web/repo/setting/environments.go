@@ -0,0 +1,383 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package setting
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	actions_model "code.gitea.io/gitea/models/actions"
+	"code.gitea.io/gitea/models/db"
+	access_model "code.gitea.io/gitea/models/perm/access"
+	secret_model "code.gitea.io/gitea/models/secret"
+	"code.gitea.io/gitea/models/unit"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/base"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/util"
+	"code.gitea.io/gitea/modules/web"
+	actions_service "code.gitea.io/gitea/services/actions"
+	"code.gitea.io/gitea/services/context"
+	"code.gitea.io/gitea/services/forms"
+	secret_service "code.gitea.io/gitea/services/secrets"
+)
+
+const (
+	tplRepoEnvironments base.TplName = "repo/settings/actions"
+)
+
+func environmentsLink(ctx *context.Context) string {
+	return ctx.Repo.RepoLink + "/settings/actions/environments"
+}
+
+// getEnvironment returns the environment of the repository from the URL, it writes a 404 if it doesn't exist.
+func getEnvironment(ctx *context.Context) *actions_model.ActionEnvironment {
+	env, err := actions_model.GetEnvironmentByID(ctx, ctx.ParamsInt64(":environment_id"))
+	if err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			ctx.NotFound("GetEnvironmentByID", err)
+		} else {
+			ctx.ServerError("GetEnvironmentByID", err)
+		}
+		return nil
+	}
+	if env.RepoID != ctx.Repo.Repository.ID {
+		ctx.NotFound("GetEnvironmentByID", nil)
+		return nil
+	}
+
+	ctx.Data["Environment"] = env
+	ctx.Data["EnvironmentLink"] = fmt.Sprintf("%s/%d", environmentsLink(ctx), env.ID)
+	return env
+}
+
+// Environments renders the environments of a repository
+func Environments(ctx *context.Context) {
+	ctx.Data["Title"] = ctx.Tr("actions.environments")
+	ctx.Data["PageType"] = "environments"
+	ctx.Data["PageIsSharedSettingsEnvironments"] = true
+
+	envs, err := db.Find[actions_model.ActionEnvironment](ctx, actions_model.FindEnvironmentsOptions{RepoID: ctx.Repo.Repository.ID})
+	if err != nil {
+		ctx.ServerError("FindEnvironments", err)
+		return
+	}
+	ctx.Data["Environments"] = envs
+
+	ctx.HTML(http.StatusOK, tplRepoEnvironments)
+}
+
+// EnvironmentCreate creates an environment without protection rules
+func EnvironmentCreate(ctx *context.Context) {
+	if ctx.HasError() {
+		ctx.JSONError(ctx.GetErrMsg())
+		return
+	}
+	form := web.GetForm(ctx).(*forms.NewEnvironmentForm)
+
+	env, err := actions_service.CreateEnvironment(ctx, ctx.Repo.Repository, form.Name, &actions_service.EnvironmentOptions{})
+	if err != nil {
+		switch {
+		case errors.Is(err, util.ErrAlreadyExist):
+			ctx.JSONError(ctx.Tr("actions.environments.creation.already_exists", form.Name))
+		case errors.Is(err, util.ErrInvalidArgument):
+			ctx.JSONError(ctx.Tr("actions.environments.creation.invalid_name"))
+		default:
+			log.Error("CreateEnvironment: %v", err)
+			ctx.JSONError(ctx.Tr("actions.environments.creation.failed"))
+		}
+		return
+	}
+
+	ctx.Flash.Success(ctx.Tr("actions.environments.creation.success", env.Name))
+	ctx.JSONRedirect(fmt.Sprintf("%s/%d", environmentsLink(ctx), env.ID))
+}
+
+// EnvironmentEdit renders the protection rules and the deployment history of an environment
+func EnvironmentEdit(ctx *context.Context) {
+	env := getEnvironment(ctx)
+	if ctx.Written() {
+		return
+	}
+	ctx.Data["Title"] = env.Name
+	ctx.Data["PageType"] = "environment"
+	ctx.Data["PageIsSharedSettingsEnvironments"] = true
+
+	reviewers, err := user_model.GetUserNamesByIDs(ctx, env.ReviewerIDs)
+	if err != nil {
+		ctx.ServerError("GetUserNamesByIDs", err)
+		return
+	}
+	ctx.Data["Reviewers"] = strings.Join(reviewers, ", ")
+	ctx.Data["BranchPatterns"] = strings.Join(env.BranchPatterns, ", ")
+
+	deployments, err := db.Find[actions_model.ActionDeployment](ctx, actions_model.FindDeploymentsOptions{
+		ListOptions:   db.ListOptions{Page: 1, PageSize: 20},
+		EnvironmentID: env.ID,
+	})
+	if err != nil {
+		ctx.ServerError("FindDeployments", err)
+		return
+	}
+	for _, deployment := range deployments {
+		deployment.Environment = env
+		if err := deployment.LoadAttributes(ctx); err != nil {
+			ctx.ServerError("LoadAttributes", err)
+			return
+		}
+	}
+	ctx.Data["Deployments"] = deployments
+
+	ctx.HTML(http.StatusOK, tplRepoEnvironments)
+}
+
+// EnvironmentEditPost updates the protection rules of an environment
+func EnvironmentEditPost(ctx *context.Context) {
+	env := getEnvironment(ctx)
+	if ctx.Written() {
+		return
+	}
+	redirectLink := ctx.Data["EnvironmentLink"].(string)
+	if ctx.HasError() {
+		ctx.Flash.Error(ctx.GetErrMsg())
+		ctx.Redirect(redirectLink)
+		return
+	}
+	form := web.GetForm(ctx).(*forms.EditEnvironmentForm)
+
+	opts := &actions_service.EnvironmentOptions{
+		WaitTimer:      form.WaitTimer,
+		BranchPatterns: splitCommaSeparated(form.BranchPatterns),
+	}
+	for _, name := range splitCommaSeparated(form.Reviewers) {
+		reviewer, err := user_model.GetUserByName(ctx, name)
+		if err != nil {
+			if user_model.IsErrUserNotExist(err) {
+				ctx.Flash.Error(ctx.Tr("actions.environments.update.reviewer_not_exist", name))
+				ctx.Redirect(redirectLink)
+				return
+			}
+			ctx.ServerError("GetUserByName", err)
+			return
+		}
+		perm, err := access_model.GetUserRepoPermission(ctx, ctx.Repo.Repository, reviewer)
+		if err != nil {
+			ctx.ServerError("GetUserRepoPermission", err)
+			return
+		}
+		if !perm.CanWrite(unit.TypeActions) {
+			ctx.Flash.Error(ctx.Tr("actions.environments.update.reviewer_no_access", name))
+			ctx.Redirect(redirectLink)
+			return
+		}
+		opts.ReviewerIDs = append(opts.ReviewerIDs, reviewer.ID)
+	}
+
+	if err := actions_service.UpdateEnvironment(ctx, env, opts); err != nil {
+		if errors.Is(err, util.ErrInvalidArgument) {
+			ctx.Flash.Error(err.Error())
+			ctx.Redirect(redirectLink)
+			return
+		}
+		ctx.ServerError("UpdateEnvironment", err)
+		return
+	}
+
+	ctx.Flash.Success(ctx.Tr("actions.environments.update.success"))
+	ctx.Redirect(redirectLink)
+}
+
+// EnvironmentDelete deletes an environment with its secrets, its variables and its deployment history
+func EnvironmentDelete(ctx *context.Context) {
+	env := getEnvironment(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	if err := actions_service.DeleteEnvironment(ctx, env); err != nil {
+		log.Error("DeleteEnvironment(%d): %v", env.ID, err)
+		ctx.JSONError(ctx.Tr("actions.environments.deletion.failed"))
+		return
+	}
+
+	ctx.Flash.Success(ctx.Tr("actions.environments.deletion.success"))
+	ctx.JSONRedirect(environmentsLink(ctx))
+}
+
+// EnvironmentSecrets renders the secrets of an environment
+func EnvironmentSecrets(ctx *context.Context) {
+	env := getEnvironment(ctx)
+	if ctx.Written() {
+		return
+	}
+	ctx.Data["Title"] = env.Name
+	ctx.Data["PageType"] = "secrets"
+	ctx.Data["PageIsSharedSettingsEnvironments"] = true
+
+	secrets, err := db.Find[secret_model.Secret](ctx, secret_model.FindSecretsOptions{RepoID: env.RepoID, EnvironmentID: env.ID})
+	if err != nil {
+		ctx.ServerError("FindSecrets", err)
+		return
+	}
+	ctx.Data["Secrets"] = secrets
+
+	ctx.HTML(http.StatusOK, tplRepoEnvironments)
+}
+
+// EnvironmentSecretsPost creates or updates a secret of an environment
+func EnvironmentSecretsPost(ctx *context.Context) {
+	env := getEnvironment(ctx)
+	if ctx.Written() {
+		return
+	}
+	if ctx.HasError() {
+		ctx.JSONError(ctx.GetErrMsg())
+		return
+	}
+	form := web.GetForm(ctx).(*forms.AddSecretForm)
+
+	s, _, err := secret_service.CreateOrUpdateEnvironmentSecret(ctx, env.RepoID, env.ID, form.Name, util.ReserveLineBreakForTextarea(form.Data))
+	if err != nil {
+		log.Error("CreateOrUpdateEnvironmentSecret failed: %v", err)
+		ctx.JSONError(ctx.Tr("secrets.creation.failed"))
+		return
+	}
+
+	ctx.Flash.Success(ctx.Tr("secrets.creation.success", s.Name))
+	ctx.JSONRedirect(ctx.Data["EnvironmentLink"].(string) + "/secrets")
+}
+
+// EnvironmentSecretsDelete deletes a secret of an environment
+func EnvironmentSecretsDelete(ctx *context.Context) {
+	env := getEnvironment(ctx)
+	if ctx.Written() {
+		return
+	}
+	id := ctx.FormInt64("id")
+
+	if err := secret_service.DeleteEnvironmentSecretByID(ctx, env.RepoID, env.ID, id); err != nil {
+		log.Error("DeleteEnvironmentSecretByID(%d) failed: %v", id, err)
+		ctx.JSONError(ctx.Tr("secrets.deletion.failed"))
+		return
+	}
+
+	ctx.Flash.Success(ctx.Tr("secrets.deletion.success"))
+	ctx.JSONRedirect(ctx.Data["EnvironmentLink"].(string) + "/secrets")
+}
+
+// EnvironmentVariables renders the variables of an environment
+func EnvironmentVariables(ctx *context.Context) {
+	env := getEnvironment(ctx)
+	if ctx.Written() {
+		return
+	}
+	ctx.Data["Title"] = env.Name
+	ctx.Data["PageType"] = "variables"
+	ctx.Data["PageIsSharedSettingsEnvironments"] = true
+
+	variables, err := db.Find[actions_model.ActionVariable](ctx, actions_model.FindVariablesOpts{RepoID: env.RepoID, EnvironmentID: env.ID})
+	if err != nil {
+		ctx.ServerError("FindVariables", err)
+		return
+	}
+	ctx.Data["Variables"] = variables
+
+	ctx.HTML(http.StatusOK, tplRepoEnvironments)
+}
+
+// EnvironmentVariableCreate creates a variable of an environment
+func EnvironmentVariableCreate(ctx *context.Context) {
+	env := getEnvironment(ctx)
+	if ctx.Written() {
+		return
+	}
+	if ctx.HasError() {
+		ctx.JSONError(ctx.GetErrMsg())
+		return
+	}
+	form := web.GetForm(ctx).(*forms.EditVariableForm)
+
+	v, err := actions_service.CreateEnvironmentVariable(ctx, env.RepoID, env.ID, form.Name, form.Data)
+	if err != nil {
+		log.Error("CreateEnvironmentVariable: %v", err)
+		ctx.JSONError(ctx.Tr("actions.variables.creation.failed"))
+		return
+	}
+
+	ctx.Flash.Success(ctx.Tr("actions.variables.creation.success", v.Name))
+	ctx.JSONRedirect(ctx.Data["EnvironmentLink"].(string) + "/variables")
+}
+
+// EnvironmentVariableUpdate updates a variable of an environment
+func EnvironmentVariableUpdate(ctx *context.Context) {
+	env := getEnvironment(ctx)
+	if ctx.Written() {
+		return
+	}
+	if ctx.HasError() {
+		ctx.JSONError(ctx.GetErrMsg())
+		return
+	}
+	form := web.GetForm(ctx).(*forms.EditVariableForm)
+
+	v := getEnvironmentVariable(ctx, env)
+	if ctx.Written() {
+		return
+	}
+	if ok, err := actions_service.UpdateVariable(ctx, v.ID, form.Name, form.Data); err != nil || !ok {
+		log.Error("UpdateVariable: %v", err)
+		ctx.JSONError(ctx.Tr("actions.variables.update.failed"))
+		return
+	}
+
+	ctx.Flash.Success(ctx.Tr("actions.variables.update.success"))
+	ctx.JSONRedirect(ctx.Data["EnvironmentLink"].(string) + "/variables")
+}
+
+// EnvironmentVariableDelete deletes a variable of an environment
+func EnvironmentVariableDelete(ctx *context.Context) {
+	env := getEnvironment(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	v := getEnvironmentVariable(ctx, env)
+	if ctx.Written() {
+		return
+	}
+	if err := actions_service.DeleteVariableByID(ctx, v.ID); err != nil {
+		log.Error("Delete variable [%d] failed: %v", v.ID, err)
+		ctx.JSONError(ctx.Tr("actions.variables.deletion.failed"))
+		return
+	}
+
+	ctx.Flash.Success(ctx.Tr("actions.variables.deletion.success"))
+	ctx.JSONRedirect(ctx.Data["EnvironmentLink"].(string) + "/variables")
+}
+
+// getEnvironmentVariable returns the variable of the environment from the URL, it writes a 404 if it doesn't exist.
+func getEnvironmentVariable(ctx *context.Context, env *actions_model.ActionEnvironment) *actions_model.ActionVariable {
+	v, has, err := db.GetByID[actions_model.ActionVariable](ctx, ctx.ParamsInt64(":variable_id"))
+	if err != nil {
+		ctx.ServerError("GetVariableByID", err)
+		return nil
+	}
+	if !has || v.RepoID != env.RepoID || v.EnvironmentID != env.ID {
+		ctx.NotFound("GetVariableByID", nil)
+		return nil
+	}
+	return v
+}
+
+func splitCommaSeparated(s string) []string {
+	var values []string
+	for _, value := range strings.Split(s, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,235 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package setting
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	actions_model "code.gitea.io/gitea/models/actions"
+	"code.gitea.io/gitea/models/db"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/util"
+	"code.gitea.io/gitea/modules/web"
+	actions_service "code.gitea.io/gitea/services/actions"
+	"code.gitea.io/gitea/services/context"
+	"code.gitea.io/gitea/services/forms"
+)
+
+// getRunnerGroupsCtx returns the context of the runners whose groups are managed, only the instance
+// and the organizations have runner groups. It writes a 404 otherwise.
+func getRunnerGroupsCtx(ctx *context.Context) *runnersCtx {
+	rCtx, err := getRunnersCtx(ctx)
+	if err != nil {
+		ctx.ServerError("getRunnersCtx", err)
+		return nil
+	}
+	if ctx.Written() {
+		return nil
+	}
+	if !rCtx.IsAdmin && !rCtx.IsOrg {
+		ctx.NotFound("getRunnerGroupsCtx", nil)
+		return nil
+	}
+	ctx.Data["PageIsSharedSettingsRunnerGroups"] = true
+	ctx.Data["RunnerGroupsLink"] = runnerGroupsLink(rCtx)
+	return rCtx
+}
+
+func runnerGroupsLink(rCtx *runnersCtx) string {
+	return strings.TrimSuffix(rCtx.RedirectLink, "runners/") + "runner-groups"
+}
+
+// getRunnerGroup returns the runner group from the URL, it writes a 404 if it doesn't exist.
+func getRunnerGroup(ctx *context.Context, rCtx *runnersCtx) *actions_model.ActionRunnerGroup {
+	g, err := actions_model.GetRunnerGroupByID(ctx, ctx.ParamsInt64(":group_id"))
+	if err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			ctx.NotFound("GetRunnerGroupByID", err)
+		} else {
+			ctx.ServerError("GetRunnerGroupByID", err)
+		}
+		return nil
+	}
+	if g.OwnerID != rCtx.OwnerID {
+		ctx.NotFound("GetRunnerGroupByID", nil)
+		return nil
+	}
+
+	ctx.Data["RunnerGroup"] = g
+	ctx.Data["RunnerGroupLink"] = fmt.Sprintf("%s/%d", runnerGroupsLink(rCtx), g.ID)
+	return g
+}
+
+// RunnerGroups renders the runner groups of the instance or of an organization
+func RunnerGroups(ctx *context.Context) {
+	rCtx := getRunnerGroupsCtx(ctx)
+	if ctx.Written() {
+		return
+	}
+	ctx.Data["Title"] = ctx.Tr("actions.runners.groups")
+	ctx.Data["PageType"] = "runner_groups"
+
+	groups, err := db.Find[actions_model.ActionRunnerGroup](ctx, actions_model.FindRunnerGroupsOptions{OwnerID: rCtx.OwnerID})
+	if err != nil {
+		ctx.ServerError("FindRunnerGroups", err)
+		return
+	}
+	ctx.Data["RunnerGroups"] = groups
+
+	ctx.HTML(http.StatusOK, rCtx.RunnersTemplate)
+}
+
+// RunnerGroupCreate creates a runner group without access policies
+func RunnerGroupCreate(ctx *context.Context) {
+	rCtx := getRunnerGroupsCtx(ctx)
+	if ctx.Written() {
+		return
+	}
+	if ctx.HasError() {
+		ctx.JSONError(ctx.GetErrMsg())
+		return
+	}
+	form := web.GetForm(ctx).(*forms.NewRunnerGroupForm)
+
+	g, err := actions_service.CreateRunnerGroup(ctx, rCtx.OwnerID, form.Name, &actions_service.RunnerGroupOptions{})
+	if err != nil {
+		switch {
+		case errors.Is(err, util.ErrAlreadyExist):
+			ctx.JSONError(ctx.Tr("actions.runners.groups.creation.already_exists", form.Name))
+		case errors.Is(err, util.ErrInvalidArgument):
+			ctx.JSONError(ctx.Tr("actions.runners.groups.creation.invalid_name"))
+		default:
+			log.Error("CreateRunnerGroup: %v", err)
+			ctx.JSONError(ctx.Tr("actions.runners.groups.creation.failed"))
+		}
+		return
+	}
+
+	ctx.Flash.Success(ctx.Tr("actions.runners.groups.creation.success", g.Name))
+	ctx.JSONRedirect(fmt.Sprintf("%s/%d", runnerGroupsLink(rCtx), g.ID))
+}
+
+// RunnerGroupEdit renders the access policies and the runners of a runner group
+func RunnerGroupEdit(ctx *context.Context) {
+	rCtx := getRunnerGroupsCtx(ctx)
+	if ctx.Written() {
+		return
+	}
+	g := getRunnerGroup(ctx, rCtx)
+	if ctx.Written() {
+		return
+	}
+	ctx.Data["Title"] = g.Name
+	ctx.Data["PageType"] = "runner_group"
+
+	owners, err := user_model.GetUserNamesByIDs(ctx, g.AllowedOwnerIDs)
+	if err != nil {
+		ctx.ServerError("GetUserNamesByIDs", err)
+		return
+	}
+	repos, err := g.GetAllowedRepoNames(ctx)
+	if err != nil {
+		ctx.ServerError("GetAllowedRepoNames", err)
+		return
+	}
+	ctx.Data["AllowedOwners"] = strings.Join(owners, ", ")
+	ctx.Data["AllowedRepos"] = strings.Join(repos, ", ")
+	ctx.Data["AllowedWorkflows"] = strings.Join(g.AllowedWorkflows, ", ")
+	ctx.Data["CanRestrictOwners"] = rCtx.IsAdmin
+
+	runners, err := db.Find[actions_model.ActionRunner](ctx, actions_model.FindRunnerOptions{GroupID: g.ID, Sort: "alphabetically"})
+	if err != nil {
+		ctx.ServerError("FindRunners", err)
+		return
+	}
+	ctx.Data["Runners"] = runners
+	ctx.Data["RunnersLink"] = strings.TrimSuffix(rCtx.RedirectLink, "/")
+
+	ctx.HTML(http.StatusOK, rCtx.RunnersTemplate)
+}
+
+// RunnerGroupEditPost updates the access policies of a runner group
+func RunnerGroupEditPost(ctx *context.Context) {
+	rCtx := getRunnerGroupsCtx(ctx)
+	if ctx.Written() {
+		return
+	}
+	g := getRunnerGroup(ctx, rCtx)
+	if ctx.Written() {
+		return
+	}
+	redirectLink := ctx.Data["RunnerGroupLink"].(string)
+	if ctx.HasError() {
+		ctx.Flash.Error(ctx.GetErrMsg())
+		ctx.Redirect(redirectLink)
+		return
+	}
+	form := web.GetForm(ctx).(*forms.EditRunnerGroupForm)
+
+	opts := &actions_service.RunnerGroupOptions{
+		Description:      form.Description,
+		AllowedWorkflows: splitCommaSeparated(form.AllowedWorkflows),
+	}
+	var err error
+	if rCtx.IsAdmin {
+		opts.AllowedOwnerIDs, err = actions_service.ResolveRunnerGroupOwners(ctx, splitCommaSeparated(form.AllowedOwners))
+		if err != nil {
+			if errors.Is(err, util.ErrNotExist) {
+				ctx.Flash.Error(err.Error())
+				ctx.Redirect(redirectLink)
+				return
+			}
+			ctx.ServerError("ResolveRunnerGroupOwners", err)
+			return
+		}
+	}
+	opts.AllowedRepoIDs, err = actions_service.ResolveRunnerGroupRepos(ctx, rCtx.OwnerID, splitCommaSeparated(form.AllowedRepos))
+	if err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			ctx.Flash.Error(err.Error())
+			ctx.Redirect(redirectLink)
+			return
+		}
+		ctx.ServerError("ResolveRunnerGroupRepos", err)
+		return
+	}
+
+	if err := actions_service.UpdateRunnerGroup(ctx, g, opts); err != nil {
+		if errors.Is(err, util.ErrInvalidArgument) {
+			ctx.Flash.Error(err.Error())
+			ctx.Redirect(redirectLink)
+			return
+		}
+		ctx.ServerError("UpdateRunnerGroup", err)
+		return
+	}
+
+	ctx.Flash.Success(ctx.Tr("actions.runners.groups.update.success"))
+	ctx.Redirect(redirectLink)
+}
+
+// RunnerGroupDelete deletes a runner group, its runners don't belong to a group anymore
+func RunnerGroupDelete(ctx *context.Context) {
+	rCtx := getRunnerGroupsCtx(ctx)
+	if ctx.Written() {
+		return
+	}
+	g := getRunnerGroup(ctx, rCtx)
+	if ctx.Written() {
+		return
+	}
+
+	if err := actions_model.DeleteRunnerGroup(ctx, g); err != nil {
+		log.Error("DeleteRunnerGroup(%d): %v", g.ID, err)
+		ctx.JSONError(ctx.Tr("actions.runners.groups.deletion.failed"))
+		return
+	}
+
+	ctx.Flash.Success(ctx.Tr("actions.runners.groups.deletion.success"))
+	ctx.JSONRedirect(runnerGroupsLink(rCtx))
+}
@@ -236,9 +236,18 @@ func UnitsPost(ctx *context.Context) {
 	}
 
 	if form.EnableActions && !unit_model.TypeActions.UnitGlobalDisabled() {
+		approvalPolicy := repo_model.ActionsApprovalPolicy(form.ActionsApprovalPolicy)
+		if approvalPolicy != repo_model.ActionsApprovalAllForks {
+			approvalPolicy = repo_model.ActionsApprovalFirstTimeContributors
+		}
 		units = append(units, repo_model.RepoUnit{
 			RepoID: repo.ID,
 			Type:   unit_model.TypeActions,
+			Config: &repo_model.ActionsConfig{
+				// the disabled workflows are managed from the actions page
+				DisabledWorkflows: repo.MustGetUnit(ctx, unit_model.TypeActions).ActionsConfig().DisabledWorkflows,
+				ApprovalPolicy:    approvalPolicy,
+			},
 		})
 	} else if !unit_model.TypeActions.UnitGlobalDisabled() {
 		deleteUnitTypes = append(deleteUnitTypes, unit_model.TypeActions)
@@ -0,0 +1,212 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package setting
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	repo_model "code.gitea.io/gitea/models/repo"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/base"
+	"code.gitea.io/gitea/modules/util"
+	"code.gitea.io/gitea/services/context"
+	terraform_service "code.gitea.io/gitea/services/terraform"
+)
+
+const (
+	tplSettingsTerraformStates base.TplName = "repo/settings/terraform_states"
+	tplSettingsTerraformState  base.TplName = "repo/settings/terraform_state"
+)
+
+func terraformStatesLink(ctx *context.Context) string {
+	return ctx.Repo.RepoLink + "/settings/terraform"
+}
+
+// getTerraformState returns the state of the repository from the URL, it writes a 404 if it doesn't exist.
+func getTerraformState(ctx *context.Context) *repo_model.TerraformState {
+	s, err := repo_model.GetTerraformStateByID(ctx, ctx.Repo.Repository.ID, ctx.ParamsInt64(":state_id"))
+	if err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			ctx.NotFound("GetTerraformStateByID", err)
+		} else {
+			ctx.ServerError("GetTerraformStateByID", err)
+		}
+		return nil
+	}
+
+	ctx.Data["TerraformState"] = s
+	ctx.Data["TerraformStateLink"] = fmt.Sprintf("%s/%d", terraformStatesLink(ctx), s.ID)
+	return s
+}
+
+// TerraformStates renders the Terraform states of a repository
+func TerraformStates(ctx *context.Context) {
+	ctx.Data["Title"] = ctx.Tr("repo.settings.terraform")
+	ctx.Data["PageIsSettingsTerraform"] = true
+	ctx.Data["TerraformStatesLink"] = terraformStatesLink(ctx)
+	ctx.Data["TerraformStateAddress"] = ctx.Repo.Repository.HTMLURL() + "/terraform/state/"
+
+	states, err := repo_model.GetTerraformStates(ctx, ctx.Repo.Repository.ID)
+	if err != nil {
+		ctx.ServerError("GetTerraformStates", err)
+		return
+	}
+	ctx.Data["TerraformStates"] = states
+
+	lockers, err := getUsersByID(ctx, states, func(s *repo_model.TerraformState) int64 { return s.LockerID })
+	if err != nil {
+		ctx.ServerError("GetPossibleUserByIDs", err)
+		return
+	}
+	ctx.Data["Lockers"] = lockers
+
+	ctx.HTML(http.StatusOK, tplSettingsTerraformStates)
+}
+
+// TerraformState renders the versions of a Terraform state
+func TerraformState(ctx *context.Context) {
+	s := getTerraformState(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	ctx.Data["Title"] = ctx.Tr("repo.settings.terraform")
+	ctx.Data["PageIsSettingsTerraform"] = true
+	ctx.Data["TerraformStatesLink"] = terraformStatesLink(ctx)
+
+	versions, err := repo_model.GetTerraformStateVersions(ctx, s.ID)
+	if err != nil {
+		ctx.ServerError("GetTerraformStateVersions", err)
+		return
+	}
+	ctx.Data["Versions"] = versions
+
+	creators, err := getUsersByID(ctx, versions, func(v *repo_model.TerraformStateVersion) int64 { return v.CreatorID })
+	if err != nil {
+		ctx.ServerError("GetPossibleUserByIDs", err)
+		return
+	}
+	ctx.Data["Creators"] = creators
+
+	ctx.HTML(http.StatusOK, tplSettingsTerraformState)
+}
+
+func getUsersByID[T any](ctx *context.Context, items []T, userID func(T) int64) (map[int64]*user_model.User, error) {
+	ids := make([]int64, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, userID(item))
+	}
+	users, err := user_model.GetPossibleUserByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[int64]*user_model.User, len(users))
+	for _, u := range users {
+		m[u.ID] = u
+	}
+	return m, nil
+}
+
+// TerraformStateVersionDownload serves the content of a version of a Terraform state
+func TerraformStateVersionDownload(ctx *context.Context) {
+	s := getTerraformState(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	v, err := repo_model.GetTerraformStateVersionByID(ctx, s.ID, ctx.ParamsInt64(":version_id"))
+	if err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			ctx.NotFound("GetTerraformStateVersionByID", err)
+		} else {
+			ctx.ServerError("GetTerraformStateVersionByID", err)
+		}
+		return
+	}
+
+	obj, err := terraform_service.OpenStateVersion(v)
+	if err != nil {
+		ctx.ServerError("OpenStateVersion", err)
+		return
+	}
+	defer obj.Close()
+
+	ctx.ServeContent(obj, &context.ServeHeaderOptions{
+		Filename:      fmt.Sprintf("%s-%d.tfstate", s.Name, v.Serial),
+		ContentLength: &v.Size,
+		ContentType:   "application/json",
+		LastModified:  v.CreatedUnix.AsLocalTime(),
+	})
+}
+
+// TerraformStateRollback restores an older version of a Terraform state
+func TerraformStateRollback(ctx *context.Context) {
+	s := getTerraformState(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	if err := terraform_service.RollbackState(ctx, ctx.Doer, s, ctx.ParamsInt64(":version_id")); err != nil {
+		if !handleTerraformStateActionError(ctx, err) {
+			ctx.ServerError("RollbackState", err)
+		}
+		return
+	}
+
+	ctx.Flash.Success(ctx.Tr("repo.settings.terraform_rollback_success"))
+	ctx.Redirect(fmt.Sprintf("%s/%d", terraformStatesLink(ctx), s.ID))
+}
+
+// TerraformStateUnlock forcibly removes the lock of a Terraform state
+func TerraformStateUnlock(ctx *context.Context) {
+	s := getTerraformState(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	if _, err := repo_model.UnlockTerraformState(ctx, s, ""); err != nil {
+		ctx.ServerError("UnlockTerraformState", err)
+		return
+	}
+
+	ctx.Flash.Success(ctx.Tr("repo.settings.terraform_unlock_success"))
+	ctx.Redirect(terraformStatesLink(ctx))
+}
+
+// TerraformStateDelete deletes a Terraform state with all its versions
+func TerraformStateDelete(ctx *context.Context) {
+	s := getTerraformState(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	if err := terraform_service.DeleteState(ctx, s); err != nil {
+		if !handleTerraformStateActionError(ctx, err) {
+			ctx.ServerError("DeleteState", err)
+		}
+		return
+	}
+
+	ctx.Flash.Success(ctx.Tr("repo.settings.terraform_delete_success"))
+	ctx.Redirect(terraformStatesLink(ctx))
+}
+
+// handleTerraformStateActionError flashes the errors caused by the user and redirects back, it reports if the error has been handled
+func handleTerraformStateActionError(ctx *context.Context, err error) bool {
+	var errLocked terraform_service.ErrStateLocked
+	switch {
+	case errors.As(err, &errLocked):
+		ctx.Flash.Error(ctx.Tr("repo.settings.terraform_state_locked"))
+	case errors.Is(err, util.ErrNotExist):
+		ctx.Flash.Error(ctx.Tr("repo.settings.terraform_version_not_exist"))
+	case errors.Is(err, terraform_service.ErrInvalidState):
+		ctx.Flash.Error(ctx.Tr("repo.settings.terraform_invalid_state"))
+	default:
+		return false
+	}
+	ctx.Redirect(terraformStatesLink(ctx))
+	return true
+}
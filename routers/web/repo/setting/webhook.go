@@ -21,6 +21,7 @@ import (
 	"code.gitea.io/gitea/modules/json"
 	"code.gitea.io/gitea/modules/setting"
 	api "code.gitea.io/gitea/modules/structs"
+	"code.gitea.io/gitea/modules/web"
 	"code.gitea.io/gitea/modules/web/middleware"
 	webhook_module "code.gitea.io/gitea/modules/webhook"
 	"code.gitea.io/gitea/services/context"
@@ -37,6 +38,8 @@ const (
 	tplOrgHookNew   base.TplName = "org/settings/hook_new"
 	tplUserHookNew  base.TplName = "user/settings/hook_new"
 	tplAdminHookNew base.TplName = "admin/hook_new"
+
+	tplCustomHookPreview base.TplName = "webhook/custom_preview"
 )
 
 // WebhookList render web hooks list page
@@ -451,6 +454,24 @@ func WebhookTest(ctx *context.Context) {
 	}
 }
 
+// WebhookCustomPreview renders the templates of a custom webhook with a sample payload
+func WebhookCustomPreview(ctx *context.Context) {
+	form := web.GetForm(ctx).(*forms.CustomWebhookPreviewForm)
+	if ctx.HasError() {
+		ctx.Data["PreviewError"] = ctx.GetErrMsg()
+		ctx.HTML(http.StatusOK, tplCustomHookPreview)
+		return
+	}
+
+	preview, err := webhook_service.PreviewCustomTemplates(form.BodyTemplate, form.HeadersTemplate, webhook_module.HookEventType(form.PreviewEvent))
+	if err != nil {
+		ctx.Data["PreviewError"] = err.Error()
+	} else {
+		ctx.Data["Preview"] = preview
+	}
+	ctx.HTML(http.StatusOK, tplCustomHookPreview)
+}
+
 // WebhookReplay replays a webhook
 func WebhookReplay(ctx *context.Context) {
 	hookTaskUUID := ctx.Params(":uuid")
@@ -0,0 +1,202 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package repo
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	actions_model "code.gitea.io/gitea/models/actions"
+	auth_model "code.gitea.io/gitea/models/auth"
+	"code.gitea.io/gitea/models/perm"
+	access_model "code.gitea.io/gitea/models/perm/access"
+	repo_model "code.gitea.io/gitea/models/repo"
+	"code.gitea.io/gitea/models/unit"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/services/context"
+	terraform_service "code.gitea.io/gitea/services/terraform"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func init() {
+	// Terraform uses the WebDAV methods to lock and unlock a state
+	chi.RegisterMethod("LOCK")
+	chi.RegisterMethod("UNLOCK")
+}
+
+// TerraformStateAssignment loads the repository of the Terraform HTTP state backend and checks the access of the doer.
+// A state often contains secrets, so every operation requires write access to the code of the repository.
+func TerraformStateAssignment(ctx *context.Context) {
+	if !ctx.IsSigned {
+		ctx.Resp.Header().Set("WWW-Authenticate", `Basic realm="Gitea"`)
+		ctx.Error(http.StatusUnauthorized)
+		return
+	}
+
+	repo, err := repo_model.GetRepositoryByOwnerAndName(ctx, ctx.Params("username"), ctx.Params("reponame"))
+	if err != nil {
+		if repo_model.IsErrRepoNotExist(err) {
+			ctx.PlainText(http.StatusNotFound, "Repository not found")
+			return
+		}
+		ctx.ServerError("GetRepositoryByOwnerAndName", err)
+		return
+	}
+
+	if ctx.Data["IsActionsToken"] == true {
+		taskID := ctx.Data["ActionsTaskID"].(int64)
+		task, err := actions_model.GetTaskByID(ctx, taskID)
+		if err != nil {
+			ctx.ServerError("GetTaskByID", err)
+			return
+		}
+		if task.RepoID != repo.ID || task.IsForkPullRequest {
+			ctx.PlainText(http.StatusForbidden, "User permission denied")
+			return
+		}
+	} else {
+		p, err := access_model.GetUserRepoPermission(ctx, repo, ctx.Doer)
+		if err != nil {
+			ctx.ServerError("GetUserRepoPermission", err)
+			return
+		}
+		if !p.CanAccess(perm.AccessModeWrite, unit.TypeCode) {
+			ctx.PlainText(http.StatusForbidden, "User permission denied")
+			return
+		}
+	}
+
+	context.CheckRepoScopedToken(ctx, repo, auth_model.Write)
+	if ctx.Written() {
+		return
+	}
+
+	if repo.IsArchived && ctx.Req.Method != http.MethodGet {
+		ctx.PlainText(http.StatusForbidden, "This repo is archived.")
+		return
+	}
+
+	ctx.Repo.Repository = repo
+}
+
+// GetTerraformState returns the latest version of a state
+func GetTerraformState(ctx *context.Context) {
+	v, obj, err := terraform_service.GetState(ctx, ctx.Repo.Repository, ctx.Params("name"))
+	if err != nil {
+		if errors.Is(err, repo_model.ErrTerraformStateNotExist) || errors.Is(err, repo_model.ErrTerraformStateVersionNotExist) {
+			// Terraform treats an empty response as a state which doesn't exist yet
+			ctx.Status(http.StatusNoContent)
+			return
+		}
+		ctx.ServerError("GetState", err)
+		return
+	}
+	defer obj.Close()
+
+	ctx.Resp.Header().Set("Content-Type", "application/json")
+	ctx.Resp.WriteHeader(http.StatusOK)
+	if _, err := io.CopyN(ctx.Resp, obj, v.Size); err != nil {
+		log.Error("Error whilst copying terraform state %s to response: %v", v.RelativePath(), err)
+	}
+}
+
+// UpdateTerraformState stores a new version of a state
+func UpdateTerraformState(ctx *context.Context) {
+	content, ok := readTerraformStateBody(ctx, setting.TerraformState.MaxSize)
+	if !ok {
+		return
+	}
+
+	if err := terraform_service.UpdateState(ctx, ctx.Doer, ctx.Repo.Repository, ctx.Params("name"), ctx.FormString("ID"), content); err != nil {
+		handleTerraformStateError(ctx, err, http.StatusLocked)
+		return
+	}
+	ctx.Status(http.StatusOK)
+}
+
+// DeleteTerraformState deletes a state with all its versions
+func DeleteTerraformState(ctx *context.Context) {
+	s, err := repo_model.GetTerraformState(ctx, ctx.Repo.Repository.ID, ctx.Params("name"))
+	if err != nil {
+		handleTerraformStateError(ctx, err, http.StatusLocked)
+		return
+	}
+
+	if err := terraform_service.DeleteState(ctx, s); err != nil {
+		handleTerraformStateError(ctx, err, http.StatusLocked)
+		return
+	}
+	ctx.Status(http.StatusOK)
+}
+
+// LockTerraformState locks a state
+func LockTerraformState(ctx *context.Context) {
+	lockInfo, ok := readTerraformStateBody(ctx, maxTerraformLockInfoSize)
+	if !ok {
+		return
+	}
+
+	if err := terraform_service.LockState(ctx, ctx.Doer, ctx.Repo.Repository, ctx.Params("name"), lockInfo); err != nil {
+		handleTerraformStateError(ctx, err, http.StatusLocked)
+		return
+	}
+	ctx.Status(http.StatusOK)
+}
+
+// UnlockTerraformState unlocks a state
+func UnlockTerraformState(ctx *context.Context) {
+	lockInfo, ok := readTerraformStateBody(ctx, maxTerraformLockInfoSize)
+	if !ok {
+		return
+	}
+
+	if err := terraform_service.UnlockState(ctx, ctx.Repo.Repository, ctx.Params("name"), lockInfo); err != nil {
+		handleTerraformStateError(ctx, err, http.StatusConflict)
+		return
+	}
+	ctx.Status(http.StatusOK)
+}
+
+// maxTerraformLockInfoSize is the maximum size of the lock information sent by Terraform, which only describes the lock
+const maxTerraformLockInfoSize = 64 * 1024
+
+// readTerraformStateBody reads the body of a request, which must not be larger than limit bytes unless limit is -1
+func readTerraformStateBody(ctx *context.Context, limit int64) ([]byte, bool) {
+	body := ctx.Req.Body
+	if limit > -1 {
+		body = http.MaxBytesReader(ctx.Resp, body, limit)
+	}
+	content, err := io.ReadAll(body)
+	if err != nil {
+		var errTooLarge *http.MaxBytesError
+		if errors.As(err, &errTooLarge) {
+			ctx.PlainText(http.StatusRequestEntityTooLarge, err.Error())
+			return nil, false
+		}
+		ctx.ServerError("ReadAll", err)
+		return nil, false
+	}
+	return content, true
+}
+
+// handleTerraformStateError writes the response for an error of the state backend.
+// A locked state is reported with the given status and the lock information of the current lock.
+func handleTerraformStateError(ctx *context.Context, err error, lockedStatus int) {
+	var errLocked terraform_service.ErrStateLocked
+	switch {
+	case errors.As(err, &errLocked):
+		ctx.Resp.Header().Set("Content-Type", "application/json")
+		ctx.Resp.WriteHeader(lockedStatus)
+		_, _ = ctx.Resp.Write([]byte(errLocked.LockInfo))
+	case errors.Is(err, repo_model.ErrTerraformStateNotExist):
+		ctx.PlainText(http.StatusNotFound, err.Error())
+	case errors.Is(err, terraform_service.ErrInvalidState), errors.Is(err, terraform_service.ErrInvalidLockInfo):
+		ctx.PlainText(http.StatusBadRequest, err.Error())
+	default:
+		ctx.ServerError("TerraformState", err)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"code.gitea.io/gitea/modules/log"
 	"code.gitea.io/gitea/modules/util"
 	"code.gitea.io/gitea/modules/web"
+	actions_service "code.gitea.io/gitea/services/actions"
 	"code.gitea.io/gitea/services/context"
 	"code.gitea.io/gitea/services/forms"
 )
@@ -74,6 +75,16 @@ func RunnerDetails(ctx *context.Context, page int, runnerID, ownerID, repoID int
 
 	ctx.Data["Runner"] = runner
 
+	// the runners of a repository can't belong to a group
+	if runner.RepoID == 0 {
+		groups, err := db.Find[actions_model.ActionRunnerGroup](ctx, actions_model.FindRunnerGroupsOptions{OwnerID: runner.OwnerID})
+		if err != nil {
+			ctx.ServerError("FindRunnerGroups", err)
+			return
+		}
+		ctx.Data["RunnerGroups"] = groups
+	}
+
 	opts := actions_model.FindTaskOptions{
 		ListOptions: db.ListOptions{
 			Page:     page,
@@ -122,6 +133,14 @@ func RunnerDetailsEditPost(ctx *context.Context, runnerID, ownerID, repoID int64
 		ctx.Redirect(redirectTo)
 		return
 	}
+	if form.GroupID != runner.GroupID {
+		if err := actions_service.SetRunnerGroup(ctx, runner, form.GroupID); err != nil {
+			log.Warn("RunnerDetailsEditPost.SetRunnerGroup failed: %v, url: %s", err, ctx.Req.URL)
+			ctx.Flash.Warning(ctx.Tr("actions.runners.update_runner_failed"))
+			ctx.Redirect(redirectTo)
+			return
+		}
+	}
 
 	log.Debug("RunnerDetailsEditPost success: %s", ctx.Req.URL)
 
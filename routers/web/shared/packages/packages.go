@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"code.gitea.io/gitea/models/db"
@@ -14,14 +15,18 @@ import (
 	repo_model "code.gitea.io/gitea/models/repo"
 	user_model "code.gitea.io/gitea/models/user"
 	"code.gitea.io/gitea/modules/base"
+	"code.gitea.io/gitea/modules/container"
 	"code.gitea.io/gitea/modules/log"
 	"code.gitea.io/gitea/modules/optional"
+	nix_module "code.gitea.io/gitea/modules/packages/nix"
 	"code.gitea.io/gitea/modules/util"
 	"code.gitea.io/gitea/modules/web"
 	"code.gitea.io/gitea/services/context"
 	"code.gitea.io/gitea/services/forms"
+	packages_service "code.gitea.io/gitea/services/packages"
 	cargo_service "code.gitea.io/gitea/services/packages/cargo"
 	container_service "code.gitea.io/gitea/services/packages/container"
+	nix_service "code.gitea.io/gitea/services/packages/nix"
 )
 
 func SetPackagesContext(ctx *context.Context, owner *user_model.User) {
@@ -33,11 +38,45 @@ func SetPackagesContext(ctx *context.Context, owner *user_model.User) {
 
 	ctx.Data["CleanupRules"] = pcrs
 
+	pps, err := packages_model.GetProxiesByOwner(ctx, owner.ID)
+	if err != nil {
+		ctx.ServerError("GetProxiesByOwner", err)
+		return
+	}
+
+	ctx.Data["Proxies"] = pps
+
+	pvrs, err := packages_model.GetVirtualRegistriesByOwner(ctx, owner.ID)
+	if err != nil {
+		ctx.ServerError("GetVirtualRegistriesByOwner", err)
+		return
+	}
+
+	ctx.Data["VirtualRegistries"] = pvrs
+
 	ctx.Data["CargoIndexExists"], err = repo_model.IsRepositoryModelExist(ctx, owner, cargo_service.IndexRepositoryName)
 	if err != nil {
 		ctx.ServerError("IsRepositoryModelExist", err)
 		return
 	}
+
+	keys, err := nix_service.GetTrustedKeys(ctx, owner.ID)
+	if err != nil {
+		ctx.ServerError("GetTrustedKeys", err)
+		return
+	}
+
+	nixTrustedKeys := make([]string, 0, len(keys))
+	for _, key := range keys {
+		nixTrustedKeys = append(nixTrustedKeys, key.String())
+	}
+	ctx.Data["NixTrustedKeys"] = strings.Join(nixTrustedKeys, "\n")
+
+	ctx.Data["ImmutableVersions"], err = packages_service.IsImmutableVersionsEnabled(ctx, owner.ID)
+	if err != nil {
+		ctx.ServerError("IsImmutableVersionsEnabled", err)
+		return
+	}
 }
 
 func SetRuleAddContext(ctx *context.Context) {
@@ -182,6 +221,13 @@ func SetRulePreviewContext(ctx *context.Context, owner *user_model.User) {
 				continue
 			}
 
+			if locked, err := packages_service.IsVersionRetentionLocked(ctx, pv); err != nil {
+				ctx.ServerError("IsVersionRetentionLocked", err)
+				return
+			} else if locked {
+				continue
+			}
+
 			toMatch := pv.LowerVersion
 			if pcr.MatchFullName {
 				toMatch = p.LowerName + "/" + pv.LowerVersion
@@ -235,6 +281,301 @@ func getCleanupRuleByContext(ctx *context.Context, owner *user_model.User) *pack
 	return nil
 }
 
+func SetProxyAddContext(ctx *context.Context) {
+	setProxyEditContext(ctx, nil)
+}
+
+func SetProxyEditContext(ctx *context.Context, owner *user_model.User) {
+	pp := getProxyByContext(ctx, owner)
+	if pp == nil {
+		return
+	}
+
+	setProxyEditContext(ctx, pp)
+}
+
+func setProxyEditContext(ctx *context.Context, pp *packages_model.PackageProxy) {
+	ctx.Data["IsEditProxy"] = pp != nil
+
+	if pp == nil {
+		pp = &packages_model.PackageProxy{}
+	}
+	ctx.Data["Proxy"] = pp
+	ctx.Data["AvailableTypes"] = packages_model.ProxyTypeList
+}
+
+func PerformProxyAddPost(ctx *context.Context, owner *user_model.User, redirectURL string, template base.TplName) {
+	performProxyEditPost(ctx, owner, nil, redirectURL, template)
+}
+
+func PerformProxyEditPost(ctx *context.Context, owner *user_model.User, redirectURL string, template base.TplName) {
+	pp := getProxyByContext(ctx, owner)
+	if pp == nil {
+		return
+	}
+
+	form := web.GetForm(ctx).(*forms.PackageProxyForm)
+
+	if form.Action == "remove" {
+		if err := packages_model.DeleteProxyByID(ctx, pp.ID); err != nil {
+			ctx.ServerError("DeleteProxyByID", err)
+			return
+		}
+
+		ctx.Flash.Success(ctx.Tr("packages.owner.settings.proxies.success.delete"))
+		ctx.Redirect(redirectURL)
+	} else {
+		performProxyEditPost(ctx, owner, pp, redirectURL, template)
+	}
+}
+
+func performProxyEditPost(ctx *context.Context, owner *user_model.User, pp *packages_model.PackageProxy, redirectURL string, template base.TplName) {
+	isEditProxy := pp != nil
+
+	if pp == nil {
+		pp = &packages_model.PackageProxy{}
+	}
+
+	form := web.GetForm(ctx).(*forms.PackageProxyForm)
+
+	pp.Enabled = form.Enabled
+	pp.OwnerID = owner.ID
+	pp.URL = form.URL
+	pp.Username = form.Username
+	// the stored password is kept if it isn't entered again
+	if form.Password != "" || form.Username == "" {
+		if err := pp.SetPassword(form.Password); err != nil {
+			ctx.ServerError("SetPassword", err)
+			return
+		}
+	}
+
+	ctx.Data["IsEditProxy"] = isEditProxy
+	ctx.Data["Proxy"] = pp
+	ctx.Data["AvailableTypes"] = packages_model.ProxyTypeList
+
+	if ctx.HasError() {
+		ctx.HTML(http.StatusOK, template)
+		return
+	}
+
+	if isEditProxy {
+		if err := packages_model.UpdateProxy(ctx, pp); err != nil {
+			ctx.ServerError("UpdateProxy", err)
+			return
+		}
+	} else {
+		pp.Type = packages_model.Type(form.Type)
+
+		if has, err := packages_model.HasOwnerProxyForPackageType(ctx, owner.ID, pp.Type); err != nil {
+			ctx.ServerError("HasOwnerProxyForPackageType", err)
+			return
+		} else if has {
+			ctx.Data["Err_Type"] = true
+			ctx.HTML(http.StatusOK, template)
+			return
+		}
+
+		var err error
+		if pp, err = packages_model.InsertProxy(ctx, pp); err != nil {
+			ctx.ServerError("InsertProxy", err)
+			return
+		}
+	}
+
+	ctx.Flash.Success(ctx.Tr("packages.owner.settings.proxies.success.update"))
+	ctx.Redirect(fmt.Sprintf("%s/proxies/%d", redirectURL, pp.ID))
+}
+
+func getProxyByContext(ctx *context.Context, owner *user_model.User) *packages_model.PackageProxy {
+	id := ctx.FormInt64("id")
+	if id == 0 {
+		id = ctx.ParamsInt64("id")
+	}
+
+	pp, err := packages_model.GetProxyByID(ctx, id)
+	if err != nil {
+		if err == packages_model.ErrPackageProxyNotExist {
+			ctx.NotFound("", err)
+		} else {
+			ctx.ServerError("GetProxyByID", err)
+		}
+		return nil
+	}
+
+	if pp != nil && pp.OwnerID == owner.ID {
+		return pp
+	}
+
+	ctx.NotFound("", fmt.Errorf("PackageProxy[%v] not associated to owner %v", id, owner))
+
+	return nil
+}
+
+func SetVirtualRegistryAddContext(ctx *context.Context) {
+	setVirtualRegistryEditContext(ctx, nil)
+}
+
+func SetVirtualRegistryEditContext(ctx *context.Context, owner *user_model.User) {
+	pvr := getVirtualRegistryByContext(ctx, owner)
+	if pvr == nil {
+		return
+	}
+
+	setVirtualRegistryEditContext(ctx, pvr)
+}
+
+func setVirtualRegistryEditContext(ctx *context.Context, pvr *packages_model.PackageVirtualRegistry) {
+	ctx.Data["IsEditVirtualRegistry"] = pvr != nil
+
+	if pvr == nil {
+		pvr = &packages_model.PackageVirtualRegistry{}
+	}
+	ctx.Data["VirtualRegistry"] = pvr
+	ctx.Data["AvailableTypes"] = packages_model.VirtualRegistryTypeList
+
+	members, err := user_model.GetUsersByIDs(ctx, pvr.MemberIDs)
+	if err != nil {
+		ctx.ServerError("GetUsersByIDs", err)
+		return
+	}
+	names := make(map[int64]string, len(members))
+	for _, member := range members {
+		names[member.ID] = member.Name
+	}
+	memberNames := make([]string, 0, len(pvr.MemberIDs))
+	for _, id := range pvr.MemberIDs {
+		if name, ok := names[id]; ok {
+			memberNames = append(memberNames, name)
+		}
+	}
+	ctx.Data["VirtualRegistryMembers"] = strings.Join(memberNames, "\n")
+}
+
+func PerformVirtualRegistryAddPost(ctx *context.Context, owner *user_model.User, redirectURL string, template base.TplName) {
+	performVirtualRegistryEditPost(ctx, owner, nil, redirectURL, template)
+}
+
+func PerformVirtualRegistryEditPost(ctx *context.Context, owner *user_model.User, redirectURL string, template base.TplName) {
+	pvr := getVirtualRegistryByContext(ctx, owner)
+	if pvr == nil {
+		return
+	}
+
+	form := web.GetForm(ctx).(*forms.PackageVirtualRegistryForm)
+
+	if form.Action == "remove" {
+		if err := packages_model.DeleteVirtualRegistryByID(ctx, pvr.ID); err != nil {
+			ctx.ServerError("DeleteVirtualRegistryByID", err)
+			return
+		}
+
+		ctx.Flash.Success(ctx.Tr("packages.owner.settings.virtual.success.delete"))
+		ctx.Redirect(redirectURL)
+	} else {
+		performVirtualRegistryEditPost(ctx, owner, pvr, redirectURL, template)
+	}
+}
+
+func performVirtualRegistryEditPost(ctx *context.Context, owner *user_model.User, pvr *packages_model.PackageVirtualRegistry, redirectURL string, template base.TplName) {
+	isEditVirtualRegistry := pvr != nil
+
+	if pvr == nil {
+		pvr = &packages_model.PackageVirtualRegistry{}
+	}
+
+	form := web.GetForm(ctx).(*forms.PackageVirtualRegistryForm)
+
+	pvr.Enabled = form.Enabled
+	pvr.OwnerID = owner.ID
+	pvr.UseProxies = form.UseProxies
+
+	ctx.Data["IsEditVirtualRegistry"] = isEditVirtualRegistry
+	ctx.Data["VirtualRegistry"] = pvr
+	ctx.Data["VirtualRegistryMembers"] = form.Members
+	ctx.Data["AvailableTypes"] = packages_model.VirtualRegistryTypeList
+
+	if ctx.HasError() {
+		ctx.HTML(http.StatusOK, template)
+		return
+	}
+
+	// the members are listed one per line by order of priority
+	pvr.MemberIDs = make([]int64, 0, 5)
+	seen := make(container.Set[int64])
+	for _, name := range strings.FieldsFunc(form.Members, func(r rune) bool { return r == '\n' || r == ',' }) {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		member, err := user_model.GetUserByName(ctx, name)
+		if err != nil {
+			if user_model.IsErrUserNotExist(err) {
+				ctx.Data["Err_Members"] = true
+				ctx.RenderWithErr(ctx.Tr("packages.owner.settings.virtual.members.not_exist", name), template, form)
+			} else {
+				ctx.ServerError("GetUserByName", err)
+			}
+			return
+		}
+		if member.ID != owner.ID && seen.Add(member.ID) {
+			pvr.MemberIDs = append(pvr.MemberIDs, member.ID)
+		}
+	}
+
+	if isEditVirtualRegistry {
+		if err := packages_model.UpdateVirtualRegistry(ctx, pvr); err != nil {
+			ctx.ServerError("UpdateVirtualRegistry", err)
+			return
+		}
+	} else {
+		pvr.Type = packages_model.Type(form.Type)
+
+		if has, err := packages_model.HasOwnerVirtualRegistryForPackageType(ctx, owner.ID, pvr.Type); err != nil {
+			ctx.ServerError("HasOwnerVirtualRegistryForPackageType", err)
+			return
+		} else if has {
+			ctx.Data["Err_Type"] = true
+			ctx.HTML(http.StatusOK, template)
+			return
+		}
+
+		var err error
+		if pvr, err = packages_model.InsertVirtualRegistry(ctx, pvr); err != nil {
+			ctx.ServerError("InsertVirtualRegistry", err)
+			return
+		}
+	}
+
+	ctx.Flash.Success(ctx.Tr("packages.owner.settings.virtual.success.update"))
+	ctx.Redirect(fmt.Sprintf("%s/virtual/%d", redirectURL, pvr.ID))
+}
+
+func getVirtualRegistryByContext(ctx *context.Context, owner *user_model.User) *packages_model.PackageVirtualRegistry {
+	id := ctx.FormInt64("id")
+	if id == 0 {
+		id = ctx.ParamsInt64("id")
+	}
+
+	pvr, err := packages_model.GetVirtualRegistryByID(ctx, id)
+	if err != nil {
+		if err == packages_model.ErrPackageVirtualRegistryNotExist {
+			ctx.NotFound("", err)
+		} else {
+			ctx.ServerError("GetVirtualRegistryByID", err)
+		}
+		return nil
+	}
+
+	if pvr != nil && pvr.OwnerID == owner.ID {
+		return pvr
+	}
+
+	ctx.NotFound("", fmt.Errorf("PackageVirtualRegistry[%v] not associated to owner %v", id, owner))
+
+	return nil
+}
+
 func InitializeCargoIndex(ctx *context.Context, owner *user_model.User) {
 	err := cargo_service.InitializeIndexRepository(ctx, owner, owner)
 	if err != nil {
@@ -258,3 +599,34 @@ func RebuildCargoIndex(ctx *context.Context, owner *user_model.User) {
 		ctx.Flash.Success(ctx.Tr("packages.owner.settings.cargo.rebuild.success"))
 	}
 }
+
+func UpdateNixTrustedKeys(ctx *context.Context, owner *user_model.User) {
+	keys := make([]*nix_module.PublicKey, 0, 2)
+	for _, line := range strings.Split(ctx.FormString("trusted_keys"), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		key, err := nix_module.ParsePublicKey(line)
+		if err != nil {
+			ctx.Flash.Error(ctx.Tr("packages.owner.settings.nix.trusted_keys.invalid", strings.TrimSpace(line)))
+			return
+		}
+		keys = append(keys, key)
+	}
+
+	if err := nix_service.SetTrustedKeys(ctx, owner.ID, keys); err != nil {
+		log.Error("SetTrustedKeys failed: %v", err)
+		ctx.Flash.Error(ctx.Tr("packages.owner.settings.nix.trusted_keys.error", err))
+	} else {
+		ctx.Flash.Success(ctx.Tr("packages.owner.settings.nix.trusted_keys.success"))
+	}
+}
+
+func UpdateImmutableVersions(ctx *context.Context, owner *user_model.User) {
+	if err := packages_service.SetImmutableVersionsEnabled(ctx, owner.ID, ctx.FormBool("immutable_versions")); err != nil {
+		log.Error("SetImmutableVersionsEnabled failed: %v", err)
+		ctx.Flash.Error(ctx.Tr("packages.owner.settings.protection.error", err))
+	} else {
+		ctx.Flash.Success(ctx.Tr("packages.owner.settings.protection.success"))
+	}
+}
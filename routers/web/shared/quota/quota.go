@@ -0,0 +1,78 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package quota
+
+import (
+	"strings"
+
+	quota_model "code.gitea.io/gitea/models/quota"
+	"code.gitea.io/gitea/services/context"
+)
+
+// UsedCategory is the storage used by a subject
+type UsedCategory struct {
+	Subject   string
+	LocaleKey string
+	Size      int64
+}
+
+// RuleOverview is a rule with the storage used by its subjects
+type RuleOverview struct {
+	Rule     *quota_model.Rule
+	Used     int64
+	Exceeded bool
+}
+
+// GroupOverview is a group with the overviews of its rules
+type GroupOverview struct {
+	Group *quota_model.Group
+	Rules []*RuleOverview
+}
+
+// PrepareStorageOverview sets the storage used by a user or an organization and its quota groups in the context data
+func PrepareStorageOverview(ctx *context.Context, userID int64) {
+	used, err := quota_model.GetUsedForUser(ctx, userID)
+	if err != nil {
+		ctx.ServerError("GetUsedForUser", err)
+		return
+	}
+	groups, err := quota_model.GetGroupsForUser(ctx, userID)
+	if err != nil {
+		ctx.ServerError("GetGroupsForUser", err)
+		return
+	}
+
+	categories := make([]*UsedCategory, 0, 6)
+	for _, subject := range []quota_model.LimitSubject{
+		quota_model.LimitSubjectSizeReposGit,
+		quota_model.LimitSubjectSizeReposLFS,
+		quota_model.LimitSubjectSizeAssetsAttachments,
+		quota_model.LimitSubjectSizeAssetsArtifacts,
+		quota_model.LimitSubjectSizeAssetsPackages,
+		quota_model.LimitSubjectSizeAll,
+	} {
+		categories = append(categories, &UsedCategory{
+			Subject:   subject.String(),
+			LocaleKey: "settings.quota." + strings.ReplaceAll(subject.String(), ":", "."),
+			Size:      used.Get(subject),
+		})
+	}
+
+	overviews := make([]*GroupOverview, 0, len(groups))
+	for _, g := range groups {
+		overview := &GroupOverview{Group: g, Rules: make([]*RuleOverview, 0, len(g.Rules))}
+		for _, r := range g.Rules {
+			sum := r.Sum(used)
+			overview.Rules = append(overview.Rules, &RuleOverview{
+				Rule:     r,
+				Used:     sum,
+				Exceeded: r.Limit >= 0 && sum >= r.Limit,
+			})
+		}
+		overviews = append(overviews, overview)
+	}
+
+	ctx.Data["QuotaUsedCategories"] = categories
+	ctx.Data["QuotaGroups"] = overviews
+}
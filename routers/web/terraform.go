@@ -0,0 +1,20 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package web
+
+import (
+	"net/http"
+
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/services/context"
+)
+
+// TerraformServiceDiscovery returns the locations of the Terraform module and provider registries
+// https://developer.hashicorp.com/terraform/internals/remote-service-discovery
+func TerraformServiceDiscovery(ctx *context.Context) {
+	ctx.JSON(http.StatusOK, map[string]string{
+		"modules.v1":   setting.AppURL + "api/packages/-/terraform/modules/v1/",
+		"providers.v1": setting.AppURL + "api/packages/-/terraform/providers/v1/",
+	})
+}
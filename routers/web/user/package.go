@@ -4,7 +4,9 @@
 package user
 
 import (
+	"errors"
 	"net/http"
+	"strings"
 
 	"code.gitea.io/gitea/models/db"
 	org_model "code.gitea.io/gitea/models/organization"
@@ -18,6 +20,8 @@ import (
 	"code.gitea.io/gitea/modules/log"
 	"code.gitea.io/gitea/modules/optional"
 	alpine_module "code.gitea.io/gitea/modules/packages/alpine"
+	arch_module "code.gitea.io/gitea/modules/packages/arch"
+	container_module "code.gitea.io/gitea/modules/packages/container"
 	debian_module "code.gitea.io/gitea/modules/packages/debian"
 	rpm_module "code.gitea.io/gitea/modules/packages/rpm"
 	"code.gitea.io/gitea/modules/setting"
@@ -28,6 +32,8 @@ import (
 	"code.gitea.io/gitea/services/context"
 	"code.gitea.io/gitea/services/forms"
 	packages_service "code.gitea.io/gitea/services/packages"
+	cargo_service "code.gitea.io/gitea/services/packages/cargo"
+	container_service "code.gitea.io/gitea/services/packages/container"
 )
 
 const (
@@ -179,6 +185,15 @@ func ViewPackageVersion(ctx *context.Context) {
 	switch pd.Package.Type {
 	case packages_model.TypeContainer:
 		ctx.Data["RegistryHost"] = setting.Packages.RegistryHost
+
+		if pfd := container_service.GetManifestFile(pd); pfd != nil {
+			referrers, err := container_service.GetReferrers(ctx, pd.Owner.ID, pd.Package.LowerName, pfd.Properties.GetByName(container_module.PropertyDigest), "")
+			if err != nil {
+				ctx.ServerError("GetReferrers", err)
+				return
+			}
+			ctx.Data["Referrers"] = referrers
+		}
 	case packages_model.TypeAlpine:
 		branches := make(container.Set[string])
 		repositories := make(container.Set[string])
@@ -200,6 +215,23 @@ func ViewPackageVersion(ctx *context.Context) {
 		ctx.Data["Branches"] = util.Sorted(branches.Values())
 		ctx.Data["Repositories"] = util.Sorted(repositories.Values())
 		ctx.Data["Architectures"] = util.Sorted(architectures.Values())
+	case packages_model.TypeArch:
+		distributions := make(container.Set[string])
+		architectures := make(container.Set[string])
+
+		for _, f := range pd.Files {
+			for _, pp := range f.Properties {
+				switch pp.Name {
+				case arch_module.PropertyDistribution:
+					distributions.Add(pp.Value)
+				case arch_module.PropertyArchitecture:
+					architectures.Add(pp.Value)
+				}
+			}
+		}
+
+		ctx.Data["Distributions"] = util.Sorted(distributions.Values())
+		ctx.Data["Architectures"] = util.Sorted(architectures.Values())
 	case packages_model.TypeDebian:
 		distributions := make(container.Set[string])
 		components := make(container.Set[string])
@@ -267,7 +299,22 @@ func ViewPackageVersion(ctx *context.Context) {
 	ctx.Data["LatestVersions"] = pvs
 	ctx.Data["TotalVersionCount"] = total
 
-	ctx.Data["CanWritePackages"] = ctx.Package.AccessMode >= perm.AccessModeWrite || ctx.IsUserSiteAdmin()
+	canWritePackages := ctx.Package.AccessMode >= perm.AccessModeWrite || ctx.IsUserSiteAdmin()
+	ctx.Data["CanWritePackages"] = canWritePackages
+
+	downloadStats, err := packages_service.GetDownloadStats(ctx, pd.Version, 30, canWritePackages)
+	if err != nil {
+		ctx.ServerError("GetDownloadStats", err)
+		return
+	}
+	var recentDownloads, maxDailyDownloads int64
+	for _, d := range downloadStats.Days {
+		recentDownloads += d.Downloads
+		maxDailyDownloads = max(maxDailyDownloads, d.Downloads)
+	}
+	ctx.Data["DownloadStats"] = downloadStats
+	ctx.Data["RecentDownloads"] = recentDownloads
+	ctx.Data["MaxDailyDownloads"] = maxDailyDownloads
 
 	hasRepositoryAccess := false
 	if pd.Repository != nil {
@@ -408,6 +455,7 @@ func PackageSettings(ctx *context.Context) {
 	})
 	ctx.Data["Repos"] = repos
 	ctx.Data["CanWritePackages"] = ctx.Package.AccessMode >= perm.AccessModeWrite || ctx.IsUserSiteAdmin()
+	ctx.Data["CanUnlockPackage"] = canUnlockPackage(ctx)
 
 	err := shared_user.LoadHeaderCount(ctx)
 	if err != nil {
@@ -418,6 +466,12 @@ func PackageSettings(ctx *context.Context) {
 	ctx.HTML(http.StatusOK, tplPackagesSettings)
 }
 
+// canUnlockPackage checks if the doer may remove the retention lock of the package version,
+// the writers of the packages can't unlock it as they could delete it afterwards
+func canUnlockPackage(ctx *context.Context) bool {
+	return ctx.Package.AccessMode >= perm.AccessModeAdmin || ctx.IsUserSiteAdmin()
+}
+
 // PackageSettingsPost updates the package settings
 func PackageSettingsPost(ctx *context.Context) {
 	pd := ctx.Package.Descriptor
@@ -455,12 +509,55 @@ func PackageSettingsPost(ctx *context.Context) {
 			ctx.Flash.Error(ctx.Tr("packages.settings.link.error"))
 		}
 
+		ctx.Redirect(ctx.Link)
+		return
+	case "lock", "unlock":
+		if form.Action == "unlock" && !canUnlockPackage(ctx) {
+			ctx.NotFound("", nil)
+			return
+		}
+		if err := packages_service.SetVersionRetentionLock(ctx, pd.Version, form.Action == "lock"); err != nil {
+			log.Error("Error updating retention lock: %v", err)
+			ctx.Flash.Error(ctx.Tr("packages.settings.lock.error"))
+		} else {
+			ctx.Flash.Success(ctx.Tr("packages.settings.lock.success"))
+		}
+
+		ctx.Redirect(ctx.Link)
+		return
+	case "deprecate", "undeprecate":
+		message := ""
+		if form.Action == "deprecate" {
+			message = strings.TrimSpace(form.DeprecationMessage)
+			if message == "" {
+				ctx.Flash.Error(ctx.Tr("packages.settings.deprecate.message_required"))
+				ctx.Redirect(ctx.Link)
+				return
+			}
+		}
+
+		err := packages_service.SetVersionDeprecation(ctx, pd.Version, message)
+		if err == nil && pd.Package.Type == packages_model.TypeCargo {
+			err = cargo_service.UpdatePackageIndexIfExists(ctx, ctx.Doer, pd.Owner, pd.Package.ID)
+		}
+		if err != nil {
+			log.Error("Error updating deprecation: %v", err)
+			ctx.Flash.Error(ctx.Tr("packages.settings.deprecate.error"))
+		} else {
+			ctx.Flash.Success(ctx.Tr("packages.settings.deprecate.success"))
+		}
+
 		ctx.Redirect(ctx.Link)
 		return
 	case "delete":
 		err := packages_service.RemovePackageVersion(ctx, ctx.Doer, ctx.Package.Descriptor.Version)
 		if err != nil {
 			log.Error("Error deleting package: %v", err)
+			if errors.Is(err, util.ErrPermissionDenied) {
+				ctx.Flash.Error(ctx.Tr("packages.settings.delete.protected"))
+				ctx.Redirect(ctx.Link)
+				return
+			}
 			ctx.Flash.Error(ctx.Tr("packages.settings.delete.error"))
 		} else {
 			ctx.Flash.Success(ctx.Tr("packages.settings.delete.success"))
@@ -489,7 +586,7 @@ func DownloadPackageFile(ctx *context.Context) {
 		return
 	}
 
-	s, u, _, err := packages_service.GetPackageFileStream(ctx, pf)
+	s, u, _, err := packages_service.GetPackageFileStream(ctx, ctx.Doer, pf)
 	if err != nil {
 		ctx.ServerError("GetPackageFileStream", err)
 		return
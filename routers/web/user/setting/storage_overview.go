@@ -0,0 +1,27 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package setting
+
+import (
+	"net/http"
+
+	"code.gitea.io/gitea/modules/base"
+	shared_quota "code.gitea.io/gitea/routers/web/shared/quota"
+	"code.gitea.io/gitea/services/context"
+)
+
+const tplSettingsStorageOverview base.TplName = "user/settings/storage_overview"
+
+// StorageOverview renders the storage used by the user and the quotas which apply to it
+func StorageOverview(ctx *context.Context) {
+	ctx.Data["Title"] = ctx.Tr("settings.storage_overview")
+	ctx.Data["PageIsStorageOverview"] = true
+
+	shared_quota.PrepareStorageOverview(ctx, ctx.Doer.ID)
+	if ctx.Written() {
+		return
+	}
+
+	ctx.HTML(http.StatusOK, tplSettingsStorageOverview)
+}
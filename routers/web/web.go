@@ -346,6 +346,13 @@ func registerRoutes(m *web.Route) {
 		}
 	}
 
+	terraformStateEnabled := func(ctx *context.Context) {
+		if !setting.TerraformState.Enabled {
+			ctx.Error(http.StatusNotFound)
+			return
+		}
+	}
+
 	federationEnabled := func(ctx *context.Context) {
 		if !setting.Federation.Enabled {
 			ctx.Error(http.StatusNotFound)
@@ -367,6 +374,13 @@ func registerRoutes(m *web.Route) {
 		}
 	}
 
+	quotaEnabled := func(ctx *context.Context) {
+		if !setting.Quota.Enabled {
+			ctx.NotFound("", nil)
+			return
+		}
+	}
+
 	packagesEnabled := func(ctx *context.Context) {
 		if !setting.Packages.Enabled {
 			ctx.Error(http.StatusForbidden)
@@ -430,6 +444,16 @@ func registerRoutes(m *web.Route) {
 		})
 	}
 
+	addSettingsRunnerGroupsRoutes := func() {
+		m.Group("/runner-groups", func() {
+			m.Get("", repo_setting.RunnerGroups)
+			m.Post("/new", web.Bind(forms.NewRunnerGroupForm{}), repo_setting.RunnerGroupCreate)
+			m.Combo("/{group_id}").Get(repo_setting.RunnerGroupEdit).
+				Post(web.Bind(forms.EditRunnerGroupForm{}), repo_setting.RunnerGroupEditPost)
+			m.Post("/{group_id}/delete", repo_setting.RunnerGroupDelete)
+		})
+	}
+
 	// FIXME: not all routes need go through same middleware.
 	// Especially some AJAX requests, we can reduce middleware number to improve performance.
 
@@ -441,6 +465,7 @@ func registerRoutes(m *web.Route) {
 			m.Get("/nodeinfo", NodeInfoLinks)
 			m.Get("/webfinger", WebfingerQuery)
 		}, federationEnabled)
+		m.Get("/terraform.json", packagesEnabled, TerraformServiceDiscovery)
 		m.Get("/change-password", func(ctx *context.Context) {
 			ctx.Redirect(setting.AppSubURL + "/user/settings/account")
 		})
@@ -591,10 +616,32 @@ func registerRoutes(m *web.Route) {
 					m.Get("/preview", user_setting.PackagesRulePreview)
 				})
 			})
+			m.Group("/proxies", func() {
+				m.Group("/add", func() {
+					m.Get("", user_setting.PackagesProxyAdd)
+					m.Post("", web.Bind(forms.PackageProxyForm{}), user_setting.PackagesProxyAddPost)
+				})
+				m.Group("/{id}", func() {
+					m.Get("", user_setting.PackagesProxyEdit)
+					m.Post("", web.Bind(forms.PackageProxyForm{}), user_setting.PackagesProxyEditPost)
+				})
+			})
+			m.Group("/virtual", func() {
+				m.Group("/add", func() {
+					m.Get("", user_setting.PackagesVirtualRegistryAdd)
+					m.Post("", web.Bind(forms.PackageVirtualRegistryForm{}), user_setting.PackagesVirtualRegistryAddPost)
+				})
+				m.Group("/{id}", func() {
+					m.Get("", user_setting.PackagesVirtualRegistryEdit)
+					m.Post("", web.Bind(forms.PackageVirtualRegistryForm{}), user_setting.PackagesVirtualRegistryEditPost)
+				})
+			})
 			m.Group("/cargo", func() {
 				m.Post("/initialize", user_setting.InitializeCargoIndex)
 				m.Post("/rebuild", user_setting.RebuildCargoIndex)
 			})
+			m.Post("/immutable_versions", user_setting.UpdateImmutableVersions)
+			m.Post("/nix/trusted_keys", user_setting.UpdateNixTrustedKeys)
 			m.Post("/chef/regenerate_keypair", user_setting.RegenerateChefKeyPair)
 		}, packagesEnabled)
 
@@ -614,6 +661,7 @@ func registerRoutes(m *web.Route) {
 			m.Post("/delete", user_setting.DeleteWebhook)
 			m.Get("/{type}/new", repo_setting.WebhookNew)
 			m.Post("/{type}/new", repo_setting.WebhookCreate)
+			m.Post("/custom/preview", web.Bind(forms.CustomWebhookPreviewForm{}), repo_setting.WebhookCustomPreview)
 			m.Group("/{id}", func() {
 				m.Get("", repo_setting.WebhookEdit)
 				m.Post("", repo_setting.WebhookUpdate)
@@ -625,7 +673,9 @@ func registerRoutes(m *web.Route) {
 			m.Get("", user_setting.BlockedUsers)
 			m.Post("/unblock", user_setting.UnblockUser)
 		})
-	}, reqSignIn, ctxDataSet("PageIsUserSettings", true, "AllThemes", setting.UI.Themes, "EnablePackages", setting.Packages.Enabled))
+
+		m.Get("/storage_overview", quotaEnabled, user_setting.StorageOverview)
+	}, reqSignIn, ctxDataSet("PageIsUserSettings", true, "AllThemes", setting.UI.Themes, "EnablePackages", setting.Packages.Enabled, "EnableQuota", setting.Quota.Enabled))
 
 	m.Group("/user", func() {
 		m.Get("/activate", auth.Activate)
@@ -717,6 +767,7 @@ func registerRoutes(m *web.Route) {
 		m.Group("/hooks", func() {
 			m.Get("", admin.DefaultOrSystemWebhooks)
 			m.Post("/delete", admin.DeleteDefaultOrSystemWebhook)
+			m.Post("/custom/preview", web.Bind(forms.CustomWebhookPreviewForm{}), repo_setting.WebhookCustomPreview)
 			m.Group("/{id}", func() {
 				m.Get("", repo_setting.WebhookEdit)
 				m.Post("", repo_setting.WebhookUpdate)
@@ -727,6 +778,7 @@ func registerRoutes(m *web.Route) {
 		m.Group("/{configType:default-hooks|system-hooks}", func() {
 			m.Get("/{type}/new", repo_setting.WebhookNew)
 			m.Post("/{type}/new", repo_setting.WebhookCreate)
+			m.Post("/custom/preview", web.Bind(forms.CustomWebhookPreviewForm{}), repo_setting.WebhookCustomPreview)
 		})
 
 		m.Group("/auths", func() {
@@ -761,6 +813,7 @@ func registerRoutes(m *web.Route) {
 		m.Group("/actions", func() {
 			m.Get("", admin.RedirectToDefaultSetting)
 			addSettingsRunnersRoutes()
+			addSettingsRunnerGroupsRoutes()
 			addSettingsVariablesRoutes()
 		})
 	}, adminReq, ctxDataSet("EnableOAuth2", setting.OAuth2.Enabled, "EnablePackages", setting.Packages.Enabled))
@@ -886,6 +939,7 @@ func registerRoutes(m *web.Route) {
 					m.Post("/delete", org.DeleteWebhook)
 					m.Get("/{type}/new", repo_setting.WebhookNew)
 					m.Post("/{type}/new", repo_setting.WebhookCreate)
+					m.Post("/custom/preview", web.Bind(forms.CustomWebhookPreviewForm{}), repo_setting.WebhookCustomPreview)
 					m.Group("/{id}", func() {
 						m.Get("", repo_setting.WebhookEdit)
 						m.Post("", repo_setting.WebhookUpdate)
@@ -904,6 +958,7 @@ func registerRoutes(m *web.Route) {
 				m.Group("/actions", func() {
 					m.Get("", org_setting.RedirectToDefaultSetting)
 					addSettingsRunnersRoutes()
+					addSettingsRunnerGroupsRoutes()
 					addSettingsSecretsRoutes()
 					addSettingsVariablesRoutes()
 				}, actions.MustEnableActions)
@@ -916,6 +971,8 @@ func registerRoutes(m *web.Route) {
 					m.Post("/unblock", org_setting.BlockedUsersUnblock)
 				})
 
+				m.Get("/storage_overview", quotaEnabled, org_setting.StorageOverview)
+
 				m.Group("/packages", func() {
 					m.Get("", org.Packages)
 					m.Group("/rules", func() {
@@ -929,12 +986,34 @@ func registerRoutes(m *web.Route) {
 							m.Get("/preview", org.PackagesRulePreview)
 						})
 					})
+					m.Group("/proxies", func() {
+						m.Group("/add", func() {
+							m.Get("", org.PackagesProxyAdd)
+							m.Post("", web.Bind(forms.PackageProxyForm{}), org.PackagesProxyAddPost)
+						})
+						m.Group("/{id}", func() {
+							m.Get("", org.PackagesProxyEdit)
+							m.Post("", web.Bind(forms.PackageProxyForm{}), org.PackagesProxyEditPost)
+						})
+					})
+					m.Group("/virtual", func() {
+						m.Group("/add", func() {
+							m.Get("", org.PackagesVirtualRegistryAdd)
+							m.Post("", web.Bind(forms.PackageVirtualRegistryForm{}), org.PackagesVirtualRegistryAddPost)
+						})
+						m.Group("/{id}", func() {
+							m.Get("", org.PackagesVirtualRegistryEdit)
+							m.Post("", web.Bind(forms.PackageVirtualRegistryForm{}), org.PackagesVirtualRegistryEditPost)
+						})
+					})
 					m.Group("/cargo", func() {
 						m.Post("/initialize", org.InitializeCargoIndex)
 						m.Post("/rebuild", org.RebuildCargoIndex)
 					})
+					m.Post("/immutable_versions", org.UpdateImmutableVersions)
+					m.Post("/nix/trusted_keys", org.UpdateNixTrustedKeys)
 				}, packagesEnabled)
-			}, ctxDataSet("EnableOAuth2", setting.OAuth2.Enabled, "EnablePackages", setting.Packages.Enabled, "PageIsOrgSettings", true))
+			}, ctxDataSet("EnableOAuth2", setting.OAuth2.Enabled, "EnablePackages", setting.Packages.Enabled, "EnableQuota", setting.Quota.Enabled, "PageIsOrgSettings", true))
 		}, context.OrgAssignment(true, true))
 	}, reqSignIn)
 	// ***** END: Organization *****
@@ -1061,6 +1140,7 @@ func registerRoutes(m *web.Route) {
 				m.Post("/delete", repo_setting.WebhookDelete)
 				m.Get("/{type}/new", repo_setting.WebhookNew)
 				m.Post("/{type}/new", repo_setting.WebhookCreate)
+				m.Post("/custom/preview", web.Bind(forms.CustomWebhookPreviewForm{}), repo_setting.WebhookCustomPreview)
 				m.Group("/{id}", func() {
 					m.Get("", repo_setting.WebhookEdit)
 					m.Post("", repo_setting.WebhookUpdate)
@@ -1088,18 +1168,48 @@ func registerRoutes(m *web.Route) {
 					m.Post("/{lid}/unlock", repo_setting.LFSUnlock)
 				})
 			})
+			m.Group("/terraform", func() {
+				m.Get("", repo_setting.TerraformStates)
+				m.Group("/{state_id}", func() {
+					m.Get("", repo_setting.TerraformState)
+					m.Post("/unlock", repo_setting.TerraformStateUnlock)
+					m.Post("/delete", repo_setting.TerraformStateDelete)
+					m.Get("/versions/{version_id}", repo_setting.TerraformStateVersionDownload)
+					m.Post("/versions/{version_id}/rollback", repo_setting.TerraformStateRollback)
+				})
+			}, terraformStateEnabled)
 			m.Group("/actions", func() {
 				m.Get("", repo_setting.RedirectToDefaultSetting)
 				addSettingsRunnersRoutes()
 				addSettingsSecretsRoutes()
 				addSettingsVariablesRoutes()
+				m.Group("/environments", func() {
+					m.Get("", repo_setting.Environments)
+					m.Post("/new", web.Bind(forms.NewEnvironmentForm{}), repo_setting.EnvironmentCreate)
+					m.Group("/{environment_id}", func() {
+						m.Combo("").Get(repo_setting.EnvironmentEdit).
+							Post(web.Bind(forms.EditEnvironmentForm{}), repo_setting.EnvironmentEditPost)
+						m.Post("/delete", repo_setting.EnvironmentDelete)
+						m.Group("/secrets", func() {
+							m.Get("", repo_setting.EnvironmentSecrets)
+							m.Post("", web.Bind(forms.AddSecretForm{}), repo_setting.EnvironmentSecretsPost)
+							m.Post("/delete", repo_setting.EnvironmentSecretsDelete)
+						})
+						m.Group("/variables", func() {
+							m.Get("", repo_setting.EnvironmentVariables)
+							m.Post("/new", web.Bind(forms.EditVariableForm{}), repo_setting.EnvironmentVariableCreate)
+							m.Post("/{variable_id}/edit", web.Bind(forms.EditVariableForm{}), repo_setting.EnvironmentVariableUpdate)
+							m.Post("/{variable_id}/delete", repo_setting.EnvironmentVariableDelete)
+						})
+					})
+				})
 			}, actions.MustEnableActions)
 			// the follow handler must be under "settings", otherwise this incomplete repo can't be accessed
 			m.Group("/migrate", func() {
 				m.Post("/retry", repo.MigrateRetryPost)
 				m.Post("/cancel", repo.MigrateCancelPost)
 			})
-		}, ctxDataSet("PageIsRepoSettings", true, "LFSStartServer", setting.LFS.StartServer))
+		}, ctxDataSet("PageIsRepoSettings", true, "LFSStartServer", setting.LFS.StartServer, "TerraformStateEnabled", setting.TerraformState.Enabled))
 	}, reqSignIn, context.RepoAssignment, context.UnitTypes(), reqRepoAdmin, context.RepoRef())
 
 	m.Group("/{username}/{reponame}/action", func() {
@@ -1326,26 +1436,6 @@ func registerRoutes(m *web.Route) {
 			m.Get("/packages", repo.Packages)
 		}
 
-		if setting.Badges.Enabled {
-			m.Group("/badges", func() {
-				m.Get("/workflows/{workflow_name}/badge.svg", badges.GetWorkflowBadge)
-				m.Group("/issues", func() {
-					m.Get(".svg", badges.GetTotalIssuesBadge)
-					m.Get("/open.svg", badges.GetOpenIssuesBadge)
-					m.Get("/closed.svg", badges.GetClosedIssuesBadge)
-				})
-				m.Group("/pulls", func() {
-					m.Get(".svg", badges.GetTotalPullsBadge)
-					m.Get("/open.svg", badges.GetOpenPullsBadge)
-					m.Get("/closed.svg", badges.GetClosedPullsBadge)
-				})
-				if !setting.Repository.DisableStars {
-					m.Get("/stars.svg", badges.GetStarsBadge)
-				}
-				m.Get("/release.svg", badges.GetLatestReleaseBadge)
-			})
-		}
-
 		m.Group("/projects", func() {
 			m.Get("", repo.Projects)
 			m.Get("/{id}", repo.ViewProject)
@@ -1390,6 +1480,9 @@ func registerRoutes(m *web.Route) {
 							Post(web.Bind(actions.ViewRequest{}), actions.ViewPost)
 						m.Post("/rerun", reqRepoActionsWriter, actions.Rerun)
 						m.Get("/logs", actions.Logs)
+						m.Get("/logs/stream", actions.LogsStream)
+						m.Post("/deployment/approve", reqSignIn, actions.ApproveDeployment)
+						m.Post("/deployment/reject", reqSignIn, actions.RejectDeployment)
 					})
 					m.Post("/cancel", reqRepoActionsWriter, actions.Cancel)
 					m.Post("/approve", reqRepoActionsWriter, actions.Approve)
@@ -1491,6 +1584,7 @@ func registerRoutes(m *web.Route) {
 			})
 			m.Post("/merge", context.RepoMustNotBeArchived(), web.Bind(forms.MergePullRequestForm{}), repo.MergePullRequest)
 			m.Post("/cancel_auto_merge", context.RepoMustNotBeArchived(), repo.CancelAutoMergePullRequest)
+			m.Post("/actions/approve", context.RepoMustNotBeArchived(), reqRepoActionsWriter, repo.ApprovePullRequestActionsRuns)
 			m.Post("/update", repo.UpdatePullRequest)
 			m.Post("/set_allow_maintainer_edit", web.Bind(forms.UpdateAllowEditsForm{}), repo.SetAllowEdits)
 			m.Post("/cleanup", context.RepoMustNotBeArchived(), context.RepoRef(), repo.CleanUpPullRequest)
@@ -1571,6 +1665,31 @@ func registerRoutes(m *web.Route) {
 		m.Get("/commit/{sha:([a-f0-9]{4,64})}.{ext:patch|diff}", repo.MustBeNotEmpty, reqRepoCodeReader, repo.RawDiff)
 	}, ignSignIn, context.RepoAssignment, context.UnitTypes())
 
+	if setting.Badges.Enabled {
+		// the badges of the private repositories can be displayed without signing in from a signed URL
+		m.Group("/{username}/{reponame}/badges", func() {
+			m.Get("/status.svg", badges.GetCommitStatusBadge)
+			m.Group("/workflows/{workflow_name}", func() {
+				m.Get("/badge.svg", badges.GetWorkflowBadge)
+				m.Get("/jobs/{job_id}/badge.svg", badges.GetWorkflowJobBadge)
+			})
+			m.Group("/issues", func() {
+				m.Get(".svg", badges.GetTotalIssuesBadge)
+				m.Get("/open.svg", badges.GetOpenIssuesBadge)
+				m.Get("/closed.svg", badges.GetClosedIssuesBadge)
+			})
+			m.Group("/pulls", func() {
+				m.Get(".svg", badges.GetTotalPullsBadge)
+				m.Get("/open.svg", badges.GetOpenPullsBadge)
+				m.Get("/closed.svg", badges.GetClosedPullsBadge)
+			})
+			if !setting.Repository.DisableStars {
+				m.Get("/stars.svg", badges.GetStarsBadge)
+			}
+			m.Get("/release.svg", badges.GetLatestReleaseBadge)
+		}, ignSignIn, badges.RepoAssignment)
+	}
+
 	m.Post("/{username}/{reponame}/lastcommit/*", ignSignInAndCsrf, context.RepoAssignment, context.UnitTypes(), context.RepoRefByType(context.RepoRefCommit), reqRepoCodeReader, repo.LastCommit)
 
 	m.Group("/{username}/{reponame}", func() {
@@ -1610,6 +1729,14 @@ func registerRoutes(m *web.Route) {
 				})
 			}, ignSignInAndCsrf, lfsServerEnabled)
 
+			m.Group("/terraform/state/{name}", func() {
+				m.Get("", repo.GetTerraformState)
+				m.Post("", repo.UpdateTerraformState)
+				m.Delete("", repo.DeleteTerraformState)
+				m.Methods("LOCK", "", repo.LockTerraformState)
+				m.Methods("UNLOCK", "", repo.UnlockTerraformState)
+			}, ignSignInAndCsrf, terraformStateEnabled, repo.TerraformStateAssignment)
+
 			gitHTTPRouters(m)
 		})
 	})
@@ -0,0 +1,77 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package actions
+
+import (
+	"context"
+
+	actions_model "code.gitea.io/gitea/models/actions"
+	"code.gitea.io/gitea/models/db"
+	issues_model "code.gitea.io/gitea/models/issues"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/util"
+)
+
+// ApproveRun approves a run from an untrusted contributor, so that its jobs can be picked up by the runners.
+func ApproveRun(ctx context.Context, run *actions_model.ActionRun, doer *user_model.User) error {
+	if !run.NeedApproval {
+		return util.NewInvalidArgumentErrorf("run %d doesn't need to be approved", run.ID)
+	}
+	jobs, err := actions_model.GetRunJobsByRunID(ctx, run.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := db.WithTx(ctx, func(ctx context.Context) error {
+		run.NeedApproval = false
+		run.ApprovedBy = doer.ID
+		if err := actions_model.UpdateRun(ctx, run, "need_approval", "approved_by"); err != nil {
+			return err
+		}
+		for _, job := range jobs {
+			// the jobs with a concurrency, calling a reusable workflow or targeting an environment are left to the job emitter
+			if len(job.Needs) == 0 && job.Status.IsBlocked() && run.ConcurrencyGroup == "" && job.RawConcurrency == "" && !job.IsCallingWorkflow() && job.RawEnvironment == "" {
+				job.Status = actions_model.StatusWaiting
+				_, err := actions_model.UpdateRunJob(ctx, job, nil, "status")
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	CreateCommitStatus(ctx, jobs...)
+
+	if err := EmitJobsIfReady(run.ID); err != nil {
+		log.Error("EmitJobsIfReady: %v", err)
+	}
+	return nil
+}
+
+// FindPullRequestRunsNeedingApproval returns the runs of a pull request which wait for the approval of a maintainer.
+func FindPullRequestRunsNeedingApproval(ctx context.Context, pr *issues_model.PullRequest) ([]*actions_model.ActionRun, error) {
+	return db.Find[actions_model.ActionRun](ctx, actions_model.FindRunOptions{
+		RepoID:       pr.BaseRepoID,
+		Ref:          pr.GetGitRefName(),
+		NeedApproval: true,
+	})
+}
+
+// ApprovePullRequestRuns approves all the runs of a pull request which wait for the approval of a maintainer.
+func ApprovePullRequestRuns(ctx context.Context, pr *issues_model.PullRequest, doer *user_model.User) error {
+	runs, err := FindPullRequestRunsNeedingApproval(ctx, pr)
+	if err != nil {
+		return err
+	}
+	for _, run := range runs {
+		if err := ApproveRun(ctx, run, doer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package actions
+
+import (
+	"testing"
+
+	actions_model "code.gitea.io/gitea/models/actions"
+	"code.gitea.io/gitea/models/db"
+	repo_model "code.gitea.io/gitea/models/repo"
+	"code.gitea.io/gitea/models/unittest"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/queue"
+	"code.gitea.io/gitea/modules/test"
+	"code.gitea.io/gitea/modules/util"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApproveRun(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+	ctx := db.DefaultContext
+	repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 4})
+	doer := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 5})
+	// the jobs are emitted by the job emitter, which isn't running
+	defer test.MockVariableValue(&jobEmitterQueue, queue.CreateUniqueQueue(ctx, "test-approve-run", func(items ...*jobUpdate) []*jobUpdate {
+		return nil
+	}))()
+
+	run, job := insertRun(t, repo, &actions_model.ActionRun{
+		Index:             8001,
+		WorkflowID:        "test.yml",
+		TriggerUserID:     2,
+		Ref:               "refs/pull/1/head",
+		IsForkPullRequest: true,
+		NeedApproval:      true,
+		Status:            actions_model.StatusBlocked,
+	}, &actions_model.ActionRunJob{JobID: "test"})
+
+	runs, err := db.Find[actions_model.ActionRun](ctx, actions_model.FindRunOptions{RepoID: repo.ID, NeedApproval: true})
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, run.ID, runs[0].ID)
+
+	require.NoError(t, ApproveRun(ctx, runs[0], doer))
+	run = unittest.AssertExistsAndLoadBean(t, &actions_model.ActionRun{ID: run.ID})
+	assert.False(t, run.NeedApproval)
+	assert.Equal(t, doer.ID, run.ApprovedBy)
+	job = unittest.AssertExistsAndLoadBean(t, &actions_model.ActionRunJob{ID: job.ID})
+	assert.Equal(t, actions_model.StatusWaiting, job.Status)
+
+	require.ErrorIs(t, ApproveRun(ctx, run, doer), util.ErrInvalidArgument)
+}
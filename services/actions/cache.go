@@ -0,0 +1,289 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package actions
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"sort"
+	"time"
+
+	actions_model "code.gitea.io/gitea/models/actions"
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/optional"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/storage"
+	"code.gitea.io/gitea/modules/timeutil"
+	"code.gitea.io/gitea/modules/util"
+)
+
+// the entries which haven't been committed for a day are abandoned
+const cacheUploadTimeout = 24 * time.Hour
+
+// CacheRefs returns the refs whose cache entries can be restored by a run, in lookup order:
+// the ref of the run, the base branch of its pull request and the default branch of the repository.
+// The entries are only saved for the ref of the run, so the runs of a pull request can't change the caches of the base branch.
+func CacheRefs(ctx context.Context, run *actions_model.ActionRun) ([]string, error) {
+	if err := run.LoadAttributes(ctx); err != nil {
+		return nil, err
+	}
+	refs := []string{run.Ref}
+	if pullPayload, err := run.GetPullRequestEventPayload(); err == nil && pullPayload.PullRequest != nil && pullPayload.PullRequest.Base != nil {
+		refs = append(refs, git.RefNameFromBranch(pullPayload.PullRequest.Base.Ref).String())
+	}
+	refs = append(refs, git.RefNameFromBranch(run.Repo.DefaultBranch).String())
+
+	// remove the duplicates while keeping the order
+	uniqueRefs := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		if !slices.Contains(uniqueRefs, ref) {
+			uniqueRefs = append(uniqueRefs, ref)
+		}
+	}
+	return uniqueRefs, nil
+}
+
+// LookupCache returns the entry restored by a job for the given keys and version, or nil if there is none.
+func LookupCache(ctx context.Context, task *actions_model.ActionTask, keys []string, version string) (*actions_model.ActionCache, error) {
+	if err := task.Job.LoadRun(ctx); err != nil {
+		return nil, err
+	}
+	refs, err := CacheRefs(ctx, task.Job.Run)
+	if err != nil {
+		return nil, err
+	}
+	c, err := actions_model.FindCache(ctx, task.RepoID, keys, version, refs)
+	if err != nil || c == nil {
+		return nil, err
+	}
+	c.LastUsed = timeutil.TimeStampNow()
+	return c, actions_model.UpdateCache(ctx, c, "last_used")
+}
+
+// ReserveCache reserves an entry for a job to upload its content.
+func ReserveCache(ctx context.Context, task *actions_model.ActionTask, key, version string, size int64) (*actions_model.ActionCache, error) {
+	if key == "" || len(key) > 512 {
+		return nil, util.NewInvalidArgumentErrorf("the key of a cache must not be empty or longer than 512 characters")
+	}
+	if version == "" || len(version) > 255 {
+		return nil, util.NewInvalidArgumentErrorf("the version of a cache must not be empty or longer than 255 characters")
+	}
+	if size > setting.Actions.CacheMaxSize {
+		return nil, util.NewInvalidArgumentErrorf("the size of the cache %d exceeds the maximum size %d", size, setting.Actions.CacheMaxSize)
+	}
+	if err := task.Job.LoadRun(ctx); err != nil {
+		return nil, err
+	}
+
+	c := &actions_model.ActionCache{
+		RepoID:   task.RepoID,
+		RunID:    task.Job.RunID,
+		CacheKey: key,
+		Version:  version,
+		Ref:      task.Job.Run.Ref,
+	}
+	if err := actions_model.InsertCache(ctx, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func cacheChunkPath(c *actions_model.ActionCache, start, end int64) string {
+	return fmt.Sprintf("%s/%d-%d.chunk", c.ChunksPath(), start, end)
+}
+
+// SaveCacheChunk saves a chunk of the content of an entry being uploaded.
+func SaveCacheChunk(c *actions_model.ActionCache, start, end int64, r io.Reader) error {
+	if c.Complete {
+		return util.NewInvalidArgumentErrorf("cache %d is already committed", c.ID)
+	}
+	if start < 0 || end < start || end >= setting.Actions.CacheMaxSize {
+		return util.NewInvalidArgumentErrorf("invalid chunk range %d-%d", start, end)
+	}
+	size := end - start + 1
+	written, err := storage.ActionsCache.Save(cacheChunkPath(c, start, end), r, size)
+	if err != nil {
+		return fmt.Errorf("save chunk of cache %d: %w", c.ID, err)
+	}
+	if written != size {
+		return util.NewInvalidArgumentErrorf("the chunk size %d doesn't match its range %d-%d", written, start, end)
+	}
+	return nil
+}
+
+type cacheChunk struct {
+	start, end int64
+	path       string
+}
+
+func listCacheChunks(c *actions_model.ActionCache) ([]*cacheChunk, error) {
+	var chunks []*cacheChunk
+	dir := c.ChunksPath()
+	if err := storage.ActionsCache.IterateObjects(dir, func(fpath string, obj storage.Object) error {
+		// the path of the objects doesn't include the subdirectory of the storage configuration
+		chunk := &cacheChunk{path: dir + "/" + path.Base(fpath)}
+		if _, err := fmt.Sscanf(path.Base(fpath), "%d-%d.chunk", &chunk.start, &chunk.end); err != nil {
+			return fmt.Errorf("parse chunk %q: %w", fpath, err)
+		}
+		chunks = append(chunks, chunk)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	sort.Slice(chunks, func(i, j int) bool {
+		return chunks[i].start < chunks[j].start
+	})
+	return chunks, nil
+}
+
+// CommitCache merges the uploaded chunks of an entry, which can then be restored,
+// and evicts the least recently used entries of the repository if its caches exceed the maximum size.
+func CommitCache(ctx context.Context, c *actions_model.ActionCache, size int64) error {
+	if c.Complete {
+		return util.NewInvalidArgumentErrorf("cache %d is already committed", c.ID)
+	}
+	chunks, err := listCacheChunks(c)
+	if err != nil {
+		return err
+	}
+	// the chunks may be uploaded more than once, but they must cover the whole content
+	var (
+		readers []io.Reader
+		objects []storage.Object
+		next    int64
+	)
+	defer func() {
+		for _, obj := range objects {
+			_ = obj.Close()
+		}
+	}()
+	for _, chunk := range chunks {
+		if chunk.start != next {
+			continue
+		}
+		f, err := storage.ActionsCache.Open(chunk.path)
+		if err != nil {
+			return fmt.Errorf("open chunk %q: %w", chunk.path, err)
+		}
+		objects = append(objects, f)
+		readers = append(readers, f)
+		next = chunk.end + 1
+	}
+	if next != size {
+		return util.NewInvalidArgumentErrorf("the uploaded chunks of cache %d cover %d bytes instead of %d", c.ID, next, size)
+	}
+
+	if _, err := storage.ActionsCache.Save(c.StoragePath(), io.MultiReader(readers...), size); err != nil {
+		return fmt.Errorf("save cache %d: %w", c.ID, err)
+	}
+	deleteCacheChunks(c)
+
+	c.Size = size
+	c.Complete = true
+	c.LastUsed = timeutil.TimeStampNow()
+	if err := actions_model.UpdateCache(ctx, c, "size", "complete", "last_used"); err != nil {
+		return err
+	}
+	return EvictCaches(ctx, c.RepoID)
+}
+
+func deleteCacheChunks(c *actions_model.ActionCache) {
+	chunks, err := listCacheChunks(c)
+	if err != nil {
+		log.Error("Cannot list the chunks of cache %d: %v", c.ID, err)
+		return
+	}
+	for _, chunk := range chunks {
+		if err := storage.ActionsCache.Delete(chunk.path); err != nil {
+			log.Error("Cannot delete chunk %q of cache %d: %v", chunk.path, c.ID, err)
+		}
+	}
+}
+
+// DeleteCache deletes an entry with its content.
+func DeleteCache(ctx context.Context, c *actions_model.ActionCache) error {
+	if _, err := db.DeleteByID[actions_model.ActionCache](ctx, c.ID); err != nil {
+		return err
+	}
+	if c.Complete {
+		if err := storage.ActionsCache.Delete(c.StoragePath()); err != nil {
+			log.Error("Cannot delete the content of cache %d: %v", c.ID, err)
+		}
+	} else {
+		deleteCacheChunks(c)
+	}
+	return nil
+}
+
+// EvictCaches deletes the least recently used entries of a repository until its caches don't exceed the maximum size.
+func EvictCaches(ctx context.Context, repoID int64) error {
+	size, err := actions_model.GetCacheSize(ctx, repoID)
+	if err != nil {
+		return err
+	}
+	if size <= setting.Actions.CacheMaxSize {
+		return nil
+	}
+
+	caches, err := db.Find[actions_model.ActionCache](ctx, actions_model.FindCachesOptions{
+		RepoID:   repoID,
+		Complete: optional.Some(true),
+	})
+	if err != nil {
+		return err
+	}
+	for _, c := range caches {
+		if size <= setting.Actions.CacheMaxSize {
+			break
+		}
+		if err := DeleteCache(ctx, c); err != nil {
+			return err
+		}
+		size -= c.Size
+	}
+	return nil
+}
+
+// CleanupCaches deletes the entries which haven't been used for the retention period of the caches
+// and the entries whose upload has been abandoned, then evicts the least recently used entries
+// of the repositories whose caches exceed the maximum size.
+func CleanupCaches(ctx context.Context) error {
+	stale, err := db.Find[actions_model.ActionCache](ctx, actions_model.FindCachesOptions{
+		Complete: optional.Some(true),
+		UsedLT:   timeutil.TimeStampNow().AddDuration(-time.Duration(setting.Actions.CacheRetentionDays) * 24 * time.Hour),
+	})
+	if err != nil {
+		return fmt.Errorf("find stale caches: %w", err)
+	}
+	abandoned, err := db.Find[actions_model.ActionCache](ctx, actions_model.FindCachesOptions{
+		Complete: optional.Some(false),
+		UsedLT:   timeutil.TimeStampNow().AddDuration(-cacheUploadTimeout),
+	})
+	if err != nil {
+		return fmt.Errorf("find abandoned caches: %w", err)
+	}
+	log.Info("Found %d stale and %d abandoned caches", len(stale), len(abandoned))
+
+	for _, c := range append(stale, abandoned...) {
+		if err := DeleteCache(ctx, c); err != nil {
+			log.Error("Cannot delete cache %d: %v", c.ID, err)
+		}
+	}
+
+	repoIDs, err := actions_model.GetReposExceedingCacheSize(ctx, setting.Actions.CacheMaxSize)
+	if err != nil {
+		return fmt.Errorf("find repos exceeding the cache size: %w", err)
+	}
+	for _, repoID := range repoIDs {
+		if err := EvictCaches(ctx, repoID); err != nil {
+			log.Error("Cannot evict the caches of repo %d: %v", repoID, err)
+		}
+	}
+	return nil
+}
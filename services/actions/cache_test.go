@@ -0,0 +1,134 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package actions
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	actions_model "code.gitea.io/gitea/models/actions"
+	"code.gitea.io/gitea/models/db"
+	repo_model "code.gitea.io/gitea/models/repo"
+	"code.gitea.io/gitea/models/unittest"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/storage"
+	"code.gitea.io/gitea/modules/test"
+	"code.gitea.io/gitea/modules/util"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+	ctx := db.DefaultContext
+	cacheStorage, err := storage.NewLocalStorage(ctx, &setting.Storage{Path: t.TempDir()})
+	require.NoError(t, err)
+	defer test.MockVariableValue(&storage.ActionsCache, cacheStorage)()
+	defer test.MockVariableValue(&setting.Actions.CacheMaxSize, 20)()
+
+	repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 4})
+
+	newTask := func(ref string, index int64) *actions_model.ActionTask {
+		_, job := insertRun(t, repo, &actions_model.ActionRun{
+			Index:      index,
+			WorkflowID: "build.yml",
+			Ref:        ref,
+			Status:     actions_model.StatusRunning,
+		}, &actions_model.ActionRunJob{JobID: "build"})
+		return &actions_model.ActionTask{RepoID: repo.ID, JobID: job.ID, Job: job}
+	}
+
+	save := func(task *actions_model.ActionTask, key, content string) *actions_model.ActionCache {
+		c, err := ReserveCache(ctx, task, key, "v1", int64(len(content)))
+		require.NoError(t, err)
+		// upload the second half first, the chunks are merged by their range
+		half := int64(len(content) / 2)
+		require.NoError(t, SaveCacheChunk(c, half, int64(len(content))-1, strings.NewReader(content[half:])))
+		require.NoError(t, SaveCacheChunk(c, 0, half-1, strings.NewReader(content[:half])))
+		require.NoError(t, CommitCache(ctx, c, int64(len(content))))
+		return c
+	}
+
+	read := func(c *actions_model.ActionCache) string {
+		f, err := storage.ActionsCache.Open(c.StoragePath())
+		require.NoError(t, err)
+		defer f.Close()
+		content, err := io.ReadAll(f)
+		require.NoError(t, err)
+		return string(content)
+	}
+
+	mainTask := newTask("refs/heads/"+repo.DefaultBranch, 6001)
+	featureTask := newTask("refs/heads/feature", 6002)
+
+	t.Run("reserve", func(t *testing.T) {
+		_, err := ReserveCache(ctx, mainTask, "", "v1", 1)
+		require.ErrorIs(t, err, util.ErrInvalidArgument)
+		_, err = ReserveCache(ctx, mainTask, "too-big", "v1", 21)
+		require.ErrorIs(t, err, util.ErrInvalidArgument)
+
+		c, err := ReserveCache(ctx, mainTask, "incomplete", "v1", 4)
+		require.NoError(t, err)
+		_, err = ReserveCache(ctx, mainTask, "incomplete", "v1", 4)
+		require.ErrorIs(t, err, util.ErrAlreadyExist)
+		require.NoError(t, SaveCacheChunk(c, 0, 1, strings.NewReader("ab")))
+		require.ErrorIs(t, CommitCache(ctx, c, 4), util.ErrInvalidArgument)
+
+		found, err := LookupCache(ctx, mainTask, []string{"incomplete"}, "v1")
+		require.NoError(t, err)
+		assert.Nil(t, found)
+	})
+
+	t.Run("lookup", func(t *testing.T) {
+		mainCache := save(mainTask, "deps-main", "main")
+		assert.Equal(t, "main", read(mainCache))
+		featureCache := save(featureTask, "deps-feature", "feature")
+
+		// the entries of the ref of the run are preferred to the ones of the default branch
+		found, err := LookupCache(ctx, featureTask, []string{"deps-main", "deps-"}, "v1")
+		require.NoError(t, err)
+		assert.Equal(t, featureCache.ID, found.ID)
+		found, err = LookupCache(ctx, featureTask, []string{"deps-main"}, "v1")
+		require.NoError(t, err)
+		assert.Equal(t, mainCache.ID, found.ID)
+		// a key also matches the entries whose key starts with it
+		found, err = LookupCache(ctx, mainTask, []string{"deps-"}, "v1")
+		require.NoError(t, err)
+		assert.Equal(t, mainCache.ID, found.ID)
+
+		// the caches of the feature branch aren't restored by the runs of the default branch
+		found, err = LookupCache(ctx, mainTask, []string{"deps-feature"}, "v1")
+		require.NoError(t, err)
+		assert.Nil(t, found)
+
+		// the version must match
+		found, err = LookupCache(ctx, mainTask, []string{"deps-main"}, "v2")
+		require.NoError(t, err)
+		assert.Nil(t, found)
+	})
+
+	t.Run("evict", func(t *testing.T) {
+		// the least recently used entry is evicted when the caches exceed the maximum size
+		c := save(mainTask, "large", "0123456789")
+		unittest.AssertExistsAndLoadBean(t, &actions_model.ActionCache{ID: c.ID})
+		size, err := actions_model.GetCacheSize(ctx, repo.ID)
+		require.NoError(t, err)
+		assert.LessOrEqual(t, size, int64(20))
+
+		_, err = storage.ActionsCache.Stat(c.StoragePath())
+		require.NoError(t, err)
+	})
+
+	t.Run("cleanup", func(t *testing.T) {
+		defer test.MockVariableValue(&setting.Actions.CacheRetentionDays, -1)()
+		require.NoError(t, CleanupCaches(ctx))
+		// only the entry which is still being uploaded is kept
+		caches, err := db.Find[actions_model.ActionCache](ctx, actions_model.FindCachesOptions{RepoID: repo.ID})
+		require.NoError(t, err)
+		require.Len(t, caches, 1)
+		assert.False(t, caches[0].Complete)
+	})
+}
@@ -11,6 +11,7 @@ import (
 	actions_model "code.gitea.io/gitea/models/actions"
 	"code.gitea.io/gitea/models/db"
 	"code.gitea.io/gitea/modules/actions"
+	"code.gitea.io/gitea/modules/container"
 	"code.gitea.io/gitea/modules/log"
 	"code.gitea.io/gitea/modules/setting"
 	"code.gitea.io/gitea/modules/timeutil"
@@ -68,6 +69,7 @@ func stopTasks(ctx context.Context, opts actions_model.FindTaskOptions) error {
 	}
 
 	CreateCommitStatus(ctx, jobs...)
+	emitJobsOfRuns(jobs)
 
 	return nil
 }
@@ -96,6 +98,20 @@ func CancelAbandonedJobs(ctx context.Context) error {
 		}
 		CreateCommitStatus(ctx, job)
 	}
+	emitJobsOfRuns(jobs)
 
 	return nil
 }
+
+// emitJobsOfRuns lets the job emitter resolve the jobs needing the stopped jobs and complete their runs.
+func emitJobsOfRuns(jobs []*actions_model.ActionRunJob) {
+	runIDs := make(container.Set[int64])
+	for _, job := range jobs {
+		runIDs.Add(job.RunID)
+	}
+	for runID := range runIDs {
+		if err := EmitJobsIfReady(runID); err != nil {
+			log.Warn("EmitJobsIfReady of run %d: %v", runID, err)
+		}
+	}
+}
@@ -110,6 +110,8 @@ func createCommitStatus(ctx context.Context, job *actions_model.ActionRunJob) er
 		description = "Waiting to run"
 	case actions_model.StatusBlocked:
 		description = "Blocked by required conditions"
+	case actions_model.StatusWaitingForApproval:
+		description = "Waiting for approval to deploy"
 	}
 
 	index, err := getIndexOfJob(ctx, job)
@@ -140,7 +142,7 @@ func toCommitStatus(status actions_model.Status) api.CommitStatusState {
 		return api.CommitStatusSuccess
 	case actions_model.StatusFailure, actions_model.StatusCancelled:
 		return api.CommitStatusFailure
-	case actions_model.StatusWaiting, actions_model.StatusBlocked, actions_model.StatusRunning:
+	case actions_model.StatusWaiting, actions_model.StatusBlocked, actions_model.StatusRunning, actions_model.StatusWaitingForApproval:
 		return api.CommitStatusPending
 	default:
 		return api.CommitStatusError
@@ -0,0 +1,137 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package actions
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	actions_model "code.gitea.io/gitea/models/actions"
+	actions_module "code.gitea.io/gitea/modules/actions"
+	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/json"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/setting"
+
+	"github.com/nektos/act/pkg/jobparser"
+	"github.com/nektos/act/pkg/model"
+)
+
+// generateGitContext returns the `github` context available to the expressions evaluated by the server.
+// The run must have its attributes loaded.
+func generateGitContext(run *actions_model.ActionRun) *model.GithubContext {
+	event := map[string]any{}
+	_ = json.Unmarshal([]byte(run.EventPayload), &event)
+
+	eventName := run.TriggerEvent
+	if eventName == "" {
+		eventName = run.Event.Event()
+	}
+
+	ref := run.Ref
+	sha := run.CommitSHA
+	baseRef := ""
+	headRef := ""
+	if pullPayload, err := run.GetPullRequestEventPayload(); err == nil && pullPayload.PullRequest != nil && pullPayload.PullRequest.Base != nil && pullPayload.PullRequest.Head != nil {
+		baseRef = pullPayload.PullRequest.Base.Ref
+		headRef = pullPayload.PullRequest.Head.Ref
+		if run.TriggerEvent == actions_module.GithubEventPullRequestTarget {
+			ref = git.BranchPrefix + pullPayload.PullRequest.Base.Name
+			sha = pullPayload.PullRequest.Base.Sha
+		}
+	}
+	refName := git.RefName(ref)
+
+	gitCtx := &model.GithubContext{
+		Event:      event,
+		Workflow:   run.WorkflowID,
+		RunID:      strconv.FormatInt(run.ID, 10),
+		RunNumber:  strconv.FormatInt(run.Index, 10),
+		EventName:  eventName,
+		Sha:        sha,
+		Ref:        ref,
+		RefName:    refName.ShortName(),
+		RefType:    refName.RefType(),
+		HeadRef:    headRef,
+		BaseRef:    baseRef,
+		ServerURL:  setting.AppURL,
+		APIURL:     setting.AppURL + "api/v1",
+		Repository: run.Repo.OwnerName + "/" + run.Repo.Name,
+	}
+	gitCtx.RepositoryOwner = run.Repo.OwnerName
+	if run.TriggerUser != nil {
+		gitCtx.Actor = run.TriggerUser.Name
+	}
+	return gitCtx
+}
+
+// evaluateRunConcurrency evaluates the workflow-level concurrency of a run which hasn't been inserted yet,
+// and cancels or holds back the run according to the other runs of its concurrency group.
+func evaluateRunConcurrency(ctx context.Context, run *actions_model.ActionRun, rawConcurrency *actions_module.RawConcurrency, vars map[string]string) error {
+	if err := run.LoadAttributes(ctx); err != nil {
+		return fmt.Errorf("LoadAttributes: %w", err)
+	}
+
+	group, cancel, err := actions_module.EvaluateConcurrency(rawConcurrency, "", nil, generateGitContext(run), nil, vars)
+	if err != nil {
+		return fmt.Errorf("EvaluateConcurrency: %w", err)
+	}
+	run.RawConcurrency = rawConcurrency.Marshal()
+	run.ConcurrencyGroup = group
+	run.ConcurrencyCancel = cancel
+
+	if err := actions_model.CancelConcurrentRuns(ctx, run); err != nil {
+		return fmt.Errorf("CancelConcurrentRuns: %w", err)
+	}
+
+	blocked, err := actions_model.ShouldBlockRunByConcurrency(ctx, run)
+	if err != nil {
+		return fmt.Errorf("ShouldBlockRunByConcurrency: %w", err)
+	}
+	if blocked {
+		run.Status = actions_model.StatusBlocked
+	}
+	return nil
+}
+
+// evaluateJobConcurrency evaluates the job-level concurrency of a job which is about to leave the blocked status.
+// The needs of the job must be done since the expressions may refer to their outputs.
+func evaluateJobConcurrency(ctx context.Context, run *actions_model.ActionRun, job *actions_model.ActionRunJob, jobs []*actions_model.ActionRunJob, vars map[string]string) error {
+	rawConcurrency, err := actions_module.UnmarshalRawConcurrency(job.RawConcurrency)
+	if err != nil {
+		return fmt.Errorf("UnmarshalRawConcurrency: %w", err)
+	}
+	job.IsConcurrencyEvaluated = true
+	if rawConcurrency == nil {
+		return nil
+	}
+
+	singleWorkflows, err := jobparser.Parse(job.WorkflowPayload)
+	if err != nil {
+		return fmt.Errorf("jobparser.Parse: %w", err)
+	}
+	if len(singleWorkflows) != 1 {
+		return fmt.Errorf("the workflow payload of job %d has %d jobs", job.ID, len(singleWorkflows))
+	}
+	id, wfJob := singleWorkflows[0].Job()
+	if wfJob == nil {
+		return fmt.Errorf("the workflow payload of job %d has no job", job.ID)
+	}
+
+	results, err := jobNeedsResults(ctx, job, jobs)
+	if err != nil {
+		return err
+	}
+
+	group, cancel, err := actions_module.EvaluateConcurrency(rawConcurrency, id, wfJob, generateGitContext(run), results, vars)
+	if err != nil {
+		// a concurrency which can't be evaluated doesn't prevent the job from running
+		log.Error("Evaluate concurrency of job %d: %v", job.ID, err)
+		return nil
+	}
+	job.ConcurrencyGroup = group
+	job.ConcurrencyCancel = cancel
+	return nil
+}
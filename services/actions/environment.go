@@ -0,0 +1,266 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package actions
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	actions_model "code.gitea.io/gitea/models/actions"
+	"code.gitea.io/gitea/models/db"
+	repo_model "code.gitea.io/gitea/models/repo"
+	user_model "code.gitea.io/gitea/models/user"
+	actions_module "code.gitea.io/gitea/modules/actions"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/timeutil"
+	"code.gitea.io/gitea/modules/util"
+	secret_service "code.gitea.io/gitea/services/secrets"
+
+	"github.com/gobwas/glob"
+	"xorm.io/builder"
+)
+
+// ValidateEnvironmentName checks the name of an environment, which is part of the URL of its settings.
+func ValidateEnvironmentName(name string) error {
+	if name == "" || len(name) > 255 || strings.TrimSpace(name) != name {
+		return util.NewInvalidArgumentErrorf("invalid environment name %q", name)
+	}
+	if strings.ContainsAny(name, "/\\?#%") {
+		return util.NewInvalidArgumentErrorf("the environment name %q contains a forbidden character", name)
+	}
+	return nil
+}
+
+// EnvironmentOptions are the protection rules of an environment.
+type EnvironmentOptions struct {
+	ReviewerIDs    []int64
+	WaitTimer      int64
+	BranchPatterns []string
+}
+
+func (opts *EnvironmentOptions) validate() error {
+	if opts.WaitTimer < 0 || opts.WaitTimer > 43200 {
+		return util.NewInvalidArgumentErrorf("the wait timer must be between 0 and 43200 minutes")
+	}
+	for _, pattern := range opts.BranchPatterns {
+		if _, err := glob.Compile(pattern, '/'); err != nil {
+			return util.NewInvalidArgumentErrorf("invalid branch pattern %q: %v", pattern, err)
+		}
+	}
+	return nil
+}
+
+// CreateEnvironment creates an environment of a repository with the given protection rules.
+func CreateEnvironment(ctx context.Context, repo *repo_model.Repository, name string, opts *EnvironmentOptions) (*actions_model.ActionEnvironment, error) {
+	if err := ValidateEnvironmentName(name); err != nil {
+		return nil, err
+	}
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	env := &actions_model.ActionEnvironment{
+		RepoID:         repo.ID,
+		Name:           name,
+		ReviewerIDs:    opts.ReviewerIDs,
+		WaitTimer:      opts.WaitTimer,
+		BranchPatterns: opts.BranchPatterns,
+	}
+	if err := actions_model.InsertEnvironment(ctx, env); err != nil {
+		return nil, err
+	}
+	return env, nil
+}
+
+// UpdateEnvironment updates the protection rules of an environment.
+// They apply to the deployments created afterwards.
+func UpdateEnvironment(ctx context.Context, env *actions_model.ActionEnvironment, opts *EnvironmentOptions) error {
+	if err := opts.validate(); err != nil {
+		return err
+	}
+	env.ReviewerIDs = opts.ReviewerIDs
+	env.WaitTimer = opts.WaitTimer
+	env.BranchPatterns = opts.BranchPatterns
+	return actions_model.UpdateEnvironment(ctx, env, "reviewer_i_ds", "wait_timer", "branch_patterns")
+}
+
+// DeleteEnvironment deletes an environment with its secrets, its variables and its deployments.
+func DeleteEnvironment(ctx context.Context, env *actions_model.ActionEnvironment) error {
+	return db.WithTx(ctx, func(ctx context.Context) error {
+		if err := secret_service.DeleteEnvironmentSecrets(ctx, env.RepoID, env.ID); err != nil {
+			return err
+		}
+		return actions_model.DeleteEnvironment(ctx, env)
+	})
+}
+
+// checkJobEnvironment creates the deployment of a job targeting an environment when it is about to run,
+// and returns the new status of the job: waiting if the environment has no protection rule to wait for,
+// waiting for approval until the deployment is approved and the wait timer of the environment has elapsed,
+// or failure if the environment can't be evaluated or doesn't accept the deployments of the ref of the run.
+func checkJobEnvironment(ctx context.Context, run *actions_model.ActionRun, job *actions_model.ActionRunJob, jobs []*actions_model.ActionRunJob, vars map[string]string) (actions_model.Status, error) {
+	if err := run.LoadAttributes(ctx); err != nil {
+		return actions_model.StatusUnknown, err
+	}
+
+	re, err := actions_module.UnmarshalRawEnvironment(job.RawEnvironment)
+	if err != nil {
+		return actions_model.StatusUnknown, fmt.Errorf("UnmarshalRawEnvironment: %w", err)
+	}
+	workflow, id, wfJob, err := parseJobPayload(job)
+	if err != nil {
+		return actions_model.StatusUnknown, err
+	}
+	results, err := jobNeedsResults(ctx, job, jobs)
+	if err != nil {
+		return actions_model.StatusUnknown, err
+	}
+	gitCtx := generateGitContext(run)
+	inputs := actions_module.ReadWorkflowCallInputs(workflow.Env)
+
+	now := timeutil.TimeStampNow()
+	fail := func(format string, a ...any) (actions_model.Status, error) {
+		log.Warn("Cannot deploy job %d to its environment: %s", job.ID, fmt.Sprintf(format, a...))
+		job.Started = now
+		job.Stopped = now
+		return actions_model.StatusFailure, nil
+	}
+
+	name, url, err := actions_module.EvaluateEnvironment(re, id, wfJob, gitCtx, results, vars, inputs)
+	if err != nil {
+		return fail("%v", err)
+	}
+	if err := ValidateEnvironmentName(name); err != nil {
+		return fail("%v", err)
+	}
+	env, err := actions_model.GetOrCreateEnvironment(ctx, run.RepoID, name)
+	if err != nil {
+		return actions_model.StatusUnknown, fmt.Errorf("GetOrCreateEnvironment: %w", err)
+	}
+	job.EnvironmentID = env.ID
+	if !env.IsRefAllowed(run.Ref) {
+		return fail("%s is not allowed to deploy to the environment %q", run.Ref, env.Name)
+	}
+
+	deployment := &actions_model.ActionDeployment{
+		RepoID:        run.RepoID,
+		EnvironmentID: env.ID,
+		RunID:         run.ID,
+		JobID:         job.ID,
+		Ref:           run.Ref,
+		CommitSHA:     run.CommitSHA,
+		URL:           url,
+		Status:        actions_model.StatusWaitingForApproval,
+		NeedApproval:  env.NeedApproval(),
+		WaitUntil:     now.AddDuration(time.Duration(env.WaitTimer) * time.Minute),
+	}
+	if deployment.CanStart(now) {
+		deployment.Status = actions_model.StatusWaiting
+	}
+	if err := db.Insert(ctx, deployment); err != nil {
+		return actions_model.StatusUnknown, err
+	}
+	job.DeploymentID = deployment.ID
+	return deployment.Status, nil
+}
+
+// ReviewDeployment approves or rejects the deployment of a job waiting for approval.
+// Only the reviewers of the environment can review its deployments.
+// A rejected job fails, an approved job runs once the wait timer of the environment has elapsed.
+func ReviewDeployment(ctx context.Context, job *actions_model.ActionRunJob, doer *user_model.User, approve bool) error {
+	if !job.Status.IsWaitingForApproval() || job.DeploymentID == 0 {
+		return util.NewInvalidArgumentErrorf("job %d isn't waiting for approval", job.ID)
+	}
+	deployment, err := actions_model.GetDeploymentByID(ctx, job.DeploymentID)
+	if err != nil {
+		return err
+	}
+	if !deployment.IsWaitingForReview() {
+		return util.NewInvalidArgumentErrorf("deployment %d isn't waiting for a review", deployment.ID)
+	}
+	env, err := actions_model.GetEnvironmentByID(ctx, deployment.EnvironmentID)
+	if err != nil {
+		return err
+	}
+	if !env.IsReviewer(doer.ID) {
+		return util.NewPermissionDeniedErrorf("%s isn't a reviewer of the environment %q", doer.Name, env.Name)
+	}
+
+	now := timeutil.TimeStampNow()
+	if err := db.WithTx(ctx, func(ctx context.Context) error {
+		deployment.Reviewed = now
+		if approve {
+			deployment.ApprovedBy = doer.ID
+			if err := actions_model.UpdateDeployment(ctx, deployment, "approved_by", "reviewed"); err != nil {
+				return err
+			}
+			if deployment.CanStart(now) {
+				return startDeployment(ctx, job)
+			}
+			return nil
+		}
+
+		deployment.RejectedBy = doer.ID
+		if err := actions_model.UpdateDeployment(ctx, deployment, "rejected_by", "reviewed"); err != nil {
+			return err
+		}
+		job.Status = actions_model.StatusFailure
+		job.Started = now
+		job.Stopped = now
+		_, err := actions_model.UpdateRunJob(ctx, job, builder.Eq{"status": actions_model.StatusWaitingForApproval}, "status", "started", "stopped")
+		return err
+	}); err != nil {
+		return err
+	}
+
+	CreateCommitStatus(ctx, job)
+	if !approve {
+		// the jobs needing the rejected job are skipped
+		return EmitJobsIfReady(job.RunID)
+	}
+	return nil
+}
+
+// StartApprovedDeployments lets the jobs whose deployment has been approved run once the wait timer of their environment has elapsed.
+func StartApprovedDeployments(ctx context.Context) error {
+	now := timeutil.TimeStampNow()
+	deployments, err := db.Find[actions_model.ActionDeployment](ctx, actions_model.FindDeploymentsOptions{
+		Statuses:  []actions_model.Status{actions_model.StatusWaitingForApproval},
+		WaitUntil: now,
+	})
+	if err != nil {
+		return fmt.Errorf("find deployments: %w", err)
+	}
+
+	for _, deployment := range deployments {
+		if !deployment.CanStart(now) {
+			continue
+		}
+		job, err := actions_model.GetRunJobByID(ctx, deployment.JobID)
+		if err != nil {
+			log.Warn("Cannot get the job of deployment %d: %v", deployment.ID, err)
+			continue
+		}
+		if job.DeploymentID != deployment.ID {
+			// the job has been rerun since
+			continue
+		}
+		if err := db.WithTx(ctx, func(ctx context.Context) error {
+			return startDeployment(ctx, job)
+		}); err != nil {
+			log.Warn("Cannot start the job of deployment %d: %v", deployment.ID, err)
+			continue
+		}
+		CreateCommitStatus(ctx, job)
+	}
+	return nil
+}
+
+// startDeployment lets a job waiting for approval be picked by a runner.
+func startDeployment(ctx context.Context, job *actions_model.ActionRunJob) error {
+	job.Status = actions_model.StatusWaiting
+	_, err := actions_model.UpdateRunJob(ctx, job, builder.Eq{"status": actions_model.StatusWaitingForApproval}, "status")
+	return err
+}
@@ -0,0 +1,134 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package actions
+
+import (
+	"testing"
+
+	actions_model "code.gitea.io/gitea/models/actions"
+	"code.gitea.io/gitea/models/db"
+	repo_model "code.gitea.io/gitea/models/repo"
+	"code.gitea.io/gitea/models/unittest"
+	user_model "code.gitea.io/gitea/models/user"
+	actions_module "code.gitea.io/gitea/modules/actions"
+	"code.gitea.io/gitea/modules/timeutil"
+	"code.gitea.io/gitea/modules/util"
+
+	"github.com/nektos/act/pkg/jobparser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateEnvironmentName(t *testing.T) {
+	require.NoError(t, ValidateEnvironmentName("production"))
+	require.NoError(t, ValidateEnvironmentName("review app"))
+	require.ErrorIs(t, ValidateEnvironmentName(""), util.ErrInvalidArgument)
+	require.ErrorIs(t, ValidateEnvironmentName(" production"), util.ErrInvalidArgument)
+	require.ErrorIs(t, ValidateEnvironmentName("review/main"), util.ErrInvalidArgument)
+}
+
+func TestJobEnvironment(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+	ctx := db.DefaultContext
+	repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 4})
+	reviewer := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 5})
+	other := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2})
+
+	env, err := CreateEnvironment(ctx, repo, "Production", &EnvironmentOptions{
+		ReviewerIDs:    []int64{reviewer.ID},
+		BranchPatterns: []string{"main", "release/*"},
+	})
+	require.NoError(t, err)
+	_, err = CreateEnvironment(ctx, repo, "production", &EnvironmentOptions{})
+	require.ErrorIs(t, err, util.ErrAlreadyExist)
+
+	workflows, err := jobparser.Parse([]byte(`
+on: push
+jobs:
+  deploy:
+    runs-on: docker
+    steps:
+      - run: ./deploy.sh
+`))
+	require.NoError(t, err)
+	payload, err := workflows[0].Marshal()
+	require.NoError(t, err)
+
+	newJob := func(ref string, index int64) (*actions_model.ActionRun, *actions_model.ActionRunJob) {
+		return insertRun(t, repo, &actions_model.ActionRun{
+			Index:         index,
+			WorkflowID:    "deploy.yml",
+			TriggerUserID: reviewer.ID,
+			Ref:           ref,
+			Status:        actions_model.StatusBlocked,
+		}, &actions_model.ActionRunJob{
+			JobID:           "deploy",
+			WorkflowPayload: payload,
+			RawEnvironment:  (&actions_module.RawEnvironment{Name: "production", URL: "https://${{ github.ref_name }}.example.com"}).Marshal(),
+		})
+	}
+
+	t.Run("ref not allowed", func(t *testing.T) {
+		run, job := newJob("refs/heads/feature", 5001)
+		status, err := checkJobEnvironment(ctx, run, job, []*actions_model.ActionRunJob{job}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, actions_model.StatusFailure, status)
+		assert.Equal(t, env.ID, job.EnvironmentID)
+		assert.Zero(t, job.DeploymentID)
+	})
+
+	t.Run("approve", func(t *testing.T) {
+		run, job := newJob("refs/heads/release/v1", 5002)
+		status, err := checkJobEnvironment(ctx, run, job, []*actions_model.ActionRunJob{job}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, actions_model.StatusWaitingForApproval, status)
+		job.Status = status
+		_, err = actions_model.UpdateRunJob(ctx, job, nil, "status", "environment_id", "deployment_id")
+		require.NoError(t, err)
+
+		deployment, err := actions_model.GetDeploymentByID(ctx, job.DeploymentID)
+		require.NoError(t, err)
+		assert.Equal(t, "https://release/v1.example.com", deployment.URL)
+		assert.True(t, deployment.IsWaitingForReview())
+
+		require.ErrorIs(t, ReviewDeployment(ctx, job, other, true), util.ErrPermissionDenied)
+		require.NoError(t, ReviewDeployment(ctx, job, reviewer, true))
+
+		job = unittest.AssertExistsAndLoadBean(t, &actions_model.ActionRunJob{ID: job.ID})
+		assert.Equal(t, actions_model.StatusWaiting, job.Status)
+		deployment = unittest.AssertExistsAndLoadBean(t, &actions_model.ActionDeployment{ID: job.DeploymentID})
+		assert.Equal(t, reviewer.ID, deployment.ApprovedBy)
+		assert.Equal(t, actions_model.StatusWaiting, deployment.Status)
+
+		require.ErrorIs(t, ReviewDeployment(ctx, job, reviewer, true), util.ErrInvalidArgument)
+	})
+
+	t.Run("wait timer", func(t *testing.T) {
+		require.NoError(t, UpdateEnvironment(ctx, env, &EnvironmentOptions{WaitTimer: 10}))
+		env := unittest.AssertExistsAndLoadBean(t, &actions_model.ActionEnvironment{ID: env.ID})
+		assert.False(t, env.NeedApproval())
+		assert.True(t, env.IsRefAllowed("refs/heads/feature"))
+
+		run, job := newJob("refs/heads/feature", 5003)
+		status, err := checkJobEnvironment(ctx, run, job, []*actions_model.ActionRunJob{job}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, actions_model.StatusWaitingForApproval, status)
+		job.Status = status
+		_, err = actions_model.UpdateRunJob(ctx, job, nil, "status", "environment_id", "deployment_id")
+		require.NoError(t, err)
+
+		require.NoError(t, StartApprovedDeployments(ctx))
+		unittest.AssertExistsAndLoadBean(t, &actions_model.ActionRunJob{ID: job.ID, Status: actions_model.StatusWaitingForApproval})
+
+		deployment := unittest.AssertExistsAndLoadBean(t, &actions_model.ActionDeployment{ID: job.DeploymentID})
+		deployment.WaitUntil = timeutil.TimeStampNow() - 1
+		require.NoError(t, actions_model.UpdateDeployment(ctx, deployment, "wait_until"))
+
+		require.NoError(t, StartApprovedDeployments(ctx))
+		unittest.AssertExistsAndLoadBean(t, &actions_model.ActionRunJob{ID: job.ID, Status: actions_model.StatusWaiting})
+	})
+
+	require.NoError(t, DeleteEnvironment(ctx, env))
+	unittest.AssertNotExistsBean(t, &actions_model.ActionDeployment{EnvironmentID: env.ID})
+}
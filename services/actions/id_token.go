@@ -0,0 +1,257 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package actions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	actions_model "code.gitea.io/gitea/models/actions"
+	actions_module "code.gitea.io/gitea/modules/actions"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/structs"
+	"code.gitea.io/gitea/services/auth/source/oauth2"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/nektos/act/pkg/jobparser"
+)
+
+// idTokenExpiration is the lifetime of the ID tokens issued to the jobs,
+// they are meant to be exchanged right away for the credentials of another service.
+const idTokenExpiration = 10 * time.Minute
+
+// ErrIDTokenUnavailable is returned when the ID tokens can't be issued to a job.
+var ErrIDTokenUnavailable = errors.New("ID tokens are not available")
+
+// IDTokenClaims are the claims of the OpenID Connect ID tokens issued to the jobs.
+// They are named like the claims of the tokens issued by GitHub, so the trust policies of the cloud providers can be reused.
+// See https://docs.github.com/en/actions/security-for-github-actions/security-hardening-your-deployments/about-security-hardening-with-openid-connect
+type IDTokenClaims struct {
+	jwt.RegisteredClaims
+
+	Ref                  string `json:"ref"`
+	RefType              string `json:"ref_type"`
+	SHA                  string `json:"sha"`
+	Repository           string `json:"repository"`
+	RepositoryID         string `json:"repository_id"`
+	RepositoryOwner      string `json:"repository_owner"`
+	RepositoryOwnerID    string `json:"repository_owner_id"`
+	RepositoryVisibility string `json:"repository_visibility"`
+	Actor                string `json:"actor"`
+	ActorID              string `json:"actor_id"`
+	Workflow             string `json:"workflow"`
+	WorkflowRef          string `json:"workflow_ref"`
+	JobWorkflowRef       string `json:"job_workflow_ref"`
+	EventName            string `json:"event_name"`
+	Environment          string `json:"environment,omitempty"`
+	HeadRef              string `json:"head_ref,omitempty"`
+	BaseRef              string `json:"base_ref,omitempty"`
+	RunID                string `json:"run_id"`
+	RunNumber            string `json:"run_number"`
+	RunAttempt           string `json:"run_attempt"`
+	RunnerEnvironment    string `json:"runner_environment"`
+}
+
+// IDTokenIssuer returns the issuer of the ID tokens issued to the jobs,
+// whose OpenID configuration is served at <issuer>/.well-known/openid-configuration.
+func IDTokenIssuer() string {
+	return setting.AppURL + "api/actions"
+}
+
+// IDTokenRequestURL returns the URL requested by the jobs to get an ID token,
+// the clients append the `audience` parameter to its query.
+func IDTokenRequestURL() string {
+	return IDTokenIssuer() + "/idtoken?api-version=2.0"
+}
+
+// IsIDTokenAvailable returns whether ID tokens can be issued to the job of a task.
+// They are signed with the key of the OAuth2 provider, which must be asymmetric so that anyone can verify them,
+// they are only issued to the jobs granted `id-token: write`, and not to the jobs of fork pull requests, like the secrets.
+func IsIDTokenAvailable(task *actions_model.ActionTask) bool {
+	if oauth2.DefaultSigningKey == nil || oauth2.DefaultSigningKey.IsSymmetric() {
+		return false
+	}
+	if !task.Job.IDTokenPermission {
+		return false
+	}
+	return !task.Job.Run.IsForkPullRequest || task.Job.Run.TriggerEvent == actions_module.GithubEventPullRequestTarget
+}
+
+// readIDTokenPermissions returns by job ID whether the jobs of a workflow are granted `id-token: write`,
+// by their permissions or else by the workflow-level permissions. Like the other scopes, it isn't granted by default.
+// The jobs of a workflow called by a job inherit its permission when they don't set theirs, and can't be granted more.
+func readIDTokenPermissions(content []byte, jobs []*jobparser.SingleWorkflow, caller *actions_model.ActionRunJob) (map[string]bool, error) {
+	wfPermissions, jobsPermissions, err := actions_module.ReadWorkflowPermissions(content)
+	if err != nil {
+		return nil, fmt.Errorf("ReadWorkflowPermissions: %w", err)
+	}
+
+	granted := make(map[string]bool, len(jobs))
+	for _, v := range jobs {
+		id, _ := v.Job()
+		permissions, ok := jobsPermissions[id]
+		if !ok {
+			permissions = wfPermissions
+		}
+		switch {
+		case caller == nil:
+			granted[id] = permissions.CanWrite("id-token")
+		case permissions == nil:
+			granted[id] = caller.IDTokenPermission
+		default:
+			granted[id] = caller.IDTokenPermission && permissions.CanWrite("id-token")
+		}
+	}
+	return granted, nil
+}
+
+// AddIDTokenRequestEnv adds to the workflow payload of a task the environment variables
+// telling the job where and how to request an ID token, as expected by the GitHub toolkit.
+func AddIDTokenRequestEnv(task *actions_model.ActionTask, payload []byte) ([]byte, error) {
+	token, err := CreateAuthorizationToken(task.ID, task.Job.RunID, task.JobID)
+	if err != nil {
+		return nil, err
+	}
+
+	workflows, err := jobparser.Parse(payload)
+	if err != nil {
+		return nil, err
+	}
+	if len(workflows) != 1 {
+		return nil, fmt.Errorf("the workflow payload of job %d has %d jobs", task.JobID, len(workflows))
+	}
+	workflow := workflows[0]
+	env := make(map[string]string, len(workflow.Env)+2)
+	for k, v := range workflow.Env {
+		env[k] = v
+	}
+	env["ACTIONS_ID_TOKEN_REQUEST_URL"] = IDTokenRequestURL()
+	env["ACTIONS_ID_TOKEN_REQUEST_TOKEN"] = token
+	workflow.Env = env
+	return workflow.Marshal()
+}
+
+// CreateIDToken returns an ID token signed for the job of a running task and the given audience.
+// The audience defaults to the URL of the owner of the repository.
+func CreateIDToken(ctx context.Context, task *actions_model.ActionTask, audience string) (string, error) {
+	if err := task.LoadAttributes(ctx); err != nil {
+		return "", err
+	}
+	if !IsIDTokenAvailable(task) {
+		return "", ErrIDTokenUnavailable
+	}
+
+	claims, err := generateIDTokenClaims(ctx, task, audience)
+	if err != nil {
+		return "", err
+	}
+
+	signingKey := oauth2.DefaultSigningKey
+	token := jwt.NewWithClaims(signingKey.SigningMethod(), claims)
+	signingKey.PreProcessToken(token)
+	return token.SignedString(signingKey.SignKey())
+}
+
+func generateIDTokenClaims(ctx context.Context, task *actions_model.ActionTask, audience string) (*IDTokenClaims, error) {
+	run := task.Job.Run
+	repo := run.Repo
+	if err := repo.LoadOwner(ctx); err != nil {
+		return nil, err
+	}
+	gitCtx := generateGitContext(run)
+
+	if audience == "" {
+		audience = repo.Owner.HTMLURL()
+	}
+
+	var environment string
+	if task.Job.EnvironmentID > 0 {
+		env, err := actions_model.GetEnvironmentByID(ctx, task.Job.EnvironmentID)
+		if err != nil {
+			return nil, err
+		}
+		environment = env.Name
+	}
+
+	// the subject identifies the repository and the environment, the pull request or the ref the job runs for
+	subject := "repo:" + repo.FullName()
+	if environment != "" {
+		subject += ":environment:" + environment
+	} else if gitCtx.EventName == actions_module.GithubEventPullRequest {
+		subject += ":pull_request"
+	} else {
+		subject += ":ref:" + gitCtx.Ref
+	}
+
+	visibility := structs.VisibleTypePublic
+	if repo.IsPrivate {
+		visibility = structs.VisibleTypePrivate
+	} else if !repo.Owner.Visibility.IsPublic() {
+		visibility = repo.Owner.Visibility
+	}
+
+	workflowRef := fmt.Sprintf("%s/.forgejo/workflows/%s@%s", repo.FullName(), run.WorkflowID, gitCtx.Ref)
+	jobWorkflowRef, err := jobWorkflowRef(ctx, task.Job, repo.FullName(), gitCtx.Ref, workflowRef)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	claims := &IDTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    IDTokenIssuer(),
+			Subject:   subject,
+			Audience:  jwt.ClaimStrings{audience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(idTokenExpiration)),
+			NotBefore: jwt.NewNumericDate(now),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ID:        uuid.NewString(),
+		},
+		Ref:                  gitCtx.Ref,
+		RefType:              gitCtx.RefType,
+		SHA:                  gitCtx.Sha,
+		Repository:           repo.FullName(),
+		RepositoryID:         strconv.FormatInt(repo.ID, 10),
+		RepositoryOwner:      repo.OwnerName,
+		RepositoryOwnerID:    strconv.FormatInt(repo.OwnerID, 10),
+		RepositoryVisibility: visibility.String(),
+		Workflow:             run.WorkflowID,
+		WorkflowRef:          workflowRef,
+		JobWorkflowRef:       jobWorkflowRef,
+		EventName:            gitCtx.EventName,
+		Environment:          environment,
+		HeadRef:              gitCtx.HeadRef,
+		BaseRef:              gitCtx.BaseRef,
+		RunID:                strconv.FormatInt(run.ID, 10),
+		RunNumber:            strconv.FormatInt(run.Index, 10),
+		RunAttempt:           strconv.FormatInt(task.Attempt, 10),
+		RunnerEnvironment:    "self-hosted",
+	}
+	if run.TriggerUser != nil {
+		claims.Actor = run.TriggerUser.Name
+		claims.ActorID = strconv.FormatInt(run.TriggerUser.ID, 10)
+	}
+	return claims, nil
+}
+
+// jobWorkflowRef returns the reference of the workflow defining a job:
+// the reusable workflow called by its parent job, or the workflow of the run.
+func jobWorkflowRef(ctx context.Context, job *actions_model.ActionRunJob, repoFullName, ref, workflowRef string) (string, error) {
+	if job.ParentJobID == 0 {
+		return workflowRef, nil
+	}
+	parent, err := actions_model.GetRunJobByID(ctx, job.ParentJobID)
+	if err != nil {
+		return "", err
+	}
+	if p, ok := strings.CutPrefix(parent.CalledWorkflow, "./"); ok {
+		return repoFullName + "/" + p + "@" + ref, nil
+	}
+	return parent.CalledWorkflow, nil
+}
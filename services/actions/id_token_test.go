@@ -0,0 +1,165 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package actions
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	actions_model "code.gitea.io/gitea/models/actions"
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/unittest"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/test"
+	"code.gitea.io/gitea/services/auth/source/oauth2"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/nektos/act/pkg/jobparser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateIDToken(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	signingKey, err := oauth2.CreateJWTSigningKey("RS256", key)
+	require.NoError(t, err)
+	defer test.MockVariableValue(&oauth2.DefaultSigningKey, signingKey)()
+	defer test.MockVariableValue(&setting.AppURL, "https://forgejo.example.com/")()
+
+	task := unittest.AssertExistsAndLoadBean(t, &actions_model.ActionTask{ID: 47})
+	require.NoError(t, task.LoadAttributes(db.DefaultContext))
+
+	// no ID token for the jobs which are not granted `id-token: write`
+	_, err = CreateIDToken(db.DefaultContext, task, "sts.example.com")
+	require.ErrorIs(t, err, ErrIDTokenUnavailable)
+
+	task.Job.IDTokenPermission = true
+	token, err := CreateIDToken(db.DefaultContext, task, "sts.example.com")
+	require.NoError(t, err)
+
+	claims := &IDTokenClaims{}
+	_, err = jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		return signingKey.VerifyKey(), nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "https://forgejo.example.com/api/actions", claims.Issuer)
+	assert.Equal(t, jwt.ClaimStrings{"sts.example.com"}, claims.Audience)
+	assert.Equal(t, "repo:user5/repo4:ref:refs/heads/master", claims.Subject)
+	assert.Equal(t, "user5/repo4", claims.Repository)
+	assert.Equal(t, "refs/heads/master", claims.Ref)
+	assert.Equal(t, "branch", claims.RefType)
+	assert.Equal(t, "artifact.yaml", claims.Workflow)
+	assert.Equal(t, "user5/repo4/.forgejo/workflows/artifact.yaml@refs/heads/master", claims.WorkflowRef)
+	assert.Equal(t, claims.WorkflowRef, claims.JobWorkflowRef)
+	assert.Equal(t, "791", claims.RunID)
+	assert.Equal(t, "187", claims.RunNumber)
+	assert.Equal(t, "push", claims.EventName)
+
+	// the audience defaults to the owner of the repository
+	token, err = CreateIDToken(db.DefaultContext, task, "")
+	require.NoError(t, err)
+	_, err = jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		return signingKey.VerifyKey(), nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, jwt.ClaimStrings{"https://forgejo.example.com/user5"}, claims.Audience)
+
+	// no ID token for the jobs of fork pull requests
+	task.Job.Run.IsForkPullRequest = true
+	task.Job.Run.TriggerEvent = "pull_request"
+	_, err = CreateIDToken(db.DefaultContext, task, "")
+	require.ErrorIs(t, err, ErrIDTokenUnavailable)
+}
+
+func TestAddIDTokenRequestEnv(t *testing.T) {
+	defer test.MockVariableValue(&setting.AppURL, "https://forgejo.example.com/")()
+
+	payload := []byte(`
+on: push
+env:
+  GREETING: hello
+jobs:
+  deploy:
+    runs-on: docker
+    steps:
+      - run: ./deploy.sh
+`)
+	task := &actions_model.ActionTask{ID: 1, JobID: 2, Job: &actions_model.ActionRunJob{RunID: 3}}
+	got, err := AddIDTokenRequestEnv(task, payload)
+	require.NoError(t, err)
+
+	workflows, err := jobparser.Parse(got)
+	require.NoError(t, err)
+	require.Len(t, workflows, 1)
+	env := workflows[0].Env
+	assert.Equal(t, "hello", env["GREETING"])
+	assert.Equal(t, "https://forgejo.example.com/api/actions/idtoken?api-version=2.0", env["ACTIONS_ID_TOKEN_REQUEST_URL"])
+	assert.NotEmpty(t, env["ACTIONS_ID_TOKEN_REQUEST_TOKEN"])
+}
+
+func TestReadIDTokenPermissions(t *testing.T) {
+	content := []byte(`
+on: push
+permissions:
+  id-token: write
+jobs:
+  granted:
+    runs-on: docker
+    steps:
+      - run: ./deploy.sh
+  none:
+    runs-on: docker
+    permissions:
+      id-token: none
+    steps:
+      - run: ./deploy.sh
+  read-all:
+    runs-on: docker
+    permissions: read-all
+    steps:
+      - run: ./deploy.sh
+`)
+	jobs, err := jobparser.Parse(content)
+	require.NoError(t, err)
+
+	granted, err := readIDTokenPermissions(content, jobs, nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"granted": true, "none": false, "read-all": false}, granted)
+
+	// the permission is not granted by default
+	content = []byte(`
+on: push
+jobs:
+  absent:
+    runs-on: docker
+    steps:
+      - run: ./deploy.sh
+  granted:
+    runs-on: docker
+    permissions:
+      contents: read
+      id-token: write
+    steps:
+      - run: ./deploy.sh
+`)
+	jobs, err = jobparser.Parse(content)
+	require.NoError(t, err)
+
+	granted, err = readIDTokenPermissions(content, jobs, nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"absent": false, "granted": true}, granted)
+
+	// the jobs of a called workflow inherit the permission of the calling job and can't be granted more
+	granted, err = readIDTokenPermissions(content, jobs, &actions_model.ActionRunJob{IDTokenPermission: true})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"absent": true, "granted": true}, granted)
+
+	granted, err = readIDTokenPermissions(content, jobs, &actions_model.ActionRunJob{})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"absent": false, "granted": false}, granted)
+}
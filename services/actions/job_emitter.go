@@ -10,6 +10,7 @@ import (
 
 	actions_model "code.gitea.io/gitea/models/actions"
 	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/modules/container"
 	"code.gitea.io/gitea/modules/graceful"
 	"code.gitea.io/gitea/modules/queue"
 
@@ -45,25 +46,85 @@ func jobEmitterQueueHandler(items ...*jobUpdate) []*jobUpdate {
 }
 
 func checkJobsOfRun(ctx context.Context, runID int64) error {
+	run, err := actions_model.GetRunByID(ctx, runID)
+	if err != nil {
+		return err
+	}
 	jobs, err := db.Find[actions_model.ActionRunJob](ctx, actions_model.FindRunJobOptions{RunID: runID})
 	if err != nil {
 		return err
 	}
+	if run.NeedApproval {
+		// the jobs will be checked again once the run is approved, unless it has been cancelled
+		return notifyWorkflowRunCompleted(ctx, run)
+	}
+
+	var vars map[string]string
+	var recheck bool // the jobs need to be checked again
 	if err := db.WithTx(ctx, func(ctx context.Context) error {
-		idToJobs := make(map[string][]*actions_model.ActionRunJob, len(jobs))
-		for _, job := range jobs {
-			idToJobs[job.JobID] = append(idToJobs[job.JobID], job)
+		if run.Status.IsBlocked() {
+			// a run which hasn't started yet waits for the other runs of its concurrency group
+			if blocked, err := actions_model.ShouldBlockRunByConcurrency(ctx, run); err != nil {
+				return err
+			} else if blocked {
+				return nil
+			}
 		}
 
 		updates := newJobStatusResolver(jobs).Resolve()
 		for _, job := range jobs {
-			if status, ok := updates[job.ID]; ok {
-				job.Status = status
-				if n, err := actions_model.UpdateRunJob(ctx, job, builder.Eq{"status": actions_model.StatusBlocked}, "status"); err != nil {
+			status, ok := updates[job.ID]
+			if !ok {
+				continue
+			}
+			cols := []string{"status"}
+			if status == actions_model.StatusWaiting && job.RawConcurrency != "" {
+				cols = append(cols, "is_concurrency_evaluated", "concurrency_group", "concurrency_cancel")
+				blocked, err := checkJobConcurrency(ctx, run, job, jobs, &vars)
+				if err != nil {
 					return err
-				} else if n != 1 {
-					return fmt.Errorf("no affected for updating blocked job %v", job.ID)
 				}
+				if blocked {
+					// keep the job blocked until the other jobs of its concurrency group are done
+					if _, err := actions_model.UpdateRunJob(ctx, job, builder.Eq{"status": actions_model.StatusBlocked}, cols[1:]...); err != nil {
+						return err
+					}
+					continue
+				}
+			}
+			if status == actions_model.StatusWaiting && job.RawEnvironment != "" && !job.IsCallingWorkflow() {
+				// a job targeting an environment waits for the protection rules of the environment
+				if err := loadRunVars(ctx, run, &vars); err != nil {
+					return err
+				}
+				checked, err := checkJobEnvironment(ctx, run, job, jobs, vars)
+				if err != nil {
+					return err
+				}
+				status = checked
+				cols = append(cols, "environment_id", "deployment_id", "started", "stopped")
+				// the jobs needing a job which can't be deployed are resolved
+				recheck = recheck || status.IsDone()
+			}
+			if status == actions_model.StatusWaiting && job.IsCallingWorkflow() {
+				// a job calling a reusable workflow never waits for a runner, it runs the jobs of the called workflow
+				if err := loadRunVars(ctx, run, &vars); err != nil {
+					return err
+				}
+				expanded, err := expandWorkflowCall(ctx, run, job, jobs, vars)
+				if err != nil {
+					return err
+				}
+				status = expanded
+				cols = append(cols, "started", "stopped", "call_outputs", "call_secrets", "call_inherit_secrets")
+				// the jobs of the called workflows are checked, and so are the jobs needing a job which couldn't call its workflow
+				recheck = recheck || status == actions_model.StatusRunning || status.IsDone()
+			}
+			job.Status = status
+			if n, err := actions_model.UpdateRunJob(ctx, job, builder.Eq{"status": actions_model.StatusBlocked}, cols...); err != nil {
+				return err
+			} else if n != 1 {
+				return fmt.Errorf("no affected for updating blocked job %v", job.ID)
 			}
 		}
 		return nil
@@ -71,6 +132,87 @@ func checkJobsOfRun(ctx context.Context, runID int64) error {
 		return err
 	}
 	CreateCommitStatus(ctx, jobs...)
+
+	if recheck {
+		if err := EmitJobsIfReady(runID); err != nil {
+			return err
+		}
+	}
+
+	// the status of the run may have been updated with the status of its jobs
+	run, err = actions_model.GetRunByID(ctx, runID)
+	if err != nil {
+		return err
+	}
+	if err := notifyWorkflowRunCompleted(ctx, run); err != nil {
+		return err
+	}
+
+	return emitJobsBlockedByConcurrency(ctx, run, jobs)
+}
+
+// loadRunVars loads the variables of a run once for all the expressions evaluated by the job emitter.
+func loadRunVars(ctx context.Context, run *actions_model.ActionRun, vars *map[string]string) error {
+	if *vars != nil {
+		return nil
+	}
+	if err := run.LoadAttributes(ctx); err != nil {
+		return err
+	}
+	v, err := actions_model.GetVariablesOfRun(ctx, run)
+	if err != nil {
+		return err
+	}
+	*vars = v
+	return nil
+}
+
+// checkJobConcurrency evaluates the concurrency of a job if it hasn't been evaluated yet,
+// cancels the other jobs of its concurrency group if needed and returns whether the job has to wait.
+func checkJobConcurrency(ctx context.Context, run *actions_model.ActionRun, job *actions_model.ActionRunJob, jobs []*actions_model.ActionRunJob, vars *map[string]string) (bool, error) {
+	if !job.IsConcurrencyEvaluated {
+		if err := loadRunVars(ctx, run, vars); err != nil {
+			return false, err
+		}
+		if err := evaluateJobConcurrency(ctx, run, job, jobs, *vars); err != nil {
+			return false, err
+		}
+		if err := actions_model.CancelConcurrentJobs(ctx, job); err != nil {
+			return false, err
+		}
+	}
+	return actions_model.ShouldBlockJobByConcurrency(ctx, job)
+}
+
+// emitJobsBlockedByConcurrency checks the runs held back by the concurrency groups
+// of a run or of its jobs once the run or the jobs are done.
+func emitJobsBlockedByConcurrency(ctx context.Context, run *actions_model.ActionRun, jobs []*actions_model.ActionRunJob) error {
+	runGroup := ""
+	if run.Status.IsDone() {
+		runGroup = run.ConcurrencyGroup
+	}
+	jobGroups := make(container.Set[string])
+	for _, job := range jobs {
+		if job.ConcurrencyGroup != "" && job.Status.IsDone() {
+			jobGroups.Add(job.ConcurrencyGroup)
+		}
+	}
+	if runGroup == "" && len(jobGroups) == 0 {
+		return nil
+	}
+
+	runIDs, err := actions_model.FindRunIDsBlockedByConcurrency(ctx, run.RepoID, runGroup, jobGroups.Values())
+	if err != nil {
+		return err
+	}
+	for _, id := range runIDs {
+		if id == run.ID {
+			continue
+		}
+		if err := EmitJobsIfReady(id); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
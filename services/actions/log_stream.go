@@ -0,0 +1,155 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package actions
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	actions_model "code.gitea.io/gitea/models/actions"
+	actions_module "code.gitea.io/gitea/modules/actions"
+	"code.gitea.io/gitea/modules/eventsource"
+	"code.gitea.io/gitea/modules/log"
+
+	runnerv1 "code.gitea.io/actions-proto-go/runner/v1"
+)
+
+// The logs of a job are pushed to the viewers of its page as the runner sends them, so they don't need to poll them.
+// The rows sent by the runner are buffered in memory while the job has viewers, and they are pushed once the runner
+// has reported which step they belong to. The streams only live in the process which receives the logs from the runner,
+// the viewers connected to another process, or whose messages are dropped, poll the logs instead.
+
+// LogStreamEvent is the name of the events of the log streams
+const LogStreamEvent = "logs"
+
+// StreamedStepLog are the lines appended to the log of a step, in the format of the logs polled by the viewers
+type StreamedStepLog struct {
+	Step    int                    `json:"step"`
+	Cursor  int64                  `json:"cursor"` // the number of lines of the step before these lines
+	Lines   []*StreamedStepLogLine `json:"lines"`
+	Started int64                  `json:"started"`
+}
+
+type StreamedStepLogLine struct {
+	Index     int64   `json:"index"`
+	Message   string  `json:"message"`
+	Timestamp float64 `json:"timestamp"`
+}
+
+type logStream struct {
+	messenger *eventsource.Messenger
+	taskID    int64
+	start     int64 // the index in the log of the task of the first buffered row
+	rows      []*runnerv1.LogRow
+	published map[int]int64 // the number of lines of each step which have been pushed
+}
+
+var logStreams = struct {
+	sync.Mutex
+	streams map[int64]*logStream
+}{streams: map[int64]*logStream{}}
+
+// SubscribeJobLogs returns the channel of the events of the logs of a job.
+func SubscribeJobLogs(jobID int64) <-chan *eventsource.Event {
+	logStreams.Lock()
+	defer logStreams.Unlock()
+	stream, ok := logStreams.streams[jobID]
+	if !ok {
+		stream = &logStream{messenger: eventsource.NewMessenger(jobID), start: -1}
+		logStreams.streams[jobID] = stream
+	}
+	return stream.messenger.Register()
+}
+
+// UnsubscribeJobLogs closes a channel returned by SubscribeJobLogs, the stream of the job is removed with its last viewer.
+func UnsubscribeJobLogs(jobID int64, channel <-chan *eventsource.Event) {
+	logStreams.Lock()
+	defer logStreams.Unlock()
+	stream, ok := logStreams.streams[jobID]
+	if !ok {
+		return
+	}
+	if stream.messenger.Unregister(channel) {
+		delete(logStreams.streams, jobID)
+	}
+}
+
+// StreamTaskLogs pushes the new lines of the steps of a task to the viewers of its job.
+// It must be called after the rows from the index are appended to the log of the task, or after its state is updated without rows.
+func StreamTaskLogs(ctx context.Context, task *actions_model.ActionTask, index int64, rows []*runnerv1.LogRow) {
+	logStreams.Lock()
+	defer logStreams.Unlock()
+	stream, ok := logStreams.streams[task.JobID]
+	if !ok {
+		return
+	}
+
+	if stream.taskID != task.ID {
+		// the job has been rerun
+		stream.taskID = task.ID
+		stream.start = -1
+		stream.rows = nil
+		stream.published = map[int]int64{}
+	}
+	if len(rows) > 0 {
+		if stream.start < 0 || index != stream.start+int64(len(stream.rows)) {
+			// the previous rows have been sent to another process, the buffer restarts from these rows
+			stream.start = index
+			stream.rows = nil
+		}
+		stream.rows = append(stream.rows, rows...)
+	}
+	if stream.start < 0 {
+		return
+	}
+
+	steps := task.Steps
+	if steps == nil {
+		var err error
+		if steps, err = actions_model.GetTaskStepsByTaskID(ctx, task.ID); err != nil {
+			log.Error("Unable to get the steps of task %d: %v", task.ID, err)
+			return
+		}
+	}
+	t := *task
+	t.Steps = steps
+
+	var logs []*StreamedStepLog
+	end := stream.start + int64(len(stream.rows))
+	for i, step := range actions_module.FullSteps(&t) {
+		from := max(step.LogIndex+stream.published[i], stream.start)
+		to := min(step.LogIndex+step.LogLength, end)
+		if from >= to {
+			continue
+		}
+		stepLog := &StreamedStepLog{
+			Step:    i,
+			Cursor:  from - step.LogIndex,
+			Lines:   make([]*StreamedStepLogLine, 0, to-from),
+			Started: int64(step.Started),
+		}
+		for j, row := range stream.rows[from-stream.start : to-stream.start] {
+			stepLog.Lines = append(stepLog.Lines, &StreamedStepLogLine{
+				Index:     stepLog.Cursor + int64(j) + 1, // start at 1
+				Message:   row.Content,
+				Timestamp: float64(row.Time.AsTime().UnixNano()) / float64(time.Second),
+			})
+		}
+		stream.published[i] = to - step.LogIndex
+		logs = append(logs, stepLog)
+
+		// the rows of the previous steps are never pushed again
+		stream.rows = stream.rows[to-stream.start:]
+		stream.start = to
+	}
+
+	if len(logs) > 0 || len(rows) == 0 {
+		// the viewers refresh the state of the job when they receive an event
+		stream.messenger.SendMessage(&eventsource.Event{
+			Name: LogStreamEvent,
+			Data: map[string]any{"taskId": task.ID, "stepsLog": logs},
+		})
+	}
+}
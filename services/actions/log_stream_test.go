@@ -0,0 +1,79 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package actions
+
+import (
+	"testing"
+	"time"
+
+	actions_model "code.gitea.io/gitea/models/actions"
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/modules/eventsource"
+
+	runnerv1 "code.gitea.io/actions-proto-go/runner/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestStreamTaskLogs(t *testing.T) {
+	ctx := db.DefaultContext
+	newRows := func(messages ...string) []*runnerv1.LogRow {
+		rows := make([]*runnerv1.LogRow, 0, len(messages))
+		for _, message := range messages {
+			rows = append(rows, &runnerv1.LogRow{Time: timestamppb.New(time.Unix(1700000000, 0)), Content: message})
+		}
+		return rows
+	}
+	receive := func(t *testing.T, channel <-chan *eventsource.Event) []*StreamedStepLog {
+		t.Helper()
+		select {
+		case event := <-channel:
+			assert.Equal(t, LogStreamEvent, event.Name)
+			return event.Data.(map[string]any)["stepsLog"].([]*StreamedStepLog)
+		default:
+			require.FailNow(t, "no event")
+			return nil
+		}
+	}
+
+	// the job has no viewers
+	task := &actions_model.ActionTask{ID: 1001, JobID: 2001, Steps: []*actions_model.ActionTaskStep{}}
+	StreamTaskLogs(ctx, task, 0, newRows("ignored"))
+
+	channel := SubscribeJobLogs(task.JobID)
+
+	task.LogLength = 2
+	StreamTaskLogs(ctx, task, 0, newRows("a", "b"))
+	logs := receive(t, channel)
+	require.Len(t, logs, 1)
+	assert.Equal(t, 0, logs[0].Step)
+	assert.EqualValues(t, 0, logs[0].Cursor)
+	require.Len(t, logs[0].Lines, 2)
+	assert.EqualValues(t, 1, logs[0].Lines[0].Index)
+	assert.Equal(t, "b", logs[0].Lines[1].Message)
+
+	task.LogLength = 3
+	StreamTaskLogs(ctx, task, 2, newRows("c"))
+	logs = receive(t, channel)
+	require.Len(t, logs, 1)
+	assert.EqualValues(t, 2, logs[0].Cursor)
+	assert.Equal(t, "c", logs[0].Lines[0].Message)
+
+	// the state is updated without new lines
+	StreamTaskLogs(ctx, task, 0, nil)
+	assert.Empty(t, receive(t, channel))
+
+	// the previous rows have been sent to another process
+	task.LogLength = 6
+	StreamTaskLogs(ctx, task, 5, newRows("f"))
+	logs = receive(t, channel)
+	require.Len(t, logs, 1)
+	assert.EqualValues(t, 5, logs[0].Cursor)
+
+	UnsubscribeJobLogs(task.JobID, channel)
+	_, ok := <-channel
+	assert.False(t, ok)
+	assert.NotContains(t, logStreams.streams, task.JobID)
+}
@@ -6,12 +6,51 @@ package actions
 import (
 	"testing"
 
+	actions_model "code.gitea.io/gitea/models/actions"
+	"code.gitea.io/gitea/models/db"
+	repo_model "code.gitea.io/gitea/models/repo"
 	"code.gitea.io/gitea/models/unittest"
 
-	_ "code.gitea.io/gitea/models/actions"
 	_ "code.gitea.io/gitea/models/activities"
+
+	"github.com/stretchr/testify/require"
 )
 
 func TestMain(m *testing.M) {
 	unittest.MainTest(m)
 }
+
+// insertRun inserts a run of the repository and its job, if job isn't nil. The fields which aren't set
+// are filled like the run fixtures, for a commit of the default branch triggered by the owner of the repository.
+func insertRun(t *testing.T, repo *repo_model.Repository, run *actions_model.ActionRun, job *actions_model.ActionRunJob) (*actions_model.ActionRun, *actions_model.ActionRunJob) {
+	t.Helper()
+
+	run.RepoID = repo.ID
+	run.OwnerID = repo.OwnerID
+	if run.TriggerUserID == 0 {
+		run.TriggerUserID = repo.OwnerID
+	}
+	if run.Ref == "" {
+		run.Ref = "refs/heads/" + repo.DefaultBranch
+	}
+	if run.CommitSHA == "" {
+		run.CommitSHA = "c2d72f548424103f01ee1dc02889c1e2bff816b0"
+	}
+	require.NoError(t, db.Insert(db.DefaultContext, run))
+	if job == nil {
+		return run, nil
+	}
+
+	job.RunID = run.ID
+	job.RepoID = repo.ID
+	job.OwnerID = repo.OwnerID
+	job.CommitSHA = run.CommitSHA
+	if job.Name == "" {
+		job.Name = job.JobID
+	}
+	if job.Status == actions_model.StatusUnknown {
+		job.Status = run.Status
+	}
+	require.NoError(t, db.Insert(db.DefaultContext, job))
+	return run, job
+}
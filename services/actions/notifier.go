@@ -6,6 +6,7 @@ package actions
 import (
 	"context"
 
+	actions_model "code.gitea.io/gitea/models/actions"
 	issues_model "code.gitea.io/gitea/models/issues"
 	packages_model "code.gitea.io/gitea/models/packages"
 	perm_model "code.gitea.io/gitea/models/perm"
@@ -753,3 +754,34 @@ func (n *actionsNotifier) MigrateRepository(ctx context.Context, doer, u *user_m
 		Sender:       convert.ToUser(ctx, doer, nil),
 	}).Notify(ctx)
 }
+
+// WorkflowRunStatusUpdate triggers the workflows chained to a run by the workflow_run event when the run is requested or completed
+func (n *actionsNotifier) WorkflowRunStatusUpdate(ctx context.Context, repo *repo_model.Repository, sender *user_model.User, run *actions_model.ActionRun) {
+	ctx = withMethod(ctx, "WorkflowRunStatusUpdate")
+
+	action := api.HookWorkflowRunRequested
+	if run.Status.IsDone() {
+		action = api.HookWorkflowRunCompleted
+	}
+
+	if length, err := workflowRunChainLength(ctx, run); err != nil {
+		log.Error("workflowRunChainLength: %v", err)
+		return
+	} else if length >= maxWorkflowRunChainLength {
+		log.Trace("run %d can't trigger workflow_run events, it is chained to %d runs", run.ID, length-1)
+		return
+	}
+
+	workflowRun, err := convert.ToActionWorkflowRun(ctx, run)
+	if err != nil {
+		log.Error("ToActionWorkflowRun: %v", err)
+		return
+	}
+
+	newNotifyInput(repo, sender, webhook_module.HookEventWorkflowRun).WithPayload(&api.WorkflowRunPayload{
+		Action:      action,
+		WorkflowRun: workflowRun,
+		Repository:  convert.ToRepo(ctx, repo, access_model.Permission{AccessMode: perm_model.AccessModeOwner}),
+		Sender:      convert.ToUser(ctx, sender, nil),
+	}).Notify(ctx)
+}
@@ -117,10 +117,11 @@ func (input *notifyInput) Notify(ctx context.Context) {
 }
 
 func notify(ctx context.Context, input *notifyInput) error {
-	if input.Doer.IsActions() {
+	if input.Doer.IsActions() && input.Event != webhook_module.HookEventWorkflowRun {
 		// avoiding triggering cyclically, for example:
 		// a comment of an issue will trigger the runner to add a new comment as reply,
 		// and the new comment will trigger the runner again.
+		// The workflow_run event, emitted by the runs of the scheduled workflows too, limits the length of the chains of runs instead.
 		log.Debug("ignore executing %v for event %v whose doer is %v", getMethod(ctx), input.Event, input.Doer.Name)
 		return nil
 	}
@@ -369,7 +370,7 @@ func handleWorkflows(
 			}
 		}
 
-		if err := actions_model.InsertRun(ctx, run, jobs); err != nil {
+		if err := InsertRun(ctx, run, dwf.Content, jobs, vars); err != nil {
 			log.Error("InsertRun: %v", err)
 			continue
 		}
@@ -452,6 +453,23 @@ func ifNeedApproval(ctx context.Context, run *actions_model.ActionRun, repo *rep
 		return false, nil
 	}
 
+	// always need approval if the repository requires it for all the pull requests from forks
+	if err := repo.LoadUnits(ctx); err != nil {
+		return false, fmt.Errorf("LoadUnits: %w", err)
+	}
+	if repo.MustGetUnit(ctx, unit_model.TypeActions).ActionsConfig().GetApprovalPolicy() == repo_model.ActionsApprovalAllForks {
+		log.Trace("need approval because repo %d requires it for all the pull requests from forks", repo.ID)
+		return true, nil
+	}
+
+	// don't need approval if the user has contributed to the repository before
+	if merged, err := issues_model.HasMergedPullRequestInRepo(ctx, repo.ID, user.ID); err != nil {
+		return false, fmt.Errorf("HasMergedPullRequestInRepo: %w", err)
+	} else if merged {
+		log.Trace("do not need approval because user %d has a merged pull request", user.ID)
+		return false, nil
+	}
+
 	// don't need approval if the user has been approved before
 	if count, err := db.Count[actions_model.ActionRun](ctx, actions_model.FindRunOptions{
 		RepoID:        repo.ID,
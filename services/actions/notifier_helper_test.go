@@ -8,10 +8,16 @@ import (
 
 	actions_model "code.gitea.io/gitea/models/actions"
 	"code.gitea.io/gitea/models/db"
+	issues_model "code.gitea.io/gitea/models/issues"
+	repo_model "code.gitea.io/gitea/models/repo"
+	unit_model "code.gitea.io/gitea/models/unit"
 	"code.gitea.io/gitea/models/unittest"
+	user_model "code.gitea.io/gitea/models/user"
+	actions_module "code.gitea.io/gitea/modules/actions"
 	webhook_module "code.gitea.io/gitea/modules/webhook"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func Test_SkipPullRequestEvent(t *testing.T) {
@@ -48,3 +54,39 @@ func Test_SkipPullRequestEvent(t *testing.T) {
 	unittest.AssertSuccessfulInsert(t, run)
 	assert.True(t, SkipPullRequestEvent(db.DefaultContext, webhook_module.HookEventPullRequestSync, repoID, commitSHA))
 }
+
+func Test_ifNeedApproval(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+	ctx := db.DefaultContext
+	repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 1})
+	owner := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2})
+	contributor := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 4})
+	run := &actions_model.ActionRun{
+		IsForkPullRequest: true,
+		TriggerEvent:      actions_module.GithubEventPullRequest,
+	}
+
+	needApproval := func(run *actions_model.ActionRun, user *user_model.User) bool {
+		need, err := ifNeedApproval(ctx, run, repo, user)
+		require.NoError(t, err)
+		return need
+	}
+
+	assert.True(t, needApproval(run, contributor))
+	assert.False(t, needApproval(run, owner))
+	assert.False(t, needApproval(&actions_model.ActionRun{IsForkPullRequest: true, TriggerEvent: actions_module.GithubEventPullRequestTarget}, contributor))
+
+	// the contributor has a merged pull request
+	issue := &issues_model.Issue{RepoID: repo.ID, Index: 1000, PosterID: contributor.ID, IsPull: true, Title: "merged"}
+	require.NoError(t, db.Insert(ctx, issue))
+	require.NoError(t, db.Insert(ctx, &issues_model.PullRequest{IssueID: issue.ID, BaseRepoID: repo.ID, HeadRepoID: repo.ID, HasMerged: true}))
+	assert.False(t, needApproval(run, contributor))
+
+	// all the pull requests from forks need an approval
+	actionsUnit := repo.MustGetUnit(ctx, unit_model.TypeActions)
+	actionsUnit.ActionsConfig().ApprovalPolicy = repo_model.ActionsApprovalAllForks
+	require.NoError(t, repo_model.UpdateRepoUnit(ctx, actionsUnit))
+	repo = unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 1})
+	assert.True(t, needApproval(run, contributor))
+	assert.False(t, needApproval(run, owner))
+}
@@ -0,0 +1,325 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package actions
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+
+	actions_model "code.gitea.io/gitea/models/actions"
+	"code.gitea.io/gitea/models/organization"
+	access_model "code.gitea.io/gitea/models/perm/access"
+	repo_model "code.gitea.io/gitea/models/repo"
+	"code.gitea.io/gitea/models/unit"
+	actions_module "code.gitea.io/gitea/modules/actions"
+	"code.gitea.io/gitea/modules/container"
+	"code.gitea.io/gitea/modules/gitrepo"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"github.com/nektos/act/pkg/jobparser"
+	"github.com/nektos/act/pkg/model"
+)
+
+// maxWorkflowCallDepth is the maximum nesting of reusable workflows, as on GitHub.
+const maxWorkflowCallDepth = 4
+
+// expandWorkflowCall inserts the jobs of the reusable workflow called by a job which is about to run,
+// and returns the new status of the calling job: running with its children, skipped if its `if` is false,
+// or failure if the called workflow can't be loaded or doesn't accept the inputs.
+func expandWorkflowCall(ctx context.Context, run *actions_model.ActionRun, job *actions_model.ActionRunJob, jobs []*actions_model.ActionRunJob, vars map[string]string) (actions_model.Status, error) {
+	if err := run.LoadAttributes(ctx); err != nil {
+		return actions_model.StatusUnknown, err
+	}
+
+	singleWorkflow, id, wfJob, err := parseJobPayload(job)
+	if err != nil {
+		return actions_model.StatusUnknown, err
+	}
+
+	results, err := jobNeedsResults(ctx, job, jobs)
+	if err != nil {
+		return actions_model.StatusUnknown, err
+	}
+	gitCtx := generateGitContext(run)
+	// the inputs of the workflow the calling job belongs to, if it's a called workflow too
+	inputs := actions_module.ReadWorkflowCallInputs(singleWorkflow.Env)
+
+	now := timeutil.TimeStampNow()
+	fail := func(format string, a ...any) (actions_model.Status, error) {
+		log.Warn("Cannot call the reusable workflow %q of job %d: %s", job.CalledWorkflow, job.ID, fmt.Sprintf(format, a...))
+		job.Started = now
+		job.Stopped = now
+		return actions_model.StatusFailure, nil
+	}
+
+	if wfJob.If.Value != "" {
+		ok, err := actions_module.EvaluateJobIf(id, wfJob, gitCtx, results, vars, inputs)
+		if err != nil {
+			return fail("%v", err)
+		}
+		if !ok {
+			return actions_model.StatusSkipped, nil
+		}
+	}
+
+	if depth := workflowCallDepth(job, jobs); depth > maxWorkflowCallDepth {
+		return fail("more than %d nested reusable workflows", maxWorkflowCallDepth)
+	}
+
+	content, err := readCalledWorkflow(ctx, run, gitCtx.Sha, job.CalledWorkflow)
+	if err != nil {
+		return fail("%v", err)
+	}
+
+	workflow, err := model.ReadWorkflow(bytes.NewReader(content))
+	if err != nil {
+		return fail("%v", err)
+	}
+	config := workflow.WorkflowCallConfig()
+	callInputs, err := actions_module.EvaluateWorkflowCallInputs(config, id, wfJob, gitCtx, results, vars, inputs)
+	if err != nil {
+		return fail("%v", err)
+	}
+
+	children, err := jobparser.Parse(content, jobparser.WithVars(vars), jobparser.WithGitContext(gitCtx))
+	if err != nil {
+		return fail("%v", err)
+	}
+	inputsEnv := actions_module.WorkflowCallInputsEnv(callInputs)
+	for _, child := range children {
+		env := maps.Clone(child.Env)
+		if env == nil {
+			env = make(map[string]string, len(inputsEnv))
+		}
+		maps.Copy(env, inputsEnv)
+		child.Env = env
+	}
+
+	_, childrenRawConcurrency, err := actions_module.ReadWorkflowRawConcurrency(content)
+	if err != nil {
+		return fail("%v", err)
+	}
+	rawConcurrency := make(map[string]string, len(childrenRawConcurrency))
+	for id, rc := range childrenRawConcurrency {
+		rawConcurrency[id] = rc.Marshal()
+	}
+
+	rawEnvironment, err := readRawEnvironments(content)
+	if err != nil {
+		return fail("%v", err)
+	}
+
+	idTokenPermission, err := readIDTokenPermissions(content, children, job)
+	if err != nil {
+		return fail("%v", err)
+	}
+
+	if _, err := actions_model.InsertChildJobs(ctx, job, children, rawConcurrency, rawEnvironment, idTokenPermission); err != nil {
+		return actions_model.StatusUnknown, fmt.Errorf("InsertChildJobs: %w", err)
+	}
+
+	job.CallOutputs = make(map[string]string, len(config.Outputs))
+	for name, output := range config.Outputs {
+		job.CallOutputs[name] = output.Value
+	}
+	callerJob := &model.Job{RawSecrets: wfJob.RawSecrets}
+	job.CallInheritSecrets = callerJob.InheritSecrets()
+	if !job.CallInheritSecrets {
+		job.CallSecrets = callerJob.Secrets()
+	}
+	job.Started = now
+	return actions_model.StatusRunning, nil
+}
+
+// checkCalledWorkflowAccess checks that the workflows of a repository can be called by the workflows of the caller repository,
+// like the actions checking out the code of another repository: the owner of the caller repository must see the owner
+// of the called repository, and be allowed to read its code.
+func checkCalledWorkflowAccess(ctx context.Context, caller, called *repo_model.Repository) error {
+	if err := caller.LoadOwner(ctx); err != nil {
+		return err
+	}
+	if err := called.LoadOwner(ctx); err != nil {
+		return err
+	}
+
+	if !organization.HasOrgOrUserVisible(ctx, called.Owner, caller.Owner) {
+		return fmt.Errorf("the repository %s is not accessible by %s", called.FullName(), caller.FullName())
+	}
+	perm, err := access_model.GetUserRepoPermission(ctx, called, caller.Owner)
+	if err != nil {
+		return err
+	}
+	if !perm.CanRead(unit.TypeCode) {
+		return fmt.Errorf("the repository %s is not accessible by %s", called.FullName(), caller.FullName())
+	}
+	return nil
+}
+
+// parseJobPayload returns the single workflow of a job, with the ID and the definition of the job.
+func parseJobPayload(job *actions_model.ActionRunJob) (*jobparser.SingleWorkflow, string, *jobparser.Job, error) {
+	singleWorkflows, err := jobparser.Parse(job.WorkflowPayload)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("jobparser.Parse: %w", err)
+	}
+	if len(singleWorkflows) != 1 {
+		return nil, "", nil, fmt.Errorf("the workflow payload of job %d has %d jobs", job.ID, len(singleWorkflows))
+	}
+	id, wfJob := singleWorkflows[0].Job()
+	if wfJob == nil {
+		return nil, "", nil, fmt.Errorf("the workflow payload of job %d has no job", job.ID)
+	}
+	return singleWorkflows[0], id, wfJob, nil
+}
+
+// readCalledWorkflow returns the content of a reusable workflow, read at the given commit for a workflow
+// of the repository of the run. The workflows of other repositories can be called if the owner of the repository
+// of the run can read their code, see checkCalledWorkflowAccess.
+func readCalledWorkflow(ctx context.Context, run *actions_model.ActionRun, sha, uses string) ([]byte, error) {
+	called, err := actions_module.ParseReusableWorkflowUses(uses)
+	if err != nil {
+		return nil, err
+	}
+
+	repo := run.Repo
+	ref := sha
+	if !called.IsLocal() {
+		repo, err = repo_model.GetRepositoryByOwnerAndName(ctx, called.OwnerName, called.RepoName)
+		if err != nil {
+			return nil, fmt.Errorf("repository %s/%s: %w", called.OwnerName, called.RepoName, err)
+		}
+		if err := checkCalledWorkflowAccess(ctx, run.Repo, repo); err != nil {
+			return nil, err
+		}
+		ref = called.Ref
+	}
+
+	gitRepo, err := gitrepo.OpenRepository(ctx, repo)
+	if err != nil {
+		return nil, fmt.Errorf("gitrepo.OpenRepository: %w", err)
+	}
+	defer gitRepo.Close()
+
+	if !called.IsLocal() {
+		if ref, err = gitRepo.ExpandRef(ref); err != nil {
+			return nil, err
+		}
+	}
+	commit, err := gitRepo.GetCommit(ref)
+	if err != nil {
+		return nil, fmt.Errorf("GetCommit: %w", err)
+	}
+	content, err := commit.GetFileContent(called.Path, 0)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", called.Path, err)
+	}
+
+	events, err := actions_module.GetEventsFromContent([]byte(content))
+	if err != nil {
+		return nil, fmt.Errorf("invalid workflow %s: %w", called.Path, err)
+	}
+	for _, evt := range events {
+		if evt.Name == actions_module.GithubEventWorkflowCall {
+			return []byte(content), nil
+		}
+	}
+	return nil, fmt.Errorf("the workflow %s isn't triggered by %s", called.Path, actions_module.GithubEventWorkflowCall)
+}
+
+// workflowCallDepth returns the nesting level of the workflow called by a job.
+func workflowCallDepth(job *actions_model.ActionRunJob, jobs []*actions_model.ActionRunJob) int {
+	parents := make(map[int64]int64, len(jobs))
+	for _, j := range jobs {
+		parents[j.ID] = j.ParentJobID
+	}
+	depth := 1
+	for id := job.ParentJobID; id > 0; id = parents[id] {
+		depth++
+	}
+	return depth
+}
+
+// JobIDPrefix returns the prefix of the job IDs of the called workflow a job belongs to.
+func JobIDPrefix(job *actions_model.ActionRunJob) string {
+	if job.ParentJobID == 0 {
+		return ""
+	}
+	return job.JobID[:strings.LastIndex(job.JobID, "/")+1]
+}
+
+// jobNeedsResults returns the results of a job and of its needs, by job ID as written in the workflow of the job.
+func jobNeedsResults(ctx context.Context, job *actions_model.ActionRunJob, jobs []*actions_model.ActionRunJob) (map[string]*jobparser.JobResult, error) {
+	prefix := JobIDPrefix(job)
+	trimNeeds := func(needs []string) []string {
+		ret := make([]string, 0, len(needs))
+		for _, need := range needs {
+			ret = append(ret, strings.TrimPrefix(need, prefix))
+		}
+		return ret
+	}
+
+	needs := container.SetOf(job.Needs...)
+	results := make(map[string]*jobparser.JobResult, len(needs)+1)
+	results[strings.TrimPrefix(job.JobID, prefix)] = &jobparser.JobResult{Needs: trimNeeds(job.Needs)}
+	for _, j := range jobs {
+		if !needs.Contains(j.JobID) {
+			continue
+		}
+		outputs, err := FindJobOutputs(ctx, j, jobs)
+		if err != nil {
+			return nil, err
+		}
+		results[strings.TrimPrefix(j.JobID, prefix)] = &jobparser.JobResult{
+			Needs:   trimNeeds(j.Needs),
+			Result:  j.Status.String(),
+			Outputs: outputs,
+		}
+	}
+	return results, nil
+}
+
+// FindJobOutputs returns the outputs of a job of a run: the outputs of its latest task,
+// or the outputs of the called workflow for a job calling a reusable workflow.
+func FindJobOutputs(ctx context.Context, job *actions_model.ActionRunJob, jobs []*actions_model.ActionRunJob) (map[string]string, error) {
+	outputs := map[string]string{}
+	if job.IsCallingWorkflow() {
+		if len(job.CallOutputs) == 0 {
+			return outputs, nil
+		}
+		prefix := job.JobID + "/"
+		childrenOutputs := map[string]map[string]string{}
+		for _, j := range jobs {
+			if j.ParentJobID != job.ID {
+				continue
+			}
+			o, err := FindJobOutputs(ctx, j, jobs)
+			if err != nil {
+				return nil, err
+			}
+			// the jobs of a matrix share the same job ID
+			id := strings.TrimPrefix(j.JobID, prefix)
+			if childrenOutputs[id] == nil {
+				childrenOutputs[id] = o
+			} else {
+				maps.Copy(childrenOutputs[id], o)
+			}
+		}
+		return actions_module.EvaluateWorkflowCallOutputs(job.CallOutputs, childrenOutputs), nil
+	}
+
+	if job.TaskID == 0 {
+		return outputs, nil
+	}
+	taskOutputs, err := actions_model.FindTaskOutputByTaskID(ctx, job.TaskID)
+	if err != nil {
+		return nil, fmt.Errorf("FindTaskOutputByTaskID: %w", err)
+	}
+	for _, o := range taskOutputs {
+		outputs[o.OutputKey] = o.OutputValue
+	}
+	return outputs, nil
+}
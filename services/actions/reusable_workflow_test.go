@@ -0,0 +1,66 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package actions
+
+import (
+	"testing"
+
+	actions_model "code.gitea.io/gitea/models/actions"
+	"code.gitea.io/gitea/models/db"
+	repo_model "code.gitea.io/gitea/models/repo"
+	"code.gitea.io/gitea/models/unittest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkflowCallJobs(t *testing.T) {
+	caller := &actions_model.ActionRunJob{ID: 1, JobID: "release", CalledWorkflow: "./.forgejo/workflows/release.yml", CallOutputs: map[string]string{
+		"version": "${{ jobs.build.outputs.version }}",
+	}}
+	nestedCaller := &actions_model.ActionRunJob{ID: 2, ParentJobID: 1, JobID: "release/build", CalledWorkflow: "./.forgejo/workflows/build.yml", CallOutputs: map[string]string{
+		"version": "1.${{ jobs.compile.outputs.minor }}",
+	}}
+	nested := &actions_model.ActionRunJob{ID: 3, ParentJobID: 2, JobID: "release/build/compile", Needs: []string{"release/build/setup"}}
+	jobs := []*actions_model.ActionRunJob{caller, nestedCaller, nested}
+
+	assert.Empty(t, JobIDPrefix(caller))
+	assert.Equal(t, "release/", JobIDPrefix(nestedCaller))
+	assert.Equal(t, "release/build/", JobIDPrefix(nested))
+
+	assert.Equal(t, 1, workflowCallDepth(caller, jobs))
+	assert.Equal(t, 2, workflowCallDepth(nestedCaller, jobs))
+
+	require.NoError(t, unittest.PrepareTestDatabase())
+	require.NoError(t, db.Insert(db.DefaultContext, &actions_model.ActionTaskOutput{TaskID: 1001, OutputKey: "minor", OutputValue: "2"}))
+	nested.TaskID = 1001
+
+	outputs, err := FindJobOutputs(db.DefaultContext, caller, jobs)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"version": "1.2"}, outputs)
+
+	results, err := jobNeedsResults(db.DefaultContext, nested, jobs)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"setup"}, results["compile"].Needs)
+}
+
+func TestCheckCalledWorkflowAccess(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+
+	check := func(callerID, calledID int64) error {
+		caller := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: callerID})
+		called := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: calledID})
+		return checkCalledWorkflowAccess(db.DefaultContext, caller, called)
+	}
+
+	// a public repository of a public owner
+	require.NoError(t, check(4, 1))
+	// a private repository of the same owner
+	require.NoError(t, check(1, 2))
+	// a private repository of another owner
+	require.Error(t, check(4, 2))
+	// a public repository of a private organization is only accessible by its members
+	require.Error(t, check(1, 40))
+	require.NoError(t, check(4, 40))
+}
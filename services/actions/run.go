@@ -0,0 +1,82 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package actions
+
+import (
+	"context"
+	"fmt"
+
+	actions_model "code.gitea.io/gitea/models/actions"
+	actions_module "code.gitea.io/gitea/modules/actions"
+	"code.gitea.io/gitea/modules/log"
+
+	"github.com/nektos/act/pkg/jobparser"
+)
+
+// InsertRun inserts a run and the jobs parsed from the workflow content.
+// The workflow-level concurrency is evaluated before the run is inserted,
+// the job-level concurrency is evaluated by the job emitter when a job is about to run.
+func InsertRun(ctx context.Context, run *actions_model.ActionRun, content []byte, jobs []*jobparser.SingleWorkflow, vars map[string]string) error {
+	wfRawConcurrency, jobsRawConcurrency, err := actions_module.ReadWorkflowRawConcurrency(content)
+	if err != nil {
+		return fmt.Errorf("ReadWorkflowRawConcurrency: %w", err)
+	}
+
+	if wfRawConcurrency != nil {
+		if err := evaluateRunConcurrency(ctx, run, wfRawConcurrency, vars); err != nil {
+			return err
+		}
+	}
+
+	rawConcurrency := make(map[string]string, len(jobsRawConcurrency))
+	for id, rc := range jobsRawConcurrency {
+		rawConcurrency[id] = rc.Marshal()
+	}
+	rawEnvironment, err := readRawEnvironments(content)
+	if err != nil {
+		return err
+	}
+
+	idTokenPermission, err := readIDTokenPermissions(content, jobs, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := actions_model.InsertRun(ctx, run, jobs, rawConcurrency, rawEnvironment, idTokenPermission); err != nil {
+		return err
+	}
+
+	if len(rawConcurrency) > 0 || len(rawEnvironment) > 0 || hasWorkflowCall(jobs) {
+		// let the job emitter evaluate the job-level concurrency, create the deployments
+		// and expand the reusable workflows of the jobs without needs
+		if err := EmitJobsIfReady(run.ID); err != nil {
+			log.Error("EmitJobsIfReady: %v", err)
+		}
+	}
+
+	NotifyWorkflowRunRequested(ctx, run)
+	return nil
+}
+
+// readRawEnvironments returns the environments of the jobs of a workflow as stored in the database, by job ID.
+func readRawEnvironments(content []byte) (map[string]string, error) {
+	jobsRawEnvironment, err := actions_module.ReadWorkflowRawEnvironments(content)
+	if err != nil {
+		return nil, fmt.Errorf("ReadWorkflowRawEnvironments: %w", err)
+	}
+	rawEnvironment := make(map[string]string, len(jobsRawEnvironment))
+	for id, re := range jobsRawEnvironment {
+		rawEnvironment[id] = re.Marshal()
+	}
+	return rawEnvironment, nil
+}
+
+func hasWorkflowCall(jobs []*jobparser.SingleWorkflow) bool {
+	for _, v := range jobs {
+		if _, job := v.Job(); job != nil && job.Uses != "" {
+			return true
+		}
+	}
+	return false
+}
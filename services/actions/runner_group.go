@@ -0,0 +1,151 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package actions
+
+import (
+	"context"
+	"strings"
+
+	actions_model "code.gitea.io/gitea/models/actions"
+	repo_model "code.gitea.io/gitea/models/repo"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/util"
+
+	"github.com/gobwas/glob"
+)
+
+// ValidateRunnerGroupName checks the name of a runner group.
+func ValidateRunnerGroupName(name string) error {
+	if name == "" || len(name) > 255 || strings.TrimSpace(name) != name {
+		return util.NewInvalidArgumentErrorf("invalid runner group name %q", name)
+	}
+	return nil
+}
+
+// RunnerGroupOptions are the access policies of a runner group.
+type RunnerGroupOptions struct {
+	Description      string
+	AllowedOwnerIDs  []int64
+	AllowedRepoIDs   []int64
+	AllowedWorkflows []string
+}
+
+func (opts *RunnerGroupOptions) validate(ctx context.Context, ownerID int64) error {
+	if ownerID != 0 && len(opts.AllowedOwnerIDs) > 0 {
+		return util.NewInvalidArgumentErrorf("only the groups of the instance runners can restrict the owners")
+	}
+	if ownerID != 0 {
+		repos, err := repo_model.GetRepositoriesMapByIDs(ctx, opts.AllowedRepoIDs)
+		if err != nil {
+			return err
+		}
+		for _, id := range opts.AllowedRepoIDs {
+			if repo, ok := repos[id]; !ok || repo.OwnerID != ownerID {
+				return util.NewInvalidArgumentErrorf("repository %d doesn't belong to the owner of the runner group", id)
+			}
+		}
+	}
+	for _, pattern := range opts.AllowedWorkflows {
+		if _, err := glob.Compile(pattern); err != nil {
+			return util.NewInvalidArgumentErrorf("invalid workflow pattern %q: %v", pattern, err)
+		}
+	}
+	return nil
+}
+
+// CreateRunnerGroup creates a group of the runners of an owner, or of the instance runners if ownerID is 0.
+func CreateRunnerGroup(ctx context.Context, ownerID int64, name string, opts *RunnerGroupOptions) (*actions_model.ActionRunnerGroup, error) {
+	if err := ValidateRunnerGroupName(name); err != nil {
+		return nil, err
+	}
+	if err := opts.validate(ctx, ownerID); err != nil {
+		return nil, err
+	}
+	g := &actions_model.ActionRunnerGroup{
+		OwnerID:          ownerID,
+		Name:             name,
+		Description:      opts.Description,
+		AllowedOwnerIDs:  opts.AllowedOwnerIDs,
+		AllowedRepoIDs:   opts.AllowedRepoIDs,
+		AllowedWorkflows: opts.AllowedWorkflows,
+	}
+	if err := actions_model.InsertRunnerGroup(ctx, g); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// UpdateRunnerGroup updates the access policies of a runner group.
+// They apply to the jobs fetched by its runners afterwards.
+func UpdateRunnerGroup(ctx context.Context, g *actions_model.ActionRunnerGroup, opts *RunnerGroupOptions) error {
+	if err := opts.validate(ctx, g.OwnerID); err != nil {
+		return err
+	}
+	g.Description = opts.Description
+	g.AllowedOwnerIDs = opts.AllowedOwnerIDs
+	g.AllowedRepoIDs = opts.AllowedRepoIDs
+	g.AllowedWorkflows = opts.AllowedWorkflows
+	return actions_model.UpdateRunnerGroup(ctx, g, "description", "allowed_owner_i_ds", "allowed_repo_i_ds", "allowed_workflows")
+}
+
+// SetRunnerGroup moves a runner to a group of the same owner, or removes it from its group if groupID is 0.
+// The runners of a repository can't belong to a group.
+func SetRunnerGroup(ctx context.Context, runner *actions_model.ActionRunner, groupID int64) error {
+	if groupID != 0 {
+		g, err := actions_model.GetRunnerGroupByID(ctx, groupID)
+		if err != nil {
+			return err
+		}
+		if runner.RepoID != 0 || g.OwnerID != runner.OwnerID {
+			return util.NewInvalidArgumentErrorf("runner %d can't belong to runner group %d", runner.ID, g.ID)
+		}
+	}
+	runner.GroupID = groupID
+	return actions_model.UpdateRunner(ctx, runner, "group_id")
+}
+
+// ResolveRunnerGroupOwners returns the IDs of the owners with the given names.
+func ResolveRunnerGroupOwners(ctx context.Context, names []string) ([]int64, error) {
+	ids := make([]int64, 0, len(names))
+	for _, name := range names {
+		u, err := user_model.GetUserByName(ctx, name)
+		if err != nil {
+			if user_model.IsErrUserNotExist(err) {
+				return nil, util.NewNotExistErrorf("the owner %q does not exist", name)
+			}
+			return nil, err
+		}
+		ids = append(ids, u.ID)
+	}
+	return ids, nil
+}
+
+// ResolveRunnerGroupRepos returns the IDs of the repositories with the given full names.
+// For the groups of an owner, the names of its repositories can be given without the name of the owner.
+func ResolveRunnerGroupRepos(ctx context.Context, ownerID int64, names []string) ([]int64, error) {
+	var ownerName string
+	if ownerID != 0 {
+		owner, err := user_model.GetUserByID(ctx, ownerID)
+		if err != nil {
+			return nil, err
+		}
+		ownerName = owner.Name
+	}
+	ids := make([]int64, 0, len(names))
+	for _, name := range names {
+		repoOwnerName, repoName, ok := strings.Cut(name, "/")
+		if !ok {
+			repoOwnerName, repoName = ownerName, name
+		}
+		repo, err := repo_model.GetRepositoryByOwnerAndName(ctx, repoOwnerName, repoName)
+		if err != nil {
+			if repo_model.IsErrRepoNotExist(err) {
+				return nil, util.NewNotExistErrorf("the repository %q does not exist", name)
+			}
+			return nil, err
+		}
+		ids = append(ids, repo.ID)
+	}
+	return ids, nil
+}
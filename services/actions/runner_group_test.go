@@ -0,0 +1,105 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package actions
+
+import (
+	"testing"
+
+	actions_model "code.gitea.io/gitea/models/actions"
+	"code.gitea.io/gitea/models/db"
+	repo_model "code.gitea.io/gitea/models/repo"
+	"code.gitea.io/gitea/models/unittest"
+	"code.gitea.io/gitea/modules/util"
+
+	"github.com/nektos/act/pkg/jobparser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunnerGroupValidation(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+	ctx := db.DefaultContext
+
+	_, err := CreateRunnerGroup(ctx, 5, " linux", &RunnerGroupOptions{})
+	require.ErrorIs(t, err, util.ErrInvalidArgument)
+	_, err = CreateRunnerGroup(ctx, 5, "linux", &RunnerGroupOptions{AllowedOwnerIDs: []int64{2}})
+	require.ErrorIs(t, err, util.ErrInvalidArgument)
+	_, err = CreateRunnerGroup(ctx, 5, "linux", &RunnerGroupOptions{AllowedRepoIDs: []int64{1}})
+	require.ErrorIs(t, err, util.ErrInvalidArgument)
+	_, err = CreateRunnerGroup(ctx, 5, "linux", &RunnerGroupOptions{AllowedWorkflows: []string{"[deploy"}})
+	require.ErrorIs(t, err, util.ErrInvalidArgument)
+
+	g, err := CreateRunnerGroup(ctx, 5, "Linux", &RunnerGroupOptions{AllowedRepoIDs: []int64{4}})
+	require.NoError(t, err)
+	_, err = CreateRunnerGroup(ctx, 5, "linux", &RunnerGroupOptions{})
+	require.ErrorIs(t, err, util.ErrAlreadyExist)
+
+	repoRunner := &actions_model.ActionRunner{UUID: "runner-group-repo", TokenHash: "runner-group-repo", Name: "repo runner", OwnerID: 0, RepoID: 4}
+	require.NoError(t, db.Insert(ctx, repoRunner))
+	require.ErrorIs(t, SetRunnerGroup(ctx, repoRunner, g.ID), util.ErrInvalidArgument)
+	otherRunner := &actions_model.ActionRunner{UUID: "runner-group-other", TokenHash: "runner-group-other", Name: "other runner", OwnerID: 2}
+	require.NoError(t, db.Insert(ctx, otherRunner))
+	require.ErrorIs(t, SetRunnerGroup(ctx, otherRunner, g.ID), util.ErrInvalidArgument)
+}
+
+func TestRunnerGroupFetchTask(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+	ctx := db.DefaultContext
+
+	workflows, err := jobparser.Parse([]byte(`
+on: push
+jobs:
+  build:
+    runs-on: runner-group
+    steps:
+      - run: make
+`))
+	require.NoError(t, err)
+	payload, err := workflows[0].Marshal()
+	require.NoError(t, err)
+
+	newJob := func(repoID int64, workflowID string, index int64) *actions_model.ActionRunJob {
+		repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: repoID})
+		_, job := insertRun(t, repo, &actions_model.ActionRun{
+			Index:      index,
+			WorkflowID: workflowID,
+			Status:     actions_model.StatusWaiting,
+		}, &actions_model.ActionRunJob{
+			JobID:           "build",
+			WorkflowPayload: payload,
+			RunsOn:          []string{"runner-group"},
+		})
+		return job
+	}
+	otherRepoJob := newJob(1, "deploy.yml", 9001)
+	otherWorkflowJob := newJob(4, "test.yml", 9002)
+	allowedJob := newJob(4, "deploy-production.yml", 9003)
+
+	g, err := CreateRunnerGroup(ctx, 0, "deployments", &RunnerGroupOptions{
+		AllowedRepoIDs:   []int64{4},
+		AllowedWorkflows: []string{"deploy*.yml"},
+	})
+	require.NoError(t, err)
+	runner := &actions_model.ActionRunner{UUID: "runner-group-instance", TokenHash: "runner-group-instance", Name: "instance runner", AgentLabels: []string{"runner-group"}}
+	require.NoError(t, db.Insert(ctx, runner))
+	require.NoError(t, SetRunnerGroup(ctx, runner, g.ID))
+
+	task, ok, err := actions_model.CreateTaskForRunner(ctx, runner)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, allowedJob.ID, task.JobID)
+
+	_, ok, err = actions_model.CreateTaskForRunner(ctx, runner)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// the runner runs any job once its group is deleted
+	require.NoError(t, actions_model.DeleteRunnerGroup(ctx, g))
+	runner = unittest.AssertExistsAndLoadBean(t, &actions_model.ActionRunner{ID: runner.ID})
+	assert.Zero(t, runner.GroupID)
+	task, ok, err = actions_model.CreateTaskForRunner(ctx, runner)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Contains(t, []int64{otherRepoJob.ID, otherWorkflowJob.ID}, task.JobID)
+}
@@ -15,6 +15,15 @@ import (
 )
 
 func CreateVariable(ctx context.Context, ownerID, repoID int64, name, data string) (*actions_model.ActionVariable, error) {
+	return createVariable(ctx, ownerID, repoID, 0, name, data)
+}
+
+// CreateEnvironmentVariable creates a variable of an environment of a repository.
+func CreateEnvironmentVariable(ctx context.Context, repoID, environmentID int64, name, data string) (*actions_model.ActionVariable, error) {
+	return createVariable(ctx, 0, repoID, environmentID, name, data)
+}
+
+func createVariable(ctx context.Context, ownerID, repoID, environmentID int64, name, data string) (*actions_model.ActionVariable, error) {
 	if err := secret_service.ValidateName(name); err != nil {
 		return nil, err
 	}
@@ -23,7 +32,7 @@ func CreateVariable(ctx context.Context, ownerID, repoID int64, name, data strin
 		return nil, err
 	}
 
-	v, err := actions_model.InsertVariable(ctx, ownerID, repoID, name, util.ReserveLineBreakForTextarea(data))
+	v, err := actions_model.InsertVariable(ctx, ownerID, repoID, environmentID, name, util.ReserveLineBreakForTextarea(data))
 	if err != nil {
 		return nil, err
 	}
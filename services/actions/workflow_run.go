@@ -0,0 +1,61 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package actions
+
+import (
+	"context"
+
+	actions_model "code.gitea.io/gitea/models/actions"
+	"code.gitea.io/gitea/modules/log"
+	webhook_module "code.gitea.io/gitea/modules/webhook"
+	notify_service "code.gitea.io/gitea/services/notify"
+)
+
+// the workflow_run event can't chain more than three levels of workflows
+// See https://docs.github.com/en/actions/using-workflows/events-that-trigger-workflows#workflow_run
+const maxWorkflowRunChainLength = 3
+
+// NotifyWorkflowRunRequested emits the workflow_run event of a run which has been created or rerun.
+func NotifyWorkflowRunRequested(ctx context.Context, run *actions_model.ActionRun) {
+	if err := run.LoadAttributes(ctx); err != nil {
+		log.Error("LoadAttributes of run %d: %v", run.ID, err)
+		return
+	}
+	notify_service.WorkflowRunStatusUpdate(ctx, run.Repo, run.TriggerUser, run)
+}
+
+// notifyWorkflowRunCompleted emits the workflow_run event of a run which is done, only once until the run is rerun.
+func notifyWorkflowRunCompleted(ctx context.Context, run *actions_model.ActionRun) error {
+	if !run.Status.IsDone() || run.IsCompletionNotified {
+		return nil
+	}
+	if notified, err := actions_model.SetRunCompletionNotified(ctx, run); err != nil {
+		return err
+	} else if !notified {
+		// another goroutine has emitted the event
+		return nil
+	}
+	if err := run.LoadAttributes(ctx); err != nil {
+		return err
+	}
+	notify_service.WorkflowRunStatusUpdate(ctx, run.Repo, run.TriggerUser, run)
+	return nil
+}
+
+// workflowRunChainLength returns the number of runs which have been chained by the workflow_run event up to the given run,
+// it doesn't count more than maxWorkflowRunChainLength runs.
+func workflowRunChainLength(ctx context.Context, run *actions_model.ActionRun) (int, error) {
+	length := 1
+	for run.Event == webhook_module.HookEventWorkflowRun && length < maxWorkflowRunChainLength {
+		payload, err := run.GetWorkflowRunEventPayload()
+		if err != nil {
+			return 0, err
+		}
+		if run, err = actions_model.GetRunByID(ctx, payload.WorkflowRun.ID); err != nil {
+			return 0, err
+		}
+		length++
+	}
+	return length, nil
+}
@@ -0,0 +1,68 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package actions
+
+import (
+	"testing"
+
+	actions_model "code.gitea.io/gitea/models/actions"
+	"code.gitea.io/gitea/models/db"
+	repo_model "code.gitea.io/gitea/models/repo"
+	"code.gitea.io/gitea/models/unittest"
+	"code.gitea.io/gitea/modules/json"
+	api "code.gitea.io/gitea/modules/structs"
+	webhook_module "code.gitea.io/gitea/modules/webhook"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkflowRunChainLength(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+	ctx := db.DefaultContext
+
+	repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 4})
+
+	// a run triggered by the completion of the given run
+	newRun := func(index int64, triggering *actions_model.ActionRun) *actions_model.ActionRun {
+		run := &actions_model.ActionRun{
+			Index:      index,
+			WorkflowID: "deploy.yml",
+			Event:      webhook_module.HookEventPush,
+			Status:     actions_model.StatusSuccess,
+		}
+		if triggering != nil {
+			payload, err := json.Marshal(&api.WorkflowRunPayload{
+				Action:      api.HookWorkflowRunCompleted,
+				WorkflowRun: &api.ActionWorkflowRun{ID: triggering.ID},
+			})
+			require.NoError(t, err)
+			run.Event = webhook_module.HookEventWorkflowRun
+			run.EventPayload = string(payload)
+		}
+		run, _ = insertRun(t, repo, run, nil)
+		return run
+	}
+
+	first := newRun(7001, nil)
+	second := newRun(7002, first)
+	third := newRun(7003, second)
+	fourth := newRun(7004, third)
+
+	for expected, run := range []*actions_model.ActionRun{first, second, third, fourth} {
+		length, err := workflowRunChainLength(ctx, run)
+		require.NoError(t, err)
+		assert.Equal(t, min(expected+1, maxWorkflowRunChainLength), length)
+	}
+
+	// the completion of a run is only notified once
+	require.NoError(t, notifyWorkflowRunCompleted(ctx, first))
+	unittest.AssertExistsAndLoadBean(t, &actions_model.ActionRun{ID: first.ID, IsCompletionNotified: true})
+	notified, err := actions_model.SetRunCompletionNotified(ctx, first)
+	require.NoError(t, err)
+	assert.False(t, notified)
+	notified, err = actions_model.SetRunCompletionNotified(ctx, second)
+	require.NoError(t, err)
+	assert.True(t, notified)
+}
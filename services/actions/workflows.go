@@ -137,7 +137,7 @@ func (entry *Workflow) Dispatch(ctx context.Context, inputGetter InputValueGette
 		return err
 	}
 
-	return actions_model.InsertRun(ctx, run, jobs)
+	return InsertRun(ctx, run, content, jobs, vars)
 }
 
 func GetWorkflowFromCommit(gitRepo *git.Repository, ref, workflowID string) (*Workflow, error) {
@@ -10,6 +10,7 @@ import (
 	"io"
 
 	"code.gitea.io/gitea/models/db"
+	quota_model "code.gitea.io/gitea/models/quota"
 	repo_model "code.gitea.io/gitea/models/repo"
 	"code.gitea.io/gitea/modules/storage"
 	"code.gitea.io/gitea/modules/util"
@@ -45,6 +46,14 @@ func NewAttachment(ctx context.Context, attach *repo_model.Attachment, file io.R
 
 // UploadAttachment upload new attachment into storage and update database
 func UploadAttachment(ctx context.Context, file io.Reader, allowedTypes string, fileSize int64, attach *repo_model.Attachment) (*repo_model.Attachment, error) {
+	repo, err := repo_model.GetRepositoryByID(ctx, attach.RepoID)
+	if err != nil {
+		return nil, err
+	}
+	if err := quota_model.CheckForUser(ctx, repo.OwnerID, quota_model.LimitSubjectSizeAssetsAttachments); err != nil {
+		return nil, err
+	}
+
 	buf := make([]byte, 1024)
 	n, _ := util.ReadAtMost(file, buf)
 	buf = buf[:n]
@@ -132,3 +132,45 @@ func Test_isGitRawOrLFSPath(t *testing.T) {
 	}
 	setting.LFS.StartServer = origLFSStartServer
 }
+
+func Test_isTerraformStatePath(t *testing.T) {
+	tests := []struct {
+		path string
+
+		want bool
+	}{
+		{
+			"/owner/repo/terraform/state/default",
+			true,
+		},
+		{
+			"/owner/repo/terraform/state/",
+			true,
+		},
+		{
+			"/owner/repo/terraform",
+			false,
+		},
+		{
+			"/owner/repo/settings/terraform",
+			false,
+		},
+	}
+
+	origEnabled := setting.TerraformState.Enabled
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			req, _ := http.NewRequest("LOCK", "http://localhost"+tt.path, nil)
+			setting.TerraformState.Enabled = false
+			if got := isTerraformStatePath(req); got {
+				t.Errorf("isTerraformStatePath() = %v, want false", got)
+			}
+			setting.TerraformState.Enabled = true
+			if got := isTerraformStatePath(req); got != tt.want {
+				t.Errorf("isTerraformStatePath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	setting.TerraformState.Enabled = origEnabled
+}
@@ -108,6 +108,12 @@ type APIRepoArchivedError struct {
 	APIError
 }
 
+// APIQuotaExceeded is an error that is raised when the storage quota of the owner is exceeded
+// swagger:response quotaExceeded
+type APIQuotaExceeded struct {
+	APIError
+}
+
 // ServerError responds with error message, status is 500
 func (ctx *APIContext) ServerError(title string, err error) {
 	ctx.Error(http.StatusInternalServerError, title, err)
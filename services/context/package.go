@@ -92,6 +92,11 @@ func packageAssignment(ctx *packageAssignmentCtx, errCb func(int, string, any))
 	return pkg
 }
 
+// PackageOwnerAccessMode returns the access mode of a user to the packages of an owner
+func PackageOwnerAccessMode(ctx *Base, owner, doer *user_model.User) (perm.AccessMode, error) {
+	return determineAccessMode(ctx, &Package{Owner: owner}, doer)
+}
+
 func determineAccessMode(ctx *Base, pkg *Package, doer *user_model.User) (perm.AccessMode, error) {
 	if setting.Service.RequireSignInView && (doer == nil || doer.IsGhost()) {
 		return perm.AccessModeNone, nil
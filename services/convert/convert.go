@@ -14,6 +14,7 @@ import (
 	actions_model "code.gitea.io/gitea/models/actions"
 	asymkey_model "code.gitea.io/gitea/models/asymkey"
 	"code.gitea.io/gitea/models/auth"
+	"code.gitea.io/gitea/models/db"
 	git_model "code.gitea.io/gitea/models/git"
 	issues_model "code.gitea.io/gitea/models/issues"
 	"code.gitea.io/gitea/models/organization"
@@ -205,6 +206,11 @@ func ToActionTask(ctx context.Context, t *actions_model.ActionTask) (*api.Action
 
 	url := strings.TrimSuffix(setting.AppURL, "/") + t.GetRunLink()
 
+	concurrencyGroup := t.Job.ConcurrencyGroup
+	if concurrencyGroup == "" {
+		concurrencyGroup = t.Job.Run.ConcurrencyGroup
+	}
+
 	return &api.ActionTask{
 		ID:           t.ID,
 		Name:         t.Job.Name,
@@ -219,6 +225,76 @@ func ToActionTask(ctx context.Context, t *actions_model.ActionTask) (*api.Action
 		CreatedAt:    t.Created.AsLocalTime(),
 		UpdatedAt:    t.Updated.AsLocalTime(),
 		RunStartedAt: t.Started.AsLocalTime(),
+
+		ConcurrencyGroup: concurrencyGroup,
+	}, nil
+}
+
+// ToActionWorkflowRun convert a actions_model.ActionRun to an api.ActionWorkflowRun
+func ToActionWorkflowRun(ctx context.Context, run *actions_model.ActionRun) (*api.ActionWorkflowRun, error) {
+	if err := run.LoadAttributes(ctx); err != nil {
+		return nil, err
+	}
+	name, err := run.GetWorkflowName(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	status, conclusion := "queued", ""
+	switch {
+	case run.Status.IsDone():
+		status, conclusion = "completed", run.Status.String()
+	case run.Status.IsRunning():
+		status = "in_progress"
+	}
+
+	return &api.ActionWorkflowRun{
+		ID:           run.ID,
+		Name:         name,
+		DisplayTitle: run.Title,
+		HeadBranch:   run.PrettyRef(),
+		HeadSHA:      run.CommitSHA,
+		RunNumber:    run.Index,
+		Event:        run.TriggerEvent,
+		Status:       status,
+		Conclusion:   conclusion,
+		WorkflowID:   run.WorkflowID,
+		HTMLURL:      run.HTMLURL(),
+		CreatedAt:    run.Created.AsLocalTime(),
+		UpdatedAt:    run.Updated.AsLocalTime(),
+		RunStartedAt: run.Started.AsLocalTime(),
+		NeedApproval: run.NeedApproval,
+	}, nil
+}
+
+// ToActionRunnerGroup convert a actions_model.ActionRunnerGroup to an api.ActionRunnerGroup
+func ToActionRunnerGroup(ctx context.Context, g *actions_model.ActionRunnerGroup) (*api.ActionRunnerGroup, error) {
+	owners, err := user_model.GetUserNamesByIDs(ctx, g.AllowedOwnerIDs)
+	if err != nil {
+		return nil, err
+	}
+	repos, err := g.GetAllowedRepoNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+	runners, err := db.Find[actions_model.ActionRunner](ctx, actions_model.FindRunnerOptions{GroupID: g.ID, Sort: "oldest"})
+	if err != nil {
+		return nil, err
+	}
+	runnerIDs := make([]int64, 0, len(runners))
+	for _, runner := range runners {
+		runnerIDs = append(runnerIDs, runner.ID)
+	}
+
+	return &api.ActionRunnerGroup{
+		ID:               g.ID,
+		Name:             g.Name,
+		Description:      g.Description,
+		AllowedOwners:    owners,
+		AllowedRepos:     repos,
+		AllowedWorkflows: g.AllowedWorkflows,
+		RunnerIDs:        runnerIDs,
+		Created:          g.Created.AsTime(),
 	}, nil
 }
 
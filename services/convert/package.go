@@ -36,6 +36,9 @@ func ToPackage(ctx context.Context, pd *packages.PackageDescriptor, doer *user_m
 		Version:    pd.Version.Version,
 		CreatedAt:  pd.Version.CreatedUnix.AsTime(),
 		HTMLURL:    pd.VersionHTMLURL(),
+
+		DeprecationMessage: pd.DeprecationMessage(),
+		RetentionLocked:    pd.IsRetentionLocked(),
 	}, nil
 }
 
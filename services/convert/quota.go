@@ -0,0 +1,53 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package convert
+
+import (
+	quota_model "code.gitea.io/gitea/models/quota"
+	api "code.gitea.io/gitea/modules/structs"
+)
+
+// ToQuotaRuleInfo converts a quota rule to API format
+func ToQuotaRuleInfo(r *quota_model.Rule) api.QuotaRuleInfo {
+	return api.QuotaRuleInfo{
+		Name:     r.Name,
+		Limit:    r.Limit,
+		Subjects: r.Subjects.Names(),
+	}
+}
+
+// ToQuotaGroup converts a quota group with its loaded rules to API format
+func ToQuotaGroup(g *quota_model.Group) api.QuotaGroup {
+	rules := make([]api.QuotaRuleInfo, 0, len(g.Rules))
+	for _, r := range g.Rules {
+		rules = append(rules, ToQuotaRuleInfo(r))
+	}
+	return api.QuotaGroup{
+		Name:  g.Name,
+		Rules: rules,
+	}
+}
+
+// ToQuotaGroupList converts a list of quota groups to API format
+func ToQuotaGroupList(groups quota_model.GroupList) []api.QuotaGroup {
+	result := make([]api.QuotaGroup, 0, len(groups))
+	for _, g := range groups {
+		result = append(result, ToQuotaGroup(g))
+	}
+	return result
+}
+
+// ToQuotaInfo converts the used storage and the quota groups of a user or an organization to API format
+func ToQuotaInfo(used *quota_model.Used, groups quota_model.GroupList) *api.QuotaInfo {
+	return &api.QuotaInfo{
+		Used: api.QuotaUsed{
+			ReposGit:          used.Git,
+			ReposLFS:          used.LFS,
+			AssetsAttachments: used.Attachments,
+			AssetsArtifacts:   used.Artifacts,
+			AssetsPackages:    used.Packages,
+		},
+		Groups: ToQuotaGroupList(groups),
+	}
+}
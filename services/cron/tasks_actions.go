@@ -19,6 +19,8 @@ func initActionsTasks() {
 	registerStopEndlessTasks()
 	registerCancelAbandonedJobs()
 	registerScheduleTasks()
+	registerStartApprovedDeployments()
+	registerCleanupActionsCache()
 }
 
 func registerStopZombieTasks() {
@@ -63,3 +65,29 @@ func registerScheduleTasks() {
 		return actions_service.StartScheduleTasks(ctx)
 	})
 }
+
+// registerStartApprovedDeployments registers a task that runs every minute to start the jobs
+// whose deployment has been approved once the wait timer of their environment has elapsed.
+func registerStartApprovedDeployments() {
+	RegisterTaskFatal("start_approved_deployments", &BaseConfig{
+		Enabled:    true,
+		RunAtStart: true,
+		Schedule:   "@every 1m",
+	}, func(ctx context.Context, _ *user_model.User, cfg Config) error {
+		return actions_service.StartApprovedDeployments(ctx)
+	})
+}
+
+// registerCleanupActionsCache registers a task that evicts the stale entries of the caches of the jobs.
+func registerCleanupActionsCache() {
+	if !setting.Actions.CacheEnabled {
+		return
+	}
+	RegisterTaskFatal("cleanup_actions_cache", &BaseConfig{
+		Enabled:    true,
+		RunAtStart: true,
+		Schedule:   "@midnight",
+	}, func(ctx context.Context, _ *user_model.User, cfg Config) error {
+		return actions_service.CleanupCaches(ctx)
+	})
+}
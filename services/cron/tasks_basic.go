@@ -17,6 +17,7 @@ import (
 	"code.gitea.io/gitea/services/auth"
 	"code.gitea.io/gitea/services/migrations"
 	mirror_service "code.gitea.io/gitea/services/mirror"
+	packages_service "code.gitea.io/gitea/services/packages"
 	packages_cleanup_service "code.gitea.io/gitea/services/packages/cleanup"
 	repo_service "code.gitea.io/gitea/services/repository"
 	archiver_service "code.gitea.io/gitea/services/repository/archiver"
@@ -157,6 +158,16 @@ func registerCleanupPackages() {
 	})
 }
 
+func registerAggregatePackageDownloads() {
+	RegisterTaskFatal("aggregate_package_downloads", &BaseConfig{
+		Enabled:    true,
+		RunAtStart: true,
+		Schedule:   "@every 1h",
+	}, func(ctx context.Context, _ *user_model.User, _ Config) error {
+		return packages_service.AggregateDownloads(ctx)
+	})
+}
+
 func registerActionsCleanup() {
 	RegisterTaskFatal("cleanup_actions", &OlderThanConfig{
 		BaseConfig: BaseConfig{
@@ -186,6 +197,7 @@ func initBasicTasks() {
 	registerCleanupHookTaskTable()
 	if setting.Packages.Enabled {
 		registerCleanupPackages()
+		registerAggregatePackageDownloads()
 	}
 	if setting.Actions.Enabled {
 		registerActionsCleanup()
@@ -80,7 +80,7 @@ func PackagesNugetNuspecCheck(ctx context.Context, logger log.Logger, autofix bo
 				continue
 			}
 
-			s, _, _, err := packages_service.GetPackageFileStream(ctx, pf)
+			s, _, _, err := packages_service.GetPackageFileStream(ctx, nil, pf)
 			if err != nil {
 				logger.Error("Failed to get nupkg file stream for %s %s: %v", pkg.Name, pv.Version, err)
 				errors++
@@ -15,7 +15,7 @@ import (
 type PackageCleanupRuleForm struct {
 	ID            int64
 	Enabled       bool
-	Type          string `binding:"Required;In(alpine,cargo,chef,composer,conan,conda,container,cran,debian,generic,go,helm,maven,npm,nuget,pub,pypi,rpm,rubygems,swift,vagrant)"`
+	Type          string `binding:"Required;In(alpine,arch,cargo,chef,composer,conan,conda,container,cran,debian,generic,go,helm,maven,nix,npm,nuget,pub,pypi,rpm,rubygems,swift,terraform,vagrant)"`
 	KeepCount     int    `binding:"In(0,1,5,10,25,50,100)"`
 	KeepPattern   string `binding:"RegexPattern"`
 	RemoveDays    int    `binding:"In(0,7,14,30,60,90,180)"`
@@ -28,3 +28,34 @@ func (f *PackageCleanupRuleForm) Validate(req *http.Request, errs binding.Errors
 	ctx := context.GetValidateContext(req)
 	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
 }
+
+// PackageProxyForm form for the upstream registry proxied by an owner
+type PackageProxyForm struct {
+	ID       int64
+	Enabled  bool
+	Type     string `binding:"Required;In(container,maven,npm,pypi)"`
+	URL      string `binding:"Required;ValidUrl"`
+	Username string
+	Password string
+	Action   string `binding:"Required;In(save,remove)"`
+}
+
+func (f *PackageProxyForm) Validate(req *http.Request, errs binding.Errors) binding.Errors {
+	ctx := context.GetValidateContext(req)
+	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
+}
+
+// PackageVirtualRegistryForm form for the virtual registry of an owner
+type PackageVirtualRegistryForm struct {
+	ID         int64
+	Enabled    bool
+	Type       string `binding:"Required;In(maven,npm,pypi)"`
+	Members    string
+	UseProxies bool
+	Action     string `binding:"Required;In(save,remove)"`
+}
+
+func (f *PackageVirtualRegistryForm) Validate(req *http.Request, errs binding.Errors) binding.Errors {
+	ctx := context.GetValidateContext(req)
+	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
+}
@@ -167,6 +167,7 @@ type RepoUnitSettingForm struct {
 	EnablePackages                        bool
 	EnablePulls                           bool
 	EnableActions                         bool
+	ActionsApprovalPolicy                 string
 	PullsIgnoreWhitespace                 bool
 	PullsAllowMerge                       bool
 	PullsAllowRebase                      bool
@@ -292,6 +293,19 @@ type WebhookForm struct {
 	Metadata    any
 }
 
+// CustomWebhookPreviewForm form for previewing the templates of a custom webhook
+type CustomWebhookPreviewForm struct {
+	BodyTemplate    string
+	HeadersTemplate string
+	PreviewEvent    string `binding:"Required"`
+}
+
+// Validate validates the fields
+func (f *CustomWebhookPreviewForm) Validate(req *http.Request, errs binding.Errors) binding.Errors {
+	ctx := context.GetValidateContext(req)
+	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
+}
+
 // .___
 // |   | ______ ________ __   ____
 // |   |/  ___//  ___/  |  \_/ __ \
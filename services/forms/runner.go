@@ -15,6 +15,7 @@ import (
 // EditRunnerForm form for admin to create runner
 type EditRunnerForm struct {
 	Description string
+	GroupID     int64
 }
 
 // Validate validates form fields
@@ -22,3 +23,52 @@ func (f *EditRunnerForm) Validate(req *http.Request, errs binding.Errors) bindin
 	ctx := context.GetValidateContext(req)
 	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
 }
+
+// NewEnvironmentForm form for creating an environment of a repository
+type NewEnvironmentForm struct {
+	Name string `binding:"Required;MaxSize(255)"`
+}
+
+// Validate validates form fields
+func (f *NewEnvironmentForm) Validate(req *http.Request, errs binding.Errors) binding.Errors {
+	ctx := context.GetValidateContext(req)
+	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
+}
+
+// EditEnvironmentForm form for editing the protection rules of an environment
+type EditEnvironmentForm struct {
+	Reviewers      string // comma-separated user names
+	WaitTimer      int64  `binding:"Range(0,43200)"`
+	BranchPatterns string // comma-separated glob patterns
+}
+
+// Validate validates form fields
+func (f *EditEnvironmentForm) Validate(req *http.Request, errs binding.Errors) binding.Errors {
+	ctx := context.GetValidateContext(req)
+	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
+}
+
+// NewRunnerGroupForm form for creating a runner group
+type NewRunnerGroupForm struct {
+	Name string `binding:"Required;MaxSize(255)"`
+}
+
+// Validate validates form fields
+func (f *NewRunnerGroupForm) Validate(req *http.Request, errs binding.Errors) binding.Errors {
+	ctx := context.GetValidateContext(req)
+	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
+}
+
+// EditRunnerGroupForm form for editing the access policies of a runner group
+type EditRunnerGroupForm struct {
+	Description      string
+	AllowedOwners    string // comma-separated user and organization names
+	AllowedRepos     string // comma-separated repository names
+	AllowedWorkflows string // comma-separated glob patterns
+}
+
+// Validate validates form fields
+func (f *EditRunnerGroupForm) Validate(req *http.Request, errs binding.Errors) binding.Errors {
+	ctx := context.GetValidateContext(req)
+	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
+}
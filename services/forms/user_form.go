@@ -444,8 +444,9 @@ func (f *WebauthnDeleteForm) Validate(req *http.Request, errs binding.Errors) bi
 
 // PackageSettingForm form for package settings
 type PackageSettingForm struct {
-	Action string
-	RepoID int64 `form:"repo_id"`
+	Action             string
+	RepoID             int64  `form:"repo_id"`
+	DeprecationMessage string `form:"deprecation_message"`
 }
 
 // Validate validates the fields
@@ -23,6 +23,7 @@ import (
 	git_model "code.gitea.io/gitea/models/git"
 	"code.gitea.io/gitea/models/perm"
 	access_model "code.gitea.io/gitea/models/perm/access"
+	quota_model "code.gitea.io/gitea/models/quota"
 	repo_model "code.gitea.io/gitea/models/repo"
 	"code.gitea.io/gitea/models/unit"
 	user_model "code.gitea.io/gitea/models/user"
@@ -179,6 +180,10 @@ func BatchHandler(ctx *context.Context) {
 		return
 	}
 
+	if isUpload && !checkQuota(ctx, repository) {
+		return
+	}
+
 	contentStore := lfs_module.NewContentStore()
 
 	var responseObjects []*lfs_module.ObjectResponse
@@ -297,6 +302,10 @@ func UploadHandler(ctx *context.Context) {
 		return
 	}
 
+	if !exists && !checkQuota(ctx, repository) {
+		return
+	}
+
 	uploadOrVerify := func() error {
 		if exists {
 			accessible, err := git_model.LFSObjectAccessible(ctx, ctx.Doer, p.Oid)
@@ -501,6 +510,20 @@ func writeStatusMessage(ctx *context.Context, status int, message string) {
 	}
 }
 
+// checkQuota writes an error response and returns false if the quota of the repository owner doesn't allow more LFS objects
+func checkQuota(ctx *context.Context, repository *repo_model.Repository) bool {
+	if err := quota_model.CheckForUser(ctx, repository.OwnerID, quota_model.LimitSubjectSizeReposLFS); err != nil {
+		if quota_model.IsErrQuotaExceeded(err) {
+			writeStatusMessage(ctx, http.StatusRequestEntityTooLarge, "the storage quota of the repository owner has been exceeded")
+		} else {
+			log.Error("Unable to check the quota of %-v. Error: %v", repository, err)
+			writeStatus(ctx, http.StatusInternalServerError)
+		}
+		return false
+	}
+	return true
+}
+
 // authenticate uses the authorization string to determine whether
 // or not to proceed. This server assumes an HTTP Basic auth format.
 func authenticate(ctx *context.Context, repository *repo_model.Repository, authorization string, requireSigned, requireWrite bool) bool {
@@ -0,0 +1,128 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"code.gitea.io/gitea/models/organization"
+	repo_model "code.gitea.io/gitea/models/repo"
+	user_model "code.gitea.io/gitea/models/user"
+	webhook_model "code.gitea.io/gitea/models/webhook"
+	"code.gitea.io/gitea/modules/base"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/translation"
+)
+
+const (
+	tplWebhookDisabledMail base.TplName = "notify/webhook_disabled"
+)
+
+// MailWebhookDisabled sends a notification email to the owners of a webhook which was disabled because its deliveries keep failing
+func MailWebhookDisabled(ctx context.Context, w *webhook_model.Webhook) {
+	if setting.MailService == nil {
+		// No mail service configured
+		return
+	}
+
+	recipients, link, err := webhookOwners(ctx, w)
+	if err != nil {
+		log.Error("webhookOwners[%d]: %v", w.ID, err)
+		return
+	}
+
+	langMap := make(map[string][]*user_model.User)
+	for _, u := range recipients {
+		if !u.IsActive {
+			// don't send emails to inactive users
+			continue
+		}
+		langMap[u.Language] = append(langMap[u.Language], u)
+	}
+
+	for lang, tos := range langMap {
+		if err := mailWebhookDisabled(w, link, lang, tos); err != nil {
+			log.Error("mailWebhookDisabled[%d]: %v", w.ID, err)
+		}
+	}
+}
+
+// webhookOwners returns the users who manage the webhook and the link to its settings
+func webhookOwners(ctx context.Context, w *webhook_model.Webhook) ([]*user_model.User, string, error) {
+	var owner *user_model.User
+	var link string
+	switch {
+	case w.RepoID > 0:
+		repo, err := repo_model.GetRepositoryByID(ctx, w.RepoID)
+		if err != nil {
+			return nil, "", err
+		}
+		if err := repo.LoadOwner(ctx); err != nil {
+			return nil, "", err
+		}
+		owner = repo.Owner
+		link = fmt.Sprintf("%s/settings/hooks/%d", repo.HTMLURL(), w.ID)
+	case w.OwnerID > 0:
+		u, err := user_model.GetUserByID(ctx, w.OwnerID)
+		if err != nil {
+			return nil, "", err
+		}
+		owner = u
+		if u.IsOrganization() {
+			link = fmt.Sprintf("%sorg/%s/settings/hooks/%d", setting.AppURL, u.Name, w.ID)
+		} else {
+			link = fmt.Sprintf("%suser/settings/hooks/%d", setting.AppURL, w.ID)
+		}
+	default:
+		admins, err := user_model.GetAllAdmins(ctx)
+		if err != nil {
+			return nil, "", err
+		}
+		return admins, fmt.Sprintf("%sadmin/hooks/%d", setting.AppURL, w.ID), nil
+	}
+
+	if !owner.IsOrganization() {
+		return []*user_model.User{owner}, link, nil
+	}
+
+	team, err := organization.OrgFromUser(owner).GetOwnerTeam(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := team.LoadMembers(ctx); err != nil {
+		return nil, "", err
+	}
+	return team.Members, link, nil
+}
+
+func mailWebhookDisabled(w *webhook_model.Webhook, link, lang string, tos []*user_model.User) error {
+	locale := translation.NewLocale(lang)
+
+	subject := locale.TrString("mail.webhook.disabled.subject", w.URL)
+
+	data := map[string]any{
+		"locale":   locale,
+		"Subject":  subject,
+		"Language": locale.Language(),
+		"Webhook":  w,
+		"Link":     link,
+	}
+
+	var content bytes.Buffer
+	if err := bodyTemplates.ExecuteTemplate(&content, string(tplWebhookDisabledMail), data); err != nil {
+		return err
+	}
+
+	for _, to := range tos {
+		msg := NewMessage(to.EmailTo(), subject, content.String())
+		msg.Info = fmt.Sprintf("UID: %d, webhook %d disabled notification", to.ID, w.ID)
+
+		SendAsync(msg)
+	}
+
+	return nil
+}
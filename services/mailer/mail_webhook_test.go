@@ -0,0 +1,64 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package mailer
+
+import (
+	"fmt"
+	"testing"
+
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/unittest"
+	user_model "code.gitea.io/gitea/models/user"
+	webhook_model "code.gitea.io/gitea/models/webhook"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMailWebhookDisabled(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	user2 := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2})
+
+	t.Run("UserWebhook", func(t *testing.T) {
+		w := &webhook_model.Webhook{
+			ID:           42,
+			OwnerID:      user2.ID,
+			URL:          "https://example.com/user-hook",
+			FailingSince: timeutil.TimeStampNow(),
+		}
+
+		called := false
+		defer mockMailSettings(func(msgs ...*Message) {
+			assert.Len(t, msgs, 1)
+			assert.Equal(t, user2.EmailTo(), msgs[0].To)
+			assert.Contains(t, msgs[0].Subject, w.URL)
+			assert.Contains(t, msgs[0].Body, fmt.Sprintf("%suser/settings/hooks/%d", setting.AppURL, w.ID))
+			assertTranslatedLocale(t, msgs[0].Body, "mail.webhook")
+			called = true
+		})()
+		MailWebhookDisabled(db.DefaultContext, w)
+		assert.True(t, called)
+	})
+
+	t.Run("OrganizationRepositoryWebhook", func(t *testing.T) {
+		w := &webhook_model.Webhook{
+			ID:           43,
+			RepoID:       3,
+			URL:          "https://example.com/repo-hook",
+			FailingSince: timeutil.TimeStampNow(),
+		}
+
+		recipients := make([]string, 0, 2)
+		defer mockMailSettings(func(msgs ...*Message) {
+			for _, msg := range msgs {
+				recipients = append(recipients, msg.To)
+				assert.Contains(t, msg.Body, "/org3/repo3/settings/hooks/43")
+			}
+		})()
+		MailWebhookDisabled(db.DefaultContext, w)
+		assert.Contains(t, recipients, user2.EmailTo())
+	})
+}
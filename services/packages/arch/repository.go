@@ -0,0 +1,459 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package arch
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	packages_model "code.gitea.io/gitea/models/packages"
+	arch_model "code.gitea.io/gitea/models/packages/arch"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/container"
+	"code.gitea.io/gitea/modules/json"
+	packages_module "code.gitea.io/gitea/modules/packages"
+	arch_module "code.gitea.io/gitea/modules/packages/arch"
+	"code.gitea.io/gitea/modules/util"
+	packages_service "code.gitea.io/gitea/services/packages"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+const (
+	DatabaseFilename      = "repository.db.tar.gz"
+	FilesDatabaseFilename = "repository.files.tar.gz"
+	SignatureExtension    = ".sig"
+)
+
+// GetOrCreateRepositoryVersion gets or creates the internal repository package
+// The Arch registry needs multiple database files which are stored in this package.
+func GetOrCreateRepositoryVersion(ctx context.Context, ownerID int64) (*packages_model.PackageVersion, error) {
+	return packages_service.GetOrCreateInternalPackageVersion(ctx, ownerID, packages_model.TypeArch, arch_module.RepositoryPackage, arch_module.RepositoryVersion)
+}
+
+// GetOrCreateKeyPair gets or creates the PGP keys used to sign packages and repository databases
+func GetOrCreateKeyPair(ctx context.Context, ownerID int64) (string, string, error) {
+	priv, err := user_model.GetSetting(ctx, ownerID, arch_module.SettingKeyPrivate)
+	if err != nil && !errors.Is(err, util.ErrNotExist) {
+		return "", "", err
+	}
+
+	pub, err := user_model.GetSetting(ctx, ownerID, arch_module.SettingKeyPublic)
+	if err != nil && !errors.Is(err, util.ErrNotExist) {
+		return "", "", err
+	}
+
+	if priv == "" || pub == "" {
+		priv, pub, err = generateKeypair()
+		if err != nil {
+			return "", "", err
+		}
+
+		if err := user_model.SetUserSetting(ctx, ownerID, arch_module.SettingKeyPrivate, priv); err != nil {
+			return "", "", err
+		}
+
+		if err := user_model.SetUserSetting(ctx, ownerID, arch_module.SettingKeyPublic, pub); err != nil {
+			return "", "", err
+		}
+	}
+
+	return priv, pub, nil
+}
+
+func generateKeypair() (string, string, error) {
+	e, err := openpgp.NewEntity("", "Arch Registry", "", nil)
+	if err != nil {
+		return "", "", err
+	}
+
+	var priv strings.Builder
+	var pub strings.Builder
+
+	w, err := armor.Encode(&priv, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		return "", "", err
+	}
+	if err := e.SerializePrivate(w, nil); err != nil {
+		return "", "", err
+	}
+	w.Close()
+
+	w, err = armor.Encode(&pub, openpgp.PublicKeyType, nil)
+	if err != nil {
+		return "", "", err
+	}
+	if err := e.Serialize(w); err != nil {
+		return "", "", err
+	}
+	w.Close()
+
+	return priv.String(), pub.String(), nil
+}
+
+// SignData creates a binary detached PGP signature of the data with the key of the owner, pacman expects this format
+func SignData(ctx context.Context, ownerID int64, r io.Reader) ([]byte, error) {
+	priv, _, err := GetOrCreateKeyPair(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := armor.Decode(strings.NewReader(priv))
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := openpgp.ReadEntity(packet.NewReader(block.Body))
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := openpgp.DetachSign(&buf, e, r, nil); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// BuildAllRepositoryFiles (re)builds all repository files for every available distributions and architectures
+func BuildAllRepositoryFiles(ctx context.Context, ownerID int64) error {
+	pv, err := GetOrCreateRepositoryVersion(ctx, ownerID)
+	if err != nil {
+		return err
+	}
+
+	// 1. Delete all existing repository files
+	pfs, err := packages_model.GetFilesByVersionID(ctx, pv.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, pf := range pfs {
+		if err := packages_service.DeletePackageFile(ctx, pf); err != nil {
+			return err
+		}
+	}
+
+	// 2. (Re)Build repository files for existing packages
+	distributions, err := arch_model.GetDistributions(ctx, ownerID)
+	if err != nil {
+		return err
+	}
+	for _, distribution := range distributions {
+		if err := BuildSpecificRepositoryFiles(ctx, ownerID, distribution); err != nil {
+			return fmt.Errorf("failed to build repository files [%s]: %w", distribution, err)
+		}
+	}
+
+	return nil
+}
+
+// BuildSpecificRepositoryFiles builds the databases of every architecture of the distribution.
+// Packages of the "any" architecture are part of all databases, so a change affects the whole distribution.
+func BuildSpecificRepositoryFiles(ctx context.Context, ownerID int64, distribution string) error {
+	pv, err := GetOrCreateRepositoryVersion(ctx, ownerID)
+	if err != nil {
+		return err
+	}
+
+	architectures, err := arch_model.GetArchitectures(ctx, ownerID, distribution)
+	if err != nil {
+		return err
+	}
+
+	// Delete the databases of architectures without packages
+	pfs, err := packages_model.GetFilesByVersionID(ctx, pv.ID)
+	if err != nil {
+		return err
+	}
+	existing := container.SetOf(architectures...)
+	for _, pf := range pfs {
+		d, architecture, _ := strings.Cut(pf.CompositeKey, "|")
+		if d == distribution && !existing.Contains(architecture) {
+			if err := packages_service.DeletePackageFile(ctx, pf); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, architecture := range architectures {
+		if err := buildDatabases(ctx, ownerID, pv, distribution, architecture); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type packageData struct {
+	Package         *packages_model.Package
+	Version         *packages_model.PackageVersion
+	File            *packages_model.PackageFile
+	Blob            *packages_model.PackageBlob
+	VersionMetadata *arch_module.VersionMetadata
+	FileMetadata    *arch_module.FileMetadata
+	Signature       string
+}
+
+func searchPackages(ctx context.Context, ownerID int64, distribution, architecture string) ([]*packageData, error) {
+	pfs, _, err := packages_model.SearchFiles(ctx, &packages_model.PackageFileSearchOptions{
+		OwnerID:     ownerID,
+		PackageType: packages_model.TypeArch,
+		Query:       "%.pkg.tar.%",
+		Properties: map[string]string{
+			arch_module.PropertyDistribution: distribution,
+			arch_module.PropertyArchitecture: architecture,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	pds := make([]*packageData, 0, len(pfs))
+	for _, pf := range pfs {
+		pv, err := packages_model.GetVersionByID(ctx, pf.VersionID)
+		if err != nil {
+			return nil, err
+		}
+		p, err := packages_model.GetPackageByID(ctx, pv.PackageID)
+		if err != nil {
+			return nil, err
+		}
+		pb, err := packages_model.GetBlobByID(ctx, pf.BlobID)
+		if err != nil {
+			return nil, err
+		}
+		pps, err := packages_model.GetProperties(ctx, packages_model.PropertyTypeFile, pf.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		pd := &packageData{
+			Package: p,
+			Version: pv,
+			File:    pf,
+			Blob:    pb,
+		}
+
+		if err := json.Unmarshal([]byte(pv.MetadataJSON), &pd.VersionMetadata); err != nil {
+			return nil, err
+		}
+		for _, pp := range pps {
+			switch pp.Name {
+			case arch_module.PropertyMetadata:
+				if err := json.Unmarshal([]byte(pp.Value), &pd.FileMetadata); err != nil {
+					return nil, err
+				}
+			case arch_module.PropertySignature:
+				pd.Signature = pp.Value
+			}
+		}
+		if pd.FileMetadata == nil {
+			pd.FileMetadata = &arch_module.FileMetadata{}
+		}
+
+		pds = append(pds, pd)
+	}
+	return pds, nil
+}
+
+// https://gitlab.archlinux.org/pacman/pacman/-/blob/master/lib/libalpm/be_sync.c
+func buildDatabases(ctx context.Context, ownerID int64, repoVersion *packages_model.PackageVersion, distribution, architecture string) error {
+	pds, err := searchPackages(ctx, ownerID, distribution, architecture)
+	if err != nil {
+		return err
+	}
+	if architecture != arch_module.AnyArchitecture {
+		anyPds, err := searchPackages(ctx, ownerID, distribution, arch_module.AnyArchitecture)
+		if err != nil {
+			return err
+		}
+		pds = append(pds, anyPds...)
+	}
+
+	// pacman can only handle a single version of a package, only the latest one is listed
+	latest := make(map[string]*packageData)
+	for _, pd := range pds {
+		if current, ok := latest[pd.Package.LowerName]; !ok || arch_module.CompareVersions(current.Version.Version, pd.Version.Version) < 0 {
+			latest[pd.Package.LowerName] = pd
+		}
+	}
+	pds = pds[:0]
+	for _, pd := range latest {
+		pds = append(pds, pd)
+	}
+	sort.Slice(pds, func(i, j int) bool {
+		return pds[i].Package.LowerName < pds[j].Package.LowerName
+	})
+
+	compositeKey := distribution + "|" + architecture
+
+	for _, database := range []struct {
+		Filename     string
+		IncludeFiles bool
+	}{
+		{DatabaseFilename, false},
+		{FilesDatabaseFilename, true},
+	} {
+		content, _ := packages_module.NewHashedBuffer()
+		defer content.Close()
+
+		if err := writeDatabase(content, pds, database.IncludeFiles); err != nil {
+			return err
+		}
+
+		if _, err := content.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+
+		signature, err := SignData(ctx, ownerID, content)
+		if err != nil {
+			return err
+		}
+
+		signatureContent, err := packages_module.CreateHashedBufferFromReader(bytes.NewReader(signature))
+		if err != nil {
+			return err
+		}
+		defer signatureContent.Close()
+
+		for _, file := range []struct {
+			Name string
+			Data packages_module.HashedSizeReader
+		}{
+			{database.Filename, content},
+			{database.Filename + SignatureExtension, signatureContent},
+		} {
+			_, err = packages_service.AddFileToPackageVersionInternal(
+				ctx,
+				repoVersion,
+				&packages_service.PackageFileCreationInfo{
+					PackageFileInfo: packages_service.PackageFileInfo{
+						Filename:     file.Name,
+						CompositeKey: compositeKey,
+					},
+					Creator:           user_model.NewGhostUser(),
+					Data:              file.Data,
+					IsLead:            false,
+					OverwriteExisting: true,
+				},
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func writeDatabase(w io.Writer, pds []*packageData, includeFiles bool) error {
+	zw := gzip.NewWriter(w)
+	defer zw.Close()
+
+	tw := tar.NewWriter(zw)
+	defer tw.Close()
+
+	for _, pd := range pds {
+		dir := pd.Package.Name + "-" + pd.Version.Version + "/"
+
+		if err := tw.WriteHeader(&tar.Header{
+			Name:     dir,
+			Mode:     0o755,
+			Typeflag: tar.TypeDir,
+		}); err != nil {
+			return err
+		}
+
+		if err := writeDatabaseEntry(tw, dir+"desc", buildDesc(pd)); err != nil {
+			return err
+		}
+
+		if includeFiles {
+			var buf bytes.Buffer
+			writeSection(&buf, "FILES", pd.FileMetadata.Files...)
+			if err := writeDatabaseEntry(tw, dir+"files", buf.Bytes()); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func writeDatabaseEntry(tw *tar.Writer, name string, content []byte) error {
+	if err := tw.WriteHeader(&tar.Header{
+		Name: name,
+		Mode: 0o644,
+		Size: int64(len(content)),
+	}); err != nil {
+		return err
+	}
+	_, err := tw.Write(content)
+	return err
+}
+
+func buildDesc(pd *packageData) []byte {
+	var buf bytes.Buffer
+
+	writeSection(&buf, "FILENAME", pd.File.Name)
+	writeSection(&buf, "NAME", pd.Package.Name)
+	writeSection(&buf, "BASE", pd.VersionMetadata.Base)
+	writeSection(&buf, "VERSION", pd.Version.Version)
+	writeSection(&buf, "DESC", pd.VersionMetadata.Description)
+	writeSection(&buf, "GROUPS", pd.VersionMetadata.Groups...)
+	writeSection(&buf, "CSIZE", fmt.Sprint(pd.Blob.Size))
+	writeSection(&buf, "ISIZE", fmt.Sprint(pd.FileMetadata.InstalledSize))
+	writeSection(&buf, "MD5SUM", pd.Blob.HashMD5)
+	writeSection(&buf, "SHA256SUM", pd.Blob.HashSHA256)
+	writeSection(&buf, "PGPSIG", pd.Signature)
+	writeSection(&buf, "URL", pd.VersionMetadata.ProjectURL)
+	writeSection(&buf, "LICENSE", pd.VersionMetadata.Licenses...)
+	writeSection(&buf, "ARCH", pd.FileMetadata.Architecture)
+	writeSection(&buf, "BUILDDATE", fmt.Sprint(pd.FileMetadata.BuildDate))
+	writeSection(&buf, "PACKAGER", pd.FileMetadata.Packager)
+	writeSection(&buf, "REPLACES", pd.FileMetadata.Replaces...)
+	writeSection(&buf, "CONFLICTS", pd.FileMetadata.Conflicts...)
+	writeSection(&buf, "PROVIDES", pd.FileMetadata.Provides...)
+	writeSection(&buf, "DEPENDS", pd.FileMetadata.Depends...)
+	writeSection(&buf, "OPTDEPENDS", pd.FileMetadata.OptDepends...)
+	writeSection(&buf, "MAKEDEPENDS", pd.FileMetadata.MakeDepends...)
+	writeSection(&buf, "CHECKDEPENDS", pd.FileMetadata.CheckDepends...)
+
+	return buf.Bytes()
+}
+
+// writeSection writes a section of a database entry, sections without values are omitted
+func writeSection(w io.Writer, name string, values ...string) {
+	if len(values) == 0 || (len(values) == 1 && values[0] == "") {
+		return
+	}
+	fmt.Fprintf(w, "%%%s%%\n", name)
+	for _, value := range values {
+		fmt.Fprintln(w, value)
+	}
+	fmt.Fprintln(w)
+}
+
+// GetPackageSignature returns the binary detached signature of the package file which was created on upload
+func GetPackageSignature(ctx context.Context, pf *packages_model.PackageFile) ([]byte, error) {
+	pps, err := packages_model.GetPropertiesByName(ctx, packages_model.PropertyTypeFile, pf.ID, arch_module.PropertySignature)
+	if err != nil {
+		return nil, err
+	}
+	if len(pps) == 0 {
+		return nil, util.ErrNotExist
+	}
+	return base64.StdEncoding.DecodeString(pps[0].Value)
+}
@@ -175,7 +175,9 @@ func BuildPackageIndex(ctx context.Context, p *packages_model.Package) (*bytes.B
 			features = make(map[string][]string)
 		}
 
+		// deprecated versions are yanked too
 		yanked, _ := strconv.ParseBool(pd.VersionProperties.GetByName(cargo_module.PropertyYanked))
+		yanked = yanked || pd.DeprecationMessage() != ""
 		entry, err := json.Marshal(&IndexVersionEntry{
 			Name:         pd.Package.Name,
 			Version:      pd.Version.Version,
@@ -16,9 +16,11 @@ import (
 	packages_module "code.gitea.io/gitea/modules/packages"
 	packages_service "code.gitea.io/gitea/services/packages"
 	alpine_service "code.gitea.io/gitea/services/packages/alpine"
+	arch_service "code.gitea.io/gitea/services/packages/arch"
 	cargo_service "code.gitea.io/gitea/services/packages/cargo"
 	container_service "code.gitea.io/gitea/services/packages/container"
 	debian_service "code.gitea.io/gitea/services/packages/debian"
+	nix_service "code.gitea.io/gitea/services/packages/nix"
 	rpm_service "code.gitea.io/gitea/services/packages/rpm"
 )
 
@@ -78,6 +80,14 @@ func ExecuteCleanupRules(outerCtx context.Context) error {
 					}
 				}
 
+				// the cleanup rules of the owner apply to immutable versions too, only the locked versions are kept
+				if locked, err := packages_service.IsVersionRetentionLocked(ctx, pv); err != nil {
+					return fmt.Errorf("CleanupRule [%d]: IsVersionRetentionLocked failed: %w", pcr.ID, err)
+				} else if locked {
+					log.Debug("Rule[%d]: keep '%s/%s' (locked)", pcr.ID, p.Name, pv.Version)
+					continue
+				}
+
 				toMatch := pv.LowerVersion
 				if pcr.MatchFullName {
 					toMatch = p.LowerName + "/" + pv.LowerVersion
@@ -128,6 +138,10 @@ func ExecuteCleanupRules(outerCtx context.Context) error {
 				if err := alpine_service.BuildAllRepositoryFiles(ctx, pcr.OwnerID); err != nil {
 					return fmt.Errorf("CleanupRule [%d]: alpine.BuildAllRepositoryFiles failed: %w", pcr.ID, err)
 				}
+			} else if pcr.Type == packages_model.TypeArch {
+				if err := arch_service.BuildAllRepositoryFiles(ctx, pcr.OwnerID); err != nil {
+					return fmt.Errorf("CleanupRule [%d]: arch.BuildAllRepositoryFiles failed: %w", pcr.ID, err)
+				}
 			} else if pcr.Type == packages_model.TypeRpm {
 				if err := rpm_service.BuildAllRepositoryFiles(ctx, pcr.OwnerID); err != nil {
 					return fmt.Errorf("CleanupRule [%d]: rpm.BuildAllRepositoryFiles failed: %w", pcr.ID, err)
@@ -154,6 +168,10 @@ func CleanupExpiredData(outerCtx context.Context, olderThan time.Duration) error
 		return err
 	}
 
+	if err := nix_service.Cleanup(ctx, olderThan); err != nil {
+		return err
+	}
+
 	ps, err := packages_model.FindUnreferencedPackages(ctx)
 	if err != nil {
 		return err
@@ -71,6 +71,10 @@ func cleanupExpiredUploadedBlobs(ctx context.Context, olderThan time.Duration) e
 			return err
 		}
 
+		if err := packages_model.DeleteDownloadsByVersionID(ctx, pv.ID); err != nil {
+			return err
+		}
+
 		if err := packages_model.DeleteVersionByID(ctx, pv.ID); err != nil {
 			return err
 		}
@@ -104,5 +108,23 @@ func ShouldBeSkipped(ctx context.Context, pcr *packages_model.PackageCleanupRule
 		}
 	}
 
+	// Skip signatures and other artifacts which are attached to an existing manifest
+	pps, err := packages_model.GetPropertiesByName(ctx, packages_model.PropertyTypeVersion, pv.ID, container_module.PropertyManifestSubject)
+	if err != nil {
+		return false, err
+	}
+	for _, pp := range pps {
+		if _, err := container_model.GetContainerBlob(ctx, &container_model.BlobSearchOptions{
+			OwnerID:    p.OwnerID,
+			Image:      p.LowerName,
+			Digest:     pp.Value,
+			IsManifest: true,
+		}); err == nil {
+			return true, nil
+		} else if err != container_model.ErrContainerBlobNotExist {
+			return false, err
+		}
+	}
+
 	return false, nil
 }
@@ -0,0 +1,59 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package container
+
+import (
+	"context"
+
+	packages_model "code.gitea.io/gitea/models/packages"
+	container_model "code.gitea.io/gitea/models/packages/container"
+	"code.gitea.io/gitea/modules/container"
+	container_module "code.gitea.io/gitea/modules/packages/container"
+)
+
+// GetReferrers gets the manifests of an image which refer to the manifest with the given digest as their subject.
+// If the artifact type isn't empty only manifests of this type are returned.
+// A manifest which is stored under multiple tags is returned only once.
+func GetReferrers(ctx context.Context, ownerID int64, image, subject, artifactType string) ([]*packages_model.PackageDescriptor, error) {
+	pvs, err := container_model.GetManifestVersions(ctx, &container_model.BlobSearchOptions{
+		OwnerID:    ownerID,
+		Image:      image,
+		Subject:    subject,
+		IsManifest: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	pds, err := packages_model.GetPackageDescriptors(ctx, pvs)
+	if err != nil {
+		return nil, err
+	}
+
+	digests := make(container.Set[string])
+	referrers := make([]*packages_model.PackageDescriptor, 0, len(pds))
+	for _, pd := range pds {
+		if artifactType != "" && pd.Metadata.(*container_module.Metadata).ArtifactType != artifactType {
+			continue
+		}
+
+		pfd := GetManifestFile(pd)
+		if pfd == nil || !digests.Add(pfd.Properties.GetByName(container_module.PropertyDigest)) {
+			continue
+		}
+
+		referrers = append(referrers, pd)
+	}
+	return referrers, nil
+}
+
+// GetManifestFile gets the manifest file of a container package version
+func GetManifestFile(pd *packages_model.PackageDescriptor) *packages_model.PackageFileDescriptor {
+	for _, pfd := range pd.Files {
+		if pfd.File.LowerName == container_model.ManifestFilename {
+			return pfd
+		}
+	}
+	return nil
+}
@@ -0,0 +1,252 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package nix
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"code.gitea.io/gitea/models/db"
+	packages_model "code.gitea.io/gitea/models/packages"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/optional"
+	packages_module "code.gitea.io/gitea/modules/packages"
+	nix_module "code.gitea.io/gitea/modules/packages/nix"
+	"code.gitea.io/gitea/modules/util"
+	packages_service "code.gitea.io/gitea/services/packages"
+)
+
+var (
+	ErrNarNotExist      = util.NewInvalidArgumentErrorf("the NAR file of the narinfo has not been uploaded")
+	ErrNarMismatch      = util.NewInvalidArgumentErrorf("the NAR file does not match the narinfo")
+	ErrUntrustedNarInfo = util.NewInvalidArgumentErrorf("the narinfo is not signed by a trusted key")
+	ErrHashMismatch     = util.NewInvalidArgumentErrorf("the store path does not match the narinfo name")
+)
+
+// GetTrustedKeys gets the public keys which must sign the uploaded narinfo files of the owner
+func GetTrustedKeys(ctx context.Context, ownerID int64) ([]*nix_module.PublicKey, error) {
+	setting, err := user_model.GetSetting(ctx, ownerID, nix_module.SettingKeyTrustedKeys)
+	if err != nil && !errors.Is(err, util.ErrNotExist) {
+		return nil, err
+	}
+
+	keys := make([]*nix_module.PublicKey, 0, 2)
+	for _, line := range strings.Split(setting, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		key, err := nix_module.ParsePublicKey(line)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// SetTrustedKeys sets the public keys which must sign the uploaded narinfo files of the owner.
+// Without keys the signatures are not checked.
+func SetTrustedKeys(ctx context.Context, ownerID int64, keys []*nix_module.PublicKey) error {
+	if len(keys) == 0 {
+		return user_model.DeleteUserSetting(ctx, ownerID, nix_module.SettingKeyTrustedKeys)
+	}
+
+	lines := make([]string, 0, len(keys))
+	for _, key := range keys {
+		lines = append(lines, key.String())
+	}
+	return user_model.SetUserSetting(ctx, ownerID, nix_module.SettingKeyTrustedKeys, strings.Join(lines, "\n"))
+}
+
+// GetOrCreateUploadVersion gets or creates the internal package which holds the NAR files without narinfo
+func GetOrCreateUploadVersion(ctx context.Context, ownerID int64) (*packages_model.PackageVersion, error) {
+	return packages_service.GetOrCreateInternalPackageVersion(ctx, ownerID, packages_model.TypeNix, nix_module.UploadPackage, nix_module.UploadVersion)
+}
+
+// GetStorePathVersion gets the package version of a store path by the hash part of the path
+func GetStorePathVersion(ctx context.Context, ownerID int64, hash string) (*packages_model.PackageVersion, error) {
+	pvs, _, err := packages_model.SearchVersions(ctx, &packages_model.PackageSearchOptions{
+		OwnerID: ownerID,
+		Type:    packages_model.TypeNix,
+		Version: packages_model.SearchValue{
+			ExactMatch: true,
+			Value:      hash,
+		},
+		IsInternal: optional.Some(false),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(pvs) == 0 {
+		return nil, packages_model.ErrPackageNotExist
+	}
+	return pvs[0], nil
+}
+
+// GetNarFile gets a NAR file by name. The file may not be referenced by a narinfo yet.
+func GetNarFile(ctx context.Context, ownerID int64, filename string) (*packages_model.PackageFile, error) {
+	uploadVersion, err := GetOrCreateUploadVersion(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	pf, err := packages_model.GetFileForVersionByName(ctx, uploadVersion.ID, filename, packages_model.EmptyFileKey)
+	if err == nil || !errors.Is(err, util.ErrNotExist) {
+		return pf, err
+	}
+
+	pfs, _, err := packages_model.SearchFiles(ctx, &packages_model.PackageFileSearchOptions{
+		OwnerID:     ownerID,
+		PackageType: packages_model.TypeNix,
+		Query:       filename,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(pfs) == 0 {
+		return nil, packages_model.ErrPackageFileNotExist
+	}
+	return pfs[0], nil
+}
+
+// UploadNar stores a NAR file until the narinfo which references it is uploaded
+func UploadNar(ctx context.Context, doer, owner *user_model.User, filename string, data packages_module.HashedSizeReader) error {
+	if err := packages_service.CheckSizeQuotaExceeded(ctx, doer, owner, packages_model.TypeNix, data.Size()); err != nil {
+		return err
+	}
+
+	uploadVersion, err := GetOrCreateUploadVersion(ctx, owner.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = packages_service.AddFileToPackageVersionInternal(
+		ctx,
+		uploadVersion,
+		&packages_service.PackageFileCreationInfo{
+			PackageFileInfo: packages_service.PackageFileInfo{
+				Filename: filename,
+			},
+			Creator:           doer,
+			Data:              data,
+			OverwriteExisting: true,
+		},
+	)
+	return err
+}
+
+// UploadNarInfo creates the package version of a store path from the narinfo and the previously uploaded NAR file.
+// The NAR file is linked to the version and shares the blob with other versions which reference the same file.
+func UploadNarInfo(ctx context.Context, doer, owner *user_model.User, hash string, ni *nix_module.NarInfo, data packages_module.HashedSizeReader) (*packages_model.PackageVersion, error) {
+	if ni.Hash() != hash {
+		return nil, ErrHashMismatch
+	}
+
+	keys, err := GetTrustedKeys(ctx, owner.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) > 0 && !ni.IsSignedBy(keys) {
+		return nil, ErrUntrustedNarInfo
+	}
+
+	narFile, err := GetNarFile(ctx, owner.ID, ni.NarFilename())
+	if err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			return nil, ErrNarNotExist
+		}
+		return nil, err
+	}
+
+	narBlob, err := packages_model.GetBlobByID(ctx, narFile.BlobID)
+	if err != nil {
+		return nil, err
+	}
+	if (ni.FileSize > 0 && ni.FileSize != narBlob.Size) || !ni.MatchesFileHash(narBlob.HashSHA256) {
+		return nil, ErrNarMismatch
+	}
+
+	uploadVersion, err := GetOrCreateUploadVersion(ctx, owner.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var pv *packages_model.PackageVersion
+	err = db.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		pv, _, err = packages_service.CreatePackageAndAddFile(
+			ctx,
+			&packages_service.PackageCreationInfo{
+				PackageInfo: packages_service.PackageInfo{
+					Owner:       owner,
+					PackageType: packages_model.TypeNix,
+					Name:        ni.Name(),
+					Version:     hash,
+				},
+				Creator:  doer,
+				Metadata: ni.VersionMetadata(),
+			},
+			&packages_service.PackageFileCreationInfo{
+				PackageFileInfo: packages_service.PackageFileInfo{
+					Filename: hash + nix_module.NarInfoExtension,
+				},
+				Creator: doer,
+				Data:    data,
+				IsLead:  true,
+			},
+		)
+		if err != nil {
+			return err
+		}
+
+		if _, err := packages_model.TryInsertFile(ctx, &packages_model.PackageFile{
+			VersionID: pv.ID,
+			BlobID:    narBlob.ID,
+			Name:      narFile.Name,
+			LowerName: narFile.LowerName,
+		}); err != nil {
+			return err
+		}
+
+		if narFile.VersionID == uploadVersion.ID {
+			return packages_service.DeletePackageFile(ctx, narFile)
+		}
+		return nil
+	})
+	return pv, err
+}
+
+// Cleanup removes the uploaded NAR files which haven't been referenced by a narinfo in time
+func Cleanup(ctx context.Context, olderThan time.Duration) error {
+	pvs, _, err := packages_model.SearchVersions(ctx, &packages_model.PackageSearchOptions{
+		Type: packages_model.TypeNix,
+		Name: packages_model.SearchValue{
+			ExactMatch: true,
+			Value:      nix_module.UploadPackage,
+		},
+		IsInternal: optional.Some(true),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, pv := range pvs {
+		pfs, _, err := packages_model.SearchFiles(ctx, &packages_model.PackageFileSearchOptions{
+			VersionID: pv.ID,
+			OlderThan: olderThan,
+		})
+		if err != nil {
+			return err
+		}
+		for _, pf := range pfs {
+			if err := packages_service.DeletePackageFile(ctx, pf); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -14,6 +14,7 @@ import (
 
 	"code.gitea.io/gitea/models/db"
 	packages_model "code.gitea.io/gitea/models/packages"
+	quota_model "code.gitea.io/gitea/models/quota"
 	repo_model "code.gitea.io/gitea/models/repo"
 	user_model "code.gitea.io/gitea/models/user"
 	"code.gitea.io/gitea/modules/json"
@@ -290,6 +291,11 @@ func addFileToPackageVersionUnchecked(ctx context.Context, pv *packages_model.Pa
 				return pf, pb, !exists, nil
 			}
 
+			// the files of locked or immutable versions can't be replaced
+			if err := CheckVersionRemovable(ctx, pv); err != nil {
+				return nil, pb, !exists, err
+			}
+
 			if err := packages_model.DeleteAllProperties(ctx, packages_model.PropertyTypeFile, pf.ID); err != nil {
 				return nil, pb, !exists, err
 			}
@@ -348,59 +354,69 @@ func CheckCountQuotaExceeded(ctx context.Context, doer, owner *user_model.User)
 	return nil
 }
 
-// CheckSizeQuotaExceeded checks if the upload size is bigger than the allowed size
-// The check is skipped if the doer is an admin.
-func CheckSizeQuotaExceeded(ctx context.Context, doer, owner *user_model.User, packageType packages_model.Type, uploadSize int64) error {
-	if doer.IsAdmin {
-		return nil
-	}
-
-	var typeSpecificSize int64
+// TypeSizeLimit returns the maximum size of a package file of the type, -1 if the size isn't limited
+func TypeSizeLimit(packageType packages_model.Type) int64 {
 	switch packageType {
 	case packages_model.TypeAlpine:
-		typeSpecificSize = setting.Packages.LimitSizeAlpine
+		return setting.Packages.LimitSizeAlpine
+	case packages_model.TypeArch:
+		return setting.Packages.LimitSizeArch
 	case packages_model.TypeCargo:
-		typeSpecificSize = setting.Packages.LimitSizeCargo
+		return setting.Packages.LimitSizeCargo
 	case packages_model.TypeChef:
-		typeSpecificSize = setting.Packages.LimitSizeChef
+		return setting.Packages.LimitSizeChef
 	case packages_model.TypeComposer:
-		typeSpecificSize = setting.Packages.LimitSizeComposer
+		return setting.Packages.LimitSizeComposer
 	case packages_model.TypeConan:
-		typeSpecificSize = setting.Packages.LimitSizeConan
+		return setting.Packages.LimitSizeConan
 	case packages_model.TypeConda:
-		typeSpecificSize = setting.Packages.LimitSizeConda
+		return setting.Packages.LimitSizeConda
 	case packages_model.TypeContainer:
-		typeSpecificSize = setting.Packages.LimitSizeContainer
+		return setting.Packages.LimitSizeContainer
 	case packages_model.TypeCran:
-		typeSpecificSize = setting.Packages.LimitSizeCran
+		return setting.Packages.LimitSizeCran
 	case packages_model.TypeDebian:
-		typeSpecificSize = setting.Packages.LimitSizeDebian
+		return setting.Packages.LimitSizeDebian
 	case packages_model.TypeGeneric:
-		typeSpecificSize = setting.Packages.LimitSizeGeneric
+		return setting.Packages.LimitSizeGeneric
 	case packages_model.TypeGo:
-		typeSpecificSize = setting.Packages.LimitSizeGo
+		return setting.Packages.LimitSizeGo
 	case packages_model.TypeHelm:
-		typeSpecificSize = setting.Packages.LimitSizeHelm
+		return setting.Packages.LimitSizeHelm
 	case packages_model.TypeMaven:
-		typeSpecificSize = setting.Packages.LimitSizeMaven
+		return setting.Packages.LimitSizeMaven
+	case packages_model.TypeNix:
+		return setting.Packages.LimitSizeNix
 	case packages_model.TypeNpm:
-		typeSpecificSize = setting.Packages.LimitSizeNpm
+		return setting.Packages.LimitSizeNpm
 	case packages_model.TypeNuGet:
-		typeSpecificSize = setting.Packages.LimitSizeNuGet
+		return setting.Packages.LimitSizeNuGet
 	case packages_model.TypePub:
-		typeSpecificSize = setting.Packages.LimitSizePub
+		return setting.Packages.LimitSizePub
 	case packages_model.TypePyPI:
-		typeSpecificSize = setting.Packages.LimitSizePyPI
+		return setting.Packages.LimitSizePyPI
 	case packages_model.TypeRpm:
-		typeSpecificSize = setting.Packages.LimitSizeRpm
+		return setting.Packages.LimitSizeRpm
 	case packages_model.TypeRubyGems:
-		typeSpecificSize = setting.Packages.LimitSizeRubyGems
+		return setting.Packages.LimitSizeRubyGems
 	case packages_model.TypeSwift:
-		typeSpecificSize = setting.Packages.LimitSizeSwift
+		return setting.Packages.LimitSizeSwift
+	case packages_model.TypeTerraform:
+		return setting.Packages.LimitSizeTerraform
 	case packages_model.TypeVagrant:
-		typeSpecificSize = setting.Packages.LimitSizeVagrant
+		return setting.Packages.LimitSizeVagrant
+	}
+	return 0
+}
+
+// CheckSizeQuotaExceeded checks if the upload size is bigger than the allowed size
+// The check is skipped if the doer is an admin.
+func CheckSizeQuotaExceeded(ctx context.Context, doer, owner *user_model.User, packageType packages_model.Type, uploadSize int64) error {
+	if doer.IsAdmin {
+		return nil
 	}
-	if typeSpecificSize > -1 && typeSpecificSize < uploadSize {
+
+	if typeSpecificSize := TypeSizeLimit(packageType); typeSpecificSize > -1 && typeSpecificSize < uploadSize {
 		return ErrQuotaTypeSize
 	}
 
@@ -417,6 +433,14 @@ func CheckSizeQuotaExceeded(ctx context.Context, doer, owner *user_model.User, p
 		}
 	}
 
+	if err := quota_model.CheckForUser(ctx, owner.ID, quota_model.LimitSubjectSizeAssetsPackages); err != nil {
+		if quota_model.IsErrQuotaExceeded(err) {
+			return ErrQuotaTotalSize
+		}
+		log.Error("CheckForUser failed: %v", err)
+		return err
+	}
+
 	return nil
 }
 
@@ -478,6 +502,10 @@ func RemovePackageVersion(ctx context.Context, doer *user_model.User, pv *packag
 	}
 	defer committer.Close()
 
+	if err := CheckVersionRemovable(dbCtx, pv); err != nil {
+		return err
+	}
+
 	pd, err := packages_model.GetPackageDescriptor(dbCtx, pv)
 	if err != nil {
 		return err
@@ -503,6 +531,15 @@ func RemovePackageFileAndVersionIfUnreferenced(ctx context.Context, doer *user_m
 	var pd *packages_model.PackageDescriptor
 
 	if err := db.WithTx(ctx, func(ctx context.Context) error {
+		pv, err := packages_model.GetVersionByID(ctx, pf.VersionID)
+		if err != nil {
+			return err
+		}
+
+		if err := CheckVersionRemovable(ctx, pv); err != nil {
+			return err
+		}
+
 		if err := DeletePackageFile(ctx, pf); err != nil {
 			return err
 		}
@@ -512,11 +549,6 @@ func RemovePackageFileAndVersionIfUnreferenced(ctx context.Context, doer *user_m
 			return err
 		}
 		if !has {
-			pv, err := packages_model.GetVersionByID(ctx, pf.VersionID)
-			if err != nil {
-				return err
-			}
-
 			pd, err = packages_model.GetPackageDescriptor(ctx, pv)
 			if err != nil {
 				return err
@@ -545,6 +577,10 @@ func DeletePackageVersionAndReferences(ctx context.Context, pv *packages_model.P
 		return err
 	}
 
+	if err := packages_model.DeleteDownloadsByVersionID(ctx, pv.ID); err != nil {
+		return err
+	}
+
 	pfs, err := packages_model.GetFilesByVersionID(ctx, pv.ID)
 	if err != nil {
 		return err
@@ -568,7 +604,7 @@ func DeletePackageFile(ctx context.Context, pf *packages_model.PackageFile) erro
 }
 
 // GetFileStreamByPackageNameAndVersion returns the content of the specific package file
-func GetFileStreamByPackageNameAndVersion(ctx context.Context, pvi *PackageInfo, pfi *PackageFileInfo) (io.ReadSeekCloser, *url.URL, *packages_model.PackageFile, error) {
+func GetFileStreamByPackageNameAndVersion(ctx context.Context, doer *user_model.User, pvi *PackageInfo, pfi *PackageFileInfo) (io.ReadSeekCloser, *url.URL, *packages_model.PackageFile, error) {
 	log.Trace("Getting package file stream: %v, %v, %s, %s, %s, %s", pvi.Owner.ID, pvi.PackageType, pvi.Name, pvi.Version, pfi.Filename, pfi.CompositeKey)
 
 	pv, err := packages_model.GetVersionByNameAndVersion(ctx, pvi.Owner.ID, pvi.PackageType, pvi.Name, pvi.Version)
@@ -580,32 +616,33 @@ func GetFileStreamByPackageNameAndVersion(ctx context.Context, pvi *PackageInfo,
 		return nil, nil, nil, err
 	}
 
-	return GetFileStreamByPackageVersion(ctx, pv, pfi)
+	return GetFileStreamByPackageVersion(ctx, doer, pv, pfi)
 }
 
 // GetFileStreamByPackageVersion returns the content of the specific package file
-func GetFileStreamByPackageVersion(ctx context.Context, pv *packages_model.PackageVersion, pfi *PackageFileInfo) (io.ReadSeekCloser, *url.URL, *packages_model.PackageFile, error) {
+func GetFileStreamByPackageVersion(ctx context.Context, doer *user_model.User, pv *packages_model.PackageVersion, pfi *PackageFileInfo) (io.ReadSeekCloser, *url.URL, *packages_model.PackageFile, error) {
 	pf, err := packages_model.GetFileForVersionByName(ctx, pv.ID, pfi.Filename, pfi.CompositeKey)
 	if err != nil {
 		return nil, nil, nil, err
 	}
 
-	return GetPackageFileStream(ctx, pf)
+	return GetPackageFileStream(ctx, doer, pf)
 }
 
 // GetPackageFileStream returns the content of the specific package file
-func GetPackageFileStream(ctx context.Context, pf *packages_model.PackageFile) (io.ReadSeekCloser, *url.URL, *packages_model.PackageFile, error) {
+func GetPackageFileStream(ctx context.Context, doer *user_model.User, pf *packages_model.PackageFile) (io.ReadSeekCloser, *url.URL, *packages_model.PackageFile, error) {
 	pb, err := packages_model.GetBlobByID(ctx, pf.BlobID)
 	if err != nil {
 		return nil, nil, nil, err
 	}
 
-	return GetPackageBlobStream(ctx, pf, pb)
+	return GetPackageBlobStream(ctx, doer, pf, pb)
 }
 
 // GetPackageBlobStream returns the content of the specific package blob
 // If the storage supports direct serving and it's enabled, only the direct serving url is returned.
-func GetPackageBlobStream(ctx context.Context, pf *packages_model.PackageFile, pb *packages_model.PackageBlob) (io.ReadSeekCloser, *url.URL, *packages_model.PackageFile, error) {
+// The download is recorded for the doer, which is nil for anonymous downloads.
+func GetPackageBlobStream(ctx context.Context, doer *user_model.User, pf *packages_model.PackageFile, pb *packages_model.PackageBlob) (io.ReadSeekCloser, *url.URL, *packages_model.PackageFile, error) {
 	key := packages_module.BlobHash256Key(pb.HashSHA256)
 
 	cs := packages_module.NewContentStore()
@@ -629,6 +666,13 @@ func GetPackageBlobStream(ctx context.Context, pf *packages_model.PackageFile, p
 			if err := packages_model.IncrementDownloadCounter(ctx, pf.VersionID); err != nil {
 				log.Error("Error incrementing download counter: %v", err)
 			}
+			var doerID int64
+			if doer != nil {
+				doerID = doer.ID
+			}
+			if err := packages_model.InsertDownload(ctx, pf.VersionID, doerID); err != nil {
+				log.Error("Error recording download: %v", err)
+			}
 		}
 	}
 	return s, u, pf, err
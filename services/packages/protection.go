@@ -0,0 +1,96 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package packages
+
+import (
+	"context"
+	"strconv"
+
+	packages_model "code.gitea.io/gitea/models/packages"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/util"
+)
+
+// SettingKeyImmutableVersions is the owner setting which makes the released package versions immutable
+const SettingKeyImmutableVersions = "packages.immutable_versions"
+
+var (
+	ErrVersionImmutable       = util.NewPermissionDeniedErrorf("released package versions are immutable")
+	ErrVersionRetentionLocked = util.NewPermissionDeniedErrorf("the package version is locked")
+)
+
+// IsImmutableVersionsEnabled checks if the released package versions of the owner are immutable
+func IsImmutableVersionsEnabled(ctx context.Context, ownerID int64) (bool, error) {
+	value, err := user_model.GetUserSetting(ctx, ownerID, SettingKeyImmutableVersions, "false")
+	if err != nil {
+		return false, err
+	}
+	return strconv.ParseBool(value)
+}
+
+// SetImmutableVersionsEnabled sets if the released package versions of the owner are immutable
+func SetImmutableVersionsEnabled(ctx context.Context, ownerID int64, enabled bool) error {
+	if !enabled {
+		return user_model.DeleteUserSetting(ctx, ownerID, SettingKeyImmutableVersions)
+	}
+	return user_model.SetUserSetting(ctx, ownerID, SettingKeyImmutableVersions, strconv.FormatBool(enabled))
+}
+
+// CheckVersionRemovable checks if the files of the version may be deleted.
+// Locked versions can never be deleted, released versions can't be deleted if the owner made them immutable.
+func CheckVersionRemovable(ctx context.Context, pv *packages_model.PackageVersion) error {
+	locked, err := IsVersionRetentionLocked(ctx, pv)
+	if err != nil {
+		return err
+	}
+	if locked {
+		return ErrVersionRetentionLocked
+	}
+
+	if pv.IsInternal {
+		return nil
+	}
+
+	p, err := packages_model.GetPackageByID(ctx, pv.PackageID)
+	if err != nil {
+		return err
+	}
+	immutable, err := IsImmutableVersionsEnabled(ctx, p.OwnerID)
+	if err != nil {
+		return err
+	}
+	if immutable {
+		return ErrVersionImmutable
+	}
+	return nil
+}
+
+// IsVersionRetentionLocked checks if the version is protected from deletion and cleanup rules
+func IsVersionRetentionLocked(ctx context.Context, pv *packages_model.PackageVersion) (bool, error) {
+	return packages_model.ExistProperty(ctx, packages_model.PropertyTypeVersion, pv.ID, packages_model.PropertyVersionRetentionLocked)
+}
+
+// SetVersionRetentionLock locks or unlocks the version
+func SetVersionRetentionLock(ctx context.Context, pv *packages_model.PackageVersion, locked bool) error {
+	if err := packages_model.DeletePropertyByName(ctx, packages_model.PropertyTypeVersion, pv.ID, packages_model.PropertyVersionRetentionLocked); err != nil {
+		return err
+	}
+	if !locked {
+		return nil
+	}
+	_, err := packages_model.InsertProperty(ctx, packages_model.PropertyTypeVersion, pv.ID, packages_model.PropertyVersionRetentionLocked, strconv.FormatBool(locked))
+	return err
+}
+
+// SetVersionDeprecation marks the version as deprecated with the message. An empty message removes the deprecation.
+func SetVersionDeprecation(ctx context.Context, pv *packages_model.PackageVersion, message string) error {
+	if err := packages_model.DeletePropertyByName(ctx, packages_model.PropertyTypeVersion, pv.ID, packages_model.PropertyVersionDeprecated); err != nil {
+		return err
+	}
+	if message == "" {
+		return nil
+	}
+	_, err := packages_model.InsertProperty(ctx, packages_model.PropertyTypeVersion, pv.ID, packages_model.PropertyVersionDeprecated, message)
+	return err
+}
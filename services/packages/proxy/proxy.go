@@ -0,0 +1,194 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+// Package proxy requests the upstream registries proxied by the owners of packages.
+// The packages which aren't found in the registry of an owner are fetched from its upstream registry
+// and stored as if they had been uploaded by the owner, they are served from the registry of the owner afterwards.
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	packages_model "code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/modules/hostmatcher"
+	"code.gitea.io/gitea/modules/json"
+	packages_module "code.gitea.io/gitea/modules/packages"
+	proxy_module "code.gitea.io/gitea/modules/proxy"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/util"
+	packages_service "code.gitea.io/gitea/services/packages"
+)
+
+// ErrUpstreamNotExist indicates that a file does not exist in the upstream registry
+var ErrUpstreamNotExist = util.NewNotExistErrorf("file does not exist in the upstream registry")
+
+// UpstreamError is an error of a request to an upstream registry
+type UpstreamError struct {
+	Err error
+}
+
+func (e *UpstreamError) Error() string {
+	return "upstream registry: " + e.Err.Error()
+}
+
+func (e *UpstreamError) Unwrap() error {
+	return e.Err
+}
+
+var getHTTPClient = sync.OnceValue(func() *http.Client {
+	allowedHostListValue := setting.Packages.ProxyAllowedHostList
+	if allowedHostListValue == "" {
+		allowedHostListValue = hostmatcher.MatchBuiltinExternal
+	}
+	allowedHostMatcher := hostmatcher.ParseHostMatchList("packages.PROXY_ALLOWED_HOST_LIST", allowedHostListValue)
+
+	return &http.Client{
+		Timeout: setting.Packages.ProxyTimeout,
+		Transport: &http.Transport{
+			Proxy:       proxy_module.Proxy(),
+			DialContext: hostmatcher.NewDialContext("package proxy", allowedHostMatcher, nil),
+		},
+	}
+})
+
+// Client requests the upstream registry of a proxy
+type Client struct {
+	base     *url.URL
+	username string
+	password string
+}
+
+// NewClient creates a client for the upstream registry of a proxy
+func NewClient(pp *packages_model.PackageProxy) (*Client, error) {
+	base, err := url.Parse(strings.TrimSuffix(pp.URL, "/"))
+	if err != nil {
+		return nil, err
+	}
+	password, err := pp.Password()
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		base:     base,
+		username: pp.Username,
+		password: password,
+	}, nil
+}
+
+// GetClient returns the client of the enabled proxy of an owner for a package type, or nil if the packages aren't proxied
+func GetClient(ctx context.Context, ownerID int64, packageType packages_model.Type) (*Client, error) {
+	pp, err := packages_model.GetEnabledProxy(ctx, ownerID, packageType)
+	if err != nil || pp == nil {
+		return nil, err
+	}
+	return NewClient(pp)
+}
+
+// URL returns the absolute URL of an escaped path relative to the upstream registry
+func (c *Client) URL(escapedPath string) string {
+	return c.base.String() + "/" + strings.TrimPrefix(escapedPath, "/")
+}
+
+// SetCredentials sets the credentials of the proxy on a request which has no authorization
+func (c *Client) SetCredentials(req *http.Request) {
+	if req.Header.Get("Authorization") == "" && (c.username != "" || c.password != "") {
+		req.SetBasicAuth(c.username, c.password)
+	}
+}
+
+// Do sends a request, the credentials of the proxy are only sent to the host of the upstream registry
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	if req.URL.Host == c.base.Host {
+		c.SetCredentials(req)
+	}
+	req.Header.Set("User-Agent", "Forgejo/"+setting.AppVer)
+	resp, err := getHTTPClient().Do(req)
+	if err != nil {
+		return nil, &UpstreamError{err}
+	}
+	return resp, nil
+}
+
+// Get requests a URL, ErrUpstreamNotExist is returned if it does not exist
+func (c *Client) Get(ctx context.Context, u string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	return resp, CheckResponse(resp)
+}
+
+// CheckResponse closes the body of a response whose status is not a success and returns the matching error
+func CheckResponse(resp *http.Response) error {
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		resp.Body.Close()
+		return ErrUpstreamNotExist
+	default:
+		resp.Body.Close()
+		return &UpstreamError{fmt.Errorf("unexpected status %s of %s", resp.Status, resp.Request.URL.Redacted())}
+	}
+}
+
+// GetJSON decodes the JSON document of a URL
+func (c *Client) GetJSON(ctx context.Context, u string, v any) error {
+	resp, err := c.Get(ctx, u, http.Header{"Accept": []string{"application/json"}})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return &UpstreamError{err}
+	}
+	return nil
+}
+
+// Download buffers the content of a URL, the content must not be larger than limit bytes unless limit is -1
+func (c *Client) Download(ctx context.Context, u string, limit int64) (*packages_module.HashedBuffer, error) {
+	resp, err := c.Get(ctx, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return ReadBody(resp, limit)
+}
+
+// ReadBody buffers the body of a response, packages_service.ErrQuotaTypeSize is returned
+// as soon as the body is known to be larger than limit bytes unless limit is -1
+func ReadBody(resp *http.Response, limit int64) (*packages_module.HashedBuffer, error) {
+	var body io.Reader = resp.Body
+	if limit > -1 {
+		if resp.ContentLength > limit {
+			return nil, packages_service.ErrQuotaTypeSize
+		}
+		body = &io.LimitedReader{R: resp.Body, N: limit + 1}
+	}
+
+	buf, err := packages_module.CreateHashedBufferFromReader(body)
+	if err != nil {
+		return nil, &UpstreamError{err}
+	}
+	if limit > -1 && buf.Size() > limit {
+		buf.Close()
+		return nil, packages_service.ErrQuotaTypeSize
+	}
+	return buf, nil
+}
@@ -0,0 +1,157 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package packages
+
+import (
+	"context"
+	"time"
+
+	"code.gitea.io/gitea/models/db"
+	packages_model "code.gitea.io/gitea/models/packages"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/timeutil"
+)
+
+const aggregateDownloadsBatchSize = 1000
+
+// AggregateDownloads sums up the recorded downloads per version, user and day and removes the aggregated records
+func AggregateDownloads(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return db.ErrCancelledf("while aggregating package downloads")
+		default:
+		}
+
+		n, err := aggregateDownloadsBatch(ctx)
+		if err != nil {
+			return err
+		}
+		if n < aggregateDownloadsBatchSize {
+			return nil
+		}
+	}
+}
+
+func aggregateDownloadsBatch(ctx context.Context) (int, error) {
+	type statKey struct {
+		VersionID int64
+		UserID    int64
+		Day       timeutil.TimeStamp
+	}
+
+	var n int
+	err := db.WithTx(ctx, func(ctx context.Context) error {
+		pds, err := packages_model.GetOldestDownloads(ctx, aggregateDownloadsBatchSize)
+		if err != nil {
+			return err
+		}
+		n = len(pds)
+		if n == 0 {
+			return nil
+		}
+
+		keys := make([]statKey, 0, n)
+		counts := make(map[statKey]int64, n)
+		for _, pd := range pds {
+			key := statKey{pd.VersionID, pd.UserID, packages_model.DayOf(pd.CreatedUnix)}
+			if _, ok := counts[key]; !ok {
+				keys = append(keys, key)
+			}
+			counts[key]++
+		}
+
+		for _, key := range keys {
+			if err := packages_model.AddDownloadStat(ctx, key.VersionID, key.UserID, key.Day, counts[key]); err != nil {
+				return err
+			}
+		}
+
+		log.Trace("Aggregated %d package downloads", n)
+
+		return packages_model.DeleteDownloadsUpToID(ctx, pds[n-1].ID)
+	})
+	return n, err
+}
+
+// maxDownloadStatsUsers is the number of users listed in the download statistics
+const maxDownloadStatsUsers = 10
+
+// DownloadStats contains the download statistics of a package version
+type DownloadStats struct {
+	Days  []*packages_model.DownloadsPerDay
+	Users []*UserDownloads
+}
+
+// UserDownloads contains the number of downloads by a user. User is nil for anonymous downloads.
+type UserDownloads struct {
+	User      *user_model.User
+	Downloads int64
+}
+
+// GetDownloadStats gets the downloads of the version per day for the last days, including days without downloads.
+// The users with the most downloads are only included if withUsers is set.
+func GetDownloadStats(ctx context.Context, pv *packages_model.PackageVersion, days int, withUsers bool) (*DownloadStats, error) {
+	today := packages_model.DayOf(timeutil.TimeStampNow())
+	since := today.AddDuration(-time.Duration(days-1) * 24 * time.Hour)
+
+	perDay, err := packages_model.GetDownloadsPerDay(ctx, pv.ID, since)
+	if err != nil {
+		return nil, err
+	}
+
+	downloads := make(map[timeutil.TimeStamp]int64, len(perDay))
+	for _, d := range perDay {
+		downloads[d.DayUnix] = d.Downloads
+	}
+
+	stats := &DownloadStats{
+		Days: make([]*packages_model.DownloadsPerDay, 0, days),
+	}
+	for day := since; day <= today; day = day.AddDuration(24 * time.Hour) {
+		stats.Days = append(stats.Days, &packages_model.DownloadsPerDay{
+			DayUnix:   day,
+			Downloads: downloads[day],
+		})
+	}
+
+	if !withUsers {
+		return stats, nil
+	}
+
+	perUser, err := packages_model.GetDownloadsPerUser(ctx, pv.ID, since, maxDownloadStatsUsers)
+	if err != nil {
+		return nil, err
+	}
+
+	userIDs := make([]int64, 0, len(perUser))
+	for _, d := range perUser {
+		userIDs = append(userIDs, d.UserID)
+	}
+	users, err := user_model.GetPossibleUserByIDs(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	userMap := make(map[int64]*user_model.User, len(users))
+	for _, u := range users {
+		userMap[u.ID] = u
+	}
+
+	stats.Users = make([]*UserDownloads, 0, len(perUser))
+	for _, d := range perUser {
+		var u *user_model.User
+		if d.UserID != 0 {
+			if u = userMap[d.UserID]; u == nil {
+				u = user_model.NewGhostUser()
+			}
+		}
+		stats.Users = append(stats.Users, &UserDownloads{
+			User:      u,
+			Downloads: d.Downloads,
+		})
+	}
+
+	return stats, nil
+}
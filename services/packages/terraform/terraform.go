@@ -0,0 +1,137 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package terraform
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	packages_model "code.gitea.io/gitea/models/packages"
+	user_model "code.gitea.io/gitea/models/user"
+	terraform_module "code.gitea.io/gitea/modules/packages/terraform"
+	"code.gitea.io/gitea/modules/util"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+// GetOrCreateKeyPair gets or creates the PGP keys used to sign the checksums of the provider releases
+func GetOrCreateKeyPair(ctx context.Context, ownerID int64) (string, string, error) {
+	priv, err := user_model.GetSetting(ctx, ownerID, terraform_module.SettingKeyPrivate)
+	if err != nil && !errors.Is(err, util.ErrNotExist) {
+		return "", "", err
+	}
+
+	pub, err := user_model.GetSetting(ctx, ownerID, terraform_module.SettingKeyPublic)
+	if err != nil && !errors.Is(err, util.ErrNotExist) {
+		return "", "", err
+	}
+
+	if priv == "" || pub == "" {
+		priv, pub, err = generateKeypair()
+		if err != nil {
+			return "", "", err
+		}
+
+		if err := user_model.SetUserSetting(ctx, ownerID, terraform_module.SettingKeyPrivate, priv); err != nil {
+			return "", "", err
+		}
+
+		if err := user_model.SetUserSetting(ctx, ownerID, terraform_module.SettingKeyPublic, pub); err != nil {
+			return "", "", err
+		}
+	}
+
+	return priv, pub, nil
+}
+
+func generateKeypair() (string, string, error) {
+	e, err := openpgp.NewEntity("", "Terraform Registry", "", nil)
+	if err != nil {
+		return "", "", err
+	}
+
+	var priv strings.Builder
+	var pub strings.Builder
+
+	w, err := armor.Encode(&priv, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		return "", "", err
+	}
+	if err := e.SerializePrivate(w, nil); err != nil {
+		return "", "", err
+	}
+	w.Close()
+
+	w, err = armor.Encode(&pub, openpgp.PublicKeyType, nil)
+	if err != nil {
+		return "", "", err
+	}
+	if err := e.Serialize(w); err != nil {
+		return "", "", err
+	}
+	w.Close()
+
+	return priv.String(), pub.String(), nil
+}
+
+// GetPublicKeyID returns the id of the public key in the format expected by the provider registry protocol
+func GetPublicKeyID(pub string) (string, error) {
+	keys, err := openpgp.ReadArmoredKeyRing(strings.NewReader(pub))
+	if err != nil {
+		return "", err
+	}
+	if len(keys) == 0 {
+		return "", errors.New("no public key found")
+	}
+	return strings.ToUpper(keys[0].PrimaryKey.KeyIdString()), nil
+}
+
+// BuildShasums builds the content of the SHA256SUMS file of a provider release from the archives of the release
+func BuildShasums(pd *packages_model.PackageDescriptor) []byte {
+	files := make([]*packages_model.PackageFileDescriptor, 0, len(pd.Files))
+	for _, pfd := range pd.Files {
+		if pfd.Properties.GetByName(terraform_module.PropertyOS) != "" {
+			files = append(files, pfd)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].File.Name < files[j].File.Name
+	})
+
+	var buf bytes.Buffer
+	for _, pfd := range files {
+		fmt.Fprintf(&buf, "%s  %s\n", pfd.Blob.HashSHA256, pfd.File.Name)
+	}
+	return buf.Bytes()
+}
+
+// SignShasums creates the binary detached signature of a SHA256SUMS file with the key of the owner
+func SignShasums(ctx context.Context, ownerID int64, shasums []byte) ([]byte, error) {
+	priv, _, err := GetOrCreateKeyPair(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := armor.Decode(strings.NewReader(priv))
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := openpgp.ReadEntity(packet.NewReader(block.Body))
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := openpgp.DetachSign(&buf, e, bytes.NewReader(shasums), nil); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -66,6 +66,12 @@ func DeleteRepositoryDirectly(ctx context.Context, doer *user_model.User, repoID
 		return fmt.Errorf("list actions artifacts of repo %v: %w", repoID, err)
 	}
 
+	// Query the caches of this repo, they will be needed after they have been deleted to remove their content in ObjectStorage
+	caches, err := db.Find[actions_model.ActionCache](ctx, actions_model.FindCachesOptions{RepoID: repoID})
+	if err != nil {
+		return fmt.Errorf("list actions caches of repo %v: %w", repoID, err)
+	}
+
 	// In case owner is a organization, we have to change repo specific teams
 	// if ignoreOrgTeams is not true
 	var org *user_model.User
@@ -179,8 +185,11 @@ func DeleteRepositoryDirectly(ctx context.Context, doer *user_model.User, repoID
 		&actions_model.ActionScheduleSpec{RepoID: repoID},
 		&actions_model.ActionSchedule{RepoID: repoID},
 		&actions_model.ActionArtifact{RepoID: repoID},
+		&actions_model.ActionCache{RepoID: repoID},
 		&repo_model.RepoArchiveDownloadCount{RepoID: repoID},
 		&actions_model.ActionRunnerToken{RepoID: repoID},
+		&actions_model.ActionEnvironment{RepoID: repoID},
+		&actions_model.ActionDeployment{RepoID: repoID},
 	); err != nil {
 		return fmt.Errorf("deleteBeans: %w", err)
 	}
@@ -264,6 +273,24 @@ func DeleteRepositoryDirectly(ctx context.Context, doer *user_model.User, repoID
 		return err
 	}
 
+	// Remove Terraform states
+	var terraformStateVersions []*repo_model.TerraformStateVersion
+	if err = sess.Where("repo_id=?", repoID).Find(&terraformStateVersions); err != nil {
+		return err
+	}
+
+	terraformStatePaths := make([]string, 0, len(terraformStateVersions))
+	for _, v := range terraformStateVersions {
+		terraformStatePaths = append(terraformStatePaths, v.RelativePath())
+	}
+
+	if err := db.DeleteBeans(ctx,
+		&repo_model.TerraformStateVersion{RepoID: repoID},
+		&repo_model.TerraformState{RepoID: repoID},
+	); err != nil {
+		return err
+	}
+
 	if repo.NumForks > 0 {
 		if _, err = sess.Exec("UPDATE `repository` SET fork_id=0,is_fork=? WHERE fork_id=?", false, repo.ID); err != nil {
 			log.Error("reset 'fork_id' and 'is_fork': %v", err)
@@ -318,6 +345,11 @@ func DeleteRepositoryDirectly(ctx context.Context, doer *user_model.User, repoID
 		system_model.RemoveStorageWithNotice(ctx, storage.RepoArchives, "Delete repo archive file", archive)
 	}
 
+	// Remove Terraform state versions
+	for _, terraformState := range terraformStatePaths {
+		system_model.RemoveStorageWithNotice(ctx, storage.TerraformState, "Delete terraform state version", terraformState)
+	}
+
 	// Remove lfs objects
 	for _, lfsObj := range lfsPaths {
 		system_model.RemoveStorageWithNotice(ctx, storage.LFS, "Delete orphaned LFS file", lfsObj)
@@ -361,6 +393,14 @@ func DeleteRepositoryDirectly(ctx context.Context, doer *user_model.User, repoID
 		}
 	}
 
+	// delete actions caches in ObjectStorage after the repo have already been deleted
+	for _, c := range caches {
+		if err := storage.ActionsCache.Delete(c.StoragePath()); err != nil {
+			log.Error("remove cache file %q: %v", c.StoragePath(), err)
+			// go on
+		}
+	}
+
 	return nil
 }
 
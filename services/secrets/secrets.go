@@ -11,21 +11,31 @@ import (
 )
 
 func CreateOrUpdateSecret(ctx context.Context, ownerID, repoID int64, name, data string) (*secret_model.Secret, bool, error) {
+	return createOrUpdateSecret(ctx, ownerID, repoID, 0, name, data)
+}
+
+// CreateOrUpdateEnvironmentSecret creates or updates a secret of an environment of a repository.
+func CreateOrUpdateEnvironmentSecret(ctx context.Context, repoID, environmentID int64, name, data string) (*secret_model.Secret, bool, error) {
+	return createOrUpdateSecret(ctx, 0, repoID, environmentID, name, data)
+}
+
+func createOrUpdateSecret(ctx context.Context, ownerID, repoID, environmentID int64, name, data string) (*secret_model.Secret, bool, error) {
 	if err := ValidateName(name); err != nil {
 		return nil, false, err
 	}
 
 	s, err := db.Find[secret_model.Secret](ctx, secret_model.FindSecretsOptions{
-		OwnerID: ownerID,
-		RepoID:  repoID,
-		Name:    name,
+		OwnerID:       ownerID,
+		RepoID:        repoID,
+		EnvironmentID: environmentID,
+		Name:          name,
 	})
 	if err != nil {
 		return nil, false, err
 	}
 
 	if len(s) == 0 {
-		s, err := secret_model.InsertEncryptedSecret(ctx, ownerID, repoID, name, data)
+		s, err := secret_model.InsertEncryptedSecret(ctx, ownerID, repoID, environmentID, name, data)
 		if err != nil {
 			return nil, false, err
 		}
@@ -40,10 +50,20 @@ func CreateOrUpdateSecret(ctx context.Context, ownerID, repoID int64, name, data
 }
 
 func DeleteSecretByID(ctx context.Context, ownerID, repoID, secretID int64) error {
+	return deleteSecretByID(ctx, ownerID, repoID, 0, secretID)
+}
+
+// DeleteEnvironmentSecretByID deletes a secret of an environment of a repository.
+func DeleteEnvironmentSecretByID(ctx context.Context, repoID, environmentID, secretID int64) error {
+	return deleteSecretByID(ctx, 0, repoID, environmentID, secretID)
+}
+
+func deleteSecretByID(ctx context.Context, ownerID, repoID, environmentID, secretID int64) error {
 	s, err := db.Find[secret_model.Secret](ctx, secret_model.FindSecretsOptions{
-		OwnerID:  ownerID,
-		RepoID:   repoID,
-		SecretID: secretID,
+		OwnerID:       ownerID,
+		RepoID:        repoID,
+		EnvironmentID: environmentID,
+		SecretID:      secretID,
 	})
 	if err != nil {
 		return err
@@ -75,6 +95,12 @@ func DeleteSecretByName(ctx context.Context, ownerID, repoID int64, name string)
 	return deleteSecret(ctx, s[0])
 }
 
+// DeleteEnvironmentSecrets deletes all the secrets of an environment of a repository.
+func DeleteEnvironmentSecrets(ctx context.Context, repoID, environmentID int64) error {
+	_, err := db.GetEngine(ctx).Where("repo_id=? AND environment_id=?", repoID, environmentID).Delete(new(secret_model.Secret))
+	return err
+}
+
 func deleteSecret(ctx context.Context, s *secret_model.Secret) error {
 	if _, err := db.DeleteByID[secret_model.Secret](ctx, s.ID); err != nil {
 		return err
@@ -0,0 +1,234 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package terraform
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"code.gitea.io/gitea/models/db"
+	repo_model "code.gitea.io/gitea/models/repo"
+	system_model "code.gitea.io/gitea/models/system"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/json"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/storage"
+	"code.gitea.io/gitea/modules/util"
+)
+
+var (
+	ErrInvalidState    = util.NewInvalidArgumentErrorf("invalid terraform state")
+	ErrInvalidLockInfo = util.NewInvalidArgumentErrorf("invalid lock info")
+)
+
+// LockInfo is the lock information sent by Terraform when it locks a state.
+// The field names are the JSON keys used by Terraform.
+type LockInfo struct {
+	ID        string
+	Operation string
+	Info      string
+	Who       string
+	Version   string
+	Created   time.Time
+	Path      string
+}
+
+// ErrStateLocked is returned if a state is locked by another lock
+type ErrStateLocked struct {
+	// LockInfo is the lock information of the current lock
+	LockInfo string
+}
+
+func (err ErrStateLocked) Error() string {
+	return "terraform state is locked"
+}
+
+// stateMetadata contains the fields of a state stored with its versions
+type stateMetadata struct {
+	Serial           int64  `json:"serial"`
+	Lineage          string `json:"lineage"`
+	TerraformVersion string `json:"terraform_version"`
+}
+
+// GetState gets the latest version of a state and its content
+func GetState(ctx context.Context, repo *repo_model.Repository, name string) (*repo_model.TerraformStateVersion, storage.Object, error) {
+	s, err := repo_model.GetTerraformState(ctx, repo.ID, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	v, err := repo_model.GetLatestTerraformStateVersion(ctx, s.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	obj, err := OpenStateVersion(v)
+	if err != nil {
+		return nil, nil, err
+	}
+	return v, obj, nil
+}
+
+// OpenStateVersion opens the content of a version of a state
+func OpenStateVersion(v *repo_model.TerraformStateVersion) (storage.Object, error) {
+	return storage.TerraformState.Open(v.RelativePath())
+}
+
+// UpdateState stores a new version of a state. If the state is locked, the lock id must match the lock of the state.
+func UpdateState(ctx context.Context, doer *user_model.User, repo *repo_model.Repository, name, lockID string, content []byte) error {
+	s, err := repo_model.GetOrInsertTerraformState(ctx, repo.ID, name)
+	if err != nil {
+		return err
+	}
+	if s.IsLocked() && s.LockID != lockID {
+		return ErrStateLocked{LockInfo: s.LockInfo}
+	}
+	return addStateVersion(ctx, doer, s, content)
+}
+
+func addStateVersion(ctx context.Context, doer *user_model.User, s *repo_model.TerraformState, content []byte) error {
+	var metadata stateMetadata
+	if err := json.Unmarshal(content, &metadata); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidState, err)
+	}
+
+	if err := db.WithTx(ctx, func(ctx context.Context) error {
+		v := &repo_model.TerraformStateVersion{
+			RepoID:           s.RepoID,
+			StateID:          s.ID,
+			Serial:           metadata.Serial,
+			Lineage:          metadata.Lineage,
+			TerraformVersion: metadata.TerraformVersion,
+			Size:             int64(len(content)),
+			CreatorID:        doer.ID,
+		}
+		if err := repo_model.InsertTerraformStateVersion(ctx, v); err != nil {
+			return err
+		}
+		_, err := storage.TerraformState.Save(v.RelativePath(), bytes.NewReader(content), v.Size)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	if setting.TerraformState.MaxVersions <= 0 {
+		return nil
+	}
+
+	versions, err := repo_model.DeleteOldTerraformStateVersions(ctx, s.ID, setting.TerraformState.MaxVersions)
+	if err != nil {
+		return err
+	}
+	removeVersionContents(ctx, versions)
+	return nil
+}
+
+// DeleteState deletes a state and all its versions. A locked state can't be deleted.
+func DeleteState(ctx context.Context, s *repo_model.TerraformState) error {
+	if s.IsLocked() {
+		return ErrStateLocked{LockInfo: s.LockInfo}
+	}
+
+	var versions []*repo_model.TerraformStateVersion
+	if err := db.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		versions, err = repo_model.DeleteTerraformState(ctx, s)
+		return err
+	}); err != nil {
+		return err
+	}
+	removeVersionContents(ctx, versions)
+	return nil
+}
+
+func removeVersionContents(ctx context.Context, versions []*repo_model.TerraformStateVersion) {
+	for _, v := range versions {
+		system_model.RemoveStorageWithNotice(ctx, storage.TerraformState, "Delete terraform state version", v.RelativePath())
+	}
+}
+
+// LockState locks a state with the lock information sent by Terraform. The state is created if it doesn't exist.
+func LockState(ctx context.Context, doer *user_model.User, repo *repo_model.Repository, name string, lockInfo []byte) error {
+	var info LockInfo
+	if err := json.Unmarshal(lockInfo, &info); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidLockInfo, err)
+	}
+	if info.ID == "" {
+		return ErrInvalidLockInfo
+	}
+
+	s, err := repo_model.GetOrInsertTerraformState(ctx, repo.ID, name)
+	if err != nil {
+		return err
+	}
+
+	locked, err := repo_model.LockTerraformState(ctx, s, info.ID, string(lockInfo), doer.ID)
+	if err != nil {
+		return err
+	}
+	if !locked {
+		s, err = repo_model.GetTerraformStateByID(ctx, repo.ID, s.ID)
+		if err != nil {
+			return err
+		}
+		return ErrStateLocked{LockInfo: s.LockInfo}
+	}
+	return nil
+}
+
+// UnlockState unlocks a state with the lock information sent by Terraform.
+// Without lock information the state is unlocked regardless of its lock.
+func UnlockState(ctx context.Context, repo *repo_model.Repository, name string, lockInfo []byte) error {
+	var lockID string
+	if len(bytes.TrimSpace(lockInfo)) > 0 {
+		var info LockInfo
+		if err := json.Unmarshal(lockInfo, &info); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidLockInfo, err)
+		}
+		lockID = info.ID
+	}
+
+	s, err := repo_model.GetTerraformState(ctx, repo.ID, name)
+	if err != nil {
+		return err
+	}
+	if !s.IsLocked() {
+		return nil
+	}
+
+	unlocked, err := repo_model.UnlockTerraformState(ctx, s, lockID)
+	if err != nil {
+		return err
+	}
+	if !unlocked {
+		return ErrStateLocked{LockInfo: s.LockInfo}
+	}
+	return nil
+}
+
+// RollbackState restores the content of an older version of a state by storing it as new version.
+// A locked state can't be rolled back.
+func RollbackState(ctx context.Context, doer *user_model.User, s *repo_model.TerraformState, versionID int64) error {
+	if s.IsLocked() {
+		return ErrStateLocked{LockInfo: s.LockInfo}
+	}
+
+	v, err := repo_model.GetTerraformStateVersionByID(ctx, s.ID, versionID)
+	if err != nil {
+		return err
+	}
+
+	obj, err := OpenStateVersion(v)
+	if err != nil {
+		return err
+	}
+	defer obj.Close()
+
+	content, err := io.ReadAll(obj)
+	if err != nil {
+		return err
+	}
+	return addStateVersion(ctx, doer, s, content)
+}
@@ -0,0 +1,96 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"net/http"
+
+	webhook_model "code.gitea.io/gitea/models/webhook"
+	"code.gitea.io/gitea/modules/eventbus"
+	"code.gitea.io/gitea/modules/json"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/svg"
+	webhook_module "code.gitea.io/gitea/modules/webhook"
+	"code.gitea.io/gitea/services/forms"
+
+	"gitea.com/go-chi/binding"
+)
+
+type amqpHandler struct{}
+
+func (amqpHandler) Type() webhook_module.HookType { return webhook_module.AMQP }
+func (amqpHandler) Icon(size int) template.HTML {
+	return svg.RenderHTML("octicon-inbox", size, "img")
+}
+
+type amqpForm struct {
+	forms.WebhookCoreForm
+	BrokerURL        string `binding:"Required"`
+	Exchange         string `binding:"MaxSize(255)"`
+	RoutingKeyPrefix string `binding:"MaxSize(100)"`
+	Secret           string
+}
+
+var _ binding.Validator = &amqpForm{}
+
+// Validate implements binding.Validator.
+func (f *amqpForm) Validate(req *http.Request, errs binding.Errors) binding.Errors {
+	errs = validateBrokerURL(req, errs, "BrokerURL", f.BrokerURL, "amqp", "amqps")
+	return validateBrokerField(req, errs, "RoutingKeyPrefix", f.RoutingKeyPrefix == "" || eventbus.ValidNATSSubject(f.RoutingKeyPrefix))
+}
+
+func (amqpHandler) UnmarshalForm(bind func(any)) forms.WebhookForm {
+	var form amqpForm
+	bind(&form)
+
+	return forms.WebhookForm{
+		WebhookCoreForm: form.WebhookCoreForm,
+		URL:             form.BrokerURL,
+		ContentType:     webhook_model.ContentTypeJSON,
+		Secret:          form.Secret,
+		HTTPMethod:      http.MethodPost,
+		Metadata: &AMQPMeta{
+			Exchange:         form.Exchange,
+			RoutingKeyPrefix: form.RoutingKeyPrefix,
+		},
+	}
+}
+
+// AMQPMeta contains the metadata for the webhook
+type AMQPMeta struct {
+	Exchange         string `json:"exchange"`
+	RoutingKeyPrefix string `json:"routing_key_prefix"`
+}
+
+// Metadata returns amqp metadata
+func (amqpHandler) Metadata(w *webhook_model.Webhook) any {
+	s := &AMQPMeta{}
+	if err := json.Unmarshal([]byte(w.Meta), s); err != nil {
+		log.Error("amqpHandler.Metadata(%d): %v", w.ID, err)
+	}
+	return s
+}
+
+// NewRequest creates the request holding the forgejo payload, it is published by Publish.
+func (amqpHandler) NewRequest(ctx context.Context, w *webhook_model.Webhook, t *webhook_model.HookTask) (*http.Request, []byte, error) {
+	return newBrokerRequest(w, t)
+}
+
+// Publish publishes the payload to the exchange with the routing key <prefix>.<event type>.<owner>.<repository>
+func (amqpHandler) Publish(ctx context.Context, d *eventbus.Dialer, w *webhook_model.Webhook, t *webhook_model.HookTask, req *http.Request, body []byte) (string, error) {
+	meta := &AMQPMeta{}
+	if err := json.Unmarshal([]byte(w.Meta), meta); err != nil {
+		return "", fmt.Errorf("amqpHandler.Publish meta json: %w", err)
+	}
+
+	msg := newBrokerMessage(req, body, eventRoutingKey(meta.RoutingKeyPrefix, t))
+	ack, err := eventbus.PublishAMQP(ctx, d, w.URL, meta.Exchange, msg)
+	if err != nil {
+		return "", fmt.Errorf("publish to exchange %q with routing key %s: %w", meta.Exchange, msg.Key, err)
+	}
+	return ack, nil
+}
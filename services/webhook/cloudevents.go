@@ -0,0 +1,102 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package webhook
+
+import (
+	"net/http"
+	"strings"
+
+	webhook_model "code.gitea.io/gitea/models/webhook"
+	"code.gitea.io/gitea/modules/json"
+	"code.gitea.io/gitea/modules/setting"
+)
+
+const (
+	cloudEventsSpecVersion = "1.0"
+	// cloudEventsTypePrefix is prepended to the event type of the hook task to build the CloudEvents type
+	cloudEventsTypePrefix = "org.forgejo."
+)
+
+// cloudEvent is the envelope of the structured content mode of the CloudEvents JSON format
+// https://github.com/cloudevents/spec/blob/v1.0.2/cloudevents/formats/json-format.md
+type cloudEvent struct {
+	SpecVersion     string  `json:"specversion"`
+	ID              string  `json:"id"`
+	Source          string  `json:"source"`
+	Type            string  `json:"type"`
+	DataContentType string  `json:"datacontenttype"`
+	Data            rawJSON `json:"data"`
+}
+
+// rawJSON is marshalled as is
+type rawJSON string
+
+func (r rawJSON) MarshalJSON() ([]byte, error) {
+	return []byte(r), nil
+}
+
+// cloudEventSource returns the URL of the repository the payload is about, the URL of the
+// owner of the package or of the organization is used for events without repository.
+func cloudEventSource(payloadContent string) string {
+	type htmlURL struct {
+		HTMLURL string `json:"html_url"`
+	}
+	var payload struct {
+		Repository *htmlURL `json:"repository"`
+		Package    *struct {
+			Owner *htmlURL `json:"owner"`
+		} `json:"package"`
+		Organization *htmlURL `json:"organization"`
+	}
+	if err := json.Unmarshal([]byte(payloadContent), &payload); err == nil {
+		switch {
+		case payload.Repository != nil && payload.Repository.HTMLURL != "":
+			return payload.Repository.HTMLURL
+		case payload.Package != nil && payload.Package.Owner != nil && payload.Package.Owner.HTMLURL != "":
+			return payload.Package.Owner.HTMLURL
+		case payload.Organization != nil && payload.Organization.HTMLURL != "":
+			return payload.Organization.HTMLURL
+		}
+	}
+	return strings.TrimSuffix(setting.AppURL, "/")
+}
+
+// newCloudEventsRequest creates a POST request sending the payload as CloudEvent, either in the
+// binary content mode with the attributes in ce-* headers or in the structured content mode with
+// the payload in a JSON envelope. The returned body is the body of the request.
+func newCloudEventsRequest(w *webhook_model.Webhook, t *webhook_model.HookTask, payloadContent string) (*http.Request, string, error) {
+	event := cloudEvent{
+		SpecVersion:     cloudEventsSpecVersion,
+		ID:              t.GetDeliveryUUID(),
+		Source:          cloudEventSource(payloadContent),
+		Type:            cloudEventsTypePrefix + string(t.EventType),
+		DataContentType: "application/json",
+	}
+
+	if w.ContentType == webhook_model.ContentTypeCloudEventsStructured {
+		event.Data = rawJSON(payloadContent)
+		envelope, err := json.Marshal(event)
+		if err != nil {
+			return nil, "", err
+		}
+
+		req, err := http.NewRequest("POST", w.URL, strings.NewReader(string(envelope)))
+		if err != nil {
+			return nil, "", err
+		}
+		req.Header.Set("Content-Type", "application/cloudevents+json")
+		return req, string(envelope), nil
+	}
+
+	req, err := http.NewRequest("POST", w.URL, strings.NewReader(payloadContent))
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Content-Type", event.DataContentType)
+	req.Header.Set("ce-specversion", event.SpecVersion)
+	req.Header.Set("ce-id", event.ID)
+	req.Header.Set("ce-source", event.Source)
+	req.Header.Set("ce-type", event.Type)
+	return req, payloadContent, nil
+}
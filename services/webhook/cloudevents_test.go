@@ -0,0 +1,120 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"testing"
+
+	webhook_model "code.gitea.io/gitea/models/webhook"
+	"code.gitea.io/gitea/modules/json"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/test"
+	webhook_module "code.gitea.io/gitea/modules/webhook"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloudEventsPayload(t *testing.T) {
+	hook := &webhook_model.Webhook{
+		RepoID:     3,
+		IsActive:   true,
+		Type:       webhook_module.FORGEJO,
+		URL:        "https://events.example.com/",
+		HTTPMethod: "POST",
+		Secret:     "s3cr3t",
+	}
+
+	data, err := pushTestPayload().JSONPayload()
+	require.NoError(t, err)
+	task := &webhook_model.HookTask{
+		UUID:           "00000000-0000-0000-0000-000000000001",
+		HookID:         hook.ID,
+		EventType:      webhook_module.HookEventPush,
+		PayloadContent: string(data),
+		PayloadVersion: 2,
+	}
+
+	signature := func(body []byte) string {
+		sig := hmac.New(sha256.New, []byte(hook.Secret))
+		sig.Write(body)
+		return hex.EncodeToString(sig.Sum(nil))
+	}
+
+	t.Run("Binary", func(t *testing.T) {
+		hook.ContentType = webhook_model.ContentTypeCloudEventsBinary
+		req, reqBody, err := defaultHandler{true}.NewRequest(context.Background(), hook, task)
+		require.NoError(t, err)
+
+		assert.Equal(t, "POST", req.Method)
+		assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+		assert.Equal(t, "1.0", req.Header.Get("ce-specversion"))
+		assert.Equal(t, task.UUID, req.Header.Get("ce-id"))
+		assert.Equal(t, "http://localhost:3000/test/repo", req.Header.Get("ce-source"))
+		assert.Equal(t, "org.forgejo.push", req.Header.Get("ce-type"))
+
+		body, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+		assert.Equal(t, data, body)
+		assert.Equal(t, data, reqBody)
+		assert.Equal(t, signature(body), req.Header.Get("X-Forgejo-Signature"))
+	})
+
+	t.Run("Structured", func(t *testing.T) {
+		hook.ContentType = webhook_model.ContentTypeCloudEventsStructured
+		req, reqBody, err := defaultHandler{true}.NewRequest(context.Background(), hook, task)
+		require.NoError(t, err)
+
+		assert.Equal(t, "application/cloudevents+json", req.Header.Get("Content-Type"))
+		assert.Empty(t, req.Header.Get("ce-id"))
+
+		body, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+		assert.Equal(t, body, reqBody)
+		assert.Equal(t, signature(body), req.Header.Get("X-Forgejo-Signature"))
+
+		var event struct {
+			SpecVersion     string `json:"specversion"`
+			ID              string `json:"id"`
+			Source          string `json:"source"`
+			Type            string `json:"type"`
+			DataContentType string `json:"datacontenttype"`
+			Data            struct {
+				Ref string `json:"ref"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(body, &event))
+		assert.Equal(t, "1.0", event.SpecVersion)
+		assert.Equal(t, task.UUID, event.ID)
+		assert.Equal(t, "http://localhost:3000/test/repo", event.Source)
+		assert.Equal(t, "org.forgejo.push", event.Type)
+		assert.Equal(t, "application/json", event.DataContentType)
+		assert.Equal(t, "refs/heads/test", event.Data.Ref)
+	})
+}
+
+func TestCloudEventSource(t *testing.T) {
+	defer test.MockVariableValue(&setting.AppURL, "https://forgejo.example.com/")()
+
+	data, err := packageTestPayload().JSONPayload()
+	require.NoError(t, err)
+	assert.Equal(t, "https://forgejo.example.com", cloudEventSource(string(data)))
+
+	p := packageTestPayload()
+	p.Package.Owner.HTMLURL = "https://forgejo.example.com/user1"
+	data, err = p.JSONPayload()
+	require.NoError(t, err)
+	assert.Equal(t, "https://forgejo.example.com/user1", cloudEventSource(string(data)))
+
+	p.Package.Owner = nil
+	p.Organization.HTMLURL = "https://forgejo.example.com/org1"
+	data, err = p.JSONPayload()
+	require.NoError(t, err)
+	assert.Equal(t, "https://forgejo.example.com/org1", cloudEventSource(string(data)))
+}
@@ -0,0 +1,519 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package webhook
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"slices"
+	"strings"
+	texttemplate "text/template"
+	"text/template/parse"
+	"time"
+
+	webhook_model "code.gitea.io/gitea/models/webhook"
+	"code.gitea.io/gitea/modules/json"
+	"code.gitea.io/gitea/modules/log"
+	api "code.gitea.io/gitea/modules/structs"
+	"code.gitea.io/gitea/modules/svg"
+	webhook_module "code.gitea.io/gitea/modules/webhook"
+	gitea_context "code.gitea.io/gitea/services/context"
+	"code.gitea.io/gitea/services/forms"
+	"code.gitea.io/gitea/services/webhook/shared"
+
+	"gitea.com/go-chi/binding"
+	"golang.org/x/net/http/httpguts"
+)
+
+// customOutputLimit is the maximum size of the rendered body and of the rendered headers
+const customOutputLimit = 1 << 20
+
+var errCustomOutputTooLarge = fmt.Errorf("the rendered template is larger than %d bytes", customOutputLimit)
+
+const (
+	// customTemplateMaxRangeDepth is the maximum number of range actions nested in each other
+	customTemplateMaxRangeDepth = 3
+	// customTemplateMaxSteps is the maximum number of range iterations and template calls of an execution
+	customTemplateMaxSteps = 1 << 20
+	// customTemplateTimeout is the maximum duration of an execution
+	customTemplateTimeout = 5 * time.Second
+)
+
+var errCustomTemplateTooLong = errors.New("the execution of the template takes too long")
+
+type customHandler struct{}
+
+func (customHandler) Type() webhook_module.HookType { return webhook_module.CUSTOM }
+func (customHandler) Icon(size int) template.HTML {
+	return svg.RenderHTML("octicon-code", size, "img")
+}
+
+type customForm struct {
+	forms.WebhookCoreForm
+	PayloadURL      string `binding:"Required;ValidUrl"`
+	HTTPMethod      string `binding:"Required;In(POST,PUT,PATCH)"`
+	BodyTemplate    string `binding:"Required"`
+	HeadersTemplate string
+	Secret          string
+}
+
+var _ binding.Validator = &customForm{}
+
+// Validate implements binding.Validator.
+func (f *customForm) Validate(req *http.Request, errs binding.Errors) binding.Errors {
+	return ValidateCustomTemplates(req, errs, f.BodyTemplate, f.HeadersTemplate)
+}
+
+func (customHandler) UnmarshalForm(bind func(any)) forms.WebhookForm {
+	var form customForm
+	bind(&form)
+
+	return forms.WebhookForm{
+		WebhookCoreForm: form.WebhookCoreForm,
+		URL:             form.PayloadURL,
+		ContentType:     webhook_model.ContentTypeJSON,
+		Secret:          form.Secret,
+		HTTPMethod:      form.HTTPMethod,
+		Metadata: &CustomMeta{
+			BodyTemplate:    form.BodyTemplate,
+			HeadersTemplate: form.HeadersTemplate,
+		},
+	}
+}
+
+// CustomMeta contains the metadata for the webhook
+type CustomMeta struct {
+	BodyTemplate    string `json:"body_template"`
+	HeadersTemplate string `json:"headers_template"`
+}
+
+// Metadata returns custom metadata
+func (customHandler) Metadata(w *webhook_model.Webhook) any {
+	s := &CustomMeta{}
+	if err := json.Unmarshal([]byte(w.Meta), s); err != nil {
+		log.Error("customHandler.Metadata(%d): %v", w.ID, err)
+	}
+	return s
+}
+
+// NewRequest creates a request with the body and the headers rendered by the templates of the webhook
+func (customHandler) NewRequest(ctx context.Context, w *webhook_model.Webhook, t *webhook_model.HookTask) (*http.Request, []byte, error) {
+	meta := &CustomMeta{}
+	if err := json.Unmarshal([]byte(w.Meta), meta); err != nil {
+		return nil, nil, fmt.Errorf("customHandler.NewRequest meta json: %w", err)
+	}
+
+	payload, err := shared.NewPayload[any](customConvertor{}, []byte(t.PayloadContent), t.EventType)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	body, headers, err := renderCustomTemplates(meta, &CustomTemplateData{
+		Event:    t.EventType,
+		Delivery: t.GetDeliveryUUID(),
+		Payload:  payload,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("customHandler.NewRequest: %w", err)
+	}
+
+	method := w.HTTPMethod
+	if method == "" {
+		method = http.MethodPost
+	}
+	req, err := http.NewRequest(method, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for _, h := range headers {
+		req.Header.Set(h.Name, h.Value)
+	}
+	return req, body, shared.AddDefaultHeaders(req, []byte(w.Secret), t, body)
+}
+
+// CustomTemplateData is the data the templates of a custom webhook are executed with
+type CustomTemplateData struct {
+	// Event is the type of the event, e.g. issue_label
+	Event webhook_module.HookEventType
+	// Delivery is the unique id of the delivery
+	Delivery string
+	// Payload is the api payload of the event, e.g. *api.IssuePayload
+	Payload any
+}
+
+// CustomHeader is a header rendered by the headers template of a custom webhook
+type CustomHeader struct {
+	Name  string
+	Value string
+}
+
+var customTemplateFuncs = texttemplate.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"trim":  strings.TrimSpace,
+	"join":  strings.Join,
+	// replace and truncate take s last to be used in pipelines
+	"replace": func(old, new, s string) string {
+		return strings.ReplaceAll(s, old, new)
+	},
+	// truncate keeps the first n runes of s
+	"truncate": func(n int, s string) string {
+		if r := []rune(s); len(r) > n && n >= 0 {
+			return string(r[:n]) + "…"
+		}
+		return s
+	},
+}
+
+func parseCustomTemplate(name, text string) (*texttemplate.Template, error) {
+	tmpl, err := texttemplate.New(name).Funcs(customTemplateFuncs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	// the templates are checked by name so that the same error is reported for a recursion
+	templates := tmpl.Templates()
+	slices.SortFunc(templates, func(a, b *texttemplate.Template) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+	for _, t := range templates {
+		if t.Tree == nil {
+			continue
+		}
+		depth, err := customRangeDepth(tmpl, t.Root, []string{t.Name()})
+		if err != nil {
+			return nil, err
+		}
+		if depth > customTemplateMaxRangeDepth {
+			return nil, fmt.Errorf("template: %s: the range actions are nested more than %d times", t.Name(), customTemplateMaxRangeDepth)
+		}
+	}
+	return tmpl, nil
+}
+
+// customRangeDepth returns the maximum number of range actions nested in each other in node,
+// including the ones of the called templates. calls are the names of the templates being walked.
+func customRangeDepth(tmpl *texttemplate.Template, node parse.Node, calls []string) (int, error) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return 0, nil
+		}
+		depth := 0
+		for _, child := range n.Nodes {
+			d, err := customRangeDepth(tmpl, child, calls)
+			if err != nil {
+				return 0, err
+			}
+			depth = max(depth, d)
+		}
+		return depth, nil
+	case *parse.IfNode:
+		return customBranchRangeDepth(tmpl, &n.BranchNode, calls, 0)
+	case *parse.WithNode:
+		return customBranchRangeDepth(tmpl, &n.BranchNode, calls, 0)
+	case *parse.RangeNode:
+		return customBranchRangeDepth(tmpl, &n.BranchNode, calls, 1)
+	case *parse.TemplateNode:
+		if slices.Contains(calls, n.Name) {
+			return 0, fmt.Errorf("template: %s: recursive call of the template %q", calls[0], n.Name)
+		}
+		called := tmpl.Lookup(n.Name)
+		if called == nil || called.Tree == nil {
+			return 0, nil
+		}
+		return customRangeDepth(tmpl, called.Root, append(slices.Clip(calls), n.Name))
+	}
+	return 0, nil
+}
+
+func customBranchRangeDepth(tmpl *texttemplate.Template, n *parse.BranchNode, calls []string, inc int) (int, error) {
+	depth, err := customRangeDepth(tmpl, n.List, calls)
+	if err != nil {
+		return 0, err
+	}
+	elseDepth, err := customRangeDepth(tmpl, n.ElseList, calls)
+	if err != nil {
+		return 0, err
+	}
+	return max(depth+inc, elseDepth), nil
+}
+
+// customStepNode is the {{customTemplateStep}} action added to the range actions and to the templates
+// to bound their execution, the function is only defined for the execution so the templates cannot call it.
+var customStepNode = func() parse.Node {
+	trees, err := parse.Parse("step", "{{customTemplateStep}}", "", "", map[string]any{"customTemplateStep": func() string { return "" }})
+	if err != nil {
+		panic(err)
+	}
+	return trees["step"].Root.Nodes[0]
+}()
+
+// addCustomSteps adds a step action at the start of node and of each range action in node
+func addCustomSteps(node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			addCustomSteps(child)
+		}
+	case *parse.IfNode:
+		addCustomSteps(n.List)
+		addCustomSteps(n.ElseList)
+	case *parse.WithNode:
+		addCustomSteps(n.List)
+		addCustomSteps(n.ElseList)
+	case *parse.RangeNode:
+		addCustomSteps(n.List)
+		addCustomSteps(n.ElseList)
+		if n.List != nil {
+			n.List.Nodes = append([]parse.Node{customStepNode.Copy()}, n.List.Nodes...)
+		}
+	}
+}
+
+// ValidateCustomTemplates adds an error to the fields of the templates which cannot be parsed
+func ValidateCustomTemplates(req *http.Request, errs binding.Errors, bodyTemplate, headersTemplate string) binding.Errors {
+	for _, tmpl := range []struct{ field, text string }{
+		{"BodyTemplate", bodyTemplate},
+		{"HeadersTemplate", headersTemplate},
+	} {
+		if _, err := parseCustomTemplate(tmpl.field, tmpl.text); err != nil {
+			ctx := gitea_context.GetWebContext(req)
+			errs = append(errs, binding.Error{
+				FieldNames:     []string{tmpl.field},
+				Classification: "",
+				Message:        ctx.Locale.TrString("repo.settings.custom.template_invalid", err.Error()),
+			})
+		}
+	}
+	return errs
+}
+
+// limitedBuffer is a buffer which refuses to grow past customOutputLimit
+type limitedBuffer struct {
+	bytes.Buffer
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > customOutputLimit {
+		return 0, errCustomOutputTooLarge
+	}
+	return b.Buffer.Write(p)
+}
+
+// executeCustomTemplate executes the template defined with the name of the event type if
+// there is one, the template defined with the name of the event (e.g. issues for issue_label)
+// otherwise, and falls back to the template itself.
+func executeCustomTemplate(name, text string, data *CustomTemplateData) ([]byte, error) {
+	tmpl, err := parseCustomTemplate(name, text)
+	if err != nil {
+		return nil, err
+	}
+
+	// each range iteration and each template call is a step, the execution fails
+	// when there are too many steps or when it takes too long
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			addCustomSteps(t.Root)
+			t.Root.Nodes = append([]parse.Node{customStepNode.Copy()}, t.Root.Nodes...)
+		}
+	}
+	steps, deadline := 0, time.Now().Add(customTemplateTimeout)
+	tmpl.Funcs(texttemplate.FuncMap{
+		"customTemplateStep": func() (string, error) {
+			steps++
+			if steps > customTemplateMaxSteps || time.Now().After(deadline) {
+				return "", errCustomTemplateTooLong
+			}
+			return "", nil
+		},
+	})
+
+	for _, n := range []string{string(data.Event), data.Event.Event()} {
+		if n != "" && tmpl.Lookup(n) != nil {
+			name = n
+			break
+		}
+	}
+
+	var buf limitedBuffer
+	if err := tmpl.ExecuteTemplate(&buf, name, data); err != nil {
+		if errors.Is(err, errCustomOutputTooLarge) {
+			return nil, errCustomOutputTooLarge
+		}
+		if errors.Is(err, errCustomTemplateTooLong) {
+			return nil, errCustomTemplateTooLong
+		}
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// parseCustomHeaders parses the "Name: value" lines rendered by the headers template, blank lines are ignored
+func parseCustomHeaders(rendered []byte) ([]CustomHeader, error) {
+	var headers []CustomHeader
+	scanner := bufio.NewScanner(bytes.NewReader(rendered))
+	scanner.Buffer(nil, customOutputLimit)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(line, ":")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if !ok || !httpguts.ValidHeaderFieldName(name) || !httpguts.ValidHeaderFieldValue(value) {
+			return nil, fmt.Errorf("invalid header line %q", line)
+		}
+		headers = append(headers, CustomHeader{Name: name, Value: value})
+	}
+	return headers, scanner.Err()
+}
+
+func renderCustomTemplates(meta *CustomMeta, data *CustomTemplateData) ([]byte, []CustomHeader, error) {
+	body, err := executeCustomTemplate("body", meta.BodyTemplate, data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("body template: %w", err)
+	}
+
+	rendered, err := executeCustomTemplate("headers", meta.HeadersTemplate, data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("headers template: %w", err)
+	}
+	headers, err := parseCustomHeaders(rendered)
+	if err != nil {
+		return nil, nil, fmt.Errorf("headers template: %w", err)
+	}
+	return body, headers, nil
+}
+
+// CustomPreview is the request rendered by the templates of a custom webhook for a sample event
+type CustomPreview struct {
+	Headers []CustomHeader
+	Body    string
+}
+
+// PreviewCustomTemplates renders the templates of a custom webhook with a sample payload of the event
+func PreviewCustomTemplates(bodyTemplate, headersTemplate string, event webhook_module.HookEventType) (*CustomPreview, error) {
+	sample, ok := customSamplePayloads[event]
+	if !ok {
+		return nil, fmt.Errorf("no sample payload for the event %s", event)
+	}
+	// the sample goes through the JSON payload, like the payload of a hook task
+	data, err := sample().JSONPayload()
+	if err != nil {
+		return nil, err
+	}
+	payload, err := shared.NewPayload[any](customConvertor{}, data, event)
+	if err != nil {
+		return nil, err
+	}
+
+	body, headers, err := renderCustomTemplates(&CustomMeta{
+		BodyTemplate:    bodyTemplate,
+		HeadersTemplate: headersTemplate,
+	}, &CustomTemplateData{
+		Event:    event,
+		Delivery: customSampleDelivery,
+		Payload:  payload,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !hasCustomHeader(headers, "Content-Type") {
+		headers = append([]CustomHeader{{Name: "Content-Type", Value: "application/json"}}, headers...)
+	}
+	return &CustomPreview{Headers: headers, Body: string(body)}, nil
+}
+
+func hasCustomHeader(headers []CustomHeader, name string) bool {
+	for _, h := range headers {
+		if strings.EqualFold(h.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// UpdateCustomMeta validates the templates of a custom webhook and updates its metadata
+// with the config options of the API, options which are not given are kept.
+func UpdateCustomMeta(w *webhook_model.Webhook, config map[string]string) error {
+	meta := &CustomMeta{}
+	if w.Meta != "" {
+		if err := json.Unmarshal([]byte(w.Meta), meta); err != nil {
+			return fmt.Errorf("invalid webhook meta: %w", err)
+		}
+	}
+	if v, ok := config["body_template"]; ok {
+		meta.BodyTemplate = v
+	}
+	if v, ok := config["headers_template"]; ok {
+		meta.HeadersTemplate = v
+	}
+	if v, ok := config["http_method"]; ok {
+		switch v = strings.ToUpper(v); v {
+		case http.MethodPost, http.MethodPut, http.MethodPatch:
+			w.HTTPMethod = v
+		default:
+			return fmt.Errorf("invalid http method %q", v)
+		}
+	}
+
+	if meta.BodyTemplate == "" {
+		return fmt.Errorf("the body template is required")
+	}
+	if _, err := parseCustomTemplate("body", meta.BodyTemplate); err != nil {
+		return fmt.Errorf("invalid body template: %w", err)
+	}
+	if _, err := parseCustomTemplate("headers", meta.HeadersTemplate); err != nil {
+		return fmt.Errorf("invalid headers template: %w", err)
+	}
+
+	b, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	w.Meta = string(b)
+	return nil
+}
+
+// customConvertor passes the api payloads as they are to the templates
+type customConvertor struct{}
+
+var _ shared.PayloadConvertor[any] = customConvertor{}
+
+func (customConvertor) Create(p *api.CreatePayload) (any, error)   { return p, nil }
+func (customConvertor) Delete(p *api.DeletePayload) (any, error)   { return p, nil }
+func (customConvertor) Fork(p *api.ForkPayload) (any, error)       { return p, nil }
+func (customConvertor) Issue(p *api.IssuePayload) (any, error)     { return p, nil }
+func (customConvertor) Push(p *api.PushPayload) (any, error)       { return p, nil }
+func (customConvertor) Release(p *api.ReleasePayload) (any, error) { return p, nil }
+func (customConvertor) Wiki(p *api.WikiPayload) (any, error)       { return p, nil }
+func (customConvertor) Package(p *api.PackagePayload) (any, error) { return p, nil }
+func (customConvertor) Repository(p *api.RepositoryPayload) (any, error) {
+	return p, nil
+}
+
+func (customConvertor) IssueComment(p *api.IssueCommentPayload) (any, error) {
+	return p, nil
+}
+
+func (customConvertor) PullRequest(p *api.PullRequestPayload) (any, error) {
+	return p, nil
+}
+
+func (customConvertor) Review(p *api.PullRequestPayload, _ webhook_module.HookEventType) (any, error) {
+	return p, nil
+}
@@ -0,0 +1,183 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package webhook
+
+import (
+	"time"
+
+	"code.gitea.io/gitea/modules/setting"
+	api "code.gitea.io/gitea/modules/structs"
+	webhook_module "code.gitea.io/gitea/modules/webhook"
+)
+
+const (
+	customSampleDelivery = "00000000-0000-0000-0000-000000000000"
+	customSampleSHA      = "2020fb4f4e1c1f2b1a5a8a6d9c1c0e5f1c1b3a47"
+)
+
+// customSampleEvents are the events the templates of a custom webhook can be previewed with
+var customSampleEvents = []webhook_module.HookEventType{
+	webhook_module.HookEventPush,
+	webhook_module.HookEventCreate,
+	webhook_module.HookEventDelete,
+	webhook_module.HookEventIssues,
+	webhook_module.HookEventIssueComment,
+	webhook_module.HookEventPullRequest,
+	webhook_module.HookEventRelease,
+}
+
+var customSamplePayloads = map[webhook_module.HookEventType]func() api.Payloader{
+	webhook_module.HookEventPush: func() api.Payloader {
+		commit := customSampleCommit()
+		return &api.PushPayload{
+			Ref:          "refs/heads/main",
+			Before:       customSampleSHA,
+			After:        customSampleSHA,
+			CompareURL:   setting.AppURL + "forgejo/sample/compare/" + customSampleSHA + "..." + customSampleSHA,
+			Commits:      []*api.PayloadCommit{commit},
+			TotalCommits: 1,
+			HeadCommit:   commit,
+			Repo:         customSampleRepo(),
+			Pusher:       customSampleUser(),
+			Sender:       customSampleUser(),
+		}
+	},
+	webhook_module.HookEventCreate: func() api.Payloader {
+		return &api.CreatePayload{
+			Sha:     customSampleSHA,
+			Ref:     "feature",
+			RefType: "branch",
+			Repo:    customSampleRepo(),
+			Sender:  customSampleUser(),
+		}
+	},
+	webhook_module.HookEventDelete: func() api.Payloader {
+		return &api.DeletePayload{
+			Ref:        "feature",
+			RefType:    "branch",
+			PusherType: api.PusherTypeUser,
+			Repo:       customSampleRepo(),
+			Sender:     customSampleUser(),
+		}
+	},
+	webhook_module.HookEventIssues: func() api.Payloader {
+		return &api.IssuePayload{
+			Action:     api.HookIssueOpened,
+			Index:      1,
+			Issue:      customSampleIssue(),
+			Repository: customSampleRepo(),
+			Sender:     customSampleUser(),
+		}
+	},
+	webhook_module.HookEventIssueComment: func() api.Payloader {
+		return &api.IssueCommentPayload{
+			Action: api.HookIssueCommentCreated,
+			Issue:  customSampleIssue(),
+			Comment: &api.Comment{
+				ID:       1,
+				HTMLURL:  setting.AppURL + "forgejo/sample/issues/1#issuecomment-1",
+				IssueURL: setting.AppURL + "forgejo/sample/issues/1",
+				Poster:   customSampleUser(),
+				Body:     "This is a sample comment",
+				Created:  customSampleTime(),
+				Updated:  customSampleTime(),
+			},
+			Repository: customSampleRepo(),
+			Sender:     customSampleUser(),
+		}
+	},
+	webhook_module.HookEventPullRequest: func() api.Payloader {
+		return &api.PullRequestPayload{
+			Action: api.HookIssueOpened,
+			Index:  2,
+			PullRequest: &api.PullRequest{
+				ID:      2,
+				Index:   2,
+				HTMLURL: setting.AppURL + "forgejo/sample/pulls/2",
+				Poster:  customSampleUser(),
+				Title:   "Sample pull request",
+				Body:    "This is a sample pull request",
+				State:   api.StateOpen,
+				Base:    &api.PRBranchInfo{Name: "main", Ref: "main", Sha: customSampleSHA, RepoID: 1, Repository: customSampleRepo()},
+				Head:    &api.PRBranchInfo{Name: "feature", Ref: "feature", Sha: customSampleSHA, RepoID: 1, Repository: customSampleRepo()},
+			},
+			Repository: customSampleRepo(),
+			Sender:     customSampleUser(),
+		}
+	},
+	webhook_module.HookEventRelease: func() api.Payloader {
+		return &api.ReleasePayload{
+			Action: api.HookReleasePublished,
+			Release: &api.Release{
+				ID:          1,
+				TagName:     "v1.0.0",
+				Target:      "main",
+				Title:       "v1.0.0",
+				Note:        "This is a sample release",
+				HTMLURL:     setting.AppURL + "forgejo/sample/releases/tag/v1.0.0",
+				TarURL:      setting.AppURL + "forgejo/sample/archive/v1.0.0.tar.gz",
+				ZipURL:      setting.AppURL + "forgejo/sample/archive/v1.0.0.zip",
+				CreatedAt:   customSampleTime(),
+				PublishedAt: customSampleTime(),
+				Publisher:   customSampleUser(),
+			},
+			Repository: customSampleRepo(),
+			Sender:     customSampleUser(),
+		}
+	},
+}
+
+func customSampleTime() time.Time {
+	return time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+}
+
+func customSampleUser() *api.User {
+	return &api.User{
+		ID:        1,
+		UserName:  "forgejo",
+		FullName:  "Forgejo",
+		Email:     "forgejo@noreply.example.org",
+		AvatarURL: setting.AppURL + "assets/img/avatar_default.png",
+		HTMLURL:   setting.AppURL + "forgejo",
+	}
+}
+
+func customSampleRepo() *api.Repository {
+	return &api.Repository{
+		ID:            1,
+		Owner:         customSampleUser(),
+		Name:          "sample",
+		FullName:      "forgejo/sample",
+		HTMLURL:       setting.AppURL + "forgejo/sample",
+		CloneURL:      setting.AppURL + "forgejo/sample.git",
+		DefaultBranch: "main",
+		Created:       customSampleTime(),
+		Updated:       customSampleTime(),
+	}
+}
+
+func customSampleCommit() *api.PayloadCommit {
+	return &api.PayloadCommit{
+		ID:        customSampleSHA,
+		Message:   "Sample commit\n",
+		URL:       setting.AppURL + "forgejo/sample/commit/" + customSampleSHA,
+		Author:    &api.PayloadUser{Name: "Forgejo", Email: "forgejo@noreply.example.org", UserName: "forgejo"},
+		Committer: &api.PayloadUser{Name: "Forgejo", Email: "forgejo@noreply.example.org", UserName: "forgejo"},
+		Timestamp: customSampleTime(),
+	}
+}
+
+func customSampleIssue() *api.Issue {
+	return &api.Issue{
+		ID:      1,
+		HTMLURL: setting.AppURL + "forgejo/sample/issues/1",
+		Index:   1,
+		Poster:  customSampleUser(),
+		Title:   "Sample issue",
+		Body:    "This is a sample issue",
+		State:   api.StateOpen,
+		Created: customSampleTime(),
+		Updated: customSampleTime(),
+	}
+}
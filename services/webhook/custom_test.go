@@ -0,0 +1,188 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package webhook
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	webhook_model "code.gitea.io/gitea/models/webhook"
+	"code.gitea.io/gitea/modules/json"
+	api "code.gitea.io/gitea/modules/structs"
+	webhook_module "code.gitea.io/gitea/modules/webhook"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCustomPayload(t *testing.T) {
+	newTask := func(t *testing.T, event webhook_module.HookEventType, p api.Payloader) *webhook_model.HookTask {
+		data, err := p.JSONPayload()
+		require.NoError(t, err)
+		return &webhook_model.HookTask{
+			UUID:           "00000000-0000-0000-0000-000000000001",
+			EventType:      event,
+			PayloadContent: string(data),
+			PayloadVersion: 2,
+		}
+	}
+	newHook := func(t *testing.T, meta *CustomMeta) *webhook_model.Webhook {
+		b, err := json.Marshal(meta)
+		require.NoError(t, err)
+		return &webhook_model.Webhook{
+			RepoID:     3,
+			IsActive:   true,
+			Type:       webhook_module.CUSTOM,
+			URL:        "https://chat.example.com/hook",
+			HTTPMethod: "PUT",
+			Meta:       string(b),
+		}
+	}
+
+	t.Run("Push", func(t *testing.T) {
+		hook := newHook(t, &CustomMeta{
+			BodyTemplate:    `{"text": {{printf "%s pushed %d commits to %s" .Payload.Pusher.UserName .Payload.TotalCommits .Payload.Repo.FullName | json}}}`,
+			HeadersTemplate: "X-Event: {{.Event}}\n\nX-Delivery: {{.Delivery}}\n",
+		})
+		task := newTask(t, webhook_module.HookEventPush, pushTestPayload())
+
+		req, reqBody, err := customHandler{}.NewRequest(context.Background(), hook, task)
+		require.NoError(t, err)
+
+		assert.Equal(t, "PUT", req.Method)
+		assert.Equal(t, "https://chat.example.com/hook", req.URL.String())
+		assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+		assert.Equal(t, "push", req.Header.Get("X-Event"))
+		assert.Equal(t, task.UUID, req.Header.Get("X-Delivery"))
+		assert.Equal(t, task.UUID, req.Header.Get("X-Forgejo-Delivery"))
+
+		body, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+		assert.Equal(t, `{"text": "user1 pushed 2 commits to test/repo"}`, string(body))
+		assert.Equal(t, body, reqBody)
+	})
+
+	t.Run("EventTemplates", func(t *testing.T) {
+		hook := newHook(t, &CustomMeta{
+			BodyTemplate: `default {{.Event}}` +
+				`{{define "issues"}}issue {{.Payload.Issue.Title}}{{end}}` +
+				`{{define "issue_label"}}label {{.Payload.Issue.Title | upper}}{{end}}`,
+			HeadersTemplate: "Content-Type: text/plain",
+		})
+
+		for event, expected := range map[webhook_module.HookEventType]string{
+			webhook_module.HookEventIssues:         "issue crash",
+			webhook_module.HookEventIssueAssign:    "issue crash",
+			webhook_module.HookEventIssueLabel:     "label CRASH",
+			webhook_module.HookEventPullRequest:    "default pull_request",
+			webhook_module.HookEventIssueMilestone: "issue crash",
+		} {
+			var p api.Payloader = issueTestPayload()
+			if event == webhook_module.HookEventPullRequest {
+				p = pullRequestTestPayload()
+			}
+			req, body, err := customHandler{}.NewRequest(context.Background(), hook, newTask(t, event, p))
+			require.NoError(t, err)
+			assert.Equal(t, expected, string(body), event)
+			assert.Equal(t, "text/plain", req.Header.Get("Content-Type"))
+		}
+	})
+
+	t.Run("InvalidHeader", func(t *testing.T) {
+		hook := newHook(t, &CustomMeta{
+			BodyTemplate:    `{}`,
+			HeadersTemplate: "X-Ref {{.Payload.Ref}}",
+		})
+		_, _, err := customHandler{}.NewRequest(context.Background(), hook, newTask(t, webhook_module.HookEventPush, pushTestPayload()))
+		assert.ErrorContains(t, err, `headers template: invalid header line "X-Ref refs/heads/test"`)
+	})
+
+	t.Run("MissingField", func(t *testing.T) {
+		hook := newHook(t, &CustomMeta{BodyTemplate: `{{.Payload.Issue.Title}}`})
+		_, _, err := customHandler{}.NewRequest(context.Background(), hook, newTask(t, webhook_module.HookEventPush, pushTestPayload()))
+		assert.ErrorContains(t, err, "body template:")
+	})
+
+	t.Run("TooLarge", func(t *testing.T) {
+		hook := newHook(t, &CustomMeta{BodyTemplate: `{{range .Payload.Commits}}{{printf "%2000000s" .ID}}{{end}}`})
+		_, _, err := customHandler{}.NewRequest(context.Background(), hook, newTask(t, webhook_module.HookEventPush, pushTestPayload()))
+		assert.ErrorIs(t, err, errCustomOutputTooLarge)
+	})
+}
+
+func TestCustomTemplateFuncs(t *testing.T) {
+	data := &CustomTemplateData{Event: webhook_module.HookEventPush, Payload: pushTestPayload()}
+	body, err := executeCustomTemplate("body", `{{.Payload.HeadCommit.Message | truncate 6}}|{{"  a b  " | trim | replace " " "-"}}|{{.Payload.HeadCommit.Author | json}}`, data)
+	require.NoError(t, err)
+	assert.Equal(t, `commit…|a-b|{"name":"user1","email":"user1@localhost","username":"user1"}`, string(body))
+}
+
+func TestCustomTemplateBounds(t *testing.T) {
+	t.Run("NestedRanges", func(t *testing.T) {
+		_, err := parseCustomTemplate("body", `{{range .Payload.Commits}}{{range .Added}}{{range .Removed}}{{range .Modified}}{{end}}{{end}}{{end}}{{end}}`)
+		assert.ErrorContains(t, err, "the range actions are nested more than 3 times")
+
+		// the ranges of the called templates are nested in the range of the caller
+		_, err = parseCustomTemplate("body", `{{define "files"}}{{range .Added}}{{range .Removed}}{{range .Modified}}{{end}}{{end}}{{end}}{{end}}{{range .Payload.Commits}}{{template "files" .}}{{end}}`)
+		assert.ErrorContains(t, err, "the range actions are nested more than 3 times")
+
+		_, err = parseCustomTemplate("body", `{{range .Payload.Commits}}{{range .Added}}{{end}}{{range .Removed}}{{range .}}{{end}}{{end}}{{end}}`)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Recursion", func(t *testing.T) {
+		_, err := parseCustomTemplate("body", `{{define "a"}}{{template "b" .}}{{end}}{{define "b"}}{{template "a" .}}{{end}}{{template "a" .}}`)
+		assert.ErrorContains(t, err, `recursive call of the template "a"`)
+	})
+
+	t.Run("TooManySteps", func(t *testing.T) {
+		// 2000 * 2000 iterations which write nothing
+		data := &CustomTemplateData{Event: webhook_module.HookEventPush, Payload: make([]int, 2000)}
+		_, err := executeCustomTemplate("body", `{{range .Payload}}{{range $.Payload}}{{end}}{{end}}`, data)
+		assert.ErrorIs(t, err, errCustomTemplateTooLong)
+
+		_, err = PreviewCustomTemplates(`{{range .Payload.Commits}}{{end}}`, `{{range .Payload.Commits}}{{end}}`, webhook_module.HookEventPush)
+		assert.NoError(t, err)
+	})
+}
+
+func TestPreviewCustomTemplates(t *testing.T) {
+	for _, event := range customSampleEvents {
+		preview, err := PreviewCustomTemplates(`{{.Event}} {{.Payload.Sender.UserName}}`, "", event)
+		require.NoError(t, err, event)
+		assert.Equal(t, string(event)+" forgejo", preview.Body)
+		assert.Equal(t, []CustomHeader{{Name: "Content-Type", Value: "application/json"}}, preview.Headers)
+	}
+
+	preview, err := PreviewCustomTemplates(`{}`, "content-type: text/plain", webhook_module.HookEventPush)
+	require.NoError(t, err)
+	assert.Equal(t, []CustomHeader{{Name: "content-type", Value: "text/plain"}}, preview.Headers)
+
+	_, err = PreviewCustomTemplates(`{{.Payload.Issue.Title`, "", webhook_module.HookEventIssues)
+	assert.Error(t, err)
+
+	_, err = PreviewCustomTemplates(`{}`, "", webhook_module.HookEventWiki)
+	assert.Error(t, err)
+}
+
+func TestUpdateCustomMeta(t *testing.T) {
+	w := &webhook_model.Webhook{Type: webhook_module.CUSTOM, HTTPMethod: "POST"}
+	require.NoError(t, UpdateCustomMeta(w, map[string]string{
+		"body_template":    `{{.Event}}`,
+		"headers_template": "X-Event: {{.Event}}",
+		"http_method":      "put",
+	}))
+	assert.Equal(t, "PUT", w.HTTPMethod)
+	assert.Equal(t, &CustomMeta{BodyTemplate: `{{.Event}}`, HeadersTemplate: "X-Event: {{.Event}}"}, customHandler{}.Metadata(w))
+
+	require.NoError(t, UpdateCustomMeta(w, map[string]string{"headers_template": ""}))
+	assert.Equal(t, &CustomMeta{BodyTemplate: `{{.Event}}`}, customHandler{}.Metadata(w))
+
+	assert.Error(t, UpdateCustomMeta(w, map[string]string{"body_template": ""}))
+	assert.Error(t, UpdateCustomMeta(w, map[string]string{"body_template": "{{.Event"}))
+	assert.Error(t, UpdateCustomMeta(w, map[string]string{"http_method": "GET"}))
+	assert.True(t, strings.HasPrefix(w.Meta, "{"))
+}
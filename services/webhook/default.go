@@ -55,8 +55,9 @@ func (defaultHandler) UnmarshalForm(bind func(any)) forms.WebhookForm {
 	bind(&form)
 
 	contentType := webhook_model.ContentTypeJSON
-	if webhook_model.HookContentType(form.ContentType) == webhook_model.ContentTypeForm {
-		contentType = webhook_model.ContentTypeForm
+	switch ct := webhook_model.HookContentType(form.ContentType); ct {
+	case webhook_model.ContentTypeForm, webhook_model.ContentTypeCloudEventsBinary, webhook_model.ContentTypeCloudEventsStructured:
+		contentType = ct
 	}
 	return forms.WebhookForm{
 		WebhookCoreForm: form.WebhookCoreForm,
@@ -105,6 +106,11 @@ func (defaultHandler) NewRequest(ctx context.Context, w *webhook_model.Webhook,
 			}
 
 			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		case webhook_model.ContentTypeCloudEventsBinary, webhook_model.ContentTypeCloudEventsStructured:
+			req, payloadContent, err = newCloudEventsRequest(w, t, payloadContent)
+			if err != nil {
+				return nil, nil, err
+			}
 		default:
 			return nil, nil, fmt.Errorf("invalid content type: %v", w.ContentType)
 		}
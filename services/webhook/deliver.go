@@ -8,6 +8,7 @@ import (
 	"crypto/tls"
 	"fmt"
 	"io"
+	"math/rand/v2"
 	"net/http"
 	"net/url"
 	"strings"
@@ -15,6 +16,7 @@ import (
 	"time"
 
 	webhook_model "code.gitea.io/gitea/models/webhook"
+	"code.gitea.io/gitea/modules/eventbus"
 	"code.gitea.io/gitea/modules/graceful"
 	"code.gitea.io/gitea/modules/hostmatcher"
 	"code.gitea.io/gitea/modules/log"
@@ -24,6 +26,7 @@ import (
 	"code.gitea.io/gitea/modules/setting"
 	"code.gitea.io/gitea/modules/timeutil"
 	webhook_module "code.gitea.io/gitea/modules/webhook"
+	notify_service "code.gitea.io/gitea/services/notify"
 
 	"github.com/gobwas/glob"
 )
@@ -70,6 +73,11 @@ func Deliver(ctx context.Context, t *webhook_model.HookTask) error {
 	for k, vals := range req.Header {
 		t.RequestInfo.Headers[k] = strings.Join(vals, ",")
 	}
+	pub, isPublisher := handler.(publisher)
+	if isPublisher {
+		// the URL of a message broker contains the credentials
+		t.RequestInfo.URL = req.URL.Redacted()
+	}
 
 	// Add Authorization Header
 	authorization, err := w.HeaderAuthorization()
@@ -104,6 +112,9 @@ func Deliver(ctx context.Context, t *webhook_model.HookTask) error {
 		return nil
 	}
 
+	// retry is set if the delivery failed in a way which might be resolved by trying again later
+	retry := false
+
 	// All code from this point will update the hook task
 	defer func() {
 		t.Delivered = timeutil.TimeStampNanoNow()
@@ -121,14 +132,24 @@ func Deliver(ctx context.Context, t *webhook_model.HookTask) error {
 
 		// Update webhook last delivery status.
 		if t.IsSucceed {
-			w.LastStatus = webhook_module.HookStatusSucceed
+			w.SetLastStatus(webhook_module.HookStatusSucceed)
 		} else {
-			w.LastStatus = webhook_module.HookStatusFail
+			w.SetLastStatus(webhook_module.HookStatusFail)
 		}
 		if err = webhook_model.UpdateWebhookLastStatus(ctx, w); err != nil {
 			log.Error("UpdateWebhookLastStatus: %v", err)
 			return
 		}
+
+		if t.IsSucceed || !w.IsActive {
+			return
+		}
+		if disableFailingWebhook(ctx, w) {
+			return
+		}
+		if retry {
+			scheduleRetry(ctx, t)
+		}
 	}()
 
 	if setting.DisableWebhooks {
@@ -140,15 +161,32 @@ func Deliver(ctx context.Context, t *webhook_model.HookTask) error {
 		return nil
 	}
 
+	if isPublisher {
+		ctx, cancel := context.WithTimeout(ctx, time.Duration(setting.Webhook.DeliverTimeout)*time.Second)
+		defer cancel()
+
+		ack, err := pub.Publish(ctx, webhookBrokerDialer, w, t, req, body)
+		if err != nil {
+			t.ResponseInfo.Body = fmt.Sprintf("Delivery: %v", err)
+			retry = true
+			return fmt.Errorf("unable to deliver webhook task[%d] to %s: %w", t.ID, t.RequestInfo.URL, err)
+		}
+		t.IsSucceed = true
+		t.ResponseInfo.Body = ack
+		return nil
+	}
+
 	resp, err := webhookHTTPClient.Do(req.WithContext(ctx))
 	if err != nil {
 		t.ResponseInfo.Body = fmt.Sprintf("Delivery: %v", err)
+		retry = true
 		return fmt.Errorf("unable to deliver webhook task[%d] in %s due to error in http client: %w", t.ID, w.URL, err)
 	}
 	defer resp.Body.Close()
 
 	// Status code is 20x can be seen as succeed.
 	t.IsSucceed = resp.StatusCode/100 == 2
+	retry = resp.StatusCode/100 == 5
 	t.ResponseInfo.Status = resp.StatusCode
 	for k, vals := range resp.Header {
 		t.ResponseInfo.Headers[k] = strings.Join(vals, ",")
@@ -163,6 +201,55 @@ func Deliver(ctx context.Context, t *webhook_model.HookTask) error {
 	return nil
 }
 
+// retryDelay returns the delay before the given retry of a delivery. The delay grows
+// exponentially with the attempts and is randomized to spread out the retries.
+func retryDelay(attempt int) time.Duration {
+	delay := setting.Webhook.RetryMaxBackoff
+	if attempt < 32 {
+		if d := setting.Webhook.RetryBackoff << (attempt - 1); d > 0 && d < delay {
+			delay = d
+		}
+	}
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+// scheduleRetry creates a new hook task which re-delivers the failed one after a delay
+func scheduleRetry(ctx context.Context, t *webhook_model.HookTask) {
+	if t.Attempt > setting.Webhook.MaxRetries {
+		return
+	}
+
+	delay := retryDelay(t.Attempt)
+	retryTask, err := webhook_model.CreateRetryHookTask(ctx, t, time.Now().Add(delay))
+	if err != nil {
+		log.Error("CreateRetryHookTask[%d]: %v", t.ID, err)
+		return
+	}
+	log.Trace("Hook delivery %s will be retried in %v: %s", t.UUID, delay, retryTask.UUID)
+
+	enqueueHookTaskAfter(retryTask.ID, delay)
+}
+
+// disableFailingWebhook disables the webhook and notifies its owners if the deliveries have been failing for too long
+func disableFailingWebhook(ctx context.Context, w *webhook_model.Webhook) bool {
+	if setting.Webhook.AutoDisableAfter <= 0 || w.FailingSince == 0 {
+		return false
+	}
+	if w.FailingSince.AddDuration(setting.Webhook.AutoDisableAfter) > timeutil.TimeStampNow() {
+		return false
+	}
+
+	if err := webhook_model.DisableWebhook(ctx, w); err != nil {
+		log.Error("DisableWebhook[%d]: %v", w.ID, err)
+		return false
+	}
+	log.Info("Webhook[%d] %s has been disabled as its deliveries are failing since %v", w.ID, w.URL, w.FailingSince.AsTime())
+
+	notify_service.WebhookDisabled(ctx, w)
+	return true
+}
+
 var (
 	webhookHTTPClient *http.Client
 	once              sync.Once
@@ -216,6 +303,11 @@ func Init() error {
 		},
 	}
 
+	webhookBrokerDialer = &eventbus.Dialer{
+		DialContext: hostmatcher.NewDialContext("webhook", allowedHostMatcher, nil),
+		TLSConfig:   &tls.Config{InsecureSkipVerify: setting.Webhook.SkipTLSVerify},
+	}
+
 	hookQueue = queue.CreateUniqueQueue(graceful.GetManager().ShutdownContext(), "webhook_sender", handler)
 	if hookQueue == nil {
 		return fmt.Errorf("unable to create webhook_sender queue")
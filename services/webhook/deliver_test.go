@@ -19,6 +19,8 @@ import (
 	webhook_model "code.gitea.io/gitea/models/webhook"
 	"code.gitea.io/gitea/modules/hostmatcher"
 	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/test"
+	"code.gitea.io/gitea/modules/timeutil"
 	webhook_module "code.gitea.io/gitea/modules/webhook"
 
 	"github.com/stretchr/testify/assert"
@@ -330,3 +332,121 @@ func TestWebhookDeliverSpecificTypes(t *testing.T) {
 		})
 	}
 }
+
+func TestWebhookDeliverRetry(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	status := http.StatusServiceUnavailable
+	var delivery string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		delivery = r.Header.Get("X-Forgejo-Delivery")
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(s.Close)
+
+	hook := &webhook_model.Webhook{
+		RepoID:      3,
+		IsActive:    true,
+		Type:        webhook_module.GITEA,
+		URL:         s.URL + "/webhook",
+		HTTPMethod:  "POST",
+		ContentType: webhook_model.ContentTypeJSON,
+	}
+	assert.NoError(t, webhook_model.CreateWebhook(db.DefaultContext, hook))
+
+	deliver := func(t *testing.T, attempt int) *webhook_model.HookTask {
+		t.Helper()
+
+		hookTask, err := webhook_model.CreateHookTask(db.DefaultContext, &webhook_model.HookTask{
+			HookID:         hook.ID,
+			EventType:      webhook_module.HookEventPush,
+			PayloadContent: `{"data": 42}`,
+			PayloadVersion: 1,
+			Attempt:        attempt,
+		})
+		assert.NoError(t, err)
+		assert.NoError(t, Deliver(context.Background(), hookTask))
+		assert.False(t, hookTask.IsSucceed)
+		return hookTask
+	}
+
+	lastTask := func(t *testing.T) *webhook_model.HookTask {
+		t.Helper()
+
+		tasks, err := webhook_model.HookTasks(db.DefaultContext, hook.ID, 1)
+		assert.NoError(t, err)
+		require.NotEmpty(t, tasks)
+		return tasks[0]
+	}
+
+	t.Run("ServerError", func(t *testing.T) {
+		before := time.Now()
+		hookTask := deliver(t, 1)
+
+		retryTask := lastTask(t)
+		assert.NotEqual(t, hookTask.ID, retryTask.ID)
+		assert.Equal(t, 2, retryTask.Attempt)
+		assert.False(t, retryTask.IsDelivered)
+		assert.Equal(t, hookTask.PayloadContent, retryTask.PayloadContent)
+
+		// the first retry happens between half and the full backoff
+		assert.GreaterOrEqual(t, retryTask.Delivered.AsTime(), before.Add(setting.Webhook.RetryBackoff/2))
+		assert.LessOrEqual(t, retryTask.Delivered.AsTime(), time.Now().Add(setting.Webhook.RetryBackoff))
+
+		// the retries are sent with the delivery UUID of the first attempt
+		assert.Equal(t, hookTask.UUID, delivery)
+		assert.NoError(t, Deliver(context.Background(), retryTask))
+		assert.Equal(t, hookTask.UUID, delivery)
+		assert.NotEqual(t, retryTask.UUID, delivery)
+	})
+
+	t.Run("MaxRetries", func(t *testing.T) {
+		hookTask := deliver(t, setting.Webhook.MaxRetries+1)
+		assert.Equal(t, hookTask.ID, lastTask(t).ID)
+	})
+
+	t.Run("ClientError", func(t *testing.T) {
+		status = http.StatusNotFound
+		defer func() { status = http.StatusServiceUnavailable }()
+
+		hookTask := deliver(t, 1)
+		assert.Equal(t, hookTask.ID, lastTask(t).ID)
+	})
+
+	t.Run("AutoDisable", func(t *testing.T) {
+		failingSince := timeutil.TimeStampNow().AddDuration(-setting.Webhook.AutoDisableAfter - time.Hour)
+		_, err := db.GetEngine(db.DefaultContext).ID(hook.ID).Cols("failing_since").Update(&webhook_model.Webhook{FailingSince: failingSince})
+		assert.NoError(t, err)
+
+		hookTask := deliver(t, 1)
+		assert.Equal(t, hookTask.ID, lastTask(t).ID)
+
+		hook := unittest.AssertExistsAndLoadBean(t, &webhook_model.Webhook{ID: hook.ID})
+		assert.False(t, hook.IsActive)
+		assert.Equal(t, webhook_module.HookStatusFail, hook.LastStatus)
+		assert.Zero(t, hook.FailingSince)
+	})
+}
+
+func TestRetryDelay(t *testing.T) {
+	defer test.MockVariableValue(&setting.Webhook.RetryBackoff, time.Minute)()
+	defer test.MockVariableValue(&setting.Webhook.RetryMaxBackoff, 10*time.Minute)()
+
+	for _, c := range []struct {
+		attempt int
+		max     time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{4, 8 * time.Minute},
+		{5, 10 * time.Minute},
+		{100, 10 * time.Minute},
+	} {
+		for i := 0; i < 10; i++ {
+			delay := retryDelay(c.attempt)
+			assert.GreaterOrEqual(t, delay, c.max/2)
+			assert.LessOrEqual(t, delay, c.max)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	webhook_model "code.gitea.io/gitea/models/webhook"
+	"code.gitea.io/gitea/modules/eventbus"
+	"code.gitea.io/gitea/modules/json"
+	webhook_module "code.gitea.io/gitea/modules/webhook"
+	gitea_context "code.gitea.io/gitea/services/context"
+	"code.gitea.io/gitea/services/webhook/shared"
+
+	"gitea.com/go-chi/binding"
+)
+
+// publisher is implemented by the handlers which publish the payloads to a message broker
+// instead of sending them to an HTTP endpoint. The request created by NewRequest is not sent,
+// it carries the payload and the headers of the message.
+type publisher interface {
+	// Publish publishes the message and returns the acknowledgement of the broker
+	Publish(ctx context.Context, d *eventbus.Dialer, w *webhook_model.Webhook, t *webhook_model.HookTask, req *http.Request, body []byte) (string, error)
+}
+
+// webhookBrokerDialer connects to the message brokers, it is restricted by the allowed host list
+var webhookBrokerDialer *eventbus.Dialer
+
+// newBrokerRequest creates the request holding the forgejo payload and the X-Forgejo-* headers for a message broker
+func newBrokerRequest(w *webhook_model.Webhook, t *webhook_model.HookTask) (*http.Request, []byte, error) {
+	req, err := http.NewRequest("PUBLISH", w.URL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	body := []byte(t.PayloadContent)
+	if err := shared.AddDefaultHeaders(req, []byte(w.Secret), t, body); err != nil {
+		return nil, nil, err
+	}
+	// the other headers only exist for the compatibility with HTTP endpoints written for Gitea, Gogs or GitHub
+	for k := range req.Header {
+		if k != "Content-Type" && !strings.HasPrefix(k, "X-Forgejo-") {
+			delete(req.Header, k)
+		}
+	}
+	return req, body, nil
+}
+
+// newBrokerMessage creates the message for the request created by newBrokerRequest
+func newBrokerMessage(req *http.Request, body []byte, key string) *eventbus.Message {
+	msg := &eventbus.Message{
+		Key:         key,
+		Headers:     map[string]string{},
+		ContentType: req.Header.Get("Content-Type"),
+		Body:        body,
+	}
+	for k, vals := range req.Header {
+		if k != "Content-Type" && k != "Authorization" {
+			msg.Headers[k] = strings.Join(vals, ",")
+		}
+	}
+	return msg
+}
+
+// eventSource returns the owner and the name of the repository the payload of the task is about,
+// the owner of the package or the organization is used for events without repository.
+func eventSource(t *webhook_model.HookTask) (owner, repo string) {
+	type user struct {
+		UserName string `json:"login"`
+	}
+	var payload struct {
+		Repository *struct {
+			FullName string `json:"full_name"`
+		} `json:"repository"`
+		Package *struct {
+			Owner *user `json:"owner"`
+		} `json:"package"`
+		Organization *user `json:"organization"`
+	}
+	if err := json.Unmarshal([]byte(t.PayloadContent), &payload); err != nil {
+		return "", ""
+	}
+
+	switch {
+	case payload.Repository != nil && payload.Repository.FullName != "":
+		owner, repo, _ = strings.Cut(payload.Repository.FullName, "/")
+		return owner, repo
+	case payload.Package != nil && payload.Package.Owner != nil:
+		return payload.Package.Owner.UserName, ""
+	case payload.Organization != nil:
+		return payload.Organization.UserName, ""
+	}
+	return "", ""
+}
+
+// eventRoutingKey returns the routing key or subject <prefix>.<event type>.<owner>.<repository> for the task.
+// Dots and wildcards in the names are replaced by underscores, a missing name is replaced by an underscore.
+func eventRoutingKey(prefix string, t *webhook_model.HookTask) string {
+	owner, repo := eventSource(t)
+	token := func(s string) string {
+		if s == "" {
+			return "_"
+		}
+		return strings.Map(func(r rune) rune {
+			switch r {
+			case '.', '*', '>', '#', ' ', '\t', '\r', '\n':
+				return '_'
+			}
+			return r
+		}, s)
+	}
+
+	key := string(t.EventType) + "." + token(owner) + "." + token(repo)
+	if prefix != "" {
+		key = prefix + "." + key
+	}
+	return key
+}
+
+// validateBrokerURL adds an error to the URL field if its scheme is not one of the given ones
+func validateBrokerURL(req *http.Request, errs binding.Errors, field, rawURL string, schemes ...string) binding.Errors {
+	u, err := url.Parse(rawURL)
+	if err == nil && slices.Contains(schemes, u.Scheme) && u.Hostname() != "" {
+		return errs
+	}
+	ctx := gitea_context.GetWebContext(req)
+	return append(errs, binding.Error{
+		FieldNames:     []string{field},
+		Classification: binding.ERR_URL,
+		Message:        ctx.Locale.TrString("repo.settings.broker_url_invalid", strings.Join(schemes, "://, ")+"://"),
+	})
+}
+
+// validateBrokerField adds an error to the field if the value is not valid
+func validateBrokerField(req *http.Request, errs binding.Errors, field string, valid bool) binding.Errors {
+	if valid {
+		return errs
+	}
+	ctx := gitea_context.GetWebContext(req)
+	return append(errs, binding.Error{
+		FieldNames:     []string{field},
+		Classification: "",
+		Message:        ctx.Locale.TrString("repo.settings.broker_field_invalid"),
+	})
+}
+
+// IsBrokerHookType returns true if the webhooks of the type publish to a message broker
+func IsBrokerHookType(hookType webhook_module.HookType) bool {
+	_, ok := GetWebhookHandler(hookType).(publisher)
+	return ok
+}
+
+// UpdateBrokerMeta validates the URL of a message broker webhook and updates its metadata
+// with the config options of the API, options which are not given are kept.
+func UpdateBrokerMeta(w *webhook_model.Webhook, config map[string]string) error {
+	u, err := url.Parse(w.URL)
+	if err != nil || u.Hostname() == "" {
+		return fmt.Errorf("invalid broker URL")
+	}
+
+	var meta any
+	switch w.Type {
+	case webhook_module.AMQP:
+		m := &AMQPMeta{}
+		if err := unmarshalBrokerMeta(w, m); err != nil {
+			return err
+		}
+		if v, ok := config["exchange"]; ok {
+			m.Exchange = v
+		}
+		if v, ok := config["routing_key_prefix"]; ok {
+			m.RoutingKeyPrefix = v
+		}
+		switch {
+		case u.Scheme != "amqp" && u.Scheme != "amqps":
+			return fmt.Errorf("the broker URL must start with amqp:// or amqps://")
+		case len(m.Exchange) > 255:
+			return fmt.Errorf("invalid exchange")
+		case m.RoutingKeyPrefix != "" && (len(m.RoutingKeyPrefix) > 100 || !eventbus.ValidNATSSubject(m.RoutingKeyPrefix)):
+			return fmt.Errorf("invalid routing key prefix")
+		}
+		meta = m
+	case webhook_module.NATS:
+		m := &NATSMeta{}
+		if err := unmarshalBrokerMeta(w, m); err != nil {
+			return err
+		}
+		if v, ok := config["subject_prefix"]; ok {
+			m.SubjectPrefix = v
+		}
+		switch {
+		case u.Scheme != "nats" && u.Scheme != "tls":
+			return fmt.Errorf("the broker URL must start with nats:// or tls://")
+		case m.SubjectPrefix != "" && (len(m.SubjectPrefix) > 100 || !eventbus.ValidNATSSubject(m.SubjectPrefix)):
+			return fmt.Errorf("invalid subject prefix")
+		}
+		meta = m
+	case webhook_module.KAFKA:
+		m := &KafkaMeta{}
+		if err := unmarshalBrokerMeta(w, m); err != nil {
+			return err
+		}
+		if v, ok := config["topic"]; ok {
+			m.Topic = v
+		}
+		switch {
+		case u.Scheme != "kafka" && u.Scheme != "kafka+tls":
+			return fmt.Errorf("the broker URL must start with kafka:// or kafka+tls://")
+		case !eventbus.ValidKafkaTopic(m.Topic):
+			return fmt.Errorf("invalid topic")
+		}
+		meta = m
+	default:
+		return fmt.Errorf("%s is not a message broker webhook", w.Type)
+	}
+
+	buf, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	w.Meta = string(buf)
+	return nil
+}
+
+func unmarshalBrokerMeta(w *webhook_model.Webhook, meta any) error {
+	if w.Meta == "" {
+		return nil
+	}
+	return json.Unmarshal([]byte(w.Meta), meta)
+}
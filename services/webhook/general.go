@@ -10,6 +10,7 @@ import (
 	"strings"
 
 	webhook_model "code.gitea.io/gitea/models/webhook"
+	"code.gitea.io/gitea/modules/git"
 	"code.gitea.io/gitea/modules/setting"
 	api "code.gitea.io/gitea/modules/structs"
 	"code.gitea.io/gitea/modules/util"
@@ -28,6 +29,29 @@ func htmlLinkFormatter(url, text string) string {
 	return fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(url), html.EscapeString(text))
 }
 
+// markdownLinkFormatter creates a markdown link
+func markdownLinkFormatter(url, text string) string {
+	return fmt.Sprintf("[%s](%s)", text, url)
+}
+
+// getPushTitle returns the title of a push and the link to its commits
+func getPushTitle(p *api.PushPayload) (title, link string) {
+	branchName := git.RefName(p.Ref).ShortName()
+
+	var commitDesc string
+	if p.TotalCommits == 1 {
+		commitDesc = "1 new commit"
+		link = p.Commits[0].URL
+	} else {
+		commitDesc = fmt.Sprintf("%d new commits", p.TotalCommits)
+		link = p.CompareURL
+	}
+	if link == "" {
+		link = p.Repo.HTMLURL + "/src/" + util.PathEscapeSegments(branchName)
+	}
+	return fmt.Sprintf("[%s:%s] %s", p.Repo.FullName, branchName, commitDesc), link
+}
+
 // getPullRequestInfo gets the information for a pull request
 func getPullRequestInfo(p *api.PullRequestPayload) (title, link, by, operator, operateResult, assignees string) {
 	title = fmt.Sprintf("[PullRequest-%s #%d]: %s\n%s", p.Repository.FullName, p.PullRequest.Index, p.Action, p.PullRequest.Title)
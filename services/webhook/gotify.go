@@ -0,0 +1,304 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	webhook_model "code.gitea.io/gitea/models/webhook"
+	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/json"
+	"code.gitea.io/gitea/modules/log"
+	api "code.gitea.io/gitea/modules/structs"
+	"code.gitea.io/gitea/modules/svg"
+	"code.gitea.io/gitea/modules/util"
+	"code.gitea.io/gitea/modules/validation"
+	webhook_module "code.gitea.io/gitea/modules/webhook"
+	"code.gitea.io/gitea/services/forms"
+	"code.gitea.io/gitea/services/webhook/shared"
+)
+
+type gotifyHandler struct{}
+
+func (gotifyHandler) Type() webhook_module.HookType { return webhook_module.GOTIFY }
+func (gotifyHandler) Icon(size int) template.HTML {
+	return svg.RenderHTML("octicon-megaphone", size, "img")
+}
+
+func (gotifyHandler) UnmarshalForm(bind func(any)) forms.WebhookForm {
+	var form struct {
+		forms.WebhookCoreForm
+		ServerURL string `binding:"Required;ValidUrl"`
+		AppToken  string `binding:"Required"`
+		Priority  int    `binding:"Range(0,10)"`
+	}
+	bind(&form)
+	form.AuthorizationHeader = "Bearer " + strings.TrimSpace(form.AppToken)
+
+	// https://gotify.net/api-docs#/message/createMessage
+	return forms.WebhookForm{
+		WebhookCoreForm: form.WebhookCoreForm,
+		URL:             strings.TrimSuffix(form.ServerURL, "/") + "/message",
+		ContentType:     webhook_model.ContentTypeJSON,
+		Secret:          "",
+		HTTPMethod:      http.MethodPost,
+		Metadata: &GotifyMeta{
+			ServerURL: form.ServerURL,
+			Priority:  form.Priority,
+		},
+	}
+}
+
+type (
+	// GotifyPayload represents a message of a gotify application
+	GotifyPayload struct {
+		Title    string       `json:"title"`
+		Message  string       `json:"message"`
+		Priority int          `json:"priority"`
+		Extras   GotifyExtras `json:"extras"`
+	}
+
+	// GotifyExtras are the extras of a message understood by the gotify clients
+	// https://gotify.net/docs/msgextras
+	GotifyExtras struct {
+		Display      GotifyDisplay       `json:"client::display"`
+		Notification *GotifyNotification `json:"client::notification,omitempty"`
+	}
+
+	// GotifyDisplay tells the clients how to render the message
+	GotifyDisplay struct {
+		ContentType string `json:"contentType"`
+	}
+
+	// GotifyNotification is the action of the notification of the message
+	GotifyNotification struct {
+		Click struct {
+			URL string `json:"url"`
+		} `json:"click"`
+	}
+
+	// GotifyMeta contains the gotify metadata
+	GotifyMeta struct {
+		ServerURL string `json:"server_url"`
+		Priority  int    `json:"priority"`
+	}
+)
+
+// Metadata returns gotify metadata
+func (gotifyHandler) Metadata(w *webhook_model.Webhook) any {
+	s := &GotifyMeta{}
+	if err := json.Unmarshal([]byte(w.Meta), s); err != nil {
+		log.Error("gotifyHandler.Metadata(%d): %v", w.ID, err)
+	}
+	return s
+}
+
+// UpdateGotifyMeta validates a gotify webhook and updates its metadata with the config options of the API,
+// options which are not given are kept. The URL is the message endpoint of the server, e.g. https://gotify.example.com/message,
+// and the application token is given as authorization header.
+func UpdateGotifyMeta(w *webhook_model.Webhook, config map[string]string) error {
+	meta := &GotifyMeta{}
+	if w.Meta != "" {
+		if err := json.Unmarshal([]byte(w.Meta), meta); err != nil {
+			return fmt.Errorf("invalid webhook meta: %w", err)
+		}
+	}
+	if v, ok := config["priority"]; ok {
+		priority, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid priority %q", v)
+		}
+		meta.Priority = priority
+	}
+
+	serverURL, ok := strings.CutSuffix(w.URL, "/message")
+	switch {
+	case !ok || !validation.IsValidURL(serverURL):
+		return fmt.Errorf("the URL must be the /message endpoint of a gotify server")
+	case meta.Priority < 0 || meta.Priority > 10:
+		return fmt.Errorf("the priority must be between 0 and 10")
+	}
+	meta.ServerURL = serverURL
+
+	b, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	w.ContentType = webhook_model.ContentTypeJSON
+	w.Meta = string(b)
+	return nil
+}
+
+// Create implements PayloadConvertor Create method
+func (g gotifyConvertor) Create(p *api.CreatePayload) (GotifyPayload, error) {
+	refName := git.RefName(p.Ref).ShortName()
+	refLink := p.Repo.HTMLURL + "/src/" + util.PathEscapeSegments(refName)
+	title := fmt.Sprintf("[%s] %s %s created", p.Repo.FullName, p.RefType, refName)
+	text := fmt.Sprintf("%s %s created by %s", p.RefType, markdownLinkFormatter(refLink, refName), p.Sender.UserName)
+
+	return g.createPayload(title, text, refLink), nil
+}
+
+// Delete implements PayloadConvertor Delete method
+func (g gotifyConvertor) Delete(p *api.DeletePayload) (GotifyPayload, error) {
+	refName := git.RefName(p.Ref).ShortName()
+	title := fmt.Sprintf("[%s] %s %s deleted", p.Repo.FullName, p.RefType, refName)
+	text := fmt.Sprintf("%s %s deleted by %s", p.RefType, refName, p.Sender.UserName)
+
+	return g.createPayload(title, text, p.Repo.HTMLURL), nil
+}
+
+// Fork implements PayloadConvertor Fork method
+func (g gotifyConvertor) Fork(p *api.ForkPayload) (GotifyPayload, error) {
+	title := fmt.Sprintf("%s is forked to %s", p.Forkee.FullName, p.Repo.FullName)
+	text := fmt.Sprintf("%s is forked to %s", p.Forkee.FullName, markdownLinkFormatter(p.Repo.HTMLURL, p.Repo.FullName))
+
+	return g.createPayload(title, text, p.Repo.HTMLURL), nil
+}
+
+// Push implements PayloadConvertor Push method
+func (g gotifyConvertor) Push(p *api.PushPayload) (GotifyPayload, error) {
+	title, titleLink := getPushTitle(p)
+
+	var text string
+	// for each commit, generate a list item
+	for i, commit := range p.Commits {
+		text += fmt.Sprintf("- %s %s - %s", markdownLinkFormatter(commit.URL, commit.ID[:7]),
+			strings.TrimRight(commit.Message, "\r\n"), commit.Author.Name)
+		// add linebreak to each commit but the last
+		if i < len(p.Commits)-1 {
+			text += "\n"
+		}
+	}
+
+	return g.createPayload(title, text, titleLink), nil
+}
+
+// Issue implements PayloadConvertor Issue method
+func (g gotifyConvertor) Issue(p *api.IssuePayload) (GotifyPayload, error) {
+	title, _, _, _ := getIssuesPayloadInfo(p, noneLinkFormatter, false)
+	text, _, attachmentText, _ := getIssuesPayloadInfo(p, markdownLinkFormatter, true)
+
+	return g.createPayload(title, joinMarkdown(text, attachmentText), p.Issue.HTMLURL), nil
+}
+
+// IssueComment implements PayloadConvertor IssueComment method
+func (g gotifyConvertor) IssueComment(p *api.IssueCommentPayload) (GotifyPayload, error) {
+	title, _, _ := getIssueCommentPayloadInfo(p, noneLinkFormatter, false)
+	text, _, _ := getIssueCommentPayloadInfo(p, markdownLinkFormatter, true)
+
+	return g.createPayload(title, joinMarkdown(text, p.Comment.Body), p.Comment.HTMLURL), nil
+}
+
+// PullRequest implements PayloadConvertor PullRequest method
+func (g gotifyConvertor) PullRequest(p *api.PullRequestPayload) (GotifyPayload, error) {
+	title, _, _, _ := getPullRequestPayloadInfo(p, noneLinkFormatter, false)
+	text, _, attachmentText, _ := getPullRequestPayloadInfo(p, markdownLinkFormatter, true)
+
+	return g.createPayload(title, joinMarkdown(text, attachmentText), p.PullRequest.HTMLURL), nil
+}
+
+// Review implements PayloadConvertor Review method
+func (g gotifyConvertor) Review(p *api.PullRequestPayload, event webhook_module.HookEventType) (GotifyPayload, error) {
+	var title, text string
+	switch p.Action {
+	case api.HookIssueReviewed:
+		action, err := parseHookPullRequestEventType(event)
+		if err != nil {
+			return GotifyPayload{}, err
+		}
+
+		title = fmt.Sprintf("[%s] Pull request review %s: #%d %s", p.Repository.FullName, action, p.Index, p.PullRequest.Title)
+		text = p.Review.Content
+	}
+
+	return g.createPayload(title, text, p.PullRequest.HTMLURL), nil
+}
+
+// Repository implements PayloadConvertor Repository method
+func (g gotifyConvertor) Repository(p *api.RepositoryPayload) (GotifyPayload, error) {
+	var title, link string
+	switch p.Action {
+	case api.HookRepoCreated:
+		title = fmt.Sprintf("[%s] Repository created", p.Repository.FullName)
+		link = p.Repository.HTMLURL
+	case api.HookRepoDeleted:
+		title = fmt.Sprintf("[%s] Repository deleted", p.Repository.FullName)
+	}
+
+	return g.createPayload(title, "", link), nil
+}
+
+// Wiki implements PayloadConvertor Wiki method
+func (g gotifyConvertor) Wiki(p *api.WikiPayload) (GotifyPayload, error) {
+	title, _, _ := getWikiPayloadInfo(p, noneLinkFormatter, false)
+	text, _, _ := getWikiPayloadInfo(p, markdownLinkFormatter, true)
+
+	return g.createPayload(title, text, p.Repository.HTMLURL+"/wiki/"+url.PathEscape(p.Page)), nil
+}
+
+// Release implements PayloadConvertor Release method
+func (g gotifyConvertor) Release(p *api.ReleasePayload) (GotifyPayload, error) {
+	title, _ := getReleasePayloadInfo(p, noneLinkFormatter, false)
+	text, _ := getReleasePayloadInfo(p, markdownLinkFormatter, true)
+
+	return g.createPayload(title, joinMarkdown(text, p.Release.Note), p.Release.HTMLURL), nil
+}
+
+// Package implements PayloadConvertor Package method
+func (g gotifyConvertor) Package(p *api.PackagePayload) (GotifyPayload, error) {
+	title, _ := getPackagePayloadInfo(p, noneLinkFormatter, false)
+	text, _ := getPackagePayloadInfo(p, markdownLinkFormatter, true)
+
+	return g.createPayload(title, text, p.Package.HTMLURL), nil
+}
+
+// createPayload creates a markdown message, gotify requires a message so the title is used if there is no text
+func (g gotifyConvertor) createPayload(title, text, click string) GotifyPayload {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		text = title
+	}
+	payload := GotifyPayload{
+		Title:    title,
+		Message:  text,
+		Priority: g.Priority,
+		Extras: GotifyExtras{
+			Display: GotifyDisplay{ContentType: "text/markdown"},
+		},
+	}
+	if click != "" {
+		payload.Extras.Notification = &GotifyNotification{}
+		payload.Extras.Notification.Click.URL = click
+	}
+	return payload
+}
+
+// joinMarkdown appends the text to the summary as a new paragraph
+func joinMarkdown(summary, text string) string {
+	if text = strings.TrimSpace(text); text == "" {
+		return summary
+	}
+	return summary + "\n\n" + text
+}
+
+type gotifyConvertor struct {
+	Priority int
+}
+
+var _ shared.PayloadConvertor[GotifyPayload] = gotifyConvertor{}
+
+func (gotifyHandler) NewRequest(ctx context.Context, w *webhook_model.Webhook, t *webhook_model.HookTask) (*http.Request, []byte, error) {
+	meta := &GotifyMeta{}
+	if err := json.Unmarshal([]byte(w.Meta), meta); err != nil {
+		return nil, nil, fmt.Errorf("gotifyHandler.NewRequest meta json: %w", err)
+	}
+	return shared.NewJSONRequest(gotifyConvertor{Priority: meta.Priority}, w, t, false)
+}
@@ -0,0 +1,140 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	webhook_model "code.gitea.io/gitea/models/webhook"
+	"code.gitea.io/gitea/modules/json"
+	api "code.gitea.io/gitea/modules/structs"
+	webhook_module "code.gitea.io/gitea/modules/webhook"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGotifyPayload(t *testing.T) {
+	gc := gotifyConvertor{Priority: 7}
+
+	t.Run("Create", func(t *testing.T) {
+		p := createTestPayload()
+
+		pl, err := gc.Create(p)
+		require.NoError(t, err)
+
+		assert.Equal(t, 7, pl.Priority)
+		assert.Equal(t, "text/markdown", pl.Extras.Display.ContentType)
+		assert.Equal(t, "[test/repo] branch test created", pl.Title)
+		assert.Equal(t, "branch [test](http://localhost:3000/test/repo/src/test) created by user1", pl.Message)
+		assert.Equal(t, "http://localhost:3000/test/repo/src/test", pl.Extras.Notification.Click.URL)
+	})
+
+	t.Run("Push", func(t *testing.T) {
+		p := pushTestPayload()
+
+		pl, err := gc.Push(p)
+		require.NoError(t, err)
+
+		assert.Equal(t, "[test/repo:test] 2 new commits", pl.Title)
+		assert.Equal(t, "- [2020558](http://localhost:3000/test/repo/commit/2020558fe2e34debb818a514715839cabd25e778) commit message - user1\n- [2020558](http://localhost:3000/test/repo/commit/2020558fe2e34debb818a514715839cabd25e778) commit message - user1", pl.Message)
+	})
+
+	t.Run("Issue", func(t *testing.T) {
+		p := issueTestPayload()
+		p.Action = api.HookIssueOpened
+
+		pl, err := gc.Issue(p)
+		require.NoError(t, err)
+
+		assert.Equal(t, "[test/repo] Issue opened: #2 crash", pl.Title)
+		assert.Equal(t, "[[test/repo](http://localhost:3000/test/repo)] Issue opened: [#2 crash](http://localhost:3000/test/repo/issues/2) by [user1](https://try.gitea.io/user1)\n\nissue body", pl.Message)
+		assert.Equal(t, "http://localhost:3000/test/repo/issues/2", pl.Extras.Notification.Click.URL)
+	})
+
+	t.Run("PullRequest", func(t *testing.T) {
+		p := pullRequestTestPayload()
+
+		pl, err := gc.PullRequest(p)
+		require.NoError(t, err)
+
+		assert.Equal(t, "[test/repo] Pull request opened: #12 Fix bug", pl.Title)
+		assert.Equal(t, "[[test/repo](http://localhost:3000/test/repo)] Pull request opened: [#12 Fix bug](http://localhost:3000/test/repo/pulls/12) by [user1](https://try.gitea.io/user1)\n\nfixes bug #2", pl.Message)
+	})
+
+	t.Run("Repository", func(t *testing.T) {
+		p := repositoryTestPayload()
+		p.Action = api.HookRepoDeleted
+
+		pl, err := gc.Repository(p)
+		require.NoError(t, err)
+
+		assert.Equal(t, "[test/repo] Repository deleted", pl.Message)
+		assert.Nil(t, pl.Extras.Notification)
+	})
+
+	t.Run("Release", func(t *testing.T) {
+		p := pullReleaseTestPayload()
+
+		pl, err := gc.Release(p)
+		require.NoError(t, err)
+
+		assert.Equal(t, "[test/repo] Release created: v1.0", pl.Title)
+		assert.Equal(t, "[[test/repo](http://localhost:3000/test/repo)] Release created: [v1.0](http://localhost:3000/test/repo/releases/tag/v1.0) by [user1](https://try.gitea.io/user1)\n\nNote of first stable release", pl.Message)
+	})
+}
+
+func TestGotifyJSONPayload(t *testing.T) {
+	p := pushTestPayload()
+	data, err := p.JSONPayload()
+	require.NoError(t, err)
+
+	hook := &webhook_model.Webhook{
+		RepoID:     3,
+		IsActive:   true,
+		Type:       webhook_module.GOTIFY,
+		URL:        "https://gotify.example.com/message",
+		Meta:       `{"server_url":"https://gotify.example.com","priority":2}`,
+		HTTPMethod: "POST",
+	}
+	task := &webhook_model.HookTask{
+		HookID:         hook.ID,
+		EventType:      webhook_module.HookEventPush,
+		PayloadContent: string(data),
+		PayloadVersion: 2,
+	}
+
+	req, reqBody, err := gotifyHandler{}.NewRequest(context.Background(), hook, task)
+	require.NotNil(t, req)
+	require.NotNil(t, reqBody)
+	require.NoError(t, err)
+
+	assert.Equal(t, "POST", req.Method)
+	assert.Equal(t, "https://gotify.example.com/message", req.URL.String())
+	assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+	var body map[string]any
+	err = json.NewDecoder(req.Body).Decode(&body)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, body["priority"])
+	assert.Equal(t, map[string]any{
+		"client::display":      map[string]any{"contentType": "text/markdown"},
+		"client::notification": map[string]any{"click": map[string]any{"url": "http://localhost:3000/test/repo/src/test"}},
+	}, body["extras"])
+}
+
+func TestUpdateGotifyMeta(t *testing.T) {
+	w := &webhook_model.Webhook{Type: webhook_module.GOTIFY, URL: "https://gotify.example.com/message"}
+	require.NoError(t, UpdateGotifyMeta(w, map[string]string{"priority": "8"}))
+	assert.Equal(t, webhook_model.ContentTypeJSON, w.ContentType)
+	assert.Equal(t, &GotifyMeta{ServerURL: "https://gotify.example.com", Priority: 8}, gotifyHandler{}.Metadata(w))
+
+	// options which are not given are kept
+	require.NoError(t, UpdateGotifyMeta(w, nil))
+	assert.Equal(t, &GotifyMeta{ServerURL: "https://gotify.example.com", Priority: 8}, gotifyHandler{}.Metadata(w))
+
+	assert.Error(t, UpdateGotifyMeta(w, map[string]string{"priority": "11"}))
+	w.URL = "https://gotify.example.com"
+	assert.Error(t, UpdateGotifyMeta(w, nil), "the URL must be the message endpoint")
+}
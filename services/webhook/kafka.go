@@ -0,0 +1,100 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"net/http"
+
+	webhook_model "code.gitea.io/gitea/models/webhook"
+	"code.gitea.io/gitea/modules/eventbus"
+	"code.gitea.io/gitea/modules/json"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/svg"
+	webhook_module "code.gitea.io/gitea/modules/webhook"
+	"code.gitea.io/gitea/services/forms"
+
+	"gitea.com/go-chi/binding"
+)
+
+type kafkaHandler struct{}
+
+func (kafkaHandler) Type() webhook_module.HookType { return webhook_module.KAFKA }
+func (kafkaHandler) Icon(size int) template.HTML {
+	return svg.RenderHTML("octicon-stack", size, "img")
+}
+
+type kafkaForm struct {
+	forms.WebhookCoreForm
+	BrokerURL string `binding:"Required"`
+	Topic     string `binding:"Required"`
+	Secret    string
+}
+
+var _ binding.Validator = &kafkaForm{}
+
+// Validate implements binding.Validator.
+func (f *kafkaForm) Validate(req *http.Request, errs binding.Errors) binding.Errors {
+	errs = validateBrokerURL(req, errs, "BrokerURL", f.BrokerURL, "kafka", "kafka+tls")
+	return validateBrokerField(req, errs, "Topic", eventbus.ValidKafkaTopic(f.Topic))
+}
+
+func (kafkaHandler) UnmarshalForm(bind func(any)) forms.WebhookForm {
+	var form kafkaForm
+	bind(&form)
+
+	return forms.WebhookForm{
+		WebhookCoreForm: form.WebhookCoreForm,
+		URL:             form.BrokerURL,
+		ContentType:     webhook_model.ContentTypeJSON,
+		Secret:          form.Secret,
+		HTTPMethod:      http.MethodPost,
+		Metadata: &KafkaMeta{
+			Topic: form.Topic,
+		},
+	}
+}
+
+// KafkaMeta contains the metadata for the webhook
+type KafkaMeta struct {
+	Topic string `json:"topic"`
+}
+
+// Metadata returns kafka metadata
+func (kafkaHandler) Metadata(w *webhook_model.Webhook) any {
+	s := &KafkaMeta{}
+	if err := json.Unmarshal([]byte(w.Meta), s); err != nil {
+		log.Error("kafkaHandler.Metadata(%d): %v", w.ID, err)
+	}
+	return s
+}
+
+// NewRequest creates the request holding the forgejo payload, it is published by Publish.
+func (kafkaHandler) NewRequest(ctx context.Context, w *webhook_model.Webhook, t *webhook_model.HookTask) (*http.Request, []byte, error) {
+	return newBrokerRequest(w, t)
+}
+
+// Publish produces the payload to the topic. The record key is <owner>/<repository>, so the
+// events of a repository keep their order.
+func (kafkaHandler) Publish(ctx context.Context, d *eventbus.Dialer, w *webhook_model.Webhook, t *webhook_model.HookTask, req *http.Request, body []byte) (string, error) {
+	meta := &KafkaMeta{}
+	if err := json.Unmarshal([]byte(w.Meta), meta); err != nil {
+		return "", fmt.Errorf("kafkaHandler.Publish meta json: %w", err)
+	}
+
+	var key string
+	if owner, repo := eventSource(t); repo != "" {
+		key = owner + "/" + repo
+	} else {
+		key = owner
+	}
+	msg := newBrokerMessage(req, body, key)
+	ack, err := eventbus.PublishKafka(ctx, d, w.URL, meta.Topic, msg)
+	if err != nil {
+		return "", fmt.Errorf("produce to topic %s: %w", meta.Topic, err)
+	}
+	return ack, nil
+}
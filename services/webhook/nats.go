@@ -0,0 +1,93 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"net/http"
+
+	webhook_model "code.gitea.io/gitea/models/webhook"
+	"code.gitea.io/gitea/modules/eventbus"
+	"code.gitea.io/gitea/modules/json"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/svg"
+	webhook_module "code.gitea.io/gitea/modules/webhook"
+	"code.gitea.io/gitea/services/forms"
+
+	"gitea.com/go-chi/binding"
+)
+
+type natsHandler struct{}
+
+func (natsHandler) Type() webhook_module.HookType { return webhook_module.NATS }
+func (natsHandler) Icon(size int) template.HTML {
+	return svg.RenderHTML("octicon-broadcast", size, "img")
+}
+
+type natsForm struct {
+	forms.WebhookCoreForm
+	BrokerURL     string `binding:"Required"`
+	SubjectPrefix string `binding:"MaxSize(100)"`
+	Secret        string
+}
+
+var _ binding.Validator = &natsForm{}
+
+// Validate implements binding.Validator.
+func (f *natsForm) Validate(req *http.Request, errs binding.Errors) binding.Errors {
+	errs = validateBrokerURL(req, errs, "BrokerURL", f.BrokerURL, "nats", "tls")
+	return validateBrokerField(req, errs, "SubjectPrefix", f.SubjectPrefix == "" || eventbus.ValidNATSSubject(f.SubjectPrefix))
+}
+
+func (natsHandler) UnmarshalForm(bind func(any)) forms.WebhookForm {
+	var form natsForm
+	bind(&form)
+
+	return forms.WebhookForm{
+		WebhookCoreForm: form.WebhookCoreForm,
+		URL:             form.BrokerURL,
+		ContentType:     webhook_model.ContentTypeJSON,
+		Secret:          form.Secret,
+		HTTPMethod:      http.MethodPost,
+		Metadata: &NATSMeta{
+			SubjectPrefix: form.SubjectPrefix,
+		},
+	}
+}
+
+// NATSMeta contains the metadata for the webhook
+type NATSMeta struct {
+	SubjectPrefix string `json:"subject_prefix"`
+}
+
+// Metadata returns nats metadata
+func (natsHandler) Metadata(w *webhook_model.Webhook) any {
+	s := &NATSMeta{}
+	if err := json.Unmarshal([]byte(w.Meta), s); err != nil {
+		log.Error("natsHandler.Metadata(%d): %v", w.ID, err)
+	}
+	return s
+}
+
+// NewRequest creates the request holding the forgejo payload, it is published by Publish.
+func (natsHandler) NewRequest(ctx context.Context, w *webhook_model.Webhook, t *webhook_model.HookTask) (*http.Request, []byte, error) {
+	return newBrokerRequest(w, t)
+}
+
+// Publish publishes the payload to the subject <prefix>.<event type>.<owner>.<repository>
+func (natsHandler) Publish(ctx context.Context, d *eventbus.Dialer, w *webhook_model.Webhook, t *webhook_model.HookTask, req *http.Request, body []byte) (string, error) {
+	meta := &NATSMeta{}
+	if err := json.Unmarshal([]byte(w.Meta), meta); err != nil {
+		return "", fmt.Errorf("natsHandler.Publish meta json: %w", err)
+	}
+
+	msg := newBrokerMessage(req, body, eventRoutingKey(meta.SubjectPrefix, t))
+	ack, err := eventbus.PublishNATS(ctx, d, w.URL, msg)
+	if err != nil {
+		return "", fmt.Errorf("publish to subject %s: %w", msg.Key, err)
+	}
+	return ack, nil
+}
@@ -0,0 +1,317 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	webhook_model "code.gitea.io/gitea/models/webhook"
+	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/json"
+	"code.gitea.io/gitea/modules/log"
+	api "code.gitea.io/gitea/modules/structs"
+	"code.gitea.io/gitea/modules/svg"
+	"code.gitea.io/gitea/modules/util"
+	"code.gitea.io/gitea/modules/validation"
+	webhook_module "code.gitea.io/gitea/modules/webhook"
+	"code.gitea.io/gitea/services/forms"
+	"code.gitea.io/gitea/services/webhook/shared"
+)
+
+type ntfyHandler struct{}
+
+func (ntfyHandler) Type() webhook_module.HookType { return webhook_module.NTFY }
+func (ntfyHandler) Icon(size int) template.HTML {
+	return svg.RenderHTML("octicon-bell", size, "img")
+}
+
+func (ntfyHandler) UnmarshalForm(bind func(any)) forms.WebhookForm {
+	var form struct {
+		forms.WebhookCoreForm
+		ServerURL   string `binding:"Required;ValidUrl"`
+		Topic       string `binding:"Required;MaxSize(64)"`
+		Priority    int    `binding:"Range(1,5)"`
+		Tags        string
+		AccessToken string
+	}
+	bind(&form)
+	if token := strings.TrimSpace(form.AccessToken); token != "" {
+		form.AuthorizationHeader = "Bearer " + token
+	}
+
+	// https://docs.ntfy.sh/publish/#publish-as-json
+	return forms.WebhookForm{
+		WebhookCoreForm: form.WebhookCoreForm,
+		URL:             form.ServerURL,
+		ContentType:     webhook_model.ContentTypeJSON,
+		Secret:          "",
+		HTTPMethod:      http.MethodPost,
+		Metadata: &NtfyMeta{
+			Topic:    form.Topic,
+			Priority: form.Priority,
+			Tags:     form.Tags,
+		},
+	}
+}
+
+type (
+	// NtfyPayload represents a message published as JSON to a ntfy server
+	NtfyPayload struct {
+		Topic    string   `json:"topic"`
+		Title    string   `json:"title"`
+		Message  string   `json:"message"`
+		Priority int      `json:"priority,omitempty"`
+		Tags     []string `json:"tags,omitempty"`
+		Click    string   `json:"click,omitempty"`
+	}
+
+	// NtfyMeta contains the ntfy metadata
+	NtfyMeta struct {
+		Topic    string `json:"topic"`
+		Priority int    `json:"priority"`
+		Tags     string `json:"tags"`
+	}
+)
+
+// Metadata returns ntfy metadata
+func (ntfyHandler) Metadata(w *webhook_model.Webhook) any {
+	s := &NtfyMeta{}
+	if err := json.Unmarshal([]byte(w.Meta), s); err != nil {
+		log.Error("ntfyHandler.Metadata(%d): %v", w.ID, err)
+	}
+	return s
+}
+
+// UpdateNtfyMeta validates a ntfy webhook and updates its metadata with the config options of the API,
+// options which are not given are kept. The access token is given as authorization header.
+func UpdateNtfyMeta(w *webhook_model.Webhook, config map[string]string) error {
+	meta := &NtfyMeta{Priority: 3}
+	if w.Meta != "" {
+		if err := json.Unmarshal([]byte(w.Meta), meta); err != nil {
+			return fmt.Errorf("invalid webhook meta: %w", err)
+		}
+	}
+	if v, ok := config["topic"]; ok {
+		meta.Topic = v
+	}
+	if v, ok := config["priority"]; ok {
+		priority, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid priority %q", v)
+		}
+		meta.Priority = priority
+	}
+	if v, ok := config["tags"]; ok {
+		meta.Tags = v
+	}
+
+	switch {
+	case !validation.IsValidURL(w.URL):
+		return fmt.Errorf("invalid server URL")
+	case meta.Topic == "" || len(meta.Topic) > 64 || strings.Contains(meta.Topic, "/"):
+		return fmt.Errorf("invalid topic")
+	case meta.Priority < 1 || meta.Priority > 5:
+		return fmt.Errorf("the priority must be between 1 and 5")
+	}
+
+	b, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	w.ContentType = webhook_model.ContentTypeJSON
+	w.Meta = string(b)
+	return nil
+}
+
+// the tags of the messages are emoji short codes, the ntfy clients display them in front of the title
+const (
+	ntfyTagCreate     = "heavy_plus_sign"
+	ntfyTagDelete     = "wastebasket"
+	ntfyTagFork       = "fork_and_knife"
+	ntfyTagPush       = "arrow_up"
+	ntfyTagIssue      = "memo"
+	ntfyTagComment    = "speech_balloon"
+	ntfyTagPull       = "twisted_rightwards_arrows"
+	ntfyTagApproved   = "white_check_mark"
+	ntfyTagRejected   = "x"
+	ntfyTagRepository = "file_folder"
+	ntfyTagWiki       = "book"
+	ntfyTagRelease    = "rocket"
+	ntfyTagPackage    = "package"
+)
+
+// Create implements PayloadConvertor Create method
+func (n ntfyConvertor) Create(p *api.CreatePayload) (NtfyPayload, error) {
+	refName := git.RefName(p.Ref).ShortName()
+	title := fmt.Sprintf("[%s] %s %s created", p.Repo.FullName, p.RefType, refName)
+
+	return n.createPayload(title, "", p.Repo.HTMLURL+"/src/"+util.PathEscapeSegments(refName), ntfyTagCreate), nil
+}
+
+// Delete implements PayloadConvertor Delete method
+func (n ntfyConvertor) Delete(p *api.DeletePayload) (NtfyPayload, error) {
+	refName := git.RefName(p.Ref).ShortName()
+	title := fmt.Sprintf("[%s] %s %s deleted", p.Repo.FullName, p.RefType, refName)
+
+	return n.createPayload(title, "", p.Repo.HTMLURL, ntfyTagDelete), nil
+}
+
+// Fork implements PayloadConvertor Fork method
+func (n ntfyConvertor) Fork(p *api.ForkPayload) (NtfyPayload, error) {
+	title := fmt.Sprintf("%s is forked to %s", p.Forkee.FullName, p.Repo.FullName)
+
+	return n.createPayload(title, "", p.Repo.HTMLURL, ntfyTagFork), nil
+}
+
+// Push implements PayloadConvertor Push method
+func (n ntfyConvertor) Push(p *api.PushPayload) (NtfyPayload, error) {
+	title, titleLink := getPushTitle(p)
+
+	var text string
+	// for each commit, generate a line
+	for i, commit := range p.Commits {
+		text += fmt.Sprintf("%s %s - %s", commit.ID[:7], strings.TrimRight(commit.Message, "\r\n"), commit.Author.Name)
+		// add linebreak to each commit but the last
+		if i < len(p.Commits)-1 {
+			text += "\n"
+		}
+	}
+
+	return n.createPayload(title, text, titleLink, ntfyTagPush), nil
+}
+
+// Issue implements PayloadConvertor Issue method
+func (n ntfyConvertor) Issue(p *api.IssuePayload) (NtfyPayload, error) {
+	title, _, text, _ := getIssuesPayloadInfo(p, noneLinkFormatter, true)
+
+	return n.createPayload(title, text, p.Issue.HTMLURL, ntfyTagIssue), nil
+}
+
+// IssueComment implements PayloadConvertor IssueComment method
+func (n ntfyConvertor) IssueComment(p *api.IssueCommentPayload) (NtfyPayload, error) {
+	title, _, _ := getIssueCommentPayloadInfo(p, noneLinkFormatter, true)
+
+	return n.createPayload(title, p.Comment.Body, p.Comment.HTMLURL, ntfyTagComment), nil
+}
+
+// PullRequest implements PayloadConvertor PullRequest method
+func (n ntfyConvertor) PullRequest(p *api.PullRequestPayload) (NtfyPayload, error) {
+	title, _, text, _ := getPullRequestPayloadInfo(p, noneLinkFormatter, true)
+
+	return n.createPayload(title, text, p.PullRequest.HTMLURL, ntfyTagPull), nil
+}
+
+// Review implements PayloadConvertor Review method
+func (n ntfyConvertor) Review(p *api.PullRequestPayload, event webhook_module.HookEventType) (NtfyPayload, error) {
+	var title, text string
+	tag := ntfyTagComment
+	switch p.Action {
+	case api.HookIssueReviewed:
+		action, err := parseHookPullRequestEventType(event)
+		if err != nil {
+			return NtfyPayload{}, err
+		}
+
+		title = fmt.Sprintf("[%s] Pull request review %s: #%d %s", p.Repository.FullName, action, p.Index, p.PullRequest.Title)
+		text = p.Review.Content
+
+		switch event {
+		case webhook_module.HookEventPullRequestReviewApproved:
+			tag = ntfyTagApproved
+		case webhook_module.HookEventPullRequestReviewRejected:
+			tag = ntfyTagRejected
+		}
+	}
+
+	return n.createPayload(title, text, p.PullRequest.HTMLURL, tag), nil
+}
+
+// Repository implements PayloadConvertor Repository method
+func (n ntfyConvertor) Repository(p *api.RepositoryPayload) (NtfyPayload, error) {
+	var title, link string
+	switch p.Action {
+	case api.HookRepoCreated:
+		title = fmt.Sprintf("[%s] Repository created", p.Repository.FullName)
+		link = p.Repository.HTMLURL
+	case api.HookRepoDeleted:
+		title = fmt.Sprintf("[%s] Repository deleted", p.Repository.FullName)
+	}
+
+	return n.createPayload(title, "", link, ntfyTagRepository), nil
+}
+
+// Wiki implements PayloadConvertor Wiki method
+func (n ntfyConvertor) Wiki(p *api.WikiPayload) (NtfyPayload, error) {
+	title, _, _ := getWikiPayloadInfo(p, noneLinkFormatter, true)
+
+	return n.createPayload(title, "", p.Repository.HTMLURL+"/wiki/"+url.PathEscape(p.Page), ntfyTagWiki), nil
+}
+
+// Release implements PayloadConvertor Release method
+func (n ntfyConvertor) Release(p *api.ReleasePayload) (NtfyPayload, error) {
+	title, _ := getReleasePayloadInfo(p, noneLinkFormatter, true)
+
+	return n.createPayload(title, p.Release.Note, p.Release.HTMLURL, ntfyTagRelease), nil
+}
+
+// Package implements PayloadConvertor Package method
+func (n ntfyConvertor) Package(p *api.PackagePayload) (NtfyPayload, error) {
+	title, _ := getPackagePayloadInfo(p, noneLinkFormatter, true)
+
+	return n.createPayload(title, "", p.Package.HTMLURL, ntfyTagPackage), nil
+}
+
+// createPayload creates the message, ntfy requires a message so the title is used if there is no text
+func (n ntfyConvertor) createPayload(title, text, click, tag string) NtfyPayload {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		text = title
+	}
+	return NtfyPayload{
+		Topic:    n.Topic,
+		Title:    title,
+		Message:  text,
+		Priority: n.Priority,
+		Tags:     append([]string{tag}, n.Tags...),
+		Click:    click,
+	}
+}
+
+// ntfyTags splits the comma separated tags of the settings
+func ntfyTags(s string) []string {
+	var tags []string
+	for _, tag := range strings.Split(s, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+type ntfyConvertor struct {
+	Topic    string
+	Priority int
+	Tags     []string
+}
+
+var _ shared.PayloadConvertor[NtfyPayload] = ntfyConvertor{}
+
+func (ntfyHandler) NewRequest(ctx context.Context, w *webhook_model.Webhook, t *webhook_model.HookTask) (*http.Request, []byte, error) {
+	meta := &NtfyMeta{}
+	if err := json.Unmarshal([]byte(w.Meta), meta); err != nil {
+		return nil, nil, fmt.Errorf("ntfyHandler.NewRequest meta json: %w", err)
+	}
+	nc := ntfyConvertor{
+		Topic:    meta.Topic,
+		Priority: meta.Priority,
+		Tags:     ntfyTags(meta.Tags),
+	}
+	return shared.NewJSONRequest(nc, w, t, false)
+}
@@ -0,0 +1,152 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	webhook_model "code.gitea.io/gitea/models/webhook"
+	"code.gitea.io/gitea/modules/json"
+	api "code.gitea.io/gitea/modules/structs"
+	webhook_module "code.gitea.io/gitea/modules/webhook"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNtfyPayload(t *testing.T) {
+	nc := ntfyConvertor{
+		Topic:    "forgejo",
+		Priority: 4,
+		Tags:     []string{"ci"},
+	}
+
+	t.Run("Create", func(t *testing.T) {
+		p := createTestPayload()
+
+		pl, err := nc.Create(p)
+		require.NoError(t, err)
+
+		assert.Equal(t, "forgejo", pl.Topic)
+		assert.Equal(t, 4, pl.Priority)
+		assert.Equal(t, []string{ntfyTagCreate, "ci"}, pl.Tags)
+		assert.Equal(t, "[test/repo] branch test created", pl.Title)
+		assert.Equal(t, pl.Title, pl.Message)
+		assert.Equal(t, "http://localhost:3000/test/repo/src/test", pl.Click)
+	})
+
+	t.Run("Push", func(t *testing.T) {
+		p := pushTestPayload()
+
+		pl, err := nc.Push(p)
+		require.NoError(t, err)
+
+		assert.Equal(t, "[test/repo:test] 2 new commits", pl.Title)
+		assert.Equal(t, "2020558 commit message - user1\n2020558 commit message - user1", pl.Message)
+		assert.Equal(t, "http://localhost:3000/test/repo/src/test", pl.Click)
+		assert.Equal(t, []string{ntfyTagPush, "ci"}, pl.Tags)
+	})
+
+	t.Run("Issue", func(t *testing.T) {
+		p := issueTestPayload()
+		p.Action = api.HookIssueOpened
+
+		pl, err := nc.Issue(p)
+		require.NoError(t, err)
+
+		assert.Equal(t, "[test/repo] Issue opened: #2 crash by user1", pl.Title)
+		assert.Equal(t, "issue body", pl.Message)
+		assert.Equal(t, "http://localhost:3000/test/repo/issues/2", pl.Click)
+	})
+
+	t.Run("PullRequest", func(t *testing.T) {
+		p := pullRequestTestPayload()
+
+		pl, err := nc.PullRequest(p)
+		require.NoError(t, err)
+
+		assert.Equal(t, "[test/repo] Pull request opened: #12 Fix bug by user1", pl.Title)
+		assert.Equal(t, "fixes bug #2", pl.Message)
+		assert.Equal(t, "http://localhost:3000/test/repo/pulls/12", pl.Click)
+	})
+
+	t.Run("Review", func(t *testing.T) {
+		p := pullRequestTestPayload()
+		p.Action = api.HookIssueReviewed
+
+		pl, err := nc.Review(p, webhook_module.HookEventPullRequestReviewRejected)
+		require.NoError(t, err)
+
+		assert.Equal(t, "[test/repo] Pull request review rejected: #12 Fix bug", pl.Title)
+		assert.Equal(t, []string{ntfyTagRejected, "ci"}, pl.Tags)
+	})
+
+	t.Run("Release", func(t *testing.T) {
+		p := pullReleaseTestPayload()
+
+		pl, err := nc.Release(p)
+		require.NoError(t, err)
+
+		assert.Equal(t, "[test/repo] Release created: v1.0 by user1", pl.Title)
+		assert.Equal(t, "Note of first stable release", pl.Message)
+		assert.Equal(t, "http://localhost:3000/test/repo/releases/tag/v1.0", pl.Click)
+		assert.Equal(t, []string{ntfyTagRelease, "ci"}, pl.Tags)
+	})
+}
+
+func TestNtfyJSONPayload(t *testing.T) {
+	p := pushTestPayload()
+	data, err := p.JSONPayload()
+	require.NoError(t, err)
+
+	hook := &webhook_model.Webhook{
+		RepoID:     3,
+		IsActive:   true,
+		Type:       webhook_module.NTFY,
+		URL:        "https://ntfy.example.com",
+		Meta:       `{"topic":"forgejo","priority":5,"tags":" ci, ,deploy "}`,
+		HTTPMethod: "POST",
+	}
+	task := &webhook_model.HookTask{
+		HookID:         hook.ID,
+		EventType:      webhook_module.HookEventPush,
+		PayloadContent: string(data),
+		PayloadVersion: 2,
+	}
+
+	req, reqBody, err := ntfyHandler{}.NewRequest(context.Background(), hook, task)
+	require.NotNil(t, req)
+	require.NotNil(t, reqBody)
+	require.NoError(t, err)
+
+	assert.Equal(t, "POST", req.Method)
+	assert.Equal(t, "https://ntfy.example.com", req.URL.String())
+	assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+	var body NtfyPayload
+	err = json.NewDecoder(req.Body).Decode(&body)
+	assert.NoError(t, err)
+	assert.Equal(t, "forgejo", body.Topic)
+	assert.Equal(t, 5, body.Priority)
+	assert.Equal(t, []string{ntfyTagPush, "ci", "deploy"}, body.Tags)
+	assert.Equal(t, "[test/repo:test] 2 new commits", body.Title)
+}
+
+func TestUpdateNtfyMeta(t *testing.T) {
+	w := &webhook_model.Webhook{Type: webhook_module.NTFY, URL: "https://ntfy.example.com", ContentType: webhook_model.ContentTypeForm}
+	assert.Error(t, UpdateNtfyMeta(w, nil), "the topic is required")
+	require.NoError(t, UpdateNtfyMeta(w, map[string]string{"topic": "forgejo", "tags": "git"}))
+	assert.Equal(t, webhook_model.ContentTypeJSON, w.ContentType)
+	assert.Equal(t, &NtfyMeta{Topic: "forgejo", Priority: 3, Tags: "git"}, ntfyHandler{}.Metadata(w))
+
+	// options which are not given are kept
+	require.NoError(t, UpdateNtfyMeta(w, map[string]string{"priority": "5"}))
+	assert.Equal(t, &NtfyMeta{Topic: "forgejo", Priority: 5, Tags: "git"}, ntfyHandler{}.Metadata(w))
+
+	assert.Error(t, UpdateNtfyMeta(w, map[string]string{"priority": "6"}))
+	assert.Error(t, UpdateNtfyMeta(w, map[string]string{"priority": "high"}))
+	assert.Error(t, UpdateNtfyMeta(w, map[string]string{"topic": "forgejo/events"}))
+	w.URL = "ntfy.example.com"
+	assert.Error(t, UpdateNtfyMeta(w, nil))
+}
@@ -140,21 +140,22 @@ func AddDefaultHeaders(req *http.Request, secret []byte, t *webhook_model.HookTa
 
 	event := t.EventType.Event()
 	eventType := string(t.EventType)
-	req.Header.Add("X-Forgejo-Delivery", t.UUID)
+	delivery := t.GetDeliveryUUID()
+	req.Header.Add("X-Forgejo-Delivery", delivery)
 	req.Header.Add("X-Forgejo-Event", event)
 	req.Header.Add("X-Forgejo-Event-Type", eventType)
 	req.Header.Add("X-Forgejo-Signature", signatureSHA256)
-	req.Header.Add("X-Gitea-Delivery", t.UUID)
+	req.Header.Add("X-Gitea-Delivery", delivery)
 	req.Header.Add("X-Gitea-Event", event)
 	req.Header.Add("X-Gitea-Event-Type", eventType)
 	req.Header.Add("X-Gitea-Signature", signatureSHA256)
-	req.Header.Add("X-Gogs-Delivery", t.UUID)
+	req.Header.Add("X-Gogs-Delivery", delivery)
 	req.Header.Add("X-Gogs-Event", event)
 	req.Header.Add("X-Gogs-Event-Type", eventType)
 	req.Header.Add("X-Gogs-Signature", signatureSHA256)
 	req.Header.Add("X-Hub-Signature", "sha1="+signatureSHA1)
 	req.Header.Add("X-Hub-Signature-256", "sha256="+signatureSHA256)
-	req.Header["X-GitHub-Delivery"] = []string{t.UUID}
+	req.Header["X-GitHub-Delivery"] = []string{delivery}
 	req.Header["X-GitHub-Event"] = []string{event}
 	req.Header["X-GitHub-Event-Type"] = []string{eventType}
 	return nil
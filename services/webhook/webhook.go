@@ -10,6 +10,7 @@ import (
 	"html/template"
 	"net/http"
 	"strings"
+	"time"
 
 	"code.gitea.io/gitea/models/db"
 	repo_model "code.gitea.io/gitea/models/repo"
@@ -54,7 +55,15 @@ var webhookHandlers = []Handler{
 	matrixHandler{},
 	wechatworkHandler{},
 	packagistHandler{},
+	ntfyHandler{},
+	gotifyHandler{},
+	zulipHandler{},
 	sourcehut.BuildsHandler{},
+
+	amqpHandler{},
+	natsHandler{},
+	kafkaHandler{},
+	customHandler{},
 }
 
 // GetWebhookHandler return the handler for a given webhook type (nil if not found)
@@ -124,6 +133,12 @@ func handler(items ...int64) []int64 {
 			continue
 		}
 
+		if delay := time.Until(task.Delivered.AsTime()); delay > 0 {
+			// A retry which is not due yet, e.g. after a restart
+			enqueueHookTaskAfter(task.ID, delay)
+			continue
+		}
+
 		if err := Deliver(ctx, task); err != nil {
 			log.Error("Unable to deliver webhook task[%d]: %v", task.ID, err)
 		}
@@ -140,6 +155,18 @@ func enqueueHookTask(taskID int64) error {
 	return nil
 }
 
+// enqueueHookTaskAfter pushes the hook task to the queue once the delay has passed
+func enqueueHookTaskAfter(taskID int64, delay time.Duration) {
+	time.AfterFunc(delay, func() {
+		if hookQueue == nil {
+			return
+		}
+		if err := enqueueHookTask(taskID); err != nil {
+			log.Error("Unable to push HookTask[%d] to the Webhook Sending queue: %v", taskID, err)
+		}
+	})
+}
+
 func checkBranch(w *webhook_model.Webhook, branch string) bool {
 	if w.BranchFilter == "" || w.BranchFilter == "*" {
 		return true
@@ -0,0 +1,329 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package webhook
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strings"
+
+	webhook_model "code.gitea.io/gitea/models/webhook"
+	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/json"
+	"code.gitea.io/gitea/modules/log"
+	api "code.gitea.io/gitea/modules/structs"
+	"code.gitea.io/gitea/modules/svg"
+	"code.gitea.io/gitea/modules/util"
+	"code.gitea.io/gitea/modules/validation"
+	webhook_module "code.gitea.io/gitea/modules/webhook"
+	"code.gitea.io/gitea/services/forms"
+	"code.gitea.io/gitea/services/webhook/shared"
+)
+
+// zulipTopicMaxLength is the maximum length of a topic name in characters
+const zulipTopicMaxLength = 60
+
+type zulipHandler struct{}
+
+func (zulipHandler) Type() webhook_module.HookType { return webhook_module.ZULIP }
+func (zulipHandler) Icon(size int) template.HTML {
+	return svg.RenderHTML("octicon-comment-discussion", size, "img")
+}
+
+func (zulipHandler) UnmarshalForm(bind func(any)) forms.WebhookForm {
+	var form struct {
+		forms.WebhookCoreForm
+		SiteURL  string `binding:"Required;ValidUrl"`
+		BotEmail string `binding:"Required;Email"`
+		APIKey   string `binding:"Required"`
+		Stream   string `binding:"Required;MaxSize(60)"`
+		Topic    string `binding:"MaxSize(60)"`
+	}
+	bind(&form)
+	form.AuthorizationHeader = "Basic " + base64.StdEncoding.EncodeToString([]byte(form.BotEmail+":"+strings.TrimSpace(form.APIKey)))
+
+	// https://zulip.com/api/send-message
+	return forms.WebhookForm{
+		WebhookCoreForm: form.WebhookCoreForm,
+		URL:             strings.TrimSuffix(form.SiteURL, "/") + "/api/v1/messages",
+		ContentType:     webhook_model.ContentTypeForm,
+		Secret:          "",
+		HTTPMethod:      http.MethodPost,
+		Metadata: &ZulipMeta{
+			SiteURL:  form.SiteURL,
+			BotEmail: form.BotEmail,
+			Stream:   form.Stream,
+			Topic:    form.Topic,
+		},
+	}
+}
+
+type (
+	// ZulipPayload represents a message sent to a stream, it is sent form encoded
+	ZulipPayload struct {
+		Type    string `json:"type"`
+		To      string `json:"to"`
+		Topic   string `json:"topic"`
+		Content string `json:"content"`
+	}
+
+	// ZulipMeta contains the zulip metadata
+	ZulipMeta struct {
+		SiteURL  string `json:"site_url"`
+		BotEmail string `json:"bot_email"`
+		Stream   string `json:"stream"`
+		// Topic is the topic of all the messages, the messages about a repository
+		// are sent to a topic named after the repository if it is empty
+		Topic string `json:"topic"`
+		// APIKey is read from the authorization header to fill the settings form
+		APIKey string `json:"-"`
+	}
+)
+
+// Metadata returns zulip metadata
+func (zulipHandler) Metadata(w *webhook_model.Webhook) any {
+	s := &ZulipMeta{}
+	if err := json.Unmarshal([]byte(w.Meta), s); err != nil {
+		log.Error("zulipHandler.Metadata(%d): %v", w.ID, err)
+	}
+	authorization, err := w.HeaderAuthorizationTrimPrefix("Basic ")
+	if err != nil {
+		log.Error("zulipHandler.Metadata(%d): %v", w.ID, err)
+	} else if credentials, err := base64.StdEncoding.DecodeString(authorization); err == nil {
+		_, s.APIKey, _ = strings.Cut(string(credentials), ":")
+	}
+	return s
+}
+
+// UpdateZulipMeta validates a zulip webhook and updates its metadata with the config options of the API,
+// options which are not given are kept. The URL is the message endpoint of the site, e.g. https://zulip.example.com/api/v1/messages,
+// and the API key of the bot is given as basic authorization header.
+func UpdateZulipMeta(w *webhook_model.Webhook, config map[string]string) error {
+	meta := &ZulipMeta{}
+	if w.Meta != "" {
+		if err := json.Unmarshal([]byte(w.Meta), meta); err != nil {
+			return fmt.Errorf("invalid webhook meta: %w", err)
+		}
+	}
+	if v, ok := config["bot_email"]; ok {
+		meta.BotEmail = v
+	}
+	if v, ok := config["stream"]; ok {
+		meta.Stream = v
+	}
+	if v, ok := config["topic"]; ok {
+		meta.Topic = v
+	}
+
+	siteURL, ok := strings.CutSuffix(w.URL, "/api/v1/messages")
+	switch {
+	case !ok || !validation.IsValidURL(siteURL):
+		return fmt.Errorf("the URL must be the /api/v1/messages endpoint of a zulip site")
+	case !isValidZulipBotEmail(meta.BotEmail):
+		return fmt.Errorf("invalid bot email")
+	case meta.Stream == "" || len(meta.Stream) > 60:
+		return fmt.Errorf("invalid stream")
+	case len(meta.Topic) > 60:
+		return fmt.Errorf("invalid topic")
+	}
+	meta.SiteURL = siteURL
+
+	b, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	w.ContentType = webhook_model.ContentTypeForm
+	w.Meta = string(b)
+	return nil
+}
+
+func isValidZulipBotEmail(email string) bool {
+	addr, err := mail.ParseAddress(email)
+	return err == nil && addr.Address == email
+}
+
+// Create implements PayloadConvertor Create method
+func (z zulipConvertor) Create(p *api.CreatePayload) (ZulipPayload, error) {
+	refName := git.RefName(p.Ref).ShortName()
+	text := fmt.Sprintf("[%s] %s %s created", markdownLinkFormatter(p.Repo.HTMLURL, p.Repo.FullName), p.RefType,
+		markdownLinkFormatter(p.Repo.HTMLURL+"/src/"+util.PathEscapeSegments(refName), refName))
+
+	return z.createPayload(p.Repo, p.Sender, text, ""), nil
+}
+
+// Delete implements PayloadConvertor Delete method
+func (z zulipConvertor) Delete(p *api.DeletePayload) (ZulipPayload, error) {
+	refName := git.RefName(p.Ref).ShortName()
+	text := fmt.Sprintf("[%s] %s %s deleted", markdownLinkFormatter(p.Repo.HTMLURL, p.Repo.FullName), p.RefType, refName)
+
+	return z.createPayload(p.Repo, p.Sender, text, ""), nil
+}
+
+// Fork implements PayloadConvertor Fork method
+func (z zulipConvertor) Fork(p *api.ForkPayload) (ZulipPayload, error) {
+	text := fmt.Sprintf("%s is forked to %s", p.Forkee.FullName, markdownLinkFormatter(p.Repo.HTMLURL, p.Repo.FullName))
+
+	return z.createPayload(p.Forkee, p.Sender, text, ""), nil
+}
+
+// Push implements PayloadConvertor Push method
+func (z zulipConvertor) Push(p *api.PushPayload) (ZulipPayload, error) {
+	title, titleLink := getPushTitle(p)
+
+	var commits string
+	// for each commit, generate a list item
+	for i, commit := range p.Commits {
+		commits += fmt.Sprintf("* %s %s - %s", markdownLinkFormatter(commit.URL, commit.ID[:7]),
+			strings.TrimRight(commit.Message, "\r\n"), commit.Author.Name)
+		// add linebreak to each commit but the last
+		if i < len(p.Commits)-1 {
+			commits += "\n"
+		}
+	}
+
+	return z.createPayload(p.Repo, p.Sender, markdownLinkFormatter(titleLink, title), commits), nil
+}
+
+// Issue implements PayloadConvertor Issue method
+func (z zulipConvertor) Issue(p *api.IssuePayload) (ZulipPayload, error) {
+	text, _, attachmentText, _ := getIssuesPayloadInfo(p, markdownLinkFormatter, false)
+
+	return z.createPayload(p.Repository, p.Sender, text, attachmentText), nil
+}
+
+// IssueComment implements PayloadConvertor IssueComment method
+func (z zulipConvertor) IssueComment(p *api.IssueCommentPayload) (ZulipPayload, error) {
+	text, _, _ := getIssueCommentPayloadInfo(p, markdownLinkFormatter, false)
+
+	return z.createPayload(p.Repository, p.Sender, text, p.Comment.Body), nil
+}
+
+// PullRequest implements PayloadConvertor PullRequest method
+func (z zulipConvertor) PullRequest(p *api.PullRequestPayload) (ZulipPayload, error) {
+	text, _, attachmentText, _ := getPullRequestPayloadInfo(p, markdownLinkFormatter, false)
+
+	return z.createPayload(p.Repository, p.Sender, text, attachmentText), nil
+}
+
+// Review implements PayloadConvertor Review method
+func (z zulipConvertor) Review(p *api.PullRequestPayload, event webhook_module.HookEventType) (ZulipPayload, error) {
+	var text, quote string
+	switch p.Action {
+	case api.HookIssueReviewed:
+		action, err := parseHookPullRequestEventType(event)
+		if err != nil {
+			return ZulipPayload{}, err
+		}
+
+		text = fmt.Sprintf("[%s] Pull request review %s: %s", markdownLinkFormatter(p.Repository.HTMLURL, p.Repository.FullName), action,
+			markdownLinkFormatter(p.PullRequest.HTMLURL, fmt.Sprintf("#%d %s", p.Index, p.PullRequest.Title)))
+		quote = p.Review.Content
+	}
+
+	return z.createPayload(p.Repository, p.Sender, text, quote), nil
+}
+
+// Repository implements PayloadConvertor Repository method
+func (z zulipConvertor) Repository(p *api.RepositoryPayload) (ZulipPayload, error) {
+	var text string
+	switch p.Action {
+	case api.HookRepoCreated:
+		text = fmt.Sprintf("[%s] Repository created", markdownLinkFormatter(p.Repository.HTMLURL, p.Repository.FullName))
+	case api.HookRepoDeleted:
+		text = fmt.Sprintf("[%s] Repository deleted", p.Repository.FullName)
+	}
+
+	return z.createPayload(p.Repository, p.Sender, text, ""), nil
+}
+
+// Wiki implements PayloadConvertor Wiki method
+func (z zulipConvertor) Wiki(p *api.WikiPayload) (ZulipPayload, error) {
+	text, _, _ := getWikiPayloadInfo(p, markdownLinkFormatter, false)
+
+	return z.createPayload(p.Repository, p.Sender, text, ""), nil
+}
+
+// Release implements PayloadConvertor Release method
+func (z zulipConvertor) Release(p *api.ReleasePayload) (ZulipPayload, error) {
+	text, _ := getReleasePayloadInfo(p, markdownLinkFormatter, false)
+
+	return z.createPayload(p.Repository, p.Sender, text, p.Release.Note), nil
+}
+
+// Package implements PayloadConvertor Package method
+func (z zulipConvertor) Package(p *api.PackagePayload) (ZulipPayload, error) {
+	text, _ := getPackagePayloadInfo(p, markdownLinkFormatter, false)
+
+	return z.createPayload(p.Repository, p.Sender, text, ""), nil
+}
+
+// createPayload creates a message to the topic of the repository, the quote is
+// rendered in a quote block below the text
+func (z zulipConvertor) createPayload(repo *api.Repository, sender *api.User, text, quote string) ZulipPayload {
+	topic := z.Topic
+	if topic == "" && repo != nil {
+		topic = repo.FullName
+	}
+	if topic == "" {
+		topic = "forgejo"
+	}
+	if r := []rune(topic); len(r) > zulipTopicMaxLength {
+		topic = string(r[:zulipTopicMaxLength-1]) + "…"
+	}
+
+	if sender != nil {
+		text += fmt.Sprintf(" by %s", sender.UserName)
+	}
+	if quote = strings.TrimSpace(quote); quote != "" {
+		text += "\n```quote\n" + quote + "\n```"
+	}
+
+	return ZulipPayload{
+		Type:    "stream",
+		To:      z.Stream,
+		Topic:   topic,
+		Content: text,
+	}
+}
+
+type zulipConvertor struct {
+	Stream string
+	Topic  string
+}
+
+var _ shared.PayloadConvertor[ZulipPayload] = zulipConvertor{}
+
+func (zulipHandler) NewRequest(ctx context.Context, w *webhook_model.Webhook, t *webhook_model.HookTask) (*http.Request, []byte, error) {
+	meta := &ZulipMeta{}
+	if err := json.Unmarshal([]byte(w.Meta), meta); err != nil {
+		return nil, nil, fmt.Errorf("zulipHandler.NewRequest meta json: %w", err)
+	}
+	zc := zulipConvertor{
+		Stream: meta.Stream,
+		Topic:  meta.Topic,
+	}
+	payload, err := shared.NewPayload(zc, []byte(t.PayloadContent), t.EventType)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	body := []byte(url.Values{
+		"type":    {payload.Type},
+		"to":      {payload.To},
+		"topic":   {payload.Topic},
+		"content": {payload.Content},
+	}.Encode())
+	req, err := http.NewRequest(http.MethodPost, w.URL, strings.NewReader(string(body)))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return req, body, nil
+}
@@ -0,0 +1,247 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package integration
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/models/unittest"
+	user_model "code.gitea.io/gitea/models/user"
+	arch_module "code.gitea.io/gitea/modules/packages/arch"
+	"code.gitea.io/gitea/tests"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPackageArch(t *testing.T) {
+	defer tests.PrepareTestEnv(t)()
+
+	user := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2})
+
+	packageName := "gitea-test"
+	distribution := "forgejo"
+
+	createPackage := func(version, architecture string) []byte {
+		pkginfo := []byte(`pkgname = ` + packageName + `
+pkgver = ` + version + `
+pkgdesc = Gitea Test Package
+url = https://gitea.io/
+builddate = 1678834800
+packager = Gitea <pack@ag.er>
+size = 1024
+arch = ` + architecture + `
+license = MIT
+depend = glibc`)
+
+		var buf bytes.Buffer
+		zw, _ := zstd.NewWriter(&buf)
+		tw := tar.NewWriter(zw)
+		for _, file := range []struct {
+			Name    string
+			Content []byte
+		}{
+			{".PKGINFO", pkginfo},
+			{"usr/bin/gitea-test", []byte("binary")},
+		} {
+			tw.WriteHeader(&tar.Header{
+				Name: file.Name,
+				Mode: 0o755,
+				Size: int64(len(file.Content)),
+			})
+			tw.Write(file.Content)
+		}
+		tw.Close()
+		zw.Close()
+		return buf.Bytes()
+	}
+
+	readDatabase := func(t *testing.T, content []byte) map[string]string {
+		zr, err := gzip.NewReader(bytes.NewReader(content))
+		assert.NoError(t, err)
+
+		entries := make(map[string]string)
+		tr := tar.NewReader(zr)
+		for {
+			hd, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			assert.NoError(t, err)
+			if hd.Typeflag != tar.TypeReg {
+				continue
+			}
+			data, err := io.ReadAll(tr)
+			assert.NoError(t, err)
+			entries[hd.Name] = string(data)
+		}
+		return entries
+	}
+
+	rootURL := fmt.Sprintf("/api/packages/%s/arch", user.Name)
+	distributionURL := rootURL + "/" + distribution
+
+	var keyring openpgp.EntityList
+
+	t.Run("RepositoryKey", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequest(t, "GET", rootURL+"/repository.key")
+		resp := MakeRequest(t, req, http.StatusOK)
+
+		assert.Equal(t, "application/pgp-keys", resp.Header().Get("Content-Type"))
+		assert.Contains(t, resp.Body.String(), "-----BEGIN PGP PUBLIC KEY BLOCK-----")
+
+		var err error
+		keyring, err = openpgp.ReadArmoredKeyRing(resp.Body)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Upload", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		content := createPackage("1.0.0-1", "x86_64")
+
+		req := NewRequestWithBody(t, "PUT", distributionURL, bytes.NewReader(content))
+		MakeRequest(t, req, http.StatusUnauthorized)
+
+		req = NewRequestWithBody(t, "PUT", distributionURL, bytes.NewReader([]byte("invalid"))).
+			AddBasicAuth(user.Name)
+		MakeRequest(t, req, http.StatusBadRequest)
+
+		req = NewRequestWithBody(t, "PUT", distributionURL, bytes.NewReader(content)).
+			AddBasicAuth(user.Name)
+		MakeRequest(t, req, http.StatusCreated)
+
+		pvs, err := packages.GetVersionsByPackageType(db.DefaultContext, user.ID, packages.TypeArch)
+		assert.NoError(t, err)
+		assert.Len(t, pvs, 1)
+
+		pd, err := packages.GetPackageDescriptor(db.DefaultContext, pvs[0])
+		assert.NoError(t, err)
+		assert.Nil(t, pd.SemVer)
+		assert.IsType(t, &arch_module.VersionMetadata{}, pd.Metadata)
+		assert.Equal(t, packageName, pd.Package.Name)
+		assert.Equal(t, "1.0.0-1", pd.Version.Version)
+
+		pfs, err := packages.GetFilesByVersionID(db.DefaultContext, pvs[0].ID)
+		assert.NoError(t, err)
+		assert.Len(t, pfs, 1)
+		assert.Equal(t, packageName+"-1.0.0-1-x86_64.pkg.tar.zst", pfs[0].Name)
+		assert.Equal(t, distribution, pfs[0].CompositeKey)
+		assert.True(t, pfs[0].IsLead)
+
+		req = NewRequestWithBody(t, "PUT", distributionURL, bytes.NewReader(content)).
+			AddBasicAuth(user.Name)
+		MakeRequest(t, req, http.StatusConflict)
+
+		req = NewRequestWithBody(t, "PUT", distributionURL, bytes.NewReader(createPackage("1.1.0-1", arch_module.AnyArchitecture))).
+			AddBasicAuth(user.Name)
+		MakeRequest(t, req, http.StatusCreated)
+	})
+
+	t.Run("Download", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		content := createPackage("1.0.0-1", "x86_64")
+
+		req := NewRequest(t, "GET", distributionURL+"/x86_64/"+packageName+"-1.0.0-1-x86_64.pkg.tar.zst")
+		resp := MakeRequest(t, req, http.StatusOK)
+		assert.Equal(t, content, resp.Body.Bytes())
+
+		req = NewRequest(t, "GET", distributionURL+"/x86_64/"+packageName+"-1.0.0-1-x86_64.pkg.tar.zst.sig")
+		resp = MakeRequest(t, req, http.StatusOK)
+
+		_, err := openpgp.CheckDetachedSignature(keyring, bytes.NewReader(content), resp.Body, nil)
+		assert.NoError(t, err)
+
+		req = NewRequest(t, "GET", distributionURL+"/x86_64/"+packageName+"-2.0.0-1-x86_64.pkg.tar.zst")
+		MakeRequest(t, req, http.StatusNotFound)
+	})
+
+	t.Run("Database", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequest(t, "GET", distributionURL+"/x86_64/"+distribution+".db")
+		resp := MakeRequest(t, req, http.StatusOK)
+		content := resp.Body.Bytes()
+
+		req = NewRequest(t, "GET", distributionURL+"/x86_64/"+distribution+".db.sig")
+		resp = MakeRequest(t, req, http.StatusOK)
+
+		_, err := openpgp.CheckDetachedSignature(keyring, bytes.NewReader(content), resp.Body, nil)
+		assert.NoError(t, err)
+
+		// the "any" package is newer, so only this version is listed
+		entries := readDatabase(t, content)
+		assert.Len(t, entries, 1)
+		desc, ok := entries[packageName+"-1.1.0-1/desc"]
+		assert.True(t, ok)
+		assert.Contains(t, desc, "%FILENAME%\n"+packageName+"-1.1.0-1-any.pkg.tar.zst\n")
+		assert.Contains(t, desc, "%NAME%\n"+packageName+"\n")
+		assert.Contains(t, desc, "%ARCH%\nany\n")
+		assert.Contains(t, desc, "%DEPENDS%\nglibc\n")
+		assert.Contains(t, desc, "%PGPSIG%\n")
+
+		req = NewRequest(t, "GET", distributionURL+"/x86_64/"+distribution+".files.tar.gz")
+		resp = MakeRequest(t, req, http.StatusOK)
+
+		entries = readDatabase(t, resp.Body.Bytes())
+		assert.Len(t, entries, 2)
+		assert.Equal(t, "%FILES%\nusr/bin/gitea-test\n\n", entries[packageName+"-1.1.0-1/files"])
+
+		// architectures without packages fall back to the "any" database
+		req = NewRequest(t, "GET", distributionURL+"/aarch64/"+distribution+".db")
+		MakeRequest(t, req, http.StatusOK)
+
+		req = NewRequest(t, "GET", rootURL+"/unknown/x86_64/unknown.db")
+		MakeRequest(t, req, http.StatusNotFound)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequest(t, "DELETE", distributionURL+"/"+packageName+"/1.1.0-1/any")
+		MakeRequest(t, req, http.StatusUnauthorized)
+
+		req = NewRequest(t, "DELETE", distributionURL+"/"+packageName+"/1.1.0-1/any").
+			AddBasicAuth(user.Name)
+		MakeRequest(t, req, http.StatusNoContent)
+
+		req = NewRequest(t, "GET", distributionURL+"/aarch64/"+distribution+".db")
+		MakeRequest(t, req, http.StatusNotFound)
+
+		req = NewRequest(t, "GET", distributionURL+"/x86_64/"+distribution+".db")
+		resp := MakeRequest(t, req, http.StatusOK)
+
+		entries := readDatabase(t, resp.Body.Bytes())
+		assert.Len(t, entries, 1)
+		assert.True(t, strings.HasPrefix(entries[packageName+"-1.0.0-1/desc"], "%FILENAME%\n"+packageName+"-1.0.0-1-x86_64.pkg.tar.zst\n"))
+
+		req = NewRequest(t, "DELETE", distributionURL+"/"+packageName+"/1.0.0-1/x86_64").
+			AddBasicAuth(user.Name)
+		MakeRequest(t, req, http.StatusNoContent)
+
+		req = NewRequest(t, "DELETE", distributionURL+"/"+packageName+"/1.0.0-1/x86_64").
+			AddBasicAuth(user.Name)
+		MakeRequest(t, req, http.StatusNotFound)
+
+		pvs, err := packages.GetVersionsByPackageType(db.DefaultContext, user.ID, packages.TypeArch)
+		assert.NoError(t, err)
+		assert.Empty(t, pvs)
+
+		req = NewRequest(t, "GET", distributionURL+"/x86_64/"+distribution+".db")
+		MakeRequest(t, req, http.StatusNotFound)
+	})
+}
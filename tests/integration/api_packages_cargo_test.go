@@ -12,6 +12,7 @@ import (
 	neturl "net/url"
 	"testing"
 
+	auth_model "code.gitea.io/gitea/models/auth"
 	"code.gitea.io/gitea/models/db"
 	"code.gitea.io/gitea/models/packages"
 	repo_model "code.gitea.io/gitea/models/repo"
@@ -21,6 +22,7 @@ import (
 	"code.gitea.io/gitea/modules/json"
 	cargo_module "code.gitea.io/gitea/modules/packages/cargo"
 	"code.gitea.io/gitea/modules/setting"
+	api "code.gitea.io/gitea/modules/structs"
 	cargo_router "code.gitea.io/gitea/routers/api/packages/cargo"
 	gitea_context "code.gitea.io/gitea/services/context"
 	cargo_service "code.gitea.io/gitea/services/packages/cargo"
@@ -370,6 +372,35 @@ func testPackageCargo(t *testing.T, _ *neturl.URL) {
 		assert.False(t, entry.Yanked)
 	})
 
+	t.Run("Deprecate", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		token := getUserToken(t, user.Name, auth_model.AccessTokenScopeWritePackage)
+		deprecationURL := fmt.Sprintf("/api/v1/packages/%s/cargo/%s/%s/deprecation", user.Name, packageName, packageVersion)
+
+		isYanked := func(t *testing.T) bool {
+			t.Helper()
+
+			content := readGitContent(t, cargo_service.BuildPackagePath(packageName))
+
+			var entry cargo_service.IndexVersionEntry
+			assert.NoError(t, json.Unmarshal([]byte(content), &entry))
+			return entry.Yanked
+		}
+
+		req := NewRequestWithJSON(t, "PUT", deprecationURL, &api.DeprecatePackageOption{Message: "security issue"}).
+			AddTokenAuth(token)
+		MakeRequest(t, req, http.StatusNoContent)
+
+		assert.True(t, isYanked(t))
+
+		req = NewRequest(t, "DELETE", deprecationURL).
+			AddTokenAuth(token)
+		MakeRequest(t, req, http.StatusNoContent)
+
+		assert.False(t, isYanked(t))
+	})
+
 	t.Run("ListOwners", func(t *testing.T) {
 		defer tests.PrintCurrentTest(t)()
 
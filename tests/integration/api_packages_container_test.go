@@ -9,6 +9,7 @@ import (
 	"encoding/base64"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"sync"
 	"testing"
@@ -73,6 +74,17 @@ func TestPackageContainer(t *testing.T) {
 	untaggedManifestDigest := "sha256:4305f5f5572b9a426b88909b036e52ee3cf3d7b9c1b01fac840e90747f56623d"
 	untaggedManifestContent := `{"schemaVersion":2,"mediaType":"` + oci.MediaTypeImageManifest + `","config":{"mediaType":"application/vnd.docker.container.image.v1+json","digest":"sha256:4607e093bec406eaadb6f3a340f63400c9d3a7038680744c406903766b938f0d","size":1069},"layers":[{"mediaType":"application/vnd.docker.image.rootfs.diff.tar.gzip","digest":"sha256:a3ed95caeb02ffe68cdd9fd84406680ae93d633cb16422d00e8a7c22955b46d4","size":32}]}`
 
+	emptyConfigDigest := "sha256:44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a"
+	emptyConfigContent := `{}`
+
+	sbomArtifactType := "application/vnd.example.sbom.v1+json"
+	sbomManifestContent := `{"schemaVersion":2,"mediaType":"` + oci.MediaTypeImageManifest + `","artifactType":"` + sbomArtifactType + `","config":{"mediaType":"` + oci.MediaTypeEmptyJSON + `","digest":"` + emptyConfigDigest + `","size":2},"layers":[{"mediaType":"application/vnd.example.sbom.v1+json","digest":"sha256:a3ed95caeb02ffe68cdd9fd84406680ae93d633cb16422d00e8a7c22955b46d4","size":32}],"subject":{"mediaType":"application/vnd.docker.distribution.manifest.v2+json","digest":"` + manifestDigest + `","size":` + fmt.Sprint(len(manifestContent)) + `},"annotations":{"org.example.sbom.format":"spdx"}}`
+	sbomManifestDigest := fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(sbomManifestContent)))
+
+	signatureArtifactType := "application/vnd.example.signature.config.v1+json"
+	signatureManifestContent := `{"schemaVersion":2,"mediaType":"` + oci.MediaTypeImageManifest + `","config":{"mediaType":"` + signatureArtifactType + `","digest":"` + emptyConfigDigest + `","size":2},"layers":[{"mediaType":"application/vnd.example.signature.v1","digest":"sha256:a3ed95caeb02ffe68cdd9fd84406680ae93d633cb16422d00e8a7c22955b46d4","size":32}],"subject":{"mediaType":"application/vnd.docker.distribution.manifest.v2+json","digest":"` + manifestDigest + `","size":` + fmt.Sprint(len(manifestContent)) + `}}`
+	signatureManifestDigest := fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(signatureManifestContent)))
+
 	indexManifestDigest := "sha256:bab112d6efb9e7f221995caaaa880352feb5bd8b1faf52fae8d12c113aa123ec"
 	indexManifestContent := `{"schemaVersion":2,"mediaType":"` + oci.MediaTypeImageIndex + `","manifests":[{"mediaType":"application/vnd.docker.distribution.manifest.v2+json","digest":"` + manifestDigest + `","platform":{"os":"linux","architecture":"arm","variant":"v7"}},{"mediaType":"` + oci.MediaTypeImageManifest + `","digest":"` + untaggedManifestDigest + `","platform":{"os":"linux","architecture":"arm64","variant":"v8"}}]}`
 
@@ -600,6 +612,104 @@ func TestPackageContainer(t *testing.T) {
 				assert.Len(t, apiPackages, 4) // "latest", "main", "multi", "sha256:..."
 			})
 
+			t.Run("Referrers", func(t *testing.T) {
+				defer tests.PrintCurrentTest(t)()
+
+				req := NewRequestWithBody(t, "POST", fmt.Sprintf("%s/blobs/uploads?digest=%s", url, emptyConfigDigest), strings.NewReader(emptyConfigContent)).
+					AddTokenAuth(userToken)
+				MakeRequest(t, req, http.StatusCreated)
+
+				for _, c := range []struct {
+					Digest  string
+					Content string
+				}{
+					{sbomManifestDigest, sbomManifestContent},
+					{signatureManifestDigest, signatureManifestContent},
+				} {
+					req = NewRequestWithBody(t, "PUT", fmt.Sprintf("%s/manifests/%s", url, c.Digest), strings.NewReader(c.Content)).
+						AddTokenAuth(userToken).
+						SetHeader("Content-Type", oci.MediaTypeImageManifest)
+					resp := MakeRequest(t, req, http.StatusCreated)
+
+					assert.Equal(t, c.Digest, resp.Header().Get("Docker-Content-Digest"))
+					assert.Equal(t, manifestDigest, resp.Header().Get("OCI-Subject"))
+				}
+
+				req = NewRequest(t, "GET", fmt.Sprintf("%s/referrers/invalid", url)).
+					AddTokenAuth(userToken)
+				MakeRequest(t, req, http.StatusBadRequest)
+
+				getReferrers := func(t *testing.T, url string) (*oci.Index, *httptest.ResponseRecorder) {
+					req := NewRequest(t, "GET", url).
+						AddTokenAuth(userToken)
+					resp := MakeRequest(t, req, http.StatusOK)
+
+					assert.Equal(t, oci.MediaTypeImageIndex, resp.Header().Get("Content-Type"))
+
+					var index *oci.Index
+					DecodeJSON(t, resp, &index)
+					assert.EqualValues(t, 2, index.SchemaVersion)
+					assert.Equal(t, oci.MediaTypeImageIndex, index.MediaType)
+					return index, resp
+				}
+
+				index, resp := getReferrers(t, fmt.Sprintf("%s/referrers/%s", url, unknownDigest))
+				assert.Empty(t, index.Manifests)
+				assert.Empty(t, resp.Header().Get("OCI-Filters-Applied"))
+
+				index, _ = getReferrers(t, fmt.Sprintf("%s/referrers/%s", url, manifestDigest))
+				assert.Len(t, index.Manifests, 2)
+				for _, m := range index.Manifests {
+					assert.Equal(t, oci.MediaTypeImageManifest, m.MediaType)
+					switch string(m.Digest) {
+					case sbomManifestDigest:
+						assert.EqualValues(t, len(sbomManifestContent), m.Size)
+						assert.Equal(t, sbomArtifactType, m.ArtifactType)
+						assert.Equal(t, map[string]string{"org.example.sbom.format": "spdx"}, m.Annotations)
+					case signatureManifestDigest:
+						assert.EqualValues(t, len(signatureManifestContent), m.Size)
+						assert.Equal(t, signatureArtifactType, m.ArtifactType)
+						assert.Empty(t, m.Annotations)
+					default:
+						assert.Fail(t, "unexpected referrer", m.Digest)
+					}
+				}
+
+				index, resp = getReferrers(t, fmt.Sprintf("%s/referrers/%s?artifactType=%s", url, manifestDigest, sbomArtifactType))
+				assert.Equal(t, "artifactType", resp.Header().Get("OCI-Filters-Applied"))
+				assert.Len(t, index.Manifests, 1)
+				assert.EqualValues(t, sbomManifestDigest, index.Manifests[0].Digest)
+
+				pv, err := packages_model.GetVersionByNameAndVersion(db.DefaultContext, user.ID, packages_model.TypeContainer, image, sbomManifestDigest)
+				assert.NoError(t, err)
+				pd, err := packages_model.GetPackageDescriptor(db.DefaultContext, pv)
+				assert.NoError(t, err)
+				assert.Equal(t, []string{manifestDigest}, getAllByName(pd.VersionProperties, container_module.PropertyManifestSubject))
+				metadata := pd.Metadata.(*container_module.Metadata)
+				assert.Equal(t, manifestDigest, metadata.Subject)
+				assert.Equal(t, sbomArtifactType, metadata.ArtifactType)
+
+				pv, err = packages_model.GetVersionByNameAndVersion(db.DefaultContext, user.ID, packages_model.TypeContainer, image, tags[0])
+				assert.NoError(t, err)
+				pd, err = packages_model.GetPackageDescriptor(db.DefaultContext, pv)
+				assert.NoError(t, err)
+
+				req = NewRequest(t, "GET", pd.VersionWebLink())
+				resp = MakeRequest(t, req, http.StatusOK)
+				assert.Contains(t, resp.Body.String(), sbomManifestDigest)
+				assert.Contains(t, resp.Body.String(), signatureManifestDigest)
+
+				// remove the referrers again to not influence the following tests
+				for _, d := range []string{sbomManifestDigest, signatureManifestDigest} {
+					req = NewRequest(t, "DELETE", fmt.Sprintf("%s/manifests/%s", url, d)).
+						AddTokenAuth(userToken)
+					MakeRequest(t, req, http.StatusAccepted)
+				}
+
+				index, _ = getReferrers(t, fmt.Sprintf("%s/referrers/%s", url, manifestDigest))
+				assert.Empty(t, index.Manifests)
+			})
+
 			t.Run("Delete", func(t *testing.T) {
 				t.Run("Blob", func(t *testing.T) {
 					defer tests.PrintCurrentTest(t)()
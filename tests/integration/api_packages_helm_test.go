@@ -18,9 +18,11 @@ import (
 	user_model "code.gitea.io/gitea/models/user"
 	helm_module "code.gitea.io/gitea/modules/packages/helm"
 	"code.gitea.io/gitea/modules/setting"
+	packages_service "code.gitea.io/gitea/services/packages"
 	"code.gitea.io/gitea/tests"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"gopkg.in/yaml.v3"
 )
 
@@ -36,8 +38,9 @@ func TestPackageHelm(t *testing.T) {
 
 	filename := fmt.Sprintf("%s-%s.tgz", packageName, packageVersion)
 
-	chartContent := `apiVersion: v2
-description: ` + packageDescription + `
+	createChart := func(description string) []byte {
+		chartContent := `apiVersion: v2
+description: ` + description + `
 name: ` + packageName + `
 type: application
 version: ` + packageVersion + `
@@ -48,18 +51,20 @@ dependencies:
   repository: https://example.com/
   version: 1.0.0`
 
-	var buf bytes.Buffer
-	zw := gzip.NewWriter(&buf)
-	archive := tar.NewWriter(zw)
-	archive.WriteHeader(&tar.Header{
-		Name: fmt.Sprintf("%s/Chart.yaml", packageName),
-		Mode: 0o600,
-		Size: int64(len(chartContent)),
-	})
-	archive.Write([]byte(chartContent))
-	archive.Close()
-	zw.Close()
-	content := buf.Bytes()
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		archive := tar.NewWriter(zw)
+		archive.WriteHeader(&tar.Header{
+			Name: fmt.Sprintf("%s/Chart.yaml", packageName),
+			Mode: 0o600,
+			Size: int64(len(chartContent)),
+		})
+		archive.Write([]byte(chartContent))
+		archive.Close()
+		zw.Close()
+		return buf.Bytes()
+	}
+	content := createChart(packageDescription)
 
 	url := fmt.Sprintf("/api/packages/%s/helm", user.Name)
 
@@ -164,4 +169,35 @@ dependencies:
 
 		assert.Equal(t, url, result.ServerInfo.ContextPath)
 	})
+
+	t.Run("ImmutableVersions", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		require.NoError(t, packages_service.SetImmutableVersionsEnabled(db.DefaultContext, user.ID, true))
+		defer func() {
+			require.NoError(t, packages_service.SetImmutableVersionsEnabled(db.DefaultContext, user.ID, false))
+		}()
+
+		uploadURL := url + "/api/charts"
+
+		// the same chart can be uploaded again
+		req := NewRequestWithBody(t, "POST", uploadURL, bytes.NewReader(content)).
+			AddBasicAuth(user.Name)
+		MakeRequest(t, req, http.StatusCreated)
+
+		// but it can't be replaced by another chart of the same version
+		req = NewRequestWithBody(t, "POST", uploadURL, bytes.NewReader(createChart("Replaced"))).
+			AddBasicAuth(user.Name)
+		MakeRequest(t, req, http.StatusForbidden)
+
+		pvs, err := packages.GetVersionsByPackageType(db.DefaultContext, user.ID, packages.TypeHelm)
+		require.NoError(t, err)
+		require.Len(t, pvs, 1)
+		pfs, err := packages.GetFilesByVersionID(db.DefaultContext, pvs[0].ID)
+		require.NoError(t, err)
+		require.Len(t, pfs, 1)
+		pb, err := packages.GetBlobByID(db.DefaultContext, pfs[0].BlobID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(content)), pb.Size)
+	})
 }
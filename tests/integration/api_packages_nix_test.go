@@ -0,0 +1,203 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package integration
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/models/unittest"
+	user_model "code.gitea.io/gitea/models/user"
+	nix_module "code.gitea.io/gitea/modules/packages/nix"
+	nix_service "code.gitea.io/gitea/services/packages/nix"
+	"code.gitea.io/gitea/tests"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPackageNix(t *testing.T) {
+	defer tests.PrepareTestEnv(t)()
+
+	user := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2})
+
+	rootURL := fmt.Sprintf("/api/packages/%s/nix", user.Name)
+
+	narContent := []byte("nar content")
+	narSum := sha256.Sum256(narContent)
+	narHash := "sha256:" + nix_module.EncodeBase32(narSum[:])
+	narFilename := nix_module.EncodeBase32(narSum[:]) + ".nar.xz"
+
+	storePathHash := "3m4jfbx6pgzkz4vbp2ik0fd2zlhbqqyy"
+	storePathName := "hello-2.12.1"
+	storePath := nix_module.StoreDir + "/" + storePathHash + "-" + storePathName
+
+	createNarInfo := func(signatures ...string) string {
+		content := `StorePath: ` + storePath + `
+URL: nar/` + narFilename + `
+Compression: xz
+FileHash: ` + narHash + `
+FileSize: ` + fmt.Sprint(len(narContent)) + `
+NarHash: ` + narHash + `
+NarSize: 1234
+References: ` + storePathHash + "-" + storePathName + `
+System: x86_64-linux
+`
+		for _, signature := range signatures {
+			content += "Sig: " + signature + "\n"
+		}
+		return content
+	}
+
+	t.Run("CacheInfo", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequest(t, "GET", rootURL+"/nix-cache-info")
+		resp := MakeRequest(t, req, http.StatusOK)
+		assert.Contains(t, resp.Body.String(), "StoreDir: /nix/store\n")
+	})
+
+	t.Run("UploadNar", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		url := rootURL + "/nar/" + narFilename
+
+		req := NewRequestWithBody(t, "PUT", url, bytes.NewReader(narContent))
+		MakeRequest(t, req, http.StatusUnauthorized)
+
+		req = NewRequestWithBody(t, "PUT", rootURL+"/nar/invalid.txt", bytes.NewReader(narContent)).
+			AddBasicAuth(user.Name)
+		MakeRequest(t, req, http.StatusBadRequest)
+
+		req = NewRequestWithBody(t, "PUT", url, bytes.NewReader(narContent)).
+			AddBasicAuth(user.Name)
+		MakeRequest(t, req, http.StatusCreated)
+
+		// the NAR is available before its narinfo is uploaded
+		req = NewRequest(t, "GET", url)
+		resp := MakeRequest(t, req, http.StatusOK)
+		assert.Equal(t, narContent, resp.Body.Bytes())
+
+		pvs, err := packages.GetVersionsByPackageType(db.DefaultContext, user.ID, packages.TypeNix)
+		assert.NoError(t, err)
+		assert.Empty(t, pvs)
+	})
+
+	t.Run("UploadNarInfo", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		url := rootURL + "/" + storePathHash + ".narinfo"
+
+		req := NewRequestWithBody(t, "PUT", url, strings.NewReader(createNarInfo()))
+		MakeRequest(t, req, http.StatusUnauthorized)
+
+		req = NewRequestWithBody(t, "PUT", url, strings.NewReader("invalid")).
+			AddBasicAuth(user.Name)
+		MakeRequest(t, req, http.StatusBadRequest)
+
+		req = NewRequestWithBody(t, "PUT", rootURL+"/0mdqa9w1p6cmli6976v4wi0sw9r4p5pr.narinfo", strings.NewReader(createNarInfo())).
+			AddBasicAuth(user.Name)
+		MakeRequest(t, req, http.StatusBadRequest)
+
+		req = NewRequestWithBody(t, "PUT", url, strings.NewReader(strings.ReplaceAll(createNarInfo(), narFilename, "0mdqa9w1p6cmli6976v4wi0sw9r4p5prkj7lzfd1877wk11c9c73.nar.xz"))).
+			AddBasicAuth(user.Name)
+		MakeRequest(t, req, http.StatusBadRequest)
+
+		pub, priv, err := ed25519.GenerateKey(nil)
+		assert.NoError(t, err)
+		key, err := nix_module.ParsePublicKey("cache-1:" + base64.StdEncoding.EncodeToString(pub))
+		assert.NoError(t, err)
+		assert.NoError(t, nix_service.SetTrustedKeys(db.DefaultContext, user.ID, []*nix_module.PublicKey{key}))
+
+		req = NewRequestWithBody(t, "PUT", url, strings.NewReader(createNarInfo())).
+			AddBasicAuth(user.Name)
+		MakeRequest(t, req, http.StatusBadRequest)
+
+		ni, err := nix_module.ParseNarInfo(strings.NewReader(createNarInfo()))
+		assert.NoError(t, err)
+		signature := "cache-1:" + base64.StdEncoding.EncodeToString(ed25519.Sign(priv, []byte(ni.Fingerprint())))
+
+		req = NewRequestWithBody(t, "PUT", url, strings.NewReader(createNarInfo(signature))).
+			AddBasicAuth(user.Name)
+		MakeRequest(t, req, http.StatusCreated)
+
+		req = NewRequestWithBody(t, "PUT", url, strings.NewReader(createNarInfo(signature))).
+			AddBasicAuth(user.Name)
+		MakeRequest(t, req, http.StatusConflict)
+
+		pvs, err := packages.GetVersionsByPackageType(db.DefaultContext, user.ID, packages.TypeNix)
+		assert.NoError(t, err)
+		assert.Len(t, pvs, 1)
+
+		pd, err := packages.GetPackageDescriptor(db.DefaultContext, pvs[0])
+		assert.NoError(t, err)
+		assert.IsType(t, &nix_module.VersionMetadata{}, pd.Metadata)
+		assert.Equal(t, storePathName, pd.Package.Name)
+		assert.Equal(t, storePathHash, pd.Version.Version)
+		assert.Equal(t, storePath, pd.Metadata.(*nix_module.VersionMetadata).StorePath)
+		assert.Len(t, pd.Files, 2)
+
+		for _, pfd := range pd.Files {
+			switch pfd.File.Name {
+			case storePathHash + ".narinfo":
+				assert.True(t, pfd.File.IsLead)
+			case narFilename:
+				assert.False(t, pfd.File.IsLead)
+				assert.EqualValues(t, len(narContent), pfd.Blob.Size)
+			default:
+				assert.Fail(t, "unexpected file", pfd.File.Name)
+			}
+		}
+
+		assert.NoError(t, nix_service.SetTrustedKeys(db.DefaultContext, user.ID, nil))
+	})
+
+	t.Run("Download", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequest(t, "GET", rootURL+"/"+storePathHash+".narinfo")
+		resp := MakeRequest(t, req, http.StatusOK)
+		assert.True(t, strings.HasPrefix(resp.Body.String(), "StorePath: "+storePath+"\n"))
+
+		req = NewRequest(t, "HEAD", rootURL+"/"+storePathHash+".narinfo")
+		MakeRequest(t, req, http.StatusOK)
+
+		req = NewRequest(t, "HEAD", rootURL+"/0mdqa9w1p6cmli6976v4wi0sw9r4p5pr.narinfo")
+		MakeRequest(t, req, http.StatusNotFound)
+
+		req = NewRequest(t, "GET", rootURL+"/nar/"+narFilename)
+		resp = MakeRequest(t, req, http.StatusOK)
+		assert.Equal(t, narContent, resp.Body.Bytes())
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		url := rootURL + "/" + storePathHash + ".narinfo"
+
+		req := NewRequest(t, "DELETE", url)
+		MakeRequest(t, req, http.StatusUnauthorized)
+
+		req = NewRequest(t, "DELETE", url).
+			AddBasicAuth(user.Name)
+		MakeRequest(t, req, http.StatusNoContent)
+
+		req = NewRequest(t, "GET", url)
+		MakeRequest(t, req, http.StatusNotFound)
+
+		req = NewRequest(t, "GET", rootURL+"/nar/"+narFilename)
+		MakeRequest(t, req, http.StatusNotFound)
+
+		pvs, err := packages.GetVersionsByPackageType(db.DefaultContext, user.ID, packages.TypeNix)
+		assert.NoError(t, err)
+		assert.Empty(t, pvs)
+	})
+}
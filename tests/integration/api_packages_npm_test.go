@@ -18,6 +18,7 @@ import (
 	user_model "code.gitea.io/gitea/models/user"
 	"code.gitea.io/gitea/modules/packages/npm"
 	"code.gitea.io/gitea/modules/setting"
+	packages_service "code.gitea.io/gitea/services/packages"
 	"code.gitea.io/gitea/tests"
 
 	"github.com/stretchr/testify/assert"
@@ -178,6 +179,27 @@ func TestPackageNpm(t *testing.T) {
 		assert.Equal(t, fmt.Sprintf("%s%s/-/%s/%s", setting.AppURL, root[1:], packageVersion, filename), pmv.Dist.Tarball)
 		assert.Equal(t, repoType, result.Repository.Type)
 		assert.Equal(t, repoURL, result.Repository.URL)
+		assert.Empty(t, pmv.Deprecated)
+	})
+
+	t.Run("PackageMetadataDeprecated", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		pv, err := packages.GetVersionByNameAndVersion(db.DefaultContext, user.ID, packages.TypeNpm, packageName, packageVersion)
+		assert.NoError(t, err)
+		assert.NoError(t, packages_service.SetVersionDeprecation(db.DefaultContext, pv, "use another version"))
+		defer func() {
+			assert.NoError(t, packages_service.SetVersionDeprecation(db.DefaultContext, pv, ""))
+		}()
+
+		req := NewRequest(t, "GET", root).
+			AddTokenAuth(token)
+		resp := MakeRequest(t, req, http.StatusOK)
+
+		var result npm.PackageMetadata
+		DecodeJSON(t, resp, &result)
+
+		assert.Equal(t, "use another version", result.Versions[packageVersion].Deprecated)
 	})
 
 	t.Run("AddTag", func(t *testing.T) {
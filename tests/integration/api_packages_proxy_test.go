@@ -0,0 +1,192 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package integration
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/models/unittest"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/packages/npm"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/test"
+	"code.gitea.io/gitea/tests"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPackageProxy(t *testing.T) {
+	defer tests.PrepareTestEnv(t)()
+
+	user := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2})
+
+	var upstreamRequests atomic.Int32
+	var upstreamHandler atomic.Value
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamRequests.Add(1)
+		upstreamHandler.Load().(http.Handler).ServeHTTP(w, r)
+	}))
+	defer upstream.Close()
+
+	insertProxy := func(t *testing.T, packageType packages.Type, handler http.Handler) {
+		upstreamHandler.Store(handler)
+
+		_, err := packages.InsertProxy(db.DefaultContext, &packages.PackageProxy{
+			Enabled: true,
+			OwnerID: user.ID,
+			Type:    packageType,
+			URL:     upstream.URL,
+		})
+		assert.NoError(t, err)
+	}
+
+	t.Run("Npm", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		packageName := "@scope/test-package"
+		packageVersion := "1.0.1"
+		filename := "test-package-1.0.1.tgz"
+		data, _ := base64.StdEncoding.DecodeString("H4sIAAAAAAAA/ytITM5OTE/VL4DQelnF+XkMVAYGBgZmJiYK2MRBwNDcSIHB2NTMwNDQzMwAqA7IMDUxA9LUdgg2UFpcklgEdAql5kD8ogCnhwio5lJQUMpLzE1VslJQcihOzi9I1S9JLS7RhSYIJR2QgrLUouLM/DyQGkM9Az1D3YIiqExKanFyUWZBCVQ2BKhVwQVJDKwosbQkI78IJO/tZ+LsbRykxFXLNdA+HwWjYBSMgpENACgAbtAACAAA")
+
+		insertProxy(t, packages.TypeNpm, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/" + packageName:
+				fmt.Fprintf(w, `{
+					"name": %q,
+					"dist-tags": {"latest": %q},
+					"versions": {
+						%q: {
+							"name": %q,
+							"version": %q,
+							"dist": {
+								"integrity": "sha512-yA4FJsVhetynGfOC1jFf79BuS+jrHbm0fhh+aHzCQkOaOBXKf9oBnC4a6DnLLnEsHQDRLYd00cwj8sCXpC+wIg==",
+								"tarball": "%s/tarballs/%s"
+							}
+						}
+					}
+				}`, packageName, packageVersion, packageVersion, packageName, packageVersion, upstream.URL, filename)
+			case "/tarballs/" + filename:
+				w.Write(data)
+			default:
+				http.NotFound(w, r)
+			}
+		}))
+
+		root := fmt.Sprintf("/api/packages/%s/npm/%s", user.Name, url.QueryEscape(packageName))
+
+		req := NewRequest(t, "GET", root)
+		resp := MakeRequest(t, req, http.StatusOK)
+
+		var metadata npm.PackageMetadata
+		DecodeJSON(t, resp, &metadata)
+		assert.Equal(t, packageVersion, metadata.DistTags["latest"])
+		assert.Contains(t, metadata.Versions, packageVersion)
+		assert.True(t, strings.HasSuffix(metadata.Versions[packageVersion].Dist.Tarball, root+"/-/"+packageVersion+"/"+filename))
+
+		pvs, err := packages.GetVersionsByPackageType(db.DefaultContext, user.ID, packages.TypeNpm)
+		assert.NoError(t, err)
+		assert.Empty(t, pvs)
+
+		// the tarball is refused if it is larger than the size limit of the npm packages
+		func() {
+			defer test.MockVariableValue(&setting.Packages.LimitSizeNpm, int64(len(data)-1))()
+
+			req := NewRequest(t, "GET", fmt.Sprintf("%s/-/%s/%s", root, packageVersion, filename))
+			MakeRequest(t, req, http.StatusForbidden)
+
+			pvs, err := packages.GetVersionsByPackageType(db.DefaultContext, user.ID, packages.TypeNpm)
+			assert.NoError(t, err)
+			assert.Empty(t, pvs)
+		}()
+
+		req = NewRequest(t, "GET", fmt.Sprintf("%s/-/%s/%s", root, packageVersion, filename))
+		resp = MakeRequest(t, req, http.StatusOK)
+		assert.Equal(t, data, resp.Body.Bytes())
+
+		pvs, err = packages.GetVersionsByPackageType(db.DefaultContext, user.ID, packages.TypeNpm)
+		assert.NoError(t, err)
+		assert.Len(t, pvs, 1)
+		assert.Equal(t, packageVersion, pvs[0].Version)
+
+		requests := upstreamRequests.Load()
+
+		req = NewRequest(t, "GET", fmt.Sprintf("%s/-/%s/%s", root, packageVersion, filename))
+		resp = MakeRequest(t, req, http.StatusOK)
+		assert.Equal(t, data, resp.Body.Bytes())
+		assert.Equal(t, requests, upstreamRequests.Load())
+
+		req = NewRequest(t, "GET", fmt.Sprintf("%s/-/%s/%s", root, "2.0.0", "test-package-2.0.0.tgz"))
+		MakeRequest(t, req, http.StatusNotFound)
+	})
+
+	t.Run("PyPI", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		packageName := "test-package"
+		packageVersion := "1.0.1"
+		filename := "test_package-1.0.1-py3-none-any.whl"
+		data := []byte("wheel content")
+		hash := sha256.Sum256(data)
+		hashSHA256 := hex.EncodeToString(hash[:])
+
+		insertProxy(t, packages.TypePyPI, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			file := fmt.Sprintf(`{"filename": %q, "url": "%s/files/%s", "requires_python": ">=3.8", "digests": {"sha256": %q}}`, filename, upstream.URL, filename, hashSHA256)
+			switch r.URL.Path {
+			case "/pypi/" + packageName + "/json":
+				fmt.Fprintf(w, `{"releases": {%q: [%s]}}`, packageVersion, file)
+			case "/pypi/" + packageName + "/" + packageVersion + "/json":
+				fmt.Fprintf(w, `{"info": {"author": "KN4CK3R", "summary": "Test Summary"}, "urls": [%s]}`, file)
+			case "/files/" + filename:
+				w.Write(data)
+			default:
+				http.NotFound(w, r)
+			}
+		}))
+
+		root := fmt.Sprintf("/api/packages/%s/pypi", user.Name)
+
+		req := NewRequest(t, "GET", fmt.Sprintf("%s/simple/%s", root, packageName))
+		resp := MakeRequest(t, req, http.StatusOK)
+		assert.Contains(t, resp.Body.String(), fmt.Sprintf("/files/%s/%s/%s#sha256=%s", packageName, packageVersion, filename, hashSHA256))
+
+		// the file is refused if it is larger than the size limit of the PyPI packages
+		func() {
+			defer test.MockVariableValue(&setting.Packages.LimitSizePyPI, int64(len(data)-1))()
+
+			req := NewRequest(t, "GET", fmt.Sprintf("%s/files/%s/%s/%s", root, packageName, packageVersion, filename))
+			MakeRequest(t, req, http.StatusForbidden)
+
+			pvs, err := packages.GetVersionsByPackageType(db.DefaultContext, user.ID, packages.TypePyPI)
+			assert.NoError(t, err)
+			assert.Empty(t, pvs)
+		}()
+
+		req = NewRequest(t, "GET", fmt.Sprintf("%s/files/%s/%s/%s", root, packageName, packageVersion, filename))
+		resp = MakeRequest(t, req, http.StatusOK)
+		assert.Equal(t, data, resp.Body.Bytes())
+
+		pvs, err := packages.GetVersionsByPackageType(db.DefaultContext, user.ID, packages.TypePyPI)
+		assert.NoError(t, err)
+		assert.Len(t, pvs, 1)
+		assert.Equal(t, packageVersion, pvs[0].Version)
+
+		requests := upstreamRequests.Load()
+
+		req = NewRequest(t, "GET", fmt.Sprintf("%s/files/%s/%s/%s", root, packageName, packageVersion, filename))
+		resp = MakeRequest(t, req, http.StatusOK)
+		assert.Equal(t, data, resp.Body.Bytes())
+		assert.Equal(t, requests, upstreamRequests.Load())
+	})
+}
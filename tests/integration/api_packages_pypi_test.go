@@ -18,8 +18,10 @@ import (
 	"code.gitea.io/gitea/models/unittest"
 	user_model "code.gitea.io/gitea/models/user"
 	"code.gitea.io/gitea/modules/packages/pypi"
+	packages_service "code.gitea.io/gitea/services/packages"
 	"code.gitea.io/gitea/tests"
 
+	"github.com/PuerkitoBio/goquery"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -179,4 +181,23 @@ func TestPackagePyPI(t *testing.T) {
 			}
 		}
 	})
+
+	t.Run("PackageMetadataYanked", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		pv, err := packages.GetVersionByNameAndVersion(db.DefaultContext, user.ID, packages.TypePyPI, packageName, packageVersion)
+		assert.NoError(t, err)
+		assert.NoError(t, packages_service.SetVersionDeprecation(db.DefaultContext, pv, "broken build"))
+
+		req := NewRequest(t, "GET", fmt.Sprintf("%s/simple/%s", root, packageName)).
+			AddBasicAuth(user.Name)
+		resp := MakeRequest(t, req, http.StatusOK)
+
+		htmlDoc := NewHTMLParser(t, resp.Body)
+		htmlDoc.doc.Find("a").Each(func(_ int, a *goquery.Selection) {
+			yanked, exists := a.Attr("data-yanked")
+			assert.True(t, exists)
+			assert.Equal(t, "broken build", yanked)
+		})
+	})
 }
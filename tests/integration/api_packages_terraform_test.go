@@ -0,0 +1,336 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package integration
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	auth_model "code.gitea.io/gitea/models/auth"
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/models/unittest"
+	user_model "code.gitea.io/gitea/models/user"
+	terraform_module "code.gitea.io/gitea/modules/packages/terraform"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/tests"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPackageTerraform(t *testing.T) {
+	defer tests.PrepareTestEnv(t)()
+
+	// the owner is private, the downloads of anonymous clients need a signed link
+	user := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 31})
+
+	token := "Bearer " + getUserToken(t, user.Name, auth_model.AccessTokenScopeWritePackage)
+
+	packageVersion := "1.0.1"
+	readme := "# Test Module"
+
+	t.Run("ServiceDiscovery", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequest(t, "GET", "/.well-known/terraform.json")
+		resp := MakeRequest(t, req, http.StatusOK)
+
+		var services map[string]string
+		DecodeJSON(t, resp, &services)
+		assert.Equal(t, setting.AppURL+"api/packages/-/terraform/modules/v1/", services["modules.v1"])
+		assert.Equal(t, setting.AppURL+"api/packages/-/terraform/providers/v1/", services["providers.v1"])
+	})
+
+	t.Run("Module", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		moduleName := "network"
+		moduleSystem := "aws"
+		uploadURL := fmt.Sprintf("/api/packages/%s/terraform/modules/%s/%s/%s", user.Name, moduleName, moduleSystem, packageVersion)
+		protocolURL := fmt.Sprintf("/api/packages/-/terraform/modules/v1/%s/%s/%s", user.Name, moduleName, moduleSystem)
+
+		createArchive := func(files map[string]string) []byte {
+			var buf bytes.Buffer
+			zw := gzip.NewWriter(&buf)
+			tw := tar.NewWriter(zw)
+			for name, content := range files {
+				tw.WriteHeader(&tar.Header{
+					Name: name,
+					Mode: 0o600,
+					Size: int64(len(content)),
+				})
+				tw.Write([]byte(content))
+			}
+			tw.Close()
+			zw.Close()
+			return buf.Bytes()
+		}
+
+		content := createArchive(map[string]string{
+			"main.tf":   `resource "null_resource" "test" {}`,
+			"README.md": readme,
+		})
+
+		t.Run("Upload", func(t *testing.T) {
+			defer tests.PrintCurrentTest(t)()
+
+			req := NewRequestWithBody(t, "PUT", uploadURL, bytes.NewReader(content))
+			MakeRequest(t, req, http.StatusUnauthorized)
+
+			req = NewRequestWithBody(t, "PUT", uploadURL, bytes.NewReader(createArchive(map[string]string{"README.md": readme}))).
+				AddTokenAuth(token)
+			MakeRequest(t, req, http.StatusBadRequest)
+
+			req = NewRequestWithBody(t, "PUT", uploadURL, bytes.NewReader(content)).
+				AddTokenAuth(token)
+			MakeRequest(t, req, http.StatusCreated)
+
+			pvs, err := packages.GetVersionsByPackageType(db.DefaultContext, user.ID, packages.TypeTerraform)
+			assert.NoError(t, err)
+			assert.Len(t, pvs, 1)
+
+			pd, err := packages.GetPackageDescriptor(db.DefaultContext, pvs[0])
+			assert.NoError(t, err)
+			assert.Equal(t, moduleName+"/"+moduleSystem, pd.Package.Name)
+			assert.Equal(t, packageVersion, pd.Version.Version)
+			assert.IsType(t, &terraform_module.Metadata{}, pd.Metadata)
+			assert.Equal(t, terraform_module.KindModule, pd.Metadata.(*terraform_module.Metadata).Kind)
+			assert.Equal(t, readme, pd.Metadata.(*terraform_module.Metadata).Readme)
+
+			req = NewRequestWithBody(t, "PUT", uploadURL, bytes.NewReader(content)).
+				AddTokenAuth(token)
+			MakeRequest(t, req, http.StatusConflict)
+		})
+
+		t.Run("EnumerateVersions", func(t *testing.T) {
+			defer tests.PrintCurrentTest(t)()
+
+			req := NewRequest(t, "GET", protocolURL+"/versions")
+			MakeRequest(t, req, http.StatusUnauthorized)
+
+			req = NewRequest(t, "GET", protocolURL+"/versions").
+				AddTokenAuth(token)
+			resp := MakeRequest(t, req, http.StatusOK)
+
+			var result struct {
+				Modules []struct {
+					Versions []struct {
+						Version string `json:"version"`
+					} `json:"versions"`
+				} `json:"modules"`
+			}
+			DecodeJSON(t, resp, &result)
+			assert.Len(t, result.Modules, 1)
+			assert.Len(t, result.Modules[0].Versions, 1)
+			assert.Equal(t, packageVersion, result.Modules[0].Versions[0].Version)
+		})
+
+		t.Run("Download", func(t *testing.T) {
+			defer tests.PrintCurrentTest(t)()
+
+			req := NewRequest(t, "GET", fmt.Sprintf("%s/%s/download", protocolURL, "9.9.9")).
+				AddTokenAuth(token)
+			MakeRequest(t, req, http.StatusNotFound)
+
+			req = NewRequest(t, "GET", fmt.Sprintf("%s/%s/download", protocolURL, packageVersion)).
+				AddTokenAuth(token)
+			resp := MakeRequest(t, req, http.StatusNoContent)
+
+			location := resp.Header().Get("X-Terraform-Get")
+			assert.True(t, strings.HasPrefix(location, setting.AppURL))
+
+			u, err := url.Parse(location)
+			assert.NoError(t, err)
+			assert.True(t, strings.HasSuffix(u.Path, ".tar.gz"))
+
+			req = NewRequest(t, "GET", u.Path)
+			MakeRequest(t, req, http.StatusUnauthorized)
+
+			req = NewRequest(t, "GET", u.Path+"?expires="+u.Query().Get("expires")+"&sig=invalid")
+			MakeRequest(t, req, http.StatusUnauthorized)
+
+			req = NewRequest(t, "GET", u.RequestURI())
+			resp = MakeRequest(t, req, http.StatusOK)
+			assert.Equal(t, content, resp.Body.Bytes())
+		})
+
+		t.Run("Delete", func(t *testing.T) {
+			defer tests.PrintCurrentTest(t)()
+
+			req := NewRequest(t, "DELETE", uploadURL)
+			MakeRequest(t, req, http.StatusUnauthorized)
+
+			req = NewRequest(t, "DELETE", uploadURL).
+				AddTokenAuth(token)
+			MakeRequest(t, req, http.StatusNoContent)
+
+			pvs, err := packages.GetVersionsByPackageType(db.DefaultContext, user.ID, packages.TypeTerraform)
+			assert.NoError(t, err)
+			assert.Empty(t, pvs)
+		})
+	})
+
+	t.Run("Provider", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		providerName := "test"
+		uploadURL := fmt.Sprintf("/api/packages/%s/terraform/providers/%s/%s", user.Name, providerName, packageVersion)
+		protocolURL := fmt.Sprintf("/api/packages/-/terraform/providers/v1/%s/%s", user.Name, providerName)
+
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		w, _ := zw.Create("terraform-provider-test_v1.0.1")
+		w.Write([]byte("provider binary"))
+		zw.Close()
+		content := buf.Bytes()
+
+		archiveName := fmt.Sprintf("terraform-provider-%s_%s_linux_amd64.zip", providerName, packageVersion)
+		manifestName := fmt.Sprintf("terraform-provider-%s_%s_manifest.json", providerName, packageVersion)
+
+		t.Run("Upload", func(t *testing.T) {
+			defer tests.PrintCurrentTest(t)()
+
+			req := NewRequestWithBody(t, "PUT", uploadURL+"/"+archiveName, bytes.NewReader(content))
+			MakeRequest(t, req, http.StatusUnauthorized)
+
+			req = NewRequestWithBody(t, "PUT", uploadURL+"/terraform-provider-other_1.0.1_linux_amd64.zip", bytes.NewReader(content)).
+				AddTokenAuth(token)
+			MakeRequest(t, req, http.StatusBadRequest)
+
+			req = NewRequestWithBody(t, "PUT", uploadURL+"/"+archiveName, strings.NewReader("not a zip")).
+				AddTokenAuth(token)
+			MakeRequest(t, req, http.StatusBadRequest)
+
+			req = NewRequestWithBody(t, "PUT", uploadURL+"/"+archiveName, bytes.NewReader(content)).
+				AddTokenAuth(token)
+			MakeRequest(t, req, http.StatusCreated)
+
+			req = NewRequestWithBody(t, "PUT", uploadURL+"/"+archiveName, bytes.NewReader(content)).
+				AddTokenAuth(token)
+			MakeRequest(t, req, http.StatusConflict)
+
+			req = NewRequestWithBody(t, "PUT", uploadURL+"/"+manifestName, strings.NewReader(`{"version":1,"metadata":{"protocol_versions":["6.0"]}}`)).
+				AddTokenAuth(token)
+			MakeRequest(t, req, http.StatusCreated)
+
+			pvs, err := packages.GetVersionsByPackageType(db.DefaultContext, user.ID, packages.TypeTerraform)
+			assert.NoError(t, err)
+			assert.Len(t, pvs, 1)
+
+			pd, err := packages.GetPackageDescriptor(db.DefaultContext, pvs[0])
+			assert.NoError(t, err)
+			assert.Equal(t, providerName, pd.Package.Name)
+			assert.Equal(t, terraform_module.KindProvider, pd.Metadata.(*terraform_module.Metadata).Kind)
+			assert.Equal(t, []string{"6.0"}, pd.Metadata.(*terraform_module.Metadata).Protocols)
+			assert.Len(t, pd.Files, 2)
+		})
+
+		t.Run("EnumerateVersions", func(t *testing.T) {
+			defer tests.PrintCurrentTest(t)()
+
+			req := NewRequest(t, "GET", protocolURL+"/versions").
+				AddTokenAuth(token)
+			resp := MakeRequest(t, req, http.StatusOK)
+
+			var result struct {
+				Versions []struct {
+					Version   string   `json:"version"`
+					Protocols []string `json:"protocols"`
+					Platforms []struct {
+						OS   string `json:"os"`
+						Arch string `json:"arch"`
+					} `json:"platforms"`
+				} `json:"versions"`
+			}
+			DecodeJSON(t, resp, &result)
+			assert.Len(t, result.Versions, 1)
+			assert.Equal(t, packageVersion, result.Versions[0].Version)
+			assert.Equal(t, []string{"6.0"}, result.Versions[0].Protocols)
+			assert.Len(t, result.Versions[0].Platforms, 1)
+			assert.Equal(t, "linux", result.Versions[0].Platforms[0].OS)
+			assert.Equal(t, "amd64", result.Versions[0].Platforms[0].Arch)
+		})
+
+		t.Run("Download", func(t *testing.T) {
+			defer tests.PrintCurrentTest(t)()
+
+			req := NewRequest(t, "GET", fmt.Sprintf("%s/%s/download/darwin/arm64", protocolURL, packageVersion)).
+				AddTokenAuth(token)
+			MakeRequest(t, req, http.StatusNotFound)
+
+			req = NewRequest(t, "GET", fmt.Sprintf("%s/%s/download/linux/amd64", protocolURL, packageVersion)).
+				AddTokenAuth(token)
+			resp := MakeRequest(t, req, http.StatusOK)
+
+			var result struct {
+				Protocols           []string `json:"protocols"`
+				Filename            string   `json:"filename"`
+				DownloadURL         string   `json:"download_url"`
+				ShasumsURL          string   `json:"shasums_url"`
+				ShasumsSignatureURL string   `json:"shasums_signature_url"`
+				Shasum              string   `json:"shasum"`
+				SigningKeys         struct {
+					GPGPublicKeys []struct {
+						KeyID      string `json:"key_id"`
+						ASCIIArmor string `json:"ascii_armor"`
+					} `json:"gpg_public_keys"`
+				} `json:"signing_keys"`
+			}
+			DecodeJSON(t, resp, &result)
+
+			hash := sha256.Sum256(content)
+			shasum := hex.EncodeToString(hash[:])
+
+			assert.Equal(t, []string{"6.0"}, result.Protocols)
+			assert.Equal(t, archiveName, result.Filename)
+			assert.Equal(t, shasum, result.Shasum)
+			assert.Len(t, result.SigningKeys.GPGPublicKeys, 1)
+
+			download := func(t *testing.T, location string) []byte {
+				u, err := url.Parse(location)
+				assert.NoError(t, err)
+
+				req := NewRequest(t, "GET", u.Path)
+				MakeRequest(t, req, http.StatusUnauthorized)
+
+				req = NewRequest(t, "GET", u.RequestURI())
+				return MakeRequest(t, req, http.StatusOK).Body.Bytes()
+			}
+
+			assert.Equal(t, content, download(t, result.DownloadURL))
+
+			shasums := download(t, result.ShasumsURL)
+			assert.Equal(t, fmt.Sprintf("%s  %s\n", shasum, archiveName), string(shasums))
+
+			signature := download(t, result.ShasumsSignatureURL)
+			keyring, err := openpgp.ReadArmoredKeyRing(strings.NewReader(result.SigningKeys.GPGPublicKeys[0].ASCIIArmor))
+			assert.NoError(t, err)
+			signer, err := openpgp.CheckDetachedSignature(keyring, bytes.NewReader(shasums), bytes.NewReader(signature), nil)
+			assert.NoError(t, err)
+			assert.Equal(t, result.SigningKeys.GPGPublicKeys[0].KeyID, strings.ToUpper(signer.PrimaryKey.KeyIdString()))
+		})
+
+		t.Run("Delete", func(t *testing.T) {
+			defer tests.PrintCurrentTest(t)()
+
+			req := NewRequest(t, "DELETE", uploadURL).
+				AddTokenAuth(token)
+			MakeRequest(t, req, http.StatusNoContent)
+
+			req = NewRequest(t, "DELETE", uploadURL).
+				AddTokenAuth(token)
+			MakeRequest(t, req, http.StatusNotFound)
+		})
+	})
+}
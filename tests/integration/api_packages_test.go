@@ -640,3 +640,271 @@ func TestPackageCleanup(t *testing.T) {
 		}
 	})
 }
+
+func TestPackageProtection(t *testing.T) {
+	defer tests.PrepareTestEnv(t)()
+
+	user := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2})
+	token := getUserToken(t, user.Name, auth_model.AccessTokenScopeWritePackage)
+
+	packageName := "protection-test"
+	packageVersion := "1.0.0"
+
+	url := fmt.Sprintf("/api/packages/%s/generic/%s/%s", user.Name, packageName, packageVersion)
+	apiURL := fmt.Sprintf("/api/v1/packages/%s/generic/%s/%s", user.Name, packageName, packageVersion)
+
+	req := NewRequestWithBody(t, "PUT", url+"/file.bin", bytes.NewReader([]byte{1, 2, 3})).
+		AddBasicAuth(user.Name)
+	MakeRequest(t, req, http.StatusCreated)
+
+	getPackage := func(t *testing.T) *api.Package {
+		t.Helper()
+
+		req := NewRequest(t, "GET", apiURL).
+			AddTokenAuth(token)
+		resp := MakeRequest(t, req, http.StatusOK)
+
+		var p *api.Package
+		DecodeJSON(t, resp, &p)
+		return p
+	}
+
+	t.Run("RetentionLock", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequest(t, "PUT", apiURL+"/lock").
+			AddTokenAuth(token)
+		MakeRequest(t, req, http.StatusNoContent)
+
+		assert.True(t, getPackage(t).RetentionLocked)
+
+		req = NewRequest(t, "DELETE", url+"/file.bin").
+			AddBasicAuth(user.Name)
+		MakeRequest(t, req, http.StatusForbidden)
+
+		req = NewRequest(t, "DELETE", apiURL).
+			AddTokenAuth(token)
+		MakeRequest(t, req, http.StatusForbidden)
+
+		pv, err := packages_model.GetVersionByNameAndVersion(db.DefaultContext, user.ID, packages_model.TypeGeneric, packageName, packageVersion)
+		assert.NoError(t, err)
+		_, err = db.GetEngine(db.DefaultContext).Exec("UPDATE package_version SET created_unix = ? WHERE id = ?", time.Now().Add(-24*time.Hour).Unix(), pv.ID)
+		assert.NoError(t, err)
+
+		pcr, err := packages_model.InsertCleanupRule(db.DefaultContext, &packages_model.PackageCleanupRule{
+			Enabled:       true,
+			OwnerID:       user.ID,
+			Type:          packages_model.TypeGeneric,
+			RemovePattern: `.*`,
+		})
+		assert.NoError(t, err)
+		defer packages_model.DeleteCleanupRuleByID(db.DefaultContext, pcr.ID)
+
+		assert.NoError(t, packages_cleanup_service.CleanupTask(db.DefaultContext, 0))
+
+		_, err = packages_model.GetVersionByID(db.DefaultContext, pv.ID)
+		assert.NoError(t, err)
+
+		req = NewRequest(t, "DELETE", apiURL+"/lock").
+			AddTokenAuth(token)
+		MakeRequest(t, req, http.StatusNoContent)
+
+		assert.False(t, getPackage(t).RetentionLocked)
+	})
+
+	t.Run("UnlockRequiresAdmin", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		admin := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 1})
+		org := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 23})
+		writer := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 5}) // user has package write access
+		writerToken := getUserToken(t, writer.Name, auth_model.AccessTokenScopeWritePackage)
+		adminToken := getUserToken(t, admin.Name, auth_model.AccessTokenScopeWritePackage)
+
+		orgURL := fmt.Sprintf("/api/packages/%s/generic/%s/%s", org.Name, packageName, packageVersion)
+		orgAPIURL := fmt.Sprintf("/api/v1/packages/%s/generic/%s/%s", org.Name, packageName, packageVersion)
+
+		req := NewRequestWithBody(t, "PUT", orgURL+"/file.bin", bytes.NewReader([]byte{1, 2, 3})).
+			AddBasicAuth(writer.Name)
+		MakeRequest(t, req, http.StatusCreated)
+
+		req = NewRequest(t, "PUT", orgAPIURL+"/lock").
+			AddTokenAuth(writerToken)
+		MakeRequest(t, req, http.StatusNoContent)
+
+		// the writers could delete the version after they unlocked it
+		req = NewRequest(t, "DELETE", orgAPIURL+"/lock").
+			AddTokenAuth(writerToken)
+		MakeRequest(t, req, http.StatusForbidden)
+
+		req = NewRequest(t, "DELETE", orgAPIURL).
+			AddTokenAuth(writerToken)
+		MakeRequest(t, req, http.StatusForbidden)
+
+		req = NewRequest(t, "DELETE", orgAPIURL+"/lock").
+			AddTokenAuth(adminToken)
+		MakeRequest(t, req, http.StatusNoContent)
+
+		req = NewRequest(t, "DELETE", orgAPIURL).
+			AddTokenAuth(writerToken)
+		MakeRequest(t, req, http.StatusNoContent)
+	})
+
+	t.Run("ImmutableVersions", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		session := loginUser(t, user.Name)
+		req := NewRequestWithValues(t, "POST", "/user/settings/packages/immutable_versions", map[string]string{
+			"_csrf":              GetCSRF(t, session, "/user/settings/packages"),
+			"immutable_versions": "on",
+		})
+		session.MakeRequest(t, req, http.StatusSeeOther)
+
+		enabled, err := packages_service.IsImmutableVersionsEnabled(db.DefaultContext, user.ID)
+		assert.NoError(t, err)
+		assert.True(t, enabled)
+
+		req = NewRequest(t, "DELETE", url+"/file.bin").
+			AddBasicAuth(user.Name)
+		MakeRequest(t, req, http.StatusForbidden)
+
+		req = NewRequest(t, "DELETE", apiURL).
+			AddTokenAuth(token)
+		MakeRequest(t, req, http.StatusForbidden)
+
+		// the cleanup rules still remove the immutable versions which are not locked
+		req = NewRequestWithBody(t, "PUT", fmt.Sprintf("/api/packages/%s/generic/%s/0.9.0/file.bin", user.Name, packageName), bytes.NewReader([]byte{1})).
+			AddBasicAuth(user.Name)
+		MakeRequest(t, req, http.StatusCreated)
+
+		_, err = db.GetEngine(db.DefaultContext).Exec("UPDATE package_version SET created_unix = ?", time.Now().Add(-24*time.Hour).Unix())
+		assert.NoError(t, err)
+
+		pcr, err := packages_model.InsertCleanupRule(db.DefaultContext, &packages_model.PackageCleanupRule{
+			Enabled:       true,
+			OwnerID:       user.ID,
+			Type:          packages_model.TypeGeneric,
+			RemovePattern: `0\.9\.0`,
+		})
+		assert.NoError(t, err)
+		defer packages_model.DeleteCleanupRuleByID(db.DefaultContext, pcr.ID)
+
+		assert.NoError(t, packages_cleanup_service.CleanupTask(db.DefaultContext, 0))
+
+		_, err = packages_model.GetVersionByNameAndVersion(db.DefaultContext, user.ID, packages_model.TypeGeneric, packageName, "0.9.0")
+		assert.ErrorIs(t, err, packages_model.ErrPackageNotExist)
+		_, err = packages_model.GetVersionByNameAndVersion(db.DefaultContext, user.ID, packages_model.TypeGeneric, packageName, packageVersion)
+		assert.NoError(t, err)
+
+		assert.NoError(t, packages_service.SetImmutableVersionsEnabled(db.DefaultContext, user.ID, false))
+	})
+
+	t.Run("Deprecation", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequestWithJSON(t, "PUT", apiURL+"/deprecation", &api.DeprecatePackageOption{}).
+			AddTokenAuth(token)
+		MakeRequest(t, req, http.StatusUnprocessableEntity)
+
+		req = NewRequestWithJSON(t, "PUT", apiURL+"/deprecation", &api.DeprecatePackageOption{Message: "use 2.0.0"}).
+			AddTokenAuth(token)
+		MakeRequest(t, req, http.StatusNoContent)
+
+		assert.Equal(t, "use 2.0.0", getPackage(t).DeprecationMessage)
+
+		req = NewRequest(t, "DELETE", apiURL+"/deprecation").
+			AddTokenAuth(token)
+		MakeRequest(t, req, http.StatusNoContent)
+
+		assert.Empty(t, getPackage(t).DeprecationMessage)
+	})
+
+	req = NewRequest(t, "DELETE", url+"/file.bin").
+		AddBasicAuth(user.Name)
+	MakeRequest(t, req, http.StatusNoContent)
+}
+
+func TestPackageDownloadStats(t *testing.T) {
+	defer tests.PrepareTestEnv(t)()
+
+	user := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2})
+	token := getUserToken(t, user.Name, auth_model.AccessTokenScopeReadPackage)
+
+	otherUser := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 4})
+	otherToken := getUserToken(t, otherUser.Name, auth_model.AccessTokenScopeReadPackage)
+
+	packageName := "stats-test"
+	packageVersion := "1.0.0"
+
+	url := fmt.Sprintf("/api/packages/%s/generic/%s/%s/file.bin", user.Name, packageName, packageVersion)
+	apiURL := fmt.Sprintf("/api/v1/packages/%s/generic/%s/%s/stats", user.Name, packageName, packageVersion)
+
+	req := NewRequestWithBody(t, "PUT", url, bytes.NewReader([]byte{1, 2, 3})).
+		AddBasicAuth(user.Name)
+	MakeRequest(t, req, http.StatusCreated)
+
+	for i := 0; i < 2; i++ {
+		req = NewRequest(t, "GET", url).
+			AddBasicAuth(user.Name)
+		MakeRequest(t, req, http.StatusOK)
+	}
+	req = NewRequest(t, "GET", url)
+	MakeRequest(t, req, http.StatusOK)
+
+	unittest.AssertCount(t, &packages_model.PackageDownload{}, 3)
+
+	assert.NoError(t, packages_service.AggregateDownloads(db.DefaultContext))
+
+	unittest.AssertCount(t, &packages_model.PackageDownload{}, 0)
+
+	t.Run("Owner", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequest(t, "GET", apiURL).
+			AddTokenAuth(token)
+		resp := MakeRequest(t, req, http.StatusOK)
+
+		var stats *api.PackageDownloadStats
+		DecodeJSON(t, resp, &stats)
+
+		assert.EqualValues(t, 3, stats.TotalDownloads)
+		assert.Len(t, stats.Days, 30)
+		assert.Equal(t, time.Now().UTC().Format(time.DateOnly), stats.Days[29].Date)
+		assert.EqualValues(t, 3, stats.Days[29].Downloads)
+		assert.EqualValues(t, 0, stats.Days[0].Downloads)
+		assert.Len(t, stats.Users, 2)
+		assert.Equal(t, user.Name, stats.Users[0].User.UserName)
+		assert.EqualValues(t, 2, stats.Users[0].Downloads)
+		assert.Nil(t, stats.Users[1].User)
+		assert.EqualValues(t, 1, stats.Users[1].Downloads)
+	})
+
+	t.Run("Reader", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequest(t, "GET", apiURL+"?days=7").
+			AddTokenAuth(otherToken)
+		resp := MakeRequest(t, req, http.StatusOK)
+
+		var stats *api.PackageDownloadStats
+		DecodeJSON(t, resp, &stats)
+
+		assert.Len(t, stats.Days, 7)
+		assert.EqualValues(t, 3, stats.Days[6].Downloads)
+		assert.Empty(t, stats.Users)
+	})
+
+	t.Run("InvalidDays", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequest(t, "GET", apiURL+"?days=366").
+			AddTokenAuth(token)
+		MakeRequest(t, req, http.StatusUnprocessableEntity)
+	})
+
+	req = NewRequest(t, "DELETE", url).
+		AddBasicAuth(user.Name)
+	MakeRequest(t, req, http.StatusNoContent)
+
+	unittest.AssertCount(t, &packages_model.PackageDownloadStat{}, 0)
+}
@@ -0,0 +1,101 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package integration
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/models/unittest"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/tests"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPackageVirtualRegistry(t *testing.T) {
+	defer tests.PrepareTestEnv(t)()
+
+	owner := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2})
+	member := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 4})
+
+	packageName := "test-package"
+
+	uploadFile := func(t *testing.T, user *user_model.User, version, filename, content string) {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		part, _ := writer.CreateFormFile("content", filename)
+		_, _ = io.Copy(part, strings.NewReader(content))
+
+		writer.WriteField("name", packageName)
+		writer.WriteField("version", version)
+		writer.WriteField("sha256_digest", fmt.Sprintf("%x", sha256.Sum256([]byte(content))))
+
+		_ = writer.Close()
+
+		req := NewRequestWithBody(t, "POST", fmt.Sprintf("/api/packages/%s/pypi", user.Name), body).
+			SetHeader("Content-Type", writer.FormDataContentType()).
+			AddBasicAuth(user.Name)
+		MakeRequest(t, req, http.StatusCreated)
+	}
+
+	uploadFile(t, owner, "1.0.0", "test_package-1.0.0.tar.gz", "owner")
+	uploadFile(t, member, "1.0.0", "test_package-1.0.0.tar.gz", "member")
+	uploadFile(t, member, "2.0.0", "test_package-2.0.0.tar.gz", "member")
+
+	root := fmt.Sprintf("/api/packages/%s/pypi", owner.Name)
+
+	t.Run("Disabled", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequest(t, "GET", fmt.Sprintf("%s/simple/%s", root, packageName))
+		resp := MakeRequest(t, req, http.StatusOK)
+		assert.Contains(t, resp.Body.String(), "test_package-1.0.0.tar.gz")
+		assert.NotContains(t, resp.Body.String(), "test_package-2.0.0.tar.gz")
+
+		req = NewRequest(t, "GET", fmt.Sprintf("%s/files/%s/2.0.0/test_package-2.0.0.tar.gz", root, packageName))
+		MakeRequest(t, req, http.StatusNotFound)
+	})
+
+	_, err := packages.InsertVirtualRegistry(db.DefaultContext, &packages.PackageVirtualRegistry{
+		Enabled:   true,
+		OwnerID:   owner.ID,
+		Type:      packages.TypePyPI,
+		MemberIDs: []int64{member.ID},
+	})
+	assert.NoError(t, err)
+
+	t.Run("PackageMetadata", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequest(t, "GET", fmt.Sprintf("%s/simple/%s", root, packageName))
+		resp := MakeRequest(t, req, http.StatusOK)
+		assert.Equal(t, 1, strings.Count(resp.Body.String(), "test_package-1.0.0.tar.gz</a>"))
+		assert.Contains(t, resp.Body.String(), fmt.Sprintf("%s/files/%s/2.0.0/test_package-2.0.0.tar.gz", root, packageName))
+	})
+
+	t.Run("Download", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		// the first owner which has the file serves it
+		req := NewRequest(t, "GET", fmt.Sprintf("%s/files/%s/1.0.0/test_package-1.0.0.tar.gz", root, packageName))
+		resp := MakeRequest(t, req, http.StatusOK)
+		assert.Equal(t, "owner", resp.Body.String())
+
+		req = NewRequest(t, "GET", fmt.Sprintf("%s/files/%s/2.0.0/test_package-2.0.0.tar.gz", root, packageName))
+		resp = MakeRequest(t, req, http.StatusOK)
+		assert.Equal(t, "member", resp.Body.String())
+
+		req = NewRequest(t, "GET", fmt.Sprintf("%s/files/%s/3.0.0/test_package-3.0.0.tar.gz", root, packageName))
+		MakeRequest(t, req, http.StatusNotFound)
+	})
+}
@@ -0,0 +1,197 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package integration
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"testing"
+
+	auth_model "code.gitea.io/gitea/models/auth"
+	"code.gitea.io/gitea/models/db"
+	issues_model "code.gitea.io/gitea/models/issues"
+	quota_model "code.gitea.io/gitea/models/quota"
+	repo_model "code.gitea.io/gitea/models/repo"
+	"code.gitea.io/gitea/models/unittest"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/setting"
+	api "code.gitea.io/gitea/modules/structs"
+	"code.gitea.io/gitea/modules/test"
+	"code.gitea.io/gitea/tests"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIQuota(t *testing.T) {
+	defer tests.PrepareTestEnv(t)()
+	defer test.MockVariableValue(&setting.Quota.Enabled, true)()
+
+	adminToken := getUserToken(t, "user1", auth_model.AccessTokenScopeWriteAdmin)
+
+	user := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2})
+	repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 1, OwnerID: user.ID})
+	issue := unittest.AssertExistsAndLoadBean(t, &issues_model.Issue{RepoID: repo.ID})
+
+	uploadAttachment := func(t *testing.T, expectedStatus int) {
+		t.Helper()
+
+		token := getUserToken(t, user.Name, auth_model.AccessTokenScopeWriteIssue)
+
+		buff := generateImg()
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		part, err := writer.CreateFormFile("attachment", "image.png")
+		assert.NoError(t, err)
+		_, err = io.Copy(part, &buff)
+		assert.NoError(t, err)
+		assert.NoError(t, writer.Close())
+
+		req := NewRequestWithBody(t, "POST", fmt.Sprintf("/api/v1/repos/%s/%s/issues/%d/assets", user.Name, repo.Name, issue.Index), body).
+			AddTokenAuth(token)
+		req.Header.Add("Content-Type", writer.FormDataContentType())
+		MakeRequest(t, req, expectedStatus)
+	}
+
+	t.Run("Rules", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequestWithJSON(t, "POST", "/api/v1/admin/quota/rules", api.CreateQuotaRuleOption{
+			Name:     "no-attachments",
+			Limit:    0,
+			Subjects: []string{"size:assets:attachments"},
+		}).AddTokenAuth(adminToken)
+		resp := MakeRequest(t, req, http.StatusCreated)
+
+		var rule api.QuotaRuleInfo
+		DecodeJSON(t, resp, &rule)
+		assert.Equal(t, "no-attachments", rule.Name)
+		assert.EqualValues(t, 0, rule.Limit)
+		assert.Equal(t, []string{"size:assets:attachments"}, rule.Subjects)
+
+		MakeRequest(t, req, http.StatusConflict)
+
+		req = NewRequestWithJSON(t, "POST", "/api/v1/admin/quota/rules", api.CreateQuotaRuleOption{
+			Name:     "invalid",
+			Limit:    1024,
+			Subjects: []string{"size:invalid"},
+		}).AddTokenAuth(adminToken)
+		MakeRequest(t, req, http.StatusUnprocessableEntity)
+
+		req = NewRequestWithJSON(t, "POST", "/api/v1/admin/quota/rules", api.CreateQuotaRuleOption{
+			Name:     "unlimited",
+			Limit:    -1,
+			Subjects: []string{"size:all"},
+		}).AddTokenAuth(adminToken)
+		MakeRequest(t, req, http.StatusCreated)
+
+		limit := int64(1024)
+		req = NewRequestWithJSON(t, "PATCH", "/api/v1/admin/quota/rules/unlimited", api.EditQuotaRuleOption{
+			Limit: &limit,
+		}).AddTokenAuth(adminToken)
+		resp = MakeRequest(t, req, http.StatusOK)
+		DecodeJSON(t, resp, &rule)
+		assert.EqualValues(t, 1024, rule.Limit)
+		assert.Equal(t, []string{"size:all"}, rule.Subjects)
+
+		req = NewRequest(t, "GET", "/api/v1/admin/quota/rules").AddTokenAuth(adminToken)
+		resp = MakeRequest(t, req, http.StatusOK)
+		var rules []api.QuotaRuleInfo
+		DecodeJSON(t, resp, &rules)
+		assert.Len(t, rules, 2)
+
+		req = NewRequest(t, "DELETE", "/api/v1/admin/quota/rules/unlimited").AddTokenAuth(adminToken)
+		MakeRequest(t, req, http.StatusNoContent)
+
+		req = NewRequest(t, "GET", "/api/v1/admin/quota/rules/unlimited").AddTokenAuth(adminToken)
+		MakeRequest(t, req, http.StatusNotFound)
+	})
+
+	t.Run("Groups", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequestWithJSON(t, "POST", "/api/v1/admin/quota/groups", api.CreateQuotaGroupOption{
+			Name:  "restricted",
+			Rules: []string{"unknown"},
+		}).AddTokenAuth(adminToken)
+		MakeRequest(t, req, http.StatusUnprocessableEntity)
+
+		req = NewRequestWithJSON(t, "POST", "/api/v1/admin/quota/groups", api.CreateQuotaGroupOption{
+			Name:  "restricted",
+			Rules: []string{"no-attachments"},
+		}).AddTokenAuth(adminToken)
+		resp := MakeRequest(t, req, http.StatusCreated)
+
+		var group api.QuotaGroup
+		DecodeJSON(t, resp, &group)
+		assert.Equal(t, "restricted", group.Name)
+		assert.Len(t, group.Rules, 1)
+
+		MakeRequest(t, req, http.StatusConflict)
+
+		req = NewRequest(t, "PUT", "/api/v1/admin/quota/groups/restricted/users/"+user.Name).AddTokenAuth(adminToken)
+		MakeRequest(t, req, http.StatusNoContent)
+
+		req = NewRequest(t, "GET", "/api/v1/admin/quota/groups/restricted/users").AddTokenAuth(adminToken)
+		resp = MakeRequest(t, req, http.StatusOK)
+		var users []api.User
+		DecodeJSON(t, resp, &users)
+		assert.Len(t, users, 1)
+		assert.Equal(t, user.Name, users[0].UserName)
+
+		req = NewRequest(t, "GET", "/api/v1/admin/users/"+user.Name+"/quota").AddTokenAuth(adminToken)
+		resp = MakeRequest(t, req, http.StatusOK)
+		var info api.QuotaInfo
+		DecodeJSON(t, resp, &info)
+		assert.Len(t, info.Groups, 1)
+		assert.Equal(t, "restricted", info.Groups[0].Name)
+	})
+
+	t.Run("UserQuota", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		used, err := quota_model.GetUsedForUser(db.DefaultContext, user.ID)
+		assert.NoError(t, err)
+
+		token := getUserToken(t, user.Name, auth_model.AccessTokenScopeReadUser)
+		req := NewRequest(t, "GET", "/api/v1/user/quota").AddTokenAuth(token)
+		resp := MakeRequest(t, req, http.StatusOK)
+
+		var info api.QuotaInfo
+		DecodeJSON(t, resp, &info)
+		assert.Equal(t, used.Git, info.Used.ReposGit)
+		assert.Equal(t, used.Attachments, info.Used.AssetsAttachments)
+		assert.Len(t, info.Groups, 1)
+
+		session := loginUser(t, user.Name)
+		resp = session.MakeRequest(t, NewRequest(t, "GET", "/user/settings/storage_overview"), http.StatusOK)
+		assert.Contains(t, resp.Body.String(), "no-attachments")
+	})
+
+	t.Run("Enforcement", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		uploadAttachment(t, http.StatusRequestEntityTooLarge)
+
+		req := NewRequest(t, "DELETE", "/api/v1/admin/quota/groups/restricted/users/"+user.Name).AddTokenAuth(adminToken)
+		MakeRequest(t, req, http.StatusNoContent)
+
+		uploadAttachment(t, http.StatusCreated)
+	})
+
+	t.Run("Cleanup", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequest(t, "DELETE", "/api/v1/admin/quota/groups/restricted").AddTokenAuth(adminToken)
+		MakeRequest(t, req, http.StatusNoContent)
+
+		req = NewRequest(t, "DELETE", "/api/v1/admin/quota/rules/no-attachments").AddTokenAuth(adminToken)
+		MakeRequest(t, req, http.StatusNoContent)
+
+		unittest.AssertNotExistsBean(t, &quota_model.GroupRuleMapping{})
+		unittest.AssertNotExistsBean(t, &quota_model.GroupMapping{})
+	})
+}
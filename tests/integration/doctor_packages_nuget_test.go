@@ -102,6 +102,7 @@ func TestDoctorPackagesNuget(t *testing.T) {
 
 		s, _, pf, err := packages_service.GetFileStreamByPackageNameAndVersion(
 			ctx,
+			nil,
 			&packages_service.PackageInfo{
 				Owner:       doer,
 				PackageType: packages_model.TypeNuGet,
@@ -102,6 +102,15 @@ func TestBadges(t *testing.T) {
 			resp = MakeRequest(t, req, http.StatusSeeOther)
 			assertBadge(t, resp, "self--test.yaml-waiting-lightgrey")
 
+			// Job of a workflow
+			req = NewRequestf(t, "GET", "/user2/%s/badges/workflows/pr.yml/jobs/test/badge.svg", repo.Name)
+			resp = MakeRequest(t, req, http.StatusSeeOther)
+			assertBadge(t, resp, "test-waiting-lightgrey")
+
+			req = NewRequestf(t, "GET", "/user2/%s/badges/workflows/pr.yml/jobs/no-such-job/badge.svg", repo.Name)
+			resp = MakeRequest(t, req, http.StatusSeeOther)
+			assertBadge(t, resp, "no--such--job-Not%20found-crimson")
+
 			// GitHub compatibility
 			req = NewRequestf(t, "GET", "/user2/%s/actions/workflows/pr.yml/badge.svg", repo.Name)
 			resp = MakeRequest(t, req, http.StatusSeeOther)
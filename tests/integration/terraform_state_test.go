@@ -0,0 +1,221 @@
+// Copyright 2024 The Forgejo Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	auth_model "code.gitea.io/gitea/models/auth"
+	"code.gitea.io/gitea/models/db"
+	repo_model "code.gitea.io/gitea/models/repo"
+	"code.gitea.io/gitea/models/unittest"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/test"
+	"code.gitea.io/gitea/tests"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTerraformState(t *testing.T) {
+	defer tests.PrepareTestEnv(t)()
+
+	user := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2})
+	repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 1, OwnerID: user.ID})
+
+	token := getUserToken(t, user.Name, auth_model.AccessTokenScopeWriteRepository)
+
+	stateURL := fmt.Sprintf("/%s/%s/terraform/state/default", user.Name, repo.Name)
+
+	newRequest := func(t *testing.T, method, url, body string) *RequestWrapper {
+		req := NewRequestWithBody(t, method, url, strings.NewReader(body))
+		req.Request.SetBasicAuth(user.Name, token)
+		return req
+	}
+
+	state := func(serial int) string {
+		return fmt.Sprintf(`{"version":4,"terraform_version":"1.5.7","serial":%d,"lineage":"0ad5c6a2","outputs":{},"resources":[]}`, serial)
+	}
+	lockInfo := func(id string) string {
+		return fmt.Sprintf(`{"ID":"%s","Operation":"OperationTypeApply","Info":"","Who":"user@host","Version":"1.5.7","Created":"2024-01-01T00:00:00Z","Path":""}`, id)
+	}
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequest(t, "GET", stateURL)
+		MakeRequest(t, req, http.StatusUnauthorized)
+
+		// read access to the repository is not enough to access the state
+		req = NewRequest(t, "GET", stateURL).AddBasicAuth("user4")
+		MakeRequest(t, req, http.StatusForbidden)
+
+		readToken := getUserToken(t, user.Name, auth_model.AccessTokenScopeReadRepository)
+		req = NewRequest(t, "GET", stateURL)
+		req.Request.SetBasicAuth(user.Name, readToken)
+		MakeRequest(t, req, http.StatusForbidden)
+	})
+
+	t.Run("NotExist", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := newRequest(t, "GET", stateURL, "")
+		MakeRequest(t, req, http.StatusNoContent)
+	})
+
+	t.Run("Lock", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := newRequest(t, "LOCK", stateURL, "invalid")
+		MakeRequest(t, req, http.StatusBadRequest)
+
+		req = newRequest(t, "LOCK", stateURL, strings.Repeat(" ", 1<<20)+lockInfo("lock-1"))
+		MakeRequest(t, req, http.StatusRequestEntityTooLarge)
+
+		req = newRequest(t, "LOCK", stateURL, lockInfo("lock-1"))
+		MakeRequest(t, req, http.StatusOK)
+
+		req = newRequest(t, "LOCK", stateURL, lockInfo("lock-2"))
+		resp := MakeRequest(t, req, http.StatusLocked)
+		assert.Equal(t, lockInfo("lock-1"), resp.Body.String())
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := newRequest(t, "POST", stateURL, state(1))
+		MakeRequest(t, req, http.StatusLocked)
+
+		req = newRequest(t, "POST", stateURL+"?ID=lock-2", state(1))
+		MakeRequest(t, req, http.StatusLocked)
+
+		req = newRequest(t, "POST", stateURL+"?ID=lock-1", "invalid")
+		MakeRequest(t, req, http.StatusBadRequest)
+
+		func() {
+			defer test.MockVariableValue(&setting.TerraformState.MaxSize, int64(len(state(1))-1))()
+
+			req := newRequest(t, "POST", stateURL+"?ID=lock-1", state(1))
+			MakeRequest(t, req, http.StatusRequestEntityTooLarge)
+		}()
+
+		req = newRequest(t, "POST", stateURL+"?ID=lock-1", state(1))
+		MakeRequest(t, req, http.StatusOK)
+
+		req = newRequest(t, "POST", stateURL+"?ID=lock-1", state(2))
+		MakeRequest(t, req, http.StatusOK)
+
+		req = newRequest(t, "GET", stateURL, "")
+		resp := MakeRequest(t, req, http.StatusOK)
+		assert.Equal(t, state(2), resp.Body.String())
+
+		s, err := repo_model.GetTerraformState(db.DefaultContext, repo.ID, "default")
+		assert.NoError(t, err)
+		versions, err := repo_model.GetTerraformStateVersions(db.DefaultContext, s.ID)
+		assert.NoError(t, err)
+		assert.Len(t, versions, 2)
+		assert.EqualValues(t, 2, versions[0].Serial)
+		assert.Equal(t, "0ad5c6a2", versions[0].Lineage)
+		assert.Equal(t, "1.5.7", versions[0].TerraformVersion)
+		assert.Equal(t, user.ID, versions[0].CreatorID)
+	})
+
+	t.Run("Unlock", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := newRequest(t, "UNLOCK", stateURL, lockInfo("lock-2"))
+		resp := MakeRequest(t, req, http.StatusConflict)
+		assert.Equal(t, lockInfo("lock-1"), resp.Body.String())
+
+		req = newRequest(t, "UNLOCK", stateURL, lockInfo("lock-1"))
+		MakeRequest(t, req, http.StatusOK)
+
+		// an unlocked state can be updated without lock id
+		req = newRequest(t, "POST", stateURL, state(3))
+		MakeRequest(t, req, http.StatusOK)
+
+		req = newRequest(t, "LOCK", stateURL, lockInfo("lock-3"))
+		MakeRequest(t, req, http.StatusOK)
+
+		// force unlock without lock information
+		req = newRequest(t, "UNLOCK", stateURL, "")
+		MakeRequest(t, req, http.StatusOK)
+
+		s, err := repo_model.GetTerraformState(db.DefaultContext, repo.ID, "default")
+		assert.NoError(t, err)
+		assert.False(t, s.IsLocked())
+	})
+
+	t.Run("Settings", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		s, err := repo_model.GetTerraformState(db.DefaultContext, repo.ID, "default")
+		assert.NoError(t, err)
+		versions, err := repo_model.GetTerraformStateVersions(db.DefaultContext, s.ID)
+		assert.NoError(t, err)
+		assert.Len(t, versions, 3)
+
+		settingsURL := fmt.Sprintf("/%s/%s/settings/terraform", user.Name, repo.Name)
+		stateSettingsURL := fmt.Sprintf("%s/%d", settingsURL, s.ID)
+
+		session := loginUser(t, user.Name)
+
+		resp := session.MakeRequest(t, NewRequest(t, "GET", settingsURL), http.StatusOK)
+		assert.Contains(t, resp.Body.String(), stateSettingsURL)
+
+		session.MakeRequest(t, NewRequest(t, "GET", stateSettingsURL), http.StatusOK)
+
+		resp = session.MakeRequest(t, NewRequest(t, "GET", fmt.Sprintf("%s/versions/%d", stateSettingsURL, versions[2].ID)), http.StatusOK)
+		assert.Equal(t, state(1), resp.Body.String())
+
+		// the settings are only available to the admins of the repository
+		loginUser(t, "user4").MakeRequest(t, NewRequest(t, "GET", settingsURL), http.StatusNotFound)
+
+		req := NewRequestWithValues(t, "POST", fmt.Sprintf("%s/versions/%d/rollback", stateSettingsURL, versions[2].ID), map[string]string{
+			"_csrf": GetCSRF(t, session, stateSettingsURL),
+		})
+		session.MakeRequest(t, req, http.StatusSeeOther)
+
+		req = newRequest(t, "GET", stateURL, "")
+		resp = MakeRequest(t, req, http.StatusOK)
+		assert.Equal(t, state(1), resp.Body.String())
+
+		req = newRequest(t, "LOCK", stateURL, lockInfo("lock-4"))
+		MakeRequest(t, req, http.StatusOK)
+
+		req = NewRequestWithValues(t, "POST", stateSettingsURL+"/unlock", map[string]string{
+			"_csrf": GetCSRF(t, session, stateSettingsURL),
+		})
+		session.MakeRequest(t, req, http.StatusSeeOther)
+
+		s, err = repo_model.GetTerraformState(db.DefaultContext, repo.ID, "default")
+		assert.NoError(t, err)
+		assert.False(t, s.IsLocked())
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := newRequest(t, "LOCK", stateURL, lockInfo("lock-5"))
+		MakeRequest(t, req, http.StatusOK)
+
+		req = newRequest(t, "DELETE", stateURL, "")
+		MakeRequest(t, req, http.StatusLocked)
+
+		req = newRequest(t, "UNLOCK", stateURL, lockInfo("lock-5"))
+		MakeRequest(t, req, http.StatusOK)
+
+		req = newRequest(t, "DELETE", stateURL, "")
+		MakeRequest(t, req, http.StatusOK)
+
+		req = newRequest(t, "GET", stateURL, "")
+		MakeRequest(t, req, http.StatusNoContent)
+
+		unittest.AssertNotExistsBean(t, &repo_model.TerraformState{RepoID: repo.ID})
+		unittest.AssertNotExistsBean(t, &repo_model.TerraformStateVersion{RepoID: repo.ID})
+	})
+}